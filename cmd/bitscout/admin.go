@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/rs/zerolog/log"
+)
+
+// adminHandler serves the Go profiler under /debug/pprof/, the expvar metrics (e.g. requests rejected
+// by API limits) at /debug/vars and the engine's runtime state at /debug/diagnostics
+func adminHandler(core *engine.EngineCore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.HandleFunc("GET /debug/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(core.Diagnostics()); err != nil {
+			log.Warn().Err(err).Msg("Failed to write diagnostics")
+		}
+	})
+	return mux
+}
+
+// startAdmin serves the admin endpoints on addr in the background. They are unauthenticated, so
+// addr should only be reachable by operators (e.g. localhost:6060). stop shuts the server down.
+func startAdmin(core *engine.EngineCore, addr string) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if host, _, _ := net.SplitHostPort(addr); host == "" || !isLoopback(host) {
+		log.Warn().Msgf("Admin endpoints on %s are reachable from other hosts and unauthenticated", addr)
+	}
+
+	// No write timeout: CPU profiles and traces stream for as long as the ?seconds parameter asks
+	server := &http.Server{Handler: adminHandler(core), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Msgf("Admin server failed: %s", err)
+		}
+	}()
+	log.Info().Msgf("Admin endpoints (pprof, diagnostics) at http://%s/debug/", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Warn().Msgf("Error stopping admin server: %s", err)
+		}
+	}, nil
+}
+
+// isLoopback reports whether host names the local machine only
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// benchVectorSize is the vector length of generated documents
+const benchVectorSize = 8
+
+// benchExtensions are the file extensions spread over generated documents
+var benchExtensions = []string{"go", "md", "txt", "json", "yaml"}
+
+// benchResult is the report of one benchmarked index
+type benchResult struct {
+	Index         string        `json:"index"`
+	Type          string        `json:"type"`
+	Documents     int           `json:"documents"`
+	IndexDuration time.Duration `json:"indexDuration"`
+	DocsPerSecond float64       `json:"docsPerSecond"`
+	Queries       int           `json:"queries"`
+	QueryErrors   int           `json:"queryErrors"`
+	QueriesPerSec float64       `json:"queriesPerSecond"`
+	P50           time.Duration `json:"p50"`
+	P95           time.Duration `json:"p95"`
+	P99           time.Duration `json:"p99"`
+	AvgResults    float64       `json:"avgResults"`
+}
+
+// runBench implements `bitscout bench`: every index under test is filled with the same corpus, then
+// queried, and indexing throughput, query throughput and latency percentiles are reported side by side
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := flags.String("config", "", "Benchmark the indexes of this starter config instead of -types")
+	types := flags.String("types", "simple,inverted,vector", "Comma-separated index types to compare")
+	corpusDir := flags.String("corpus", "", "Load the corpus from this directory instead of generating one")
+	numDocs := flags.Int("docs", 10000, "Number of documents to generate")
+	queryFile := flags.String("queries", "", "File with one query per line (default: a generated mix of text, dimension and vector queries)")
+	numQueries := flags.Int("n", 1000, "Number of queries to run per index, cycling through the queries")
+	concurrency := flags.Int("concurrency", 1, "Number of queries run concurrently")
+	batchSize := flags.Int("batch-size", 1000, "Number of documents added per AddDocuments call")
+	seed := flags.Int64("seed", 1, "Seed of the generated corpus and queries")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	verbose := flags.Bool("v", false, "Keep info logging (slows searches down)")
+	flags.Parse(args)
+
+	if *numQueries <= 0 || *concurrency <= 0 || *batchSize <= 0 {
+		return fmt.Errorf("-n, -concurrency and -batch-size must be positive")
+	}
+	if !*verbose {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+
+	configs, cleanup, err := benchIndexes(*configPath, *types)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	docs, err := benchCorpus(*corpusDir, *numDocs, rng)
+	if err != nil {
+		return err
+	}
+	queries, err := benchQueries(*queryFile, rng)
+	if err != nil {
+		return err
+	}
+
+	factory := index.NewIndexFactory()
+	var results []benchResult
+	for _, ic := range configs {
+		idx, err := factory.Create(ic.Type, ic.Config)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", ic.Name, err)
+		}
+		result, err := benchIndex(idx, docs, queries, *numQueries, *concurrency, *batchSize)
+		idx.Close()
+		if err != nil {
+			return fmt.Errorf("index %s: %w", ic.Name, err)
+		}
+		result.Index, result.Type = ic.Name, ic.Type
+		results = append(results, result)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	printBenchReport(results, len(queries), *concurrency)
+	return nil
+}
+
+// benchIndexes returns the indexes to benchmark. Persisted indexes of -types are kept in a temporary
+// directory, which cleanup removes; those of a starter config use their configured database.
+func benchIndexes(configPath, types string) ([]IndexConfig, func(), error) {
+	cleanup := func() {}
+	if configPath != "" {
+		loaded, err := readStarterConfig(starterSource(configPath))
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("could not load config file %s: %w", configPath, err)
+		}
+		return withDefaults(loaded).Indexes, cleanup, nil
+	}
+
+	var configs []IndexConfig
+	for _, typeName := range strings.Split(types, ",") {
+		typeName = strings.TrimSpace(typeName)
+		if typeName == "" {
+			continue
+		}
+		ic := IndexConfig{Name: typeName, Type: typeName, Config: map[string]interface{}{}}
+		if typeName == "persisted" || typeName == "PersistedSimpleIndex" {
+			dir, err := os.MkdirTemp("", "bitscout-bench-")
+			if err != nil {
+				return nil, cleanup, err
+			}
+			previous := cleanup
+			cleanup = func() { previous(); os.RemoveAll(dir) }
+			ic.Config["db_path"] = filepath.Join(dir, "index.db")
+		}
+		configs = append(configs, ic)
+	}
+	if len(configs) == 0 {
+		return nil, cleanup, fmt.Errorf("no index types to benchmark")
+	}
+	return configs, cleanup, nil
+}
+
+// benchCorpus loads the documents of a directory, or generates n documents with text, metadata
+// (fileExtension, fileSize, lastModified) and vectors
+func benchCorpus(dir string, n int, rng *rand.Rand) ([]models.Document, error) {
+	if dir != "" {
+		docs, err := loaders.NewFilesystemLoader(dir).Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load corpus %s: %w", dir, err)
+		}
+		return docs, nil
+	}
+
+	vocabulary := benchVocabulary(2000, rng)
+	docs := make([]models.Document, n)
+	for i := range docs {
+		words := make([]string, 20+rng.Intn(200))
+		for j := range words {
+			// Squared to skew the distribution towards common words, as in natural text
+			r := rng.Float64()
+			words[j] = vocabulary[int(r*r*float64(len(vocabulary)))]
+		}
+		vector := make([]float64, benchVectorSize)
+		for j := range vector {
+			vector[j] = rng.Float64()
+		}
+		extension := benchExtensions[rng.Intn(len(benchExtensions))]
+		docs[i] = models.Document{
+			ID:     fmt.Sprintf("doc-%d", i),
+			Text:   strings.Join(words, " "),
+			Source: fmt.Sprintf("bench/%d.%s", i, extension),
+			Vector: vector,
+			Meta: map[string]string{
+				"fileExtension": extension,
+				"fileSize":      fmt.Sprint(rng.Intn(100000)),
+				"lastModified":  fmt.Sprint(1700000000 + rng.Intn(30000000)),
+			},
+		}
+	}
+	return docs, nil
+}
+
+// benchVocabulary generates n distinct pronounceable words
+func benchVocabulary(n int, rng *rand.Rand) []string {
+	const consonants, vowels = "bcdfghklmnprstvz", "aeiou"
+	seen := make(map[string]bool, n)
+	words := make([]string, 0, n)
+	for len(words) < n {
+		var b strings.Builder
+		for syllables := 1 + rng.Intn(3); syllables > 0; syllables-- {
+			b.WriteByte(consonants[rng.Intn(len(consonants))])
+			b.WriteByte(vowels[rng.Intn(len(vowels))])
+		}
+		if word := b.String(); !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// benchQueries reads the queries of a file (blank lines and lines starting with # are skipped), or
+// generates a mix of free-text, dimension and vector queries against the generated corpus
+func benchQueries(path string, rng *rand.Rand) ([]string, error) {
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		var queries []string
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				queries = append(queries, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(queries) == 0 {
+			return nil, fmt.Errorf("no queries in %s", path)
+		}
+		return queries, nil
+	}
+
+	vocabulary := benchVocabulary(200, rng)
+	var queries []string
+	for i := 0; i < 20; i++ {
+		queries = append(queries, vocabulary[rng.Intn(len(vocabulary))])
+	}
+	for _, extension := range benchExtensions {
+		queries = append(queries,
+			"fileExtension="+extension,
+			fmt.Sprintf("fileExtension=%s and fileSize>%d", extension, rng.Intn(100000)))
+	}
+	for i := 0; i < 5; i++ {
+		vector := make([]float64, benchVectorSize)
+		for j := range vector {
+			vector[j] = rng.Float64()
+		}
+		queries = append(queries, index.FormatVectorLiteral(vector))
+	}
+	return queries, nil
+}
+
+// benchIndex adds the corpus to an index in batches and flushes it, then runs n queries on concurrency workers
+func benchIndex(idx index.Index, docs []models.Document, queries []string, n, concurrency, batchSize int) (benchResult, error) {
+	result := benchResult{Documents: len(docs), Queries: n}
+
+	started := time.Now()
+	for start := 0; start < len(docs); start += batchSize {
+		end := start + batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		if err := idx.AddDocuments(context.Background(), docs[start:end]); err != nil {
+			return result, fmt.Errorf("failed to index documents: %w", err)
+		}
+	}
+	// Persisted indexes write asynchronously; the measurement includes the writes
+	if err := idx.Flush(); err != nil {
+		return result, fmt.Errorf("failed to flush index: %w", err)
+	}
+	result.IndexDuration = time.Since(started)
+	result.DocsPerSecond = float64(len(docs)) / result.IndexDuration.Seconds()
+
+	latencies := make([]time.Duration, n)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	next := make(chan int)
+	totalResults := 0
+	started = time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				queryStarted := time.Now()
+				found, err := idx.Search(context.Background(), queries[i%len(queries)])
+				latencies[i] = time.Since(queryStarted)
+				mu.Lock()
+				if err != nil {
+					result.QueryErrors++
+				}
+				totalResults += len(found)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	elapsed := time.Since(started)
+
+	if result.QueryErrors > 0 {
+		log.Warn().Msgf("%d of %d queries failed", result.QueryErrors, n)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.QueriesPerSec = float64(n) / elapsed.Seconds()
+	result.P50 = percentile(latencies, 50)
+	result.P95 = percentile(latencies, 95)
+	result.P99 = percentile(latencies, 99)
+	result.AvgResults = float64(totalResults) / float64(n)
+	return result, nil
+}
+
+// percentile returns the p-th percentile of sorted latencies (nearest rank)
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printBenchReport(results []benchResult, distinctQueries, concurrency int) {
+	if len(results) > 0 {
+		fmt.Printf("%d documents, %d queries (%d distinct, concurrency %d)\n\n",
+			results[0].Documents, results[0].Queries, distinctQueries, concurrency)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "INDEX\tTYPE\tINDEXING\tDOCS/S\tQUERIES/S\tP50\tP95\tP99\tAVG RESULTS\tERRORS\t")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.0f\t%.0f\t%s\t%s\t%s\t%.1f\t%d\t\n",
+			r.Index, r.Type, r.IndexDuration.Round(time.Millisecond), r.DocsPerSecond, r.QueriesPerSec,
+			r.P50.Round(time.Microsecond), r.P95.Round(time.Microsecond), r.P99.Round(time.Microsecond),
+			r.AvgResults, r.QueryErrors)
+	}
+	w.Flush()
+}
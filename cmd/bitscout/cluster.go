@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/cluster"
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultNodeTimeout bounds the search of each peer node when the cluster config sets no node_timeout
+const defaultNodeTimeout = 2 * time.Second
+
+// ClusterConfig distributes searches: each search runs on this node and on every peer, which serve
+// each other's searches over gRPC on listen. Every node of a cluster shares the same secret. With shards,
+// the documents of the default index are spread over the nodes by consistent hash of their IDs. With raft,
+// the nodes agree on the indexes created at runtime, the aliases and the shard map.
+// Example: { "node_id": "a", "listen": ":7070", "secret": "s3cret", "shards": 16, "peers": [{ "id": "b", "address": "10.0.0.2:7070" }] }
+type ClusterConfig struct {
+	NodeID          string       `json:"node_id,omitempty"`           // Name of this node (default local; required with shards)
+	Listen          string       `json:"listen,omitempty"`            // Address peers search this node on (empty: not served)
+	Secret          string       `json:"secret,omitempty"`            // Bearer token nodes authenticate each other with
+	Peers           []PeerConfig `json:"peers,omitempty"`             // Nodes searches are sent to
+	NodeTimeout     string       `json:"node_timeout,omitempty"`      // Deadline of each peer's search (default 2s)
+	RequireAllNodes bool         `json:"require_all_nodes,omitempty"` // Fail searches a peer fails instead of returning partial results
+	Shards          int          `json:"shards,omitempty"`            // Shards of the default index, the same on every node (0: not sharded)
+	Raft            *RaftConfig  `json:"raft,omitempty"`              // Replicates the cluster metadata (nil: every node keeps its own)
+}
+
+// PeerConfig is a node of the cluster
+type PeerConfig struct {
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	RaftAddress string `json:"raft_address,omitempty"` // Address the node's raft listens on (required with raft)
+}
+
+// RaftConfig replicates the indexes created at runtime, the aliases and the shard map with raft.
+// Example: { "address": "10.0.0.1:7071", "dir": "data/raft", "bootstrap": true }
+type RaftConfig struct {
+	Address   string `json:"address"`             // Address this node's raft listens on and peers reach it at
+	Dir       string `json:"dir"`                 // Directory of the raft log and snapshots
+	Bootstrap bool   `json:"bootstrap,omitempty"` // Start a new cluster of this node and its peers if there is no raft state
+}
+
+// startCluster distributes searches to the configured peers and serves their searches in the background.
+// stop stops serving and closes the connections to the peers.
+func startCluster(core *engine.EngineCore, cfg *ClusterConfig) (stop func(), err error) {
+	if cfg == nil {
+		return func() {}, nil
+	}
+	timeout := defaultNodeTimeout
+	if cfg.NodeTimeout != "" {
+		if timeout, err = time.ParseDuration(cfg.NodeTimeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("cluster node_timeout must be a positive duration, got %q", cfg.NodeTimeout)
+		}
+	}
+	if cfg.Secret == "" {
+		log.Warn().Msg("Cluster has no secret: any host reaching its nodes can search them")
+	}
+
+	manager := cluster.NewManager()
+	for _, peer := range cfg.Peers {
+		if err := manager.RegisterNode(peer.ID, peer.Address); err != nil {
+			return nil, fmt.Errorf("invalid cluster peer %q: %w", peer.ID, err)
+		}
+	}
+	transport := cluster.NewTransport(cfg.Secret)
+	core.SetClusterManager(manager)
+	core.SetClusterTransport(transport, engine.ClusterOptions{NodeID: cfg.NodeID, NodeTimeout: timeout, RequireAllNodes: cfg.RequireAllNodes})
+
+	// Sharding replaces the default index by one routing its documents to the nodes of their shards
+	var writes ports.ShardWritePort
+	var sharded *cluster.ShardedIndex
+	if cfg.Shards != 0 {
+		if sharded, err = shardDefaultIndex(core, cfg, manager, transport); err != nil {
+			transport.Close()
+			return nil, err
+		}
+		writes = sharded
+	}
+
+	var metadata *cluster.MetadataStore
+	if cfg.Raft != nil {
+		if metadata, err = startRaft(core, cfg, manager, transport, sharded); err != nil {
+			transport.Close()
+			return nil, err
+		}
+	}
+
+	var server *cluster.Server
+	if cfg.Listen != "" {
+		listener, err := net.Listen("tcp", cfg.Listen)
+		if err != nil {
+			if metadata != nil {
+				metadata.Close()
+			}
+			transport.Close()
+			return nil, err
+		}
+		server = cluster.NewServer(core, writes, cfg.Secret)
+		if metadata != nil {
+			server.SetMetadata(metadata)
+		}
+		go func() {
+			if err := server.Serve(listener); err != nil {
+				log.Error().Msgf("Cluster server failed: %s", err)
+			}
+		}()
+		log.Info().Msgf("Serving cluster searches at %s", listener.Addr())
+	}
+	log.Info().Msgf("Distributing searches to %d peer nodes", len(cfg.Peers))
+
+	return func() {
+		if server != nil {
+			server.Stop()
+		}
+		if metadata != nil {
+			if err := metadata.Close(); err != nil {
+				log.Warn().Msgf("Error stopping raft: %s", err)
+			}
+		}
+		if err := transport.Close(); err != nil {
+			log.Warn().Msgf("Error closing cluster connections: %s", err)
+		}
+	}, nil
+}
+
+// shardDefaultIndex spreads the documents of the default index over the shards of the cluster
+func shardDefaultIndex(core *engine.EngineCore, cfg *ClusterConfig, manager *cluster.Manager, transport *cluster.Transport) (*cluster.ShardedIndex, error) {
+	if cfg.NodeID == "" || cfg.Listen == "" {
+		return nil, fmt.Errorf("sharded cluster nodes need a node_id and a listen address for their peers to write to")
+	}
+	peers, _ := manager.ListNodes()
+	router, err := cluster.NewRouter(cfg.NodeID, cfg.Shards, peers)
+	if err != nil {
+		return nil, err
+	}
+	name, local, err := core.DefaultIndex()
+	if err != nil {
+		return nil, err
+	}
+	sharded := cluster.NewShardedIndex(local, router, manager, transport)
+	core.RegisterIndex(name, sharded)
+	log.Info().Msgf("Index %s is split into %d shards over %d nodes", name, cfg.Shards, len(peers)+1)
+	return sharded, nil
+}
+
+// startRaft replicates the cluster metadata: changes are applied to the engine and, on sharded nodes,
+// to the routing of documents as each node applies them
+func startRaft(core *engine.EngineCore, cfg *ClusterConfig, manager *cluster.Manager, transport *cluster.Transport, sharded *cluster.ShardedIndex) (*cluster.MetadataStore, error) {
+	if cfg.NodeID == "" || cfg.Listen == "" {
+		return nil, fmt.Errorf("raft nodes need a node_id and a listen address for followers to forward changes to")
+	}
+	options := cluster.RaftOptions{
+		NodeID:    cfg.NodeID,
+		Address:   cfg.Raft.Address,
+		Dir:       cfg.Raft.Dir,
+		Bootstrap: cfg.Raft.Bootstrap,
+		Servers:   []cluster.RaftServer{{ID: cfg.NodeID, Address: cfg.Raft.Address}},
+	}
+	nodes := []string{cfg.NodeID}
+	for _, peer := range cfg.Peers {
+		if peer.RaftAddress == "" {
+			return nil, fmt.Errorf("cluster peer %q needs a raft_address", peer.ID)
+		}
+		options.Servers = append(options.Servers, cluster.RaftServer{ID: peer.ID, Address: peer.RaftAddress})
+		nodes = append(nodes, peer.ID)
+	}
+	if cfg.Shards != 0 {
+		options.Shards = ports.ShardMap{Shards: cfg.Shards, Nodes: nodes}
+	}
+
+	observer := func(meta ports.ClusterMetadata) {
+		core.ApplyClusterMetadata(meta)
+		if sharded == nil || meta.Shards.Shards == 0 {
+			return
+		}
+		var peers []string
+		for _, node := range meta.Shards.Nodes {
+			if node != cfg.NodeID {
+				peers = append(peers, node)
+			}
+		}
+		router, err := cluster.NewRouter(cfg.NodeID, meta.Shards.Shards, peers)
+		if err != nil {
+			log.Error().Msgf("Invalid replicated shard map: %s", err)
+			return
+		}
+		sharded.SetRouter(router)
+	}
+	metadata, err := cluster.NewMetadataStore(options, manager, transport, observer)
+	if err != nil {
+		return nil, err
+	}
+	core.SetMetadataStore(metadata)
+	log.Info().Msgf("Replicating cluster metadata with raft at %s", cfg.Raft.Address)
+	return metadata, nil
+}
+
+// runCluster implements `bitscout cluster rebalance`, which has every node of a sharded cluster move the
+// documents of the shards it no longer holds to their new nodes, after nodes joined or left and every
+// node was restarted with the new peers
+func runCluster(args []string) error {
+	if len(args) == 0 || args[0] != "rebalance" {
+		return fmt.Errorf("usage: bitscout cluster rebalance [-config file]")
+	}
+	flags := flag.NewFlagSet("cluster rebalance", flag.ExitOnError)
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file of a node of the cluster")
+	timeout := flags.Duration("timeout", time.Hour, "Deadline of the rebalancing of each node")
+	flags.Parse(args[1:])
+
+	cfg, err := readStarterConfig(starterSource(*configPath))
+	if err != nil {
+		return err
+	}
+	if cfg.Cluster == nil || cfg.Cluster.Shards == 0 {
+		return fmt.Errorf("%s has no sharded cluster", *configPath)
+	}
+	nodes := []PeerConfig{{ID: cfg.Cluster.NodeID, Address: dialAddr(cfg.Cluster.Listen)}}
+	nodes = append(nodes, cfg.Cluster.Peers...)
+
+	transport := cluster.NewTransport(cfg.Cluster.Secret)
+	defer transport.Close()
+	total := 0
+	for _, node := range nodes {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		moved, err := transport.Rebalance(ctx, node.Address)
+		cancel()
+		total += moved
+		if err != nil {
+			return fmt.Errorf("node %s: %w (%d documents moved so far)", node.ID, err, total)
+		}
+		fmt.Printf("Node %s: moved %d documents\n", node.ID, moved)
+	}
+	fmt.Printf("Rebalanced %d nodes, %d documents moved\n", len(nodes), total)
+	return nil
+}
+
+// dialAddr turns a listen address into one to connect to, e.g. :7070 into localhost:7070
+func dialAddr(listen string) string {
+	if host, port, err := net.SplitHostPort(listen); err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		return net.JoinHostPort("localhost", port)
+	}
+	return listen
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/rs/zerolog/log"
+)
+
+// writePIDFile records the process ID in path, refusing to start when the file names a running process
+// (e.g. another bitscout holding the same databases). remove deletes the file if it still holds our PID.
+func writePIDFile(path string) (remove func(), err error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && processRunning(pid) {
+			return nil, fmt.Errorf("PID file %s names running process %d", path, pid)
+		}
+		log.Warn().Msgf("Replacing stale PID file %s", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// Written to a temporary file and renamed, so readers never see a partial PID
+	pid := strconv.Itoa(os.Getpid())
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(pid+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	log.Info().Msgf("Wrote PID %s to %s", pid, path)
+
+	return func() {
+		data, err := os.ReadFile(path)
+		if err != nil || strings.TrimSpace(string(data)) != pid {
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			log.Error().Msgf("Error removing PID file %s: %s", path, err)
+		}
+	}, nil
+}
+
+// processRunning reports whether a process with the given PID exists
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// stopWithin stops the engine: the APIs stop accepting requests, then the indexes close, persisted
+// ones after writing their queued operations and closing their databases. It gives up when the
+// timeout expires or another SIGINT/SIGTERM arrives, returning the context error.
+func stopWithin(core *engine.EngineCore, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+	go func() {
+		select {
+		case <-interrupts:
+			log.Warn().Msg("Interrupted again, abandoning graceful shutdown")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := core.Stop(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("shutdown did not finish within %s", timeout)
+	}
+	return err
+}
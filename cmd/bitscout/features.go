@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/features"
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// featureExtractors holds the feature extractors configured in the starter config. Names known to the
+// features package are created in its registry and merged into documents (Meta and Vector) before indexing;
+// other names refer to extractors registered with the engine (e.g. plugins), which only add metadata.
+type featureExtractors struct {
+	core     *engine.EngineCore
+	factory  *features.ExtractorFactory
+	registry *features.FeatureRegistry
+	cache    *features.FeatureCache
+	names    []string // Configured extractors, in config order
+}
+
+// registerFeatures creates the configured feature extractors and their cache
+func registerFeatures(core *engine.EngineCore, configs []FeatureConfig, cacheConfig *FeatureCacheConfig) (*featureExtractors, error) {
+	f := &featureExtractors{
+		core:     core,
+		factory:  features.NewExtractorFactory(),
+		registry: features.NewFeatureRegistry(),
+	}
+	if err := f.openCache(cacheConfig); err != nil {
+		return nil, err
+	}
+	for _, fc := range configs {
+		if err := f.apply(fc); err != nil {
+			f.close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// openCache sets up the feature cache; without a configured database it is held in memory
+func (f *featureExtractors) openCache(cfg *FeatureCacheConfig) error {
+	if cfg == nil {
+		cfg = &FeatureCacheConfig{}
+	}
+	f.cache = features.NewFeatureCache(cfg.Size)
+	if cfg.DBPath != "" {
+		cache, err := features.OpenFeatureCache(cfg.DBPath, cfg.Size)
+		if err != nil {
+			return err
+		}
+		f.cache = cache
+		log.Info().Msgf("Feature cache persisted in %s", cfg.DBPath)
+	}
+	f.registry.SetCache(f.cache)
+	return nil
+}
+
+// close closes the feature cache
+func (f *featureExtractors) close() {
+	if err := f.cache.Close(); err != nil {
+		log.Error().Msgf("Error closing feature cache: %s", err)
+	}
+}
+
+// apply creates or reconfigures a configured feature extractor
+func (f *featureExtractors) apply(fc FeatureConfig) error {
+	if !f.factory.Has(fc.Name) {
+		if !f.configured(fc.Name) {
+			f.names = append(f.names, fc.Name)
+		}
+		if len(fc.Config) == 0 {
+			return nil
+		}
+		return f.core.ConfigureFeatureExtractor(fc.Name, fc.Config)
+	}
+
+	cfg, err := features.FromMap(fc.Config)
+	if err != nil {
+		return fmt.Errorf("feature extractor %s: %w", fc.Name, err)
+	}
+	merge, err := features.MergeOptionsFromMap(fc.Config)
+	if err != nil {
+		return fmt.Errorf("feature extractor %s: %w", fc.Name, err)
+	}
+	if _, exists := f.registry.GetExtractor(fc.Name); !exists {
+		extractor, err := f.factory.Create(fc.Name, cfg)
+		if err != nil {
+			return fmt.Errorf("feature extractor %s: %w", fc.Name, err)
+		}
+		if err := f.registry.Register(extractor); err != nil {
+			return err
+		}
+		f.names = append(f.names, fc.Name)
+	}
+	if err := f.registry.Configure(fc.Name, cfg); err != nil {
+		return err
+	}
+	return f.registry.SetMergeOptions(fc.Name, merge)
+}
+
+func (f *featureExtractors) configured(name string) bool {
+	for _, configured := range f.names {
+		if configured == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wire sets the feature extraction of a loader's pipeline
+func (f *featureExtractors) wire(pipeline *engine.Pipeline, lc LoaderConfig) {
+	merged, others := f.extractorsOf(lc)
+	pipeline.Extractors = append(pipeline.Extractors, others...)
+	if len(merged) > 0 {
+		pipeline.Processor = &featureStage{registry: f.registry, extractors: merged}
+		log.Debug().Msgf("Loader %s uses feature extractors %v", lc.Name, merged)
+	}
+}
+
+// extractorsOf splits the extractors applied to a loader's documents into those merged by the feature
+// registry and those registered with the engine. A loader without a "features" list uses every configured
+// extractor.
+func (f *featureExtractors) extractorsOf(lc LoaderConfig) (merged, others []string) {
+	names := lc.Features
+	if names == nil {
+		names = f.names
+	}
+	for _, name := range names {
+		if _, ok := f.registry.GetExtractor(name); ok {
+			merged = append(merged, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	return merged, others
+}
+
+// normalizeQuery scales vector literal queries with the corpus statistics of the feature extractors, so
+// they compare to documents the way the documents compare to each other. Queries are assumed to be laid
+// out like the documents of a loader using every configured extractor; where clauses are kept as is.
+func (f *featureExtractors) normalizeQuery(query string) string {
+	vector, where, ok, err := index.ParseVectorQuery(query)
+	if !ok || err != nil {
+		return query
+	}
+	scaled, err := f.registry.NormalizeQuery(vector, f.registry.ListExtractors())
+	if err != nil {
+		log.Debug().Msgf("Not normalizing query %s: %s", query, err)
+		return query
+	}
+	return index.FormatVectorQuery(scaled, where)
+}
+
+// featureStage adapts the feature registry to the engine's pipeline processor port
+type featureStage struct {
+	registry   *features.FeatureRegistry
+	extractors []string
+}
+
+func (s *featureStage) ProcessDocuments(ctx context.Context, docs []models.Document) error {
+	return s.registry.MergeContext(ctx, docs, s.extractors)
+}
@@ -1,39 +1,96 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/aawadall/bit-scout/internal/api"
+	"github.com/aawadall/bit-scout/internal/config"
 	"github.com/aawadall/bit-scout/internal/engine"
 	"github.com/aawadall/bit-scout/internal/index"
 	"github.com/aawadall/bit-scout/internal/loaders"
 	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/plugins"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/internal/telemetry"
+	"github.com/aawadall/bit-scout/internal/webhooks"
 	"github.com/rs/zerolog/log"
 )
 
-// Adapter for index.SimpleIndex to ports.IndexPort
-// Only implements required methods
-// (AddDocument, Search, Count, Close)
-type simpleIndexAdapter struct {
-	idx *index.SimpleIndex
+// Adapter for any index.Index to ports.IndexPort
+// Implements the required methods (AddDocument, Search, Count, Close)
+// plus the batch, mutation, reconfiguration and transfer extensions
+type indexAdapter struct {
+	idx index.Index
 }
 
-func (a *simpleIndexAdapter) AddDocument(doc interface{}) error {
+func (a *indexAdapter) AddDocument(ctx context.Context, doc interface{}) error {
 	d, ok := doc.(models.Document)
 	if !ok {
 		return fmt.Errorf("expected models.Document, got %T", doc)
 	}
-	return a.idx.AddDocument(d)
+	ctx, err := writeContext(ctx)
+	if err != nil {
+		return err
+	}
+	return a.idx.AddDocument(ctx, d)
+}
+
+func (a *indexAdapter) AddDocuments(ctx context.Context, docs []models.Document) error {
+	ctx, err := writeContext(ctx)
+	if err != nil {
+		return err
+	}
+	return a.idx.AddDocuments(ctx, docs)
 }
 
-func (a *simpleIndexAdapter) Search(query string) ([]interface{}, error) {
-	results, err := a.idx.Search(query)
+func (a *indexAdapter) UpdateDocument(ctx context.Context, doc models.Document) error {
+	ctx, err := writeContext(ctx)
+	if err != nil {
+		return err
+	}
+	return a.idx.UpdateDocument(ctx, doc.ID, doc)
+}
+
+func (a *indexAdapter) DeleteDocument(ctx context.Context, id string) error {
+	ctx, err := writeContext(ctx)
+	if err != nil {
+		return err
+	}
+	return a.idx.DeleteDocument(ctx, id)
+}
+
+// writeContext passes the durability of a write in ctx, if any, to the index
+func writeContext(ctx context.Context) (context.Context, error) {
+	name := ports.DurabilityFrom(ctx)
+	if name == "" {
+		return ctx, nil
+	}
+	durability, err := index.ParseDurability(name)
+	if err != nil {
+		return ctx, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+	}
+	return index.WithDurability(ctx, durability), nil
+}
+
+func (a *indexAdapter) Search(ctx context.Context, query string) ([]interface{}, error) {
+	if principals, ok := ports.PrincipalsFrom(ctx); ok {
+		ctx = index.WithPrincipals(ctx, principals)
+	}
+	ctx = index.WithLanguage(ctx, ports.LanguageFrom(ctx))
+	results, err := a.idx.Search(ctx, query)
+	var invalid *index.InvalidQueryError
+	if errors.As(err, &invalid) {
+		return nil, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -44,21 +101,207 @@ func (a *simpleIndexAdapter) Search(query string) ([]interface{}, error) {
 	return out, nil
 }
 
-func (a *simpleIndexAdapter) Count() (int, error) {
+// GetDocuments fetches documents by ID, leaving out those the principals of ctx may not see
+func (a *indexAdapter) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	if principals, ok := ports.PrincipalsFrom(ctx); ok {
+		ctx = index.WithPrincipals(ctx, principals)
+	}
+	return a.idx.GetDocuments(ctx, ids)
+}
+
+// ListDocuments pages through the documents in ID order, leaving out those the principals of ctx may not see
+func (a *indexAdapter) ListDocuments(ctx context.Context, opts ports.ListOptions) (ports.DocumentPage, error) {
+	if principals, ok := ports.PrincipalsFrom(ctx); ok {
+		ctx = index.WithPrincipals(ctx, principals)
+	}
+	page, err := a.idx.ListDocuments(ctx, index.ListOptions(opts))
+	if errors.Is(err, index.ErrInvalidListOptions) {
+		return ports.DocumentPage{}, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+	}
+	if err != nil {
+		return ports.DocumentPage{}, err
+	}
+	return ports.DocumentPage(page), nil
+}
+
+func (a *indexAdapter) Export(w io.Writer) error {
+	return a.idx.Export(w)
+}
+
+func (a *indexAdapter) Import(r io.Reader) error {
+	return a.idx.Import(r)
+}
+
+func (a *indexAdapter) Configure(config map[string]interface{}) error {
+	return a.idx.Configure(config)
+}
+
+func (a *indexAdapter) Count() (int, error) {
 	return a.idx.Count()
 }
 
-func (a *simpleIndexAdapter) Close() error {
+func (a *indexAdapter) Flush() error {
+	return a.idx.Flush()
+}
+
+func (a *indexAdapter) Optimize() error {
+	return a.idx.Optimize()
+}
+
+func (a *indexAdapter) Clear() error {
+	return a.idx.Clear()
+}
+
+// Verify compares the index's documents in memory and on disk, when it keeps them in both
+func (a *indexAdapter) Verify(repair string) (ports.VerifyReport, error) {
+	verifier, ok := a.idx.(index.Verifier)
+	if !ok {
+		return ports.VerifyReport{}, fmt.Errorf("%w: the index keeps its documents in one place", ports.ErrNotSupported)
+	}
+	report, err := verifier.Verify(repair)
+	if errors.Is(err, index.ErrUnknownRepair) {
+		return ports.VerifyReport{}, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+	}
+	if err != nil {
+		return ports.VerifyReport{}, err
+	}
+	return ports.VerifyReport{Documents: report.Documents, Stored: report.Stored, MissingOnDisk: report.MissingOnDisk,
+		MissingInMemory: report.MissingInMemory, Mismatched: report.Mismatched, Repaired: report.Repaired}, nil
+}
+
+func (a *indexAdapter) Size() (int, error) {
+	return a.idx.Size()
+}
+
+// TermStats returns the statistics of indexes ranking free-text queries; other indexes rank none
+func (a *indexAdapter) TermStats(query string, ids []string) (ports.TermStats, error) {
+	scorer, ok := a.idx.(index.TermScorer)
+	if !ok {
+		return ports.TermStats{}, nil
+	}
+	stats, err := scorer.TermStats(query, ids)
+	return ports.TermStats(stats), err
+}
+
+// ValidateQuery checks a query without running it, when the index can
+func (a *indexAdapter) ValidateQuery(query string) []ports.QueryError {
+	validator, ok := a.idx.(index.QueryValidator)
+	if !ok {
+		return nil
+	}
+	errs := validator.ValidateQuery(query)
+	out := make([]ports.QueryError, len(errs))
+	for i, err := range errs {
+		out[i] = ports.QueryError(err)
+	}
+	return out
+}
+
+// ExplainQuery plans a query without running it
+func (a *indexAdapter) ExplainQuery(query string) (ports.QueryPlan, error) {
+	prepared, err := a.idx.PrepareQuery(query)
+	var invalid *index.InvalidQueryError
+	if errors.As(err, &invalid) {
+		return ports.QueryPlan{}, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+	}
+	if err != nil {
+		return ports.QueryPlan{}, err
+	}
+	plan := prepared.Explain()
+	steps := make([]ports.PlanStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = ports.PlanStep(step)
+	}
+	return ports.QueryPlan{Plan: plan.Plan, Documents: plan.Documents, Steps: steps}, nil
+}
+
+// ListDimensions returns the metadata keys of the indexed documents, when the index keeps them
+func (a *indexAdapter) ListDimensions(ctx context.Context, filter func(models.Document) bool) []ports.Dimension {
+	lister, ok := a.idx.(index.DimensionLister)
+	if !ok {
+		return nil
+	}
+	if principals, ok := ports.PrincipalsFrom(ctx); ok {
+		ctx = index.WithPrincipals(ctx, principals)
+	}
+	dimensions := lister.ListDimensions(ctx, filter)
+	out := make([]ports.Dimension, len(dimensions))
+	for i, dimension := range dimensions {
+		out[i] = ports.Dimension(dimension)
+	}
+	return out
+}
+
+// TopValues returns the most frequent values of a dimension, when the index keeps them
+func (a *indexAdapter) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ports.ValueCount {
+	lister, ok := a.idx.(index.DimensionLister)
+	if !ok {
+		return nil
+	}
+	if principals, ok := ports.PrincipalsFrom(ctx); ok {
+		ctx = index.WithPrincipals(ctx, principals)
+	}
+	values := lister.TopValues(ctx, dimension, n, filter)
+	out := make([]ports.ValueCount, len(values))
+	for i, value := range values {
+		out[i] = ports.ValueCount(value)
+	}
+	return out
+}
+
+// Diagnostics reports the index's internals, or its document count and approximate size
+func (a *indexAdapter) Diagnostics() map[string]interface{} {
+	if diagnoser, ok := a.idx.(index.Diagnoser); ok {
+		return diagnoser.Diagnostics()
+	}
+	count, _ := a.idx.Count()
+	size, _ := a.idx.Size()
+	return map[string]interface{}{"documents": count, "bytes": size}
+}
+
+// MaxResults returns the cap of the index's config on the results of each search (0: none)
+func (a *indexAdapter) MaxResults() int {
+	if capper, ok := a.idx.(index.ResultCapper); ok {
+		return capper.MaxResults()
+	}
+	return 0
+}
+
+// WarmUp rebuilds the structures the index derives from its documents, when it has any
+func (a *indexAdapter) WarmUp(ctx context.Context) error {
+	if warmer, ok := a.idx.(index.Warmer); ok {
+		return warmer.WarmUp(ctx)
+	}
+	return nil
+}
+
+// HealthCheck checks the index's resources, when it has any that can fail
+func (a *indexAdapter) HealthCheck() error {
+	if checker, ok := a.idx.(index.HealthChecker); ok {
+		return checker.HealthCheck()
+	}
+	return nil
+}
+
+func (a *indexAdapter) PersistenceStats() (ports.PersistenceStats, error) {
+	reporter, ok := a.idx.(index.PersistenceReporter)
+	if !ok {
+		return ports.PersistenceStats{}, fmt.Errorf("persistence stats are %w by the index", ports.ErrNotSupported)
+	}
+	return ports.PersistenceStats(reporter.PersistenceStats()), nil
+}
+
+func (a *indexAdapter) Close() error {
 	return a.idx.Close()
 }
 
-// Adapter for loaders.FilesystemLoader to ports.LoaderPort
+// Adapter for any loaders.CorpusLoader to ports.LoaderPort
 // Only implements required method (Load)
-type filesystemLoaderAdapter struct {
-	loader *loaders.FilesystemLoader
+type corpusLoaderAdapter struct {
+	loader loaders.CorpusLoader
 }
 
-func (a *filesystemLoaderAdapter) Load(source string) ([]interface{}, error) {
+func (a *corpusLoaderAdapter) Load(source string) ([]interface{}, error) {
 	docs, err := a.loader.Load()
 	if err != nil {
 		return nil, err
@@ -70,14 +313,57 @@ func (a *filesystemLoaderAdapter) Load(source string) ([]interface{}, error) {
 	return out, nil
 }
 
-// LoaderConfig represents a loader configuration from the starter config
-// Example: { "name": "filesystem", "type": "FilesystemLoader", "config": { "root": "." } }
-type LoaderConfig struct {
+// Adapter for any loaders.CorpusLoader to ports.StreamingLoaderPort
+type streamingLoaderAdapter struct {
+	loader loaders.CorpusLoader
+}
+
+func (a *streamingLoaderAdapter) Load(ctx context.Context) (<-chan models.Document, <-chan error) {
+	return loaders.Stream(ctx, a.loader)
+}
+
+// IndexConfig represents an index configuration from the starter config
+// Type is one of "simple", "persisted", "inverted", "vector" or "remote"; Config holds per-index options
+// Example: { "name": "docs", "type": "persisted", "config": { "db_path": "./data/index.db" } }
+type IndexConfig struct {
 	Name   string                 `json:"name"`
 	Type   string                 `json:"type"`
 	Config map[string]interface{} `json:"config"`
 }
 
+// defaultIndexes is used when the starter config does not list any indexes
+var defaultIndexes = []IndexConfig{
+	{Name: "simple", Type: "simple", Config: map[string]interface{}{
+		"max_results": 10,
+		"dimensions":  []string{"fileSize", "lastModified", "fileExtension"},
+	}},
+}
+
+// LoaderConfig represents a loader configuration from the starter config
+// Example: { "name": "filesystem", "type": "FilesystemLoader", "index": "simple", "config": { "root": "." }, "schedule": { "interval": "10m" } }
+type LoaderConfig struct {
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Index    string                 `json:"index,omitempty"` // Index the loaded documents go into (default: the first index)
+	Config   map[string]interface{} `json:"config"`
+	Schedule *ScheduleConfig        `json:"schedule,omitempty"`
+	Features []string               `json:"features,omitempty"` // Feature extractors applied to the documents (default: all configured; [] for none)
+}
+
+// ScheduleConfig represents a periodic refresh of a loader
+// Example: { "interval": "10m" }
+type ScheduleConfig struct {
+	Interval string `json:"interval"`
+}
+
+// shutdownTimeout bounds how long a graceful shutdown may take
+const shutdownTimeout = 30 * time.Second
+
+// defaultLoaders is used when the starter config does not list any loaders
+var defaultLoaders = []LoaderConfig{
+	{Name: "filesystem", Type: "FilesystemLoader", Config: map[string]interface{}{"root": "."}},
+}
+
 // APIConfig represents an API configuration from the starter config
 // Example: { "name": "graphql", "type": "GraphQL", "config": { "listen": ":8080" } }
 type APIConfig struct {
@@ -86,111 +372,734 @@ type APIConfig struct {
 	Config map[string]interface{} `json:"config"`
 }
 
+// defaultAPIs is used when the starter config does not list any APIs
+var defaultAPIs = []APIConfig{
+	{Name: "graphql", Type: "GraphQL", Config: map[string]interface{}{"listen": ":8080"}},
+}
+
+// FeatureConfig represents a feature extractor configuration from the starter config.
+// Built-in extractors (filesystem, content, mime, hash, keywords, media, tfidf, embedding) accept "enabled",
+// "weight", "normalize", "vectorize", "parameters", "feature_map", "meta"/"vector" to choose where
+// the features are merged, "normalization"/"normalizer_path" to scale vectors with corpus statistics, and
+// "parallelism"/"timeout" to extract documents concurrently, and "cache" to reuse the features of unchanged
+// documents; any other name must be a registered extractor such as a plugin.
+// Example: { "name": "filesystem", "config": { "weight": 0.5, "vector": false } }
+type FeatureConfig struct {
+	Name   string                 `json:"name"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// FeatureCacheConfig configures the cache used by feature extractors with "cache": true. Without a
+// db_path the cache is only held in memory.
+// Example: { "size": 50000, "db_path": "./data/features.db" }
+type FeatureCacheConfig struct {
+	Size   int    `json:"size,omitempty"` // Entries kept in memory (default: features.DefaultFeatureCacheSize)
+	DBPath string `json:"db_path,omitempty"`
+}
+
+// WebhookConfig represents a webhook notified of engine events
+// Example: { "name": "ci", "config": { "url": "https://example.com/hook", "events": ["loader_completed"], "retries": 3 } }
+type WebhookConfig struct {
+	Name   string                 `json:"name"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// SearchConfig configures the middlewares applied to every search and their deadline
+// Example: { "log": true, "timeout": "2s", "rate_limit": { "per_second": 50, "burst": 100 } }
+type SearchConfig struct {
+	Log       bool             `json:"log,omitempty"`
+	Timeout   string           `json:"timeout,omitempty"` // Searches running longer are aborted (default: none)
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+	QueryLog  *QueryLogConfig  `json:"query_log,omitempty"`
+	// SavedSearches is the bbolt database of saved searches (default: saved searches disabled)
+	SavedSearches string `json:"saved_searches,omitempty"`
+}
+
+// QueryLogConfig writes a JSON line per search to a file rotated by size and age. Searches taking at
+// least slow_threshold are logged with their evaluation details.
+// Example: { "path": "./logs/queries.log", "slow_threshold": "250ms", "max_size_mb": 100, "max_age": "168h", "max_backups": 10 }
+type QueryLogConfig struct {
+	Path          string `json:"path"`
+	SlowThreshold string `json:"slow_threshold,omitempty"`
+	MaxSizeMB     int    `json:"max_size_mb,omitempty"` // Size a file is rotated at (default 100)
+	MaxAge        string `json:"max_age,omitempty"`     // Age rotated files are deleted at, rounded up to days (default: kept)
+	MaxBackups    int    `json:"max_backups,omitempty"` // Rotated files kept (default: all)
+	Compress      bool   `json:"compress,omitempty"`    // Gzip rotated files
+}
+
+// RateLimitConfig limits the number of searches per second across all APIs
+type RateLimitConfig struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst"`
+}
+
+// SnapshotConfig sets where the admin APIs write index snapshots (without it, snapshots are disabled),
+// and optionally snapshots the indexes on a schedule and restores them on startup
+// Example: { "dir": "./snapshots", "interval": "1h", "retain": 24, "restore_on_start": true }
+type SnapshotConfig struct {
+	Dir            string   `json:"dir"`
+	Interval       string   `json:"interval,omitempty"`         // Time between scheduled snapshots (default: none scheduled)
+	Retain         int      `json:"retain,omitempty"`           // Snapshots kept per index (default: all)
+	Indexes        []string `json:"indexes,omitempty"`          // Indexes snapshotted and restored (default: all that can be exported)
+	RestoreOnStart bool     `json:"restore_on_start,omitempty"` // Restore empty indexes from their latest snapshots
+}
+
+// snapshotDir returns the snapshot directory of the config (which may be nil)
+func snapshotDir(cfg *SnapshotConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Dir
+}
+
+// snapshotOptions converts the snapshot config (which may be nil) to engine options
+func snapshotOptions(cfg *SnapshotConfig) (engine.SnapshotOptions, error) {
+	var options engine.SnapshotOptions
+	if cfg == nil {
+		return options, nil
+	}
+	if cfg.Retain < 0 {
+		return options, fmt.Errorf("snapshots retain must not be negative, got %d", cfg.Retain)
+	}
+	options = engine.SnapshotOptions{Retain: cfg.Retain, Indexes: cfg.Indexes, RestoreOnStart: cfg.RestoreOnStart}
+	if cfg.Interval != "" {
+		interval, err := time.ParseDuration(cfg.Interval)
+		if err != nil || interval <= 0 {
+			return options, fmt.Errorf("invalid snapshots interval %q: must be a positive duration", cfg.Interval)
+		}
+		options.Interval = interval
+	}
+	return options, nil
+}
+
+// HealthConfig sets the limits of the /healthz and /readyz checks
+// Example: { "max_memory_mb": 2048, "stuck_after": "1h" }
+type HealthConfig struct {
+	MaxMemoryMB int    `json:"max_memory_mb,omitempty"` // Memory the Go runtime may hold (default: GOMEMLIMIT, if set)
+	StuckAfter  string `json:"stuck_after,omitempty"`   // Duration of a loader run after which it counts as stuck (default 30m)
+}
+
+// healthOptions converts the health config (which may be nil) to engine options
+func healthOptions(cfg *HealthConfig) (engine.HealthOptions, error) {
+	var options engine.HealthOptions
+	if cfg == nil {
+		return options, nil
+	}
+	if cfg.MaxMemoryMB < 0 {
+		return options, fmt.Errorf("health max_memory_mb must not be negative, got %d", cfg.MaxMemoryMB)
+	}
+	options.MemoryLimit = uint64(cfg.MaxMemoryMB) << 20
+	if cfg.StuckAfter != "" {
+		stuckAfter, err := time.ParseDuration(cfg.StuckAfter)
+		if err != nil {
+			return options, fmt.Errorf("invalid health stuck_after %q: %w", cfg.StuckAfter, err)
+		}
+		options.StuckAfter = stuckAfter
+	}
+	return options, nil
+}
+
+// WarmUpConfig sets the warm-up phase run on startup, before the APIs report ready
+// Example: { "rebuild": true, "queries": ["fileExtension=go"], "timeout": "2m" }
+type WarmUpConfig struct {
+	Rebuild     bool     `json:"rebuild,omitempty"`     // Rebuild the ACL bitmaps, metadata catalogs and postings of the indexes
+	Parallelism int      `json:"parallelism,omitempty"` // Indexes rebuilt at once (default: one per CPU)
+	Queries     []string `json:"queries,omitempty"`     // Queries run against the default index
+	Timeout     string   `json:"timeout,omitempty"`     // Deadline of the whole warm-up (default: none)
+}
+
+// warmUpOptions converts the warm-up config (which may be nil) to engine options
+func warmUpOptions(cfg *WarmUpConfig) (engine.WarmUpOptions, error) {
+	var options engine.WarmUpOptions
+	if cfg == nil {
+		return options, nil
+	}
+	if cfg.Parallelism < 0 {
+		return options, fmt.Errorf("warm_up parallelism must not be negative, got %d", cfg.Parallelism)
+	}
+	options = engine.WarmUpOptions{Rebuild: cfg.Rebuild, Parallelism: cfg.Parallelism, Queries: cfg.Queries}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return options, fmt.Errorf("invalid warm_up timeout %q: %w", cfg.Timeout, err)
+		}
+		options.Timeout = timeout
+	}
+	return options, nil
+}
+
 // StarterConfig holds the structure for the starter JSON config
-// Only index config is used for now, but features can be extended
-// as needed.
 type StarterConfig struct {
-	Index   map[string]interface{} `json:"indexes"`
-	Loaders []LoaderConfig         `json:"loaders"`
-	Apis    []APIConfig            `json:"apis"`
-	// Features map[string]features.ExtractorConfig `json:"features"` // Uncomment if you want to support feature config
+	Indexes       []IndexConfig        `json:"indexes"`
+	Loaders       []LoaderConfig       `json:"loaders"`
+	Apis          []APIConfig          `json:"apis"`
+	Features      []FeatureConfig      `json:"features,omitempty"`
+	FeatureCache  *FeatureCacheConfig  `json:"feature_cache,omitempty"`
+	FeatureSchema *FeatureSchemaConfig `json:"feature_schema,omitempty"`
+	Webhooks      []WebhookConfig      `json:"webhooks,omitempty"`
+	Search        *SearchConfig        `json:"search,omitempty"`
+	Health        *HealthConfig        `json:"health,omitempty"`
+	WarmUp        *WarmUpConfig        `json:"warm_up,omitempty"`
+	Auth          *api.AuthConfig      `json:"auth,omitempty"`   // API keys and JWT validation, enforced by every API
+	Limits        *api.LimitsConfig    `json:"limits,omitempty"` // Per-client rate limits and request body caps of every API
+	Snapshots     *SnapshotConfig      `json:"snapshots,omitempty"`
+	Cluster       *ClusterConfig       `json:"cluster,omitempty"`
+	// Telemetry exports OpenTelemetry traces of loading, extraction, indexing and searches (see telemetry.Setup)
+	// Example: { "exporter": "otlp", "protocol": "grpc", "endpoint": "localhost:4317", "insecure": true }
+	Telemetry map[string]interface{} `json:"telemetry,omitempty"`
+
+	unresolved map[string]interface{} // The config read, with its secret references unresolved (see config.HideSecrets)
 }
 
-func loadStarterConfig(path string) (*StarterConfig, error) {
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
+// withDefaults fills the sections missing from a starter config (which may be nil) with the defaults
+func withDefaults(cfg *StarterConfig) *StarterConfig {
+	resolved := StarterConfig{}
+	if cfg != nil {
+		resolved = *cfg
+	}
+	if len(resolved.Indexes) == 0 {
+		resolved.Indexes = defaultIndexes
+	}
+	if len(resolved.Loaders) == 0 {
+		resolved.Loaders = defaultLoaders
+	}
+	if len(resolved.Apis) == 0 {
+		resolved.Apis = defaultAPIs
+	}
+	return &resolved
+}
+
+// starterSource is the config adapter the starter config at path is read from: the file, in JSON, YAML
+// or TOML, or the key of an etcd:// or consul:// URL, layered over the default indexes, loaders and APIs
+// and under BITSCOUT_* environment overrides
+func starterSource(path string) config.Source {
+	source := config.Open(path)
+	defaults := StarterConfig{Indexes: defaultIndexes, Loaders: defaultLoaders, Apis: defaultAPIs}
+	if data, err := json.Marshal(defaults); err == nil {
+		var layer map[string]interface{}
+		if json.Unmarshal(data, &layer) == nil {
+			source.SetDefaults(layer)
+		}
+	}
+	return source
+}
+
+// readStarterConfig loads the starter config from source, logging the problems of its sections
+func readStarterConfig(source config.Source) (*StarterConfig, error) {
+	if err := source.Load(); err != nil {
 		return nil, err
 	}
 	var cfg StarterConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := source.Decode(&cfg); err != nil {
 		return nil, err
 	}
+	cfg.unresolved = source.Unresolved()
+	warnConfigIssues(source.Name(), source.GetConfig())
 	return &cfg, nil
 }
 
+// registerIndexes instantiates every configured index and registers it with the engine.
+// The first index becomes the engine's default index.
+func registerIndexes(core *engine.EngineCore, configs []IndexConfig) (map[string]index.Index, error) {
+	factory := index.NewIndexFactory()
+	indexes := make(map[string]index.Index, len(configs))
+	for _, ic := range configs {
+		if _, exists := indexes[ic.Name]; exists {
+			closeIndexes(indexes)
+			return nil, fmt.Errorf("duplicate index name %s", ic.Name)
+		}
+		idx, err := createIndex(core, factory, ic)
+		if err != nil {
+			closeIndexes(indexes)
+			return nil, err
+		}
+		indexes[ic.Name] = idx
+	}
+	return indexes, nil
+}
+
+// indexProvider instantiates the indexes created at runtime through the admin APIs
+func indexProvider(factory *index.IndexFactory) engine.IndexProvider {
+	return func(spec ports.IndexSpec) (ports.IndexPort, error) {
+		idx, err := factory.Create(spec.Type, spec.Config)
+		if err != nil {
+			return nil, err
+		}
+		return &indexAdapter{idx: idx}, nil
+	}
+}
+
+// createIndex instantiates a single index and registers it with the engine
+func createIndex(core *engine.EngineCore, factory *index.IndexFactory, ic IndexConfig) (index.Index, error) {
+	idx, err := factory.Create(ic.Type, ic.Config)
+	if err != nil {
+		return nil, fmt.Errorf("index %s: %w", ic.Name, err)
+	}
+	core.RegisterIndex(ic.Name, &indexAdapter{idx: idx})
+	return idx, nil
+}
+
+// closeIndexes closes every index, flushing persisted ones to disk
+func closeIndexes(indexes map[string]index.Index) {
+	for name, idx := range indexes {
+		if err := idx.Close(); err != nil {
+			log.Error().Msgf("Error closing index %s: %s", name, err)
+		}
+	}
+}
+
+// registerLoaders instantiates every configured loader and registers it with the registry and the engine
+func registerLoaders(core *engine.EngineCore, registry *loaders.LoaderRegistry, factory *loaders.LoaderFactory, configs []LoaderConfig) error {
+	for _, lc := range configs {
+		if err := createLoader(core, registry, factory, lc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createLoader instantiates a single loader and registers it with the registry and the engine
+func createLoader(core *engine.EngineCore, registry *loaders.LoaderRegistry, factory *loaders.LoaderFactory, lc LoaderConfig) error {
+	loader, err := factory.Create(lc.Type, lc.Config)
+	if err != nil {
+		return fmt.Errorf("loader %s: %w", lc.Name, err)
+	}
+	registry.Register(lc.Name, loader)
+	core.RegisterLoader(lc.Name, &corpusLoaderAdapter{loader: loader})
+	core.RegisterStreamingLoader(lc.Name, &streamingLoaderAdapter{loader: loader})
+	if incremental, ok := loader.(loaders.IncrementalCorpusLoader); ok {
+		core.RegisterIncrementalLoader(lc.Name, incremental)
+	}
+	return nil
+}
+
+// registerPlugins starts the plugins found in dir, adding their loaders to the loader factory
+// and their feature extractors to the engine
+func registerPlugins(core *engine.EngineCore, factory *loaders.LoaderFactory, dir string) (*plugins.Manager, error) {
+	manager := plugins.NewManager()
+	if err := manager.Discover(dir); err != nil {
+		return nil, err
+	}
+	manager.RegisterLoaderTypes(factory)
+	for name, extractor := range manager.Extractors() {
+		core.RegisterFeatureExtractor(name, extractor)
+	}
+	return manager, nil
+}
+
+// compileFilter compiles a role's document filter written as a boolean query (e.g. "directory contains public")
+func compileFilter(expression string) (func(doc models.Document) bool, error) {
+	query, err := index.ParseQuery(expression)
+	if err != nil {
+		return nil, err
+	}
+	return func(doc models.Document) bool {
+		matches, err := query.Evaluate(doc)
+		return err == nil && matches
+	}, nil
+}
+
+// registerAPIs instantiates every configured API on top of the engine and registers it with the engine.
+// With an auth config every API requires credentials; with a limits config every API enforces them.
+func registerAPIs(core *engine.EngineCore, configs []APIConfig, auth *api.AuthConfig, limits *api.LimitsConfig) error {
+	factory := api.NewAPIFactory()
+	if auth != nil {
+		authenticator, err := api.NewAuthenticator(*auth, compileFilter)
+		if err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+		factory.SetAuthenticator(authenticator)
+	}
+	if limits != nil {
+		limiter, err := api.NewLimiter(*limits)
+		if err != nil {
+			return fmt.Errorf("limits: %w", err)
+		}
+		factory.SetLimiter(limiter)
+	}
+	for _, ac := range configs {
+		adapter, err := factory.Create(ac.Type, core, ac.Config)
+		if err != nil {
+			return fmt.Errorf("api %s: %w", ac.Name, err)
+		}
+		core.RegisterAPI(ac.Name, adapter)
+	}
+	return nil
+}
+
+// registerWebhooks subscribes every configured webhook to the engine's events
+func registerWebhooks(core *engine.EngineCore, configs []WebhookConfig) error {
+	for _, wc := range configs {
+		hook, err := webhooks.NewWebhookFromConfig(wc.Config)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %w", wc.Name, err)
+		}
+		core.RegisterEventSubscriber(hook)
+		log.Info().Msgf("Registered webhook %s", wc.Name)
+	}
+	return nil
+}
+
+// registerSearchMiddlewares installs the configured search middlewares, then the normalization of
+// vector queries by the feature extractors
+func registerSearchMiddlewares(core *engine.EngineCore, cfg *SearchConfig, extractors *featureExtractors) error {
+	if cfg == nil {
+		cfg = &SearchConfig{}
+	}
+	if cfg.Log {
+		core.UseSearchMiddleware(engine.LogSearches())
+	}
+	if cfg.RateLimit != nil {
+		if cfg.RateLimit.PerSecond <= 0 {
+			return fmt.Errorf("search rate limit must be positive, got %g", cfg.RateLimit.PerSecond)
+		}
+		core.UseSearchMiddleware(engine.RateLimit(cfg.RateLimit.PerSecond, cfg.RateLimit.Burst))
+	}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("search timeout must be a positive duration, got %q", cfg.Timeout)
+		}
+		core.SetSearchTimeout(timeout)
+	}
+	core.UseSearchMiddleware(engine.RewriteQuery(extractors.normalizeQuery))
+	return nil
+}
+
+// registerPipelines wires every configured loader to its index through its feature extractors.
+// Scheduled loaders are loaded incrementally from the start so their periodic refreshes only apply diffs.
+func registerPipelines(core *engine.EngineCore, configs []LoaderConfig, extractors *featureExtractors, batchSize int) error {
+	for _, lc := range configs {
+		pipeline, err := pipelineFor(lc, extractors, batchSize)
+		if err != nil {
+			return err
+		}
+		if err := core.AddPipeline(pipeline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pipelineFor builds the engine pipeline of a configured loader
+func pipelineFor(lc LoaderConfig, extractors *featureExtractors, batchSize int) (engine.Pipeline, error) {
+	pipeline := engine.Pipeline{Loader: lc.Name, Index: lc.Index, BatchSize: batchSize}
+	extractors.wire(&pipeline, lc)
+	if lc.Schedule != nil {
+		interval, err := time.ParseDuration(lc.Schedule.Interval)
+		if err != nil {
+			return pipeline, fmt.Errorf("invalid schedule interval %q for loader %s: %w", lc.Schedule.Interval, lc.Name, err)
+		}
+		pipeline.Interval = interval
+	}
+	return pipeline, nil
+}
+
+// subcommands run instead of the search engine when named as the first argument, e.g. `bitscout export -index docs`
+var subcommands = map[string]func(args []string) error{
+	"export":  runExport,
+	"import":  runImport,
+	"bench":   runBench,
+	"config":  runConfig,
+	"cluster": runCluster,
+	"tui":     runTUI,
+	"search":  runSearch,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Error().Msgf("bitscout %s: %s", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	serve("bitscout", os.Args[1:], false)
+}
+
+// runTUI implements `bitscout tui`: the search engine with a terminal dashboard instead of the prompt
+func runTUI(args []string) error {
+	serve("tui", args, true)
+	return nil
+}
+
+// serve runs the search engine until it is interrupted or the user quits the interactive search,
+// which is the dashboard when requested and the prompt otherwise
+func serve(name string, args []string, dashboard bool) {
 	log.Info().Msg("Starting bitscout")
 
 	// Parse flags
-	daemon := flag.Bool("daemon", false, "Run as a background daemon (no interactive search)")
-	configPath := flag.String("config", "config/starter_config.json", "Path to starter config JSON file")
-	flag.Parse()
-
-	// Initialize EngineCore
-	core := engine.NewEngineCore()
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	daemon := flags.Bool("daemon", false, "Run as a background daemon (no interactive search)")
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file, or etcd:// or consul:// URL of a key holding it")
+	batchSize := flags.Int("batch-size", engine.DefaultBatchSize, "Number of documents indexed per batch while loading")
+	watchInterval := flags.Duration("watch", config.DefaultWatchInterval, "How often to check the config file for changes, or to retry watching a remote config (0 disables; SIGHUP always reloads)")
+	pluginsDir := flags.String("plugins", "plugins", "Directory scanned for bitscout-loader-* and bitscout-extractor-* plugin executables")
+	historyPath := flags.String("history", defaultHistoryPath(), "File the interactive search history is kept in (empty: not saved)")
+	pidFile := flags.String("pidfile", "", "File the process ID is written to while running (empty: none)")
+	stopTimeout := flags.Duration("shutdown-timeout", shutdownTimeout, "How long a graceful shutdown may take before bitscout exits anyway")
+	adminAddr := flags.String("admin", "", "Address of the pprof and diagnostics endpoints, e.g. localhost:6060 (empty: disabled)")
+	flags.Parse(args)
 
-	// Initialize loader registry and register loader
-	registry := loaders.NewLoaderRegistry()
-	filesystemLoader := loaders.NewFilesystemLoader(".")
-	registry.Register("filesystem", filesystemLoader)
-	// Register loader with core using adapter
-	core.RegisterLoader("filesystem", &filesystemLoaderAdapter{loader: filesystemLoader})
+	// Exit with a failure status when shutdown was abandoned; registered first so it runs after every other deferred cleanup
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
-	// Load documents
-	documents, err := registry.LoadAll()
-	if err != nil {
-		log.Error().Msgf("Error loading documents: %s", err)
-		return
+	// Claim the PID file before opening any database, so a second instance fails fast instead of blocking
+	if *pidFile != "" {
+		removePIDFile, err := writePIDFile(*pidFile)
+		if err != nil {
+			log.Error().Msgf("Error writing PID file: %s", err)
+			return
+		}
+		defer removePIDFile()
 	}
 
-	log.Info().Msgf("Loaded %d documents", len(documents))
+	// Initialize EngineCore; search subscriptions match new documents like the indexes' searches do
+	core := engine.NewEngineCore()
+	core.SetQueryMatcher(index.Matches)
+	core.SetIndexProvider(indexProvider(index.NewIndexFactory()))
+
+	// Serve the profiler from the start, so slow initial loads can be profiled too
+	if *adminAddr != "" {
+		stopAdmin, err := startAdmin(core, *adminAddr)
+		if err != nil {
+			log.Error().Msgf("Error starting admin endpoints: %s", err)
+			return
+		}
+		defer stopAdmin()
+	}
 
 	// Load starter config
-	cfg, err := loadStarterConfig(*configPath)
+	source := starterSource(*configPath)
+	core.RegisterConfig("starter", source)
+	loaded, err := readStarterConfig(source)
 	if err != nil {
 		log.Warn().Msgf("Could not load config file %s: %s. Using default config.", *configPath, err)
 	}
+	cfg := withDefaults(loaded)
 
-	// Initialize and configure index
-	idx := index.NewSimpleIndex()
-	if cfg != nil && cfg.Index != nil {
-		if err := idx.Configure(cfg.Index); err != nil {
-			log.Error().Msgf("Error configuring index from config file: %s", err)
+	// Install the tracer provider before anything creates spans; spans still buffered are flushed on exit
+	if cfg.Telemetry != nil {
+		shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Telemetry)
+		if err != nil {
+			log.Error().Msgf("Error setting up tracing: %s", err)
 			return
 		}
-	} else {
-		config := map[string]interface{}{
-			"max_results": 10,
-			"dimensions":  []string{"fileSize", "lastModified", "fileExtension"},
-		}
-		if err := idx.Configure(config); err != nil {
-			log.Error().Msgf("Error configuring index: %s", err)
-			return
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), *stopTimeout)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.Error().Msgf("Error flushing traces: %s", err)
+			}
+		}()
+	}
+
+	// Start plugins; their loader types become available to the loader config
+	loaderFactory := loaders.NewLoaderFactory()
+	pluginManager, err := registerPlugins(core, loaderFactory, *pluginsDir)
+	if err != nil {
+		log.Error().Msgf("Error loading plugins: %s", err)
+		return
+	}
+	defer pluginManager.Close()
+
+	// Initialize loader registry and register configured loaders
+	registry := loaders.NewLoaderRegistry()
+	if err := registerLoaders(core, registry, loaderFactory, cfg.Loaders); err != nil {
+		log.Error().Msgf("Error creating loaders: %s", err)
+		return
+	}
+
+	// Initialize configured indexes
+	indexes, err := registerIndexes(core, cfg.Indexes)
+	if err != nil {
+		log.Error().Msgf("Error creating indexes: %s", err)
+		return
+	}
+
+	// From here on the engine owns the indexes and closes them on shutdown
+	defer func() {
+		if err := stopWithin(core, *stopTimeout); err != nil {
+			log.Error().Msgf("Error stopping engine: %s", err)
+			exitCode = 1
 		}
+	}()
+
+	// Feature extractors run over every batch a loader produces, before it is indexed
+	extractors, err := registerFeatures(core, cfg.Features, cfg.FeatureCache)
+	if err != nil {
+		log.Error().Msgf("Error creating feature extractors: %s", err)
+		return
 	}
-	// Register index with core using adapter
-	core.RegisterIndex("simple", &simpleIndexAdapter{idx: idx})
+	defer extractors.close()
 
-	// Add documents to index
-	if err := idx.AddDocuments(documents); err != nil {
-		log.Error().Msgf("Error adding documents to index: %s", err)
+	if err := registerPipelines(core, cfg.Loaders, extractors, *batchSize); err != nil {
+		log.Error().Msgf("Error wiring loaders: %s", err)
 		return
 	}
 
-	// Get index statistics
-	count, err := idx.Count()
+	// Documents indexed with different features than the configured ones are warned about or reindexed
+	schemas, err := checkFeatureSchemas(cfg, extractors, registry)
 	if err != nil {
-		log.Error().Msgf("Error getting index count: %s", err)
-	} else {
-		log.Info().Msgf("Index contains %d documents", count)
+		log.Error().Msgf("Error checking feature schemas: %s", err)
+		return
+	}
+
+	if err := registerAPIs(core, cfg.Apis, cfg.Auth, cfg.Limits); err != nil {
+		log.Error().Msgf("Error creating APIs: %s", err)
+		return
+	}
+
+	if err := registerWebhooks(core, cfg.Webhooks); err != nil {
+		log.Error().Msgf("Error creating webhooks: %s", err)
+		return
 	}
 
-	size, err := idx.Size()
+	if err := registerSearchMiddlewares(core, cfg.Search, extractors); err != nil {
+		log.Error().Msgf("Error configuring search: %s", err)
+		return
+	}
+	health, err := healthOptions(cfg.Health)
 	if err != nil {
-		log.Error().Msgf("Error getting index size: %s", err)
-	} else {
-		log.Info().Msgf("Index size: %d bytes", size)
+		log.Error().Msgf("Error configuring health checks: %s", err)
+		return
 	}
+	core.SetHealthOptions(health)
+	warmUp, err := warmUpOptions(cfg.WarmUp)
+	if err != nil {
+		log.Error().Msgf("Error configuring warm-up: %s", err)
+		return
+	}
+	core.SetWarmUpOptions(warmUp)
+	core.SetSnapshotDir(snapshotDir(cfg.Snapshots))
+	snapshots, err := snapshotOptions(cfg.Snapshots)
+	if err == nil {
+		err = core.SetSnapshotOptions(snapshots)
+	}
+	if err != nil {
+		log.Error().Msgf("Error configuring snapshots: %s", err)
+		return
+	}
+
+	closeQueryLog, err := openQueryLog(core, cfg.Search)
+	if err != nil {
+		log.Error().Msgf("Error opening query log: %s", err)
+		return
+	}
+	defer closeQueryLog()
+
+	closeSavedSearches, err := openSavedSearches(core, cfg.Search)
+	if err != nil {
+		log.Error().Msgf("Error opening saved searches: %s", err)
+		return
+	}
+	defer closeSavedSearches()
+
+	stopCluster, err := startCluster(core, cfg.Cluster)
+	if err != nil {
+		log.Error().Msgf("Error starting cluster: %s", err)
+		return
+	}
+	defer stopCluster()
+
+	// Run initial loads, start the scheduler and the APIs; an interrupt cancels loading and refreshes
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	r := newReloader(core, registry, loaderFactory, extractors, indexes, cfg, *batchSize)
 
+	// The dashboard starts before the initial loads so it can show their progress; quitting it stops them
+	var quit <-chan struct{}
+	if dashboard && !*daemon {
+		var restore func()
+		quit, restore, err = newTUI(core, r, extractors).start(ctx, *historyPath)
+		if err != nil {
+			log.Error().Msgf("Error starting dashboard: %s", err)
+			return
+		}
+		defer restore()
+		go func() {
+			select {
+			case <-quit:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	if err := core.Start(ctx); err != nil {
+		log.Error().Msgf("Error starting engine: %s", err)
+		return
+	}
+	schemas.record()
+
+	// Get index statistics
+	for _, ic := range cfg.Indexes {
+		idx := indexes[ic.Name]
+		count, err := idx.Count()
+		if err != nil {
+			log.Error().Msgf("Error getting index %s count: %s", ic.Name, err)
+		} else {
+			log.Info().Msgf("Index %s contains %d documents", ic.Name, count)
+		}
+
+		size, err := idx.Size()
+		if err != nil {
+			log.Error().Msgf("Error getting index %s size: %s", ic.Name, err)
+		} else {
+			log.Info().Msgf("Index %s size: %d bytes", ic.Name, size)
+		}
+	}
+
+	// Reload the config on SIGHUP or when the file changes
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	var changes <-chan struct{}
+	if *watchInterval > 0 {
+		if remote, ok := source.(*config.Remote); ok {
+			changes = remote.Watch(ctx, *watchInterval)
+		} else {
+			changes = config.Watch(ctx, *configPath, *watchInterval)
+		}
+	}
+
+	// Search interactively unless running as a daemon; quitting the prompt stops bitscout
 	if *daemon {
 		log.Info().Msgf("Running in daemon mode. No interactive search. PID: %d", os.Getpid())
-		// Keep the process alive
-		select {}
-	} else {
-		// Create your API implementation (inject dependencies as needed)
-		gqlAPI := &api.GraphQLAPI{}
-		if err := gqlAPI.Start(); err != nil {
-			log.Error().Msgf("Failed to start GraphQL server: %s", err)
+	} else if !dashboard {
+		var restore func()
+		quit, restore = newREPL(core, r, extractors).start(ctx, *historyPath)
+		defer restore()
+	}
+
+	// Serve until an API fails or the process is interrupted
+	errs := core.Errors()
+	for {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				// Every API has exited (or none are configured); keep refreshing until interrupted
+				errs = nil
+				continue
+			}
+			log.Error().Msgf("API server failed: %s", err)
+			return
+		case <-reloads:
+			log.Info().Msg("Received SIGHUP, reloading config")
+			r.reloadFile(ctx, source)
+		case <-changes:
+			r.reloadFile(ctx, source)
+		case <-quit:
+			log.Info().Msg("Shutting down")
+			return
+		case <-ctx.Done():
+			log.Info().Msg("Shutting down")
+			return
 		}
 	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// defaultQueryLogSizeMB is the size query log files are rotated at unless configured
+const defaultQueryLogSizeMB = 100
+
+// queryAST is the query log representation of a parsed query
+type queryAST struct {
+	Type       string         `json:"type"` // "and" of conditions, or "text" for a full-text search
+	Conditions []queryASTNode `json:"conditions,omitempty"`
+	Text       string         `json:"text,omitempty"` // The query of full-text searches, or the free text before conditions
+}
+
+type queryASTNode struct {
+	Dimension string `json:"dimension"`
+	Operator  string `json:"op"`
+	Value     string `json:"value"`
+}
+
+// parseQueryAST parses a query the way the indexes do: dimension conditions, else full text
+func parseQueryAST(query string) (interface{}, error) {
+	parsed, err := index.ParseQuery(query)
+	if err != nil || len(parsed.Conditions) == 0 {
+		return queryAST{Type: "text", Text: query}, nil
+	}
+	ast := queryAST{Type: "and", Text: parsed.Text}
+	for _, condition := range parsed.Conditions {
+		ast.Conditions = append(ast.Conditions, queryASTNode{Dimension: condition.Dimension, Operator: string(condition.Operator), Value: condition.Value})
+	}
+	return ast, nil
+}
+
+// openQueryLog installs the configured query log on the engine. close flushes and closes its file.
+func openQueryLog(core *engine.EngineCore, cfg *SearchConfig) (close func() error, err error) {
+	if cfg == nil || cfg.QueryLog == nil {
+		return func() error { return nil }, nil
+	}
+	qc := cfg.QueryLog
+	if qc.Path == "" {
+		return nil, fmt.Errorf("query log path is required")
+	}
+	var slow time.Duration
+	if qc.SlowThreshold != "" {
+		if slow, err = time.ParseDuration(qc.SlowThreshold); err != nil {
+			return nil, fmt.Errorf("invalid query log slow_threshold %q: %w", qc.SlowThreshold, err)
+		}
+	}
+	maxAgeDays := 0
+	if qc.MaxAge != "" {
+		maxAge, err := time.ParseDuration(qc.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query log max_age %q: %w", qc.MaxAge, err)
+		}
+		maxAgeDays = int(math.Ceil(maxAge.Hours() / 24))
+	}
+	maxSize := qc.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultQueryLogSizeMB
+	}
+	if err := os.MkdirAll(filepath.Dir(qc.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   qc.Path,
+		MaxSize:    maxSize,
+		MaxAge:     maxAgeDays,
+		MaxBackups: qc.MaxBackups,
+		Compress:   qc.Compress,
+	}
+	core.SetQueryLog(engine.NewQueryLog(file, engine.QueryLogOptions{SlowThreshold: slow, Parse: parseQueryAST}))
+	log.Info().Msgf("Logging queries to %s (slow threshold %s)", qc.Path, slow)
+	return func() error {
+		core.SetQueryLog(nil)
+		return file.Close()
+	}, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/rs/zerolog/log"
+)
+
+// reloader applies starter config changes to a running engine.
+// Index options, loaders and feature extractor settings are applied in place;
+// changes that need new listeners (apis, webhooks, search middlewares), the feature cache and schema or a different index type are logged
+// and left for a restart.
+type reloader struct {
+	core      *engine.EngineCore
+	registry  *loaders.LoaderRegistry
+	factory   *loaders.LoaderFactory
+	features  *featureExtractors
+	indexes   map[string]index.Index
+	batchSize int
+
+	mu      sync.RWMutex // Guards replacing current, which other goroutines read through config
+	current *StarterConfig
+}
+
+func newReloader(core *engine.EngineCore, registry *loaders.LoaderRegistry, factory *loaders.LoaderFactory, features *featureExtractors, indexes map[string]index.Index, current *StarterConfig, batchSize int) *reloader {
+	return &reloader{core: core, registry: registry, factory: factory, features: features, indexes: indexes, current: current, batchSize: batchSize}
+}
+
+// reloadFile re-reads the starter config from source and applies it; an unreadable config leaves the
+// engine unchanged
+func (r *reloader) reloadFile(ctx context.Context, source config.Source) {
+	loaded, err := readStarterConfig(source)
+	if err != nil {
+		log.Error().Msgf("Config reload failed, keeping current config: %s", err)
+		return
+	}
+	r.apply(ctx, withDefaults(loaded))
+}
+
+// apply diffs the next config against the current one and applies the differences.
+// Each change is applied independently so a bad entry does not block the others.
+func (r *reloader) apply(ctx context.Context, next *StarterConfig) {
+	currentIndexes := indexesByName(r.current.Indexes)
+	nextIndexes := indexesByName(next.Indexes)
+	currentLoaders := loadersByName(r.current.Loaders)
+	nextLoaders := loadersByName(next.Loaders)
+	applied := &StarterConfig{
+		Apis:          r.current.Apis,
+		Features:      next.Features,
+		FeatureCache:  r.current.FeatureCache,
+		FeatureSchema: r.current.FeatureSchema,
+		Webhooks:      r.current.Webhooks,
+		Search:        r.current.Search,
+		Telemetry:     r.current.Telemetry,
+		Health:        r.current.Health,
+		Auth:          r.current.Auth,
+		Limits:        r.current.Limits,
+		Snapshots:     r.current.Snapshots,
+		Cluster:       r.current.Cluster,
+	}
+
+	// Indexes are added (or reconfigured) first so new loaders can target them
+	factory := index.NewIndexFactory()
+	for _, ic := range next.Indexes {
+		old, exists := currentIndexes[ic.Name]
+		switch {
+		case !exists:
+			idx, err := createIndex(r.core, factory, ic)
+			if err != nil {
+				log.Error().Msgf("Config reload: %s", err)
+				continue
+			}
+			r.indexes[ic.Name] = idx
+			log.Info().Msgf("Config reload: added index %s", ic.Name)
+		case old.Type != ic.Type:
+			log.Warn().Msgf("Config reload: changing the type of index %s requires a restart", ic.Name)
+			applied.Indexes = append(applied.Indexes, old)
+			continue
+		case !reflect.DeepEqual(old.Config, ic.Config):
+			if err := r.core.ConfigureIndex(ic.Name, ic.Config); err != nil {
+				log.Error().Msgf("Config reload: reconfiguring index %s: %s", ic.Name, err)
+				applied.Indexes = append(applied.Indexes, old)
+				continue
+			}
+			log.Info().Msgf("Config reload: reconfigured index %s", ic.Name)
+		}
+		applied.Indexes = append(applied.Indexes, ic)
+	}
+
+	// Loaders that were removed or changed are torn down; changed ones are then recreated
+	for _, lc := range r.current.Loaders {
+		if next, ok := nextLoaders[lc.Name]; ok && reflect.DeepEqual(lc, next) {
+			applied.Loaders = append(applied.Loaders, lc)
+			continue
+		}
+		if err := r.removeLoader(lc.Name); err != nil {
+			log.Error().Msgf("Config reload: removing loader %s: %s", lc.Name, err)
+			applied.Loaders = append(applied.Loaders, lc)
+			delete(nextLoaders, lc.Name)
+			continue
+		}
+		log.Info().Msgf("Config reload: removed loader %s (its documents stay indexed)", lc.Name)
+	}
+	for _, lc := range next.Loaders {
+		if old, ok := currentLoaders[lc.Name]; ok && reflect.DeepEqual(old, lc) {
+			continue
+		}
+		if _, ok := nextLoaders[lc.Name]; !ok {
+			continue // teardown failed above; the old loader is still running
+		}
+		if err := r.addLoader(ctx, lc); err != nil {
+			log.Error().Msgf("Config reload: adding loader %s: %s", lc.Name, err)
+			continue
+		}
+		applied.Loaders = append(applied.Loaders, lc)
+		log.Info().Msgf("Config reload: added loader %s", lc.Name)
+	}
+
+	// Indexes removed from the config are closed once no loader targets them
+	for _, ic := range r.current.Indexes {
+		if _, ok := nextIndexes[ic.Name]; ok {
+			continue
+		}
+		removed, err := r.core.UnregisterIndex(ic.Name)
+		if err != nil {
+			log.Error().Msgf("Config reload: removing index %s: %s", ic.Name, err)
+			applied.Indexes = append(applied.Indexes, ic)
+			continue
+		}
+		if err := removed.Close(); err != nil {
+			log.Error().Msgf("Config reload: closing index %s: %s", ic.Name, err)
+		}
+		delete(r.indexes, ic.Name)
+		log.Info().Msgf("Config reload: removed index %s", ic.Name)
+	}
+
+	// Feature extractor settings (e.g. weights) are applied to the registered extractors; new extractors
+	// are used by loaders added from now on
+	currentFeatures := make(map[string]FeatureConfig, len(r.current.Features))
+	for _, fc := range r.current.Features {
+		currentFeatures[fc.Name] = fc
+	}
+	for _, fc := range next.Features {
+		if old, ok := currentFeatures[fc.Name]; ok && reflect.DeepEqual(old, fc) {
+			continue
+		}
+		if err := r.features.apply(fc); err != nil {
+			log.Error().Msgf("Config reload: %s", err)
+			continue
+		}
+		log.Info().Msgf("Config reload: reconfigured feature extractor %s", fc.Name)
+	}
+
+	if !reflect.DeepEqual(r.current.Apis, next.Apis) {
+		log.Warn().Msg("Config reload: API changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Auth, next.Auth) {
+		log.Warn().Msg("Config reload: auth changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Limits, next.Limits) {
+		log.Warn().Msg("Config reload: limits changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Webhooks, next.Webhooks) {
+		log.Warn().Msg("Config reload: webhook changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Cluster, next.Cluster) {
+		log.Warn().Msg("Config reload: cluster changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Search, next.Search) {
+		log.Warn().Msg("Config reload: search middleware changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.FeatureCache, next.FeatureCache) {
+		log.Warn().Msg("Config reload: feature cache changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.FeatureSchema, next.FeatureSchema) {
+		log.Warn().Msg("Config reload: feature schema changes require a restart")
+	}
+	if !reflect.DeepEqual(r.current.Health, next.Health) {
+		if options, err := healthOptions(next.Health); err != nil {
+			log.Error().Msgf("Config reload: %s", err)
+		} else {
+			r.core.SetHealthOptions(options)
+			applied.Health = next.Health
+			log.Info().Msg("Config reload: updated health check limits")
+		}
+	}
+	if !reflect.DeepEqual(r.current.Snapshots, next.Snapshots) {
+		options, err := snapshotOptions(next.Snapshots)
+		if err == nil {
+			err = r.core.SetSnapshotOptions(options)
+		}
+		if err != nil {
+			log.Error().Msgf("Config reload: %s", err)
+		} else {
+			r.core.SetSnapshotDir(snapshotDir(next.Snapshots))
+			applied.Snapshots = next.Snapshots
+			log.Info().Msgf("Config reload: snapshot directory set to %q, scheduled every %s", snapshotDir(next.Snapshots), options.Interval)
+		}
+	}
+	if !reflect.DeepEqual(r.current.Telemetry, next.Telemetry) {
+		log.Warn().Msg("Config reload: telemetry changes require a restart")
+	}
+
+	r.mu.Lock()
+	r.current = applied
+	r.mu.Unlock()
+	log.Info().Msg("Config reload complete")
+}
+
+// config returns the config currently in effect
+func (r *reloader) config() *StarterConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// addLoader creates a loader, wires its pipeline and runs its initial load
+func (r *reloader) addLoader(ctx context.Context, lc LoaderConfig) error {
+	pipeline, err := pipelineFor(lc, r.features, r.batchSize)
+	if err != nil {
+		return err
+	}
+	if err := createLoader(r.core, r.registry, r.factory, lc); err != nil {
+		return err
+	}
+	if err := r.core.AddPipeline(pipeline); err != nil {
+		r.unregisterLoader(lc.Name)
+		return err
+	}
+	if err := r.core.StartPipeline(ctx, lc.Name); err != nil {
+		// Roll back so the next reload retries from scratch
+		r.removeLoader(lc.Name)
+		return err
+	}
+	return nil
+}
+
+// removeLoader stops a loader's pipeline and unregisters it
+func (r *reloader) removeLoader(name string) error {
+	if err := r.core.RemovePipeline(name); err != nil {
+		return err
+	}
+	return r.unregisterLoader(name)
+}
+
+func (r *reloader) unregisterLoader(name string) error {
+	r.registry.Unregister(name)
+	return r.core.UnregisterLoader(name)
+}
+
+func indexesByName(configs []IndexConfig) map[string]IndexConfig {
+	byName := make(map[string]IndexConfig, len(configs))
+	for _, ic := range configs {
+		byName[ic.Name] = ic
+	}
+	return byName
+}
+
+func loadersByName(configs []LoaderConfig) map[string]LoaderConfig {
+	byName := make(map[string]LoaderConfig, len(configs))
+	for _, lc := range configs {
+		byName[lc.Name] = lc
+	}
+	return byName
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+const (
+	replPrompt       = "bitscout> "
+	replHistorySize  = 500 // Lines kept in the REPL history
+	replDefaultLimit = 10  // Results printed per search
+)
+
+// replCommands lists the REPL commands with their help text, in help order
+var replCommands = []struct{ name, help string }{
+	{":help", "show this help"},
+	{":stats", "show document counts and loader statuses"},
+	{":config", "show the starter config in effect"},
+	{":load", "reload every loader, or the named ones (:load docs mail)"},
+	{":dims", "list the known dimensions"},
+	{":limit", "set the number of results shown (:limit 20)"},
+	{":quit", "stop bitscout (also Ctrl-D or Ctrl-C)"},
+}
+
+// repl is the interactive search prompt of the non-daemon mode. Lines are searched against the
+// default index; lines starting with ':' are commands. Dimension names are completed with tab.
+type repl struct {
+	core       *engine.EngineCore
+	reloader   *reloader
+	extractors *featureExtractors
+	out        io.Writer
+	limit      int
+
+	mu         sync.Mutex
+	dimensions map[string]bool // Known dimensions: built-ins, configured ones, features and result metadata
+}
+
+func newREPL(core *engine.EngineCore, r *reloader, extractors *featureExtractors) *repl {
+	return &repl{core: core, reloader: r, extractors: extractors, out: os.Stdout, limit: replDefaultLimit}
+}
+
+// start runs the REPL on stdin in the background; done is closed once the user quits. On a terminal,
+// input is line edited with history and completion, and log output is routed through the prompt so
+// it does not garble the line being typed. restore must be called before exiting to reset the terminal.
+// Piped input is read line by line; reaching its end stops the REPL but not the engine.
+func (r *repl) start(ctx context.Context, historyPath string) (done <-chan struct{}, restore func()) {
+	quit := make(chan struct{})
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		go func() {
+			if r.runLines(ctx, os.Stdin) {
+				close(quit)
+			}
+		}()
+		return quit, func() {}
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		log.Warn().Msgf("Interactive search disabled: %s", err)
+		return nil, func() {}
+	}
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, replPrompt)
+	history := openHistory(historyPath, replHistorySize)
+	terminal.History = history
+	terminal.AutoCompleteCallback = r.complete
+	r.out = terminal
+	logger := log.Logger
+	log.Logger = logger.Output(terminal)
+
+	go func() {
+		defer close(quit)
+		for {
+			line, err := terminal.ReadLine()
+			if err != nil {
+				return // Ctrl-D, Ctrl-C or a closed input
+			}
+			if r.exec(ctx, line) {
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return quit, func() {
+		once.Do(func() {
+			log.Logger = logger
+			history.close()
+			if err := term.Restore(fd, state); err != nil {
+				log.Error().Msgf("Error restoring terminal: %s", err)
+			}
+		})
+	}
+}
+
+// runLines executes every line of input and reports whether one of them asked to quit
+func (r *repl) runLines(ctx context.Context, input io.Reader) bool {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if r.exec(ctx, scanner.Text()) {
+			return true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error().Msgf("Error reading search input: %s", err)
+	}
+	return false
+}
+
+// exec runs a command or a search and reports whether the REPL should quit
+func (r *repl) exec(ctx context.Context, line string) (quit bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, ":") {
+		r.search(line)
+		return false
+	}
+
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":help", ":h", ":?":
+		r.help()
+	case ":stats":
+		r.stats()
+	case ":config":
+		r.config()
+	case ":load":
+		r.load(ctx, fields[1:])
+	case ":dims":
+		fmt.Fprintln(r.out, strings.Join(r.knownDimensions(), "  "))
+	case ":limit":
+		r.setLimit(fields[1:])
+	case ":quit", ":q", ":exit":
+		return true
+	default:
+		fmt.Fprintf(r.out, "Unknown command %s; type :help for the list of commands\n", fields[0])
+	}
+	return false
+}
+
+func (r *repl) help() {
+	fmt.Fprintln(r.out, "Type a query to search the default index, e.g. fileExtension=go and fileSize>1000, or free text.")
+	for _, command := range replCommands {
+		fmt.Fprintf(r.out, "  %-8s %s\n", command.name, command.help)
+	}
+	fmt.Fprintln(r.out, "Tab completes dimension names and commands; up/down browse the history.")
+}
+
+// search runs a query and prints its results. The dimensions of a dimension query are printed
+// with each result.
+func (r *repl) search(query string) {
+	started := time.Now()
+	results, err := r.core.Search(ports.SearchQuery{Query: query, Caller: "repl"})
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %s\n", err)
+		return
+	}
+	elapsed := time.Since(started)
+
+	var shown []string
+	if parsed, err := index.ParseQuery(query); err == nil {
+		for _, condition := range parsed.Conditions {
+			shown = append(shown, condition.Dimension)
+		}
+	}
+	for i, doc := range results.Documents {
+		r.learn(doc)
+		if i < r.limit {
+			fmt.Fprintln(r.out, formatResult(i+1, doc, shown))
+		}
+	}
+	if hidden := len(results.Documents) - r.limit; hidden > 0 {
+		fmt.Fprintf(r.out, "... and %d more (:limit to show more)\n", hidden)
+	}
+	fmt.Fprintf(r.out, "%d results in %s\n", len(results.Documents), elapsed.Round(time.Microsecond))
+}
+
+// formatResult renders a search result as its rank, source and ID, followed by the requested dimensions
+func formatResult(rank int, doc models.Document, dimensions []string) string {
+	source := doc.Source
+	if source == "" {
+		source = doc.Meta["filename"]
+	}
+	line := fmt.Sprintf("%3d. %s [%s]", rank, source, doc.ID)
+	for _, dimension := range dimensions {
+		if value, ok := doc.Meta[dimension]; ok {
+			line += fmt.Sprintf("\n     %s=%s", dimension, value)
+		}
+	}
+	return line
+}
+
+func (r *repl) stats() {
+	stats, err := r.core.Stats()
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %s\n", err)
+		return
+	}
+	fmt.Fprintf(r.out, "Documents: %d\n", stats.NumDocuments)
+	for _, status := range stats.Indexes {
+		fmt.Fprintf(r.out, "Index %s: %d documents\n", status.Name, status.NumDocuments)
+	}
+	for _, status := range stats.Loaders {
+		line := fmt.Sprintf("Loader %s -> %s: every %s, %d runs", status.Name, status.Index, status.Interval, status.Runs)
+		if !status.LastRun.IsZero() {
+			line += fmt.Sprintf(", last %s (+%d ~%d -%d)", status.LastRun.Format(time.RFC3339), status.Added, status.Modified, status.Deleted)
+		}
+		if status.Running {
+			line += ", running"
+		}
+		if status.LastError != "" {
+			line += ", error: " + status.LastError
+		}
+		fmt.Fprintln(r.out, line)
+	}
+}
+
+// config prints the starter config in effect, with the values read from secrets shown as their references
+func (r *repl) config() {
+	cfg := r.reloader.config()
+	data, err := json.Marshal(cfg)
+	var shown map[string]interface{}
+	if err == nil {
+		err = json.Unmarshal(data, &shown)
+	}
+	if err == nil {
+		config.HideSecrets(shown, cfg.unresolved)
+		data, err = json.MarshalIndent(shown, "", "  ")
+	}
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %s\n", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(data))
+}
+
+// load reruns the pipelines of the named loaders (default: every configured loader)
+func (r *repl) load(ctx context.Context, names []string) {
+	if len(names) == 0 {
+		for _, lc := range r.reloader.config().Loaders {
+			names = append(names, lc.Name)
+		}
+	}
+	for _, name := range names {
+		started := time.Now()
+		if err := r.core.StartPipeline(ctx, name); err != nil {
+			fmt.Fprintf(r.out, "Error loading %s: %s\n", name, err)
+			continue
+		}
+		fmt.Fprintf(r.out, "Loaded %s in %s\n", name, time.Since(started).Round(time.Millisecond))
+	}
+}
+
+func (r *repl) setLimit(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(r.out, "Showing %d results per search\n", r.limit)
+		return
+	}
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit <= 0 {
+		fmt.Fprintf(r.out, "Invalid limit %s; expected a positive number\n", args[0])
+		return
+	}
+	r.limit = limit
+}
+
+// learn adds the metadata keys of a result to the known dimensions
+func (r *repl) learn(doc models.Document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dimensions == nil {
+		r.dimensions = r.configuredDimensions()
+	}
+	for key := range doc.Meta {
+		r.dimensions[key] = true
+	}
+}
+
+// knownDimensions returns the known dimension names, sorted
+func (r *repl) knownDimensions() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dimensions == nil {
+		r.dimensions = r.configuredDimensions()
+	}
+	names := make([]string, 0, len(r.dimensions))
+	for name := range r.dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configuredDimensions collects the dimensions known before any search: those every document has, the
+// "dimensions" of the configured indexes and the features of the configured extractors
+func (r *repl) configuredDimensions() map[string]bool {
+	dimensions := map[string]bool{"filename": true, "path": true, "text": true}
+	for _, ic := range r.reloader.config().Indexes {
+		switch listed := ic.Config["dimensions"].(type) {
+		case []string:
+			for _, name := range listed {
+				dimensions[name] = true
+			}
+		case []interface{}:
+			for _, name := range listed {
+				if s, ok := name.(string); ok {
+					dimensions[s] = true
+				}
+			}
+		}
+	}
+	for _, extractor := range r.extractors.registry.Schema(r.extractors.registry.ListExtractors()).Extractors {
+		for _, feature := range extractor.Features {
+			dimensions[feature] = true
+		}
+	}
+	return dimensions
+}
+
+// complete is the terminal's tab completion: the word before the cursor is completed to the longest
+// prefix shared by the matching commands (at the start of the line) or dimensions
+func (r *repl) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	start := strings.LastIndexAny(line[:pos], " \t(") + 1
+	word := line[start:pos]
+	if word == "" {
+		return "", 0, false
+	}
+
+	var candidates []string
+	if start == 0 && strings.HasPrefix(word, ":") {
+		for _, command := range replCommands {
+			candidates = append(candidates, command.name)
+		}
+	} else {
+		candidates = r.knownDimensions()
+	}
+	completion := ""
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, word) {
+			continue
+		}
+		if completion == "" {
+			completion = candidate
+		} else {
+			completion = commonPrefix(completion, candidate)
+		}
+	}
+	if len(completion) <= len(word) {
+		return "", 0, false
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// fileHistory is a bounded REPL history that appends every entry to a file, so it survives restarts.
+// Without a usable file it is only kept in memory.
+type fileHistory struct {
+	entries []string // Oldest first
+	size    int
+	file    *os.File
+}
+
+// openHistory reads the most recent entries of the history file at path (empty: in memory only)
+func openHistory(path string, size int) *fileHistory {
+	h := &fileHistory{size: size}
+	if path == "" {
+		return h
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if data, err := os.ReadFile(path); err == nil {
+		saved := 0
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.push(line)
+				saved++
+			}
+		}
+		if saved > 2*size {
+			flags |= os.O_TRUNC // Rewritten below with the kept entries
+		}
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		_ = os.MkdirAll(dir, 0755)
+	}
+	file, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		log.Warn().Msgf("Search history will not be saved: %s", err)
+		return h
+	}
+	h.file = file
+	if flags&os.O_TRUNC != 0 {
+		for _, entry := range h.entries {
+			fmt.Fprintln(file, entry)
+		}
+	}
+	return h
+}
+
+// defaultHistoryPath is ~/.bitscout_history, or none without a home directory
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bitscout_history")
+}
+
+func (h *fileHistory) push(entry string) {
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+}
+
+// Add records an entry; blank lines and repeats of the previous entry are skipped
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.push(entry)
+	if h.file != nil {
+		if _, err := fmt.Fprintln(h.file, entry); err != nil {
+			log.Warn().Msgf("Error saving search history: %s", err)
+			h.file.Close()
+			h.file = nil
+		}
+	}
+}
+
+func (h *fileHistory) Len() int {
+	return len(h.entries)
+}
+
+// At returns the idx-th most recent entry
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+func (h *fileHistory) close() {
+	if h.file != nil {
+		h.file.Close()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/features"
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/rs/zerolog/log"
+)
+
+// Ways to handle documents indexed with a different feature schema than the configured one
+const (
+	schemaWarn    = "warn"    // Log the differences and keep serving the old documents
+	schemaFail    = "fail"    // Refuse to start until the index is rebuilt
+	schemaReindex = "reindex" // Re-extract the features of every document of the loader
+)
+
+// FeatureSchemaConfig records the features and vector layout each index was built with, so a feature
+// extractor change that makes old documents incomparable to new ones is detected on startup.
+// Example: { "path": "./data/feature_schema.json", "on_mismatch": "reindex" }
+type FeatureSchemaConfig struct {
+	Path       string `json:"path"`
+	OnMismatch string `json:"on_mismatch,omitempty"` // warn (default), fail or reindex
+}
+
+// schemaCheck holds the feature schemas of the configured loaders between the startup check and
+// recording them once the initial loads are done
+type schemaCheck struct {
+	store      *features.SchemaStore
+	cfg        *StarterConfig
+	extractors *featureExtractors
+	stale      map[string]bool // Loaders whose documents keep the recorded schema
+}
+
+// checkFeatureSchemas compares the feature schema of every configured loader against the one recorded
+// for its index and handles differences as configured. It returns nil without a schema path.
+func checkFeatureSchemas(cfg *StarterConfig, extractors *featureExtractors, registry *loaders.LoaderRegistry) (*schemaCheck, error) {
+	if cfg.FeatureSchema == nil || cfg.FeatureSchema.Path == "" {
+		return nil, nil
+	}
+	mode := cfg.FeatureSchema.OnMismatch
+	if mode == "" {
+		mode = schemaWarn
+	}
+	if mode != schemaWarn && mode != schemaFail && mode != schemaReindex {
+		return nil, fmt.Errorf("unknown feature schema on_mismatch %s (known: %s, %s, %s)", mode, schemaFail, schemaReindex, schemaWarn)
+	}
+	store, err := features.OpenSchemaStore(cfg.FeatureSchema.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &schemaCheck{store: store, cfg: cfg, extractors: extractors, stale: make(map[string]bool)}
+	var mismatches []string
+	for _, lc := range cfg.Loaders {
+		indexName := check.indexOf(lc)
+		recorded, ok := store.Get(indexName, lc.Name)
+		if !ok {
+			continue // Recorded once the loader has been loaded
+		}
+		diffs := recorded.Diff(check.schemaOf(lc))
+		if len(diffs) == 0 {
+			continue
+		}
+		mismatch := fmt.Sprintf("loader %s (index %s): %s", lc.Name, indexName, strings.Join(diffs, ", "))
+		switch mode {
+		case schemaFail:
+			mismatches = append(mismatches, mismatch)
+		case schemaWarn:
+			log.Warn().Msgf("Feature schema changed for %s; reindex it to make its documents comparable", mismatch)
+			check.stale[lc.Name] = true
+		case schemaReindex:
+			check.reindex(lc, registry, mismatch)
+		}
+	}
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("feature schema changed, reindexing required: %s", strings.Join(mismatches, "; "))
+	}
+	return check, nil
+}
+
+// reindex makes the next load of a loader rebuild all of its documents
+func (c *schemaCheck) reindex(lc LoaderConfig, registry *loaders.LoaderRegistry, mismatch string) {
+	if lc.Schedule == nil {
+		// Unscheduled loaders load everything on startup
+		log.Info().Msgf("Feature schema changed for %s; its documents are rebuilt by the initial load", mismatch)
+		return
+	}
+	loader, _ := registry.Get(lc.Name)
+	invalidator, ok := loader.(interface{ Invalidate() })
+	if !ok {
+		log.Warn().Msgf("Feature schema changed for %s, but the loader cannot be reindexed in place", mismatch)
+		c.stale[lc.Name] = true
+		return
+	}
+	invalidator.Invalidate()
+	log.Info().Msgf("Feature schema changed for %s; reindexing its documents", mismatch)
+}
+
+// record saves the feature schema of every loader whose documents were built with the current config
+func (c *schemaCheck) record() {
+	if c == nil {
+		return
+	}
+	byIndex := make(map[string]map[string]features.Schema)
+	for _, lc := range c.cfg.Loaders {
+		indexName := c.indexOf(lc)
+		schema := c.schemaOf(lc)
+		if c.stale[lc.Name] {
+			schema, _ = c.store.Get(indexName, lc.Name)
+		}
+		if byIndex[indexName] == nil {
+			byIndex[indexName] = make(map[string]features.Schema)
+		}
+		byIndex[indexName][lc.Name] = schema
+	}
+	for indexName, schemas := range byIndex {
+		if err := c.store.Record(indexName, schemas); err != nil {
+			log.Error().Msgf("Error recording the feature schema of index %s: %s", indexName, err)
+		}
+	}
+}
+
+// indexOf returns the index a loader's documents go into
+func (c *schemaCheck) indexOf(lc LoaderConfig) string {
+	if lc.Index == "" && len(c.cfg.Indexes) > 0 {
+		return c.cfg.Indexes[0].Name
+	}
+	return lc.Index
+}
+
+// schemaOf returns the feature schema of a loader's documents. Extractors registered with the engine
+// (e.g. plugins) are only listed by name, since their configuration is opaque.
+func (c *schemaCheck) schemaOf(lc LoaderConfig) features.Schema {
+	merged, others := c.extractors.extractorsOf(lc)
+	schema := c.extractors.registry.Schema(merged)
+	for _, name := range others {
+		schema.Extractors = append(schema.Extractors, features.ExtractorSchema{Name: name})
+	}
+	return schema
+}
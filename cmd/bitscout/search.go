@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/internal/searches"
+)
+
+// openSavedSearches opens the saved search database of the search config, if any, for the engine
+func openSavedSearches(core *engine.EngineCore, cfg *SearchConfig) (close func() error, err error) {
+	if cfg == nil || cfg.SavedSearches == "" {
+		return func() error { return nil }, nil
+	}
+	store, err := searches.OpenStore(cfg.SavedSearches)
+	if err != nil {
+		return nil, err
+	}
+	core.SetSavedSearchStore(store)
+	return store.Close, nil
+}
+
+// paramFlags collects the repeated -param name=value flags of `bitscout search`
+type paramFlags map[string]string
+
+func (p paramFlags) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlags) Set(pair string) error {
+	name, value, ok := strings.Cut(pair, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("parameters are name=value, got %q", pair)
+	}
+	p[name] = value
+	return nil
+}
+
+// runSearch implements `bitscout search`: a query (the arguments) or a saved search is run against the
+// default index, built as on startup, and its results are written as NDJSON. -save, -list and -delete
+// manage the saved searches instead.
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file")
+	saved := flags.String("saved", "", "Saved search to run")
+	params := paramFlags{}
+	flags.Var(params, "param", "Parameter of the saved search, name=value (repeatable)")
+	sort := flags.String("sort", "", "Order of the results: score (default), id or a field; a - prefix reverses it")
+	fields := flags.String("fields", "", "Comma-separated fields to return, those to leave out prefixed with -")
+	save := flags.String("save", "", "Save the query as a saved search of this name instead of running it")
+	list := flags.Bool("list", false, "List the saved searches")
+	remove := flags.String("delete", "", "Delete the saved search of this name")
+	load := flags.Bool("load", true, "Run the loaders of the index before searching (disable to search only persisted documents)")
+	pluginsDir := flags.String("plugins", "plugins", "Directory scanned for plugin executables")
+	flags.Parse(args)
+	query := strings.Join(flags.Args(), " ")
+
+	cfg, name, err := transferConfig(*configPath, "")
+	if err != nil {
+		return err
+	}
+	core := engine.NewEngineCore()
+	defer stopEngine(core)
+	closeSearches, err := openSavedSearches(core, cfg.Search)
+	if err != nil {
+		return err
+	}
+	defer closeSearches()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	switch {
+	case *list:
+		list, err := core.ListSavedSearches("")
+		if err != nil {
+			return savedSearchError(err)
+		}
+		for _, search := range list {
+			if err := encoder.Encode(search); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *remove != "":
+		return savedSearchError(core.DeleteSavedSearch("", *remove))
+	case *save != "":
+		search, err := core.SaveSearch(ports.SavedSearch{Name: *save, Query: query, Sort: *sort, Fields: splitFields(*fields), Params: params})
+		if err != nil {
+			return savedSearchError(err)
+		}
+		return encoder.Encode(search)
+	}
+
+	search := ports.SearchQuery{Query: query, Sort: *sort, Caller: "cli"}
+	if *saved != "" {
+		if search, err = core.SavedSearchQuery("", *saved, params); err != nil {
+			return savedSearchError(err)
+		}
+		search.Caller = "cli"
+		if *sort != "" {
+			search.Sort = *sort
+		}
+	} else if strings.TrimSpace(query) == "" {
+		return errors.New("a query or -saved is required")
+	}
+	if *fields != "" {
+		if search.Projection, err = models.ParseProjection(splitFields(*fields)); err != nil {
+			return fmt.Errorf("invalid fields: %w", err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if _, err := registerIndexes(core, cfg.Indexes); err != nil {
+		return err
+	}
+	if *load {
+		cleanup, err := loadIndex(ctx, core, cfg, name, *pluginsDir)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
+	results, err := core.SearchContext(ctx, search)
+	if err != nil {
+		return err
+	}
+	for _, doc := range results.Documents {
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitFields splits the comma-separated -fields flag
+func splitFields(fields string) []string {
+	var names []string
+	for _, name := range strings.Split(fields, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// savedSearchError explains the saved search errors of an engine without a saved search database
+func savedSearchError(err error) error {
+	if errors.Is(err, ports.ErrNotSupported) {
+		return fmt.Errorf("%w (set search.saved_searches in the config)", err)
+	}
+	return err
+}
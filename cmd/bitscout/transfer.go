@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/rs/zerolog/log"
+)
+
+// runExport implements `bitscout export`: the index is built as on startup (persisted indexes are
+// opened and, unless -load=false, the loaders feeding the index are run) and written as NDJSON
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file")
+	indexName := flags.String("index", "", "Index to export (default: the first configured index)")
+	output := flags.String("o", "", "File the export is written to (default: stdout)")
+	load := flags.Bool("load", true, "Run the loaders of the index before exporting (disable to export only persisted documents)")
+	pluginsDir := flags.String("plugins", "plugins", "Directory scanned for plugin executables")
+	flags.Parse(args)
+
+	cfg, name, err := transferConfig(*configPath, *indexName)
+	if err != nil {
+		return err
+	}
+	core := engine.NewEngineCore()
+	defer stopEngine(core)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if _, err := registerIndexes(core, cfg.Indexes); err != nil {
+		return err
+	}
+	if *load {
+		cleanup, err := loadIndex(ctx, core, cfg, name, *pluginsDir)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := core.ExportIndex(name, w); err != nil {
+		return err
+	}
+	if *output != "" {
+		log.Info().Msgf("Exported index %s to %s", name, *output)
+	}
+	return nil
+}
+
+// runImport implements `bitscout import`: the documents of an export are added to a persisted index
+// of the starter config. Indexes held in memory are imported into a running server with the REST API.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file")
+	indexName := flags.String("index", "", "Index to import into (default: the first configured index)")
+	input := flags.String("i", "", "File the export is read from (default: stdin)")
+	flags.Parse(args)
+
+	cfg, name, err := transferConfig(*configPath, *indexName)
+	if err != nil {
+		return err
+	}
+	for _, ic := range cfg.Indexes {
+		if ic.Name == name && ic.Type != "persisted" && ic.Type != "PersistedSimpleIndex" {
+			return fmt.Errorf("index %s is held in memory; import into a running server with POST /indexes/%s/import", name, name)
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	core := engine.NewEngineCore()
+	defer stopEngine(core)
+	if _, err := registerIndexes(core, cfg.Indexes); err != nil {
+		return err
+	}
+	return core.ImportIndex(name, r)
+}
+
+// transferConfig loads the starter config and resolves the index to export or import
+func transferConfig(path, indexName string) (*StarterConfig, string, error) {
+	loaded, err := readStarterConfig(starterSource(path))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not load config file %s: %w", path, err)
+	}
+	cfg := withDefaults(loaded)
+	if indexName == "" {
+		return cfg, cfg.Indexes[0].Name, nil
+	}
+	for _, ic := range cfg.Indexes {
+		if ic.Name == indexName {
+			return cfg, indexName, nil
+		}
+	}
+	return nil, "", fmt.Errorf("index %s is not configured", indexName)
+}
+
+// loadIndex runs the pipelines of the loaders feeding an index, with their feature extractors.
+// cleanup releases the plugins and feature extractors and must be called even on error.
+func loadIndex(ctx context.Context, core *engine.EngineCore, cfg *StarterConfig, indexName, pluginsDir string) (cleanup func(), err error) {
+	var cleanups []func()
+	cleanup = func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	loaderFactory := loaders.NewLoaderFactory()
+	pluginManager, err := registerPlugins(core, loaderFactory, pluginsDir)
+	if err != nil {
+		return cleanup, err
+	}
+	cleanups = append(cleanups, pluginManager.Close)
+
+	var feeding []LoaderConfig
+	for _, lc := range cfg.Loaders {
+		target := lc.Index
+		if target == "" {
+			target = cfg.Indexes[0].Name
+		}
+		if target == indexName {
+			// A full load, so refreshes of the server's scheduled loaders are not affected
+			lc.Schedule = nil
+			feeding = append(feeding, lc)
+		}
+	}
+	if err := registerLoaders(core, loaders.NewLoaderRegistry(), loaderFactory, feeding); err != nil {
+		return cleanup, err
+	}
+	extractors, err := registerFeatures(core, cfg.Features, cfg.FeatureCache)
+	if err != nil {
+		return cleanup, err
+	}
+	cleanups = append(cleanups, extractors.close)
+	if err := registerPipelines(core, feeding, extractors, engine.DefaultBatchSize); err != nil {
+		return cleanup, err
+	}
+	for _, lc := range feeding {
+		if err := core.StartPipeline(ctx, lc.Name); err != nil {
+			return cleanup, err
+		}
+	}
+	return cleanup, nil
+}
+
+// stopEngine closes the indexes of an engine used by a subcommand, flushing persisted ones
+func stopEngine(core *engine.EngineCore) {
+	if err := stopWithin(core, shutdownTimeout); err != nil {
+		log.Error().Msgf("Error stopping engine: %s", err)
+	}
+}
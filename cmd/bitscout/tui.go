@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/engine"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+const (
+	tuiRefreshInterval = 250 * time.Millisecond // How often the dashboard redraws the engine state
+	tuiRecentQueries   = 8                      // Searches listed in the recent queries pane
+	tuiLogLines        = 200                    // Log lines kept for the log pane
+)
+
+var (
+	tuiPane  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1)
+	tuiTitle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	tuiDim   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiError = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	tuiOK    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+)
+
+// tuiFeed collects what the engine reports between redraws: search and loader events and log lines.
+// Writers never block, so logging from any goroutine is safe while the dashboard is drawing.
+type tuiFeed struct {
+	mu      sync.Mutex
+	queries []ports.Event // Most recent first
+	loaders map[string]*loaderProgress
+	logs    []string
+}
+
+// loaderProgress is the state of a loader's current or last run, as seen through engine events
+type loaderProgress struct {
+	index    string
+	running  bool
+	indexed  int // Documents indexed by the current or last run
+	started  time.Time
+	duration time.Duration
+	err      string
+}
+
+func newTUIFeed() *tuiFeed {
+	return &tuiFeed{loaders: make(map[string]*loaderProgress)}
+}
+
+func (f *tuiFeed) handle(event ports.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch event.Type {
+	case ports.EventSearchExecuted:
+		f.queries = append([]ports.Event{event}, f.queries...)
+		if len(f.queries) > tuiRecentQueries {
+			f.queries = f.queries[:tuiRecentQueries]
+		}
+	case ports.EventDocumentIndexed:
+		progress := f.loader(event.Loader)
+		if !progress.running {
+			*progress = loaderProgress{running: true, started: event.Time}
+		}
+		progress.index = event.Index
+		progress.indexed += len(event.DocumentIDs)
+	case ports.EventLoaderCompleted:
+		progress := f.loader(event.Loader)
+		progress.running = false
+		progress.index = event.Index
+		progress.indexed = event.Results
+		progress.duration = event.Duration
+		progress.err = event.Error
+	}
+}
+
+func (f *tuiFeed) loader(name string) *loaderProgress {
+	progress, ok := f.loaders[name]
+	if !ok {
+		progress = &loaderProgress{}
+		f.loaders[name] = progress
+	}
+	return progress
+}
+
+// Write receives the log output, one formatted line per call
+func (f *tuiFeed) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		f.logs = append(f.logs, line)
+	}
+	if len(f.logs) > tuiLogLines {
+		f.logs = f.logs[len(f.logs)-tuiLogLines:]
+	}
+	return len(p), nil
+}
+
+// tui is the dashboard of `bitscout tui`: index and loader status, recent queries, the log and a
+// search pane that accepts the REPL's queries and commands
+type tui struct {
+	core    *engine.EngineCore
+	prompt  *repl // Runs commands, completes dimensions and learns them from results
+	history *fileHistory
+	feed    *tuiFeed
+}
+
+func newTUI(core *engine.EngineCore, r *reloader, extractors *featureExtractors) *tui {
+	return &tui{core: core, prompt: newREPL(core, r, extractors), feed: newTUIFeed()}
+}
+
+// start runs the dashboard in the background, taking over the terminal and the log output; done is
+// closed once the user quits. restore must be called before exiting to reset the terminal.
+func (t *tui) start(ctx context.Context, historyPath string) (done <-chan struct{}, restore func(), err error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, nil, fmt.Errorf("the dashboard needs a terminal; run without tui for line based search")
+	}
+	t.history = openHistory(historyPath, replHistorySize)
+	unsubscribe := t.core.Subscribe(t.feed.handle, ports.EventSearchExecuted, ports.EventDocumentIndexed, ports.EventLoaderCompleted)
+	logger := log.Logger
+	log.Logger = logger.Output(zerolog.ConsoleWriter{Out: t.feed, NoColor: true, TimeFormat: "15:04:05"})
+
+	program := tea.NewProgram(newTUIModel(ctx, t), tea.WithAltScreen(), tea.WithContext(ctx))
+	quit := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(quit)
+		if _, err := program.Run(); err != nil && ctx.Err() == nil {
+			logger.Error().Msgf("Dashboard failed: %s", err)
+		}
+	}()
+
+	var once sync.Once
+	return quit, func() {
+		once.Do(func() {
+			program.Quit()
+			<-finished
+			unsubscribe()
+			log.Logger = logger
+			t.history.close()
+		})
+	}, nil
+}
+
+type tuiTickMsg time.Time
+
+// tuiOutputMsg carries the output of a search or command run in the background
+type tuiOutputMsg struct {
+	text string
+	quit bool
+}
+
+type tuiModel struct {
+	ctx     context.Context
+	t       *tui
+	input   textinput.Model
+	output  string
+	busy    bool
+	browse  int // Position in the history while browsing it with up/down (-1: not browsing)
+	stats   ports.Stats
+	statErr error
+	width   int
+	height  int
+}
+
+func newTUIModel(ctx context.Context, t *tui) tuiModel {
+	input := textinput.New()
+	input.Prompt = replPrompt
+	input.Placeholder = "fileExtension=go and fileSize>1000, free text, or :help"
+	input.Focus()
+	return tuiModel{ctx: ctx, t: t, input: input, browse: -1, output: "Type a query and press enter."}
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(tuiRefreshInterval, func(now time.Time) tea.Msg { return tuiTickMsg(now) })
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, tuiTick())
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = msg.Width - len(replPrompt) - 6
+		return m, nil
+	case tuiTickMsg:
+		m.stats, m.statErr = m.t.core.Stats()
+		return m, tuiTick()
+	case tuiOutputMsg:
+		m.busy = false
+		m.output = msg.text
+		if msg.quit {
+			return m, tea.Quit
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "enter":
+			line := strings.TrimSpace(m.input.Value())
+			if line == "" || m.busy {
+				return m, nil
+			}
+			m.t.history.Add(line)
+			m.input.SetValue("")
+			m.browse = -1
+			m.busy = true
+			m.output = tuiDim.Render("Running " + line + " ...")
+			return m, m.run(line)
+		case "tab":
+			value := m.input.Value()
+			if completed, pos, ok := m.t.prompt.complete(value, m.input.Position(), '\t'); ok {
+				m.input.SetValue(completed)
+				m.input.SetCursor(pos)
+			}
+			return m, nil
+		case "up", "down":
+			m.browseHistory(msg.String() == "up")
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// run executes a search or command in the background, collecting the REPL's output for the search pane
+func (m tuiModel) run(line string) tea.Cmd {
+	prompt, ctx := m.t.prompt, m.ctx
+	return func() tea.Msg {
+		var out bytes.Buffer
+		prompt.out = &out
+		quit := prompt.exec(ctx, line)
+		return tuiOutputMsg{text: strings.TrimRight(out.String(), "\n"), quit: quit}
+	}
+}
+
+func (m *tuiModel) browseHistory(older bool) {
+	history := m.t.history
+	switch {
+	case older && m.browse+1 < history.Len():
+		m.browse++
+	case !older && m.browse >= 0:
+		m.browse--
+	default:
+		return
+	}
+	if m.browse < 0 {
+		m.input.SetValue("")
+		return
+	}
+	m.input.SetValue(history.At(m.browse))
+	m.input.CursorEnd()
+}
+
+func (m tuiModel) View() string {
+	if m.width == 0 {
+		return "Starting bitscout..."
+	}
+	width := m.width - 2
+	half := width/2 - 1
+
+	header := tuiTitle.Render("bitscout") + tuiDim.Render(fmt.Sprintf("  %d documents in %d indexes", m.stats.NumDocuments, len(m.stats.Indexes)))
+	if m.statErr != nil {
+		header += "  " + tuiError.Render(m.statErr.Error())
+	}
+	top := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.pane("Indexes", m.indexesView(), half),
+		m.pane("Loaders", m.loadersView(), width-half-2))
+	queries := m.pane("Recent queries", m.queriesView(), width)
+
+	// The search and log panes share the height left by the fixed panes and the footer
+	used := lipgloss.Height(header) + lipgloss.Height(top) + lipgloss.Height(queries) + 1
+	free := m.height - used - 4
+	if free < 6 {
+		free = 6
+	}
+	searchLines := free * 2 / 3
+	search := m.pane("Search", m.input.View()+"\n"+firstLines(m.output, searchLines-1), width)
+	logs := m.pane("Log", m.logView(free-searchLines-2, width-4), width)
+	footer := tuiDim.Render("enter search · tab complete · ↑/↓ history · :help commands · esc quit")
+	return lipgloss.JoinVertical(lipgloss.Left, header, top, queries, search, logs, footer)
+}
+
+func (m tuiModel) pane(title, body string, width int) string {
+	return tuiPane.Width(width - 2).Render(tuiTitle.Render(title) + "\n" + body)
+}
+
+func (m tuiModel) indexesView() string {
+	if len(m.stats.Indexes) == 0 {
+		return tuiDim.Render("no indexes")
+	}
+	var lines []string
+	for _, status := range m.stats.Indexes {
+		lines = append(lines, fmt.Sprintf("%-20s %8d docs", status.Name, status.NumDocuments))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadersView combines the progress reported by engine events with the schedule of scheduled loaders
+func (m tuiModel) loadersView() string {
+	feed := m.t.feed
+	feed.mu.Lock()
+	progress := make(map[string]loaderProgress, len(feed.loaders))
+	for name, p := range feed.loaders {
+		progress[name] = *p
+	}
+	feed.mu.Unlock()
+	scheduled := make(map[string]ports.LoaderStatus)
+	for _, status := range m.stats.Loaders {
+		scheduled[status.Name] = status
+	}
+	names := make([]string, 0, len(progress))
+	for name := range progress {
+		names = append(names, name)
+	}
+	for name := range scheduled {
+		if _, ok := progress[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return tuiDim.Render("waiting for loaders")
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		p := progress[name]
+		line := fmt.Sprintf("%-14s", name)
+		switch {
+		case p.running:
+			line += fmt.Sprintf(" loading  %d docs, %s", p.indexed, time.Since(p.started).Round(time.Second))
+		case p.err != "":
+			line += " " + tuiError.Render("failed: "+p.err)
+		case p.duration > 0:
+			line += " " + tuiOK.Render("done") + fmt.Sprintf(" %d docs in %s", p.indexed, p.duration.Round(time.Millisecond))
+		default:
+			line += tuiDim.Render(" idle")
+		}
+		if status, ok := scheduled[name]; ok && !status.NextRun.IsZero() {
+			line += tuiDim.Render(fmt.Sprintf(" · next %s", status.NextRun.Format("15:04:05")))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m tuiModel) queriesView() string {
+	feed := m.t.feed
+	feed.mu.Lock()
+	queries := append([]ports.Event(nil), feed.queries...)
+	feed.mu.Unlock()
+	if len(queries) == 0 {
+		return tuiDim.Render("no searches yet")
+	}
+	var lines []string
+	for _, event := range queries {
+		line := fmt.Sprintf("%s  %-40s %5d results %10s", event.Time.Format("15:04:05"), truncate(event.Query, 40), event.Results, event.Duration.Round(time.Microsecond))
+		if event.Error != "" {
+			line += " " + tuiError.Render(event.Error)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m tuiModel) logView(lines, width int) string {
+	feed := m.t.feed
+	feed.mu.Lock()
+	logs := feed.logs
+	if len(logs) > lines {
+		logs = logs[len(logs)-lines:]
+	}
+	shown := make([]string, len(logs))
+	for i, line := range logs {
+		shown[i] = truncate(line, width)
+	}
+	feed.mu.Unlock()
+	return tuiDim.Render(strings.Join(shown, "\n"))
+}
+
+// firstLines keeps the first n lines of text, noting how many were cut
+func firstLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if n > 1 && len(lines) > n {
+		lines = append(lines[:n-1], tuiDim.Render(fmt.Sprintf("... %d more lines", len(lines)-n+1)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
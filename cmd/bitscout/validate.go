@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/api"
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/features"
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/internal/telemetry"
+	"github.com/rs/zerolog/log"
+)
+
+// configIssue is a problem found in a starter config. Errors make `bitscout config validate` fail;
+// warnings point at entries that may be intended, such as loader types provided by plugins.
+type configIssue struct {
+	Path    string
+	Message string
+	Warning bool
+}
+
+func (i configIssue) String() string {
+	severity := "error"
+	if i.Warning {
+		severity = "warning"
+	}
+	if i.Path == "" {
+		return fmt.Sprintf("%-7s  %s", severity, i.Message)
+	}
+	return fmt.Sprintf("%-7s  %s: %s", severity, i.Path, i.Message)
+}
+
+// runConfig implements `bitscout config validate [file]`, which checks a starter config against the
+// schema and the references between its sections, and `bitscout config schema`, which prints the schema
+// as a JSON Schema document for editors
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bitscout config validate [file] | bitscout config schema")
+	}
+	switch args[0] {
+	case "schema":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(starterConfigSchema())
+	case "validate":
+		return runValidate(args[1:])
+	}
+	return fmt.Errorf("unknown config command %s (known: schema, validate)", args[0])
+}
+
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := flags.String("config", "config/starter_config.json", "Path to starter config JSON, YAML or TOML file")
+	flags.Parse(args)
+	path := *configPath
+	if flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+
+	raw, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	issues, err := starterConfigIssues(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	errorCount := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if !issue.Warning {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%s has %d error(s)", path, errorCount)
+	}
+	fmt.Printf("%s is valid (%d warning(s))\n", path, len(issues))
+	return nil
+}
+
+// starterConfigIssues checks a starter config against its schema, then the references between sections
+func starterConfigIssues(raw interface{}) ([]configIssue, error) {
+	var issues []configIssue
+	for _, problem := range starterConfigSchema().Validate(raw) {
+		issues = append(issues, configIssue{Path: problem.Path, Message: problem.Message})
+	}
+	// References are only checked in a config with the right shape, which then decodes cleanly
+	if len(issues) > 0 {
+		return issues, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cfg StarterConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return append(issues, checkReferences(&cfg)...), nil
+}
+
+// warnConfigIssues logs the problems of a starter config that is about to be used anyway, so malformed
+// sections are not silently ignored
+func warnConfigIssues(source string, raw map[string]interface{}) {
+	issues, err := starterConfigIssues(raw)
+	if err != nil {
+		return
+	}
+	for _, issue := range issues {
+		log.Warn().Msgf("Config %s: %s: %s", source, issue.Path, issue.Message)
+	}
+}
+
+// checkReferences finds problems the schema cannot express: duplicate names, loaders targeting
+// undefined indexes, and loader types and feature extractors that are not built in
+func checkReferences(cfg *StarterConfig) []configIssue {
+	var issues []configIssue
+	indexNames := make(map[string]bool)
+	for i, ic := range cfg.Indexes {
+		if indexNames[ic.Name] {
+			issues = append(issues, configIssue{Path: fmt.Sprintf("indexes[%d].name", i), Message: fmt.Sprintf("duplicate index name %q", ic.Name)})
+		}
+		indexNames[ic.Name] = true
+	}
+
+	featureNames := make(map[string]bool)
+	extractorFactory := features.NewExtractorFactory()
+	for i, fc := range cfg.Features {
+		featureNames[fc.Name] = true
+		if !extractorFactory.Has(fc.Name) {
+			issues = append(issues, configIssue{Path: fmt.Sprintf("features[%d].name", i), Warning: true,
+				Message: fmt.Sprintf("%q is not a built-in feature extractor (known: %s); it must be provided by a plugin", fc.Name, strings.Join(extractorFactory.Types(), ", "))})
+		}
+	}
+
+	loaderNames := make(map[string]bool)
+	loaderFactory := loaders.NewLoaderFactory()
+	builtinLoaders := make(map[string]bool)
+	for _, typeName := range loaderFactory.Types() {
+		builtinLoaders[typeName] = true
+	}
+	for i, lc := range cfg.Loaders {
+		path := fmt.Sprintf("loaders[%d]", i)
+		if loaderNames[lc.Name] {
+			issues = append(issues, configIssue{Path: path + ".name", Message: fmt.Sprintf("duplicate loader name %q", lc.Name)})
+		}
+		loaderNames[lc.Name] = true
+		if lc.Index != "" && len(cfg.Indexes) > 0 && !indexNames[lc.Index] {
+			issues = append(issues, configIssue{Path: path + ".index", Message: fmt.Sprintf("index %q is not configured", lc.Index)})
+		}
+		if !builtinLoaders[lc.Type] {
+			issues = append(issues, configIssue{Path: path + ".type", Warning: true,
+				Message: fmt.Sprintf("%q is not a built-in loader type (known: %s); it must be provided by a plugin", lc.Type, strings.Join(loaderFactory.Types(), ", "))})
+		}
+		for j, name := range lc.Features {
+			if !featureNames[name] {
+				issues = append(issues, configIssue{Path: fmt.Sprintf("%s.features[%d]", path, j), Warning: true,
+					Message: fmt.Sprintf("feature extractor %q is not configured in features; it must be provided by a plugin", name)})
+			}
+		}
+	}
+
+	apiNames := make(map[string]bool)
+	for i, ac := range cfg.Apis {
+		if apiNames[ac.Name] {
+			issues = append(issues, configIssue{Path: fmt.Sprintf("apis[%d].name", i), Message: fmt.Sprintf("duplicate api name %q", ac.Name)})
+		}
+		apiNames[ac.Name] = true
+	}
+	return issues
+}
+
+// starterConfigSchema describes the starter config. Sections holding options of a type (index, loader
+// and API configs) reject unknown keys for built-in types; feature extractor configs accept any key, as
+// keys other than the listed options are passed to the extractor as parameters.
+func starterConfigSchema() *config.Schema {
+	str := func(description string) *config.Schema {
+		return &config.Schema{Type: config.Types{"string"}, Description: description}
+	}
+	list := func(description string) *config.Schema {
+		return &config.Schema{Type: config.Types{"array", "string"}, Description: description, Items: str("")}
+	}
+	boolean := func(description string) *config.Schema {
+		return &config.Schema{Type: config.Types{"boolean"}, Description: description}
+	}
+	integer := func(description string, minimum float64) *config.Schema {
+		return &config.Schema{Type: config.Types{"integer"}, Description: description, Minimum: config.Float64(minimum)}
+	}
+	number := func(description string, minimum float64) *config.Schema {
+		return &config.Schema{Type: config.Types{"number"}, Description: description, Minimum: config.Float64(minimum)}
+	}
+	duration := func(description string) *config.Schema {
+		return &config.Schema{Type: config.Types{"string"}, Format: config.FormatDuration, Description: description}
+	}
+	enum := func(description string, values ...interface{}) *config.Schema {
+		return &config.Schema{Type: config.Types{"string"}, Description: description, Enum: values}
+	}
+	object := func(description string, properties map[string]*config.Schema, required ...string) *config.Schema {
+		return &config.Schema{Type: config.Types{"object"}, Description: description, Properties: properties, Required: required}
+	}
+	scopes := func(description string) *config.Schema {
+		return &config.Schema{Type: config.Types{"array"}, Description: description, Items: enum("", api.ScopeSearch, api.ScopeIndex, api.ScopeAdmin)}
+	}
+	// ofType applies a config schema to the entries of a given type
+	ofType := func(types []string, cfg *config.Schema) *config.Schema {
+		values := make([]interface{}, len(types))
+		for i, typeName := range types {
+			values[i] = typeName
+		}
+		return &config.Schema{
+			If:   &config.Schema{Required: []string{"type"}, Properties: map[string]*config.Schema{"type": {Enum: values}}},
+			Then: &config.Schema{Properties: map[string]*config.Schema{"config": cfg}},
+		}
+	}
+
+	indexOptions := func(properties map[string]*config.Schema, required ...string) *config.Schema {
+		properties["max_results"] = integer("Maximum number of results per search", 1)
+		properties["dimensions"] = list("Metadata fields offered as query dimensions")
+		return object("", properties, required...).Closed()
+	}
+	index := object("An index documents are loaded into and searched in", map[string]*config.Schema{
+		"name":   str("Name other sections refer to the index by"),
+		"type":   enum("Index type", "simple", "persisted", "inverted", "vector", "remote", "SimpleIndex", "PersistedSimpleIndex", "InvertedIndex", "VectorIndex", "RemoteIndex"),
+		"config": object("Options of the index type", nil),
+	}, "name", "type").Closed()
+	collation := func() *config.Schema {
+		return enum("How =, != and contains compare text (default case_insensitive); == is always case-sensitive", "case_insensitive", "case_sensitive")
+	}
+	spillDir := func() *config.Schema {
+		return str("Directory exports of large indexes spill the IDs of their documents to (default: the system's temporary directory)")
+	}
+	index.AllOf = []*config.Schema{
+		ofType([]string{"simple", "SimpleIndex"}, indexOptions(map[string]*config.Schema{
+			"read_only": boolean("Reject adds, updates, deletes and imports (default false)"),
+			"collation": collation(),
+			"spill_dir": spillDir(),
+		})),
+		ofType([]string{"persisted", "PersistedSimpleIndex"}, indexOptions(map[string]*config.Schema{
+			"db_path":             str("bbolt database file (default ./data/index.db)"),
+			"load":                boolean("Load the stored documents on startup (default true)"),
+			"read_only":           boolean("Open an existing database read-only, without the async writer, and reject mutations (default false)"),
+			"durability":          enum("When writes without a durability of their own return: once in memory (none), once committed to the database (persisted) or once synced to disk (fsync) (default none)", "none", "persisted", "fsync"),
+			"no_sync":             boolean("Commit to the database without syncing it to disk; only fsync writes then sync (default false)"),
+			"write_retries":       integer("Retries of a failed database write before it is given up on (default 3)", 0),
+			"write_retry_backoff": duration("Delay before the first retry of a failed database write, doubled for each next one, e.g. \"50ms\" (default 50ms)"),
+			"wal":                 boolean("Log each write next to the database before queuing it, so writes a crash keeps from being applied are replayed on the next start; writes then wait for room in a full queue instead of being dropped (default false)"),
+			"collation":           collation(),
+			"spill_dir":           spillDir(),
+		})),
+		ofType([]string{"inverted", "InvertedIndex"}, indexOptions(map[string]*config.Schema{
+			"analyzer":         enum("Text analyzer (default standard)", "standard", "english", "whitespace", "keyword"),
+			"stopwords":        list("Terms dropped during analysis, replacing the analyzer's defaults; _english_ stands for the built-in English list"),
+			"stopword_files":   list("Files of stopwords added to stopwords, separated by whitespace (lines starting with # ignored)"),
+			"protected_terms":  list("Terms never dropped as stopwords or as shorter than min_token_length, nor split into n-grams, e.g. go or c"),
+			"min_token_length": integer("Shortest term indexed", 0),
+			"normalization":    enum("Unicode normalization of the text before analysis (default none)", "nfc", "nfd", "nfkc", "nfkd"),
+			"fold_accents":     boolean("Strip diacritics from terms, so café matches cafe (default false)"),
+			"case_fold":        boolean("Lowercase terms with full Unicode case folding, e.g. Straße as strasse (default false)"),
+			"cjk_bigrams":      boolean("Split runs of Chinese, Japanese and Korean characters into overlapping bigrams, so such text is searchable (default false)"),
+			"ngram":            integer("Split terms into overlapping character n-grams of this length (default 0: not split)", 0),
+			"languages":        object("Analyzer options by language code, e.g. {\"en\": {\"analyzer\": \"english\"}}: documents of a language are analyzed with its options, others with those of the index", nil),
+			"language_field":   str("Metadata key holding the language of documents, e.g. from a language detection extractor (default language)"),
+			"fields":           object("Options of fields (text, source, vector or a metadata key), e.g. {\"text\": {\"store\": false}}: index (searchable, default true) and store (kept in memory, default true)", nil),
+			"field_store_path": str("bbolt file the fields that are not stored in memory are kept in, and read back from for results (default: none, they are dropped)"),
+			"memory_budget_mb": integer("Memory the documents and postings may hold before the text of the oldest documents is evicted to field_store_path (default 0: no budget)", 0),
+			"text_weight":      number("Weight of the text relevance of free-text results, combined with their boosts (default 1)", 0),
+			"score_script":     str("Expression scoring free-text results, e.g. _score * 1.5 + log(word_count) - age_days * 0.01, with _score their weighted text relevance plus boosts"),
+			"boosts":           object("Boosts of free-text results by field, e.g. {\"lastModified\": {\"function\": \"decay\", \"scale\": \"720h\"}}: function (decay, linear or log), weight (default 1) and scale (half-life of decay)", nil),
+			"collation":        collation(),
+			"spill_dir":        spillDir(),
+		})),
+		ofType([]string{"vector", "VectorIndex"}, indexOptions(map[string]*config.Schema{
+			"metric":      enum("Similarity metric (default cosine)", "cosine", "dot", "euclidean"),
+			"k":           integer("Number of nearest neighbours returned (default 10)", 1),
+			"vector_size": integer("Required vector length (default 0: any)", 0),
+			"collation":   collation(),
+			"spill_dir":   spillDir(),
+		})),
+		ofType([]string{"remote", "RemoteIndex"}, indexOptions(map[string]*config.Schema{
+			"url":     str("Base URL of the REST API of the bit-scout node holding the index, e.g. http://10.0.0.5:8081"),
+			"index":   str("Name of the remote node's default index, which searches and writes go to"),
+			"api_key": str("API key or token sent as bearer token to the remote node"),
+			"timeout": duration("Deadline of each request to the remote node (default 30s)"),
+		}, "url", "index")),
+	}
+
+	loader := object("A source of documents", map[string]*config.Schema{
+		"name":     str("Name of the loader"),
+		"type":     str("Loader type: FilesystemLoader, MboxLoader, IMAPLoader or a plugin loader type"),
+		"index":    str("Index the loaded documents go into (default: the first index)"),
+		"config":   object("Options of the loader type", nil),
+		"schedule": object("Periodic refresh", map[string]*config.Schema{"interval": duration("Time between refreshes, e.g. 10m")}, "interval").Closed(),
+		"features": &config.Schema{Type: config.Types{"array"}, Description: "Feature extractors applied to the documents (default: all configured)", Items: str("")},
+	}, "name", "type").Closed()
+	loader.AllOf = []*config.Schema{
+		ofType([]string{"FilesystemLoader"}, object("", map[string]*config.Schema{
+			"root":    str("Directory to load (default .)"),
+			"include": list("Glob patterns of the files to load"),
+			"exclude": list("Glob patterns of the files and directories to skip"),
+		}).Closed()),
+		ofType([]string{"MboxLoader"}, object("", map[string]*config.Schema{
+			"path": str("mbox file to load"),
+		}, "path").Closed()),
+		ofType([]string{"IMAPLoader"}, object("", map[string]*config.Schema{
+			"address":  str("Server address, e.g. imap.example.com:993"),
+			"username": str("Login user"),
+			"password": str("Login password"),
+			"mailbox":  str("Mailbox to load (default INBOX)"),
+			"tls":      boolean("Connect over TLS (default true)"),
+			"insecure": boolean("Skip TLS certificate verification"),
+		}, "address", "username").Closed()),
+	}
+
+	api := object("An API serving searches", map[string]*config.Schema{
+		"name": str("Name of the API"),
+		"type": enum("API type", "GraphQL", "REST", "gRPC"),
+		"config": object("Options of the API", map[string]*config.Schema{
+			"listen": str("Listen address, e.g. :8080"),
+			"cors": object("Browser access from other origins (GraphQL and REST only)", map[string]*config.Schema{
+				"origins": list("Allowed origins, e.g. http://localhost:3000, or * for any"),
+				"headers": list("Request headers allowed besides Authorization, Content-Type, X-API-Key and traceparent"),
+				"max_age": duration("How long browsers may cache preflight responses"),
+			}, "origins").Closed(),
+			"playground": str("Path of the GraphiQL playground, e.g. /playground (GraphQL only)"),
+		}).Closed(),
+	}, "name", "type").Closed()
+
+	feature := object("A feature extractor", map[string]*config.Schema{
+		"name": str("Built-in extractor (filesystem, content, mime, hash, keywords, media, tfidf, embedding) or a plugin extractor"),
+		"config": object("Options of the extractor; other keys are passed to it as parameters", map[string]*config.Schema{
+			"enabled":         boolean("Run the extractor (default true)"),
+			"weight":          &config.Schema{Type: config.Types{"number"}, Description: "Weight of the extractor's features (default 1)"},
+			"normalize":       boolean("Normalize numeric features (default true)"),
+			"vectorize":       boolean("Include the features in vectors (default true)"),
+			"parameters":      object("Extractor specific parameters", nil),
+			"feature_map":     object("Renames of the extracted features", nil),
+			"meta":            boolean("Merge the features into document metadata (default true)"),
+			"vector":          boolean("Merge the features into document vectors (default true)"),
+			"normalization":   enum("Scaling of vectors with corpus statistics", features.NormalizeMinMax, features.NormalizeZScore),
+			"normalizer_path": str("File the normalization statistics are kept in"),
+			"parallelism":     integer("Documents extracted concurrently", 0),
+			"timeout":         duration("Time limit per document"),
+			"cache":           boolean("Reuse the features of unchanged documents"),
+		}),
+	}, "name").Closed()
+
+	webhook := object("A webhook notified of engine events", map[string]*config.Schema{
+		"name": str("Name of the webhook"),
+		"config": object("Options of the webhook", map[string]*config.Schema{
+			"url": str("URL events are posted to"),
+			"events": &config.Schema{Type: config.Types{"array", "string"}, Description: "Events to send (default: all)",
+				Items: enum("", string(ports.EventDocumentIndexed), string(ports.EventDocumentDeleted), string(ports.EventSearchExecuted), string(ports.EventLoaderCompleted), string(ports.EventQueryMatched))},
+			"headers": object("Extra request headers", nil),
+			"secret":  str("Key of the request signature"),
+			"timeout": duration("Time limit per delivery"),
+			"retries": integer("Redeliveries of failed events", 0),
+			"backoff": duration("Delay before the first redelivery"),
+		}, "url").Closed(),
+	}, "name", "config").Closed()
+
+	schema := object("", map[string]*config.Schema{
+		"$schema":  str("JSON Schema of this file, for editors"),
+		"indexes":  &config.Schema{Type: config.Types{"array"}, Items: index},
+		"loaders":  &config.Schema{Type: config.Types{"array"}, Items: loader},
+		"apis":     &config.Schema{Type: config.Types{"array"}, Items: api},
+		"features": &config.Schema{Type: config.Types{"array"}, Items: feature},
+		"feature_cache": object("Cache of extracted features", map[string]*config.Schema{
+			"size":    integer("Entries kept in memory", 0),
+			"db_path": str("bbolt database the cache is persisted in"),
+		}).Closed(),
+		"feature_schema": object("Detection of feature changes between runs", map[string]*config.Schema{
+			"path":        str("File the feature schemas are recorded in"),
+			"on_mismatch": enum("Handling of documents indexed with other features", schemaWarn, schemaFail, schemaReindex),
+		}, "path").Closed(),
+		"webhooks": &config.Schema{Type: config.Types{"array"}, Items: webhook},
+		"search": object("Middlewares applied to every search", map[string]*config.Schema{
+			"log":     boolean("Log every search"),
+			"timeout": duration("Deadline of each search, e.g. 2s; slower searches are aborted (default: none)"),
+			"rate_limit": object("Limit of searches across all APIs", map[string]*config.Schema{
+				"per_second": &config.Schema{Type: config.Types{"number"}, ExclusiveMinimum: config.Float64(0), Description: "Sustained searches per second"},
+				"burst":      integer("Searches allowed at once", 0),
+			}, "per_second").Closed(),
+			"query_log": object("JSON lines log of every search, rotated by size and age", map[string]*config.Schema{
+				"path":           str("Log file"),
+				"slow_threshold": duration("Latency from which searches are logged with their evaluation details, e.g. 250ms"),
+				"max_size_mb":    integer("Size a file is rotated at (default 100)", 1),
+				"max_age":        duration("Age rotated files are deleted at, e.g. 168h (default: kept)"),
+				"max_backups":    integer("Rotated files kept (default: all)", 0),
+				"compress":       boolean("Gzip rotated files"),
+			}, "path").Closed(),
+			"saved_searches": str("bbolt database of the saved searches, e.g. ./data/searches.db (default: saved searches disabled)"),
+		}).Closed(),
+		"auth": object("Credentials required by every API", map[string]*config.Schema{
+			"api_keys": &config.Schema{Type: config.Types{"array"}, Items: object("A static key, sent as Authorization: Bearer <key> or X-API-Key", map[string]*config.Schema{
+				"name":       str("Name of the key holder, for logs"),
+				"key":        str("The key"),
+				"scopes":     scopes("Endpoints the key may use"),
+				"roles":      list("Roles of the key holder, granting their scopes and document filters"),
+				"namespace":  str("Tenant namespace the key is bound to: the index (or alias) it indexes into, searches and counts alone"),
+				"principals": list("Groups of the key holder: it finds the documents allowed to them, its name or roles"),
+			}, "key").Closed()},
+			"jwt": object("Validation of bearer JWTs", map[string]*config.Schema{
+				"secret":          str("HMAC secret (HS256, HS384, HS512)"),
+				"public_key_file": str("PEM public key (RSA, ECDSA or Ed25519)"),
+				"issuer":          str("Required iss claim"),
+				"audience":        str("Required aud claim"),
+				"scope_claim":     str("Claim holding the token's scopes (default scope)"),
+				"role_claim":      str("Claim holding the token's roles (default roles)"),
+				"namespace_claim": str("Claim binding the token to a tenant namespace (default namespace)"),
+				"principal_claim": str("Claim holding the token's groups, which documents' allowed_principals may name (default groups)"),
+				"leeway":          duration("Allowed clock skew, e.g. 30s"),
+			}).Closed(),
+			"roles": &config.Schema{Type: config.Types{"array"}, Items: object("A role; reader, writer and admin are built in", map[string]*config.Schema{
+				"name":         str("Name of the role"),
+				"scopes":       scopes("Endpoints the role may use"),
+				"filter":       str("Query the documents the role may find must match, e.g. directory contains public"),
+				"unrestricted": boolean("Holders see every document, whatever the filters of their other roles"),
+			}, "name").Closed()},
+			"protected": scopes("Endpoints requiring credentials (default: all)"),
+		}).Closed(),
+		"limits": object("Per-client rate limits and request body caps of every API", map[string]*config.Schema{
+			"rate_limit": object("Requests per client: API key or JWT subject, else IP", map[string]*config.Schema{
+				"per_second": &config.Schema{Type: config.Types{"number"}, Minimum: config.Float64(0), Description: "Requests per second refilled"},
+				"burst":      integer("Requests allowed at once (default: per_second)", 0),
+			}, "per_second").Closed(),
+			"max_document_bytes": integer("Body cap of single documents and GraphQL requests (default 1 MiB)", 0),
+			"max_import_bytes":   integer("Body cap of index imports and bulk requests (default 1 GiB)", 0),
+		}).Closed(),
+		"snapshots": object("Index snapshots written by the admin APIs or on a schedule (without it, snapshots are disabled)", map[string]*config.Schema{
+			"dir":              str("Directory snapshots are written to, as <index>-<time>.ndjson"),
+			"interval":         duration("Time between scheduled snapshots, e.g. \"1h\" (default: none scheduled)"),
+			"retain":           integer("Snapshots kept per index, the oldest pruned after each new one (default: all)", 0),
+			"indexes":          &config.Schema{Type: config.Types{"array"}, Description: "Indexes snapshotted and restored (default: every index that can be exported)", Items: str("")},
+			"restore_on_start": boolean("Restore each empty index from its latest snapshot on startup, before the initial loads"),
+		}, "dir").Closed(),
+		"cluster": object("Distributed search across peer nodes", map[string]*config.Schema{
+			"node_id": str("Name of this node (default local)"),
+			"listen":  str("Address peers search this node on over gRPC, e.g. :7070 (empty: not served)"),
+			"secret":  str("Bearer token the nodes authenticate each other with"),
+			"peers": &config.Schema{Type: config.Types{"array"}, Items: object("A node searches are sent to", map[string]*config.Schema{
+				"id":           str("Name of the node"),
+				"address":      str("Address the node serves cluster searches on, e.g. 10.0.0.2:7070"),
+				"raft_address": str("Address the node's raft listens on, e.g. 10.0.0.2:7071 (required with raft)"),
+			}, "id", "address").Closed()},
+			"node_timeout":      duration("Deadline of each peer's search (default 2s)"),
+			"require_all_nodes": boolean("Fail searches a peer fails instead of returning partial results"),
+			"shards":            integer("Shards the default index is split into, the same on every node (requires node_id and listen)", 1),
+			"raft": object("Replicates the indexes created at runtime, the aliases and the shard map with raft (requires node_id and listen)", map[string]*config.Schema{
+				"address":   str("Address this node's raft listens on and peers reach it at, e.g. 10.0.0.1:7071"),
+				"dir":       str("Directory of the raft log and snapshots"),
+				"bootstrap": boolean("Start a new cluster of this node and its peers if there is no raft state"),
+			}, "address", "dir").Closed(),
+		}).Closed(),
+		"health": object("Limits of the /healthz and /readyz checks", map[string]*config.Schema{
+			"max_memory_mb": integer("Memory the Go runtime may hold (default: GOMEMLIMIT, if set)", 0),
+			"stuck_after":   duration("Duration of a loader run after which it counts as stuck (default 30m)"),
+		}).Closed(),
+		"warm_up": object("Warm-up run on startup after the initial loads, before the APIs report ready", map[string]*config.Schema{
+			"rebuild":     boolean("Rebuild the ACL bitmaps, metadata catalogs and postings of the indexes (default false)"),
+			"parallelism": integer("Indexes rebuilt at once (default: one per CPU)", 0),
+			"queries":     list("Queries run against the default index, e.g. to page in the documents they read"),
+			"timeout":     duration("Deadline of the whole warm-up (default: none)"),
+		}).Closed(),
+		"telemetry": object("OpenTelemetry tracing of loading, extraction, indexing and searches", map[string]*config.Schema{
+			"exporter":     enum("Where spans are sent (default otlp)", telemetry.ExporterOTLP, telemetry.ExporterStdout, telemetry.ExporterNone),
+			"protocol":     enum("OTLP transport (default http)", "http", "grpc"),
+			"endpoint":     str("Collector address, e.g. localhost:4318 (default: OTEL_EXPORTER_OTLP_ENDPOINT)"),
+			"insecure":     boolean("Connect to the collector without TLS"),
+			"service_name": str("service.name of the spans (default bitscout)"),
+			"sample_ratio": &config.Schema{Type: config.Types{"number"}, Minimum: config.Float64(0), Maximum: config.Float64(1), Description: "Share of traces recorded (default 1)"},
+		}).Closed(),
+	}).Closed()
+	schema.Draft = config.SchemaDraft
+	schema.Title = "bitscout starter config"
+	return schema
+}
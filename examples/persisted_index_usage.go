@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -81,12 +82,12 @@ func main() {
 	}
 
 	// Add documents (this will be persisted asynchronously)
-	if err := index2.AddDocuments(documents); err != nil {
+	if err := index2.AddDocuments(context.Background(), documents); err != nil {
 		log.Error().Err(err).Msg("Failed to add documents")
 	}
 
 	// Search immediately (works from memory)
-	results, err := index2.Search("Go programming")
+	results, err := index2.Search(context.Background(), "Go programming")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to search")
 	} else {
@@ -97,7 +98,7 @@ func main() {
 	}
 
 	// Advanced search
-	results, err = index2.Search("fileExtension=go")
+	results, err = index2.Search(context.Background(), "fileExtension=go")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to search")
 	} else {
@@ -134,7 +135,7 @@ func main() {
 	}
 
 	// Search again to verify data is available
-	results, err = index3.Search("database")
+	results, err = index3.Search(context.Background(), "database")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to search")
 	} else {
@@ -182,14 +183,14 @@ func demonstrateFirstTimeCreation() {
 		Meta:   map[string]string{"type": "first"},
 	}
 
-	if err := index.AddDocument(doc); err != nil {
+	if err := index.AddDocument(context.Background(), doc); err != nil {
 		log.Error().Err(err).Msg("Failed to add first document")
 	} else {
 		fmt.Println("Added first document to new database")
 	}
 
 	// Verify data is searchable
-	results, err := index.Search("first document")
+	results, err := index.Search(context.Background(), "first document")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to search")
 	} else {
@@ -0,0 +1,23 @@
+// Command bitscout-extractor-wordcount is an example feature extractor plugin.
+//
+// Build it into bit-scout's plugins directory to register it as the "wordcount" feature extractor:
+//
+//	go build -o plugins/bitscout-extractor-wordcount ./examples/plugins/wordcount
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aawadall/bit-scout/pkg/plugin"
+)
+
+type wordCount struct{}
+
+func (wordCount) Extract(ctx context.Context, doc plugin.Document) (map[string]interface{}, error) {
+	return map[string]interface{}{"wordCount": len(strings.Fields(doc.Text))}, nil
+}
+
+func main() {
+	plugin.ServeExtractor(wordCount{})
+}
@@ -0,0 +1,333 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// createIndexRequest is the body of POST /indexes
+type createIndexRequest struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// setAliasRequest is the body of PUT /aliases/{alias}
+type setAliasRequest struct {
+	Index string `json:"index"`
+}
+
+// snapshotResponse is the body returned by POST /indexes/{name}/snapshot
+type snapshotResponse struct {
+	Index string    `json:"index"`
+	Path  string    `json:"path"`
+	Time  time.Time `json:"time"`
+	Bytes int64     `json:"bytes"`
+}
+
+// verifyResponse is the body returned by POST /indexes/{name}/verify
+type verifyResponse struct {
+	Index           string   `json:"index"`
+	Consistent      bool     `json:"consistent"`
+	Documents       int      `json:"documents"`
+	Stored          int      `json:"stored"`
+	MissingOnDisk   []string `json:"missingOnDisk"`
+	MissingInMemory []string `json:"missingInMemory"`
+	Mismatched      []string `json:"mismatched"`
+	Repaired        int      `json:"repaired"`
+}
+
+// newVerifyResponse is the response of a verification of an index, with empty lists rather than null
+func newVerifyResponse(name string, report ports.VerifyReport) verifyResponse {
+	nonNil := func(ids []string) []string {
+		if ids == nil {
+			return []string{}
+		}
+		return ids
+	}
+	return verifyResponse{
+		Index:           name,
+		Consistent:      len(report.MissingOnDisk)+len(report.MissingInMemory)+len(report.Mismatched) == 0,
+		Documents:       report.Documents,
+		Stored:          report.Stored,
+		MissingOnDisk:   nonNil(report.MissingOnDisk),
+		MissingInMemory: nonNil(report.MissingInMemory),
+		Mismatched:      nonNil(report.Mismatched),
+		Repaired:        report.Repaired,
+	}
+}
+
+// indexAdmin returns the index administration of a backend (or API), or one failing with ErrNotSupported
+func indexAdmin(backend ports.EnginePort) ports.IndexAdminPort {
+	if admin, ok := backend.(ports.IndexAdminPort); ok {
+		return admin
+	}
+	return unsupportedAdmin{}
+}
+
+// decodeConfig decodes a JSON object argument of the GraphQL API (nil: no config)
+func decodeConfig(config *string) (map[string]interface{}, error) {
+	if config == nil || *config == "" {
+		return nil, nil
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(*config), &decoded); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return decoded, nil
+}
+
+// unsupportedAdmin stands in for the administration of backends that have none
+type unsupportedAdmin struct{}
+
+func (unsupportedAdmin) err() error {
+	return fmt.Errorf("%w: index administration", ports.ErrNotSupported)
+}
+
+func (u unsupportedAdmin) CreateIndex(ports.IndexSpec) error                   { return u.err() }
+func (u unsupportedAdmin) DropIndex(string) error                              { return u.err() }
+func (u unsupportedAdmin) ConfigureIndex(string, map[string]interface{}) error { return u.err() }
+func (u unsupportedAdmin) FlushIndex(string) error                             { return u.err() }
+func (u unsupportedAdmin) OptimizeIndex(string) error                          { return u.err() }
+func (u unsupportedAdmin) ClearIndex(string) error                             { return u.err() }
+func (u unsupportedAdmin) VerifyIndex(string, string) (ports.VerifyReport, error) {
+	return ports.VerifyReport{}, u.err()
+}
+func (u unsupportedAdmin) SnapshotIndex(string) (ports.Snapshot, error) {
+	return ports.Snapshot{}, u.err()
+}
+func (u unsupportedAdmin) SetAlias(string, string) error               { return u.err() }
+func (u unsupportedAdmin) RemoveAlias(string) error                    { return u.err() }
+func (u unsupportedAdmin) TriggerLoader(context.Context, string) error { return u.err() }
+
+// adminStatus maps an index administration error to an HTTP status
+func adminStatus(err error) int {
+	switch {
+	case errors.Is(err, ports.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ports.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ports.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ports.ErrNotSupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// The APIs administer indexes through backends that support it
+
+func (a *RESTAPI) CreateIndex(spec ports.IndexSpec) error {
+	return indexAdmin(a.backend).CreateIndex(spec)
+}
+
+func (a *RESTAPI) DropIndex(name string) error {
+	return indexAdmin(a.backend).DropIndex(name)
+}
+
+func (a *RESTAPI) ConfigureIndex(name string, config map[string]interface{}) error {
+	return indexAdmin(a.backend).ConfigureIndex(name, config)
+}
+
+func (a *RESTAPI) FlushIndex(name string) error {
+	return indexAdmin(a.backend).FlushIndex(name)
+}
+
+func (a *RESTAPI) OptimizeIndex(name string) error {
+	return indexAdmin(a.backend).OptimizeIndex(name)
+}
+
+func (a *RESTAPI) ClearIndex(name string) error {
+	return indexAdmin(a.backend).ClearIndex(name)
+}
+
+func (a *RESTAPI) VerifyIndex(name, repair string) (ports.VerifyReport, error) {
+	return indexAdmin(a.backend).VerifyIndex(name, repair)
+}
+
+func (a *RESTAPI) SnapshotIndex(name string) (ports.Snapshot, error) {
+	return indexAdmin(a.backend).SnapshotIndex(name)
+}
+
+func (a *RESTAPI) SetAlias(alias, index string) error {
+	return indexAdmin(a.backend).SetAlias(alias, index)
+}
+
+func (a *RESTAPI) RemoveAlias(alias string) error {
+	return indexAdmin(a.backend).RemoveAlias(alias)
+}
+
+func (a *RESTAPI) TriggerLoader(ctx context.Context, name string) error {
+	return indexAdmin(a.backend).TriggerLoader(ctx, name)
+}
+
+func (g *GraphQLAPI) CreateIndex(spec ports.IndexSpec) error {
+	return indexAdmin(g.backend).CreateIndex(spec)
+}
+
+func (g *GraphQLAPI) DropIndex(name string) error {
+	return indexAdmin(g.backend).DropIndex(name)
+}
+
+func (g *GraphQLAPI) ConfigureIndex(name string, config map[string]interface{}) error {
+	return indexAdmin(g.backend).ConfigureIndex(name, config)
+}
+
+func (g *GraphQLAPI) FlushIndex(name string) error {
+	return indexAdmin(g.backend).FlushIndex(name)
+}
+
+func (g *GraphQLAPI) OptimizeIndex(name string) error {
+	return indexAdmin(g.backend).OptimizeIndex(name)
+}
+
+func (g *GraphQLAPI) ClearIndex(name string) error {
+	return indexAdmin(g.backend).ClearIndex(name)
+}
+
+func (g *GraphQLAPI) VerifyIndex(name, repair string) (ports.VerifyReport, error) {
+	return indexAdmin(g.backend).VerifyIndex(name, repair)
+}
+
+func (g *GraphQLAPI) SnapshotIndex(name string) (ports.Snapshot, error) {
+	return indexAdmin(g.backend).SnapshotIndex(name)
+}
+
+func (g *GraphQLAPI) SetAlias(alias, index string) error {
+	return indexAdmin(g.backend).SetAlias(alias, index)
+}
+
+func (g *GraphQLAPI) RemoveAlias(alias string) error {
+	return indexAdmin(g.backend).RemoveAlias(alias)
+}
+
+func (g *GraphQLAPI) TriggerLoader(ctx context.Context, name string) error {
+	return indexAdmin(g.backend).TriggerLoader(ctx, name)
+}
+
+// handleCreateIndex creates an index from a JSON body with its name, type and config
+func (a *RESTAPI) handleCreateIndex(w http.ResponseWriter, r *http.Request) {
+	var request createIndexRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	if err := a.CreateIndex(ports.IndexSpec{Name: request.Name, Type: request.Type, Config: request.Config}); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"index": request.Name, "status": "created"})
+}
+
+func (a *RESTAPI) handleDropIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.DropIndex(name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "dropped"})
+}
+
+// handleConfigureIndex applies the JSON object in the body as the index's new configuration
+func (a *RESTAPI) handleConfigureIndex(w http.ResponseWriter, r *http.Request) {
+	var config map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	name := r.PathValue("name")
+	if err := a.ConfigureIndex(name, config); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "configured"})
+}
+
+func (a *RESTAPI) handleFlushIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.FlushIndex(name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "flushed"})
+}
+
+func (a *RESTAPI) handleOptimizeIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.OptimizeIndex(name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "optimized"})
+}
+
+func (a *RESTAPI) handleClearIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.ClearIndex(name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "cleared"})
+}
+
+// handleVerifyIndex compares an index's documents in memory and in storage, repairing them in the
+// direction of the repair parameter ("disk" or "memory"; none by default)
+func (a *RESTAPI) handleVerifyIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	report, err := a.VerifyIndex(name, r.URL.Query().Get("repair"))
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newVerifyResponse(name, report))
+}
+
+// handleSnapshotIndex writes an export of the index on the server and answers where
+func (a *RESTAPI) handleSnapshotIndex(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := a.SnapshotIndex(r.PathValue("name"))
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, snapshotResponse{Index: snapshot.Index, Path: snapshot.Path, Time: snapshot.Time, Bytes: snapshot.Bytes})
+}
+
+// handleSetAlias points the alias in the path to the index named in the JSON body
+func (a *RESTAPI) handleSetAlias(w http.ResponseWriter, r *http.Request) {
+	var request setAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	alias := r.PathValue("alias")
+	if err := a.SetAlias(alias, request.Index); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"alias": alias, "index": request.Index, "status": "set"})
+}
+
+func (a *RESTAPI) handleRemoveAlias(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+	if err := a.RemoveAlias(alias); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"alias": alias, "status": "removed"})
+}
+
+// handleRunLoader runs a loader's pipeline and answers once the run is complete
+func (a *RESTAPI) handleRunLoader(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.TriggerLoader(r.Context(), name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"loader": name, "status": "completed"})
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// adminBackend keeps the names of its indexes and the operations applied to them
+type adminBackend struct {
+	memoryBackend
+	indexes    map[string]map[string]interface{}
+	operations []string
+}
+
+func newAdminBackend() *adminBackend {
+	return &adminBackend{indexes: map[string]map[string]interface{}{"docs": nil}}
+}
+
+func (b *adminBackend) CreateIndex(spec ports.IndexSpec) error {
+	if _, ok := b.indexes[spec.Name]; ok {
+		return fmt.Errorf("%w: index %s already exists", ports.ErrConflict, spec.Name)
+	}
+	b.indexes[spec.Name] = spec.Config
+	return nil
+}
+
+func (b *adminBackend) DropIndex(name string) error {
+	if _, ok := b.indexes[name]; !ok {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	delete(b.indexes, name)
+	return nil
+}
+
+func (b *adminBackend) ConfigureIndex(name string, config map[string]interface{}) error {
+	if _, ok := b.indexes[name]; !ok {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	b.indexes[name] = config
+	return nil
+}
+
+func (b *adminBackend) FlushIndex(name string) error {
+	b.operations = append(b.operations, "flush "+name)
+	return nil
+}
+
+func (b *adminBackend) OptimizeIndex(name string) error {
+	b.operations = append(b.operations, "optimize "+name)
+	return nil
+}
+
+func (b *adminBackend) ClearIndex(name string) error {
+	b.operations = append(b.operations, "clear "+name)
+	return nil
+}
+
+func (b *adminBackend) VerifyIndex(name, repair string) (ports.VerifyReport, error) {
+	if repair != "" && repair != "disk" {
+		return ports.VerifyReport{}, fmt.Errorf("%w: unknown repair direction %q", ports.ErrInvalid, repair)
+	}
+	return ports.VerifyReport{Documents: 2, Stored: 1, MissingOnDisk: []string{"2"}}, nil
+}
+
+func (b *adminBackend) SnapshotIndex(name string) (ports.Snapshot, error) {
+	return ports.Snapshot{Index: name, Path: "/snapshots/" + name + ".ndjson", Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Bytes: 42}, nil
+}
+
+func (b *adminBackend) SetAlias(alias, index string) error {
+	if _, ok := b.indexes[index]; !ok {
+		return fmt.Errorf("index %s: %w", index, ports.ErrNotFound)
+	}
+	b.operations = append(b.operations, "alias "+alias+" "+index)
+	return nil
+}
+
+func (b *adminBackend) RemoveAlias(alias string) error {
+	b.operations = append(b.operations, "unalias "+alias)
+	return nil
+}
+
+func (b *adminBackend) TriggerLoader(ctx context.Context, name string) error {
+	if name != "fs" {
+		return fmt.Errorf("pipeline for loader %s: %w", name, ports.ErrNotFound)
+	}
+	b.operations = append(b.operations, "run "+name)
+	return nil
+}
+
+func TestRESTAPI_IndexAdmin(t *testing.T) {
+	backend := newAdminBackend()
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	rec := serve(handler, http.MethodPost, "/indexes", `{"name":"logs","type":"simple","config":{"max":1}}`, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, backend.indexes, "logs")
+	rec = serve(handler, http.MethodPost, "/indexes", `{"name":"logs","type":"simple"}`, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(handler, http.MethodPut, "/indexes/logs/config", `{"max":2}`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]interface{}{"max": 2.0}, backend.indexes["logs"])
+	rec = serve(handler, http.MethodPut, "/indexes/missing/config", `{}`, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/indexes/logs/flush", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/indexes/logs/optimize", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/indexes/logs/clear", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/loaders/fs/run", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(handler, http.MethodPost, "/loaders/web/run", "", nil).Code)
+	assert.Equal(t, []string{"flush logs", "optimize logs", "clear logs", "run fs"}, backend.operations)
+
+	rec = serve(handler, http.MethodPost, "/indexes/logs/verify?repair=disk", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"index":"logs","consistent":false,"documents":2,"stored":1,"missingOnDisk":["2"],"missingInMemory":[],"mismatched":[],"repaired":0}`, rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodPost, "/indexes/logs/verify?repair=both", "", nil).Code)
+
+	rec = serve(handler, http.MethodPost, "/indexes/logs/snapshot", "", nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var snapshot snapshotResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshot))
+	assert.Equal(t, "/snapshots/logs.ndjson", snapshot.Path)
+	assert.Equal(t, int64(42), snapshot.Bytes)
+
+	rec = serve(handler, http.MethodPut, "/aliases/current", `{"index":"logs"}`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(handler, http.MethodPut, "/aliases/current", `{"index":"missing"}`, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodDelete, "/aliases/current", "", nil).Code)
+	assert.Equal(t, []string{"flush logs", "optimize logs", "clear logs", "run fs", "alias current logs", "unalias current"}, backend.operations)
+
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodDelete, "/indexes/logs", "", nil).Code)
+	assert.NotContains(t, backend.indexes, "logs")
+
+	// Backends without administration
+	rec = serve(NewRESTAPI(&memoryBackend{}, ":0").Handler(), http.MethodPost, "/indexes/docs/flush", "", nil)
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestRESTAPI_IndexAdminRequiresAdminScope(t *testing.T) {
+	rest := NewRESTAPI(newAdminBackend(), ":0")
+	rest.SetAuthenticator(newTestAuthenticator(t, nil))
+	handler := rest.Handler()
+
+	rec := serve(handler, http.MethodDelete, "/indexes/docs", "", map[string]string{"X-API-Key": "read-key"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	admin := signToken(t, jwt.MapClaims{"sub": "ops", "iss": "https://idp.example.com", "exp": time.Now().Add(time.Hour).Unix(), "scope": "admin"})
+	rec = serve(handler, http.MethodDelete, "/indexes/docs", "", map[string]string{"Authorization": "Bearer " + admin})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestGraphQLAPI_IndexAdmin(t *testing.T) {
+	backend := newAdminBackend()
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+	mutate := func(query string) map[string]json.RawMessage {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		rec := serve(handler, http.MethodPost, "/query", string(body), map[string]string{"Content-Type": "application/json"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	data := mutate(`mutation { createIndex(name: "logs", type: "simple", config: "{\"max\": 1}") { error } }`)
+	assert.JSONEq(t, `{"error": null}`, string(data["createIndex"]))
+	assert.Equal(t, map[string]interface{}{"max": 1.0}, backend.indexes["logs"])
+
+	data = mutate(`mutation { configureIndex(name: "logs", config: "not json") { error } }`)
+	assert.Contains(t, string(data["configureIndex"]), "invalid config")
+
+	data = mutate(`mutation { dropIndex(name: "missing") { error } }`)
+	assert.Contains(t, string(data["dropIndex"]), "not found")
+
+	mutate(`mutation { flushIndex(name: "logs") { error } optimizeIndex(name: "logs") { error } clearIndex(name: "logs") { error } runLoader(name: "fs") { error } }`)
+	assert.Equal(t, []string{"flush logs", "optimize logs", "clear logs", "run fs"}, backend.operations)
+
+	data = mutate(`mutation { verifyIndex(name: "logs") { index consistent documents stored missingOnDisk missingInMemory mismatched repaired error } }`)
+	assert.JSONEq(t, `{"index": "logs", "consistent": false, "documents": 2, "stored": 1, "missingOnDisk": ["2"], "missingInMemory": [], "mismatched": [], "repaired": 0, "error": null}`, string(data["verifyIndex"]))
+	data = mutate(`mutation { verifyIndex(name: "logs", repair: "both") { consistent error } }`)
+	assert.Contains(t, string(data["verifyIndex"]), "unknown repair direction")
+
+	data = mutate(`mutation { snapshotIndex(name: "logs") { index path time bytes error } }`)
+	assert.JSONEq(t, `{"index": "logs", "path": "/snapshots/logs.ndjson", "time": "2024-05-01T00:00:00Z", "bytes": 42, "error": null}`, string(data["snapshotIndex"]))
+}
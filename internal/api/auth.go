@@ -0,0 +1,517 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// Scopes group the endpoints of every API adapter; credentials are granted scopes
+const (
+	ScopeSearch = "search" // Searches and statistics
+	ScopeIndex  = "index"  // Adding, importing and deleting documents
+	ScopeAdmin  = "admin"  // Exports and engine commands (start, stop)
+)
+
+var (
+	// ErrUnauthenticated is returned when a protected endpoint is called without valid credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when the credentials do not grant the endpoint's scope
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Built-in roles, which configured roles of the same name replace
+const (
+	RoleReader = "reader" // Searches
+	RoleWriter = "writer" // Searches and indexing
+	RoleAdmin  = "admin"  // Every endpoint
+)
+
+// AuthConfig is the "auth" section of the starter config, applied to every API
+// Example: { "api_keys": [{ "name": "ci", "key": "s3cret", "roles": ["writer"] }], "jwt": { "secret": "...", "issuer": "https://idp" }, "protected": ["index", "admin"] }
+type AuthConfig struct {
+	APIKeys []APIKeyConfig `json:"api_keys,omitempty"`
+	JWT     *JWTConfig     `json:"jwt,omitempty"`
+	// Roles defines roles beyond (or replacing) reader, writer and admin
+	Roles []RoleConfig `json:"roles,omitempty"`
+	// Protected lists the scopes whose endpoints require credentials (default: all); the others are public
+	Protected []string `json:"protected,omitempty"`
+}
+
+// APIKeyConfig is a static key, sent as "Authorization: Bearer <key>" or "X-API-Key: <key>". It is
+// granted its scopes and those of its roles. A key bound to a namespace only reaches the namespace's index.
+// Its principals (e.g. groups) let it see the documents allowed to them, beyond those allowed to its name and roles.
+type APIKeyConfig struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key"`
+	Scopes     []string `json:"scopes,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Principals []string `json:"principals,omitempty"`
+}
+
+// RoleConfig grants scopes and restricts the documents searches return to those matching Filter, a
+// query expression such as "directory contains public" (empty: no restriction of its own). Holders of an
+// Unrestricted role see every document, whatever the filters of their other roles.
+// Example: { "name": "partner", "scopes": ["search"], "filter": "directory contains public" }
+type RoleConfig struct {
+	Name         string   `json:"name"`
+	Scopes       []string `json:"scopes"`
+	Filter       string   `json:"filter,omitempty"`
+	Unrestricted bool     `json:"unrestricted,omitempty"`
+}
+
+// FilterCompiler compiles a role's filter expression into a document predicate
+type FilterCompiler func(expression string) (func(doc models.Document) bool, error)
+
+// role is a compiled RoleConfig
+type role struct {
+	scopes       []string
+	filter       func(doc models.Document) bool // nil: no restriction of its own
+	unrestricted bool
+}
+
+// builtInRoles are available without configuration
+var builtInRoles = map[string]RoleConfig{
+	RoleReader: {Name: RoleReader, Scopes: []string{ScopeSearch}},
+	RoleWriter: {Name: RoleWriter, Scopes: []string{ScopeSearch, ScopeIndex}},
+	RoleAdmin:  {Name: RoleAdmin, Scopes: []string{ScopeSearch, ScopeIndex, ScopeAdmin}},
+}
+
+// JWTConfig validates bearer JWTs signed with an HMAC secret (HS256/384/512) or the private key of a
+// PEM public key (RS*, PS*, ES* or EdDSA). The token's scopes are read from ScopeClaim, a space
+// separated string or a list.
+type JWTConfig struct {
+	Secret        string `json:"secret,omitempty"`
+	PublicKeyFile string `json:"public_key_file,omitempty"`
+	Issuer        string `json:"issuer,omitempty"`
+	Audience      string `json:"audience,omitempty"`
+	ScopeClaim    string `json:"scope_claim,omitempty"` // Default "scope"
+	RoleClaim     string `json:"role_claim,omitempty"`  // Claim holding the token's roles, a list or space separated string (default "roles")
+	// Claim binding the token to a namespace (default "namespace"); tokens without it are unbound
+	NamespaceClaim string `json:"namespace_claim,omitempty"`
+	// Claim holding the token's principals beyond its subject and roles, a list or space separated string (default "groups")
+	PrincipalClaim string `json:"principal_claim,omitempty"`
+	Leeway         string `json:"leeway,omitempty"` // Allowed clock skew, e.g. 30s
+}
+
+// Principal is the caller identified by an Authenticator
+type Principal struct {
+	Name   string // API key name or JWT subject
+	Roles  []string
+	Scopes map[string]bool
+	// Filter restricts the documents the caller sees to those matching the filter of any of its roles
+	// (nil: all documents)
+	Filter func(doc models.Document) bool
+	// Namespace is the tenant namespace the caller is bound to: it indexes into, searches and counts
+	// only the namespace's index (empty: unbound, the default index and engine-wide endpoints)
+	Namespace string
+	// Principals are the name, roles and groups of the caller: it sees the documents with AllowedPrincipals
+	// only if it holds one of them
+	Principals []string
+}
+
+type principalKey struct{}
+
+// PrincipalFrom returns the caller authenticated for a request, if any
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// Authenticator checks API keys and JWTs and enforces the scopes of endpoints. A nil Authenticator
+// allows every request, so adapters can use it unconditionally.
+type Authenticator struct {
+	keys           []APIKeyConfig
+	roles          map[string]role
+	protected      map[string]bool
+	keyFunc        jwt.Keyfunc
+	parser         *jwt.Parser
+	scopeClaim     string
+	roleClaim      string
+	namespaceClaim string
+	principalClaim string
+}
+
+// NewAuthenticator validates an auth config and builds its authenticator. Role filters are compiled
+// with filters; without a compiler roles cannot have filters.
+func NewAuthenticator(cfg AuthConfig, filters FilterCompiler) (*Authenticator, error) {
+	a := &Authenticator{keys: cfg.APIKeys, roles: make(map[string]role), protected: make(map[string]bool)}
+	roles := make(map[string]RoleConfig, len(builtInRoles)+len(cfg.Roles))
+	for name, rc := range builtInRoles {
+		roles[name] = rc
+	}
+	for _, rc := range cfg.Roles {
+		if rc.Name == "" {
+			return nil, errors.New("roles need a name")
+		}
+		roles[rc.Name] = rc
+	}
+	for name, rc := range roles {
+		if err := checkScopes(rc.Scopes); err != nil {
+			return nil, fmt.Errorf("role %s: %w", name, err)
+		}
+		compiled := role{scopes: rc.Scopes, unrestricted: rc.Unrestricted}
+		if rc.Filter != "" && rc.Unrestricted {
+			return nil, fmt.Errorf("role %s: an unrestricted role cannot have a filter", name)
+		}
+		if rc.Filter != "" {
+			if filters == nil {
+				return nil, fmt.Errorf("role %s: document filters are not supported", name)
+			}
+			filter, err := filters(rc.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("role %s: invalid filter %q: %w", name, rc.Filter, err)
+			}
+			compiled.filter = filter
+		}
+		a.roles[name] = compiled
+	}
+	for i, key := range cfg.APIKeys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("api key %d (%s) has no key", i, key.Name)
+		}
+		if err := checkScopes(key.Scopes); err != nil {
+			return nil, fmt.Errorf("api key %s: %w", key.Name, err)
+		}
+		for _, name := range key.Roles {
+			if _, ok := a.roles[name]; !ok {
+				return nil, fmt.Errorf("api key %s: unknown role %q", key.Name, name)
+			}
+		}
+	}
+	protected := cfg.Protected
+	if protected == nil {
+		protected = []string{ScopeSearch, ScopeIndex, ScopeAdmin}
+	}
+	if err := checkScopes(protected); err != nil {
+		return nil, fmt.Errorf("protected: %w", err)
+	}
+	for _, scope := range protected {
+		a.protected[scope] = true
+	}
+	if cfg.JWT != nil {
+		if err := a.configureJWT(*cfg.JWT); err != nil {
+			return nil, fmt.Errorf("jwt: %w", err)
+		}
+	}
+	if len(a.keys) == 0 && a.keyFunc == nil && len(a.protected) > 0 {
+		return nil, errors.New("protected endpoints need api_keys or jwt to authenticate with")
+	}
+	return a, nil
+}
+
+func checkScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope != ScopeSearch && scope != ScopeIndex && scope != ScopeAdmin {
+			return fmt.Errorf("unknown scope %q (known: %s, %s, %s)", scope, ScopeSearch, ScopeIndex, ScopeAdmin)
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) configureJWT(cfg JWTConfig) error {
+	options := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	switch {
+	case cfg.Secret != "" && cfg.PublicKeyFile != "":
+		return errors.New("set either secret or public_key_file, not both")
+	case cfg.Secret != "":
+		secret := []byte(cfg.Secret)
+		a.keyFunc = func(*jwt.Token) (interface{}, error) { return secret, nil }
+		options = append(options, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	case cfg.PublicKeyFile != "":
+		pem, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+		key, methods, err := parsePublicKey(pem)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cfg.PublicKeyFile, err)
+		}
+		a.keyFunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+		options = append(options, jwt.WithValidMethods(methods))
+	default:
+		return errors.New("secret or public_key_file is required")
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	if cfg.Leeway != "" {
+		leeway, err := time.ParseDuration(cfg.Leeway)
+		if err != nil {
+			return fmt.Errorf("invalid leeway %q: %w", cfg.Leeway, err)
+		}
+		options = append(options, jwt.WithLeeway(leeway))
+	}
+	a.parser = jwt.NewParser(options...)
+	a.scopeClaim = cfg.ScopeClaim
+	if a.scopeClaim == "" {
+		a.scopeClaim = "scope"
+	}
+	a.roleClaim = cfg.RoleClaim
+	if a.roleClaim == "" {
+		a.roleClaim = "roles"
+	}
+	a.namespaceClaim = cfg.NamespaceClaim
+	if a.namespaceClaim == "" {
+		a.namespaceClaim = "namespace"
+	}
+	a.principalClaim = cfg.PrincipalClaim
+	if a.principalClaim == "" {
+		a.principalClaim = "groups"
+	}
+	return nil
+}
+
+// parsePublicKey reads a PEM RSA, ECDSA or Ed25519 public key and the signing methods it verifies
+func parsePublicKey(pem []byte) (interface{}, []string, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return key, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(pem); err == nil {
+		return key, []string{"ES256", "ES384", "ES512"}, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+		return key, []string{"EdDSA"}, nil
+	}
+	return nil, nil, errors.New("not an RSA, ECDSA or Ed25519 public key")
+}
+
+// Authenticate identifies the caller presenting a credential (an API key or a JWT). An empty
+// credential returns no principal and no error.
+func (a *Authenticator) Authenticate(credential string) (*Principal, error) {
+	if credential == "" {
+		return nil, nil
+	}
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(credential), []byte(key.Key)) == 1 {
+			principal := a.principal(key.Name, key.Scopes, key.Roles, key.Principals)
+			principal.Namespace = key.Namespace
+			return principal, nil
+		}
+	}
+	if a.parser == nil {
+		return nil, fmt.Errorf("%w: unknown API key", ErrUnauthenticated)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(credential, claims, a.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+	}
+	subject, _ := claims.GetSubject()
+	principal := a.principal(subject, claimList(claims[a.scopeClaim]), claimList(claims[a.roleClaim]), claimList(claims[a.principalClaim]))
+	principal.Namespace, _ = claims[a.namespaceClaim].(string)
+	return principal, nil
+}
+
+// claimList reads a claim holding a space separated string or a list of strings
+func claimList(claim interface{}) []string {
+	var values []string
+	switch value := claim.(type) {
+	case string:
+		values = strings.Fields(value)
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// principal grants a caller its scopes and those of its roles. Unknown roles (e.g. of other
+// applications, in tokens) grant nothing. Callers holding roles with filters see the documents matching
+// any of them, whatever their other roles and scopes, unless one of their roles is unrestricted or they
+// have the admin scope. Their principals are their name, their roles and groups.
+func (a *Authenticator) principal(name string, scopes, roles, groups []string) *Principal {
+	principal := &Principal{Name: name, Scopes: make(map[string]bool), Principals: []string{}}
+	if name != "" {
+		principal.Principals = append(principal.Principals, name)
+	}
+	for _, scope := range scopes {
+		principal.Scopes[scope] = true
+	}
+	var filters []func(doc models.Document) bool
+	unrestricted := false
+	for _, name := range roles {
+		r, ok := a.roles[name]
+		if !ok {
+			continue
+		}
+		principal.Roles = append(principal.Roles, name)
+		for _, scope := range r.scopes {
+			principal.Scopes[scope] = true
+		}
+		if r.unrestricted {
+			unrestricted = true
+		}
+		if r.filter != nil {
+			filters = append(filters, r.filter)
+		}
+	}
+	principal.Principals = append(principal.Principals, principal.Roles...)
+	principal.Principals = append(principal.Principals, groups...)
+	if !unrestricted && !principal.Scopes[ScopeAdmin] && len(filters) > 0 {
+		principal.Filter = func(doc models.Document) bool {
+			for _, filter := range filters {
+				if filter(doc) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return principal
+}
+
+// documentFilter returns the document filter of the caller authenticated in ctx (nil: all documents)
+func documentFilter(ctx context.Context) func(doc models.Document) bool {
+	if principal, ok := PrincipalFrom(ctx); ok {
+		return principal.Filter
+	}
+	return nil
+}
+
+type documentPrincipalsKey struct{}
+
+// withDocumentPrincipals records in ctx, for documentPrincipals, that the request went through an
+// authenticator: anonymous callers (nil principal) only see the documents without access control list
+func withDocumentPrincipals(ctx context.Context, principal *Principal) context.Context {
+	principals := []string{}
+	if principal != nil {
+		principals = principal.Principals
+	}
+	return context.WithValue(ctx, documentPrincipalsKey{}, principals)
+}
+
+// documentPrincipals returns the principals of the caller in ctx, whose access control lists searches
+// enforce (nil: the API has no authenticator, every document is visible)
+func documentPrincipals(ctx context.Context) []string {
+	principals, _ := ctx.Value(documentPrincipalsKey{}).([]string)
+	return principals
+}
+
+// namespaceOf returns the namespace of the caller authenticated in ctx ("": unbound)
+func namespaceOf(ctx context.Context) string {
+	if principal, ok := PrincipalFrom(ctx); ok {
+		return principal.Namespace
+	}
+	return ""
+}
+
+// tenancy is what callers bound to a namespace may do with an endpoint
+type tenancy int
+
+const (
+	tenantScoped tenancy = iota // Serves the caller's namespace instead of the default index (searches, documents, statistics)
+	tenantIndex                 // Names an index, which must be the caller's namespace
+	tenantDenied                // Engine-wide (creating indexes, aliases, loaders, start and stop): unbound callers only
+)
+
+// authorizeNamespace checks that the caller authenticated in ctx may use an endpoint of the given
+// tenancy on index (the index it names, if any). Unbound callers may use every endpoint.
+func authorizeNamespace(ctx context.Context, t tenancy, index string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || principal.Namespace == "" {
+		return nil
+	}
+	switch {
+	case t == tenantDenied:
+		return fmt.Errorf("%w: %s is bound to namespace %s and may not use engine-wide endpoints", ErrForbidden, principal.Name, principal.Namespace)
+	case t == tenantIndex && index != principal.Namespace:
+		return fmt.Errorf("%w: %s is bound to namespace %s, not %s", ErrForbidden, principal.Name, principal.Namespace, index)
+	}
+	return nil
+}
+
+// Authorize checks that the caller authenticated in ctx may use an endpoint of the given scope
+func (a *Authenticator) Authorize(ctx context.Context, scope string) error {
+	if a == nil || !a.protected[scope] {
+		return nil
+	}
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: %s endpoints require an API key or token", ErrUnauthenticated, scope)
+	}
+	if !principal.Scopes[scope] {
+		return fmt.Errorf("%w: %s is not granted the %s scope", ErrForbidden, principal.Name, scope)
+	}
+	return nil
+}
+
+// credential extracts the bearer token or X-API-Key header of a request
+func credential(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Middleware authenticates the credentials of every request of api, storing the principal in the request
+// context for Authorize. Requests with invalid credentials are rejected with 401, even for public endpoints,
+// once they are counted against the rate limit of their IP so credentials cannot be guessed faster.
+func (a *Authenticator) Middleware(api string, limits *Limiter, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(credential(r))
+		if err != nil {
+			if wait, limitErr := limits.admit(api, clientKey(r)); limitErr != nil {
+				writeRateLimited(w, wait, limitErr)
+				return
+			}
+			log.Warn().Msgf("Rejected request to %s from %s: %s", r.URL.Path, r.RemoteAddr, err)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		ctx := withDocumentPrincipals(r.Context(), principal)
+		if principal != nil {
+			ctx = context.WithValue(ctx, principalKey{}, principal)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// require wraps a handler of the given scope and tenancy, answering 401 or 403 to callers that may not
+// use it. The index of tenantIndex handlers is their {name} path value.
+func (a *Authenticator) require(scope string, t tenancy, next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Authorize(r.Context(), scope); err != nil {
+			writeError(w, authStatus(err), err)
+			return
+		}
+		if err := authorizeNamespace(r.Context(), t, r.PathValue("name")); err != nil {
+			writeError(w, authStatus(err), err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authStatus maps an Authorize error to an HTTP status
+func authStatus(err error) int {
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func newTestAuthenticator(t *testing.T, protected []string) *Authenticator {
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys:   []APIKeyConfig{{Name: "reader", Key: "read-key", Scopes: []string{ScopeSearch}}},
+		JWT:       &JWTConfig{Secret: "jwt-secret", Issuer: "https://idp.example.com"},
+		Protected: protected,
+	}, nil)
+	assert.NoError(t, err)
+	return auth
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("jwt-secret"))
+	assert.NoError(t, err)
+	return token
+}
+
+func serve(handler http.Handler, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRESTAPI_APIKeys(t *testing.T) {
+	rest := NewRESTAPI(&memoryBackend{}, ":0")
+	rest.SetAuthenticator(newTestAuthenticator(t, nil))
+	handler := rest.Handler()
+	doc := `{"id":"1","text":"hello"}`
+
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/search?q=hello", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/search?q=hello", "", map[string]string{"X-API-Key": "wrong"}).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=hello", "", map[string]string{"X-API-Key": "read-key"}).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=hello", "", map[string]string{"Authorization": "Bearer read-key"}).Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodPost, "/documents", doc, map[string]string{"X-API-Key": "read-key"}).Code)
+
+	// Pings and health probes stay public
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/ping", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/healthz", "", nil).Code)
+}
+
+func TestRESTAPI_JWT(t *testing.T) {
+	rest := NewRESTAPI(&memoryBackend{}, ":0")
+	rest.SetAuthenticator(newTestAuthenticator(t, []string{ScopeIndex, ScopeAdmin}))
+	handler := rest.Handler()
+	doc := `{"id":"1","text":"hello"}`
+	bearer := func(claims jwt.MapClaims) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + signToken(t, claims)}
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	// Searches are public here; indexing needs a token granting the index scope
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=hello", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodPost, "/documents", doc, nil).Code)
+	assert.Equal(t, http.StatusCreated, serve(handler, http.MethodPost, "/documents", doc,
+		bearer(jwt.MapClaims{"sub": "ci", "iss": "https://idp.example.com", "exp": exp, "scope": "search index"})).Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodPost, "/documents", doc,
+		bearer(jwt.MapClaims{"sub": "ci", "iss": "https://idp.example.com", "exp": exp, "scope": []string{"search"}})).Code)
+
+	// Expired, foreign and non-expiring tokens are rejected
+	for _, claims := range []jwt.MapClaims{
+		{"sub": "ci", "iss": "https://idp.example.com", "exp": time.Now().Add(-time.Hour).Unix(), "scope": "index"},
+		{"sub": "ci", "iss": "https://other.example.com", "exp": exp, "scope": "index"},
+		{"sub": "ci", "iss": "https://idp.example.com", "scope": "index"},
+	} {
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodPost, "/documents", doc, bearer(claims)).Code)
+	}
+}
+
+func TestGraphQLAPI_AuthorizesFields(t *testing.T) {
+	backend := &memoryBackend{docs: []models.Document{{ID: "1", Text: "hello"}}}
+	graphQL := NewGraphQLAPI(backend, ":0")
+	graphQL.SetAuthenticator(newTestAuthenticator(t, nil))
+	handler := graphQL.Handler()
+	query := func(q string, headers map[string]string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]string{"query": q})
+		headers["Content-Type"] = "application/json"
+		rec := serve(handler, http.MethodPost, "/query", string(body), headers)
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := query(`{ search(query: {query: "hello"}) { totalCount } }`, map[string]string{})
+	assert.Contains(t, resp["errors"].([]interface{})[0].(map[string]interface{})["message"], "unauthenticated")
+
+	resp = query(`{ search(query: {query: "hello"}) { totalCount } }`, map[string]string{"X-API-Key": "read-key"})
+	assert.Nil(t, resp["errors"])
+	assert.Equal(t, 1.0, resp["data"].(map[string]interface{})["search"].(map[string]interface{})["totalCount"])
+
+	resp = query(`mutation { index(document: {id: "2", text: "hi"}) { error } }`, map[string]string{"X-API-Key": "read-key"})
+	assert.Contains(t, resp["errors"].([]interface{})[0].(map[string]interface{})["message"], "forbidden")
+	assert.Len(t, backend.docs, 1)
+
+	resp = query(`{ ping { pong } }`, map[string]string{})
+	assert.Nil(t, resp["errors"])
+	resp = query(`{ __schema { queryType { name } } }`, map[string]string{})
+	assert.Nil(t, resp["errors"])
+}
+
+func TestGraphQLAPI_DeniesFieldsWithoutScope(t *testing.T) {
+	// Every top-level field is public or has a scope
+	schema := NewExecutableSchema(Config{}).Schema()
+	for _, object := range []*ast.Definition{schema.Query, schema.Mutation, schema.Subscription} {
+		for _, field := range object.Fields {
+			name := object.Name + "." + field.Name
+			_, scoped := graphQLScopes[name]
+			assert.True(t, scoped || graphQLPublic[name] || strings.HasPrefix(field.Name, "__"), name)
+		}
+	}
+
+	// Fields added without one are not resolved, even without an authenticator
+	graphQL := NewGraphQLAPI(&memoryBackend{}, ":0")
+	resolved := false
+	next := func(ctx context.Context) (interface{}, error) {
+		resolved = true
+		return nil, nil
+	}
+	field := func(object, name string) context.Context {
+		return graphql.WithFieldContext(context.Background(), &graphql.FieldContext{Object: object, Field: graphql.CollectedField{Field: &ast.Field{Name: name}}})
+	}
+	_, err := graphQL.authorizeField(field("Query", "secrets"), next)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.False(t, resolved)
+	_, err = graphQL.authorizeField(field("Document", "secrets"), next)
+	assert.NoError(t, err, "fields of result types are not top-level fields")
+	assert.True(t, resolved)
+}
+
+func TestNewAuthenticator_Validates(t *testing.T) {
+	_, err := NewAuthenticator(AuthConfig{}, nil)
+	assert.ErrorContains(t, err, "need api_keys or jwt")
+	_, err = NewAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{{Name: "x", Key: "k", Scopes: []string{"write"}}}}, nil)
+	assert.ErrorContains(t, err, "unknown scope")
+	_, err = NewAuthenticator(AuthConfig{JWT: &JWTConfig{}}, nil)
+	assert.ErrorContains(t, err, "secret or public_key_file is required")
+
+	// Adapters that cannot enforce authentication are refused
+	factory := NewAPIFactory()
+	factory.SetAuthenticator(newTestAuthenticator(t, nil))
+	factory.RegisterType("plain", func(backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error) {
+		return plainAPI{}, nil
+	})
+	_, err = factory.Create("plain", &memoryBackend{}, nil)
+	assert.ErrorContains(t, err, "does not support authentication")
+}
+
+func TestAuthenticator_RoleFilters(t *testing.T) {
+	compile := func(expression string) (func(doc models.Document) bool, error) {
+		return func(doc models.Document) bool { return strings.Contains(doc.Source, expression) }, nil
+	}
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKeyConfig{
+			{Name: "partner", Key: "partner-key", Roles: []string{"partner"}},
+			{Name: "contractor", Key: "contractor-key", Roles: []string{"partner", RoleWriter}},
+			{Name: "staff", Key: "staff-key", Roles: []string{"partner", "staff"}},
+			{Name: "ops", Key: "ops-key", Roles: []string{"partner", RoleAdmin}},
+		},
+		JWT: &JWTConfig{Secret: "jwt-secret"},
+		Roles: []RoleConfig{
+			{Name: "partner", Scopes: []string{ScopeSearch}, Filter: "public"},
+			{Name: "staff", Scopes: []string{ScopeSearch}, Unrestricted: true},
+		},
+	}, compile)
+	assert.NoError(t, err)
+
+	backend := &memoryBackend{docs: []models.Document{
+		{ID: "1", Text: "report", Source: "/public/report.txt"},
+		{ID: "2", Text: "report", Source: "/internal/report.txt"},
+	}}
+	rest := NewRESTAPI(backend, ":0")
+	rest.SetAuthenticator(auth)
+	handler := rest.Handler()
+	search := func(headers map[string]string) []string {
+		rec := serve(handler, http.MethodGet, "/search?q=report", "", headers)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp searchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		var ids []string
+		for _, doc := range resp.Results {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	// Roles grant scopes; filters apply whatever the other roles, unless one is unrestricted or grants admin
+	assert.Equal(t, []string{"1"}, search(map[string]string{"X-API-Key": "partner-key"}))
+	assert.Equal(t, []string{"1"}, search(map[string]string{"X-API-Key": "contractor-key"}))
+	assert.Equal(t, []string{"1", "2"}, search(map[string]string{"X-API-Key": "staff-key"}))
+	assert.Equal(t, []string{"1", "2"}, search(map[string]string{"X-API-Key": "ops-key"}))
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodPost, "/documents", `{"id":"3"}`, map[string]string{"X-API-Key": "partner-key"}).Code)
+
+	// JWT roles, including ones unknown here; scopes granted directly do not lift the filters
+	exp := time.Now().Add(time.Hour).Unix()
+	token := signToken(t, jwt.MapClaims{"sub": "ext", "roles": []string{"partner", "billing"}, "exp": exp})
+	assert.Equal(t, []string{"1"}, search(map[string]string{"Authorization": "Bearer " + token}))
+	token = signToken(t, jwt.MapClaims{"sub": "ext", "scope": "search", "roles": []string{RoleReader, "partner"}, "exp": exp})
+	assert.Equal(t, []string{"1"}, search(map[string]string{"Authorization": "Bearer " + token}))
+	token = signToken(t, jwt.MapClaims{"sub": "ext", "scope": "search index", "exp": exp})
+	assert.Equal(t, []string{"1", "2"}, search(map[string]string{"Authorization": "Bearer " + token}))
+	token = signToken(t, jwt.MapClaims{"sub": "ext", "scope": "search admin", "roles": []string{"partner"}, "exp": exp})
+	assert.Equal(t, []string{"1", "2"}, search(map[string]string{"Authorization": "Bearer " + token}))
+
+	_, err = NewAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{{Key: "k", Roles: []string{"nobody"}}}}, compile)
+	assert.ErrorContains(t, err, `unknown role "nobody"`)
+	_, err = NewAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{{Key: "k"}}, Roles: []RoleConfig{{Name: "partner", Filter: "public"}}}, nil)
+	assert.ErrorContains(t, err, "document filters are not supported")
+	_, err = NewAuthenticator(AuthConfig{Roles: []RoleConfig{{Name: "partner", Filter: "public", Unrestricted: true}}}, compile)
+	assert.ErrorContains(t, err, "unrestricted role cannot have a filter")
+}
+
+func TestAuthenticator_DocumentPrincipals(t *testing.T) {
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKeyConfig{
+			{Name: "alice", Key: "alice-key", Roles: []string{RoleReader}},
+			{Name: "bob", Key: "bob-key", Roles: []string{RoleReader}, Principals: []string{"finance"}},
+		},
+		JWT:       &JWTConfig{Secret: "jwt-secret", PrincipalClaim: "teams"},
+		Protected: []string{ScopeIndex, ScopeAdmin},
+	}, nil)
+	assert.NoError(t, err)
+
+	backend := &memoryBackend{docs: []models.Document{
+		{ID: "1", Text: "report"},
+		{ID: "2", Text: "report", AllowedPrincipals: []string{"alice"}},
+		{ID: "3", Text: "report", AllowedPrincipals: []string{"finance", RoleAdmin}},
+	}}
+	rest := NewRESTAPI(backend, ":0")
+	rest.SetAuthenticator(auth)
+	handler := rest.Handler()
+	search := func(headers map[string]string) []string {
+		rec := serve(handler, http.MethodGet, "/search?q=report", "", headers)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp searchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		var ids []string
+		for _, doc := range resp.Results {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	// Callers see the documents allowed to their name, roles or groups; anonymous ones only unrestricted documents
+	assert.Equal(t, []string{"1"}, search(nil))
+	assert.Equal(t, []string{"1", "2"}, search(map[string]string{"X-API-Key": "alice-key"}))
+	assert.Equal(t, []string{"1", "3"}, search(map[string]string{"X-API-Key": "bob-key"}))
+	token := signToken(t, jwt.MapClaims{"sub": "carol", "teams": "finance legal", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.Equal(t, []string{"1", "3"}, search(map[string]string{"Authorization": "Bearer " + token}))
+	token = signToken(t, jwt.MapClaims{"sub": "dave", "roles": []string{RoleAdmin}, "exp": time.Now().Add(time.Hour).Unix()})
+	assert.Equal(t, []string{"1", "3"}, search(map[string]string{"Authorization": "Bearer " + token}))
+
+	// Without an authenticator access control lists are not enforced
+	handler = NewRESTAPI(backend, ":0").Handler()
+	assert.Equal(t, []string{"1", "2", "3"}, search(nil))
+}
+
+// plainAPI is an API adapter without authentication support
+type plainAPI struct{ ports.APIPort }
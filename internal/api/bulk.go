@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// bulkRequestItem is an item of POST /documents/bulk, e.g. { "action": "index", "document": { "id": "1", "text": "..." } }
+// or { "action": "delete", "id": "1" }. The action defaults to index.
+type bulkRequestItem struct {
+	Action   ports.BulkAction `json:"action,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Document *models.Document `json:"document,omitempty"`
+}
+
+// bulkResponse is the body returned by POST /documents/bulk, with the outcome of each item in request order
+type bulkResponse struct {
+	Errors bool               `json:"errors"` // Whether any item failed
+	Items  []bulkItemResponse `json:"items"`
+}
+
+// bulkItemResponse reports an item's outcome as an HTTP status (201 or 200 on success) and error
+type bulkItemResponse struct {
+	Action ports.BulkAction `json:"action"`
+	ID     string           `json:"id"`
+	Status int              `json:"status"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// toBulkItem converts a request item into a bulk item; a top-level id fills in a missing document id
+func (item bulkRequestItem) toBulkItem() ports.BulkItem {
+	out := ports.BulkItem{Action: item.Action}
+	if out.Action == "" {
+		out.Action = ports.BulkIndex
+	}
+	if item.Document != nil {
+		out.Document = *item.Document
+	}
+	if out.Document.ID == "" {
+		out.Document.ID = item.ID
+	}
+	return out
+}
+
+// toBulkResponse reports bulk results with a status per item
+func toBulkResponse(results ports.BulkResults) bulkResponse {
+	resp := bulkResponse{Errors: results.Failed() > 0, Items: make([]bulkItemResponse, len(results.Items))}
+	for i, item := range results.Items {
+		resp.Items[i] = bulkItemResponse{Action: item.Action, ID: item.ID, Status: bulkItemStatus(item)}
+		if item.Err != nil {
+			resp.Items[i].Error = item.Err.Error()
+		}
+	}
+	return resp
+}
+
+// bulkItemStatus maps the outcome of a bulk item to an HTTP status
+func bulkItemStatus(item ports.BulkItemResult) int {
+	switch {
+	case item.Err == nil && item.Action == ports.BulkIndex:
+		return http.StatusCreated
+	case item.Err == nil:
+		return http.StatusOK
+	case errors.Is(item.Err, ports.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(item.Err, ports.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(item.Err, ports.ErrNotSupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// bulk applies bulk items through backends that support them, to the namespace of the caller in ctx if
+// it is bound to one
+func bulk(ctx context.Context, backend ports.EnginePort, items []ports.BulkItem) (ports.BulkResults, error) {
+	if namespace := namespaceOf(ctx); namespace != "" {
+		return namespaces(backend).BulkNamespace(ctx, namespace, items)
+	}
+	bulker, ok := backend.(ports.BulkPort)
+	if !ok {
+		return ports.BulkResults{}, fmt.Errorf("%w: bulk requests", ports.ErrNotSupported)
+	}
+	return bulker.Bulk(ctx, items)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// toGraphQLDocument converts a document into its GraphQL representation (Meta is a JSON-encoded object)
+func toGraphQLDocument(doc models.Document) *Document {
+	out := &Document{
+		ID:     stringPtr(doc.ID),
+		Text:   stringPtr(doc.Text),
+		Source: stringPtr(doc.Source),
+		Vector: doc.Vector,
+	}
+	if len(doc.AllowedPrincipals) > 0 {
+		out.AllowedPrincipals = doc.AllowedPrincipals
+	}
+	if len(doc.Meta) > 0 {
+		if meta, err := json.Marshal(doc.Meta); err == nil {
+			out.Meta = stringPtr(string(meta))
+		}
+	}
+	return out
+}
+
+// fromDocumentInput converts a GraphQL document input into a document
+func fromDocumentInput(input DocumentInput) (models.Document, error) {
+	doc := models.Document{
+		ID:                derefString(input.ID),
+		Text:              derefString(input.Text),
+		Source:            derefString(input.Source),
+		Vector:            input.Vector,
+		AllowedPrincipals: input.AllowedPrincipals,
+	}
+	if input.Meta != nil && *input.Meta != "" {
+		if err := json.Unmarshal([]byte(*input.Meta), &doc.Meta); err != nil {
+			return models.Document{}, err
+		}
+	}
+	return doc, nil
+}
+
+// queryProjection reads the fields of a GraphQL query input, named as in the Document type
+func queryProjection(fields []string) (models.Projection, error) {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = strings.Replace(field, "allowedPrincipals", "allowed_principals", 1)
+	}
+	projection, err := models.ParseProjection(names)
+	if err != nil {
+		return models.Projection{}, fmt.Errorf("invalid fields: %w", err)
+	}
+	return projection, nil
+}
+
+// toStatsResult converts engine statistics into their GraphQL representation. Times are RFC 3339
+// strings, null when unset; durations are seconds.
+func toStatsResult(stats ports.Stats) *StatsResult {
+	out := &StatsResult{
+		NumDocuments:      stats.NumDocuments,
+		StartedAt:         timePtr(stats.StartedAt),
+		UptimeSeconds:     stats.Uptime.Seconds(),
+		Indexes:           make([]*IndexStats, len(stats.Indexes)),
+		Loaders:           make([]*LoaderStats, len(stats.Loaders)),
+		FeatureExtractors: make([]*FeatureExtractorStats, len(stats.FeatureExtractors)),
+		Memory: &MemoryStats{
+			HeapAllocBytes:     int(stats.Memory.HeapAllocBytes),
+			HeapInuseBytes:     int(stats.Memory.HeapInuseBytes),
+			SysBytes:           int(stats.Memory.SysBytes),
+			NumGc:              int(stats.Memory.NumGC),
+			LastGCPauseSeconds: stats.Memory.LastGCPause.Seconds(),
+		},
+		Queries: &QueryStats{
+			Total:     int(stats.Queries.Total),
+			Failed:    int(stats.Queries.Failed),
+			LastQuery: timePtr(stats.Queries.LastQuery),
+		},
+	}
+	if stats.Queries.Total > 0 {
+		out.Queries.AverageLatencySeconds = stats.Queries.TotalLatency.Seconds() / float64(stats.Queries.Total)
+	}
+	for i, index := range stats.Indexes {
+		out.Indexes[i] = &IndexStats{Name: index.Name, NumDocuments: index.NumDocuments}
+		if index.SizeBytes >= 0 {
+			size := index.SizeBytes
+			out.Indexes[i].SizeBytes = &size
+		}
+		if persistence := index.Persistence; persistence != nil {
+			out.Indexes[i].Persistence = &PersistenceStats{
+				Failed:      int(persistence.Failed),
+				Retried:     int(persistence.Retried),
+				LastFailure: timePtr(persistence.LastFailure),
+			}
+			if persistence.LastError != "" {
+				out.Indexes[i].Persistence.LastError = stringPtr(persistence.LastError)
+			}
+		}
+	}
+	for i, loader := range stats.Loaders {
+		out.Loaders[i] = &LoaderStats{
+			Name:                loader.Name,
+			Index:               loader.Index,
+			IntervalSeconds:     loader.Interval.Seconds(),
+			Runs:                loader.Runs,
+			Running:             loader.Running,
+			LastRun:             timePtr(loader.LastRun),
+			LastDurationSeconds: loader.LastDuration.Seconds(),
+			NextRun:             timePtr(loader.NextRun),
+			Added:               loader.Added,
+			Modified:            loader.Modified,
+			Deleted:             loader.Deleted,
+		}
+		if loader.LastError != "" {
+			out.Loaders[i].LastError = stringPtr(loader.LastError)
+		}
+	}
+	for i, extractor := range stats.FeatureExtractors {
+		out.FeatureExtractors[i] = &FeatureExtractorStats{Name: extractor.Name, Loaders: extractor.Loaders}
+		if extractor.Loaders == nil {
+			out.FeatureExtractors[i].Loaders = []string{}
+		}
+	}
+	return out
+}
+
+// toQueryValidationResult converts the validation of a query to its GraphQL type
+func toQueryValidationResult(validation ports.QueryValidation) *QueryValidationResult {
+	return &QueryValidationResult{Valid: validation.Valid, Errors: toGraphQLQueryErrors(validation.Errors)}
+}
+
+// toQueryPlanResult converts the plan of a query to its GraphQL type
+func toQueryPlanResult(plan ports.QueryPlan) *QueryPlanResult {
+	steps := make([]*PlanStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = &PlanStep{Condition: step.Condition, Access: step.Access, Estimate: step.Estimate}
+	}
+	return &QueryPlanResult{Index: plan.Index, Plan: plan.Plan, Documents: plan.Documents, Steps: steps}
+}
+
+// toGraphQLQueryErrors converts the problems of a query to their GraphQL type
+func toGraphQLQueryErrors(errs []ports.QueryError) []*QueryError {
+	out := make([]*QueryError, len(errs))
+	for i, err := range errs {
+		out[i] = &QueryError{Offset: err.Offset, Length: err.Length, Code: err.Code, Message: err.Message}
+		if err.Suggestion != "" {
+			out[i].Suggestion = stringPtr(err.Suggestion)
+		}
+	}
+	return out
+}
+
+// toGraphQLStoredQueries converts stored queries to their GraphQL type
+func toGraphQLStoredQueries(queries []ports.StoredQuery) []*StoredQuery {
+	out := make([]*StoredQuery, len(queries))
+	for i, query := range queries {
+		out[i] = toGraphQLStoredQuery(query)
+	}
+	return out
+}
+
+func toGraphQLStoredQuery(query ports.StoredQuery) *StoredQuery {
+	out := &StoredQuery{ID: query.ID, Query: query.Query, Created: query.Created.UTC().Format(time.RFC3339)}
+	if query.Namespace != "" {
+		out.Namespace = stringPtr(query.Namespace)
+	}
+	return out
+}
+
+// toGraphQLSavedSearches converts saved searches to their GraphQL type
+func toGraphQLSavedSearches(searches []ports.SavedSearch) []*SavedSearch {
+	out := make([]*SavedSearch, len(searches))
+	for i, search := range searches {
+		out[i] = toGraphQLSavedSearch(search)
+	}
+	return out
+}
+
+func toGraphQLSavedSearch(search ports.SavedSearch) *SavedSearch {
+	out := &SavedSearch{Name: search.Name, Query: search.Query, Fields: search.Fields, Updated: search.Updated.UTC().Format(time.RFC3339)}
+	if search.Sort != "" {
+		out.Sort = stringPtr(search.Sort)
+	}
+	if len(search.Params) > 0 {
+		if data, err := json.Marshal(search.Params); err == nil {
+			out.Params = stringPtr(string(data))
+		}
+	}
+	return out
+}
+
+// commandResult wraps an error (or success) as a CommandResult
+func commandResult(err error) *CommandResult {
+	if err == nil {
+		return &CommandResult{}
+	}
+	return &CommandResult{Error: stringPtr(err.Error())}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// timePtr formats t as RFC 3339, or returns nil for the zero time
+func timePtr(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	return stringPtr(t.UTC().Format(time.RFC3339))
+}
+
+func toGraphQLDimensions(dimensions []ports.Dimension) []*Dimension {
+	out := make([]*Dimension, len(dimensions))
+	for i, dimension := range dimensions {
+		out[i] = &Dimension{Name: dimension.Name, Documents: dimension.Documents, Values: dimension.Values}
+	}
+	return out
+}
+
+func toGraphQLValueCounts(values []ports.ValueCount) []*ValueCount {
+	out := make([]*ValueCount, len(values))
+	for i, value := range values {
+		out[i] = &ValueCount{Value: value.Value, Count: value.Count}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+)
+
+// CORSConfig lets browser frontends served from other origins call an API
+// Example: { "origins": ["http://localhost:3000"], "headers": ["X-Request-ID"], "max_age": "10m" }
+type CORSConfig struct {
+	Origins []string      // Allowed origins; "*" allows any
+	Headers []string      // Request headers allowed besides Authorization, Content-Type, X-API-Key and traceparent
+	MaxAge  time.Duration // How long browsers may cache preflight responses (0: browser default)
+}
+
+// corsHeaders are the request headers the APIs read, always allowed
+var corsHeaders = []string{"Authorization", "Content-Type", "X-API-Key", "traceparent"}
+
+// corsFromConfig reads the optional "cors" object of an API's config (nil: CORS disabled)
+func corsFromConfig(cfg map[string]interface{}) (*CORSConfig, error) {
+	section, err := config.Map(cfg, "cors")
+	if err != nil || section == nil {
+		return nil, err
+	}
+	origins, err := config.Strings(section, "origins")
+	if err != nil {
+		return nil, err
+	}
+	if len(origins) == 0 {
+		return nil, errors.New("cors needs at least one origin")
+	}
+	headers, err := config.Strings(section, "headers")
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := config.Duration(section, "max_age", 0)
+	if err != nil {
+		return nil, err
+	}
+	return &CORSConfig{Origins: origins, Headers: headers, MaxAge: maxAge}, nil
+}
+
+// allowed returns the Access-Control-Allow-Origin value for a request origin, if it is allowed
+func (c *CORSConfig) allowed(origin string) (string, bool) {
+	for _, allowed := range c.Origins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// middleware adds the CORS headers for allowed origins and answers their preflight requests. It
+// runs before authentication, as browsers send preflights without credentials. A nil config
+// leaves requests untouched.
+func (c *CORSConfig) middleware(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	headers := strings.Join(append(slices.Clone(corsHeaders), c.Headers...), ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowOrigin, ok := c.allowed(origin)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if c.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	created, err := NewAPIFactory().Create("GraphQL", &memoryBackend{}, map[string]interface{}{
+		"cors":       map[string]interface{}{"origins": []interface{}{"http://localhost:3000"}, "headers": "X-Request-ID", "max_age": "10m"},
+		"playground": "/playground",
+	})
+	assert.NoError(t, err)
+	graphql := created.(*GraphQLAPI)
+	graphql.SetAuthenticator(newTestAuthenticator(t, nil))
+	handler := graphql.Handler()
+
+	// Preflights need no credentials
+	rec := serve(handler, http.MethodOptions, "/query", "", map[string]string{
+		"Origin":                        "http://localhost:3000",
+		"Access-Control-Request-Method": "POST",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "X-Request-ID")
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = serve(handler, http.MethodPost, "/query", `{"query":"{ ping { pong } }"}`, map[string]string{
+		"Origin":       "http://localhost:3000",
+		"Content-Type": "application/json",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Other origins get no CORS headers
+	rec = serve(handler, http.MethodPost, "/query", `{"query":"{ ping { pong } }"}`, map[string]string{
+		"Origin":       "http://evil.example.com",
+		"Content-Type": "application/json",
+	})
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(handler, http.MethodGet, "/playground", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "graphiql"))
+}
+
+func TestCORS_Config(t *testing.T) {
+	rest, err := NewAPIFactory().Create("REST", &memoryBackend{}, map[string]interface{}{
+		"cors": map[string]interface{}{"origins": "*"},
+	})
+	assert.NoError(t, err)
+	rec := serve(rest.(*RESTAPI).Handler(), http.MethodGet, "/ping", "", map[string]string{"Origin": "http://app.example.com"})
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	_, err = NewAPIFactory().Create("REST", &memoryBackend{}, map[string]interface{}{"cors": map[string]interface{}{}})
+	assert.ErrorContains(t, err, "at least one origin")
+	_, err = NewAPIFactory().Create("GraphQL", &memoryBackend{}, map[string]interface{}{"playground": "/query"})
+	assert.ErrorContains(t, err, "playground must be a path")
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// defaultTopValues is the number of values topValues returns when no limit is given
+const defaultTopValues = 10
+
+// dimensions returns the dimension introspection of a backend (or API), or one failing with
+// ErrNotSupported
+func dimensions(backend ports.EnginePort) ports.DimensionPort {
+	if lister, ok := backend.(ports.DimensionPort); ok {
+		return lister
+	}
+	return unsupportedDimensions{}
+}
+
+// unsupportedDimensions stands in for the dimension introspection of backends that have none
+type unsupportedDimensions struct{}
+
+func (unsupportedDimensions) err() error {
+	return fmt.Errorf("%w: dimension introspection", ports.ErrNotSupported)
+}
+
+func (u unsupportedDimensions) ListDimensions(context.Context, ports.DimensionQuery) ([]ports.Dimension, error) {
+	return nil, u.err()
+}
+func (u unsupportedDimensions) TopValues(context.Context, ports.DimensionQuery, string, int) ([]ports.ValueCount, error) {
+	return nil, u.err()
+}
+
+// dimensionQuery selects the documents of the caller authenticated in ctx, as its searches do
+func dimensionQuery(ctx context.Context) ports.DimensionQuery {
+	return ports.DimensionQuery{Namespace: namespaceOf(ctx), Principals: documentPrincipals(ctx), Filter: documentFilter(ctx)}
+}
+
+// The APIs describe dimensions through backends that support it
+
+func (a *RESTAPI) ListDimensions(ctx context.Context, query ports.DimensionQuery) ([]ports.Dimension, error) {
+	return dimensions(a.backend).ListDimensions(ctx, query)
+}
+
+func (a *RESTAPI) TopValues(ctx context.Context, query ports.DimensionQuery, dimension string, n int) ([]ports.ValueCount, error) {
+	return dimensions(a.backend).TopValues(ctx, query, dimension, n)
+}
+
+func (g *GraphQLAPI) ListDimensions(ctx context.Context, query ports.DimensionQuery) ([]ports.Dimension, error) {
+	return dimensions(g.backend).ListDimensions(ctx, query)
+}
+
+func (g *GraphQLAPI) TopValues(ctx context.Context, query ports.DimensionQuery, dimension string, n int) ([]ports.ValueCount, error) {
+	return dimensions(g.backend).TopValues(ctx, query, dimension, n)
+}
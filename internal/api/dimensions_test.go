@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// dimensionBackend describes one dimension, fileExtension, recording the last query
+type dimensionBackend struct {
+	memoryBackend
+	query ports.DimensionQuery
+}
+
+func (b *dimensionBackend) ListDimensions(_ context.Context, query ports.DimensionQuery) ([]ports.Dimension, error) {
+	b.query = query
+	return []ports.Dimension{{Name: "fileExtension", Documents: 3, Values: 2}}, nil
+}
+
+func (b *dimensionBackend) TopValues(_ context.Context, query ports.DimensionQuery, dimension string, n int) ([]ports.ValueCount, error) {
+	b.query = query
+	values := []ports.ValueCount{{Value: "go", Count: 2}, {Value: "md", Count: 1}}
+	if n > 0 && n < len(values) {
+		values = values[:n]
+	}
+	return values, nil
+}
+
+// queryGraphQL posts a GraphQL query to a new GraphQL API of backend, authenticated by auth (nil: none)
+// with the API key (empty: anonymous)
+func queryGraphQL(backend ports.EnginePort, auth *Authenticator, key, q string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	graphQL := NewGraphQLAPI(backend, ":0")
+	if auth != nil {
+		graphQL.SetAuthenticator(auth)
+	}
+	rec := httptest.NewRecorder()
+	graphQL.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGraphQLAPI_Dimensions(t *testing.T) {
+	query := func(backend ports.EnginePort, q string) *httptest.ResponseRecorder {
+		return queryGraphQL(backend, nil, "", q)
+	}
+
+	rec := query(&dimensionBackend{}, `{ dimensions { dimensions { name documents values } error } topValues(dimension: "fileExtension", limit: 1) { dimension values { value count } error } }`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{
+		"dimensions":{"dimensions":[{"name":"fileExtension","documents":3,"values":2}],"error":null},
+		"topValues":{"dimension":"fileExtension","values":[{"value":"go","count":2}],"error":null}}}`, rec.Body.String())
+
+	rec = query(&dimensionBackend{}, `{ topValues(dimension: "fileExtension", limit: -1) { values { value } error } }`)
+	assert.Contains(t, rec.Body.String(), "limit must not be negative")
+
+	// Backends without dimension introspection
+	rec = query(&memoryBackend{}, `{ dimensions { dimensions { name } error } }`)
+	assert.Contains(t, rec.Body.String(), "not supported")
+}
+
+func TestGraphQLAPI_DimensionsOfCaller(t *testing.T) {
+	compile := func(expression string) (func(doc models.Document) bool, error) {
+		return func(doc models.Document) bool { return strings.Contains(doc.Source, expression) }, nil
+	}
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys:   []APIKeyConfig{{Name: "partner", Key: "partner-key", Roles: []string{"partner"}, Principals: []string{"acme"}}},
+		Roles:     []RoleConfig{{Name: "partner", Scopes: []string{ScopeSearch}, Filter: "public"}},
+		Protected: []string{ScopeIndex, ScopeAdmin},
+	}, compile)
+	assert.NoError(t, err)
+
+	// The backend counts only the documents the caller's searches would return
+	backend := &dimensionBackend{}
+	for _, q := range []string{`{ dimensions { error } }`, `{ topValues(dimension: "fileExtension") { error } }`} {
+		backend.query = ports.DimensionQuery{}
+		rec := queryGraphQL(backend, auth, "partner-key", q)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"partner", "partner", "acme"}, backend.query.Principals, q)
+		if assert.NotNil(t, backend.query.Filter, q) {
+			assert.True(t, backend.query.Filter(models.Document{Source: "/public/a.txt"}))
+			assert.False(t, backend.query.Filter(models.Document{Source: "/internal/a.txt"}))
+		}
+	}
+
+	// Anonymous callers see only the documents without access control list
+	backend.query = ports.DimensionQuery{}
+	assert.Equal(t, http.StatusOK, queryGraphQL(backend, auth, "", `{ dimensions { error } }`).Code)
+	assert.Equal(t, []string{}, backend.query.Principals)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// documentGetter returns the fetching of documents by ID of a backend (or API), or one failing with
+// ErrNotSupported
+func documentGetter(backend ports.EnginePort) ports.DocumentGetterPort {
+	if getter, ok := backend.(ports.DocumentGetterPort); ok {
+		return getter
+	}
+	return unsupportedDocumentGetter{}
+}
+
+// unsupportedDocumentGetter stands in for the fetching of documents of backends that have none
+type unsupportedDocumentGetter struct{}
+
+func (unsupportedDocumentGetter) GetDocuments(context.Context, ports.SearchQuery, []string) ([]models.Document, error) {
+	return nil, fmt.Errorf("%w: fetching documents by ID", ports.ErrNotSupported)
+}
+
+// The APIs fetch documents through backends that support it
+
+func (a *RESTAPI) GetDocuments(ctx context.Context, query ports.SearchQuery, ids []string) ([]models.Document, error) {
+	return documentGetter(a.backend).GetDocuments(ctx, query, ids)
+}
+
+func (g *GraphQLAPI) GetDocuments(ctx context.Context, query ports.SearchQuery, ids []string) ([]models.Document, error) {
+	return documentGetter(g.backend).GetDocuments(ctx, query, ids)
+}
+
+// documentLister returns the listing of documents of a backend, or one failing with ErrNotSupported
+func documentLister(backend ports.EnginePort) ports.DocumentListerPort {
+	if lister, ok := backend.(ports.DocumentListerPort); ok {
+		return lister
+	}
+	return unsupportedDocumentLister{}
+}
+
+// unsupportedDocumentLister stands in for the listing of documents of backends that have none
+type unsupportedDocumentLister struct{}
+
+func (unsupportedDocumentLister) ListDocuments(context.Context, string, ports.ListOptions) (ports.DocumentPage, error) {
+	return ports.DocumentPage{}, fmt.Errorf("%w: listing documents", ports.ErrNotSupported)
+}
+
+func (a *RESTAPI) ListDocuments(ctx context.Context, name string, opts ports.ListOptions) (ports.DocumentPage, error) {
+	return documentLister(a.backend).ListDocuments(ctx, name, opts)
+}
+
+// documentsQuery is the query documents are fetched with for the caller of ctx: its namespace and
+// document filters
+func documentsQuery(ctx context.Context, projection models.Projection) ports.SearchQuery {
+	return ports.SearchQuery{
+		Filter:     documentFilter(ctx),
+		Namespace:  namespaceOf(ctx),
+		Principals: documentPrincipals(ctx),
+		Projection: projection,
+	}
+}
+
+// handleGetDocument writes the document with the ID of the path, 404 if the caller's index has none the
+// caller may see
+func (a *RESTAPI) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	projection, err := searchProjection(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id := r.PathValue("id")
+	docs, err := a.GetDocuments(r.Context(), documentsQuery(r.Context(), projection), []string{id})
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	if len(docs) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("document %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, docs[0])
+}
+
+// mgetRequest is the body of POST /documents/mget
+type mgetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// handleMultiGet writes the documents with the IDs of the body, in their order, leaving out those not found
+func (a *RESTAPI) handleMultiGet(w http.ResponseWriter, r *http.Request) {
+	var request mgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	if len(request.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("ids must list at least one document ID"))
+		return
+	}
+	projection, err := searchProjection(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	docs, err := a.GetDocuments(r.Context(), documentsQuery(r.Context(), projection), request.IDs)
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"documents": docs})
+}
+
+// listOptions reads the after, offset, limit and where parameters of a listing of documents
+func listOptions(r *http.Request) (ports.ListOptions, error) {
+	params := r.URL.Query()
+	opts := ports.ListOptions{After: params.Get("after"), Where: params.Get("where")}
+	for name, value := range map[string]*int{"offset": &opts.Offset, "limit": &opts.Limit} {
+		if params.Get(name) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(params.Get(name))
+		if err != nil || n < 0 {
+			return ports.ListOptions{}, fmt.Errorf("%s must be a non-negative integer, got %q", name, params.Get(name))
+		}
+		*value = n
+	}
+	return opts, nil
+}
+
+// handleListDocuments writes a page of the documents of an index, in ID order, with the total matching
+// and the after parameter of the next page
+func (a *RESTAPI) handleListDocuments(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := a.ListDocuments(r.Context(), r.PathValue("name"), opts)
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	if page.Documents == nil {
+		page.Documents = []models.Document{}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// gettingBackend fetches its documents by ID
+type gettingBackend struct {
+	memoryBackend
+}
+
+func (b *gettingBackend) GetDocuments(ctx context.Context, query ports.SearchQuery, ids []string) ([]models.Document, error) {
+	var docs []models.Document
+	for _, id := range ids {
+		for _, doc := range b.docs {
+			if doc.ID == id && (query.Filter == nil || query.Filter(doc)) {
+				docs = append(docs, query.Projection.Apply(doc))
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (b *gettingBackend) ListDocuments(ctx context.Context, name string, opts ports.ListOptions) (ports.DocumentPage, error) {
+	if name != "docs" {
+		return ports.DocumentPage{}, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	docs := b.docs[min(opts.Offset, len(b.docs)):]
+	return ports.DocumentPage{Documents: docs[:min(opts.Limit, len(docs))], Total: len(b.docs), Next: opts.Where}, nil
+}
+
+func TestRESTAPI_GetDocuments(t *testing.T) {
+	backend := &gettingBackend{memoryBackend{docs: []models.Document{{ID: "1", Text: "one", Source: "a.go"}, {ID: "2", Text: "two"}}}}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/documents/1?fields=source", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"1","text":"","source":"a.go"}`, rec.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/documents/missing", "").Code)
+
+	rec = serve(http.MethodPost, "/documents/mget", `{"ids":["2","missing","1"]}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Documents []models.Document `json:"documents"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Documents, 2) {
+		assert.Equal(t, "2", resp.Documents[0].ID)
+		assert.Equal(t, "1", resp.Documents[1].ID)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/documents/mget", `{"ids":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/documents/mget", `{"ids":`).Code)
+
+	// Backends without fetching by ID
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/documents/1", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_Documents(t *testing.T) {
+	backend := &gettingBackend{memoryBackend{docs: []models.Document{{ID: "1", Text: "one"}, {ID: "2", Text: "two"}}}}
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+	body, _ := json.Marshal(map[string]string{"query": `{ document(id: "2") { document { id text } error } missing: document(id: "3") { document { id } error } documents(ids: ["1", "3", "2"]) { documents { id } error } }`})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			Document  DocumentResult  `json:"document"`
+			Missing   DocumentResult  `json:"missing"`
+			Documents DocumentsResult `json:"documents"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.NotNil(t, resp.Data.Document.Document) {
+		assert.Equal(t, "two", derefString(resp.Data.Document.Document.Text))
+	}
+	assert.Nil(t, resp.Data.Missing.Document)
+	assert.Nil(t, resp.Data.Missing.Error)
+	assert.Len(t, resp.Data.Documents.Documents, 2)
+}
+
+func TestRESTAPI_ListDocuments(t *testing.T) {
+	backend := &gettingBackend{memoryBackend{docs: []models.Document{{ID: "1", Text: "one"}, {ID: "2", Text: "two"}}}}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/indexes/docs/documents?offset=1&limit=5&where=x")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"documents":[{"id":"2","text":"two","source":""}],"total":2,"next":"x"}`, rec.Body.String())
+	rec = get("/indexes/docs/documents?offset=5&limit=5")
+	assert.JSONEq(t, `{"documents":[],"total":2}`, rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, get("/indexes/docs/documents?limit=-1").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/indexes/docs/documents?offset=x").Code)
+	assert.Equal(t, http.StatusNotFound, get("/indexes/other/documents").Code)
+
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/indexes/docs/documents", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
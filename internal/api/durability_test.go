@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// durableBackend records the durability of each write
+type durableBackend struct {
+	memoryBackend
+	durabilities []string
+}
+
+func (b *durableBackend) IndexContext(ctx context.Context, namespace string, doc models.Document) error {
+	b.durabilities = append(b.durabilities, ports.DurabilityFrom(ctx))
+	return b.Index(doc)
+}
+
+func (b *durableBackend) Bulk(ctx context.Context, items []ports.BulkItem) (ports.BulkResults, error) {
+	b.durabilities = append(b.durabilities, ports.DurabilityFrom(ctx))
+	return ports.BulkResults{Items: make([]ports.BulkItemResult, len(items))}, nil
+}
+
+func TestRESTAPI_Durability(t *testing.T) {
+	backend := &durableBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	for _, target := range []string{"/documents", "/documents?durability=persisted", "/documents?durability=fsync"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"id":"1","text":"one"}`)))
+		assert.Equal(t, http.StatusCreated, rec.Code, target)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents/bulk?durability=none", strings.NewReader(`[{"document":{"id":"2"}}]`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"", ports.DurabilityPersisted, ports.DurabilityFsync, ports.DurabilityNone}, backend.durabilities)
+
+	// Unknown durabilities are rejected before writing
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents?durability=always", strings.NewReader(`{"id":"1"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents/bulk?durability=always", strings.NewReader(`[]`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Len(t, backend.durabilities, 4)
+
+	// Backends that cannot pass a durability to single writes
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec,
+		httptest.NewRequest(http.MethodPost, "/documents?durability=fsync", strings.NewReader(`{"id":"1"}`)))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// queryExplainer returns the query plans of a backend (or API), or one failing with ErrNotSupported
+func queryExplainer(backend ports.EnginePort) ports.QueryExplainerPort {
+	if explainer, ok := backend.(ports.QueryExplainerPort); ok {
+		return explainer
+	}
+	return unsupportedQueryExplainer{}
+}
+
+// unsupportedQueryExplainer stands in for the query plans of backends that have none
+type unsupportedQueryExplainer struct{}
+
+func (unsupportedQueryExplainer) ExplainQuery(ports.SearchQuery) (ports.QueryPlan, error) {
+	return ports.QueryPlan{}, fmt.Errorf("%w: query plans", ports.ErrNotSupported)
+}
+
+// The APIs explain queries through backends that support it
+
+func (a *RESTAPI) ExplainQuery(query ports.SearchQuery) (ports.QueryPlan, error) {
+	return queryExplainer(a.backend).ExplainQuery(query)
+}
+
+func (g *GraphQLAPI) ExplainQuery(query ports.SearchQuery) (ports.QueryPlan, error) {
+	return queryExplainer(g.backend).ExplainQuery(query)
+}
+
+// handleExplain plans the query q for the caller's namespace without running it
+func (a *RESTAPI) handleExplain(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.Query().Has("q") {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+	plan, err := a.ExplainQuery(ports.SearchQuery{Query: r.URL.Query().Get("q"), Namespace: namespaceOf(r.Context())})
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// explainingBackend plans every query as a range lookup, and fails queries with "=<"
+type explainingBackend struct {
+	memoryBackend
+}
+
+func (b *explainingBackend) ExplainQuery(query ports.SearchQuery) (ports.QueryPlan, error) {
+	if strings.Contains(query.Query, "=<") {
+		return ports.QueryPlan{}, fmt.Errorf("%w: unknown operator \"=<\"", ports.ErrInvalid)
+	}
+	return ports.QueryPlan{Index: "default", Plan: "conditions", Documents: 10,
+		Steps: []ports.PlanStep{{Condition: query.Query, Access: "range", Estimate: 2}}}, nil
+}
+
+func TestRESTAPI_Explain(t *testing.T) {
+	handler := NewRESTAPI(&explainingBackend{}, ":0").Handler()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/explain?q=" + url.QueryEscape("fileSize<10"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"index":"default","plan":"conditions","documents":10,"steps":[{"condition":"fileSize<10","access":"range","estimate":2}]}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get("/explain?q="+url.QueryEscape("fileSize=<10")).Code)
+	assert.Equal(t, http.StatusBadRequest, get("/explain").Code)
+
+	// Backends without query plans
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/explain?q=x", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_ExplainQuery(t *testing.T) {
+	handler := NewGraphQLAPI(&explainingBackend{}, ":0").Handler()
+	body, _ := json.Marshal(map[string]string{"query": `{ explainQuery(query: "fileSize<10") { index plan documents steps { condition access estimate } error } }`})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			ExplainQuery QueryPlanResult `json:"explainQuery"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "conditions", resp.Data.ExplainQuery.Plan)
+	assert.Nil(t, resp.Data.ExplainQuery.Error)
+	if assert.Len(t, resp.Data.ExplainQuery.Steps, 1) {
+		assert.Equal(t, PlanStep{Condition: "fileSize<10", Access: "range", Estimate: 2}, *resp.Data.ExplainQuery.Steps[0])
+	}
+}
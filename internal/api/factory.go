@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// APIConstructor builds an API adapter from the "config" map of an apis entry in the starter config
+type APIConstructor func(backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error)
+
+// APIFactory instantiates API adapters by type name
+type APIFactory struct {
+	constructors map[string]APIConstructor
+	auth         *Authenticator
+	limits       *Limiter
+}
+
+// AuthenticatedAPI is implemented by API adapters that enforce an Authenticator
+type AuthenticatedAPI interface {
+	SetAuthenticator(auth *Authenticator)
+}
+
+// LimitedAPI is implemented by API adapters that enforce a Limiter
+type LimitedAPI interface {
+	SetLimiter(limits *Limiter)
+}
+
+// NewAPIFactory creates a factory with the built-in API types registered
+func NewAPIFactory() *APIFactory {
+	f := &APIFactory{constructors: make(map[string]APIConstructor)}
+	f.RegisterType("GraphQL", newGraphQLAPIFromConfig)
+	f.RegisterType("REST", newRESTAPIFromConfig)
+	f.RegisterType("gRPC", newGRPCAPIFromConfig)
+	return f
+}
+
+// RegisterType adds (or replaces) the constructor for an API type
+func (f *APIFactory) RegisterType(typeName string, constructor APIConstructor) {
+	f.constructors[typeName] = constructor
+}
+
+// Types returns the registered API type names, sorted
+func (f *APIFactory) Types() []string {
+	types := make([]string, 0, len(f.constructors))
+	for typeName := range f.constructors {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// SetAuthenticator makes every API created afterwards require credentials, so authentication is
+// enforced the same way across adapters. Create fails for adapters that cannot enforce it.
+func (f *APIFactory) SetAuthenticator(auth *Authenticator) {
+	f.auth = auth
+}
+
+// SetLimiter makes every API created afterwards rate limit clients and cap request bodies.
+// Adapters that cannot enforce the limits are created with a warning.
+func (f *APIFactory) SetLimiter(limits *Limiter) {
+	f.limits = limits
+}
+
+// Create instantiates an API adapter of the given type from its config map
+func (f *APIFactory) Create(typeName string, backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error) {
+	constructor, ok := f.constructors[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown API type %s (known types: %v)", typeName, f.Types())
+	}
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	api, err := constructor(backend, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s API: %w", typeName, err)
+	}
+	if f.auth != nil {
+		authenticated, ok := api.(AuthenticatedAPI)
+		if !ok {
+			return nil, fmt.Errorf("%s API does not support authentication", typeName)
+		}
+		authenticated.SetAuthenticator(f.auth)
+	}
+	if f.limits != nil {
+		if limited, ok := api.(LimitedAPI); ok {
+			limited.SetLimiter(f.limits)
+		} else {
+			log.Warn().Msgf("APIFactory: %s API does not support request limits", typeName)
+		}
+	}
+	log.Info().Msgf("APIFactory: created %s API", typeName)
+	return api, nil
+}
+
+func newGraphQLAPIFromConfig(backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error) {
+	listen, err := config.String(cfg, "listen", ":8080")
+	if err != nil {
+		return nil, err
+	}
+	cors, err := corsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	path, err := config.String(cfg, "playground", "")
+	if err != nil {
+		return nil, err
+	}
+	if path != "" && (!strings.HasPrefix(path, "/") || path == "/query") {
+		return nil, fmt.Errorf("playground must be a path other than /query, got %q", path)
+	}
+	api := NewGraphQLAPI(backend, listen)
+	api.SetCORS(cors)
+	api.SetPlayground(path)
+	return api, nil
+}
+
+func newRESTAPIFromConfig(backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error) {
+	listen, err := config.String(cfg, "listen", ":8081")
+	if err != nil {
+		return nil, err
+	}
+	cors, err := corsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	api := NewRESTAPI(backend, listen)
+	api.SetCORS(cors)
+	return api, nil
+}
+
+func newGRPCAPIFromConfig(backend ports.EnginePort, cfg map[string]interface{}) (ports.APIPort, error) {
+	listen, err := config.String(cfg, "listen", ":9090")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg["cors"]; ok {
+		return nil, fmt.Errorf("cors does not apply to the gRPC API")
+	}
+	return NewGRPCAPI(backend, listen), nil
+}
+
+// displayAddr turns a listen address like ":8080" into something clickable for logs
+func displayAddr(listen string) string {
+	if strings.HasPrefix(listen, ":") {
+		return "localhost" + listen
+	}
+	return listen
+}
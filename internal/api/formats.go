@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// Media types of the search result formats
+const (
+	formatJSON   = "application/json"
+	formatNDJSON = "application/x-ndjson"
+	formatCSV    = "text/csv"
+)
+
+// formatAliases are the values of the format query parameter
+var formatAliases = map[string]string{
+	"json":   formatJSON,
+	"ndjson": formatNDJSON,
+	"csv":    formatCSV,
+}
+
+// searchFormat picks the format of search results: the format query parameter (json, ndjson, csv)
+// if set, else the first supported media type of the Accept header, else JSON
+func searchFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		mediaType, ok := formatAliases[strings.ToLower(format)]
+		return mediaType, ok
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case formatJSON, formatNDJSON, formatCSV:
+			return mediaType, true
+		case "application/ndjson", "application/jsonl":
+			return formatNDJSON, true
+		}
+	}
+	return formatJSON, true
+}
+
+// writeNDJSON writes one document per line, so pipelines can process results as they arrive
+func writeNDJSON(w http.ResponseWriter, docs []models.Document) {
+	w.Header().Set("Content-Type", formatNDJSON)
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			log.Warn().Err(err).Msg("Failed to write NDJSON response")
+			return
+		}
+	}
+}
+
+// writeCSV writes a header row and a row per document with the given columns: id, text and source,
+// or metadata keys. Without columns, id and source are followed by every metadata key of the results.
+func writeCSV(w http.ResponseWriter, docs []models.Document, columns []string) {
+	if len(columns) == 0 {
+		columns = defaultCSVColumns(docs)
+	}
+	w.Header().Set("Content-Type", formatCSV+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	out := csv.NewWriter(w)
+	out.Write(columns)
+	row := make([]string, len(columns))
+	for _, doc := range docs {
+		for i, column := range columns {
+			row[i] = doc.Field(column)
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Warn().Err(err).Msg("Failed to write CSV response")
+	}
+}
+
+// defaultCSVColumns returns id, source and the sorted metadata keys of the documents
+func defaultCSVColumns(docs []models.Document) []string {
+	keys := make(map[string]bool)
+	for _, doc := range docs {
+		for key := range doc.Meta {
+			keys[key] = true
+		}
+	}
+	meta := make([]string, 0, len(keys))
+	for key := range keys {
+		if key != "id" && key != "source" {
+			meta = append(meta, key)
+		}
+	}
+	sort.Strings(meta)
+	return append([]string{"id", "source"}, meta...)
+}
+
+// csvColumns reads the comma separated columns query parameter
+func csvColumns(r *http.Request) []string {
+	return queryList(r, "columns")
+}
+
+// searchProjection reads the comma separated fields query parameter: the fields to return, and those to
+// leave out prefixed with "-" (e.g. fields=-text)
+func searchProjection(r *http.Request) (models.Projection, error) {
+	projection, err := models.ParseProjection(queryList(r, "fields"))
+	if err != nil {
+		return models.Projection{}, fmt.Errorf("invalid fields: %w", err)
+	}
+	return projection, nil
+}
+
+// queryList reads a comma separated query parameter
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range strings.Split(r.URL.Query().Get(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
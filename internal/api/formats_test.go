@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestRESTAPI_SearchFormats(t *testing.T) {
+	backend := &memoryBackend{docs: []models.Document{
+		{ID: "1", Text: "hello world", Source: "a.txt", Meta: map[string]string{"lang": "en"}},
+		{ID: "2", Text: "hello, \"there\"", Source: "b.txt", Meta: map[string]string{"author": "sam"}},
+	}}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	search := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := search("/search?q=hello", "application/x-ndjson")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	var doc models.Document
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &doc))
+	assert.Equal(t, "2", doc.ID)
+
+	// Without columns, the metadata keys of the results follow id and source
+	rec = search("/search?q=hello", "text/html, text/csv;q=0.9")
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "id,source,author,lang\n1,a.txt,,en\n2,b.txt,sam,\n", rec.Body.String())
+
+	rec = search("/search?q=hello&format=csv&columns=id,text,lang", "")
+	assert.Equal(t, "id,text,lang\n1,hello world,en\n2,\"hello, \"\"there\"\"\",\n", rec.Body.String())
+
+	// JSON stays the default, and the format parameter wins over Accept
+	rec = search("/search?q=hello", "")
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	rec = search("/search?q=hello&format=json", "text/csv")
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+
+	rec = search("/search?q=hello&format=xml", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -5,93 +5,321 @@ package api
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
-	// Add GraphQL library import
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
-	"github.com/graphql-go/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
 
-	"github.com/aawadall/bit-scout/internal/api/generated"
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/aawadall/bit-scout/internal/ports"
 )
 
-// GraphQLAPI is a minimal implementation of the APIPort interface for GraphQL.
+// shutdownTimeout bounds how long Stop waits for in-flight requests
+const shutdownTimeout = 5 * time.Second
+
+// GraphQLAPI implements the APIPort interface by serving the gqlgen schema over HTTP.
+// Operations are delegated to the engine backend.
 type GraphQLAPI struct {
-	schema *graphql.Schema
-}
-
-// Define a minimal GraphQL schema as a string or using graphql-go types
-var rootQuery = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Query",
-	Fields: graphql.Fields{
-		// Example: add a simple 'ping' field
-		"ping": &graphql.Field{
-			Type: graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				return "pong", nil
-			},
-		},
-	},
-})
+	backend ports.EnginePort
+	listen  string
+	auth    *Authenticator
+	limits  *Limiter
+	// Browser access during development
+	cors       *CORSConfig
+	playground string // Path of the GraphiQL playground ("": none)
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// NewGraphQLAPI creates a GraphQL API serving on the given listen address (e.g. ":8080")
+func NewGraphQLAPI(backend ports.EnginePort, listen string) *GraphQLAPI {
+	return &GraphQLAPI{backend: backend, listen: listen}
+}
 
 func (g *GraphQLAPI) Name() string {
 	return "GraphQL"
 }
 
-func (g *GraphQLAPI) Start() error {
-	// Initialize the GraphQL schema
-	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query: rootQuery,
-		// Mutation: rootMutation, // Add if needed
+// Handler returns the HTTP handler serving the GraphQL endpoint
+func (g *GraphQLAPI) Handler() http.Handler {
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{api: g}}))
+	// Subscriptions (graphql-ws); the first message may carry the credentials browsers cannot send as headers
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader:              websocket.Upgrader{CheckOrigin: g.checkOrigin},
+		InitFunc:              g.authenticateWebsocket,
 	})
-	if err != nil {
-		return err
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.AroundFields(g.authorizeField)
+
+	mux := http.NewServeMux()
+	query := limitBody(g.Name(), g.limits.maxDocumentBytes(), withRemoteAddr(srv))
+	mux.Handle("/query", traced("/query", g.limits.Middleware(g.Name(), query)))
+	if g.playground != "" {
+		// The page is static; the queries it sends are authenticated like any other
+		mux.Handle("GET "+g.playground, playground.Handler("bit-scout", "/query"))
 	}
-	g.schema = &schema
+	handleHealth(mux, g.backend)
+	return g.cors.middleware(g.auth.Middleware(g.Name(), g.limits, mux))
+}
 
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: g}))
-	http.Handle("/query", srv)
-	log.Println("GraphQL server running at http://localhost:8080/query")
-	return http.ListenAndServe(":8080", nil)
+// SetCORS lets browser frontends on the configured origins query the API (nil: same origin only)
+func (g *GraphQLAPI) SetCORS(cors *CORSConfig) {
+	g.cors = cors
 }
 
-func (g *GraphQLAPI) Stop() error {
-	// TODO: Implement GraphQL server shutdown
-	return errors.New("GraphQL Stop not implemented")
+// SetPlayground serves the GraphiQL playground at path, e.g. "/playground" ("": none)
+func (g *GraphQLAPI) SetPlayground(path string) {
+	g.playground = path
 }
 
-func (g *GraphQLAPI) Search(query ports.SearchQuery) (ports.SearchResults, error) {
-	// TODO: Implement GraphQL search
-	return ports.SearchResults{}, errors.New("GraphQL Search not implemented")
+// SetAuthenticator requires credentials for the GraphQL fields (nil: none)
+func (g *GraphQLAPI) SetAuthenticator(auth *Authenticator) {
+	g.auth = auth
 }
 
-func (g *GraphQLAPI) Stats() (ports.Stats, error) {
-	// TODO: Implement GraphQL stats
-	return ports.Stats{}, errors.New("GraphQL Stats not implemented")
+// SetLimiter rate limits GraphQL requests per client and caps their bodies (nil: default cap only)
+func (g *GraphQLAPI) SetLimiter(limits *Limiter) {
+	g.limits = limits
 }
 
-func (g *GraphQLAPI) Index(doc models.Document) error {
-	// TODO: Implement GraphQL index
-	return errors.New("GraphQL Index not implemented")
+// graphQLPublic are the top-level fields anyone may resolve: ping and introspection
+var graphQLPublic = map[string]bool{
+	"Query.ping":     true,
+	"Query.__schema": true,
+	"Query.__type":   true,
+}
+
+// graphQLScopes are the scopes of the top-level fields. Top-level fields neither listed nor in
+// graphQLPublic are denied, so a field added without a scope is not served to everyone.
+var graphQLScopes = map[string]string{
+	"Query.search":               ScopeSearch,
+	"Query.stats":                ScopeSearch,
+	"Query.storedQueries":        ScopeSearch,
+	"Query.percolate":            ScopeSearch,
+	"Query.savedSearches":        ScopeSearch,
+	"Query.runSavedSearch":       ScopeSearch,
+	"Query.validateQuery":        ScopeSearch,
+	"Query.explainQuery":         ScopeSearch,
+	"Query.document":             ScopeSearch,
+	"Query.documents":            ScopeSearch,
+	"Query.dimensions":           ScopeSearch,
+	"Query.topValues":            ScopeSearch,
+	"Mutation.index":             ScopeIndex,
+	"Mutation.bulk":              ScopeIndex,
+	"Mutation.start":             ScopeAdmin,
+	"Mutation.stop":              ScopeAdmin,
+	"Mutation.createIndex":       ScopeAdmin,
+	"Mutation.dropIndex":         ScopeAdmin,
+	"Mutation.configureIndex":    ScopeAdmin,
+	"Mutation.flushIndex":        ScopeAdmin,
+	"Mutation.optimizeIndex":     ScopeAdmin,
+	"Mutation.clearIndex":        ScopeAdmin,
+	"Mutation.verifyIndex":       ScopeAdmin,
+	"Mutation.snapshotIndex":     ScopeAdmin,
+	"Mutation.setAlias":          ScopeAdmin,
+	"Mutation.removeAlias":       ScopeAdmin,
+	"Mutation.runLoader":         ScopeAdmin,
+	"Mutation.registerQuery":     ScopeSearch,
+	"Mutation.deleteQuery":       ScopeSearch,
+	"Mutation.saveSearch":        ScopeSearch,
+	"Mutation.deleteSavedSearch": ScopeSearch,
+
+	"Subscription.search": ScopeSearch,
 }
 
-type resolver struct {
-	api ports.API
+// graphQLTenancy is what callers bound to a namespace may do with the top-level fields; fields not listed
+// serve the caller's namespace. The index of tenantIndex fields is their name argument.
+var graphQLTenancy = map[string]tenancy{
+	"Mutation.start":          tenantDenied,
+	"Mutation.stop":           tenantDenied,
+	"Mutation.createIndex":    tenantDenied,
+	"Mutation.dropIndex":      tenantIndex,
+	"Mutation.configureIndex": tenantIndex,
+	"Mutation.flushIndex":     tenantIndex,
+	"Mutation.optimizeIndex":  tenantIndex,
+	"Mutation.clearIndex":     tenantIndex,
+	"Mutation.verifyIndex":    tenantIndex,
+	"Mutation.snapshotIndex":  tenantIndex,
+	"Mutation.setAlias":       tenantDenied,
+	"Mutation.removeAlias":    tenantDenied,
+	"Mutation.runLoader":      tenantDenied,
 }
 
-func (r *resolver) Query() generated.QueryResolver {
-	return &queryResolver{r}
+// authorizeField resolves a top-level field only if it is public or the caller is granted its scope and,
+// when bound to a namespace, may use it
+func (g *GraphQLAPI) authorizeField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	if field := graphql.GetFieldContext(ctx); field != nil && isRootObject(field.Object) {
+		name := field.Object + "." + field.Field.Name
+		if scope, ok := graphQLScopes[name]; ok {
+			if err := g.auth.Authorize(ctx, scope); err != nil {
+				return nil, err
+			}
+		} else if !graphQLPublic[name] {
+			return nil, fmt.Errorf("%w: %s has no scope", ErrForbidden, name)
+		}
+		if t, ok := graphQLTenancy[name]; ok {
+			index, _ := field.Args["name"].(string)
+			if err := authorizeNamespace(ctx, t, index); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return next(ctx)
 }
 
-type queryResolver struct{ *resolver }
+// isRootObject reports whether object is the type of top-level fields: Query, Mutation or Subscription
+func isRootObject(object string) bool {
+	return object == "Query" || object == "Mutation" || object == "Subscription"
+}
 
-func (r *queryResolver) Ping(ctx context.Context) (*generated.PingResult, error) {
-	// Call your API port implementation
-	pong, err := r.api.Ping(ctx)
+// checkOrigin accepts websocket connections from the page's own origin and the CORS origins
+func (g *GraphQLAPI) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if g.cors != nil {
+		_, ok := g.cors.allowed(origin)
+		return ok
+	}
+	return false
+}
+
+// authenticateWebsocket authenticates the "Authorization" or "X-API-Key" of a websocket's
+// connection_init payload, for connections that did not authenticate with headers
+func (g *GraphQLAPI) authenticateWebsocket(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	if _, ok := PrincipalFrom(ctx); ok || g.auth == nil {
+		return ctx, nil, nil
+	}
+	credential := payload.GetString("X-API-Key")
+	if token, ok := strings.CutPrefix(payload.Authorization(), "Bearer "); ok {
+		credential = strings.TrimSpace(token)
+	}
+	principal, err := g.auth.Authenticate(credential)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return &generated.PingResult{Pong: pong}, nil
+	if principal != nil {
+		ctx = context.WithValue(withDocumentPrincipals(ctx, principal), principalKey{}, principal)
+	}
+	return ctx, nil, nil
+}
+
+// SubscribeSearch pushes the documents matching a query as they are indexed, until ctx is done
+func (g *GraphQLAPI) SubscribeSearch(ctx context.Context, query ports.SearchQuery) (<-chan ports.SearchMatch, error) {
+	return subscribeSearch(ctx, g.backend, query)
+}
+
+type remoteAddrKey struct{}
+
+// withRemoteAddr makes the client address of a request available to the resolvers
+func withRemoteAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), remoteAddrKey{}, r.RemoteAddr)))
+	})
+}
+
+// remoteAddr returns the client address stored by withRemoteAddr
+func remoteAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// graphQLSearch runs a search for the caller in ctx, in its namespace and with its document filters
+func graphQLSearch(ctx context.Context, api ports.APIPort, search ports.SearchQuery) *SearchResult {
+	search.Caller = strings.TrimSpace(api.Name() + " " + remoteAddr(ctx))
+	search.Filter = documentFilter(ctx)
+	search.Namespace = namespaceOf(ctx)
+	search.Principals = documentPrincipals(ctx)
+	var results ports.SearchResults
+	var err error
+	if traced, ok := api.(ports.ContextSearchPort); ok {
+		results, err = traced.SearchContext(ctx, search)
+	} else {
+		results, err = api.Search(search)
+	}
+	if err != nil {
+		return &SearchResult{Results: []*Document{}, Error: stringPtr(err.Error())}
+	}
+	out := make([]*Document, 0, len(results.Documents))
+	for _, doc := range results.Documents {
+		out = append(out, toGraphQLDocument(doc))
+	}
+	result := &SearchResult{Results: out, TotalCount: len(out), FailedNodes: results.FailedNodes}
+	if len(results.Warnings) > 0 {
+		result.Warnings = toGraphQLQueryErrors(results.Warnings)
+	}
+	return result
+}
+
+// Start serves the GraphQL endpoint until Stop is called (blocking)
+func (g *GraphQLAPI) Start() error {
+	server := &http.Server{Addr: g.listen, Handler: g.Handler()}
+	g.mu.Lock()
+	g.server = server
+	g.mu.Unlock()
+
+	log.Info().Msgf("GraphQL server running at http://%s/query", displayAddr(g.listen))
+	if g.playground != "" {
+		log.Info().Msgf("GraphQL playground at http://%s%s", displayAddr(g.listen), g.playground)
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop gracefully shuts down the GraphQL server
+func (g *GraphQLAPI) Stop() error {
+	g.mu.Lock()
+	server := g.server
+	g.server = nil
+	g.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+func (g *GraphQLAPI) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return g.backend.Search(query)
+}
+
+// SearchContext is Search as part of the trace in ctx
+func (g *GraphQLAPI) SearchContext(ctx context.Context, query ports.SearchQuery) (ports.SearchResults, error) {
+	return searchContext(ctx, g.backend, query)
+}
+
+// Bulk applies many document operations in one request; items fail independently
+func (g *GraphQLAPI) Bulk(ctx context.Context, items []ports.BulkItem) (ports.BulkResults, error) {
+	return bulk(ctx, g.backend, items)
+}
+
+func (g *GraphQLAPI) Stats() (ports.Stats, error) {
+	return g.backend.Stats()
+}
+
+func (g *GraphQLAPI) Index(doc models.Document) error {
+	return g.backend.Index(doc)
 }
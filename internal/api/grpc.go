@@ -0,0 +1,350 @@
+package api
+
+// gRPC Implementation to API port
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * gRPC API: searches, statistics and writes as the unary methods of the bitscout.Search service. Like
+ * the cluster transport, messages are JSON (content type application/grpc+json), so clients need no
+ * generated code, and credentials are sent as "authorization" ("Bearer <key or token>") or "x-api-key"
+ * metadata.
+ **/
+
+const grpcServiceName = "bitscout.Search"
+
+// grpcCodec encodes gRPC messages as JSON
+type grpcCodec struct{}
+
+func (grpcCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (grpcCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (grpcCodec) Name() string                       { return "json" }
+
+func init() {
+	encoding.RegisterCodec(grpcCodec{})
+}
+
+// grpcSearchRequest is the message of the Search method
+type grpcSearchRequest struct {
+	Query    string   `json:"query"`
+	Sort     string   `json:"sort,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Fields   []string `json:"fields,omitempty"` // Projection, as the fields parameter of GET /search
+}
+
+// grpcIndexRequest is the message of the Index method
+type grpcIndexRequest struct {
+	Document   models.Document `json:"document"`
+	Durability string          `json:"durability,omitempty"` // As the durability parameter of POST /documents
+}
+
+// grpcBulkRequest is the message of the Bulk method
+type grpcBulkRequest struct {
+	Items      []bulkRequestItem `json:"items"`
+	Durability string            `json:"durability,omitempty"`
+}
+
+// grpcEmpty is the message of methods without parameters
+type grpcEmpty struct{}
+
+// grpcPublic are the methods anyone may call
+var grpcPublic = map[string]bool{
+	"Ping": true,
+}
+
+// grpcScopes are the scopes of the methods. Methods neither listed nor in grpcPublic are denied.
+var grpcScopes = map[string]string{
+	"Search": ScopeSearch,
+	"Stats":  ScopeSearch,
+	"Index":  ScopeIndex,
+	"Bulk":   ScopeIndex,
+}
+
+// grpcServiceDesc describes the search service to gRPC, in place of generated code
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		grpcUnary("Ping", func(g *GRPCAPI, ctx context.Context, _ *grpcEmpty) (*map[string]string, error) {
+			return &map[string]string{"message": "pong"}, nil
+		}),
+		grpcUnary("Search", func(g *GRPCAPI, ctx context.Context, req *grpcSearchRequest) (*searchResponse, error) {
+			return g.search(ctx, *req)
+		}),
+		grpcUnary("Stats", func(g *GRPCAPI, ctx context.Context, _ *grpcEmpty) (*ports.Stats, error) {
+			stats, err := engineStats(ctx, g.backend)
+			return &stats, err
+		}),
+		grpcUnary("Index", func(g *GRPCAPI, ctx context.Context, req *grpcIndexRequest) (*map[string]string, error) {
+			if req.Document.ID == "" {
+				return nil, fmt.Errorf("%w: document id is required", ports.ErrInvalid)
+			}
+			ctx, err := withDurability(ctx, req.Durability)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+			}
+			if err := indexDocument(ctx, g.backend, req.Document); err != nil {
+				return nil, err
+			}
+			return &map[string]string{"id": req.Document.ID}, nil
+		}),
+		grpcUnary("Bulk", func(g *GRPCAPI, ctx context.Context, req *grpcBulkRequest) (*bulkResponse, error) {
+			items := make([]ports.BulkItem, len(req.Items))
+			for i, item := range req.Items {
+				items[i] = item.toBulkItem()
+			}
+			ctx, err := withDurability(ctx, req.Durability)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ports.ErrInvalid, err)
+			}
+			results, err := bulk(ctx, g.backend, items)
+			if err != nil {
+				return nil, err
+			}
+			resp := toBulkResponse(results)
+			return &resp, nil
+		}),
+	},
+}
+
+// grpcUnary adapts a method of the search service to gRPC, converting its errors to gRPC statuses
+func grpcUnary[Req, Resp any](name string, method func(g *GRPCAPI, ctx context.Context, req *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				resp, err := method(srv.(*GRPCAPI), ctx, req.(*Req))
+				if err != nil {
+					return nil, grpcStatus(err)
+				}
+				return resp, nil
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + grpcServiceName + "/" + name}, handler)
+		},
+	}
+}
+
+// grpcStatus converts an error to the gRPC status of its kind
+func grpcStatus(err error) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, ErrUnauthenticated):
+		code = codes.Unauthenticated
+	case errors.Is(err, ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, ports.ErrRateLimited):
+		code = codes.ResourceExhausted
+	case errors.Is(err, ports.ErrNotSupported):
+		code = codes.Unimplemented
+	case errors.Is(err, ports.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ports.ErrInvalid):
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+// GRPCAPI implements the APIPort interface as the bitscout.Search gRPC service.
+// Operations are delegated to the engine backend.
+type GRPCAPI struct {
+	backend ports.EnginePort
+	listen  string
+	auth    *Authenticator
+	limits  *Limiter
+
+	mu     sync.Mutex
+	server *grpc.Server
+}
+
+// NewGRPCAPI creates a gRPC API serving on the given listen address (e.g. ":9090")
+func NewGRPCAPI(backend ports.EnginePort, listen string) *GRPCAPI {
+	return &GRPCAPI{backend: backend, listen: listen}
+}
+
+func (g *GRPCAPI) Name() string {
+	return "gRPC"
+}
+
+// SetAuthenticator requires credentials for the methods (nil: none)
+func (g *GRPCAPI) SetAuthenticator(auth *Authenticator) {
+	g.auth = auth
+}
+
+// SetLimiter rate limits the methods per client and caps messages at max_import_bytes (nil: default cap only)
+func (g *GRPCAPI) SetLimiter(limits *Limiter) {
+	g.limits = limits
+}
+
+// Server returns a gRPC server of the search service, with the API's authentication and limits
+func (g *GRPCAPI) Server() *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(g.intercept), grpc.MaxRecvMsgSize(int(g.limits.maxImportBytes())))
+	server.RegisterService(&grpcServiceDesc, g)
+	return server
+}
+
+// Start serves the search service until Stop is called (blocking)
+func (g *GRPCAPI) Start() error {
+	listener, err := net.Listen("tcp", g.listen)
+	if err != nil {
+		return err
+	}
+	server := g.Server()
+	g.mu.Lock()
+	g.server = server
+	g.mu.Unlock()
+
+	log.Info().Msgf("gRPC server running at %s (service %s)", displayAddr(g.listen), grpcServiceName)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop waits for the calls in progress and stops serving
+func (g *GRPCAPI) Stop() error {
+	g.mu.Lock()
+	server := g.server
+	g.server = nil
+	g.mu.Unlock()
+
+	if server != nil {
+		server.GracefulStop()
+	}
+	return nil
+}
+
+func (g *GRPCAPI) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return g.backend.Search(query)
+}
+
+func (g *GRPCAPI) Stats() (ports.Stats, error) {
+	return g.backend.Stats()
+}
+
+func (g *GRPCAPI) Index(doc models.Document) error {
+	return g.backend.Index(doc)
+}
+
+// intercept authenticates the credentials of every call and rate limits its client, before enforcing the
+// scope of the method. Calls rejected for their credentials count against the client's limit too.
+func (g *GRPCAPI) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var principal *Principal
+	var authErr error
+	if g.auth != nil {
+		principal, authErr = g.auth.Authenticate(grpcCredential(ctx))
+	}
+	if _, err := g.limits.admit(g.Name(), grpcClientKey(ctx, principal)); err != nil {
+		return nil, grpcStatus(err)
+	}
+	if authErr != nil {
+		log.Warn().Msgf("Rejected call to %s from %s: %s", info.FullMethod, grpcPeer(ctx), authErr)
+		return nil, grpcStatus(authErr)
+	}
+	if g.auth != nil {
+		ctx = withDocumentPrincipals(ctx, principal)
+		if principal != nil {
+			ctx = context.WithValue(ctx, principalKey{}, principal)
+		}
+	}
+
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	if scope, ok := grpcScopes[method]; ok {
+		if err := g.auth.Authorize(ctx, scope); err != nil {
+			return nil, grpcStatus(err)
+		}
+	} else if !grpcPublic[method] {
+		return nil, grpcStatus(fmt.Errorf("%w: %s has no scope", ErrForbidden, info.FullMethod))
+	}
+	return handler(ctx, req)
+}
+
+// search runs a search for the caller in ctx, in its namespace and with its document filters
+func (g *GRPCAPI) search(ctx context.Context, req grpcSearchRequest) (*searchResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("%w: missing query", ports.ErrInvalid)
+	}
+	projection, err := models.ParseProjection(req.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid fields: %s", ports.ErrInvalid, err)
+	}
+	results, err := searchContext(ctx, g.backend, ports.SearchQuery{
+		Query:      req.Query,
+		Caller:     g.Name() + " " + grpcPeer(ctx),
+		Filter:     documentFilter(ctx),
+		Namespace:  namespaceOf(ctx),
+		Principals: documentPrincipals(ctx),
+		Sort:       req.Sort,
+		Projection: projection,
+		Language:   req.Language,
+	})
+	if err != nil {
+		return nil, err
+	}
+	docs := results.Documents
+	if docs == nil {
+		docs = []models.Document{}
+	}
+	return &searchResponse{Results: docs, TotalCount: len(docs), FailedNodes: results.FailedNodes, Warnings: results.Warnings}, nil
+}
+
+// grpcCredential extracts the "x-api-key" or bearer "authorization" metadata of a call
+func grpcCredential(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// grpcPeer returns the address of the client of a call
+func grpcPeer(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// grpcClientKey identifies the client of a call for rate limiting, as clientKey does for HTTP requests
+func grpcClientKey(ctx context.Context, principal *Principal) string {
+	if principal != nil && principal.Name != "" {
+		return "principal " + principal.Name
+	}
+	host := grpcPeer(ctx)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return "ip " + host
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// startGRPC serves api on a local port, returning a function calling its methods with the given
+// metadata (key, value pairs)
+func startGRPC(t *testing.T, api *GRPCAPI) func(method string, req, resp any, md ...string) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := api.Server()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return func(method string, req, resp any, md ...string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), md...)
+		return conn.Invoke(ctx, "/bitscout.Search/"+method, req, resp, grpc.CallContentSubtype("json"))
+	}
+}
+
+func TestGRPCAPI_IndexAndSearch(t *testing.T) {
+	backend := &memoryBackend{}
+	call := startGRPC(t, NewGRPCAPI(backend, ":0"))
+
+	var pong map[string]string
+	assert.NoError(t, call("Ping", &grpcEmpty{}, &pong))
+	assert.Equal(t, "pong", pong["message"])
+
+	var indexed map[string]string
+	assert.NoError(t, call("Index", &grpcIndexRequest{Document: models.Document{ID: "1", Text: "hello world"}}, &indexed))
+	assert.Equal(t, "1", indexed["id"])
+	assert.Len(t, backend.docs, 1)
+
+	var results searchResponse
+	assert.NoError(t, call("Search", &grpcSearchRequest{Query: "hello"}, &results))
+	assert.Equal(t, 1, results.TotalCount)
+	assert.Equal(t, "1", results.Results[0].ID)
+
+	var stats map[string]interface{}
+	assert.NoError(t, call("Stats", &grpcEmpty{}, &stats))
+	assert.Equal(t, 1.0, stats["NumDocuments"])
+
+	// Errors have the gRPC status of their kind
+	tests := []struct {
+		method string
+		req    any
+		code   codes.Code
+	}{
+		{"Search", &grpcSearchRequest{Query: " "}, codes.InvalidArgument},
+		{"Search", &grpcSearchRequest{Query: "hello", Fields: []string{"-id"}}, codes.InvalidArgument},
+		{"Index", &grpcIndexRequest{Document: models.Document{Text: "no id"}}, codes.InvalidArgument},
+		{"Index", &grpcIndexRequest{Document: models.Document{ID: "2"}, Durability: "always"}, codes.InvalidArgument},
+		{"Bulk", &grpcBulkRequest{Items: []bulkRequestItem{{ID: "3"}}}, codes.Unimplemented},
+		{"Delete", &grpcEmpty{}, codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, status.Code(call(tt.method, tt.req, &map[string]interface{}{})), tt.method)
+	}
+	assert.Len(t, backend.docs, 1)
+}
+
+func TestGRPCAPI_Authenticates(t *testing.T) {
+	backend := &memoryBackend{docs: []models.Document{{ID: "1", Text: "hello"}}}
+	api := NewGRPCAPI(backend, ":0")
+	api.SetAuthenticator(newTestAuthenticator(t, nil))
+	limiter, err := NewLimiter(LimitsConfig{RateLimit: &ClientRateLimit{PerSecond: 0.001, Burst: 2}})
+	assert.NoError(t, err)
+	api.SetLimiter(limiter)
+	call := startGRPC(t, api)
+	search := &grpcSearchRequest{Query: "hello"}
+
+	assert.NoError(t, call("Ping", &grpcEmpty{}, &map[string]string{}))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("Search", search, &searchResponse{})))
+	assert.NoError(t, call("Search", search, &searchResponse{}, "authorization", "Bearer read-key"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("Index", &grpcIndexRequest{Document: models.Document{ID: "2"}}, &map[string]string{}, "x-api-key", "read-key")))
+	assert.Len(t, backend.docs, 1)
+
+	// Calls with invalid credentials count against the client's limit: its IP has used its two calls
+	// (the anonymous ping and search), so guessing keys is limited too
+	err = call("Search", search, &searchResponse{}, "x-api-key", "guessed-key")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Methods without a scope are denied
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+	_, err = NewGRPCAPI(backend, ":0").intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/bitscout.Search/Export"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
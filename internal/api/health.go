@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// handleHealth registers the liveness (/healthz) and readiness (/readyz) probes on mux. They answer
+// 200 with the status of every component when all pass, else 503. Backends without health checks
+// are always reported healthy.
+func handleHealth(mux *http.ServeMux, backend ports.EnginePort) {
+	probe := func(check func(ports.HealthPort) ports.HealthReport) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			report := ports.HealthReport{Status: ports.HealthOK, Components: []ports.ComponentHealth{}}
+			if health, ok := backend.(ports.HealthPort); ok {
+				report = check(health)
+			}
+			status := http.StatusOK
+			if !report.Healthy() {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, report)
+		}
+	}
+	mux.Handle("GET /healthz", probe(ports.HealthPort.Liveness))
+	mux.Handle("GET /readyz", probe(ports.HealthPort.Readiness))
+}
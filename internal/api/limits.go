@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// Default request body caps, applied even without a limits config
+const (
+	DefaultMaxDocumentBytes = 1 << 20 // 1 MiB: single documents and GraphQL requests
+	DefaultMaxImportBytes   = 1 << 30 // 1 GiB: index imports and bulk requests
+)
+
+// limitMetrics counts the requests rejected by limits, per API ("REST.rate_limited",
+// "GraphQL.payload_too_large"). They are served with the other expvars, e.g. on the admin address.
+var limitMetrics = expvar.NewMap("bitscout_api_limits")
+
+// LimitsConfig is the "limits" section of the starter config, applied to every API
+// Example: { "rate_limit": { "per_second": 20, "burst": 40 }, "max_document_bytes": 1048576, "max_import_bytes": 104857600 }
+type LimitsConfig struct {
+	RateLimit        *ClientRateLimit `json:"rate_limit,omitempty"`
+	MaxDocumentBytes int64            `json:"max_document_bytes,omitempty"` // Body of single documents and GraphQL requests (default 1 MiB)
+	MaxImportBytes   int64            `json:"max_import_bytes,omitempty"`   // Body of index imports and bulk requests (default 1 GiB)
+}
+
+// ClientRateLimit limits the requests of each client: the API key or JWT subject of authenticated
+// callers, else the client IP
+type ClientRateLimit struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst,omitempty"` // Requests allowed at once (default: per_second, at least 1)
+}
+
+// Limiter rate limits clients and caps request bodies. A nil Limiter applies the default body caps only.
+type Limiter struct {
+	cfg LimitsConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+// clientBucket is the token bucket of a client
+type clientBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientSweepInterval is how often buckets of clients gone quiet are dropped
+const clientSweepInterval = time.Minute
+
+// NewLimiter validates a limits config and builds its limiter
+func NewLimiter(cfg LimitsConfig) (*Limiter, error) {
+	if cfg.RateLimit != nil {
+		if cfg.RateLimit.PerSecond <= 0 {
+			return nil, fmt.Errorf("rate_limit.per_second must be positive, got %g", cfg.RateLimit.PerSecond)
+		}
+		if cfg.RateLimit.Burst < 0 {
+			return nil, fmt.Errorf("rate_limit.burst must not be negative, got %d", cfg.RateLimit.Burst)
+		}
+	}
+	if cfg.MaxDocumentBytes < 0 || cfg.MaxImportBytes < 0 {
+		return nil, errors.New("max_document_bytes and max_import_bytes must not be negative")
+	}
+	return &Limiter{cfg: cfg, now: time.Now, clients: make(map[string]*clientBucket)}, nil
+}
+
+// Middleware answers 429 with a Retry-After header to clients over their rate limit. It must run
+// inside the Authenticator's middleware to tell authenticated clients apart (which counts the requests
+// it rejects itself), and is left out of health probes so orchestrators are never limited.
+func (l *Limiter) Middleware(api string, next http.Handler) http.Handler {
+	if l == nil || l.cfg.RateLimit == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, err := l.admit(api, clientKey(r)); err != nil {
+			writeRateLimited(w, wait, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeRateLimited answers 429 to a request admit rejected, telling the client when to retry
+func writeRateLimited(w http.ResponseWriter, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, err)
+}
+
+// admit takes a request of client from its rate limit. Over the limit, it counts the rejection and
+// returns how long until the client may retry, with an error wrapping ports.ErrRateLimited.
+func (l *Limiter) admit(api, client string) (time.Duration, error) {
+	if l == nil || l.cfg.RateLimit == nil {
+		return 0, nil
+	}
+	wait, ok := l.take(client)
+	if ok {
+		return 0, nil
+	}
+	limitMetrics.Add(api+".rate_limited", 1)
+	log.Debug().Msgf("%s API: rate limited %s", api, client)
+	return wait, fmt.Errorf("%w: more than %g requests per second", ports.ErrRateLimited, l.cfg.RateLimit.PerSecond)
+}
+
+// clientKey identifies the client of a request for rate limiting. Forwarding headers are not
+// trusted, as clients could set them to evade their limit.
+func clientKey(r *http.Request) string {
+	if principal, ok := PrincipalFrom(r.Context()); ok && principal.Name != "" {
+		return "principal " + principal.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip " + host
+}
+
+// take consumes a token of the client's bucket, or returns how long until one is available
+func (l *Limiter) take(client string) (time.Duration, bool) {
+	rate := l.cfg.RateLimit.PerSecond
+	capacity := float64(l.cfg.RateLimit.Burst)
+	if capacity == 0 {
+		capacity = math.Max(1, math.Floor(rate))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= clientSweepInterval {
+		l.sweep(now, capacity/rate)
+	}
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &clientBucket{tokens: capacity, last: now}
+		l.clients[client] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / rate * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// sweep drops the buckets that have refilled, as new ones start full anyway; the caller must hold mu
+func (l *Limiter) sweep(now time.Time, refillSeconds float64) {
+	for client, bucket := range l.clients {
+		if now.Sub(bucket.last).Seconds() >= refillSeconds {
+			delete(l.clients, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// maxDocumentBytes returns the body cap of single documents and GraphQL requests
+func (l *Limiter) maxDocumentBytes() int64 {
+	if l == nil || l.cfg.MaxDocumentBytes == 0 {
+		return DefaultMaxDocumentBytes
+	}
+	return l.cfg.MaxDocumentBytes
+}
+
+// maxImportBytes returns the body cap of index imports and bulk requests
+func (l *Limiter) maxImportBytes() int64 {
+	if l == nil || l.cfg.MaxImportBytes == 0 {
+		return DefaultMaxImportBytes
+	}
+	return l.cfg.MaxImportBytes
+}
+
+// limitBody answers 413 to requests declaring a body over max bytes, and makes reading past max
+// fail for the others (see payloadTooLarge)
+func limitBody(api string, max int64, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			limitMetrics.Add(api+".payload_too_large", 1)
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body over %d bytes", max))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// payloadTooLarge reports whether err comes from reading past a body cap of limitBody, counting it
+func payloadTooLarge(api string, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		limitMetrics.Add(api+".payload_too_large", 1)
+		return true
+	}
+	return false
+}
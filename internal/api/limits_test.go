@@ -0,0 +1,113 @@
+package api
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_RateLimitsPerClient(t *testing.T) {
+	limiter, err := NewLimiter(LimitsConfig{RateLimit: &ClientRateLimit{PerSecond: 1, Burst: 2}})
+	assert.NoError(t, err)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	rejected := limitCount("REST.rate_limited")
+	rest := NewRESTAPI(&memoryBackend{}, ":0")
+	rest.SetLimiter(limiter)
+	handler := rest.Handler()
+	search := func(remoteAddr string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/search?q=hello", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	assert.Equal(t, http.StatusOK, search("10.0.0.1:1000").StatusCode)
+	assert.Equal(t, http.StatusOK, search("10.0.0.1:1001").StatusCode)
+	limited := search("10.0.0.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode)
+	assert.Equal(t, "1", limited.Header.Get("Retry-After"))
+	// Other clients and health probes are not affected
+	assert.Equal(t, http.StatusOK, search("10.0.0.2:1000").StatusCode)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/healthz", "", nil).Code)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, search("10.0.0.1:1003").StatusCode)
+	assert.Equal(t, int64(1), limitCount("REST.rate_limited")-rejected)
+}
+
+// limitCount returns the value of a counter of limitMetrics, which other tests may have counted too
+func limitCount(key string) int64 {
+	if counter, ok := limitMetrics.Get(key).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
+
+func TestLimiter_AuthenticatedClients(t *testing.T) {
+	limiter, err := NewLimiter(LimitsConfig{RateLimit: &ClientRateLimit{PerSecond: 0.001}})
+	assert.NoError(t, err)
+	auth, err := NewAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{
+		{Name: "a", Key: "key-a", Roles: []string{RoleReader}},
+		{Name: "b", Key: "key-b", Roles: []string{RoleReader}},
+	}}, nil)
+	assert.NoError(t, err)
+
+	rest := NewRESTAPI(&memoryBackend{}, ":0")
+	rest.SetAuthenticator(auth)
+	rest.SetLimiter(limiter)
+	handler := rest.Handler()
+
+	// Clients sharing an address are limited by their keys
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=x", "", map[string]string{"X-API-Key": "key-a"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(handler, http.MethodGet, "/search?q=x", "", map[string]string{"X-API-Key": "key-a"}).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=x", "", map[string]string{"X-API-Key": "key-b"}).Code)
+
+	// Requests with invalid credentials count against their IP, so keys cannot be guessed faster
+	rejected := limitCount("GraphQL.rate_limited")
+	graphql := NewGraphQLAPI(&memoryBackend{}, ":0")
+	graphql.SetAuthenticator(auth)
+	graphqlLimiter, err := NewLimiter(LimitsConfig{RateLimit: &ClientRateLimit{PerSecond: 0.001}})
+	assert.NoError(t, err)
+	graphql.SetLimiter(graphqlLimiter)
+	for _, handler := range []http.Handler{handler, graphql.Handler()} {
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/healthz", "", map[string]string{"X-API-Key": "guess-1"}).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler, http.MethodGet, "/healthz", "", map[string]string{"X-API-Key": "guess-2"}).Code)
+	}
+	assert.Equal(t, int64(1), limitCount("GraphQL.rate_limited")-rejected)
+}
+
+func TestLimiter_BodyCaps(t *testing.T) {
+	limiter, err := NewLimiter(LimitsConfig{MaxDocumentBytes: 64, MaxImportBytes: 128})
+	assert.NoError(t, err)
+	backend := &memoryBackend{}
+	rest := NewRESTAPI(backend, ":0")
+	rest.SetLimiter(limiter)
+	handler := rest.Handler()
+
+	assert.Equal(t, http.StatusCreated, serve(handler, http.MethodPost, "/documents", `{"id":"1","text":"small"}`, nil).Code)
+	large := `{"id":"2","text":"` + strings.Repeat("x", 100) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(handler, http.MethodPost, "/documents", large, nil).Code)
+	assert.Len(t, backend.docs, 1)
+
+	// Bodies of unknown length are cut off while reading
+	req := httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(large))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	graphql := NewGraphQLAPI(backend, ":0")
+	graphql.SetLimiter(limiter)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(graphql.Handler(), http.MethodPost, "/query", `{"query":"`+strings.Repeat(" ", 100)+`{ ping }"}`, nil).Code)
+
+	_, err = NewLimiter(LimitsConfig{RateLimit: &ClientRateLimit{}})
+	assert.ErrorContains(t, err, "per_second must be positive")
+}
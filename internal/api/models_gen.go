@@ -2,16 +2,59 @@
 
 package api
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A bulk operation; deletes only need the id. The id fills in a missing document id.
+type BulkItemInput struct {
+	Action   BulkAction     `json:"action"`
+	ID       *string        `json:"id,omitempty"`
+	Document *DocumentInput `json:"document,omitempty"`
+}
+
+type BulkItemResult struct {
+	Action BulkAction `json:"action"`
+	ID     *string    `json:"id,omitempty"`
+	Error  *string    `json:"error,omitempty"`
+}
+
+type BulkResult struct {
+	// Whether any item failed
+	Errors bool `json:"errors"`
+	// The outcome of each item, in request order
+	Items []*BulkItemResult `json:"items"`
+	Error *string           `json:"error,omitempty"`
+}
+
 type CommandResult struct {
 	Error *string `json:"error,omitempty"`
 }
 
+type Dimension struct {
+	Name string `json:"name"`
+	// Documents holding the metadata key
+	Documents int `json:"documents"`
+	// Distinct values of the metadata key
+	Values int `json:"values"`
+}
+
+type DimensionsResult struct {
+	Dimensions []*Dimension `json:"dimensions"`
+	Error      *string      `json:"error,omitempty"`
+}
+
 type Document struct {
 	ID     *string   `json:"id,omitempty"`
 	Text   *string   `json:"text,omitempty"`
 	Source *string   `json:"source,omitempty"`
 	Vector []float64 `json:"vector,omitempty"`
 	Meta   *string   `json:"meta,omitempty"`
+	// Principals (users, roles or groups) allowed to find the document; empty: everyone
+	AllowedPrincipals []string `json:"allowedPrincipals,omitempty"`
 }
 
 type DocumentInput struct {
@@ -20,28 +63,302 @@ type DocumentInput struct {
 	Source *string   `json:"source,omitempty"`
 	Vector []float64 `json:"vector,omitempty"`
 	Meta   *string   `json:"meta,omitempty"`
+	// Principals (users, roles or groups) allowed to find the document; empty: everyone
+	AllowedPrincipals []string `json:"allowedPrincipals,omitempty"`
+}
+
+type DocumentResult struct {
+	Document *Document `json:"document,omitempty"`
+	Error    *string   `json:"error,omitempty"`
+}
+
+type DocumentsResult struct {
+	Documents []*Document `json:"documents"`
+	Error     *string     `json:"error,omitempty"`
+}
+
+type FeatureExtractorStats struct {
+	Name string `json:"name"`
+	// Loaders whose documents go through the extractor
+	Loaders []string `json:"loaders"`
+}
+
+type IndexStats struct {
+	Name         string `json:"name"`
+	NumDocuments int    `json:"numDocuments"`
+	// Approximate size in bytes, null if the index does not report it
+	SizeBytes *int `json:"sizeBytes,omitempty"`
+	// Failed async writes to storage, null if the index does not report them
+	Persistence *PersistenceStats `json:"persistence,omitempty"`
+}
+
+type LoaderStats struct {
+	Name                string  `json:"name"`
+	Index               string  `json:"index"`
+	IntervalSeconds     float64 `json:"intervalSeconds"`
+	Runs                int     `json:"runs"`
+	Running             bool    `json:"running"`
+	LastRun             *string `json:"lastRun,omitempty"`
+	LastDurationSeconds float64 `json:"lastDurationSeconds"`
+	LastError           *string `json:"lastError,omitempty"`
+	NextRun             *string `json:"nextRun,omitempty"`
+	// Documents added, modified and deleted by the last run
+	Added    int `json:"added"`
+	Modified int `json:"modified"`
+	Deleted  int `json:"deleted"`
+}
+
+type MemoryStats struct {
+	HeapAllocBytes     int     `json:"heapAllocBytes"`
+	HeapInuseBytes     int     `json:"heapInuseBytes"`
+	SysBytes           int     `json:"sysBytes"`
+	NumGc              int     `json:"numGC"`
+	LastGCPauseSeconds float64 `json:"lastGCPauseSeconds"`
 }
 
 type Mutation struct {
 }
 
+type PercolateResult struct {
+	Matches []*StoredQuery `json:"matches"`
+	Error   *string        `json:"error,omitempty"`
+}
+
+type PersistenceStats struct {
+	// Writes given up on after their retries
+	Failed      int     `json:"failed"`
+	Retried     int     `json:"retried"`
+	LastError   *string `json:"lastError,omitempty"`
+	LastFailure *string `json:"lastFailure,omitempty"`
+}
+
 type PingResult struct {
 	Pong string `json:"pong"`
 }
 
+type PlanStep struct {
+	Condition string `json:"condition"`
+	// inverted, bitmap, range or scan
+	Access string `json:"access"`
+	// Documents expected to match the step alone
+	Estimate int `json:"estimate"`
+}
+
 type Query struct {
 }
 
+type QueryError struct {
+	// Characters (Unicode code points) before the problem
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	// syntax, unknown_dimension, dimension_not_allowed, bad_operator, bad_select or vector_size
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Replacement of the characters at offset, if one is likely
+	Suggestion *string `json:"suggestion,omitempty"`
+}
+
 type QueryInput struct {
 	Query string `json:"query"`
+	// Order of the results: score (default), id or a field (text, source, a metadata key); a - prefix reverses it
+	Sort *string `json:"sort,omitempty"`
+	// Fields to return (text, source, vector, allowedPrincipals, meta or a metadata key), those to leave out prefixed with -; the id is always returned
+	Fields []string `json:"fields,omitempty"`
+	// Language of the query, e.g. de: indexes analyzing documents by language search only the documents of that language
+	Language *string `json:"language,omitempty"`
+}
+
+type QueryPlanResult struct {
+	Index string `json:"index"`
+	// text, conditions, text_conditions, vector or remote
+	Plan      string `json:"plan"`
+	Documents int    `json:"documents"`
+	// In the order they run
+	Steps []*PlanStep `json:"steps"`
+	Error *string     `json:"error,omitempty"`
+}
+
+type QueryStats struct {
+	Total                 int     `json:"total"`
+	Failed                int     `json:"failed"`
+	AverageLatencySeconds float64 `json:"averageLatencySeconds"`
+	LastQuery             *string `json:"lastQuery,omitempty"`
+}
+
+type QueryValidationResult struct {
+	Valid  bool          `json:"valid"`
+	Errors []*QueryError `json:"errors"`
+	Error  *string       `json:"error,omitempty"`
+}
+
+type SavedSearch struct {
+	Name string `json:"name"`
+	// Query with optional {{name}} placeholders
+	Query  string   `json:"query"`
+	Sort   *string  `json:"sort,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+	// Default values of placeholders, a JSON object
+	Params  *string `json:"params,omitempty"`
+	Updated string  `json:"updated"`
+}
+
+type SavedSearchInput struct {
+	// Letters, digits, '_', '-' and '.'
+	Name string `json:"name"`
+	// Query with optional {{name}} placeholders, e.g. extension:{{ext}}
+	Query  string   `json:"query"`
+	Sort   *string  `json:"sort,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+	// Default values of placeholders, a JSON object of strings
+	Params *string `json:"params,omitempty"`
+}
+
+type SavedSearchResult struct {
+	Search *SavedSearch `json:"search,omitempty"`
+	Error  *string      `json:"error,omitempty"`
+}
+
+type SavedSearchesResult struct {
+	Searches []*SavedSearch `json:"searches"`
+	Error    *string        `json:"error,omitempty"`
+}
+
+type SearchMatch struct {
+	Index    string    `json:"index"`
+	Document *Document `json:"document"`
 }
 
 type SearchResult struct {
 	Results    []*Document `json:"results"`
 	TotalCount int         `json:"totalCount"`
-	Error      *string     `json:"error,omitempty"`
+	// Peer nodes a distributed search got no results from (the results are partial)
+	FailedNodes []string `json:"failedNodes,omitempty"`
+	// Why the query matched nothing, e.g. dimensions no document has
+	Warnings []*QueryError `json:"warnings,omitempty"`
+	Error    *string       `json:"error,omitempty"`
+}
+
+type SnapshotResult struct {
+	Index *string `json:"index,omitempty"`
+	// Path of the snapshot on the server
+	Path  *string `json:"path,omitempty"`
+	Time  *string `json:"time,omitempty"`
+	Bytes *int    `json:"bytes,omitempty"`
+	Error *string `json:"error,omitempty"`
 }
 
 type StatsResult struct {
 	NumDocuments int `json:"numDocuments"`
+	// When the engine started (RFC 3339), null while it is starting
+	StartedAt         *string                  `json:"startedAt,omitempty"`
+	UptimeSeconds     float64                  `json:"uptimeSeconds"`
+	Indexes           []*IndexStats            `json:"indexes"`
+	Loaders           []*LoaderStats           `json:"loaders"`
+	FeatureExtractors []*FeatureExtractorStats `json:"featureExtractors"`
+	Memory            *MemoryStats             `json:"memory"`
+	Queries           *QueryStats              `json:"queries"`
+}
+
+type StoredQueriesResult struct {
+	Queries []*StoredQuery `json:"queries"`
+	Error   *string        `json:"error,omitempty"`
+}
+
+type StoredQuery struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	// Namespace whose documents the query matches; null: any index
+	Namespace *string `json:"namespace,omitempty"`
+	Created   string  `json:"created"`
+}
+
+type StoredQueryResult struct {
+	Query *StoredQuery `json:"query,omitempty"`
+	Error *string      `json:"error,omitempty"`
+}
+
+type Subscription struct {
+}
+
+type TopValuesResult struct {
+	Dimension string        `json:"dimension"`
+	Values    []*ValueCount `json:"values"`
+	Error     *string       `json:"error,omitempty"`
+}
+
+type ValueCount struct {
+	Value string `json:"value"`
+	// Documents holding the value
+	Count int `json:"count"`
+}
+
+type VerifyResult struct {
+	Index      *string `json:"index,omitempty"`
+	Consistent bool    `json:"consistent"`
+	// Documents in memory
+	Documents int `json:"documents"`
+	// Documents in storage
+	Stored          int      `json:"stored"`
+	MissingOnDisk   []string `json:"missingOnDisk"`
+	MissingInMemory []string `json:"missingInMemory"`
+	Mismatched      []string `json:"mismatched"`
+	Repaired        int      `json:"repaired"`
+	Error           *string  `json:"error,omitempty"`
+}
+
+type BulkAction string
+
+const (
+	BulkActionIndex  BulkAction = "INDEX"
+	BulkActionUpdate BulkAction = "UPDATE"
+	BulkActionDelete BulkAction = "DELETE"
+)
+
+var AllBulkAction = []BulkAction{
+	BulkActionIndex,
+	BulkActionUpdate,
+	BulkActionDelete,
+}
+
+func (e BulkAction) IsValid() bool {
+	switch e {
+	case BulkActionIndex, BulkActionUpdate, BulkActionDelete:
+		return true
+	}
+	return false
+}
+
+func (e BulkAction) String() string {
+	return string(e)
+}
+
+func (e *BulkAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BulkAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BulkAction", str)
+	}
+	return nil
+}
+
+func (e BulkAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *BulkAction) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e BulkAction) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// namespaces returns the namespace operations of a backend (or API), or ones failing with ErrNotSupported
+func namespaces(backend ports.EnginePort) ports.NamespacePort {
+	if namespaces, ok := backend.(ports.NamespacePort); ok {
+		return namespaces
+	}
+	return unsupportedNamespaces{}
+}
+
+// unsupportedNamespaces stands in for the namespaces of backends that have none
+type unsupportedNamespaces struct{}
+
+func (unsupportedNamespaces) err() error {
+	return fmt.Errorf("%w: namespaces", ports.ErrNotSupported)
+}
+
+func (u unsupportedNamespaces) IndexNamespace(string, models.Document) error { return u.err() }
+func (u unsupportedNamespaces) BulkNamespace(context.Context, string, []ports.BulkItem) (ports.BulkResults, error) {
+	return ports.BulkResults{}, u.err()
+}
+func (u unsupportedNamespaces) NamespaceStats(string) (ports.Stats, error) {
+	return ports.Stats{}, u.err()
+}
+
+// indexDocument adds a document to the namespace of the caller in ctx, or to the default index if the
+// caller is unbound
+func indexDocument(ctx context.Context, backend ports.EnginePort, doc models.Document) error {
+	if indexer, ok := backend.(ports.ContextIndexPort); ok {
+		return indexer.IndexContext(ctx, namespaceOf(ctx), doc)
+	}
+	if ports.DurabilityFrom(ctx) != "" {
+		return fmt.Errorf("%w: write durability", ports.ErrNotSupported)
+	}
+	if namespace := namespaceOf(ctx); namespace != "" {
+		return namespaces(backend).IndexNamespace(namespace, doc)
+	}
+	return backend.Index(doc)
+}
+
+// engineStats returns the statistics of the namespace of the caller in ctx, or engine-wide ones if the
+// caller is unbound
+func engineStats(ctx context.Context, backend ports.EnginePort) (ports.Stats, error) {
+	if namespace := namespaceOf(ctx); namespace != "" {
+		return namespaces(backend).NamespaceStats(namespace)
+	}
+	return backend.Stats()
+}
+
+// namespaceStatus maps an error of an operation on the caller's namespace to an HTTP status
+func namespaceStatus(err error) int {
+	switch {
+	case errors.Is(err, ports.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ports.ErrNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, ports.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// The APIs serve namespaces through backends that support them
+
+func (a *RESTAPI) IndexNamespace(namespace string, doc models.Document) error {
+	return namespaces(a.backend).IndexNamespace(namespace, doc)
+}
+
+func (a *RESTAPI) BulkNamespace(ctx context.Context, namespace string, items []ports.BulkItem) (ports.BulkResults, error) {
+	return namespaces(a.backend).BulkNamespace(ctx, namespace, items)
+}
+
+func (a *RESTAPI) NamespaceStats(namespace string) (ports.Stats, error) {
+	return namespaces(a.backend).NamespaceStats(namespace)
+}
+
+func (g *GraphQLAPI) IndexNamespace(namespace string, doc models.Document) error {
+	return namespaces(g.backend).IndexNamespace(namespace, doc)
+}
+
+func (g *GraphQLAPI) BulkNamespace(ctx context.Context, namespace string, items []ports.BulkItem) (ports.BulkResults, error) {
+	return namespaces(g.backend).BulkNamespace(ctx, namespace, items)
+}
+
+func (g *GraphQLAPI) NamespaceStats(namespace string) (ports.Stats, error) {
+	return namespaces(g.backend).NamespaceStats(namespace)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// namespacedBackend holds the documents of the default index and of each namespace in memory
+type namespacedBackend struct {
+	memoryBackend
+	namespaces map[string]*memoryBackend
+}
+
+func (b *namespacedBackend) namespace(name string) (*memoryBackend, error) {
+	if name == "" {
+		return &b.memoryBackend, nil
+	}
+	backend, ok := b.namespaces[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: namespace %s has no index", ports.ErrNotFound, name)
+	}
+	return backend, nil
+}
+
+func (b *namespacedBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	backend, err := b.namespace(query.Namespace)
+	if err != nil {
+		return ports.SearchResults{}, err
+	}
+	return backend.Search(query)
+}
+
+func (b *namespacedBackend) IndexNamespace(namespace string, doc models.Document) error {
+	backend, err := b.namespace(namespace)
+	if err != nil {
+		return err
+	}
+	return backend.Index(doc)
+}
+
+func (b *namespacedBackend) BulkNamespace(ctx context.Context, namespace string, items []ports.BulkItem) (ports.BulkResults, error) {
+	backend, err := b.namespace(namespace)
+	if err != nil {
+		return ports.BulkResults{}, err
+	}
+	var results ports.BulkResults
+	for _, item := range items {
+		results.Items = append(results.Items, ports.BulkItemResult{Action: item.Action, ID: item.Document.ID, Err: backend.Index(item.Document)})
+	}
+	return results, nil
+}
+
+func (b *namespacedBackend) NamespaceStats(namespace string) (ports.Stats, error) {
+	backend, err := b.namespace(namespace)
+	if err != nil {
+		return ports.Stats{}, err
+	}
+	return ports.Stats{NumDocuments: len(backend.docs), Indexes: []ports.IndexStatus{{Name: namespace, NumDocuments: len(backend.docs)}}}, nil
+}
+
+func newNamespacedBackend() *namespacedBackend {
+	return &namespacedBackend{
+		memoryBackend: memoryBackend{docs: []models.Document{{ID: "shared", Text: "hello"}}},
+		namespaces: map[string]*memoryBackend{
+			"team-a": {docs: []models.Document{{ID: "a1", Text: "hello"}, {ID: "a2", Text: "hello"}}},
+			"team-c": {},
+		},
+	}
+}
+
+func newNamespacedAuthenticator(t *testing.T) *Authenticator {
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKeyConfig{
+			{Name: "team-a", Key: "a-key", Roles: []string{RoleAdmin}, Namespace: "team-a"},
+			{Name: "team-b", Key: "b-key", Roles: []string{RoleWriter}, Namespace: "team-b"},
+			{Name: "ops", Key: "ops-key", Roles: []string{RoleAdmin}},
+		},
+		JWT: &JWTConfig{Secret: "jwt-secret", NamespaceClaim: "tenant"},
+	}, nil)
+	assert.NoError(t, err)
+	return auth
+}
+
+func TestRESTAPI_NamespaceIsolation(t *testing.T) {
+	backend := newNamespacedBackend()
+	rest := NewRESTAPI(backend, ":0")
+	rest.SetAuthenticator(newNamespacedAuthenticator(t))
+	handler := rest.Handler()
+	teamA := map[string]string{"X-API-Key": "a-key"}
+	search := func(headers map[string]string) []string {
+		rec := serve(handler, http.MethodGet, "/search?q=hello", "", headers)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp searchResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		var ids []string
+		for _, doc := range resp.Results {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	// Bound keys search, index into and count their namespace alone
+	assert.Equal(t, []string{"a1", "a2"}, search(teamA))
+	assert.Equal(t, []string{"shared"}, search(map[string]string{"X-API-Key": "ops-key"}))
+	assert.Equal(t, http.StatusCreated, serve(handler, http.MethodPost, "/documents", `{"id":"a3","text":"hello"}`, teamA).Code)
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/documents/bulk", `[{"document":{"id":"a4","text":"hello"}}]`, teamA).Code)
+	assert.Len(t, backend.namespaces["team-a"].docs, 4)
+	assert.Len(t, backend.docs, 1)
+
+	rec := serve(handler, http.MethodGet, "/stats", "", teamA)
+	var stats ports.Stats
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, 4, stats.NumDocuments)
+	assert.Equal(t, "team-a", stats.Indexes[0].Name)
+
+	// Namespaces without an index are not found
+	assert.Equal(t, http.StatusNotFound, serve(handler, http.MethodGet, "/search?q=hello", "", map[string]string{"X-API-Key": "b-key"}).Code)
+	assert.Equal(t, http.StatusNotFound, serve(handler, http.MethodPost, "/documents", `{"id":"b1"}`, map[string]string{"X-API-Key": "b-key"}).Code)
+
+	// Bound keys only administer their own namespace's index, and nothing engine-wide
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodGet, "/indexes/team-c/export", "", teamA).Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodDelete, "/indexes/team-c", "", teamA).Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodPost, "/indexes", `{"name":"team-d","type":"simple"}`, teamA).Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodPut, "/aliases/team-c", `{"index":"team-a"}`, teamA).Code)
+	assert.NotEqual(t, http.StatusForbidden, serve(handler, http.MethodPost, "/indexes/team-a/flush", "", teamA).Code)
+	assert.NotEqual(t, http.StatusForbidden, serve(handler, http.MethodPost, "/indexes/team-c/flush", "", map[string]string{"X-API-Key": "ops-key"}).Code)
+
+	// Tokens are bound by their namespace claim
+	token := signToken(t, jwt.MapClaims{"sub": "ci", "exp": time.Now().Add(time.Hour).Unix(), "roles": "reader", "tenant": "team-a"})
+	assert.Equal(t, []string{"a1", "a2", "a3", "a4"}, search(map[string]string{"Authorization": "Bearer " + token}))
+}
+
+func TestGraphQLAPI_NamespaceIsolation(t *testing.T) {
+	backend := newNamespacedBackend()
+	graphQL := NewGraphQLAPI(backend, ":0")
+	graphQL.SetAuthenticator(newNamespacedAuthenticator(t))
+	handler := graphQL.Handler()
+	query := func(q string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]string{"query": q})
+		rec := serve(handler, http.MethodPost, "/query", string(body), map[string]string{"Content-Type": "application/json", "X-API-Key": "a-key"})
+		var resp map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := query(`{ search(query: {query: "hello"}) { totalCount } stats { numDocuments } }`)
+	assert.Nil(t, resp["errors"])
+	assert.Equal(t, 2.0, resp["data"].(map[string]interface{})["search"].(map[string]interface{})["totalCount"])
+	assert.Equal(t, 2.0, resp["data"].(map[string]interface{})["stats"].(map[string]interface{})["numDocuments"])
+
+	resp = query(`mutation { index(document: {id: "a3", text: "hi"}) { error } }`)
+	assert.Nil(t, resp["errors"])
+	assert.Len(t, backend.namespaces["team-a"].docs, 3)
+
+	resp = query(`mutation { dropIndex(name: "team-c") { error } }`)
+	assert.Contains(t, resp["errors"].([]interface{})[0].(map[string]interface{})["message"], "forbidden")
+	resp = query(`mutation { createIndex(name: "team-d", type: "simple") { error } }`)
+	assert.Contains(t, resp["errors"].([]interface{})[0].(map[string]interface{})["message"], "forbidden")
+}
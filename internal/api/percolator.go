@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// registerQueryRequest is the body of POST /percolator/queries
+type registerQueryRequest struct {
+	ID    string `json:"id,omitempty"`
+	Query string `json:"query"`
+}
+
+// percolateResponse is the body returned by POST /percolate
+type percolateResponse struct {
+	Matches []ports.StoredQuery `json:"matches"`
+}
+
+// percolator returns the stored queries of a backend (or API), or ones failing with ErrNotSupported
+func percolator(backend ports.EnginePort) ports.PercolatorPort {
+	if percolator, ok := backend.(ports.PercolatorPort); ok {
+		return percolator
+	}
+	return unsupportedPercolator{}
+}
+
+// unsupportedPercolator stands in for the stored queries of backends that have none
+type unsupportedPercolator struct{}
+
+func (unsupportedPercolator) err() error {
+	return fmt.Errorf("%w: stored queries", ports.ErrNotSupported)
+}
+
+func (u unsupportedPercolator) RegisterQuery(ports.StoredQuery) (ports.StoredQuery, error) {
+	return ports.StoredQuery{}, u.err()
+}
+func (u unsupportedPercolator) DeleteQuery(string, string) error { return u.err() }
+func (u unsupportedPercolator) ListQueries(string) ([]ports.StoredQuery, error) {
+	return nil, u.err()
+}
+func (u unsupportedPercolator) Percolate(string, models.Document) ([]ports.StoredQuery, error) {
+	return nil, u.err()
+}
+
+// registerQuery stores a query for the caller in ctx: in its namespace, matching only the documents it
+// may see
+func registerQuery(ctx context.Context, backend ports.EnginePort, id, query string) (ports.StoredQuery, error) {
+	return percolator(backend).RegisterQuery(ports.StoredQuery{
+		ID:         id,
+		Query:      query,
+		Namespace:  namespaceOf(ctx),
+		Principals: documentPrincipals(ctx),
+	})
+}
+
+// The APIs serve stored queries through backends that support them
+
+func (a *RESTAPI) RegisterQuery(query ports.StoredQuery) (ports.StoredQuery, error) {
+	return percolator(a.backend).RegisterQuery(query)
+}
+
+func (a *RESTAPI) DeleteQuery(namespace, id string) error {
+	return percolator(a.backend).DeleteQuery(namespace, id)
+}
+
+func (a *RESTAPI) ListQueries(namespace string) ([]ports.StoredQuery, error) {
+	return percolator(a.backend).ListQueries(namespace)
+}
+
+func (a *RESTAPI) Percolate(namespace string, doc models.Document) ([]ports.StoredQuery, error) {
+	return percolator(a.backend).Percolate(namespace, doc)
+}
+
+func (g *GraphQLAPI) RegisterQuery(query ports.StoredQuery) (ports.StoredQuery, error) {
+	return percolator(g.backend).RegisterQuery(query)
+}
+
+func (g *GraphQLAPI) DeleteQuery(namespace, id string) error {
+	return percolator(g.backend).DeleteQuery(namespace, id)
+}
+
+func (g *GraphQLAPI) ListQueries(namespace string) ([]ports.StoredQuery, error) {
+	return percolator(g.backend).ListQueries(namespace)
+}
+
+func (g *GraphQLAPI) Percolate(namespace string, doc models.Document) ([]ports.StoredQuery, error) {
+	return percolator(g.backend).Percolate(namespace, doc)
+}
+
+// handleRegisterQuery stores the query of a JSON body, with the id it names or a generated one
+func (a *RESTAPI) handleRegisterQuery(w http.ResponseWriter, r *http.Request) {
+	var request registerQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	query, err := registerQuery(r.Context(), a.backend, request.ID, request.Query)
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, query)
+}
+
+func (a *RESTAPI) handleListQueries(w http.ResponseWriter, r *http.Request) {
+	queries, err := a.ListQueries(namespaceOf(r.Context()))
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, queries)
+}
+
+func (a *RESTAPI) handleDeleteQuery(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := a.DeleteQuery(namespaceOf(r.Context()), id); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "status": "deleted"})
+}
+
+// handlePercolate returns the stored queries the document in the body matches, without indexing it
+func (a *RESTAPI) handlePercolate(w http.ResponseWriter, r *http.Request) {
+	var doc models.Document
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	matches, err := a.Percolate(namespaceOf(r.Context()), doc)
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, percolateResponse{Matches: matches})
+}
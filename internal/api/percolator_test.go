@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// percolatorBackend stores queries matching the documents whose text contains them
+type percolatorBackend struct {
+	memoryBackend
+	queries []ports.StoredQuery
+}
+
+func (b *percolatorBackend) RegisterQuery(query ports.StoredQuery) (ports.StoredQuery, error) {
+	if query.ID == "" {
+		query.ID = fmt.Sprintf("q%d", len(b.queries)+1)
+	}
+	query.Created = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.queries = append(b.queries, query)
+	return query, nil
+}
+
+func (b *percolatorBackend) DeleteQuery(namespace, id string) error {
+	for i, query := range b.queries {
+		if query.ID == id && (namespace == "" || query.Namespace == namespace) {
+			b.queries = append(b.queries[:i], b.queries[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: stored query %s", ports.ErrNotFound, id)
+}
+
+func (b *percolatorBackend) ListQueries(namespace string) ([]ports.StoredQuery, error) {
+	queries := []ports.StoredQuery{}
+	for _, query := range b.queries {
+		if namespace == "" || query.Namespace == namespace {
+			queries = append(queries, query)
+		}
+	}
+	return queries, nil
+}
+
+func (b *percolatorBackend) Percolate(namespace string, doc models.Document) ([]ports.StoredQuery, error) {
+	queries, _ := b.ListQueries(namespace)
+	matches := []ports.StoredQuery{}
+	for _, query := range queries {
+		if strings.Contains(doc.Text, query.Query) {
+			matches = append(matches, query)
+		}
+	}
+	return matches, nil
+}
+
+func TestRESTAPI_Percolator(t *testing.T) {
+	backend := &percolatorBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/percolator/queries", `{"query":"go"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var stored ports.StoredQuery
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stored))
+	assert.Equal(t, "q1", stored.ID)
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/percolator/queries", `{"id":"rust","query":"rust"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/percolator/queries", `{`).Code)
+
+	rec = serve(http.MethodGet, "/percolator/queries", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var queries []ports.StoredQuery
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queries))
+	assert.Len(t, queries, 2)
+
+	rec = serve(http.MethodPost, "/percolate", `{"id":"1","text":"learning go"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp percolateResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Matches, 1)
+	assert.Equal(t, "q1", resp.Matches[0].ID)
+	// Percolating does not index the document
+	assert.Empty(t, backend.docs)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/percolator/queries/rust", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/percolator/queries/rust", "").Code)
+
+	// Backends without stored queries
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/percolator/queries", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_Percolator(t *testing.T) {
+	backend := &percolatorBackend{}
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+	post := func(query string, out interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+
+	var registered struct {
+		Data struct {
+			RegisterQuery StoredQueryResult `json:"registerQuery"`
+		} `json:"data"`
+	}
+	post(`mutation { registerQuery(query: "go", id: "golang") { query { id query created } error } }`, &registered)
+	assert.Nil(t, registered.Data.RegisterQuery.Error)
+	assert.Equal(t, "golang", registered.Data.RegisterQuery.Query.ID)
+	assert.Equal(t, "2026-01-01T00:00:00Z", registered.Data.RegisterQuery.Query.Created)
+
+	var percolated struct {
+		Data struct {
+			Percolate PercolateResult `json:"percolate"`
+		} `json:"data"`
+	}
+	post(`{ percolate(document: {id: "1", text: "go go"}) { matches { id query } error } }`, &percolated)
+	assert.Len(t, percolated.Data.Percolate.Matches, 1)
+	assert.Equal(t, "go", percolated.Data.Percolate.Matches[0].Query)
+
+	var deleted struct {
+		Data struct {
+			DeleteQuery CommandResult `json:"deleteQuery"`
+		} `json:"data"`
+	}
+	post(`mutation { deleteQuery(id: "golang") { error } }`, &deleted)
+	assert.Nil(t, deleted.Data.DeleteQuery.Error)
+
+	var listed struct {
+		Data struct {
+			StoredQueries StoredQueriesResult `json:"storedQueries"`
+		} `json:"data"`
+	}
+	post(`{ storedQueries { queries { id } error } }`, &listed)
+	assert.Empty(t, listed.Data.StoredQueries.Queries)
+}
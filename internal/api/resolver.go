@@ -1,7 +1,11 @@
 package api
 
+import "github.com/aawadall/bit-scout/internal/ports"
+
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{}
+type Resolver struct {
+	api ports.APIPort
+}
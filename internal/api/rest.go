@@ -0,0 +1,435 @@
+package api
+
+// REST Implementation to API port
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// RESTAPI implements the APIPort interface as a JSON-over-HTTP API.
+// Operations are delegated to the engine backend.
+type RESTAPI struct {
+	backend ports.EnginePort
+	listen  string
+	auth    *Authenticator
+	limits  *Limiter
+	cors    *CORSConfig
+
+	mu     sync.Mutex
+	server *http.Server
+	// Cancelled when the API stops, ending open event streams
+	streams     context.Context
+	stopStreams context.CancelFunc
+}
+
+// searchResponse is the body returned by GET /search
+type searchResponse struct {
+	Results     []models.Document  `json:"results"`
+	TotalCount  int                `json:"totalCount"`
+	FailedNodes []string           `json:"failedNodes,omitempty"` // Peer nodes missing from partial results
+	Warnings    []ports.QueryError `json:"warnings,omitempty"`    // Why the query matched nothing, e.g. unknown dimensions
+}
+
+// errorResponse is the body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewRESTAPI creates a REST API serving on the given listen address (e.g. ":8081")
+func NewRESTAPI(backend ports.EnginePort, listen string) *RESTAPI {
+	return &RESTAPI{backend: backend, listen: listen}
+}
+
+func (a *RESTAPI) Name() string {
+	return "REST"
+}
+
+// Handler returns the HTTP handler serving the REST routes
+func (a *RESTAPI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /ping", traced("/ping", http.HandlerFunc(a.handlePing)))
+	// Routes by the scope callers need when authentication is configured, and what callers bound to a
+	// namespace may do with them
+	routes := map[string]struct {
+		scope   string
+		tenancy tenancy
+		handler http.HandlerFunc
+	}{
+		"GET /search":                     {ScopeSearch, tenantScoped, a.handleSearch},
+		"GET /search/subscribe":           {ScopeSearch, tenantScoped, a.handleSubscribe},
+		"POST /search/template":           {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleSearchTemplate))},
+		"POST /search/template/render":    {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleRenderTemplate))},
+		"GET /stats":                      {ScopeSearch, tenantScoped, a.handleStats},
+		"GET /validate":                   {ScopeSearch, tenantScoped, a.handleValidate},
+		"GET /explain":                    {ScopeSearch, tenantScoped, a.handleExplain},
+		"POST /documents":                 {ScopeIndex, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleIndex))},
+		"POST /documents/bulk":            {ScopeIndex, tenantScoped, limitBody(a.Name(), a.limits.maxImportBytes(), http.HandlerFunc(a.handleBulk))},
+		"GET /documents/{id}":             {ScopeSearch, tenantScoped, a.handleGetDocument},
+		"POST /documents/mget":            {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleMultiGet))},
+		"GET /indexes/{name}/export":      {ScopeAdmin, tenantIndex, a.handleExport},
+		"GET /indexes/{name}/documents":   {ScopeAdmin, tenantIndex, a.handleListDocuments},
+		"POST /indexes/{name}/import":     {ScopeIndex, tenantIndex, limitBody(a.Name(), a.limits.maxImportBytes(), http.HandlerFunc(a.handleImport))},
+		"POST /indexes":                   {ScopeAdmin, tenantDenied, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleCreateIndex))},
+		"DELETE /indexes/{name}":          {ScopeAdmin, tenantIndex, a.handleDropIndex},
+		"PUT /indexes/{name}/config":      {ScopeAdmin, tenantIndex, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleConfigureIndex))},
+		"POST /indexes/{name}/flush":      {ScopeAdmin, tenantIndex, a.handleFlushIndex},
+		"POST /indexes/{name}/optimize":   {ScopeAdmin, tenantIndex, a.handleOptimizeIndex},
+		"POST /indexes/{name}/clear":      {ScopeAdmin, tenantIndex, a.handleClearIndex},
+		"POST /indexes/{name}/verify":     {ScopeAdmin, tenantIndex, a.handleVerifyIndex},
+		"POST /indexes/{name}/snapshot":   {ScopeAdmin, tenantIndex, a.handleSnapshotIndex},
+		"PUT /aliases/{alias}":            {ScopeAdmin, tenantDenied, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleSetAlias))},
+		"DELETE /aliases/{alias}":         {ScopeAdmin, tenantDenied, a.handleRemoveAlias},
+		"POST /loaders/{name}/run":        {ScopeAdmin, tenantDenied, a.handleRunLoader},
+		"POST /percolator/queries":        {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleRegisterQuery))},
+		"GET /percolator/queries":         {ScopeSearch, tenantScoped, a.handleListQueries},
+		"DELETE /percolator/queries/{id}": {ScopeSearch, tenantScoped, a.handleDeleteQuery},
+		"POST /percolate":                 {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handlePercolate))},
+		"GET /searches":                   {ScopeSearch, tenantScoped, a.handleListSavedSearches},
+		"GET /searches/{name}":            {ScopeSearch, tenantScoped, a.handleGetSavedSearch},
+		"PUT /searches/{name}":            {ScopeSearch, tenantScoped, limitBody(a.Name(), a.limits.maxDocumentBytes(), http.HandlerFunc(a.handleSaveSearch))},
+		"DELETE /searches/{name}":         {ScopeSearch, tenantScoped, a.handleDeleteSavedSearch},
+		"GET /searches/{name}/run":        {ScopeSearch, tenantScoped, a.handleRunSavedSearch},
+	}
+	for pattern, route := range routes {
+		_, path, _ := strings.Cut(pattern, " ")
+		mux.Handle(pattern, traced(path, a.limits.Middleware(a.Name(), a.auth.require(route.scope, route.tenancy, route.handler))))
+	}
+	handleHealth(mux, a.backend)
+	return a.cors.middleware(a.auth.Middleware(a.Name(), a.limits, mux))
+}
+
+// SetCORS lets browser frontends on the configured origins call the API (nil: same origin only)
+func (a *RESTAPI) SetCORS(cors *CORSConfig) {
+	a.cors = cors
+}
+
+// SetAuthenticator requires credentials for the REST routes (nil: none)
+func (a *RESTAPI) SetAuthenticator(auth *Authenticator) {
+	a.auth = auth
+}
+
+// SetLimiter rate limits the REST routes per client and caps request bodies (nil: default caps only)
+func (a *RESTAPI) SetLimiter(limits *Limiter) {
+	a.limits = limits
+}
+
+// Start serves the REST routes until Stop is called (blocking)
+func (a *RESTAPI) Start() error {
+	server := &http.Server{Addr: a.listen, Handler: a.Handler()}
+	a.mu.Lock()
+	a.server = server
+	a.streams, a.stopStreams = context.WithCancel(context.Background())
+	a.mu.Unlock()
+
+	log.Info().Msgf("REST server running at http://%s", displayAddr(a.listen))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Stop gracefully shuts down the REST server
+func (a *RESTAPI) Stop() error {
+	a.mu.Lock()
+	server := a.server
+	a.server = nil
+	if a.stopStreams != nil {
+		a.stopStreams()
+	}
+	a.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+func (a *RESTAPI) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return a.backend.Search(query)
+}
+
+// SearchContext is Search as part of the trace in ctx
+func (a *RESTAPI) SearchContext(ctx context.Context, query ports.SearchQuery) (ports.SearchResults, error) {
+	return searchContext(ctx, a.backend, query)
+}
+
+// SubscribeSearch pushes the documents matching a query as they are indexed, until ctx is done
+func (a *RESTAPI) SubscribeSearch(ctx context.Context, query ports.SearchQuery) (<-chan ports.SearchMatch, error) {
+	return subscribeSearch(ctx, a.backend, query)
+}
+
+// streamContext is cancelled when the API stops (never, if it was not started through Start)
+func (a *RESTAPI) streamContext() context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.streams == nil {
+		return context.Background()
+	}
+	return a.streams
+}
+
+func (a *RESTAPI) Stats() (ports.Stats, error) {
+	return a.backend.Stats()
+}
+
+func (a *RESTAPI) Index(doc models.Document) error {
+	return a.backend.Index(doc)
+}
+
+// Bulk applies many document operations in one request; items fail independently
+func (a *RESTAPI) Bulk(ctx context.Context, items []ports.BulkItem) (ports.BulkResults, error) {
+	return bulk(ctx, a.backend, items)
+}
+
+func (a *RESTAPI) handlePing(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"message": "pong"})
+}
+
+func (a *RESTAPI) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+	projection, err := searchProjection(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.serveSearch(w, r, ports.SearchQuery{Query: query, Sort: r.URL.Query().Get("sort"), Projection: projection, Language: r.URL.Query().Get("lang")})
+}
+
+// serveSearch runs a search for the caller of r, in its namespace and with its document filters, and
+// writes the results in the format r asks for
+func (a *RESTAPI) serveSearch(w http.ResponseWriter, r *http.Request, search ports.SearchQuery) {
+	format, ok := searchFormat(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("format must be json, ndjson or csv"))
+		return
+	}
+	search.Caller = a.Name() + " " + r.RemoteAddr
+	search.Filter = documentFilter(r.Context())
+	search.Namespace = namespaceOf(r.Context())
+	search.Principals = documentPrincipals(r.Context())
+
+	results, err := a.SearchContext(r.Context(), search)
+	if errors.Is(err, ports.ErrRateLimited) {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, http.StatusGatewayTimeout, err)
+		return
+	}
+	if errors.Is(err, ports.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, ports.ErrInvalid) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	docs := results.Documents
+	if docs == nil {
+		docs = []models.Document{}
+	}
+	w.Header().Add("Vary", "Accept")
+	switch format {
+	case formatNDJSON:
+		writeNDJSON(w, docs)
+	case formatCSV:
+		writeCSV(w, docs, csvColumns(r))
+	default:
+		writeJSON(w, http.StatusOK, searchResponse{Results: docs, TotalCount: len(docs), FailedNodes: results.FailedNodes, Warnings: results.Warnings})
+	}
+}
+
+func (a *RESTAPI) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := engineStats(r.Context(), a)
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *RESTAPI) handleIndex(w http.ResponseWriter, r *http.Request) {
+	var doc models.Document
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	if doc.ID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("document id is required"))
+		return
+	}
+	ctx, err := durabilityContext(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := indexDocument(ctx, a.backend, doc); err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": doc.ID})
+}
+
+// handleBulk applies a JSON array of bulk items, answering 200 with each item's status even if some fail
+func (a *RESTAPI) handleBulk(w http.ResponseWriter, r *http.Request) {
+	var request []bulkRequestItem
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	items := make([]ports.BulkItem, len(request))
+	for i, item := range request {
+		items[i] = item.toBulkItem()
+	}
+	ctx, err := durabilityContext(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	results, err := a.Bulk(ctx, items)
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toBulkResponse(results))
+}
+
+// durabilityContext passes the durability parameter of a write ("none", "persisted" or "fsync"; absent:
+// the index's default) to the indexes applying it
+func durabilityContext(r *http.Request) (context.Context, error) {
+	return withDurability(r.Context(), r.URL.Query().Get("durability"))
+}
+
+// withDurability passes a write durability of a request to the indexes applying it, failing for unknown ones
+func withDurability(ctx context.Context, durability string) (context.Context, error) {
+	switch durability {
+	case "", ports.DurabilityNone, ports.DurabilityPersisted, ports.DurabilityFsync:
+		return ports.WithDurability(ctx, durability), nil
+	}
+	return nil, fmt.Errorf("unknown durability %q (want %s, %s or %s)", durability, ports.DurabilityNone, ports.DurabilityPersisted, ports.DurabilityFsync)
+}
+
+// transfer returns the backend's index export and import, answering 501 if it has none
+func (a *RESTAPI) transfer(w http.ResponseWriter) (ports.IndexTransferPort, bool) {
+	transfer, ok := a.backend.(ports.IndexTransferPort)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errors.New("index export and import are not supported"))
+	}
+	return transfer, ok
+}
+
+// handleExport answers with an index as NDJSON: a header line, then one document per line
+func (a *RESTAPI) handleExport(w http.ResponseWriter, r *http.Request) {
+	transfer, ok := a.transfer(w)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	stream := &exportStream{w: w, name: name}
+	err := transfer.ExportIndex(name, stream)
+	switch {
+	case err == nil && !stream.started:
+		stream.start()
+	case err != nil && !stream.started:
+		writeError(w, transferStatus(err), err)
+	case err != nil:
+		// The status is sent: abort the response so the client does not take the export for complete
+		log.Warn().Err(err).Msgf("Failed to send export of index %s", name)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportStream sends an export as it is written, with the headers of a successful export once its
+// first line is, so exports failing before writing anything can still be answered with an error status
+type exportStream struct {
+	w       http.ResponseWriter
+	name    string
+	started bool
+}
+
+func (s *exportStream) start() {
+	s.w.Header().Set("Content-Type", "application/x-ndjson")
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.name+".ndjson"))
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *exportStream) Write(p []byte) (int, error) {
+	if !s.started {
+		s.start()
+	}
+	return s.w.Write(p)
+}
+
+// handleImport adds the documents of an NDJSON export in the request body to an index
+func (a *RESTAPI) handleImport(w http.ResponseWriter, r *http.Request) {
+	transfer, ok := a.transfer(w)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	if err := transfer.ImportIndex(name, r.Body); err != nil {
+		if payloadTooLarge(a.Name(), err) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, transferStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"index": name, "status": "imported"})
+}
+
+// bodyStatus maps an error reading a request body to an HTTP status
+func (a *RESTAPI) bodyStatus(err error) int {
+	if payloadTooLarge(a.Name(), err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// transferStatus maps an export or import error to an HTTP status
+func transferStatus(err error) int {
+	switch {
+	case errors.Is(err, ports.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ports.ErrNotSupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusBadRequest
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn().Err(err).Msg("Failed to write JSON response")
+	}
+}
+
+// writeError writes an error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,424 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// memoryBackend is a minimal EnginePort holding documents in memory
+type memoryBackend struct {
+	docs []models.Document
+}
+
+func (b *memoryBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	var results ports.SearchResults
+	for _, doc := range b.docs {
+		if query.Filter != nil && !query.Filter(doc) || query.Principals != nil && !doc.VisibleTo(query.Principals) {
+			continue
+		}
+		if strings.Contains(doc.Text, query.Query) {
+			results.Documents = append(results.Documents, doc)
+		}
+	}
+	return results, nil
+}
+
+func (b *memoryBackend) Stats() (ports.Stats, error) {
+	return ports.Stats{NumDocuments: len(b.docs)}, nil
+}
+
+func (b *memoryBackend) Index(doc models.Document) error {
+	b.docs = append(b.docs, doc)
+	return nil
+}
+
+func TestRESTAPI_IndexAndSearch(t *testing.T) {
+	backend := &memoryBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	rec := httptest.NewRecorder()
+	body := `{"id":"1","text":"hello world","source":"test"}`
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Len(t, backend.docs, 1)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=hello", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp searchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.TotalCount)
+	assert.Equal(t, "1", resp.Results[0].ID)
+}
+
+func TestRESTAPI_SearchRequiresQuery(t *testing.T) {
+	handler := NewRESTAPI(&memoryBackend{}, ":0").Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// timedOutBackend fails every search as past its deadline
+type timedOutBackend struct{ memoryBackend }
+
+func (b *timedOutBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return ports.SearchResults{}, fmt.Errorf("search timed out after 1s: %w", context.DeadlineExceeded)
+}
+
+// sortingBackend fails searches with an invalid sort order and records the others
+type sortingBackend struct {
+	memoryBackend
+	sort string
+}
+
+func (b *sortingBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	if query.Sort == "-" {
+		return ports.SearchResults{}, fmt.Errorf("sort %q names no field: %w", query.Sort, ports.ErrInvalid)
+	}
+	b.sort = query.Sort
+	return b.memoryBackend.Search(query)
+}
+
+func TestRESTAPI_SearchSort(t *testing.T) {
+	backend := &sortingBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=report&sort=-source", "", nil).Code)
+	assert.Equal(t, "-source", backend.sort)
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodGet, "/search?q=report&sort=-", "", nil).Code)
+}
+
+// languageBackend records the language hints of its searches
+type languageBackend struct {
+	memoryBackend
+	language string
+}
+
+func (b *languageBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	b.language = query.Language
+	return b.memoryBackend.Search(query)
+}
+
+func TestRESTAPI_SearchLanguage(t *testing.T) {
+	backend := &languageBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=bericht&lang=de", "", nil).Code)
+	assert.Equal(t, "de", backend.language)
+}
+
+// projectingBackend records the projections of its searches
+type projectingBackend struct {
+	memoryBackend
+	projection models.Projection
+}
+
+func (b *projectingBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	b.projection = query.Projection
+	return b.memoryBackend.Search(query)
+}
+
+func TestRESTAPI_SearchFields(t *testing.T) {
+	backend := &projectingBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "/search?q=report&fields=source,filename,-owner", "", nil).Code)
+	assert.Equal(t, models.Projection{Include: []string{"source", "filename"}, Exclude: []string{"owner"}}, backend.projection)
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodGet, "/search?q=report&fields=-id", "", nil).Code)
+}
+
+func TestRESTAPI_SearchTimeout(t *testing.T) {
+	handler := NewRESTAPI(&timedOutBackend{}, ":0").Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=hello", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), "timed out")
+}
+
+func TestAPIFactory_Create(t *testing.T) {
+	factory := NewAPIFactory()
+
+	api, err := factory.Create("REST", &memoryBackend{}, map[string]interface{}{"listen": ":9090"})
+	assert.NoError(t, err)
+	assert.Equal(t, "REST", api.Name())
+	assert.Equal(t, ":9090", api.(*RESTAPI).listen)
+
+	api, err = factory.Create("gRPC", &memoryBackend{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", api.(*GRPCAPI).listen)
+	_, err = factory.Create("gRPC", &memoryBackend{}, map[string]interface{}{"cors": map[string]interface{}{"origins": []interface{}{"*"}}})
+	assert.Error(t, err)
+
+	_, err = factory.Create("SOAP", &memoryBackend{}, nil)
+	assert.Error(t, err)
+}
+
+// transferBackend is a memoryBackend that exports and imports the "docs" index. Exports call written
+// once their header is written, then fail with failure, if set.
+type transferBackend struct {
+	memoryBackend
+	imported string
+	written  func()
+	failure  error
+}
+
+func (b *transferBackend) ExportIndex(name string, w io.Writer) error {
+	if name != "docs" {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	if _, err := io.WriteString(w, `{"format":"bitscout-index","version":1}`+"\n"); err != nil {
+		return err
+	}
+	if b.written != nil {
+		b.written()
+	}
+	return b.failure
+}
+
+func (b *transferBackend) ImportIndex(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	b.imported = string(data)
+	return err
+}
+
+func TestRESTAPI_ExportImport(t *testing.T) {
+	backend := &transferBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/indexes/docs/export", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "bitscout-index")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/indexes/other/export", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/indexes/docs/import", strings.NewReader("export")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "export", backend.imported)
+
+	// Exports are sent as they are written, and aborted when they fail midway
+	rec = httptest.NewRecorder()
+	backend.written = func() {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bitscout-index", "the export is not buffered")
+	}
+	backend.failure = errors.New("disk failure")
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/indexes/docs/export", nil))
+	})
+
+	// Backends without export and import
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/indexes/docs/export", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+// contextBackend records the span context its searches are made in
+type contextBackend struct {
+	memoryBackend
+	spanContext trace.SpanContext
+}
+
+func (b *contextBackend) SearchContext(ctx context.Context, query ports.SearchQuery) (ports.SearchResults, error) {
+	b.spanContext = trace.SpanContextFromContext(ctx)
+	return b.Search(query)
+}
+
+func TestRESTAPI_PropagatesTraceContext(t *testing.T) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	backend := &contextBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=hello", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", backend.spanContext.TraceID().String())
+	assert.NotEqual(t, "00f067aa0ba902b7", backend.spanContext.SpanID().String())
+}
+
+// healthBackend reports a fixed readiness
+type healthBackend struct {
+	memoryBackend
+	ready bool
+}
+
+func (b *healthBackend) Liveness() ports.HealthReport {
+	return ports.HealthReport{Status: ports.HealthOK, Components: []ports.ComponentHealth{{Name: "memory", Status: ports.HealthOK}}}
+}
+
+func (b *healthBackend) Readiness() ports.HealthReport {
+	if b.ready {
+		return b.Liveness()
+	}
+	return ports.HealthReport{Status: ports.HealthFail, Components: []ports.ComponentHealth{{Name: "engine", Status: ports.HealthFail, Message: "starting"}}}
+}
+
+func TestRESTAPI_HealthProbes(t *testing.T) {
+	backend := &healthBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report ports.HealthReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "starting", report.Components[0].Message)
+
+	backend.ready = true
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Backends without health checks are always healthy
+	rec = httptest.NewRecorder()
+	NewGraphQLAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// bulkBackend applies bulk items to its documents, failing deletes
+type bulkBackend struct {
+	memoryBackend
+}
+
+func (b *bulkBackend) Bulk(ctx context.Context, items []ports.BulkItem) (ports.BulkResults, error) {
+	var results ports.BulkResults
+	for _, item := range items {
+		result := ports.BulkItemResult{Action: item.Action, ID: item.Document.ID}
+		switch {
+		case item.Document.ID == "":
+			result.Err = fmt.Errorf("%w: document id is required", ports.ErrInvalid)
+		case item.Action == ports.BulkDelete:
+			result.Err = fmt.Errorf("%w: deletes", ports.ErrNotSupported)
+		default:
+			result.Err = b.Index(item.Document)
+		}
+		results.Items = append(results.Items, result)
+	}
+	return results, nil
+}
+
+func TestRESTAPI_Bulk(t *testing.T) {
+	backend := &bulkBackend{}
+	handler := NewRESTAPI(backend, ":0").Handler()
+
+	body := `[{"action":"index","document":{"id":"1","text":"one"}},{"document":{"text":"no id"}},{"action":"delete","id":"1"}]`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents/bulk", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp bulkResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Errors)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusBadRequest, http.StatusNotImplemented},
+		[]int{resp.Items[0].Status, resp.Items[1].Status, resp.Items[2].Status})
+	assert.Equal(t, ports.BulkIndex, resp.Items[1].Action)
+	assert.Equal(t, "1", resp.Items[2].ID)
+	assert.Len(t, backend.docs, 1)
+
+	// Backends without bulk support
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/documents/bulk", strings.NewReader("[]")))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_Bulk(t *testing.T) {
+	backend := &bulkBackend{}
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+
+	query := `{"query":"mutation { bulk(items: [{action: INDEX, document: {id: \"1\", text: \"one\"}}, {action: DELETE, id: \"1\"}]) { errors items { action id error } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			Bulk BulkResult `json:"bulk"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Bulk.Errors)
+	assert.Len(t, resp.Data.Bulk.Items, 2)
+	assert.Nil(t, resp.Data.Bulk.Items[0].Error)
+	assert.Equal(t, BulkActionDelete, resp.Data.Bulk.Items[1].Action)
+	assert.Contains(t, *resp.Data.Bulk.Items[1].Error, "not supported")
+	assert.Len(t, backend.docs, 1)
+}
+
+// statsBackend reports fixed engine statistics
+type statsBackend struct {
+	memoryBackend
+	stats ports.Stats
+}
+
+func (b *statsBackend) Stats() (ports.Stats, error) {
+	return b.stats, nil
+}
+
+func TestGraphQLAPI_Stats(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	backend := &statsBackend{stats: ports.Stats{
+		NumDocuments:      3,
+		StartedAt:         started,
+		Uptime:            90 * time.Second,
+		Indexes:           []ports.IndexStatus{{Name: "docs", NumDocuments: 3, SizeBytes: 2048}, {Name: "remote", SizeBytes: -1}},
+		Loaders:           []ports.LoaderStatus{{Name: "fs", Index: "docs", Interval: time.Minute, Runs: 2, LastRun: started, LastError: "disk on fire", Added: 3}},
+		FeatureExtractors: []ports.FeatureExtractorStatus{{Name: "tfidf", Loaders: []string{"fs"}}, {Name: "unused"}},
+		Memory:            ports.MemoryDiagnostics{SysBytes: 1 << 20},
+		Queries:           ports.QueryStats{Total: 4, Failed: 1, TotalLatency: 2 * time.Second},
+	}}
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+
+	query := `{"query":"{ stats { numDocuments startedAt uptimeSeconds indexes { name sizeBytes } loaders { name intervalSeconds lastRun lastError nextRun added } featureExtractors { name loaders } memory { sysBytes } queries { total failed averageLatencySeconds lastQuery } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			Stats StatsResult `json:"stats"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	stats := resp.Data.Stats
+	assert.Equal(t, "2024-05-01T12:00:00Z", *stats.StartedAt)
+	assert.Equal(t, 90.0, stats.UptimeSeconds)
+	assert.Equal(t, 2048, *stats.Indexes[0].SizeBytes)
+	assert.Nil(t, stats.Indexes[1].SizeBytes)
+	assert.Equal(t, 60.0, stats.Loaders[0].IntervalSeconds)
+	assert.Equal(t, "disk on fire", *stats.Loaders[0].LastError)
+	assert.Nil(t, stats.Loaders[0].NextRun)
+	assert.Equal(t, []string{"fs"}, stats.FeatureExtractors[0].Loaders)
+	assert.Equal(t, []string{}, stats.FeatureExtractors[1].Loaders)
+	assert.Equal(t, 1<<20, stats.Memory.SysBytes)
+	assert.Equal(t, 0.5, stats.Queries.AverageLatencySeconds)
+	assert.Nil(t, stats.Queries.LastQuery)
+}
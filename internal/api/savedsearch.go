@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// saveSearchRequest is the body of PUT /searches/{name}
+type saveSearchRequest struct {
+	Query  string            `json:"query"`
+	Sort   string            `json:"sort,omitempty"`
+	Fields []string          `json:"fields,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// searchTemplateRequest is the body of POST /search/template and /search/template/render: the saved
+// search rendered and the values of its parameters
+type searchTemplateRequest struct {
+	ID     string                 `json:"id"`
+	Params map[string]interface{} `json:"params,omitempty"`
+	Sort   string                 `json:"sort,omitempty"`
+	Fields []string               `json:"fields,omitempty"`
+}
+
+// renderResponse is the body returned by POST /search/template/render
+type renderResponse struct {
+	Query string `json:"query"`
+}
+
+// searchParameters are the query parameters of GET /searches/{name}/run that are not parameters of the
+// saved search
+var searchParameters = map[string]bool{"format": true, "columns": true, "sort": true, "fields": true}
+
+// savedSearches returns the saved searches of a backend (or API), or ones failing with ErrNotSupported
+func savedSearches(backend ports.EnginePort) ports.SavedSearchPort {
+	if searches, ok := backend.(ports.SavedSearchPort); ok {
+		return searches
+	}
+	return unsupportedSavedSearches{}
+}
+
+// unsupportedSavedSearches stands in for the saved searches of backends that have none
+type unsupportedSavedSearches struct{}
+
+func (unsupportedSavedSearches) err() error {
+	return fmt.Errorf("%w: saved searches", ports.ErrNotSupported)
+}
+
+func (u unsupportedSavedSearches) SaveSearch(ports.SavedSearch) (ports.SavedSearch, error) {
+	return ports.SavedSearch{}, u.err()
+}
+func (u unsupportedSavedSearches) GetSavedSearch(string, string) (ports.SavedSearch, error) {
+	return ports.SavedSearch{}, u.err()
+}
+func (u unsupportedSavedSearches) DeleteSavedSearch(string, string) error { return u.err() }
+func (u unsupportedSavedSearches) ListSavedSearches(string) ([]ports.SavedSearch, error) {
+	return nil, u.err()
+}
+func (u unsupportedSavedSearches) SavedSearchQuery(string, string, map[string]string) (ports.SearchQuery, error) {
+	return ports.SearchQuery{}, u.err()
+}
+
+// saveSearch saves a search in the namespace of the caller in ctx
+func saveSearch(ctx context.Context, backend ports.EnginePort, search ports.SavedSearch) (ports.SavedSearch, error) {
+	search.Namespace = namespaceOf(ctx)
+	return savedSearches(backend).SaveSearch(search)
+}
+
+// The APIs serve saved searches through backends that support them
+
+func (a *RESTAPI) SaveSearch(search ports.SavedSearch) (ports.SavedSearch, error) {
+	return savedSearches(a.backend).SaveSearch(search)
+}
+
+func (a *RESTAPI) GetSavedSearch(namespace, name string) (ports.SavedSearch, error) {
+	return savedSearches(a.backend).GetSavedSearch(namespace, name)
+}
+
+func (a *RESTAPI) DeleteSavedSearch(namespace, name string) error {
+	return savedSearches(a.backend).DeleteSavedSearch(namespace, name)
+}
+
+func (a *RESTAPI) ListSavedSearches(namespace string) ([]ports.SavedSearch, error) {
+	return savedSearches(a.backend).ListSavedSearches(namespace)
+}
+
+func (a *RESTAPI) SavedSearchQuery(namespace, name string, params map[string]string) (ports.SearchQuery, error) {
+	return savedSearches(a.backend).SavedSearchQuery(namespace, name, params)
+}
+
+func (g *GraphQLAPI) SaveSearch(search ports.SavedSearch) (ports.SavedSearch, error) {
+	return savedSearches(g.backend).SaveSearch(search)
+}
+
+func (g *GraphQLAPI) GetSavedSearch(namespace, name string) (ports.SavedSearch, error) {
+	return savedSearches(g.backend).GetSavedSearch(namespace, name)
+}
+
+func (g *GraphQLAPI) DeleteSavedSearch(namespace, name string) error {
+	return savedSearches(g.backend).DeleteSavedSearch(namespace, name)
+}
+
+func (g *GraphQLAPI) ListSavedSearches(namespace string) ([]ports.SavedSearch, error) {
+	return savedSearches(g.backend).ListSavedSearches(namespace)
+}
+
+func (g *GraphQLAPI) SavedSearchQuery(namespace, name string, params map[string]string) (ports.SearchQuery, error) {
+	return savedSearches(g.backend).SavedSearchQuery(namespace, name, params)
+}
+
+// handleSaveSearch creates or replaces the saved search named in the path with the JSON body
+func (a *RESTAPI) handleSaveSearch(w http.ResponseWriter, r *http.Request) {
+	var request saveSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return
+	}
+	search, err := saveSearch(r.Context(), a.backend, ports.SavedSearch{
+		Name:   r.PathValue("name"),
+		Query:  request.Query,
+		Sort:   request.Sort,
+		Fields: request.Fields,
+		Params: request.Params,
+	})
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, search)
+}
+
+func (a *RESTAPI) handleGetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, err := a.GetSavedSearch(namespaceOf(r.Context()), r.PathValue("name"))
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, search)
+}
+
+func (a *RESTAPI) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := a.DeleteSavedSearch(namespaceOf(r.Context()), name); err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"name": name, "status": "deleted"})
+}
+
+func (a *RESTAPI) handleListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	searches, err := a.ListSavedSearches(namespaceOf(r.Context()))
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, searches)
+}
+
+// handleRunSavedSearch runs a saved search like GET /search. The query parameters other than format,
+// columns, sort and fields fill in its placeholders; sort and fields replace its own.
+func (a *RESTAPI) handleRunSavedSearch(w http.ResponseWriter, r *http.Request) {
+	params := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		if !searchParameters[name] && len(values) > 0 {
+			params[name] = values[0]
+		}
+	}
+	search, err := a.SavedSearchQuery(namespaceOf(r.Context()), r.PathValue("name"), params)
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return
+	}
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		search.Sort = sort
+	}
+	if strings.TrimSpace(r.URL.Query().Get("fields")) != "" {
+		if search.Projection, err = searchProjection(r); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	a.serveSearch(w, r, search)
+}
+
+// handleSearchTemplate runs a saved search rendered with the parameters of the JSON body, so clients pass
+// values rather than query strings. The results are written like those of GET /search.
+func (a *RESTAPI) handleSearchTemplate(w http.ResponseWriter, r *http.Request) {
+	search, request, ok := a.renderTemplate(w, r)
+	if !ok {
+		return
+	}
+	if request.Sort != "" {
+		search.Sort = request.Sort
+	}
+	if len(request.Fields) > 0 {
+		projection, err := queryProjection(request.Fields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		search.Projection = projection
+	}
+	a.serveSearch(w, r, search)
+}
+
+// handleRenderTemplate returns the query a saved search renders to with the parameters of the JSON body
+func (a *RESTAPI) handleRenderTemplate(w http.ResponseWriter, r *http.Request) {
+	if search, _, ok := a.renderTemplate(w, r); ok {
+		writeJSON(w, http.StatusOK, renderResponse{Query: search.Query})
+	}
+}
+
+// renderTemplate renders the saved search of a template request, writing the error if it cannot
+func (a *RESTAPI) renderTemplate(w http.ResponseWriter, r *http.Request) (ports.SearchQuery, searchTemplateRequest, bool) {
+	var request searchTemplateRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, a.bodyStatus(err), err)
+		return ports.SearchQuery{}, request, false
+	}
+	if request.ID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing template id"))
+		return ports.SearchQuery{}, request, false
+	}
+	params, err := templateParams(request.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return ports.SearchQuery{}, request, false
+	}
+	search, err := a.SavedSearchQuery(namespaceOf(r.Context()), request.ID, params)
+	if err != nil {
+		writeError(w, adminStatus(err), err)
+		return ports.SearchQuery{}, request, false
+	}
+	return search, request, true
+}
+
+// savedSearchParams decodes the parameters of a GraphQL saved search run, a JSON object
+func savedSearchParams(params *string) (map[string]string, error) {
+	decoded, err := decodeConfig(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params: %w", errors.Unwrap(err))
+	}
+	return templateParams(decoded)
+}
+
+// templateParams converts the JSON values of template parameters, strings, numbers and booleans, to the
+// text they are rendered as
+func templateParams(params map[string]interface{}) (map[string]string, error) {
+	out := make(map[string]string, len(params))
+	for name, value := range params {
+		switch v := value.(type) {
+		case string:
+			out[name] = v
+		case json.Number:
+			out[name] = v.String()
+		case float64:
+			out[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			out[name] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("invalid params: %s must be a string, number or boolean", name)
+		}
+	}
+	return out, nil
+}
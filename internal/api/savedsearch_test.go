@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// savedSearchBackend keeps saved searches in memory, ignoring namespaces
+type savedSearchBackend struct {
+	memoryBackend
+	searches map[string]ports.SavedSearch
+}
+
+func (b *savedSearchBackend) SaveSearch(search ports.SavedSearch) (ports.SavedSearch, error) {
+	if b.searches == nil {
+		b.searches = make(map[string]ports.SavedSearch)
+	}
+	b.searches[search.Name] = search
+	return search, nil
+}
+
+func (b *savedSearchBackend) GetSavedSearch(namespace, name string) (ports.SavedSearch, error) {
+	search, ok := b.searches[name]
+	if !ok {
+		return ports.SavedSearch{}, fmt.Errorf("%w: saved search %s", ports.ErrNotFound, name)
+	}
+	return search, nil
+}
+
+func (b *savedSearchBackend) DeleteSavedSearch(namespace, name string) error {
+	if _, err := b.GetSavedSearch(namespace, name); err != nil {
+		return err
+	}
+	delete(b.searches, name)
+	return nil
+}
+
+func (b *savedSearchBackend) ListSavedSearches(namespace string) ([]ports.SavedSearch, error) {
+	searches := []ports.SavedSearch{}
+	for _, search := range b.searches {
+		searches = append(searches, search)
+	}
+	return searches, nil
+}
+
+func (b *savedSearchBackend) SavedSearchQuery(namespace, name string, params map[string]string) (ports.SearchQuery, error) {
+	search, err := b.GetSavedSearch(namespace, name)
+	if err != nil {
+		return ports.SearchQuery{}, err
+	}
+	query := search.Query
+	for param, value := range params {
+		query = strings.ReplaceAll(query, "{{"+param+"}}", value)
+	}
+	return ports.SearchQuery{Query: query, Sort: search.Sort}, nil
+}
+
+func TestRESTAPI_SavedSearches(t *testing.T) {
+	backend := &savedSearchBackend{memoryBackend: memoryBackend{docs: []models.Document{{ID: "1", Text: "TODO go"}, {ID: "2", Text: "TODO md"}}}}
+	handler := NewRESTAPI(backend, ":0").Handler()
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodPut, "/searches/my-todos", `{"query":"TODO {{ext}}","params":{"ext":"go"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "TODO {{ext}}", backend.searches["my-todos"].Query)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/searches/my-todos", `{`).Code)
+
+	rec = serve(http.MethodGet, "/searches/my-todos", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var search ports.SavedSearch
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &search))
+	assert.Equal(t, map[string]string{"ext": "go"}, search.Params)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/searches/unknown", "").Code)
+
+	rec = serve(http.MethodGet, "/searches", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var list []ports.SavedSearch
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+
+	// Query parameters other than format, columns, sort and fields fill in the placeholders
+	rec = serve(http.MethodGet, "/searches/my-todos/run?ext=md&format=json", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp searchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Results, 1) {
+		assert.Equal(t, "2", resp.Results[0].ID)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/searches/my-todos/run?ext=md&fields=-id", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/searches/unknown/run", "").Code)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/searches/my-todos", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/searches/my-todos", "").Code)
+
+	// Backends without saved searches
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/searches", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_SavedSearches(t *testing.T) {
+	backend := &savedSearchBackend{memoryBackend: memoryBackend{docs: []models.Document{{ID: "1", Text: "TODO go"}, {ID: "2", Text: "TODO md"}}}}
+	handler := NewGraphQLAPI(backend, ":0").Handler()
+	post := func(query string, out interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+
+	var saved struct {
+		Data struct {
+			SaveSearch SavedSearchResult `json:"saveSearch"`
+		} `json:"data"`
+	}
+	post(`mutation { saveSearch(search: {name: "my-todos", query: "TODO {{ext}}", params: "{\"ext\": \"go\"}"}) { search { name query params } error } }`, &saved)
+	assert.Nil(t, saved.Data.SaveSearch.Error)
+	if assert.NotNil(t, saved.Data.SaveSearch.Search) {
+		assert.Equal(t, `{"ext":"go"}`, derefString(saved.Data.SaveSearch.Search.Params))
+	}
+
+	var run struct {
+		Data struct {
+			RunSavedSearch SearchResult `json:"runSavedSearch"`
+		} `json:"data"`
+	}
+	post(`{ runSavedSearch(name: "my-todos", params: "{\"ext\": \"md\"}") { results { id } totalCount error } }`, &run)
+	assert.Nil(t, run.Data.RunSavedSearch.Error)
+	assert.Equal(t, 1, run.Data.RunSavedSearch.TotalCount)
+	post(`{ runSavedSearch(name: "my-todos", params: "[1]") { results { id } totalCount error } }`, &run)
+	assert.NotNil(t, run.Data.RunSavedSearch.Error)
+
+	var deleted struct {
+		Data struct {
+			DeleteSavedSearch CommandResult `json:"deleteSavedSearch"`
+		} `json:"data"`
+	}
+	post(`mutation { deleteSavedSearch(name: "my-todos") { error } }`, &deleted)
+	assert.Nil(t, deleted.Data.DeleteSavedSearch.Error)
+
+	var listed struct {
+		Data struct {
+			SavedSearches SavedSearchesResult `json:"savedSearches"`
+		} `json:"data"`
+	}
+	post(`{ savedSearches { searches { name } error } }`, &listed)
+	assert.Empty(t, listed.Data.SavedSearches.Searches)
+}
+
+func TestRESTAPI_SearchTemplate(t *testing.T) {
+	backend := &savedSearchBackend{memoryBackend: memoryBackend{docs: []models.Document{{ID: "1", Text: "size 1000"}, {ID: "2", Text: "size 20"}}}}
+	backend.SaveSearch(ports.SavedSearch{Name: "sized", Query: "size {{size}}"})
+	handler := NewRESTAPI(backend, ":0").Handler()
+	serve := func(target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	// Numbers are rendered as written
+	rec := serve("/search/template/render", `{"id":"sized","params":{"size":1000}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var rendered renderResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rendered))
+	assert.Equal(t, "size 1000", rendered.Query)
+
+	rec = serve("/search/template", `{"id":"sized","params":{"size":"20"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp searchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Results, 1) {
+		assert.Equal(t, "2", resp.Results[0].ID)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve("/search/template", `{"params":{"size":"20"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/search/template", `{"id":"sized","params":{"size":[20]}}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve("/search/template", `{"id":"unknown"}`).Code)
+}
@@ -7,36 +7,340 @@ package api
 import (
 	"context"
 	"fmt"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/ports"
 )
 
 // Start is the resolver for the start field.
 func (r *mutationResolver) Start(ctx context.Context) (*CommandResult, error) {
-	panic(fmt.Errorf("not implemented: Start - start"))
+	return commandResult(fmt.Errorf("start is not supported over the %s API", r.api.Name())), nil
 }
 
 // Stop is the resolver for the stop field.
 func (r *mutationResolver) Stop(ctx context.Context) (*CommandResult, error) {
-	panic(fmt.Errorf("not implemented: Stop - stop"))
+	return commandResult(fmt.Errorf("stop is not supported over the %s API", r.api.Name())), nil
 }
 
 // Index is the resolver for the index field.
 func (r *mutationResolver) Index(ctx context.Context, document DocumentInput) (*CommandResult, error) {
-	panic(fmt.Errorf("not implemented: Index - index"))
+	doc, err := fromDocumentInput(document)
+	if err != nil {
+		return commandResult(fmt.Errorf("invalid document meta: %w", err)), nil
+	}
+	return commandResult(indexDocument(ctx, r.api, doc)), nil
+}
+
+// Bulk is the resolver for the bulk field.
+func (r *mutationResolver) Bulk(ctx context.Context, items []*BulkItemInput) (*BulkResult, error) {
+	bulkItems := make([]ports.BulkItem, len(items))
+	for i, item := range items {
+		bulkItems[i] = ports.BulkItem{Action: ports.BulkAction(strings.ToLower(string(item.Action)))}
+		if item.Document != nil {
+			doc, err := fromDocumentInput(*item.Document)
+			if err != nil {
+				return &BulkResult{Items: []*BulkItemResult{}, Error: stringPtr(fmt.Sprintf("invalid meta of item %d: %s", i, err))}, nil
+			}
+			bulkItems[i].Document = doc
+		}
+		if bulkItems[i].Document.ID == "" {
+			bulkItems[i].Document.ID = derefString(item.ID)
+		}
+	}
+	bulker, ok := r.api.(ports.BulkPort)
+	if !ok {
+		return &BulkResult{Items: []*BulkItemResult{}, Error: stringPtr(fmt.Sprintf("bulk requests are not supported over the %s API", r.api.Name()))}, nil
+	}
+	results, err := bulker.Bulk(ctx, bulkItems)
+	if err != nil {
+		return &BulkResult{Items: []*BulkItemResult{}, Error: stringPtr(err.Error())}, nil
+	}
+	out := &BulkResult{Errors: results.Failed() > 0, Items: make([]*BulkItemResult, len(results.Items))}
+	for i, item := range results.Items {
+		out.Items[i] = &BulkItemResult{Action: BulkAction(strings.ToUpper(string(item.Action))), ID: stringPtr(item.ID)}
+		if item.Err != nil {
+			out.Items[i].Error = stringPtr(item.Err.Error())
+		}
+	}
+	return out, nil
+}
+
+// CreateIndex is the resolver for the createIndex field.
+func (r *mutationResolver) CreateIndex(ctx context.Context, name string, typeArg string, config *string) (*CommandResult, error) {
+	indexConfig, err := decodeConfig(config)
+	if err != nil {
+		return commandResult(err), nil
+	}
+	return commandResult(indexAdmin(r.api).CreateIndex(ports.IndexSpec{Name: name, Type: typeArg, Config: indexConfig})), nil
+}
+
+// DropIndex is the resolver for the dropIndex field.
+func (r *mutationResolver) DropIndex(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).DropIndex(name)), nil
+}
+
+// ConfigureIndex is the resolver for the configureIndex field.
+func (r *mutationResolver) ConfigureIndex(ctx context.Context, name string, config string) (*CommandResult, error) {
+	indexConfig, err := decodeConfig(&config)
+	if err != nil {
+		return commandResult(err), nil
+	}
+	return commandResult(indexAdmin(r.api).ConfigureIndex(name, indexConfig)), nil
+}
+
+// FlushIndex is the resolver for the flushIndex field.
+func (r *mutationResolver) FlushIndex(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).FlushIndex(name)), nil
+}
+
+// OptimizeIndex is the resolver for the optimizeIndex field.
+func (r *mutationResolver) OptimizeIndex(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).OptimizeIndex(name)), nil
+}
+
+// ClearIndex is the resolver for the clearIndex field.
+func (r *mutationResolver) ClearIndex(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).ClearIndex(name)), nil
+}
+
+// VerifyIndex is the resolver for the verifyIndex field.
+func (r *mutationResolver) VerifyIndex(ctx context.Context, name string, repair *string) (*VerifyResult, error) {
+	report, err := indexAdmin(r.api).VerifyIndex(name, derefString(repair))
+	if err != nil {
+		return &VerifyResult{MissingOnDisk: []string{}, MissingInMemory: []string{}, Mismatched: []string{}, Error: stringPtr(err.Error())}, nil
+	}
+	verified := newVerifyResponse(name, report)
+	return &VerifyResult{Index: stringPtr(name), Consistent: verified.Consistent, Documents: verified.Documents, Stored: verified.Stored,
+		MissingOnDisk: verified.MissingOnDisk, MissingInMemory: verified.MissingInMemory, Mismatched: verified.Mismatched, Repaired: verified.Repaired}, nil
+}
+
+// SnapshotIndex is the resolver for the snapshotIndex field.
+func (r *mutationResolver) SnapshotIndex(ctx context.Context, name string) (*SnapshotResult, error) {
+	snapshot, err := indexAdmin(r.api).SnapshotIndex(name)
+	if err != nil {
+		return &SnapshotResult{Error: stringPtr(err.Error())}, nil
+	}
+	bytes := int(snapshot.Bytes)
+	return &SnapshotResult{Index: stringPtr(snapshot.Index), Path: stringPtr(snapshot.Path), Time: timePtr(snapshot.Time), Bytes: &bytes}, nil
+}
+
+// SetAlias is the resolver for the setAlias field.
+func (r *mutationResolver) SetAlias(ctx context.Context, alias string, index string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).SetAlias(alias, index)), nil
+}
+
+// RemoveAlias is the resolver for the removeAlias field.
+func (r *mutationResolver) RemoveAlias(ctx context.Context, alias string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).RemoveAlias(alias)), nil
+}
+
+// RunLoader is the resolver for the runLoader field.
+func (r *mutationResolver) RunLoader(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(indexAdmin(r.api).TriggerLoader(ctx, name)), nil
+}
+
+// RegisterQuery is the resolver for the registerQuery field.
+func (r *mutationResolver) RegisterQuery(ctx context.Context, query string, id *string) (*StoredQueryResult, error) {
+	stored, err := registerQuery(ctx, r.api, derefString(id), query)
+	if err != nil {
+		return &StoredQueryResult{Error: stringPtr(err.Error())}, nil
+	}
+	return &StoredQueryResult{Query: toGraphQLStoredQuery(stored)}, nil
+}
+
+// DeleteQuery is the resolver for the deleteQuery field.
+func (r *mutationResolver) DeleteQuery(ctx context.Context, id string) (*CommandResult, error) {
+	return commandResult(percolator(r.api).DeleteQuery(namespaceOf(ctx), id)), nil
+}
+
+// SaveSearch is the resolver for the saveSearch field.
+func (r *mutationResolver) SaveSearch(ctx context.Context, search SavedSearchInput) (*SavedSearchResult, error) {
+	params, err := savedSearchParams(search.Params)
+	if err != nil {
+		return &SavedSearchResult{Error: stringPtr(err.Error())}, nil
+	}
+	saved, err := saveSearch(ctx, r.api, ports.SavedSearch{Name: search.Name, Query: search.Query, Sort: derefString(search.Sort), Fields: search.Fields, Params: params})
+	if err != nil {
+		return &SavedSearchResult{Error: stringPtr(err.Error())}, nil
+	}
+	return &SavedSearchResult{Search: toGraphQLSavedSearch(saved)}, nil
+}
+
+// DeleteSavedSearch is the resolver for the deleteSavedSearch field.
+func (r *mutationResolver) DeleteSavedSearch(ctx context.Context, name string) (*CommandResult, error) {
+	return commandResult(savedSearches(r.api).DeleteSavedSearch(namespaceOf(ctx), name)), nil
 }
 
 // Ping is the resolver for the ping field.
 func (r *queryResolver) Ping(ctx context.Context) (*PingResult, error) {
-	panic(fmt.Errorf("not implemented: Ping - ping"))
+	return &PingResult{Pong: "pong"}, nil
 }
 
 // Stats is the resolver for the stats field.
 func (r *queryResolver) Stats(ctx context.Context) (*StatsResult, error) {
-	panic(fmt.Errorf("not implemented: Stats - stats"))
+	stats, err := engineStats(ctx, r.api)
+	if err != nil {
+		return nil, err
+	}
+	return toStatsResult(stats), nil
 }
 
 // Search is the resolver for the search field.
 func (r *queryResolver) Search(ctx context.Context, query QueryInput) (*SearchResult, error) {
-	panic(fmt.Errorf("not implemented: Search - search"))
+	projection, err := queryProjection(query.Fields)
+	if err != nil {
+		return &SearchResult{Results: []*Document{}, Error: stringPtr(err.Error())}, nil
+	}
+	return graphQLSearch(ctx, r.api, ports.SearchQuery{Query: query.Query, Sort: derefString(query.Sort), Projection: projection, Language: derefString(query.Language)}), nil
+}
+
+// ValidateQuery is the resolver for the validateQuery field.
+func (r *queryResolver) ValidateQuery(ctx context.Context, query string) (*QueryValidationResult, error) {
+	validation, err := queryValidator(r.api).ValidateQuery(ports.SearchQuery{Query: query, Namespace: namespaceOf(ctx)})
+	if err != nil {
+		return &QueryValidationResult{Errors: []*QueryError{}, Error: stringPtr(err.Error())}, nil
+	}
+	return toQueryValidationResult(validation), nil
+}
+
+// Document is the resolver for the document field.
+func (r *queryResolver) Document(ctx context.Context, id string, fields []string) (*DocumentResult, error) {
+	projection, err := queryProjection(fields)
+	if err != nil {
+		return &DocumentResult{Error: stringPtr(err.Error())}, nil
+	}
+	docs, err := documentGetter(r.api).GetDocuments(ctx, documentsQuery(ctx, projection), []string{id})
+	if err != nil {
+		return &DocumentResult{Error: stringPtr(err.Error())}, nil
+	}
+	if len(docs) == 0 {
+		return &DocumentResult{}, nil
+	}
+	return &DocumentResult{Document: toGraphQLDocument(docs[0])}, nil
+}
+
+// Documents is the resolver for the documents field.
+func (r *queryResolver) Documents(ctx context.Context, ids []string, fields []string) (*DocumentsResult, error) {
+	projection, err := queryProjection(fields)
+	if err != nil {
+		return &DocumentsResult{Documents: []*Document{}, Error: stringPtr(err.Error())}, nil
+	}
+	docs, err := documentGetter(r.api).GetDocuments(ctx, documentsQuery(ctx, projection), ids)
+	if err != nil {
+		return &DocumentsResult{Documents: []*Document{}, Error: stringPtr(err.Error())}, nil
+	}
+	out := make([]*Document, len(docs))
+	for i, doc := range docs {
+		out[i] = toGraphQLDocument(doc)
+	}
+	return &DocumentsResult{Documents: out}, nil
+}
+
+// ExplainQuery is the resolver for the explainQuery field.
+func (r *queryResolver) ExplainQuery(ctx context.Context, query string) (*QueryPlanResult, error) {
+	plan, err := queryExplainer(r.api).ExplainQuery(ports.SearchQuery{Query: query, Namespace: namespaceOf(ctx)})
+	if err != nil {
+		return &QueryPlanResult{Steps: []*PlanStep{}, Error: stringPtr(err.Error())}, nil
+	}
+	return toQueryPlanResult(plan), nil
+}
+
+// StoredQueries is the resolver for the storedQueries field.
+func (r *queryResolver) StoredQueries(ctx context.Context) (*StoredQueriesResult, error) {
+	queries, err := percolator(r.api).ListQueries(namespaceOf(ctx))
+	if err != nil {
+		return &StoredQueriesResult{Queries: []*StoredQuery{}, Error: stringPtr(err.Error())}, nil
+	}
+	return &StoredQueriesResult{Queries: toGraphQLStoredQueries(queries)}, nil
+}
+
+// Percolate is the resolver for the percolate field.
+func (r *queryResolver) Percolate(ctx context.Context, document DocumentInput) (*PercolateResult, error) {
+	doc, err := fromDocumentInput(document)
+	if err != nil {
+		return &PercolateResult{Matches: []*StoredQuery{}, Error: stringPtr(fmt.Sprintf("invalid document meta: %s", err))}, nil
+	}
+	matches, err := percolator(r.api).Percolate(namespaceOf(ctx), doc)
+	if err != nil {
+		return &PercolateResult{Matches: []*StoredQuery{}, Error: stringPtr(err.Error())}, nil
+	}
+	return &PercolateResult{Matches: toGraphQLStoredQueries(matches)}, nil
+}
+
+// SavedSearches is the resolver for the savedSearches field.
+func (r *queryResolver) SavedSearches(ctx context.Context) (*SavedSearchesResult, error) {
+	searches, err := savedSearches(r.api).ListSavedSearches(namespaceOf(ctx))
+	if err != nil {
+		return &SavedSearchesResult{Searches: []*SavedSearch{}, Error: stringPtr(err.Error())}, nil
+	}
+	return &SavedSearchesResult{Searches: toGraphQLSavedSearches(searches)}, nil
+}
+
+// RunSavedSearch is the resolver for the runSavedSearch field.
+func (r *queryResolver) RunSavedSearch(ctx context.Context, name string, params *string) (*SearchResult, error) {
+	values, err := savedSearchParams(params)
+	if err != nil {
+		return &SearchResult{Results: []*Document{}, Error: stringPtr(err.Error())}, nil
+	}
+	search, err := savedSearches(r.api).SavedSearchQuery(namespaceOf(ctx), name, values)
+	if err != nil {
+		return &SearchResult{Results: []*Document{}, Error: stringPtr(err.Error())}, nil
+	}
+	return graphQLSearch(ctx, r.api, search), nil
+}
+
+// Dimensions is the resolver for the dimensions field.
+func (r *queryResolver) Dimensions(ctx context.Context) (*DimensionsResult, error) {
+	list, err := dimensions(r.api).ListDimensions(ctx, dimensionQuery(ctx))
+	if err != nil {
+		return &DimensionsResult{Dimensions: []*Dimension{}, Error: stringPtr(err.Error())}, nil
+	}
+	return &DimensionsResult{Dimensions: toGraphQLDimensions(list)}, nil
+}
+
+// TopValues is the resolver for the topValues field.
+func (r *queryResolver) TopValues(ctx context.Context, dimension string, limit *int) (*TopValuesResult, error) {
+	n := defaultTopValues
+	if limit != nil {
+		n = *limit
+	}
+	if n < 0 {
+		return &TopValuesResult{Dimension: dimension, Values: []*ValueCount{}, Error: stringPtr("limit must not be negative")}, nil
+	}
+	values, err := dimensions(r.api).TopValues(ctx, dimensionQuery(ctx), dimension, n)
+	if err != nil {
+		return &TopValuesResult{Dimension: dimension, Values: []*ValueCount{}, Error: stringPtr(err.Error())}, nil
+	}
+	return &TopValuesResult{Dimension: dimension, Values: toGraphQLValueCounts(values)}, nil
+}
+
+// Search is the resolver for the search field.
+func (r *subscriptionResolver) Search(ctx context.Context, query QueryInput) (<-chan *SearchMatch, error) {
+	subscriber, ok := r.api.(ports.SubscriptionPort)
+	if !ok {
+		return nil, fmt.Errorf("search subscriptions are not supported over the %s API", r.api.Name())
+	}
+	projection, err := queryProjection(query.Fields)
+	if err != nil {
+		return nil, err
+	}
+	search := ports.SearchQuery{Query: query.Query, Caller: strings.TrimSpace(r.api.Name() + " " + remoteAddr(ctx)), Filter: documentFilter(ctx), Namespace: namespaceOf(ctx), Principals: documentPrincipals(ctx), Projection: projection, Language: derefString(query.Language)}
+	matches, err := subscriber.SubscribeSearch(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *SearchMatch)
+	go func() {
+		defer close(out)
+		for match := range matches {
+			select {
+			case out <- &SearchMatch{Index: match.Index, Document: toGraphQLDocument(match.Document)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
 }
 
 // Mutation returns MutationResolver implementation.
@@ -45,5 +349,9 @@ func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
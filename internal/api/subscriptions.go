@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// subscriptionKeepAlive is how often idle event streams send a comment, so proxies keep them open
+const subscriptionKeepAlive = 15 * time.Second
+
+// matchEvent is the data of a "match" event of GET /search/subscribe
+type matchEvent struct {
+	Index    string          `json:"index"`
+	Document models.Document `json:"document"`
+	Time     time.Time       `json:"time"`
+}
+
+// subscribeSearch subscribes to a query through backends that support it
+func subscribeSearch(ctx context.Context, backend ports.EnginePort, query ports.SearchQuery) (<-chan ports.SearchMatch, error) {
+	subscriber, ok := backend.(ports.SubscriptionPort)
+	if !ok {
+		return nil, fmt.Errorf("%w: search subscriptions", ports.ErrNotSupported)
+	}
+	return subscriber.SubscribeSearch(ctx, query)
+}
+
+// handleSubscribe streams the documents matching q as they are indexed, as Server-Sent Events named
+// "match". The stream ends when the client disconnects or the API stops.
+func (a *RESTAPI) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+	projection, err := searchProjection(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// Open streams would otherwise hold up a graceful shutdown
+	defer context.AfterFunc(a.streamContext(), cancel)()
+
+	matches, err := a.SubscribeSearch(ctx, ports.SearchQuery{Query: query, Caller: a.Name() + " " + r.RemoteAddr, Filter: documentFilter(r.Context()), Namespace: namespaceOf(r.Context()), Principals: documentPrincipals(r.Context()), Projection: projection})
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	stream := http.NewResponseController(w)
+	if err := stream.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(subscriptionKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case match, ok := <-matches:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(matchEvent{Index: match.Index, Document: match.Document, Time: match.Time})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: match\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			return
+		}
+	}
+}
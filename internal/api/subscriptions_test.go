@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// subscriptionBackend pushes the documents indexed after a subscription that contain its query
+type subscriptionBackend struct {
+	memoryBackend
+	subscribed chan ports.SearchQuery
+	matches    chan ports.SearchMatch
+}
+
+func newSubscriptionBackend() *subscriptionBackend {
+	return &subscriptionBackend{subscribed: make(chan ports.SearchQuery, 1), matches: make(chan ports.SearchMatch, 1)}
+}
+
+func (b *subscriptionBackend) SubscribeSearch(ctx context.Context, query ports.SearchQuery) (<-chan ports.SearchMatch, error) {
+	b.subscribed <- query
+	return b.matches, nil
+}
+
+func TestRESTAPI_SubscribeSearch(t *testing.T) {
+	backend := newSubscriptionBackend()
+	server := httptest.NewServer(NewRESTAPI(backend, ":0").Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/search/subscribe?q=hello")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "hello", (<-backend.subscribed).Query)
+
+	backend.matches <- ports.SearchMatch{Index: "docs", Document: models.Document{ID: "1", Text: "hello world"}}
+	reader := bufio.NewReader(resp.Body)
+	event, _ := reader.ReadString('\n')
+	data, _ := reader.ReadString('\n')
+	assert.Equal(t, "event: match\n", event)
+	var match matchEvent
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &match))
+	assert.Equal(t, "docs", match.Index)
+	assert.Equal(t, "1", match.Document.ID)
+
+	// Backends without subscriptions
+	rec := httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/subscribe?q=hello", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_SubscribeSearch(t *testing.T) {
+	backend := newSubscriptionBackend()
+	graphql := NewGraphQLAPI(backend, ":0")
+	graphql.SetAuthenticator(newTestAuthenticator(t, nil))
+	c := client.New(graphql.Handler(), client.Path("/query"))
+
+	// Browsers authenticate with the connection_init payload
+	sub := c.WebsocketWithPayload(`subscription { search(query: {query: "hello"}) { index document { id } } }`,
+		map[string]any{"Authorization": "Bearer read-key"})
+	defer sub.Close()
+	select {
+	case query := <-backend.subscribed:
+		assert.Equal(t, "hello", query.Query)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no subscription")
+	}
+
+	backend.matches <- ports.SearchMatch{Index: "docs", Document: models.Document{ID: "1"}}
+	var resp struct {
+		Search struct {
+			Index    string
+			Document struct{ ID string }
+		}
+	}
+	assert.NoError(t, sub.Next(&resp))
+	assert.Equal(t, "docs", resp.Search.Index)
+	assert.Equal(t, "1", resp.Search.Document.ID)
+
+	// Without credentials the search scope is refused
+	unauthenticated := c.Websocket(`subscription { search(query: {query: "hello"}) { index } }`)
+	defer unauthenticated.Close()
+	assert.ErrorContains(t, unauthenticated.Next(&resp), "unauthenticated")
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// queryValidator returns the query validation of a backend (or API), or one failing with ErrNotSupported
+func queryValidator(backend ports.EnginePort) ports.QueryValidatorPort {
+	if validator, ok := backend.(ports.QueryValidatorPort); ok {
+		return validator
+	}
+	return unsupportedQueryValidator{}
+}
+
+// unsupportedQueryValidator stands in for the query validation of backends that have none
+type unsupportedQueryValidator struct{}
+
+func (unsupportedQueryValidator) ValidateQuery(ports.SearchQuery) (ports.QueryValidation, error) {
+	return ports.QueryValidation{}, fmt.Errorf("%w: query validation", ports.ErrNotSupported)
+}
+
+// The APIs validate queries through backends that support it
+
+func (a *RESTAPI) ValidateQuery(query ports.SearchQuery) (ports.QueryValidation, error) {
+	return queryValidator(a.backend).ValidateQuery(query)
+}
+
+func (g *GraphQLAPI) ValidateQuery(query ports.SearchQuery) (ports.QueryValidation, error) {
+	return queryValidator(g.backend).ValidateQuery(query)
+}
+
+// handleValidate checks the query q for the caller's namespace without running it. Invalid queries are
+// answered with 200 and the problems found, so user interfaces can show them inline.
+func (a *RESTAPI) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.Query().Has("q") {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+	validation, err := a.ValidateQuery(ports.SearchQuery{Query: r.URL.Query().Get("q"), Namespace: namespaceOf(r.Context())})
+	if err != nil {
+		writeError(w, namespaceStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, validation)
+}
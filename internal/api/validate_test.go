@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// validatingBackend reports queries with "=<" as having a bad operator there
+type validatingBackend struct {
+	memoryBackend
+}
+
+func (b *validatingBackend) ValidateQuery(query ports.SearchQuery) (ports.QueryValidation, error) {
+	validation := ports.QueryValidation{Valid: true, Errors: []ports.QueryError{}}
+	if offset := strings.Index(query.Query, "=<"); offset >= 0 {
+		validation.Valid = false
+		validation.Errors = append(validation.Errors, ports.QueryError{Offset: offset, Length: 2, Code: "bad_operator", Message: `unknown operator "=<"`, Suggestion: "<="})
+	}
+	return validation, nil
+}
+
+// Search warns about the unknown dimensions of searches matching nothing
+func (b *validatingBackend) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	results, err := b.memoryBackend.Search(query)
+	if len(results.Documents) == 0 && strings.HasPrefix(query.Query, "author=") {
+		results.Warnings = []ports.QueryError{{Offset: 0, Length: 6, Code: "unknown_dimension", Message: `no document has the dimension "author"`}}
+	}
+	return results, err
+}
+
+func TestRESTAPI_SearchWarnings(t *testing.T) {
+	handler := NewRESTAPI(&validatingBackend{}, ":0").Handler()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape("author=alice"), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response struct {
+		Warnings []ports.QueryError `json:"warnings"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []ports.QueryError{{Offset: 0, Length: 6, Code: "unknown_dimension", Message: `no document has the dimension "author"`}}, response.Warnings)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=alice", nil))
+	assert.NotContains(t, rec.Body.String(), "warnings")
+}
+
+func TestRESTAPI_Validate(t *testing.T) {
+	handler := NewRESTAPI(&validatingBackend{}, ":0").Handler()
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/validate?q=" + url.QueryEscape("fileSize=<10"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var validation ports.QueryValidation
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &validation))
+	assert.False(t, validation.Valid)
+	assert.Equal(t, []ports.QueryError{{Offset: 8, Length: 2, Code: "bad_operator", Message: `unknown operator "=<"`, Suggestion: "<="}}, validation.Errors)
+
+	rec = get("/validate?q=" + url.QueryEscape("fileExtension=go"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"valid":true,"errors":[]}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get("/validate").Code)
+
+	// Backends without query validation
+	rec = httptest.NewRecorder()
+	NewRESTAPI(&memoryBackend{}, ":0").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/validate?q=x", nil))
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestGraphQLAPI_ValidateQuery(t *testing.T) {
+	handler := NewGraphQLAPI(&validatingBackend{}, ":0").Handler()
+	body, _ := json.Marshal(map[string]string{"query": `{ validateQuery(query: "fileSize=<10") { valid errors { offset length code message suggestion } error } }`})
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			ValidateQuery QueryValidationResult `json:"validateQuery"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.False(t, resp.Data.ValidateQuery.Valid)
+	if assert.Len(t, resp.Data.ValidateQuery.Errors, 1) {
+		assert.Equal(t, 8, resp.Data.ValidateQuery.Errors[0].Offset)
+		assert.Equal(t, "<=", derefString(resp.Data.ValidateQuery.Errors[0].Suggestion))
+	}
+}
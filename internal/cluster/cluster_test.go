@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// shardBackend answers every shard search with its documents, or blocks until cancelled for "slow"
+type shardBackend struct {
+	docs []models.Document
+}
+
+func (b *shardBackend) SearchShard(ctx context.Context, query ports.ShardQuery) (ports.ShardResults, error) {
+	if query.Query == "slow" {
+		<-ctx.Done()
+		return ports.ShardResults{}, ctx.Err()
+	}
+	return ports.ShardResults{Node: "b", Documents: b.docs, Stats: ports.TermStats{
+		Terms: []string{query.Query}, Documents: 10, DocFreq: map[string]int{query.Query: 1},
+		TermFreq: map[string]map[string]int{"1": {query.Query: 2}},
+	}}, nil
+}
+
+// serve serves backend and writes (if any) on a local port until the test ends and returns its address
+func serve(t *testing.T, backend ports.ShardSearchPort, writes ports.ShardWritePort, secret string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(backend, writes, secret)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestTransport_SearchShard(t *testing.T) {
+	address := serve(t, &shardBackend{docs: []models.Document{{ID: "1", Text: "hello", Meta: map[string]string{"lang": "en"}}}}, nil, "s3cret")
+	transport := NewTransport("s3cret")
+	defer transport.Close()
+
+	results, err := transport.SearchShard(context.Background(), address, ports.ShardQuery{Query: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", results.Node)
+	assert.Equal(t, []models.Document{{ID: "1", Text: "hello", Meta: map[string]string{"lang": "en"}}}, results.Documents)
+	assert.Equal(t, 2, results.Stats.TermFreq["1"]["hello"])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = transport.SearchShard(ctx, address, ports.ShardQuery{Query: "slow"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTransport_WriteShardAndRebalance(t *testing.T) {
+	local := newMemIndex()
+	router, _ := NewRouter("b", 4, nil)
+	sharded := NewShardedIndex(local, router, NewManager(), nil)
+	address := serve(t, &shardBackend{}, sharded, "")
+	unsharded := serve(t, &shardBackend{}, nil, "")
+	transport := NewTransport("")
+	defer transport.Close()
+
+	write := ports.ShardWrite{Add: []models.Document{{ID: "1"}, {ID: "2"}}, Delete: []string{"2"}}
+	assert.NoError(t, transport.WriteShard(context.Background(), address, write))
+	assert.Equal(t, map[string]models.Document{"1": {ID: "1"}}, local.docs)
+	moved, err := transport.Rebalance(context.Background(), address)
+	assert.NoError(t, err)
+	assert.Zero(t, moved)
+
+	// Unimplemented statuses come back as ErrNotSupported
+	assert.ErrorIs(t, transport.WriteShard(context.Background(), unsharded, write), ports.ErrNotSupported)
+	_, err = transport.Rebalance(context.Background(), unsharded)
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+}
+
+func TestTransport_RequiresSecret(t *testing.T) {
+	address := serve(t, &shardBackend{}, nil, "s3cret")
+	for _, secret := range []string{"", "wrong"} {
+		transport := NewTransport(secret)
+		_, err := transport.SearchShard(context.Background(), address, ports.ShardQuery{Query: "hello"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		transport.Close()
+	}
+}
+
+func TestManager(t *testing.T) {
+	manager := NewManager()
+	assert.NoError(t, manager.RegisterNode("b", "10.0.0.2:7070"))
+	assert.NoError(t, manager.RegisterNode("c", "10.0.0.3:7070"))
+	assert.NoError(t, manager.RegisterNode("b", "10.0.0.4:7070"))
+	assert.ErrorIs(t, manager.RegisterNode("d", ""), ports.ErrInvalid)
+
+	nodes, _ := manager.ListNodes()
+	assert.Equal(t, []string{"b", "c"}, nodes)
+	address, err := manager.NodeAddress("b")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.4:7070", address)
+
+	assert.NoError(t, manager.DeregisterNode("b"))
+	assert.ErrorIs(t, manager.DeregisterNode("b"), ports.ErrNotFound)
+	_, err = manager.NodeAddress("b")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+	nodes, _ = manager.ListNodes()
+	assert.Equal(t, []string{"c"}, nodes)
+}
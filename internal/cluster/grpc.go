@@ -0,0 +1,296 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/**
+ * gRPC transport between the nodes of a cluster: shard searches, shard writes and rebalancing. Messages
+ * are the ports' shard types encoded as JSON, so the service needs no generated code.
+ **/
+
+const serviceName = "bitscout.Cluster"
+
+// jsonCodec encodes gRPC messages as JSON (content type application/grpc+json)
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// rebalanceRequest and rebalanceResult are the messages of the Rebalance method
+type rebalanceRequest struct{}
+type rebalanceResult struct {
+	Moved int
+}
+
+// metadataResult is the message returned by the ApplyMetadata method: the index of the change in the log
+type metadataResult struct {
+	Index uint64
+}
+
+// serviceDesc describes the cluster service to gRPC, in place of generated code
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("SearchShard", func(s *Server, ctx context.Context, query *ports.ShardQuery) (*ports.ShardResults, error) {
+			results, err := s.search.SearchShard(ctx, *query)
+			return &results, err
+		}),
+		unary("WriteShard", func(s *Server, ctx context.Context, write *ports.ShardWrite) (*struct{}, error) {
+			if s.writes == nil {
+				return nil, fmt.Errorf("node is not sharded: writes %w", ports.ErrNotSupported)
+			}
+			return &struct{}{}, s.writes.WriteShard(ctx, *write)
+		}),
+		unary("Rebalance", func(s *Server, ctx context.Context, _ *rebalanceRequest) (*rebalanceResult, error) {
+			if s.writes == nil {
+				return nil, fmt.Errorf("node is not sharded: rebalancing %w", ports.ErrNotSupported)
+			}
+			moved, err := s.writes.Rebalance(ctx)
+			return &rebalanceResult{Moved: moved}, err
+		}),
+		unary("ApplyMetadata", func(s *Server, ctx context.Context, change *ports.MetadataChange) (*metadataResult, error) {
+			if s.metadata == nil {
+				return nil, fmt.Errorf("node has no raft metadata: changes %w", ports.ErrNotSupported)
+			}
+			index, err := s.metadata.ApplyMetadata(ctx, *change)
+			return &metadataResult{Index: index}, err
+		}),
+	},
+}
+
+// unary adapts a method of the cluster service to gRPC, converting its errors to gRPC statuses
+func unary[Req, Resp any](name string, method func(s *Server, ctx context.Context, req *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				resp, err := method(srv.(*Server), ctx, req.(*Req))
+				if err != nil {
+					return nil, statusError(err)
+				}
+				return resp, nil
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodName(name)}, handler)
+		},
+	}
+}
+
+// methodName returns the full gRPC name of a method of the cluster service
+func methodName(name string) string {
+	return "/" + serviceName + "/" + name
+}
+
+// statusError converts an error to the gRPC status of its kind
+func statusError(err error) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, ports.ErrNotSupported):
+		code = codes.Unimplemented
+	case errors.Is(err, ports.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ports.ErrConflict):
+		code = codes.AlreadyExists
+	case errors.Is(err, ports.ErrInvalid):
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
+
+// portsError converts the gRPC status of a failed call back to an error of its kind
+func portsError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	var kind error
+	switch st.Code() {
+	case codes.Unimplemented:
+		kind = ports.ErrNotSupported
+	case codes.NotFound:
+		kind = ports.ErrNotFound
+	case codes.AlreadyExists:
+		kind = ports.ErrConflict
+	case codes.InvalidArgument:
+		kind = ports.ErrInvalid
+	default:
+		return err
+	}
+	return remoteError{kind: kind, message: st.Message()}
+}
+
+// remoteError is an error of a peer node, of the kind of a ports sentinel error
+type remoteError struct {
+	kind    error
+	message string
+}
+
+func (e remoteError) Error() string { return e.message }
+func (e remoteError) Unwrap() error { return e.kind }
+
+// Server serves the shard searches and writes of peer nodes over gRPC. With a secret, peers must send it
+// as a bearer token.
+type Server struct {
+	search   ports.ShardSearchPort
+	writes   ports.ShardWritePort
+	metadata *MetadataStore
+	server   *grpc.Server
+}
+
+// NewServer creates a Server evaluating shard searches with search and applying shard writes with writes
+// (nil: the node is not sharded)
+func NewServer(search ports.ShardSearchPort, writes ports.ShardWritePort, secret string) *Server {
+	var options []grpc.ServerOption
+	if secret != "" {
+		options = append(options, grpc.UnaryInterceptor(requireSecret(secret)))
+	}
+	s := &Server{search: search, writes: writes, server: grpc.NewServer(options...)}
+	s.server.RegisterService(&serviceDesc, s)
+	return s
+}
+
+// SetMetadata applies the metadata changes forwarded by followers to store (nil: none). Call it before Serve.
+func (s *Server) SetMetadata(store *MetadataStore) {
+	s.metadata = store
+}
+
+// Serve accepts peer connections on listener until Stop
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Stop waits for the requests in progress and stops serving
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// requireSecret rejects requests without the shared secret as bearer token
+func requireSecret(secret string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			token, ok := strings.CutPrefix(value, "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid cluster secret")
+	}
+}
+
+// Transport sends shard searches to peer nodes over gRPC, keeping a connection per node
+type Transport struct {
+	secret string
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+}
+
+// NewTransport creates a Transport sending secret (if any) as bearer token
+func NewTransport(secret string) *Transport {
+	return &Transport{secret: secret, conns: make(map[string]*grpc.ClientConn)}
+}
+
+// SearchShard evaluates a query on the node at address
+func (t *Transport) SearchShard(ctx context.Context, address string, query ports.ShardQuery) (ports.ShardResults, error) {
+	var results ports.ShardResults
+	if err := t.invoke(ctx, address, "SearchShard", &query, &results); err != nil {
+		return ports.ShardResults{}, err
+	}
+	return results, nil
+}
+
+// WriteShard applies a write to the shards of the node at address
+func (t *Transport) WriteShard(ctx context.Context, address string, write ports.ShardWrite) error {
+	return t.invoke(ctx, address, "WriteShard", &write, &struct{}{})
+}
+
+// Rebalance has the node at address move the documents of the shards it no longer holds
+func (t *Transport) Rebalance(ctx context.Context, address string) (int, error) {
+	var result rebalanceResult
+	err := t.invoke(ctx, address, "Rebalance", &rebalanceRequest{}, &result)
+	return result.Moved, err
+}
+
+// ApplyMetadata forwards a metadata change to the raft leader at address, returning its index in the log
+func (t *Transport) ApplyMetadata(ctx context.Context, address string, change ports.MetadataChange) (uint64, error) {
+	var result metadataResult
+	err := t.invoke(ctx, address, "ApplyMetadata", &change, &result)
+	return result.Index, err
+}
+
+// invoke calls a method of the cluster service on the node at address
+func (t *Transport) invoke(ctx context.Context, address, method string, req, resp any) error {
+	conn, err := t.conn(address)
+	if err != nil {
+		return err
+	}
+	if t.secret != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t.secret)
+	}
+	if err := conn.Invoke(ctx, methodName(method), req, resp, grpc.CallContentSubtype(jsonCodec{}.Name())); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return portsError(err)
+	}
+	return nil
+}
+
+// conn returns the connection to address, connecting lazily
+func (t *Transport) conn(address string) (*grpc.ClientConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[address]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	t.conns[address] = conn
+	return conn, nil
+}
+
+// Close closes the connections to every node
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var firstErr error
+	for address, conn := range t.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(t.conns, address)
+	}
+	return firstErr
+}
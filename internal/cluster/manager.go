@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// Manager is a cluster manager holding the peer nodes in memory, e.g. as listed by the config
+type Manager struct {
+	mu    sync.RWMutex
+	nodes map[string]string // node ID -> address
+	order []string          // node IDs in registration order
+}
+
+// NewManager creates a Manager without nodes
+func NewManager() *Manager {
+	return &Manager{nodes: make(map[string]string)}
+}
+
+// RegisterNode adds a peer node, or moves a registered node to a new address
+func (m *Manager) RegisterNode(nodeID string, address string) error {
+	if nodeID == "" || address == "" {
+		return fmt.Errorf("%w: nodes need an ID and an address", ports.ErrInvalid)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nodes[nodeID]; !ok {
+		m.order = append(m.order, nodeID)
+	}
+	m.nodes[nodeID] = address
+	return nil
+}
+
+// DeregisterNode removes a peer node
+func (m *Manager) DeregisterNode(nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nodes[nodeID]; !ok {
+		return fmt.Errorf("node %s: %w", nodeID, ports.ErrNotFound)
+	}
+	delete(m.nodes, nodeID)
+	for i, id := range m.order {
+		if id == nodeID {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ListNodes returns the IDs of the peer nodes in registration order
+func (m *Manager) ListNodes() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.order...), nil
+}
+
+// NodeAddress returns the address a peer node serves shard searches on
+func (m *Manager) NodeAddress(nodeID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	address, ok := m.nodes[nodeID]
+	if !ok {
+		return "", fmt.Errorf("node %s: %w", nodeID, ports.ErrNotFound)
+	}
+	return address, nil
+}
@@ -0,0 +1,438 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/rs/zerolog/log"
+)
+
+/**
+ * Cluster metadata replicated with Raft: the nodes agree on the indexes created at runtime, the aliases
+ * and the shard map by applying the same log of changes
+ **/
+
+// raftApplyTimeout bounds how long proposing a change may take
+const raftApplyTimeout = 10 * time.Second
+
+// RaftServer is a voting member of the Raft cluster
+type RaftServer struct {
+	ID      string
+	Address string // Raft address, host:port
+}
+
+// RaftOptions configures a MetadataStore
+type RaftOptions struct {
+	NodeID  string
+	Address string // Raft address this node binds and advertises, host:port
+	Dir     string // Directory of the Raft log and snapshots
+	// Bootstrap starts a new cluster of Servers (this node included) if the node has no Raft state yet.
+	// Bootstrap a single node, or every node with the same Servers.
+	Bootstrap bool
+	Servers   []RaftServer
+	// Shard map the leader proposes when it differs from the replicated one (0 shards: none)
+	Shards ports.ShardMap
+}
+
+// MetadataObserver is called with the new metadata after each applied change and snapshot restore
+type MetadataObserver func(ports.ClusterMetadata)
+
+// metadataForwarder sends changes to the leader, which answers with the index of the change in the log
+type metadataForwarder interface {
+	ApplyMetadata(ctx context.Context, address string, change ports.MetadataChange) (uint64, error)
+}
+
+// MetadataStore replicates the cluster metadata with Raft. Followers forward changes to the leader over
+// the cluster transport.
+type MetadataStore struct {
+	nodeID    string
+	raft      *raft.Raft
+	fsm       *metadataFSM
+	manager   ports.ClusterManagerPort
+	forwarder metadataForwarder
+	shards    ports.ShardMap
+	closers   []io.Closer
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewMetadataStore starts the Raft node of this node. manager and transport find and reach the leader
+// to forward changes; observer (if any) is called as changes are applied.
+func NewMetadataStore(options RaftOptions, manager ports.ClusterManagerPort, transport *Transport, observer MetadataObserver) (*MetadataStore, error) {
+	if options.NodeID == "" || options.Address == "" || options.Dir == "" {
+		return nil, fmt.Errorf("%w: raft needs a node ID, an address and a directory", ports.ErrInvalid)
+	}
+	advertise, err := net.ResolveTCPAddr("tcp", options.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: raft address %s: %w", ports.ErrInvalid, options.Address, err)
+	}
+	if advertise.IP == nil || advertise.IP.IsUnspecified() {
+		return nil, fmt.Errorf("%w: raft address %s must name a host other nodes can reach", ports.ErrInvalid, options.Address)
+	}
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	store, err := newBoltRaftStore(filepath.Join(options.Dir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft store: %w", err)
+	}
+	snapshots, err := raft.NewFileSnapshotStore(options.Dir, 2, os.Stderr)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to open raft snapshots: %w", err)
+	}
+	network, err := raft.NewTCPTransport(options.Address, advertise, 3, raftApplyTimeout, os.Stderr)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to listen for raft on %s: %w", options.Address, err)
+	}
+	var forwarder metadataForwarder
+	if transport != nil {
+		forwarder = transport
+	}
+	s, err := openMetadataStore(options, store, store, snapshots, network, manager, forwarder, observer)
+	if err != nil {
+		network.Close()
+		store.Close()
+		return nil, err
+	}
+	s.closers = append(s.closers, network, store)
+	return s, nil
+}
+
+// openMetadataStore starts a Raft node on the given storage and transport
+func openMetadataStore(options RaftOptions, logs raft.LogStore, stable raft.StableStore, snapshots raft.SnapshotStore,
+	network raft.Transport, manager ports.ClusterManagerPort, forwarder metadataForwarder, observer MetadataObserver) (*MetadataStore, error) {
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(options.NodeID)
+	config.Logger = hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Warn, Output: os.Stderr})
+
+	if options.Bootstrap {
+		existing, err := raft.HasExistingState(logs, stable, snapshots)
+		if err != nil {
+			return nil, err
+		}
+		if !existing {
+			servers := []raft.Server{{ID: config.LocalID, Address: network.LocalAddr()}}
+			for _, server := range options.Servers {
+				if server.ID != options.NodeID {
+					servers = append(servers, raft.Server{ID: raft.ServerID(server.ID), Address: raft.ServerAddress(server.Address)})
+				}
+			}
+			if err := raft.BootstrapCluster(config, logs, stable, snapshots, network, raft.Configuration{Servers: servers}); err != nil {
+				return nil, fmt.Errorf("failed to bootstrap raft: %w", err)
+			}
+		}
+	}
+
+	fsm := newMetadataFSM(observer)
+	node, err := raft.NewRaft(config, fsm, logs, stable, snapshots, network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft: %w", err)
+	}
+	s := &MetadataStore{
+		nodeID:    options.NodeID,
+		raft:      node,
+		fsm:       fsm,
+		manager:   manager,
+		forwarder: forwarder,
+		shards:    options.Shards,
+		done:      make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.watchLeadership()
+	return s, nil
+}
+
+// Metadata returns the metadata as applied on this node
+func (s *MetadataStore) Metadata() ports.ClusterMetadata {
+	return s.fsm.metadata()
+}
+
+// Leader returns the ID of the current leader (empty: none known)
+func (s *MetadataStore) Leader() string {
+	_, id := s.raft.LeaderWithID()
+	return string(id)
+}
+
+// Propose replicates a change, returning once it is applied on this node. Changes that conflict with the
+// metadata (e.g. creating an index that exists) fail with the error of the leader.
+func (s *MetadataStore) Propose(change ports.MetadataChange) error {
+	if s.raft.State() == raft.Leader {
+		_, err := s.apply(change)
+		return err
+	}
+	_, leader := s.raft.LeaderWithID()
+	if leader == "" {
+		return errors.New("no raft leader elected")
+	}
+	if s.manager == nil || s.forwarder == nil {
+		return fmt.Errorf("cannot forward the change to leader %s: no cluster transport", leader)
+	}
+	address, err := s.manager.NodeAddress(string(leader))
+	if err != nil {
+		return fmt.Errorf("leader %s: %w", leader, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), raftApplyTimeout)
+	defer cancel()
+	index, err := s.forwarder.ApplyMetadata(ctx, address, change)
+	if err != nil {
+		return err
+	}
+	return s.fsm.waitApplied(ctx, index)
+}
+
+// ApplyMetadata applies a change forwarded by a follower, which must run on the leader
+func (s *MetadataStore) ApplyMetadata(ctx context.Context, change ports.MetadataChange) (uint64, error) {
+	if s.raft.State() != raft.Leader {
+		return 0, fmt.Errorf("%w: node %s is not the raft leader", ports.ErrNotSupported, s.nodeID)
+	}
+	return s.apply(change)
+}
+
+// apply appends a change to the log as leader, returning its index once applied
+func (s *MetadataStore) apply(change ports.MetadataChange) (uint64, error) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return 0, err
+	}
+	future := s.raft.Apply(data, raftApplyTimeout)
+	if err := future.Error(); err != nil {
+		return 0, fmt.Errorf("failed to replicate %s: %w", change.Op, err)
+	}
+	if err, ok := future.Response().(error); ok {
+		return future.Index(), err
+	}
+	return future.Index(), nil
+}
+
+// watchLeadership proposes the configured shard map each time this node becomes leader
+func (s *MetadataStore) watchLeadership() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.done:
+			return
+		case leader := <-s.raft.LeaderCh():
+			if !leader || s.shards.Shards == 0 || sameShardMap(s.Metadata().Shards, s.shards) {
+				continue
+			}
+			if _, err := s.apply(ports.MetadataChange{Op: ports.MetadataSetShards, Shards: s.shards}); err != nil {
+				log.Warn().Err(err).Msg("Failed to replicate the shard map")
+			}
+		}
+	}
+}
+
+// Close stops the Raft node
+func (s *MetadataStore) Close() error {
+	close(s.done)
+	err := s.raft.Shutdown().Error()
+	s.wg.Wait()
+	for _, closer := range s.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// sameShardMap reports whether two shard maps place shards on the same nodes
+func sameShardMap(a, b ports.ShardMap) bool {
+	if a.Shards != b.Shards || len(a.Nodes) != len(b.Nodes) {
+		return false
+	}
+	for i := range a.Nodes {
+		if a.Nodes[i] != b.Nodes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataFSM is the state machine Raft applies metadata changes to
+type metadataFSM struct {
+	mu       sync.Mutex
+	state    ports.ClusterMetadata
+	applied  uint64
+	progress chan struct{} // Closed and replaced whenever applied moves
+	observer MetadataObserver
+}
+
+func newMetadataFSM(observer MetadataObserver) *metadataFSM {
+	return &metadataFSM{state: emptyMetadata(), progress: make(chan struct{}), observer: observer}
+}
+
+func emptyMetadata() ports.ClusterMetadata {
+	return ports.ClusterMetadata{Indexes: make(map[string]ports.IndexSpec), Aliases: make(map[string]string)}
+}
+
+// Apply applies a committed change, returning its error (if any) as the response
+func (f *metadataFSM) Apply(entry *raft.Log) interface{} {
+	var change ports.MetadataChange
+	if err := json.Unmarshal(entry.Data, &change); err != nil {
+		f.advance(entry.Index, nil)
+		return fmt.Errorf("%w: undecodable metadata change: %w", ports.ErrInvalid, err)
+	}
+	f.mu.Lock()
+	err := applyChange(&f.state, change)
+	var changed *ports.ClusterMetadata
+	if err == nil {
+		meta := cloneMetadata(f.state)
+		changed = &meta
+	}
+	f.mu.Unlock()
+	f.advance(entry.Index, changed)
+	return err
+}
+
+// advance records the index of the last applied entry and notifies the observer of changed metadata
+func (f *metadataFSM) advance(index uint64, changed *ports.ClusterMetadata) {
+	if changed != nil && f.observer != nil {
+		f.observer(*changed)
+	}
+	f.mu.Lock()
+	f.applied = index
+	close(f.progress)
+	f.progress = make(chan struct{})
+	f.mu.Unlock()
+}
+
+// waitApplied waits until the entry at index is applied
+func (f *metadataFSM) waitApplied(ctx context.Context, index uint64) error {
+	for {
+		f.mu.Lock()
+		applied, progress := f.applied, f.progress
+		f.mu.Unlock()
+		if applied >= index {
+			return nil
+		}
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for metadata change %d: %w", index, ctx.Err())
+		}
+	}
+}
+
+// metadata returns a copy of the state
+func (f *metadataFSM) metadata() ports.ClusterMetadata {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return cloneMetadata(f.state)
+}
+
+func (f *metadataFSM) Snapshot() (raft.FSMSnapshot, error) {
+	data, err := json.Marshal(f.metadata())
+	if err != nil {
+		return nil, err
+	}
+	return metadataSnapshot(data), nil
+}
+
+func (f *metadataFSM) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+	state := emptyMetadata()
+	if err := json.NewDecoder(snapshot).Decode(&state); err != nil {
+		return fmt.Errorf("failed to restore metadata snapshot: %w", err)
+	}
+	if state.Indexes == nil {
+		state.Indexes = make(map[string]ports.IndexSpec)
+	}
+	if state.Aliases == nil {
+		state.Aliases = make(map[string]string)
+	}
+	f.mu.Lock()
+	f.state = state
+	f.mu.Unlock()
+	if f.observer != nil {
+		f.observer(cloneMetadata(state))
+	}
+	return nil
+}
+
+// metadataSnapshot is the JSON encoded state at the time of a snapshot
+type metadataSnapshot []byte
+
+func (s metadataSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s metadataSnapshot) Release() {}
+
+// applyChange applies a change to the metadata, rejecting changes that conflict with it
+func applyChange(meta *ports.ClusterMetadata, change ports.MetadataChange) error {
+	switch change.Op {
+	case ports.MetadataCreateIndex:
+		name := change.Index.Name
+		if name == "" || change.Index.Type == "" {
+			return fmt.Errorf("%w: an index needs a name and a type", ports.ErrInvalid)
+		}
+		if _, exists := meta.Indexes[name]; exists {
+			return fmt.Errorf("%w: index %s already exists", ports.ErrConflict, name)
+		}
+		if _, exists := meta.Aliases[name]; exists {
+			return fmt.Errorf("%w: %s is an alias", ports.ErrConflict, name)
+		}
+		meta.Indexes[name] = change.Index
+	case ports.MetadataDropIndex:
+		if _, exists := meta.Indexes[change.Name]; !exists {
+			return fmt.Errorf("index %s: %w", change.Name, ports.ErrNotFound)
+		}
+		delete(meta.Indexes, change.Name)
+		for alias, target := range meta.Aliases {
+			if target == change.Name {
+				delete(meta.Aliases, alias)
+			}
+		}
+	case ports.MetadataSetAlias:
+		if change.Name == "" || change.Target == "" {
+			return fmt.Errorf("%w: an alias needs a name and an index", ports.ErrInvalid)
+		}
+		if _, exists := meta.Indexes[change.Name]; exists {
+			return fmt.Errorf("%w: %s is an index", ports.ErrConflict, change.Name)
+		}
+		meta.Aliases[change.Name] = change.Target
+	case ports.MetadataRemoveAlias:
+		if _, exists := meta.Aliases[change.Name]; !exists {
+			return fmt.Errorf("alias %s: %w", change.Name, ports.ErrNotFound)
+		}
+		delete(meta.Aliases, change.Name)
+	case ports.MetadataSetShards:
+		if change.Shards.Shards < 0 {
+			return fmt.Errorf("%w: negative shard count", ports.ErrInvalid)
+		}
+		meta.Shards = ports.ShardMap{Shards: change.Shards.Shards, Nodes: append([]string(nil), change.Shards.Nodes...)}
+	default:
+		return fmt.Errorf("%w: unknown metadata change %q", ports.ErrInvalid, change.Op)
+	}
+	return nil
+}
+
+// cloneMetadata returns a deep copy of the metadata
+func cloneMetadata(meta ports.ClusterMetadata) ports.ClusterMetadata {
+	clone := emptyMetadata()
+	for name, spec := range meta.Indexes {
+		clone.Indexes[name] = spec
+	}
+	for alias, target := range meta.Aliases {
+		clone.Aliases[alias] = target
+	}
+	clone.Shards = ports.ShardMap{Shards: meta.Shards.Shards, Nodes: append([]string(nil), meta.Shards.Nodes...)}
+	return clone
+}
@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// storeForwarder forwards changes to the stores of an in-memory cluster, by node ID
+type storeForwarder struct {
+	mu     sync.Mutex
+	stores map[string]*MetadataStore
+}
+
+func (f *storeForwarder) ApplyMetadata(ctx context.Context, address string, change ports.MetadataChange) (uint64, error) {
+	f.mu.Lock()
+	store := f.stores[address]
+	f.mu.Unlock()
+	return store.ApplyMetadata(ctx, change)
+}
+
+// raftCluster starts a cluster of in-memory raft nodes, recording the metadata each observes
+func raftCluster(t *testing.T, ids []string, shards ports.ShardMap) (map[string]*MetadataStore, func(id string) ports.ClusterMetadata) {
+	transports := make(map[string]*raft.InmemTransport)
+	var servers []RaftServer
+	for _, id := range ids {
+		_, transports[id] = raft.NewInmemTransport(raft.ServerAddress(id))
+		servers = append(servers, RaftServer{ID: id, Address: id})
+	}
+	for _, a := range transports {
+		for id, b := range transports {
+			a.Connect(raft.ServerAddress(id), b)
+		}
+	}
+
+	forwarder := &storeForwarder{stores: make(map[string]*MetadataStore)}
+	manager := NewManager()
+	var mu sync.Mutex
+	observed := make(map[string]ports.ClusterMetadata)
+	for _, id := range ids {
+		assert.NoError(t, manager.RegisterNode(id, id))
+		observer := func(meta ports.ClusterMetadata) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed[id] = meta
+		}
+		options := RaftOptions{NodeID: id, Address: id, Bootstrap: true, Servers: servers, Shards: shards}
+		store, err := openMetadataStore(options, raft.NewInmemStore(), raft.NewInmemStore(), raft.NewInmemSnapshotStore(), transports[id], manager, forwarder, observer)
+		assert.NoError(t, err)
+		forwarder.mu.Lock()
+		forwarder.stores[id] = store
+		forwarder.mu.Unlock()
+		t.Cleanup(func() { store.Close() })
+	}
+	return forwarder.stores, func(id string) ports.ClusterMetadata {
+		mu.Lock()
+		defer mu.Unlock()
+		return observed[id]
+	}
+}
+
+func TestMetadataStore_ReplicatesChanges(t *testing.T) {
+	shards := ports.ShardMap{Shards: 8, Nodes: []string{"a", "b", "c"}}
+	stores, observed := raftCluster(t, []string{"a", "b", "c"}, shards)
+	assert.Eventually(t, func() bool { return stores["a"].Leader() != "" }, 10*time.Second, 10*time.Millisecond)
+
+	// Changes go through followers as well as the leader
+	leader := stores["a"].Leader()
+	var follower string
+	for id := range stores {
+		if id != leader {
+			follower = id
+		}
+	}
+	spec := ports.IndexSpec{Name: "logs", Type: "simple", Config: map[string]interface{}{"max": 1.0}}
+	assert.NoError(t, stores[follower].Propose(ports.MetadataChange{Op: ports.MetadataCreateIndex, Index: spec}))
+	assert.Equal(t, spec, observed(follower).Indexes["logs"])
+	assert.NoError(t, stores[leader].Propose(ports.MetadataChange{Op: ports.MetadataSetAlias, Name: "current", Target: "logs"}))
+
+	err := stores[follower].Propose(ports.MetadataChange{Op: ports.MetadataCreateIndex, Index: spec})
+	assert.ErrorIs(t, err, ports.ErrConflict)
+	err = stores[leader].Propose(ports.MetadataChange{Op: ports.MetadataDropIndex, Name: "missing"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	// Every node applies the same changes, including the shard map proposed by the leader
+	for id, store := range stores {
+		assert.Eventually(t, func() bool {
+			meta := store.Metadata()
+			return meta.Aliases["current"] == "logs" && meta.Shards.Shards == 8
+		}, 5*time.Second, 10*time.Millisecond, "node %s", id)
+		assert.Equal(t, ports.ClusterMetadata{
+			Indexes: map[string]ports.IndexSpec{"logs": spec},
+			Aliases: map[string]string{"current": "logs"},
+			Shards:  shards,
+		}, store.Metadata())
+	}
+}
+
+func TestMetadataFSM_ApplyAndRestore(t *testing.T) {
+	var observed []ports.ClusterMetadata
+	fsm := newMetadataFSM(func(meta ports.ClusterMetadata) { observed = append(observed, meta) })
+	apply := func(index uint64, data string) interface{} {
+		return fsm.Apply(&raft.Log{Index: index, Data: []byte(data)})
+	}
+
+	assert.Nil(t, apply(1, `{"Op":"create_index","Index":{"Name":"logs","Type":"simple"}}`))
+	assert.Nil(t, apply(2, `{"Op":"set_alias","Name":"current","Target":"logs"}`))
+	assert.ErrorIs(t, apply(3, `{"Op":"set_alias","Name":"logs","Target":"current"}`).(error), ports.ErrConflict)
+	assert.ErrorIs(t, apply(4, `{"Op":"remove_alias","Name":"missing"}`).(error), ports.ErrNotFound)
+	assert.ErrorIs(t, apply(5, `{"Op":"rename_index"}`).(error), ports.ErrInvalid)
+	assert.ErrorIs(t, apply(6, `not json`).(error), ports.ErrInvalid)
+	assert.Len(t, observed, 2)
+	assert.NoError(t, fsm.waitApplied(context.Background(), 6))
+
+	snapshot, err := fsm.Snapshot()
+	assert.NoError(t, err)
+	sink := &memorySink{}
+	assert.NoError(t, snapshot.Persist(sink))
+
+	// Dropping an index removes its aliases
+	assert.Nil(t, apply(7, `{"Op":"drop_index","Name":"logs"}`))
+	assert.Empty(t, fsm.metadata().Aliases)
+
+	restored := newMetadataFSM(nil)
+	assert.NoError(t, restored.Restore(sink))
+	assert.Equal(t, map[string]string{"current": "logs"}, restored.metadata().Aliases)
+	assert.Equal(t, "simple", restored.metadata().Indexes["logs"].Type)
+}
+
+func TestBoltRaftStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raft.db")
+	store, err := newBoltRaftStore(path)
+	assert.NoError(t, err)
+
+	var logs []*raft.Log
+	for i := uint64(1); i <= 5; i++ {
+		logs = append(logs, &raft.Log{Index: i, Term: 1, Type: raft.LogCommand, Data: []byte(fmt.Sprint(i))})
+	}
+	assert.NoError(t, store.StoreLogs(logs))
+	assert.NoError(t, store.DeleteRange(1, 2))
+	first, _ := store.FirstIndex()
+	last, _ := store.LastIndex()
+	assert.Equal(t, uint64(3), first)
+	assert.Equal(t, uint64(5), last)
+	var entry raft.Log
+	assert.NoError(t, store.GetLog(4, &entry))
+	assert.Equal(t, []byte("4"), entry.Data)
+	assert.ErrorIs(t, store.GetLog(1, &entry), raft.ErrLogNotFound)
+
+	assert.NoError(t, store.SetUint64([]byte("term"), 7))
+	_, err = store.Get([]byte("vote"))
+	assert.EqualError(t, err, "not found")
+	assert.NoError(t, store.Close())
+
+	// The log and state survive reopening
+	store, err = newBoltRaftStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+	term, err := store.GetUint64([]byte("term"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), term)
+	last, _ = store.LastIndex()
+	assert.Equal(t, uint64(5), last)
+}
+
+// memorySink keeps a snapshot in memory, and reads it back for a restore
+type memorySink struct {
+	data []byte
+	read int
+}
+
+func (s *memorySink) Write(p []byte) (int, error) {
+	s.data = append(s.data, p...)
+	return len(p), nil
+}
+
+func (s *memorySink) Read(p []byte) (int, error) {
+	if s.read >= len(s.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.data[s.read:])
+	s.read += n
+	return n, nil
+}
+
+func (s *memorySink) Close() error  { return nil }
+func (s *memorySink) ID() string    { return "memory" }
+func (s *memorySink) Cancel() error { return nil }
@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/hashicorp/raft"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	raftLogsBucket   = []byte("logs")
+	raftStableBucket = []byte("stable")
+	// errKeyNotFound is the error Raft expects from a stable store for missing keys
+	errKeyNotFound = errors.New("not found")
+)
+
+// boltRaftStore stores the Raft log and the Raft state (current term, vote) in a bbolt database
+type boltRaftStore struct {
+	db *bbolt.DB
+}
+
+// newBoltRaftStore opens (or creates) a Raft store
+func newBoltRaftStore(path string) (*boltRaftStore, error) {
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(raftLogsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(raftStableBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltRaftStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *boltRaftStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltRaftStore) FirstIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if key, _ := tx.Bucket(raftLogsBucket).Cursor().First(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+func (s *boltRaftStore) LastIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if key, _ := tx.Bucket(raftLogsBucket).Cursor().Last(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+func (s *boltRaftStore) GetLog(index uint64, log *raft.Log) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(raftLogsBucket).Get(uint64Key(index))
+		if value == nil {
+			return raft.ErrLogNotFound
+		}
+		return json.Unmarshal(value, log)
+	})
+}
+
+func (s *boltRaftStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+func (s *boltRaftStore) StoreLogs(logs []*raft.Log) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(raftLogsBucket)
+		for _, log := range logs {
+			value, err := json.Marshal(log)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(uint64Key(log.Index), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltRaftStore) DeleteRange(min, max uint64) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(raftLogsBucket)
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Seek(uint64Key(min)); key != nil && binary.BigEndian.Uint64(key) <= max; key, _ = cursor.Next() {
+			keys = append(keys, append([]byte(nil), key...))
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltRaftStore) Set(key []byte, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(raftStableBucket).Put(key, value)
+	})
+}
+
+func (s *boltRaftStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if stored := tx.Bucket(raftStableBucket).Get(key); stored != nil {
+			value = append([]byte(nil), stored...)
+			return nil
+		}
+		return errKeyNotFound
+	})
+	return value, err
+}
+
+func (s *boltRaftStore) SetUint64(key []byte, value uint64) error {
+	return s.Set(key, uint64Key(value))
+}
+
+func (s *boltRaftStore) GetUint64(key []byte) (uint64, error) {
+	value, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// uint64Key encodes an index big-endian, so keys sort by index
+func uint64Key(index uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, index)
+	return key
+}
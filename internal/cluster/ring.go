@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// ringReplicas is the number of points each member has on a ring, which evens out the keys per member
+const ringReplicas = 128
+
+// Ring is a consistent hash ring: members are placed on it at ringReplicas points each and a key belongs
+// to the member of the first point at or after its hash. Adding or removing a member only moves the keys
+// of that member's points.
+type Ring struct {
+	points  []uint64
+	members map[uint64]string
+}
+
+// NewRing places members on a ring
+func NewRing(members []string) *Ring {
+	ring := &Ring{members: make(map[uint64]string, len(members)*ringReplicas)}
+	for _, member := range members {
+		for i := 0; i < ringReplicas; i++ {
+			point := hashKey(fmt.Sprintf("%s#%d", member, i))
+			if _, taken := ring.members[point]; taken {
+				continue
+			}
+			ring.members[point] = member
+			ring.points = append(ring.points, point)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// Locate returns the member key belongs to ("" for an empty ring)
+func (r *Ring) Locate(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.members[r.points[i]]
+}
+
+// hashKey hashes a key with FNV-1a, mixed (splitmix64 finalizer) so similar keys spread over the ring
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Router assigns documents to a fixed number of shards by consistent hash of their ID, and each shard to
+// a node of the cluster by consistent hash of its name
+type Router struct {
+	self   string
+	shards *Ring
+	nodes  *Ring
+}
+
+// NewRouter creates a Router for this node (self) and its peers
+func NewRouter(self string, shards int, peers []string) (*Router, error) {
+	if shards <= 0 {
+		return nil, fmt.Errorf("shard count must be positive, got %d", shards)
+	}
+	names := make([]string, shards)
+	for i := range names {
+		names[i] = fmt.Sprintf("shard-%d", i)
+	}
+	nodes := append([]string{self}, peers...)
+	return &Router{self: self, shards: NewRing(names), nodes: NewRing(nodes)}, nil
+}
+
+// Shard returns the shard of a document
+func (r *Router) Shard(id string) string {
+	return r.shards.Locate(id)
+}
+
+// Owner returns the node holding the shard of a document
+func (r *Router) Owner(id string) string {
+	return r.nodes.Locate(r.Shard(id))
+}
+
+// Local reports whether this node holds the shard of a document
+func (r *Router) Local(id string) bool {
+	return r.Owner(id) == r.self
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing_SpreadsKeysAndMovesFewOnJoin(t *testing.T) {
+	before := NewRing([]string{"a", "b", "c"})
+	after := NewRing([]string{"a", "b", "c", "d"})
+
+	counts := make(map[string]int)
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("doc-%d", i)
+		owner := before.Locate(key)
+		counts[owner]++
+		if next := after.Locate(key); next != owner {
+			assert.Equal(t, "d", next, "keys only move to the new member")
+			moved++
+		}
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		assert.InDelta(t, 3333, counts[member], 800)
+	}
+	assert.InDelta(t, 2500, moved, 800)
+	assert.Equal(t, "", NewRing(nil).Locate("doc"))
+}
+
+func TestRouter(t *testing.T) {
+	_, err := NewRouter("a", 0, nil)
+	assert.Error(t, err)
+
+	router, err := NewRouter("a", 8, []string{"b"})
+	assert.NoError(t, err)
+	shards := make(map[string]bool)
+	owners := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		shards[router.Shard(id)] = true
+		owners[router.Owner(id)] = true
+		assert.Equal(t, router.Owner(id) == "a", router.Local(id))
+	}
+	assert.Len(t, shards, 8)
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, owners)
+
+	// Every node routes alike
+	peer, _ := NewRouter("b", 8, []string{"a"})
+	assert.Equal(t, router.Owner("doc-1"), peer.Owner("doc-1"))
+}
@@ -0,0 +1,370 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// shardBatchSize is the number of documents sent to a node per write when importing or rebalancing
+const shardBatchSize = 1000
+
+// ShardedIndex routes the documents of an index to the node holding their shard: documents of this node's
+// shards are written to the wrapped index, the others sent to their node. Searches evaluate this node's
+// shards only; distributed searches gather the shards of the other nodes.
+type ShardedIndex struct {
+	local     ports.IndexPort
+	mu        sync.RWMutex // Guards router, replaced when the shard map changes
+	router    *Router
+	manager   ports.ClusterManagerPort
+	transport ports.ShardTransportPort
+}
+
+// NewShardedIndex shards local over the nodes of manager, reached through transport
+func NewShardedIndex(local ports.IndexPort, router *Router, manager ports.ClusterManagerPort, transport ports.ShardTransportPort) *ShardedIndex {
+	return &ShardedIndex{local: local, router: router, manager: manager, transport: transport}
+}
+
+// SetRouter replaces the routing of documents, e.g. when the replicated shard map changes. Documents
+// already indexed stay where they are until rebalanced.
+func (s *ShardedIndex) SetRouter(router *Router) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.router = router
+}
+
+// currentRouter returns the routing of documents
+func (s *ShardedIndex) currentRouter() *Router {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.router
+}
+
+// AddDocument adds a document to its shard
+func (s *ShardedIndex) AddDocument(ctx context.Context, doc interface{}) error {
+	d, ok := doc.(models.Document)
+	if !ok {
+		return fmt.Errorf("expected models.Document, got %T", doc)
+	}
+	return s.route(ctx, ports.ShardWrite{Add: []models.Document{d}})
+}
+
+// AddDocuments adds documents to their shards
+func (s *ShardedIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	return s.route(ctx, ports.ShardWrite{Add: docs})
+}
+
+// UpdateDocument replaces a document in its shard
+func (s *ShardedIndex) UpdateDocument(ctx context.Context, doc models.Document) error {
+	return s.route(ctx, ports.ShardWrite{Update: []models.Document{doc}})
+}
+
+// DeleteDocument removes a document from its shard
+func (s *ShardedIndex) DeleteDocument(ctx context.Context, id string) error {
+	return s.route(ctx, ports.ShardWrite{Delete: []string{id}})
+}
+
+// Search evaluates a query against this node's shards
+func (s *ShardedIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	return s.local.Search(ctx, query)
+}
+
+// Count returns the number of documents in this node's shards
+func (s *ShardedIndex) Count() (int, error) {
+	return s.local.Count()
+}
+
+// Close closes the wrapped index
+func (s *ShardedIndex) Close() error {
+	return s.local.Close()
+}
+
+// route splits a write by the node holding each document and applies every part
+func (s *ShardedIndex) route(ctx context.Context, write ports.ShardWrite) error {
+	router := s.currentRouter()
+	parts := make(map[string]*ports.ShardWrite)
+	var owners []string
+	part := func(id string) *ports.ShardWrite {
+		owner := router.Owner(id)
+		if _, ok := parts[owner]; !ok {
+			parts[owner] = &ports.ShardWrite{}
+			owners = append(owners, owner)
+		}
+		return parts[owner]
+	}
+	for _, doc := range write.Add {
+		p := part(doc.ID)
+		p.Add = append(p.Add, doc)
+	}
+	for _, doc := range write.Update {
+		p := part(doc.ID)
+		p.Update = append(p.Update, doc)
+	}
+	for _, id := range write.Delete {
+		p := part(id)
+		p.Delete = append(p.Delete, id)
+	}
+
+	var errs []error
+	for _, owner := range owners {
+		if err := s.writeTo(ctx, owner, *parts[owner]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeTo applies a write to the shards of a node
+func (s *ShardedIndex) writeTo(ctx context.Context, node string, write ports.ShardWrite) error {
+	if node == s.currentRouter().self {
+		return s.WriteShard(ctx, write)
+	}
+	address, err := s.manager.NodeAddress(node)
+	if err != nil {
+		return err
+	}
+	if err := s.transport.WriteShard(ctx, address, write); err != nil {
+		return fmt.Errorf("node %s: %w", node, err)
+	}
+	return nil
+}
+
+// WriteShard applies a write to this node's shards without routing it
+func (s *ShardedIndex) WriteShard(ctx context.Context, write ports.ShardWrite) error {
+	if len(write.Add) > 0 {
+		if batcher, ok := s.local.(ports.BatchIndexPort); ok {
+			if err := batcher.AddDocuments(ctx, write.Add); err != nil {
+				return err
+			}
+		} else {
+			for _, doc := range write.Add {
+				if err := s.local.AddDocument(ctx, doc); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(write.Update) == 0 && len(write.Delete) == 0 {
+		return nil
+	}
+	mutable, ok := s.local.(ports.MutableIndexPort)
+	if !ok {
+		return fmt.Errorf("updates and deletes are %w by the index", ports.ErrNotSupported)
+	}
+	for _, doc := range write.Update {
+		if err := mutable.UpdateDocument(ctx, doc); err != nil {
+			return err
+		}
+	}
+	for _, id := range write.Delete {
+		if err := mutable.DeleteDocument(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebalance moves the documents of the shards this node no longer holds, after nodes joined or left, to
+// the nodes holding them now. It returns the number of documents moved.
+func (s *ShardedIndex) Rebalance(ctx context.Context) (int, error) {
+	exporter, ok := s.local.(ports.TransferIndexPort)
+	if !ok {
+		return 0, fmt.Errorf("rebalancing is %w by the index: it cannot be exported", ports.ErrNotSupported)
+	}
+	mutable, ok := s.local.(ports.MutableIndexPort)
+	if !ok {
+		return 0, fmt.Errorf("rebalancing is %w by the index: it cannot delete documents", ports.ErrNotSupported)
+	}
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf); err != nil {
+		return 0, err
+	}
+	router := s.currentRouter()
+	moving := make(map[string][]models.Document)
+	var owners []string
+	err := readExport(&buf, func(doc models.Document) error {
+		if owner := router.Owner(doc.ID); owner != router.self {
+			if _, ok := moving[owner]; !ok {
+				owners = append(owners, owner)
+			}
+			moving[owner] = append(moving[owner], doc)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Documents are deleted once their new node has them, so a failed move loses none
+	moved := 0
+	for _, owner := range owners {
+		docs := moving[owner]
+		for start := 0; start < len(docs); start += shardBatchSize {
+			batch := docs[start:min(start+shardBatchSize, len(docs))]
+			if err := s.writeTo(ctx, owner, ports.ShardWrite{Add: batch}); err != nil {
+				return moved, err
+			}
+			for _, doc := range batch {
+				if err := mutable.DeleteDocument(ctx, doc.ID); err != nil {
+					return moved, err
+				}
+			}
+			moved += len(batch)
+		}
+		log.Info().Msgf("Rebalanced %d documents to node %s", len(docs), owner)
+	}
+	return moved, nil
+}
+
+// Export writes the documents of this node's shards
+func (s *ShardedIndex) Export(w io.Writer) error {
+	exporter, ok := s.local.(ports.TransferIndexPort)
+	if !ok {
+		return fmt.Errorf("export is %w by the index", ports.ErrNotSupported)
+	}
+	return exporter.Export(w)
+}
+
+// Import adds the documents of an export to their shards
+func (s *ShardedIndex) Import(r io.Reader) error {
+	batch := make([]models.Document, 0, shardBatchSize)
+	err := readExport(r, func(doc models.Document) error {
+		batch = append(batch, doc)
+		if len(batch) < shardBatchSize {
+			return nil
+		}
+		err := s.AddDocuments(context.Background(), batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return s.AddDocuments(context.Background(), batch)
+}
+
+// TermStats returns the term statistics of this node's shards, if the wrapped index ranks queries
+func (s *ShardedIndex) TermStats(query string, ids []string) (ports.TermStats, error) {
+	if scorer, ok := s.local.(ports.TermStatsIndexPort); ok {
+		return scorer.TermStats(query, ids)
+	}
+	return ports.TermStats{}, nil
+}
+
+// GetDocuments fetches documents by ID from this node's shards
+func (s *ShardedIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	if getter, ok := s.local.(ports.DocumentGetterIndexPort); ok {
+		return getter.GetDocuments(ctx, ids)
+	}
+	return nil, fmt.Errorf("fetching documents by ID is %w by the index", ports.ErrNotSupported)
+}
+
+// ListDocuments pages through the documents of this node's shards
+func (s *ShardedIndex) ListDocuments(ctx context.Context, opts ports.ListOptions) (ports.DocumentPage, error) {
+	if lister, ok := s.local.(ports.DocumentListerIndexPort); ok {
+		return lister.ListDocuments(ctx, opts)
+	}
+	return ports.DocumentPage{}, fmt.Errorf("listing documents is %w by the index", ports.ErrNotSupported)
+}
+
+// Clear removes every document of this node's shards
+func (s *ShardedIndex) Clear() error {
+	if cleared, ok := s.local.(ports.ClearableIndexPort); ok {
+		return cleared.Clear()
+	}
+	return fmt.Errorf("clear is %w by the index", ports.ErrNotSupported)
+}
+
+// Verify compares the documents of this node's shards in memory and in storage
+func (s *ShardedIndex) Verify(repair string) (ports.VerifyReport, error) {
+	if verified, ok := s.local.(ports.VerifiableIndexPort); ok {
+		return verified.Verify(repair)
+	}
+	return ports.VerifyReport{}, fmt.Errorf("verify is %w by the index", ports.ErrNotSupported)
+}
+
+// Size returns the size of this node's shards
+func (s *ShardedIndex) Size() (int, error) {
+	if sized, ok := s.local.(ports.SizedIndexPort); ok {
+		return sized.Size()
+	}
+	return 0, fmt.Errorf("size is %w by the index", ports.ErrNotSupported)
+}
+
+// Configure reconfigures the wrapped index
+func (s *ShardedIndex) Configure(config map[string]interface{}) error {
+	if configurable, ok := s.local.(ports.ConfigurableIndexPort); ok {
+		return configurable.Configure(config)
+	}
+	return fmt.Errorf("reconfiguration is %w by the index", ports.ErrNotSupported)
+}
+
+// Flush writes the wrapped index's pending changes to storage
+func (s *ShardedIndex) Flush() error {
+	if maintained, ok := s.local.(ports.MaintenanceIndexPort); ok {
+		return maintained.Flush()
+	}
+	return fmt.Errorf("flush is %w by the index", ports.ErrNotSupported)
+}
+
+// Optimize optimizes the wrapped index
+func (s *ShardedIndex) Optimize() error {
+	if maintained, ok := s.local.(ports.MaintenanceIndexPort); ok {
+		return maintained.Optimize()
+	}
+	return fmt.Errorf("optimize is %w by the index", ports.ErrNotSupported)
+}
+
+// HealthCheck checks the wrapped index
+func (s *ShardedIndex) HealthCheck() error {
+	if checker, ok := s.local.(ports.HealthCheckIndexPort); ok {
+		return checker.HealthCheck()
+	}
+	return nil
+}
+
+// MaxResults returns the cap of the wrapped index on the results of each search (0: none)
+func (s *ShardedIndex) MaxResults() int {
+	if capper, ok := s.local.(ports.ResultCapIndexPort); ok {
+		return capper.MaxResults()
+	}
+	return 0
+}
+
+// PersistenceStats returns the failed async writes of this node's shards
+func (s *ShardedIndex) PersistenceStats() (ports.PersistenceStats, error) {
+	if reporter, ok := s.local.(ports.PersistenceStatsIndexPort); ok {
+		return reporter.PersistenceStats()
+	}
+	return ports.PersistenceStats{}, fmt.Errorf("persistence stats are %w by the index", ports.ErrNotSupported)
+}
+
+// readExport calls add for every document of an index export, after its header line
+func readExport(r io.Reader, add func(doc models.Document) error) error {
+	decoder := json.NewDecoder(r)
+	var header json.RawMessage
+	if err := decoder.Decode(&header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("invalid export header: %w", err)
+	}
+	for decoder.More() {
+		var doc models.Document
+		if err := decoder.Decode(&doc); err != nil {
+			return fmt.Errorf("invalid exported document: %w", err)
+		}
+		if err := add(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// memIndex is a minimal mutable, exportable index holding documents in memory
+type memIndex struct {
+	docs map[string]models.Document
+}
+
+func newMemIndex() *memIndex { return &memIndex{docs: make(map[string]models.Document)} }
+
+func (m *memIndex) AddDocument(ctx context.Context, doc interface{}) error {
+	d := doc.(models.Document)
+	m.docs[d.ID] = d
+	return nil
+}
+
+func (m *memIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	for _, doc := range docs {
+		m.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+func (m *memIndex) UpdateDocument(ctx context.Context, doc models.Document) error {
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *memIndex) DeleteDocument(ctx context.Context, id string) error {
+	delete(m.docs, id)
+	return nil
+}
+
+func (m *memIndex) Search(ctx context.Context, query string) ([]interface{}, error) { return nil, nil }
+func (m *memIndex) Count() (int, error)                                             { return len(m.docs), nil }
+func (m *memIndex) Close() error                                                    { return nil }
+func (m *memIndex) Import(r io.Reader) error                                        { return nil }
+
+func (m *memIndex) Export(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(map[string]string{"format": "bitscout-index"}); err != nil {
+		return err
+	}
+	for _, doc := range m.docs {
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loopTransport delivers shard writes to the sharded indexes of other nodes in the same process
+type loopTransport map[string]*ShardedIndex
+
+func (l loopTransport) WriteShard(ctx context.Context, address string, write ports.ShardWrite) error {
+	return l[address].WriteShard(ctx, write)
+}
+
+func (l loopTransport) Rebalance(ctx context.Context, address string) (int, error) {
+	return l[address].Rebalance(ctx)
+}
+
+// shardCluster shards the local indexes of nodes (ID -> index) over each other, addressed by their IDs
+func shardCluster(t *testing.T, shards int, locals map[string]*memIndex) map[string]*ShardedIndex {
+	transport := make(loopTransport)
+	for id, local := range locals {
+		manager := NewManager()
+		var peers []string
+		for peer := range locals {
+			if peer != id {
+				assert.NoError(t, manager.RegisterNode(peer, peer))
+				peers = append(peers, peer)
+			}
+		}
+		router, err := NewRouter(id, shards, peers)
+		assert.NoError(t, err)
+		transport[id] = NewShardedIndex(local, router, manager, transport)
+	}
+	return transport
+}
+
+// assertPlaced checks that every node holds exactly the documents of its shards, and n in total
+func assertPlaced(t *testing.T, nodes map[string]*ShardedIndex, locals map[string]*memIndex, n int) {
+	total := 0
+	for id, local := range locals {
+		for docID := range local.docs {
+			assert.True(t, nodes[id].router.Local(docID), "document %s on node %s", docID, id)
+		}
+		total += len(local.docs)
+	}
+	assert.Equal(t, n, total)
+}
+
+func TestShardedIndex_RoutesWrites(t *testing.T) {
+	locals := map[string]*memIndex{"a": newMemIndex(), "b": newMemIndex()}
+	nodes := shardCluster(t, 8, locals)
+
+	docs := make([]models.Document, 100)
+	for i := range docs {
+		docs[i] = models.Document{ID: fmt.Sprintf("doc-%d", i)}
+	}
+	assert.NoError(t, nodes["a"].AddDocuments(context.Background(), docs))
+	assertPlaced(t, nodes, locals, 100)
+	assert.NotEmpty(t, locals["a"].docs)
+	assert.NotEmpty(t, locals["b"].docs)
+
+	// Updates and deletes reach the node holding the document, wherever they are made
+	var remote string
+	for id := range locals["b"].docs {
+		remote = id
+		break
+	}
+	assert.NoError(t, nodes["a"].UpdateDocument(context.Background(), models.Document{ID: remote, Text: "updated"}))
+	assert.Equal(t, "updated", locals["b"].docs[remote].Text)
+	assert.NoError(t, nodes["a"].DeleteDocument(context.Background(), remote))
+	assertPlaced(t, nodes, locals, 99)
+}
+
+func TestShardedIndex_RebalanceAfterJoin(t *testing.T) {
+	locals := map[string]*memIndex{"a": newMemIndex(), "b": newMemIndex()}
+	nodes := shardCluster(t, 16, locals)
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, nodes["b"].AddDocument(context.Background(), models.Document{ID: fmt.Sprintf("doc-%d", i)}))
+	}
+
+	// Node c joins: the others are restarted with it as peer, then rebalanced
+	locals["c"] = newMemIndex()
+	nodes = shardCluster(t, 16, locals)
+	moved := 0
+	for _, id := range []string{"a", "b", "c"} {
+		n, err := nodes[id].Rebalance(context.Background())
+		assert.NoError(t, err)
+		moved += n
+	}
+	assert.Equal(t, len(locals["c"].docs), moved, "only the shards of the new node move")
+	assert.NotZero(t, moved)
+	assertPlaced(t, nodes, locals, 200)
+}
+
+func TestShardedIndex_Import(t *testing.T) {
+	locals := map[string]*memIndex{"a": newMemIndex(), "b": newMemIndex()}
+	nodes := shardCluster(t, 4, locals)
+	source := newMemIndex()
+	for i := 0; i < shardBatchSize+10; i++ {
+		source.docs[fmt.Sprintf("doc-%d", i)] = models.Document{ID: fmt.Sprintf("doc-%d", i)}
+	}
+	reader, writer := io.Pipe()
+	go func() { writer.CloseWithError(source.Export(writer)) }()
+
+	assert.NoError(t, nodes["a"].Import(reader))
+	assertPlaced(t, nodes, locals, shardBatchSize+10)
+}
@@ -0,0 +1,385 @@
+package config
+
+/*
+ConfigPorts backed by configuration files or a remote key-value store. The config is layered, each layer
+merged over the ones before it (objects key by key, other values replaced whole): the defaults, the
+documents read in order, the environment overrides, then the config applied at runtime. Secret references
+in the loaded values, such as ${env:S3_KEY}, are resolved on each load (see secrets.go).
+
+An environment variable BITSCOUT_<PATH>=<value> overrides the value at PATH, whose segments are matched
+against the keys of objects (longest first, so BITSCOUT_SEARCH_MAX_RESULTS sets search.max_results) and
+against the positions or names of list items (BITSCOUT_INDEXES_DOCS_CONFIG_MAX_RESULTS sets the
+config.max_results of the index named docs). Values are read as JSON when they parse, else as strings.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvPrefix starts the names of the environment variables that override config values
+const DefaultEnvPrefix = "BITSCOUT_"
+
+// Source is a config read from files or a remote store
+type Source interface {
+	Load() error
+	Name() string
+	GetConfig() map[string]interface{}
+	ApplyConfig(config map[string]interface{}) error
+	Decode(v interface{}) error
+	Unresolved() map[string]interface{}
+	SetDefaults(defaults map[string]interface{})
+	SetEnvPrefix(prefix string)
+}
+
+// Open returns the config of a remote URL (see NewRemote) or a file path
+func Open(source string) Source {
+	if IsRemote(source) {
+		return NewRemote(source)
+	}
+	return NewFile(source)
+}
+
+// layers merges the layers of a config: defaults, the loaded documents, environment overrides and the
+// config applied at runtime
+type layers struct {
+	mu         sync.RWMutex
+	envPrefix  string
+	defaults   map[string]interface{}
+	applied    map[string]interface{} // Merged by ApplyConfig, kept across loads
+	loaded     map[string]interface{} // Defaults, documents and environment overrides, merged by load
+	config     map[string]interface{} // loaded with applied merged over it
+	unresolved map[string]interface{} // loaded before its secret references were resolved
+}
+
+// SetDefaults sets the values used where neither the documents nor the environment set one, from the
+// next Load
+func (l *layers) SetDefaults(defaults map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults = defaults
+}
+
+// SetEnvPrefix sets the prefix of the environment variables that override config values, from the next
+// Load ("": none do)
+func (l *layers) SetEnvPrefix(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.envPrefix = prefix
+}
+
+// load merges documents, in order, over the defaults and the environment overrides over them, then
+// resolves their secret references
+func (l *layers) load(documents ...map[string]interface{}) error {
+	l.mu.RLock()
+	loaded := mergeConfig(nil, l.defaults)
+	prefix := l.envPrefix
+	l.mu.RUnlock()
+	for _, document := range documents {
+		loaded = mergeConfig(loaded, document)
+	}
+	if prefix != "" {
+		applyEnv(loaded, prefix, os.Environ())
+	}
+	unresolved := mergeConfig(nil, loaded)
+	if err := ResolveSecrets(loaded); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaded = loaded
+	l.unresolved = unresolved
+	l.config = mergeConfig(loaded, l.applied)
+	return nil
+}
+
+// GetConfig returns a copy of the config
+func (l *layers) GetConfig() map[string]interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return mergeConfig(nil, l.config)
+}
+
+// Unresolved returns a copy of the loaded config with its secret references unresolved, for HideSecrets
+func (l *layers) Unresolved() map[string]interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return mergeConfig(nil, l.unresolved)
+}
+
+// ApplyConfig merges config over the loaded documents and the environment; it is kept when they are
+// loaded again
+func (l *layers) ApplyConfig(config map[string]interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.applied = mergeConfig(l.applied, config)
+	l.config = mergeConfig(l.loaded, l.applied)
+	return nil
+}
+
+// Decode decodes the config into v, as encoding/json decodes a document
+func (l *layers) Decode(v interface{}) error {
+	data, err := json.Marshal(l.GetConfig())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// File is a ConfigPort reading JSON, YAML and TOML files (see ReadFile), layered over defaults and under
+// environment overrides
+type File struct {
+	layers
+	paths []string
+}
+
+// NewFile creates a config of the files at paths, later files overriding earlier ones. It is empty
+// until Load.
+func NewFile(paths ...string) *File {
+	return &File{layers: layers{envPrefix: DefaultEnvPrefix}, paths: paths}
+}
+
+// Paths returns the paths of the config files
+func (f *File) Paths() []string {
+	return f.paths
+}
+
+// Name describes where the config is read from
+func (f *File) Name() string {
+	return strings.Join(f.paths, ", ")
+}
+
+// Load reads the files and the environment overrides and resolves secret references (see ResolveSecrets).
+// When a file or a secret cannot be read, the config loaded before is kept.
+func (f *File) Load() error {
+	documents := make([]map[string]interface{}, 0, len(f.paths))
+	for _, path := range f.paths {
+		document, err := ReadFile(path)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, document)
+	}
+	return f.load(documents...)
+}
+
+// ReadFile reads a config file in the format of its extension (see Parse)
+func ReadFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Parse decodes a config document in the format of the extension of its name: YAML (.yaml, .yml), TOML
+// (.toml) or JSON (any other)
+func Parse(name string, data []byte) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	var raw interface{}
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		raw = jsonKeys(raw)
+	case ".toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, PositionError(data, err)
+		}
+		return config, nil
+	}
+	// Round-trip through JSON so values have the types decoded JSON has
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("config must be an object")
+	}
+	return config, nil
+}
+
+// jsonKeys converts the objects YAML decodes with non-string keys to objects JSON can encode
+func jsonKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonKeys(item)
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonKeys(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonKeys(item)
+		}
+	}
+	return value
+}
+
+// PositionError adds the line and column of a JSON syntax or type error in data
+func PositionError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("invalid JSON at line %d, column %d: %w", line, column, err)
+}
+
+// mergeConfig returns a copy of base with layer merged over it
+func mergeConfig(base, layer map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(layer))
+	for key, value := range base {
+		merged[key] = copyValue(value)
+	}
+	for key, value := range layer {
+		if object, ok := value.(map[string]interface{}); ok {
+			if under, ok := merged[key].(map[string]interface{}); ok {
+				merged[key] = mergeConfig(under, object)
+				continue
+			}
+		}
+		merged[key] = copyValue(value)
+	}
+	return merged
+}
+
+// copyValue deep-copies the objects and lists of a config value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return mergeConfig(nil, v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// applyEnv sets the values of the environment variables (NAME=value) starting with prefix in config
+func applyEnv(config map[string]interface{}, prefix string, environ []string) {
+	sort.Strings(environ)
+	for _, variable := range environ {
+		name, raw, _ := strings.Cut(variable, "=")
+		path, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		var segments []string
+		for _, segment := range strings.Split(path, "_") {
+			if segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		if len(segments) == 0 || !override(config, segments, value) {
+			log.Warn().Msgf("Ignoring environment variable %s: it matches no config entry", name)
+			continue
+		}
+		log.Info().Msgf("Config overridden by environment variable %s", name)
+	}
+}
+
+// override sets value at the path of segments under node, reporting whether the path was found. A key
+// missing from an object is added, unless part of the path names another key; items missing from a list
+// are not.
+func override(node interface{}, segments []string, value interface{}) bool {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		matched := false
+		for i := len(segments); i > 0; i-- {
+			key := strings.ToLower(strings.Join(segments[:i], "_"))
+			child, ok := n[key]
+			if !ok {
+				continue
+			}
+			if i == len(segments) {
+				n[key] = value
+				return true
+			}
+			if override(child, segments[i:], value) {
+				return true
+			}
+			matched = true
+		}
+		if matched {
+			return false
+		}
+		n[strings.ToLower(strings.Join(segments, "_"))] = value
+		return true
+	case []interface{}:
+		for i := len(segments); i > 0; i-- {
+			item, ok := listItem(n, strings.Join(segments[:i], "_"))
+			if !ok {
+				continue
+			}
+			if i == len(segments) {
+				n[item] = value
+				return true
+			}
+			if override(n[item], segments[i:], value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listItem finds the item of a list at a position or with a name (compared without case)
+func listItem(list []interface{}, segment string) (int, bool) {
+	if i, err := strconv.Atoi(segment); err == nil {
+		return i, i >= 0 && i < len(list)
+	}
+	for i, item := range list {
+		if object, ok := item.(map[string]interface{}); ok {
+			if name, ok := object["name"].(string); ok && strings.EqualFold(name, segment) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
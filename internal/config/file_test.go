@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_Layers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	local := filepath.Join(dir, "local.yaml")
+	assert.NoError(t, os.WriteFile(base, []byte(`{"search": {"max_results": 10, "timeout": "1s"}, "indexes": [{"name": "docs"}]}`), 0644))
+	assert.NoError(t, os.WriteFile(local, []byte("search:\n  max_results: 20\nlimits:\n  burst: 5\n"), 0644))
+
+	file := NewFile(base, local)
+	assert.Empty(t, file.GetConfig())
+	file.SetDefaults(map[string]interface{}{
+		"search": map[string]interface{}{"max_results": 5.0, "cache": true},
+		"apis":   []interface{}{"rest"},
+	})
+	assert.NoError(t, file.Load())
+
+	cfg := file.GetConfig()
+	assert.Equal(t, map[string]interface{}{"max_results": 20.0, "timeout": "1s", "cache": true}, cfg["search"])
+	assert.Equal(t, map[string]interface{}{"burst": 5.0}, cfg["limits"], "YAML values decode as JSON values do")
+	assert.Equal(t, []interface{}{"rest"}, cfg["apis"])
+
+	// Runtime changes are kept across loads; copies returned do not change the config
+	assert.NoError(t, file.ApplyConfig(map[string]interface{}{"search": map[string]interface{}{"timeout": "2s"}}))
+	cfg["search"].(map[string]interface{})["timeout"] = "changed"
+	assert.NoError(t, file.Load())
+	var decoded struct {
+		Search struct {
+			MaxResults int    `json:"max_results"`
+			Timeout    string `json:"timeout"`
+		} `json:"search"`
+	}
+	assert.NoError(t, file.Decode(&decoded))
+	assert.Equal(t, 20, decoded.Search.MaxResults)
+	assert.Equal(t, "2s", decoded.Search.Timeout)
+
+	// A broken file keeps the config loaded before
+	assert.NoError(t, os.WriteFile(base, []byte("{\n  \"search\": }"), 0644))
+	err := file.Load()
+	assert.ErrorContains(t, err, "invalid JSON at line 2")
+	assert.Equal(t, 20.0, file.GetConfig()["search"].(map[string]interface{})["max_results"])
+}
+
+func TestFile_EnvOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"search": {"max_results": 10},
+		"indexes": [{"name": "docs", "config": {}}, {"name": "logs", "config": {"read_only": false}}]
+	}`), 0644))
+	t.Setenv("BITSCOUT_SEARCH_MAX_RESULTS", "50")
+	t.Setenv("BITSCOUT_INDEXES_DOCS_CONFIG_DB_PATH", "./docs.db")
+	t.Setenv("BITSCOUT_INDEXES_1_CONFIG_READ_ONLY", "true")
+	t.Setenv("BITSCOUT_INDEXES_MISSING_CONFIG_DB_PATH", "ignored")
+	t.Setenv("BITSCOUT_WATCH", `{"interval": "1m"}`)
+
+	file := NewFile(path)
+	assert.NoError(t, file.Load())
+	cfg := file.GetConfig()
+	assert.Equal(t, map[string]interface{}{"max_results": 50.0}, cfg["search"])
+	indexes := cfg["indexes"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"db_path": "./docs.db"}, indexes[0].(map[string]interface{})["config"])
+	assert.Equal(t, map[string]interface{}{"read_only": true}, indexes[1].(map[string]interface{})["config"])
+	assert.Equal(t, map[string]interface{}{"interval": "1m"}, cfg["watch"])
+	assert.Len(t, cfg, 3)
+
+	file.SetEnvPrefix("")
+	assert.NoError(t, file.Load())
+	assert.Equal(t, map[string]interface{}{"max_results": 10.0}, file.GetConfig()["search"])
+}
+
+func TestReadFile_Formats(t *testing.T) {
+	// The example starter configs hold the same config in each format
+	expected, err := ReadFile("../../config/starter_config.json")
+	assert.NoError(t, err)
+	delete(expected, "$schema")
+	for _, path := range []string{"../../config/starter_config.yaml", "../../config/starter_config.toml"} {
+		cfg, err := ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, cfg, path)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("- not\n- an object\n"), 0644))
+	_, err = ReadFile(path)
+	assert.ErrorContains(t, err, "config must be an object")
+}
@@ -0,0 +1,316 @@
+package config
+
+/*
+A ConfigPort reading its document from a key of etcd (v3, through its JSON gateway) or Consul KV, for
+fleets of nodes configured centrally. The document's format is picked by the extension of the key, as
+for files, and it is layered over defaults and under environment overrides the same way. Watch long-polls
+the key (an etcd watch or a Consul blocking query) and signals each change, for hot reloads.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	remoteTimeout   = 10 * time.Second // Deadline of a read of the key
+	consulWaitLimit = "5m"             // Longest a Consul blocking query waits for a change
+)
+
+// errKeyMissing is returned for keys that are not in the store
+var errKeyMissing = errors.New("key not found")
+
+// kvStore reads a key of a key-value store
+type kvStore interface {
+	// get returns the value of key and its version, which changes with each write of the key
+	get(ctx context.Context, key string) ([]byte, uint64, error)
+	// wait returns once key may have been written after version, or ctx ends
+	wait(ctx context.Context, key string, version uint64) error
+}
+
+// Remote is a ConfigPort reading a document from a key of etcd or Consul
+type Remote struct {
+	layers
+	source  string // URL without credentials
+	store   kvStore
+	key     string
+	err     error         // Set when the URL is invalid
+	version atomic.Uint64 // Version of the document loaded last
+}
+
+// IsRemote reports whether source is the URL of a remote config rather than a file path
+func IsRemote(source string) bool {
+	scheme, _, ok := strings.Cut(source, "://")
+	return ok && (strings.HasPrefix(scheme, "etcd") || strings.HasPrefix(scheme, "consul"))
+}
+
+// NewRemote creates a config of the key of a URL: etcd://host:2379/<key> or consul://host:8500/<key>
+// (etcd+https and consul+https for TLS). A Consul ACL token is read from the token query parameter or
+// CONSUL_HTTP_TOKEN. It is empty until Load.
+func NewRemote(source string) *Remote {
+	r := &Remote{layers: layers{envPrefix: DefaultEnvPrefix}, source: source}
+	parsed, err := url.Parse(source)
+	if err != nil {
+		r.err = fmt.Errorf("invalid remote config URL: %w", err)
+		return r
+	}
+	r.key = strings.TrimPrefix(parsed.Path, "/")
+	token := parsed.Query().Get("token")
+	parsed.RawQuery = ""
+	r.source = parsed.String()
+
+	kind, transport, _ := strings.Cut(parsed.Scheme, "+")
+	if transport == "" {
+		transport = "http"
+	}
+	base := transport + "://" + parsed.Host
+	client := &http.Client{}
+	switch {
+	case r.key == "" || parsed.Host == "":
+		r.err = fmt.Errorf("remote config URL %s needs a host and a key", r.source)
+	case transport != "http" && transport != "https":
+		r.err = fmt.Errorf("remote config URL %s: unknown transport %s (want http or https)", r.source, transport)
+	case kind == "etcd":
+		r.store = &etcdStore{base: base, client: client}
+	case kind == "consul":
+		if token == "" {
+			token = os.Getenv("CONSUL_HTTP_TOKEN")
+		}
+		r.store = &consulStore{base: base, token: token, client: client}
+	default:
+		r.err = fmt.Errorf("remote config URL %s: unknown store %s (want etcd or consul)", r.source, kind)
+	}
+	return r
+}
+
+// Name describes where the config is read from
+func (r *Remote) Name() string {
+	return r.source
+}
+
+// Load reads the document of the key and the environment overrides and resolves secret references. When
+// the key or a secret cannot be read, the config loaded before is kept.
+func (r *Remote) Load() error {
+	if r.err != nil {
+		return r.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	data, version, err := r.store.get(ctx, r.key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.source, err)
+	}
+	document, err := Parse(r.key, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.source, err)
+	}
+	if err := r.load(document); err != nil {
+		return fmt.Errorf("%s: %w", r.source, err)
+	}
+	r.version.Store(version)
+	return nil
+}
+
+// Watch sends on the returned channel whenever the key is written after the document loaded last,
+// retrying after retry (DefaultWatchInterval if not positive) when the store cannot be reached. The
+// channel is closed when ctx is cancelled.
+func (r *Remote) Watch(ctx context.Context, retry time.Duration) <-chan struct{} {
+	if retry <= 0 {
+		retry = DefaultWatchInterval
+	}
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		if r.err != nil {
+			<-ctx.Done()
+			return
+		}
+		version := r.version.Load()
+		for ctx.Err() == nil {
+			err := r.store.wait(ctx, r.key, version)
+			var latest uint64
+			if err == nil {
+				getCtx, cancel := context.WithTimeout(ctx, remoteTimeout)
+				_, latest, err = r.store.get(getCtx, r.key)
+				cancel()
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Warn().Err(err).Msgf("Watching config %s failed, retrying in %s", r.source, retry)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retry):
+				}
+				continue
+			}
+			if latest == version {
+				continue
+			}
+			version = latest
+			log.Info().Msgf("Config %s changed", r.source)
+			// Coalesce changes that arrive while a reload is still pending
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes
+}
+
+// consulStore reads keys through the Consul KV HTTP API
+type consulStore struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func (c *consulStore) get(ctx context.Context, key string) ([]byte, uint64, error) {
+	entry, _, err := c.entry(ctx, key, url.Values{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return entry.Value, entry.ModifyIndex, nil
+}
+
+// wait runs a blocking query, which Consul answers once the key is written after version or its wait
+// limit passes
+func (c *consulStore) wait(ctx context.Context, key string, version uint64) error {
+	_, _, err := c.entry(ctx, key, url.Values{"index": {strconv.FormatUint(version, 10)}, "wait": {consulWaitLimit}})
+	if errors.Is(err, errKeyMissing) {
+		return nil // Deleted, which get reports
+	}
+	return err
+}
+
+// consulEntry is a key of a Consul KV response
+type consulEntry struct {
+	Value       []byte // Base64 in JSON
+	ModifyIndex uint64
+}
+
+func (c *consulStore) entry(ctx context.Context, key string, query url.Values) (consulEntry, uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/v1/kv/"+key+"?"+query.Encode(), nil)
+	if err != nil {
+		return consulEntry{}, 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return consulEntry{}, 0, fmt.Errorf("consul: %w", err)
+	}
+	defer resp.Body.Close()
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return consulEntry{}, index, errKeyMissing
+	case resp.StatusCode != http.StatusOK:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return consulEntry{}, index, fmt.Errorf("consul: %s (HTTP %d)", strings.TrimSpace(string(message)), resp.StatusCode)
+	}
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil || len(entries) == 0 {
+		return consulEntry{}, index, fmt.Errorf("consul: invalid response for key %s", key)
+	}
+	return entries[0], index, nil
+}
+
+// etcdStore reads keys through the JSON gateway of the etcd v3 API
+type etcdStore struct {
+	base   string
+	client *http.Client
+}
+
+// etcdKV is a key of an etcd range response; int64 fields are strings in JSON
+type etcdKV struct {
+	Value       []byte `json:"value"` // Base64 in JSON
+	ModRevision string `json:"mod_revision"`
+}
+
+func (e *etcdStore) get(ctx context.Context, key string) ([]byte, uint64, error) {
+	resp, err := e.post(ctx, "/v3/kv/range", map[string]interface{}{"key": []byte(key)})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Kvs []etcdKV `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, fmt.Errorf("etcd: invalid response for key %s: %w", key, err)
+	}
+	if len(result.Kvs) == 0 {
+		return nil, 0, errKeyMissing
+	}
+	revision, _ := strconv.ParseUint(result.Kvs[0].ModRevision, 10, 64)
+	return result.Kvs[0].Value, revision, nil
+}
+
+// wait watches the key from the revision after version and returns at its first event
+func (e *etcdStore) wait(ctx context.Context, key string, version uint64) error {
+	resp, err := e.post(ctx, "/v3/watch", map[string]interface{}{
+		"create_request": map[string]interface{}{"key": []byte(key), "start_revision": strconv.FormatUint(version+1, 10)},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Canceled bool              `json:"canceled"`
+				Events   []json.RawMessage `json:"events"`
+			} `json:"result"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("etcd: watch of key %s ended: %w", key, err)
+		}
+		if len(message.Result.Events) > 0 || message.Result.Canceled {
+			return nil
+		}
+	}
+}
+
+func (e *etcdStore) post(ctx context.Context, path string, request interface{}) (*http.Response, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.base+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("etcd: %s (HTTP %d)", strings.TrimSpace(string(message)), resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKV is a key-value store whose writes wake up the requests waiting for them
+type fakeKV struct {
+	mu      sync.Mutex
+	value   []byte
+	version uint64
+	changed chan struct{} // Closed by the next write
+}
+
+func newFakeKV(value string) *fakeKV {
+	return &fakeKV{value: []byte(value), version: 1, changed: make(chan struct{})}
+}
+
+func (kv *fakeKV) put(value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.value = []byte(value)
+	kv.version++
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+}
+
+func (kv *fakeKV) read() ([]byte, uint64, <-chan struct{}) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.value, kv.version, kv.changed
+}
+
+// consulServer serves the key config.yaml as Consul does, with blocking queries
+func consulServer(t *testing.T, kv *fakeKV) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/bitscout/config.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		value, version, changed := kv.read()
+		if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index >= version {
+			select {
+			case <-changed:
+				value, version, _ = kv.read()
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(version, 10))
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"Key": "bitscout/config.yaml", "Value": value, "ModifyIndex": version}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// etcdServer serves the key config.json as the etcd JSON gateway does, with watches
+func etcdServer(t *testing.T, kv *fakeKV) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Key    []byte `json:"key"`
+			Create struct {
+				Key      []byte `json:"key"`
+				Revision string `json:"start_revision"`
+			} `json:"create_request"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		value, version, changed := kv.read()
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			assert.Equal(t, "config.json", string(request.Key))
+			json.NewEncoder(w).Encode(map[string]interface{}{"kvs": []map[string]interface{}{{"value": value, "mod_revision": strconv.FormatUint(version, 10)}}})
+		case "/v3/watch":
+			assert.Equal(t, "config.json", string(request.Create.Key))
+			fmt.Fprintln(w, `{"result":{"created":true}}`)
+			w.(http.Flusher).Flush()
+			if start, _ := strconv.ParseUint(request.Create.Revision, 10, 64); start > version {
+				select {
+				case <-changed:
+				case <-r.Context().Done():
+					return
+				}
+			}
+			fmt.Fprintln(w, `{"result":{"events":[{"type":"PUT"}]}}`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// awaitChange waits for a signal of a watch
+func awaitChange(t *testing.T, changes <-chan struct{}) {
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+}
+
+func TestRemote_Consul(t *testing.T) {
+	kv := newFakeKV("search:\n  max_results: 10\n")
+	server := consulServer(t, kv)
+	t.Setenv("BITSCOUT_SEARCH_TIMEOUT", "2s")
+
+	remote := NewRemote("consul://" + strings.TrimPrefix(server.URL, "http://") + "/bitscout/config.yaml?token=secret")
+	assert.Equal(t, "consul://"+strings.TrimPrefix(server.URL, "http://")+"/bitscout/config.yaml", remote.Name(), "the token is not shown")
+	assert.NoError(t, remote.Load())
+	assert.Equal(t, map[string]interface{}{"max_results": 10.0, "timeout": "2s"}, remote.GetConfig()["search"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := remote.Watch(ctx, 10*time.Millisecond)
+	kv.put("search:\n  max_results: 20\n")
+	awaitChange(t, changes)
+	assert.NoError(t, remote.Load())
+	assert.Equal(t, 20.0, remote.GetConfig()["search"].(map[string]interface{})["max_results"])
+	cancel()
+	for range changes {
+	}
+
+	remote = NewRemote("consul://" + strings.TrimPrefix(server.URL, "http://") + "/bitscout/missing.yaml?token=secret")
+	assert.ErrorIs(t, remote.Load(), errKeyMissing)
+}
+
+func TestRemote_Etcd(t *testing.T) {
+	kv := newFakeKV(`{"indexes": [{"name": "docs"}]}`)
+	server := etcdServer(t, kv)
+
+	remote := NewRemote("etcd://" + strings.TrimPrefix(server.URL, "http://") + "/config.json")
+	assert.NoError(t, remote.Load())
+	var decoded struct {
+		Indexes []struct{ Name string } `json:"indexes"`
+	}
+	assert.NoError(t, remote.Decode(&decoded))
+	assert.Equal(t, "docs", decoded.Indexes[0].Name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := remote.Watch(ctx, 10*time.Millisecond)
+	kv.put(`{"indexes": [{"name": "logs"}]}`)
+	awaitChange(t, changes)
+	assert.NoError(t, remote.Load())
+	assert.NoError(t, remote.Decode(&decoded))
+	assert.Equal(t, "logs", decoded.Indexes[0].Name)
+}
+
+func TestNewRemote_Invalid(t *testing.T) {
+	assert.True(t, IsRemote("etcd+https://10.0.0.1:2379/bitscout.yaml"))
+	assert.False(t, IsRemote("config/starter_config.json"))
+	for source, message := range map[string]string{
+		"consul://localhost:8500":         "needs a host and a key",
+		"redis://localhost:6379/config":   "unknown store redis",
+		"etcd+ftp://localhost:2379/key":   "unknown transport ftp",
+		"consul://local host:8500/config": "invalid remote config URL",
+	} {
+		assert.ErrorContains(t, NewRemote(source).Load(), message, source)
+	}
+}
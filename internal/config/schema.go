@@ -0,0 +1,253 @@
+package config
+
+/*
+A subset of JSON Schema for validating configuration files. Schemas marshal to standard JSON Schema
+documents, so editors can use them for completion and inline errors as well.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaDraft is the JSON Schema dialect of marshalled schemas
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// FormatDuration is the format of strings holding a Go duration (e.g. "10m", "1h30m")
+const FormatDuration = "duration"
+
+// Schema describes the expected shape of a configuration value. Validate supports the keywords type,
+// enum, const, format (duration), minimum, exclusiveMinimum, maximum, properties, required, additionalProperties,
+// items, allOf and if/then; an if schema only matches when it produces no problems.
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+}
+
+// Types lists the JSON types a value may have: "object", "array", "string", "number", "integer",
+// "boolean" or "null". A single type marshals as a string.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Problem is a value that does not match its schema
+type Problem struct {
+	Path    string // Location of the value, e.g. "loaders[0].config.root" ("" for the document itself)
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Float64 returns a pointer to f, for the Minimum, ExclusiveMinimum and Maximum keywords
+func Float64(f float64) *float64 {
+	return &f
+}
+
+// Closed marks an object schema as rejecting keys it does not list in Properties
+func (s *Schema) Closed() *Schema {
+	closed := false
+	s.AdditionalProperties = &closed
+	return s
+}
+
+// Validate checks a value decoded from JSON against the schema and returns its problems. Object keys
+// are visited in sorted order, so the problems of a value are always reported in the same order.
+func (s *Schema) Validate(value interface{}) []Problem {
+	var problems []Problem
+	s.validate("", value, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, value interface{}, problems *[]Problem) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		report("must be %s, got %s", strings.Join(s.Type, " or "), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !contains(s.Enum, value) {
+		report("must be one of %s, got %s", formatValues(s.Enum), formatValue(value))
+	}
+	if s.Const != nil && !equal(s.Const, value) {
+		report("must be %s, got %s", formatValue(s.Const), formatValue(value))
+	}
+	if str, ok := value.(string); ok && s.Format == FormatDuration {
+		if _, err := time.ParseDuration(str); err != nil {
+			report("must be a duration such as \"30s\" or \"10m\", got %q", str)
+		}
+	}
+	if number, ok := value.(float64); ok {
+		if s.Minimum != nil && number < *s.Minimum {
+			report("must be at least %v, got %v", *s.Minimum, number)
+		}
+		if s.ExclusiveMinimum != nil && number <= *s.ExclusiveMinimum {
+			report("must be greater than %v, got %v", *s.ExclusiveMinimum, number)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			report("must be at most %v, got %v", *s.Maximum, number)
+		}
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		s.validateObject(path, object, problems)
+	}
+	if array, ok := value.([]interface{}); ok && s.Items != nil {
+		for i, item := range array {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+		}
+	}
+	for _, sub := range s.AllOf {
+		sub.validate(path, value, problems)
+	}
+	if s.If != nil && s.Then != nil {
+		var mismatches []Problem
+		s.If.validate(path, value, &mismatches)
+		if len(mismatches) == 0 {
+			s.Then.validate(path, value, problems)
+		}
+	}
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}, problems *[]Problem) {
+	for _, key := range s.Required {
+		if _, ok := object[key]; !ok {
+			*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("missing required key %q", key)})
+		}
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := joinPath(path, key)
+		if property, ok := s.Properties[key]; ok {
+			property.validate(child, object[key], problems)
+		} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			*problems = append(*problems, Problem{Path: child, Message: fmt.Sprintf("unknown key (known keys: %s)", strings.Join(s.keys(), ", "))})
+		}
+	}
+}
+
+// keys returns the sorted property names of an object schema
+func (s *Schema) keys() []string {
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Schema) matchesType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, expected := range s.Type {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type of a value decoded from JSON; whole numbers are "integer"
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// equal compares values decoded from JSON with values written in Go, e.g. the int 1 with the float64 1
+func equal(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func formatValues(values []interface{}) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = formatValue(value)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	var value interface{}
+	assert.NoError(t, json.Unmarshal([]byte(data), &value))
+	return value
+}
+
+func loaderSchema() *Schema {
+	return &Schema{
+		Type: Types{"object"},
+		Properties: map[string]*Schema{
+			"loaders": {Type: Types{"array"}, Items: (&Schema{
+				Type:     Types{"object"},
+				Required: []string{"name", "type"},
+				Properties: map[string]*Schema{
+					"name":     {Type: Types{"string"}},
+					"type":     {Type: Types{"string"}},
+					"config":   {Type: Types{"object"}},
+					"interval": {Type: Types{"string"}, Format: FormatDuration},
+					"workers":  {Type: Types{"integer"}, Minimum: Float64(1)},
+				},
+				If: &Schema{
+					Required:   []string{"type"},
+					Properties: map[string]*Schema{"type": {Const: "MboxLoader"}},
+				},
+				Then: &Schema{Properties: map[string]*Schema{
+					"config": (&Schema{
+						Required:   []string{"path"},
+						Properties: map[string]*Schema{"path": {Type: Types{"string"}}},
+					}).Closed(),
+				}},
+			}).Closed()},
+		},
+	}
+}
+
+func TestSchema_ValidConfigHasNoProblems(t *testing.T) {
+	value := decode(t, `{"loaders": [
+		{"name": "fs", "type": "FilesystemLoader", "config": {"root": "."}, "interval": "10m", "workers": 2},
+		{"name": "mail", "type": "MboxLoader", "config": {"path": "mail.mbox"}}
+	]}`)
+	assert.Empty(t, loaderSchema().Validate(value))
+}
+
+func TestSchema_ReportsProblemsWithPaths(t *testing.T) {
+	value := decode(t, `{"loaders": [
+		{"name": 3, "type": "FilesystemLoader", "interval": "often", "workers": 0, "extra": true},
+		{"name": "mail", "type": "MboxLoader", "config": {"file": "mail.mbox"}}
+	]}`)
+	var got []string
+	for _, problem := range loaderSchema().Validate(value) {
+		got = append(got, problem.String())
+	}
+	assert.Equal(t, []string{
+		"loaders[0].extra: unknown key (known keys: config, interval, name, type, workers)",
+		`loaders[0].interval: must be a duration such as "30s" or "10m", got "often"`,
+		"loaders[0].name: must be string, got integer",
+		"loaders[0].workers: must be at least 1, got 0",
+		`loaders[1].config: missing required key "path"`,
+		"loaders[1].config.file: unknown key (known keys: path)",
+	}, got)
+}
+
+func TestSchema_EnumAndNumberTypes(t *testing.T) {
+	schema := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{
+		"metric": {Type: Types{"string"}, Enum: []interface{}{"cosine", "dot"}},
+		"weight": {Type: Types{"number"}, ExclusiveMinimum: Float64(0)},
+		"k":      {Type: Types{"integer"}},
+		"ratio":  {Type: Types{"number"}, Maximum: Float64(1)},
+	}}
+
+	assert.Empty(t, schema.Validate(decode(t, `{"metric": "dot", "weight": 2, "k": 5, "ratio": 1}`)))
+	problems := schema.Validate(decode(t, `{"metric": "l2", "weight": 0, "k": 1.5, "ratio": 1.5}`))
+	assert.Equal(t, []Problem{
+		{Path: "k", Message: "must be integer, got number"},
+		{Path: "metric", Message: `must be one of "cosine", "dot", got "l2"`},
+		{Path: "ratio", Message: "must be at most 1, got 1.5"},
+		{Path: "weight", Message: "must be greater than 0, got 0"},
+	}, problems)
+}
+
+func TestSchema_MarshalsAsJSONSchema(t *testing.T) {
+	schema := (&Schema{
+		Draft:      SchemaDraft,
+		Type:       Types{"object"},
+		Properties: map[string]*Schema{"tags": {Type: Types{"string", "array"}}},
+	}).Closed()
+
+	data, err := json.Marshal(schema)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"tags": {"type": ["string", "array"]}},
+		"additionalProperties": false
+	}`, string(data))
+
+	var decoded Schema
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, Types{"object"}, decoded.Type)
+	assert.Equal(t, Types{"string", "array"}, decoded.Properties["tags"].Type)
+}
@@ -0,0 +1,225 @@
+package config
+
+/*
+Secret references in config values, resolved when a config is loaded so credentials need not be stored in
+the config itself:
+
+	${env:NAME}          the environment variable NAME
+	${file:PATH}         the content of the file at PATH, without trailing newlines (e.g. /run/secrets/token)
+	${vault:PATH#KEY}    the field KEY of the Vault secret at PATH (KV version 1 or 2), read from VAULT_ADDR
+	                     with VAULT_TOKEN
+
+A reference may be the whole value or part of it ("Bearer ${env:TOKEN}"); $${ is a literal ${.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVaultAddr = "http://127.0.0.1:8200"
+	vaultTimeout     = 10 * time.Second // Deadline of a read of a Vault secret
+)
+
+// secretPattern matches secret references and the escaped $${
+var secretPattern = regexp.MustCompile(`\$\$\{|\$\{(env|file|vault):([^}]*)\}`)
+
+// secretResolver resolves the references of one config, reading each Vault secret once
+type secretResolver struct {
+	vault map[string]map[string]interface{} // Vault secrets read, by path
+}
+
+// ResolveSecrets replaces the secret references in the string values of config, in place. The path of the
+// first value that cannot be resolved is in the error.
+func ResolveSecrets(config map[string]interface{}) error {
+	r := &secretResolver{vault: make(map[string]map[string]interface{})}
+	for key, value := range config {
+		resolved, err := r.resolveValue(key, value)
+		if err != nil {
+			return err
+		}
+		config[key] = resolved
+	}
+	return nil
+}
+
+// HideSecrets replaces the values of config resolved from secret references with the references, in place,
+// so the config can be shown without its secrets. unresolved is the config before ResolveSecrets; values
+// at paths where it has no reference are kept.
+func HideSecrets(config, unresolved map[string]interface{}) {
+	for key, value := range config {
+		config[key] = hideSecrets(value, unresolved[key])
+	}
+}
+
+// hideSecrets returns value, or its reference in unresolved when it was resolved from one
+func hideSecrets(value, unresolved interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if reference, ok := unresolved.(string); ok && hasSecretReference(reference) {
+			return reference
+		}
+	case map[string]interface{}:
+		references, _ := unresolved.(map[string]interface{})
+		for key, item := range v {
+			v[key] = hideSecrets(item, references[key])
+		}
+	case []interface{}:
+		references, _ := unresolved.([]interface{})
+		for i, item := range v {
+			var reference interface{}
+			if i < len(references) {
+				reference = references[i]
+			}
+			v[i] = hideSecrets(item, reference)
+		}
+	}
+	return value
+}
+
+// hasSecretReference reports whether value holds a secret reference, besides escaped $${
+func hasSecretReference(value string) bool {
+	for _, groups := range secretPattern.FindAllStringSubmatch(value, -1) {
+		if groups[1] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveValue resolves the references in value, found at path
+func (r *secretResolver) resolveValue(path string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(path, v)
+	case map[string]interface{}:
+		for key, item := range v {
+			resolved, err := r.resolveValue(path+"."+key, item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, item := range v {
+			resolved, err := r.resolveValue(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return value, nil
+}
+
+func (r *secretResolver) resolveString(path, value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var err error
+	resolved := secretPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if err != nil {
+			return match
+		}
+		if match == "$${" {
+			return "${"
+		}
+		groups := secretPattern.FindStringSubmatch(match)
+		var secret string
+		if secret, err = r.secret(groups[1], groups[2]); err != nil {
+			err = fmt.Errorf("config %s: secret %s: %w", path, match, err)
+		}
+		return secret
+	})
+	return resolved, err
+}
+
+// secret reads the secret of a reference of kind env, file or vault
+func (r *secretResolver) secret(kind, ref string) (string, error) {
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		path, key, ok := strings.Cut(ref, "#")
+		if !ok || path == "" || key == "" {
+			return "", fmt.Errorf("want ${vault:<path>#<key>}")
+		}
+		data, cached := r.vault[path]
+		if !cached {
+			var err error
+			if data, err = r.readVault(path); err != nil {
+				return "", err
+			}
+			r.vault[path] = data
+		}
+		value, ok := data[key]
+		if !ok {
+			return "", fmt.Errorf("vault secret %s has no key %s", path, key)
+		}
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	}
+}
+
+// readVault reads the fields of the Vault secret at path, through the HTTP API
+func (r *secretResolver) readVault(path string) (map[string]interface{}, error) {
+	addr, ok := os.LookupEnv("VAULT_ADDR")
+	if !ok || addr == "" {
+		addr = defaultVaultAddr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := os.LookupEnv("VAULT_TOKEN"); ok {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace, ok := os.LookupEnv("VAULT_NAMESPACE"); ok {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("vault: %s (HTTP %d)", strings.TrimSpace(string(message)), resp.StatusCode)
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("vault: invalid response for %s: %w", path, err)
+	}
+	// KV version 2 nests the fields under data, next to their metadata
+	if fields, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			return fields, nil
+		}
+	}
+	return secret.Data, nil
+}
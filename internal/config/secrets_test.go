@@ -0,0 +1,120 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vaultServer serves a KV version 2 secret at secret/data/bitscout and a version 1 one at kv/s3
+func vaultServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/bitscout":
+			w.Write([]byte(`{"data": {"data": {"api_key": "sk-vault", "port": 9200}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/s3":
+			w.Write([]byte(`{"data": {"secret_key": "s3-secret"}}`))
+		default:
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveSecrets(t *testing.T) {
+	server := vaultServer(t)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("S3_KEY", "AKIA123")
+	token := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(token, []byte("file-token\n"), 0600))
+
+	cfg := map[string]interface{}{
+		"loaders": []interface{}{map[string]interface{}{"config": map[string]interface{}{
+			"access_key": "${env:S3_KEY}",
+			"secret_key": "${vault:kv/s3#secret_key}",
+		}}},
+		"embedding": map[string]interface{}{
+			"api_key": "${vault:secret/data/bitscout#api_key}",
+			"port":    "${vault:secret/data/bitscout#port}",
+			"headers": []interface{}{"Bearer ${file:" + token + "}", "$${env:S3_KEY}"},
+			"timeout": 5.0,
+		},
+	}
+	assert.NoError(t, ResolveSecrets(cfg))
+	assert.Equal(t, map[string]interface{}{"access_key": "AKIA123", "secret_key": "s3-secret"},
+		cfg["loaders"].([]interface{})[0].(map[string]interface{})["config"])
+	assert.Equal(t, map[string]interface{}{
+		"api_key": "sk-vault",
+		"port":    "9200",
+		"headers": []interface{}{"Bearer file-token", "${env:S3_KEY}"},
+		"timeout": 5.0,
+	}, cfg["embedding"])
+
+	for ref, message := range map[string]string{
+		"${env:MISSING_SECRET}":            "config key: secret ${env:MISSING_SECRET}: environment variable MISSING_SECRET is not set",
+		"${file:/nonexistent/token}":       "no such file",
+		"${vault:kv/s3}":                   "want ${vault:<path>#<key>}",
+		"${vault:kv/s3#access_key}":        "vault secret kv/s3 has no key access_key",
+		"${vault:kv/missing#secret_key}":   "HTTP 404",
+		"prefix ${env:MISSING_SECRET} end": "environment variable MISSING_SECRET is not set",
+	} {
+		assert.ErrorContains(t, ResolveSecrets(map[string]interface{}{"key": ref}), message, ref)
+	}
+}
+
+func TestFile_Secrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("search:\n  api_key: ${env:SEARCH_KEY}\n"), 0644))
+	t.Setenv("SEARCH_KEY", "first")
+
+	file := NewFile(path)
+	assert.NoError(t, file.Load())
+	assert.Equal(t, "first", file.GetConfig()["search"].(map[string]interface{})["api_key"])
+
+	// Secrets are read again on each load, and one that cannot be keeps the config loaded before
+	t.Setenv("SEARCH_KEY", "rotated")
+	assert.NoError(t, file.Load())
+	assert.Equal(t, "rotated", file.GetConfig()["search"].(map[string]interface{})["api_key"])
+	os.Unsetenv("SEARCH_KEY")
+	assert.ErrorContains(t, file.Load(), "config search.api_key: secret ${env:SEARCH_KEY}")
+	assert.Equal(t, "rotated", file.GetConfig()["search"].(map[string]interface{})["api_key"])
+}
+
+func TestHideSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`auth:
+  api_keys:
+    - name: ci
+      key: ${env:CI_KEY}
+    - name: local
+      key: change-me
+  jwt:
+    secret: prefix-${env:JWT_SECRET}
+    issuer: $${env:ISSUER}
+`), 0644))
+	t.Setenv("CI_KEY", "ci-secret")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+
+	file := NewFile(path)
+	assert.NoError(t, file.Load())
+	cfg := file.GetConfig()
+	HideSecrets(cfg, file.Unresolved())
+	assert.Equal(t, map[string]interface{}{
+		"api_keys": []interface{}{
+			map[string]interface{}{"name": "ci", "key": "${env:CI_KEY}"},
+			map[string]interface{}{"name": "local", "key": "change-me"},
+		},
+		"jwt": map[string]interface{}{"secret": "prefix-${env:JWT_SECRET}", "issuer": "${env:ISSUER}"},
+	}, cfg["auth"])
+	assert.Equal(t, "ci-secret", file.GetConfig()["auth"].(map[string]interface{})["api_keys"].([]interface{})[0].(map[string]interface{})["key"])
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_TOML(t *testing.T) {
+	doc, err := Parse("config.toml", []byte(`
+title = "bit-scout"
+search.max_results = 1_000
+ratio = 0.5
+
+[apis.rest]
+listen = ":8081"
+
+[[indexes]]
+name = "docs"
+[indexes.config]
+dimensions = ["fileSize"]
+
+[[indexes]]
+name = "logs"
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"title":  "bit-scout",
+		"search": map[string]interface{}{"max_results": 1000.0},
+		"ratio":  0.5,
+		"apis":   map[string]interface{}{"rest": map[string]interface{}{"listen": ":8081"}},
+		"indexes": []interface{}{
+			map[string]interface{}{"name": "docs", "config": map[string]interface{}{"dimensions": []interface{}{"fileSize"}}},
+			map[string]interface{}{"name": "logs"},
+		},
+	}, doc)
+
+	_, err = Parse("config.toml", []byte("a = 1\na = 2"))
+	assert.ErrorContains(t, err, "invalid TOML")
+	assert.ErrorContains(t, err, "line 2")
+}
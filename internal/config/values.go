@@ -0,0 +1,154 @@
+package config
+
+/*
+Typed accessors for the loosely-typed config maps found in the starter config
+(values decoded from JSON arrive as string, float64, bool, []interface{}, or map[string]interface{}).
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// String reads an optional string value from a config map
+func String(config map[string]interface{}, key, fallback string) (string, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("config key %s must be a string, got %T", key, value)
+	}
+	return s, nil
+}
+
+// Strings reads an optional list of strings (a single string is accepted as a one-element list)
+func Strings(config map[string]interface{}, key string) ([]string, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("config key %s[%d] must be a string, got %T", key, i, item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("config key %s must be a list of strings, got %T", key, value)
+	}
+}
+
+// Bool reads an optional boolean value (the strings "true"/"false" are accepted too)
+func Bool(config map[string]interface{}, key string, fallback bool) (bool, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("config key %s must be a boolean: %w", key, err)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("config key %s must be a boolean, got %T", key, value)
+	}
+}
+
+// Int reads an optional integer value. JSON numbers (float64) must be whole.
+func Int(config map[string]interface{}, key string, fallback int) (int, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("config key %s must be a whole number, got %v", key, v)
+		}
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("config key %s must be an integer: %w", key, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("config key %s must be an integer, got %T", key, value)
+	}
+}
+
+// Float reads an optional numeric value
+func Float(config map[string]interface{}, key string, fallback float64) (float64, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("config key %s must be a number: %w", key, err)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("config key %s must be a number, got %T", key, value)
+	}
+}
+
+// Duration reads an optional duration written as a Go duration string (e.g. "10m", "1h30m")
+func Duration(config map[string]interface{}, key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("config key %s must be a duration string, got %T", key, value)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("config key %s must be a duration: %w", key, err)
+	}
+	return d, nil
+}
+
+// Map reads an optional nested object
+func Map(config map[string]interface{}, key string) (map[string]interface{}, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config key %s must be an object, got %T", key, value)
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultWatchInterval is how often Watch checks the config file for changes
+const DefaultWatchInterval = 5 * time.Second
+
+// Watch polls a config file and sends on the returned channel whenever its contents change.
+// Polling (rather than filesystem notifications) also catches editors that replace the file
+// and config maps that are swapped via symlinks. The channel is closed when ctx is cancelled.
+func Watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	changes := make(chan struct{}, 1)
+	last := fileDigest(path)
+
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				digest := fileDigest(path)
+				if bytes.Equal(digest, last) {
+					continue
+				}
+				last = digest
+				log.Info().Msgf("Config file %s changed", path)
+				// Coalesce changes that arrive while a reload is still pending
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes
+}
+
+// fileDigest hashes a file's contents; a missing or unreadable file hashes to nil
+func fileDigest(path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
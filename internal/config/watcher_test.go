@@ -0,0 +1,30 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch_SignalsOnContentChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := Watch(ctx, path, 5*time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"indexes": []}`), 0644))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected a change notification")
+	}
+
+	cancel()
+	for range changes {
+	}
+}
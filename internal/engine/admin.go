@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Runtime administration: used by the admin APIs to manage indexes and loaders without a restart
+ **/
+
+// IndexProvider instantiates an index of a given type, for indexes created at runtime
+type IndexProvider func(spec ports.IndexSpec) (ports.IndexPort, error)
+
+// SetIndexProvider enables creating indexes at runtime with provider
+func (e *EngineCore) SetIndexProvider(provider IndexProvider) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.indexProvider = provider
+}
+
+// SetSnapshotDir enables index snapshots, written as exports into dir
+func (e *EngineCore) SetSnapshotDir(dir string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshotDir = dir
+}
+
+// CreateIndex instantiates an index with the index provider and registers it. With cluster metadata,
+// the index is created on every node once the change is replicated.
+func (e *EngineCore) CreateIndex(spec ports.IndexSpec) error {
+	e.mu.RLock()
+	provider, metadata := e.indexProvider, e.metadata
+	_, exists := e.indexes[spec.Name]
+	_, aliased := e.aliases[spec.Name]
+	e.mu.RUnlock()
+	switch {
+	case provider == nil:
+		return fmt.Errorf("%w: creating indexes needs an index provider", ports.ErrNotSupported)
+	case spec.Name == "" || spec.Type == "":
+		return fmt.Errorf("%w: an index needs a name and a type", ports.ErrInvalid)
+	case exists:
+		return fmt.Errorf("%w: index %s already exists", ports.ErrConflict, spec.Name)
+	case aliased:
+		return fmt.Errorf("%w: %s is an alias", ports.ErrConflict, spec.Name)
+	}
+	if metadata != nil {
+		return metadata.Propose(ports.MetadataChange{Op: ports.MetadataCreateIndex, Index: spec})
+	}
+	return e.createIndex(provider, spec)
+}
+
+// createIndex instantiates an index with provider and registers it
+func (e *EngineCore) createIndex(provider IndexProvider, spec ports.IndexSpec) error {
+	index, err := provider(spec)
+	if err != nil {
+		return fmt.Errorf("%w: index %s: %w", ports.ErrInvalid, spec.Name, err)
+	}
+	e.mu.Lock()
+	if _, exists := e.indexes[spec.Name]; exists {
+		e.mu.Unlock()
+		index.Close()
+		return fmt.Errorf("%w: index %s already exists", ports.ErrConflict, spec.Name)
+	}
+	e.indexes[spec.Name] = index
+	if e.defaultIndex == "" {
+		e.defaultIndex = spec.Name
+	}
+	e.mu.Unlock()
+	log.Info().Msgf("Created %s index %s", spec.Type, spec.Name)
+	return nil
+}
+
+// DropIndex unregisters an index and closes it. Indexes created from the cluster metadata are dropped on
+// every node once the change is replicated.
+func (e *EngineCore) DropIndex(name string) error {
+	e.mu.RLock()
+	metadata, replicated := e.metadata, e.replicated[name]
+	err := e.unregistrable(name)
+	e.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if metadata != nil && replicated {
+		return metadata.Propose(ports.MetadataChange{Op: ports.MetadataDropIndex, Name: name})
+	}
+	return e.dropIndex(name)
+}
+
+// dropIndex unregisters an index and closes it
+func (e *EngineCore) dropIndex(name string) error {
+	index, err := e.UnregisterIndex(name)
+	if err != nil {
+		return err
+	}
+	if err := index.Close(); err != nil {
+		return fmt.Errorf("failed to close index %s: %w", name, err)
+	}
+	return nil
+}
+
+// FlushIndex writes an index's pending changes to storage
+func (e *EngineCore) FlushIndex(name string) error {
+	index, err := e.maintenanceIndex(name)
+	if err != nil {
+		return err
+	}
+	if err := index.Flush(); err != nil {
+		return fmt.Errorf("failed to flush index %s: %w", name, err)
+	}
+	log.Info().Msgf("Flushed index %s", name)
+	return nil
+}
+
+// OptimizeIndex optimizes an index for faster searches
+func (e *EngineCore) OptimizeIndex(name string) error {
+	index, err := e.maintenanceIndex(name)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	if err := index.Optimize(); err != nil {
+		return fmt.Errorf("failed to optimize index %s: %w", name, err)
+	}
+	log.Info().Msgf("Optimized index %s in %s", name, time.Since(started))
+	return nil
+}
+
+// ClearIndex removes every document of an index, keeping its configuration
+func (e *EngineCore) ClearIndex(name string) error {
+	index, ok := e.index(name)
+	if !ok {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	cleared, ok := index.(ports.ClearableIndexPort)
+	if !ok {
+		return fmt.Errorf("index %s: clear %w", name, ports.ErrNotSupported)
+	}
+	if err := cleared.Clear(); err != nil {
+		return fmt.Errorf("failed to clear index %s: %w", name, err)
+	}
+	log.Info().Msgf("Cleared index %s", name)
+	return nil
+}
+
+// VerifyIndex compares the documents of an index in memory and in storage, and repairs the differences
+// in a direction ("": none)
+func (e *EngineCore) VerifyIndex(name, repair string) (ports.VerifyReport, error) {
+	index, ok := e.index(name)
+	if !ok {
+		return ports.VerifyReport{}, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	verified, ok := index.(ports.VerifiableIndexPort)
+	if !ok {
+		return ports.VerifyReport{}, fmt.Errorf("index %s: verify %w", name, ports.ErrNotSupported)
+	}
+	report, err := verified.Verify(repair)
+	if err != nil {
+		return ports.VerifyReport{}, fmt.Errorf("failed to verify index %s: %w", name, err)
+	}
+	log.Info().Msgf("Verified index %s: %d documents in memory, %d stored, %d repaired", name, report.Documents, report.Stored, report.Repaired)
+	return report, nil
+}
+
+// maintenanceIndex looks up an index that supports flushing and optimizing
+func (e *EngineCore) maintenanceIndex(name string) (ports.MaintenanceIndexPort, error) {
+	index, ok := e.index(name)
+	if !ok {
+		return nil, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	maintained, ok := index.(ports.MaintenanceIndexPort)
+	if !ok {
+		return nil, fmt.Errorf("index %s: flush and optimize %w", name, ports.ErrNotSupported)
+	}
+	return maintained, nil
+}
+
+// SnapshotIndex exports an index into the snapshot directory, as <index>-<UTC time>.ndjson. The export
+// is written to a temporary file first, so snapshots on disk are always complete.
+func (e *EngineCore) SnapshotIndex(name string) (ports.Snapshot, error) {
+	e.mu.RLock()
+	dir := e.snapshotDir
+	e.mu.RUnlock()
+	if dir == "" {
+		return ports.Snapshot{}, fmt.Errorf("%w: snapshots need a snapshot directory", ports.ErrNotSupported)
+	}
+	if _, ok := e.index(name); !ok {
+		return ports.Snapshot{}, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return ports.Snapshot{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	snapshot := ports.Snapshot{Index: name, Time: time.Now().UTC()}
+	snapshot.Path = filepath.Join(dir, fmt.Sprintf("%s-%s.ndjson", name, snapshot.Time.Format(snapshotTimeLayout)))
+	file, err := os.CreateTemp(dir, "."+name+"-*.tmp")
+	if err != nil {
+		return ports.Snapshot{}, fmt.Errorf("failed to create snapshot of index %s: %w", name, err)
+	}
+	defer os.Remove(file.Name()) // No-op once renamed
+	if err := e.ExportIndex(name, file); err != nil {
+		file.Close()
+		return ports.Snapshot{}, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return ports.Snapshot{}, fmt.Errorf("failed to write snapshot of index %s: %w", name, err)
+	}
+	info, err := file.Stat()
+	if err == nil {
+		snapshot.Bytes = info.Size()
+	}
+	if err := file.Close(); err != nil {
+		return ports.Snapshot{}, fmt.Errorf("failed to write snapshot of index %s: %w", name, err)
+	}
+	if err := os.Rename(file.Name(), snapshot.Path); err != nil {
+		return ports.Snapshot{}, fmt.Errorf("failed to write snapshot of index %s: %w", name, err)
+	}
+	log.Info().Msgf("Wrote snapshot of index %s to %s (%d bytes)", name, snapshot.Path, snapshot.Bytes)
+	return snapshot, nil
+}
+
+// TriggerLoader runs a loader's pipeline now: scheduled loaders refresh incrementally (failing if a run is
+// in progress), others load everything again. The run stops early if ctx is cancelled.
+func (e *EngineCore) TriggerLoader(ctx context.Context, name string) error {
+	p, ok := e.pipeline(name)
+	if !ok {
+		return fmt.Errorf("pipeline for loader %s: %w", name, ports.ErrNotFound)
+	}
+	if p.Interval > 0 {
+		return e.RunLoader(ctx, name)
+	}
+	loaded, err := e.StreamLoader(ctx, p.Loader, p.Index, p.BatchSize)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Loaded %d documents from %s", loaded, p.Loader)
+	return nil
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// maintainedIndex counts flushes, optimizations and clears
+type maintainedIndex struct {
+	closingIndex
+	flushes, optimizations, clears int
+}
+
+func (m *maintainedIndex) Flush() error {
+	m.flushes++
+	return nil
+}
+
+func (m *maintainedIndex) Optimize() error {
+	m.optimizations++
+	return nil
+}
+
+func (m *maintainedIndex) Clear() error {
+	m.clears++
+	return nil
+}
+
+func (m *maintainedIndex) Verify(repair string) (ports.VerifyReport, error) {
+	if repair != "" && repair != "disk" {
+		return ports.VerifyReport{}, fmt.Errorf("%w: unknown repair direction %q", ports.ErrInvalid, repair)
+	}
+	report := ports.VerifyReport{Documents: 2, Stored: 1, MissingOnDisk: []string{"2"}}
+	if repair == "disk" {
+		report.Repaired = 1
+	}
+	return report, nil
+}
+
+func TestEngineCore_CreateAndDropIndex(t *testing.T) {
+	core := NewEngineCore()
+	assert.ErrorIs(t, core.CreateIndex(ports.IndexSpec{Name: "docs", Type: "simple"}), ports.ErrNotSupported)
+
+	created := &maintainedIndex{}
+	core.SetIndexProvider(func(spec ports.IndexSpec) (ports.IndexPort, error) {
+		if spec.Type != "simple" {
+			return nil, errors.New("unknown index type")
+		}
+		return created, nil
+	})
+	assert.NoError(t, core.CreateIndex(ports.IndexSpec{Name: "docs", Type: "simple"}))
+	assert.ErrorIs(t, core.CreateIndex(ports.IndexSpec{Name: "docs", Type: "simple"}), ports.ErrConflict)
+	assert.ErrorIs(t, core.CreateIndex(ports.IndexSpec{Name: "other", Type: "btree"}), ports.ErrInvalid)
+	assert.ErrorIs(t, core.CreateIndex(ports.IndexSpec{Type: "simple"}), ports.ErrInvalid)
+
+	// The first index becomes the default one, which cannot be dropped
+	assert.NoError(t, core.Index(makeDocs(1)[0]))
+	assert.ErrorIs(t, core.DropIndex("docs"), ports.ErrConflict)
+	core.RegisterIndex("old", &batchRecorder{})
+	assert.NoError(t, core.SetDefaultIndex("old"))
+	assert.NoError(t, core.DropIndex("docs"))
+	assert.True(t, created.closed)
+	assert.ErrorIs(t, core.DropIndex("docs"), ports.ErrNotFound)
+}
+
+func TestEngineCore_FlushOptimizeIndex(t *testing.T) {
+	core := NewEngineCore()
+	idx := &maintainedIndex{}
+	core.RegisterIndex("docs", idx)
+	core.RegisterIndex("plain", &batchRecorder{})
+
+	assert.NoError(t, core.FlushIndex("docs"))
+	assert.NoError(t, core.OptimizeIndex("docs"))
+	assert.Equal(t, 1, idx.flushes)
+	assert.Equal(t, 1, idx.optimizations)
+	assert.ErrorIs(t, core.FlushIndex("plain"), ports.ErrNotSupported)
+	assert.ErrorIs(t, core.OptimizeIndex("missing"), ports.ErrNotFound)
+
+	assert.NoError(t, core.ClearIndex("docs"))
+	assert.Equal(t, 1, idx.clears)
+	assert.ErrorIs(t, core.ClearIndex("plain"), ports.ErrNotSupported)
+	assert.ErrorIs(t, core.ClearIndex("missing"), ports.ErrNotFound)
+
+	report, err := core.VerifyIndex("docs", "disk")
+	assert.NoError(t, err)
+	assert.Equal(t, ports.VerifyReport{Documents: 2, Stored: 1, MissingOnDisk: []string{"2"}, Repaired: 1}, report)
+	_, err = core.VerifyIndex("docs", "both")
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+	_, err = core.VerifyIndex("plain", "")
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+	_, err = core.VerifyIndex("missing", "")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_SnapshotIndex(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("docs", &transferIndex{})
+	_, err := core.SnapshotIndex("docs")
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+
+	dir := t.TempDir()
+	core.SetSnapshotDir(dir)
+	snapshot, err := core.SnapshotIndex("docs")
+	assert.NoError(t, err)
+	assert.Equal(t, "docs", snapshot.Index)
+	assert.Equal(t, int64(len("export\n")), snapshot.Bytes)
+	data, err := os.ReadFile(snapshot.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "export\n", string(data))
+
+	// Only the snapshot is left in the directory
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = core.SnapshotIndex("missing")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_TriggerLoader(t *testing.T) {
+	core := NewEngineCore()
+	recorder := &batchRecorder{}
+	core.RegisterIndex("idx", recorder)
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(2)})
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", BatchSize: 10}))
+
+	assert.NoError(t, core.TriggerLoader(context.Background(), "fs"))
+	assert.NoError(t, core.TriggerLoader(context.Background(), "fs"))
+	assert.Len(t, recorder.batches, 2)
+	assert.ErrorIs(t, core.TriggerLoader(context.Background(), "missing"), ports.ErrNotFound)
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// SetDefaultIndex selects the index used by API searches and manual indexing
+func (e *EngineCore) SetDefaultIndex(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.indexes[name]; !ok {
+		return fmt.Errorf("index %s not registered", name)
+	}
+	e.defaultIndex = name
+	return nil
+}
+
+// Search runs a query through the search middlewares against the default index
+func (e *EngineCore) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return e.SearchContext(context.Background(), query)
+}
+
+// SetSearchTimeout bounds every search to timeout (0: unbounded). Searches past it are aborted and
+// fail with an error wrapping context.DeadlineExceeded.
+func (e *EngineCore) SetSearchTimeout(timeout time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.searchTimeout = timeout
+}
+
+// SearchContext is Search as part of the caller's trace: the search and its evaluation by the index
+// are recorded as child spans of the span in ctx. Cancelling ctx aborts the search. Searches of a
+// namespace evaluate the namespace's index on this node only.
+func (e *EngineCore) SearchContext(ctx context.Context, query ports.SearchQuery) (results ports.SearchResults, err error) {
+	e.mu.RLock()
+	timeout := e.searchTimeout
+	e.mu.RUnlock()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, span := e.startSpan(ctx, "engine.Search", attrQuery.String(query.Query))
+	defer func() {
+		span.SetAttributes(attrResults.Int(len(results.Documents)))
+		endSpan(span, err)
+	}()
+	if query, err = withSelect(query); err != nil {
+		return ports.SearchResults{}, err
+	}
+	name, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return ports.SearchResults{}, err
+	}
+	span.SetAttributes(attrIndex.String(name))
+
+	started := time.Now()
+	evaluation := &searchEvaluation{}
+	results, err = e.searchChain(ctx, index, evaluation)(query)
+	// Middlewares may filter on any field, so the fields are only selected once they are done
+	project(results.Documents, query.Projection)
+	latency := time.Since(started)
+	if errors.Is(err, context.DeadlineExceeded) && timeout > 0 {
+		err = fmt.Errorf("search timed out after %s: %w", timeout, err)
+	}
+	e.queries.record(latency, err)
+	event := ports.Event{Type: ports.EventSearchExecuted, Index: name, Query: query.Query, Results: len(results.Documents), Duration: latency}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	e.publish(event)
+
+	e.mu.RLock()
+	queryLog := e.queryLog
+	e.mu.RUnlock()
+	if queryLog != nil {
+		queryLog.record(name, query, results, err, latency, evaluation)
+	}
+	return results, err
+}
+
+func (e *EngineCore) search(ctx context.Context, index ports.IndexPort, query ports.SearchQuery, evaluation *searchEvaluation) (ports.SearchResults, error) {
+	// The query as rewritten by the middlewares, evaluated by the index
+	order, err := parseSort(query.Sort)
+	if err != nil {
+		return ports.SearchResults{}, err
+	}
+	ctx, span := e.startSpan(ctx, "index.Search", attrQuery.String(query.Query))
+	ctx = ports.WithPrincipals(ctx, query.Principals)
+	ctx = ports.WithLanguage(ctx, query.Language)
+	started := time.Now()
+	var results []models.Document
+	var failedNodes []string
+	distributed := query.Namespace == "" && e.distributed()
+	if distributed {
+		results, failedNodes, err = e.searchCluster(ctx, index, query.Query)
+	} else {
+		results, err = searchIndex(ctx, index, query.Query)
+	}
+	*evaluation = searchEvaluation{query: query.Query, duration: time.Since(started), indexResults: len(results)}
+	span.SetAttributes(attrResults.Int(len(results)))
+	endSpan(span, err)
+	if err != nil {
+		return ports.SearchResults{}, err
+	}
+
+	docs := make([]models.Document, 0, len(results))
+	for _, doc := range results {
+		// Indexes may not enforce the access control lists themselves
+		if query.Filter != nil && !query.Filter(doc) || query.Principals != nil && !doc.VisibleTo(query.Principals) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	sortDocuments(docs, order)
+	// The index's cap applies to the results the caller may see, in the order asked for
+	if capper, ok := index.(ports.ResultCapIndexPort); ok {
+		if limit := capper.MaxResults(); limit > 0 && len(docs) > limit {
+			docs = docs[:limit]
+		}
+	}
+	var warnings []ports.QueryError
+	if len(results) == 0 && !distributed {
+		// Other nodes may have the dimensions this node does not
+		warnings = dimensionWarnings(index, query.Query)
+	}
+	return ports.SearchResults{Documents: docs, FailedNodes: failedNodes, Warnings: warnings}, nil
+}
+
+// searchIndex evaluates a query against an index
+func searchIndex(ctx context.Context, index ports.IndexPort, query string) ([]models.Document, error) {
+	results, err := index.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]models.Document, len(results))
+	for i, result := range results {
+		doc, ok := result.(models.Document)
+		if !ok {
+			return nil, fmt.Errorf("unexpected search result type %T", result)
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// Index adds a document to the default index
+func (e *EngineCore) Index(doc models.Document) error {
+	return e.IndexNamespace("", doc)
+}
+
+// DefaultIndex returns the name and adapter of the index API searches and manual indexing use
+func (e *EngineCore) DefaultIndex() (string, ports.IndexPort, error) {
+	return e.defaultIndexPort()
+}
+
+func (e *EngineCore) defaultIndexPort() (string, ports.IndexPort, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	index, ok := e.indexes[e.defaultIndex]
+	if !ok {
+		return "", nil, errors.New("no index registered")
+	}
+	return e.defaultIndex, index, nil
+}
+
+// StartAPIs starts every registered API in its own goroutine. Errors from APIs that fail are sent
+// on the returned channel, which is closed once all APIs have exited.
+func (e *EngineCore) StartAPIs() <-chan error {
+	apis := e.apiSnapshot()
+	errs := make(chan error, len(apis))
+	var wg sync.WaitGroup
+	for name, api := range apis {
+		wg.Add(1)
+		go func(name string, api ports.APIPort) {
+			defer wg.Done()
+			log.Info().Msgf("Starting %s API %s", api.Name(), name)
+			if err := api.Start(); err != nil {
+				errs <- fmt.Errorf("API %s: %w", name, err)
+			}
+		}(name, api)
+	}
+	go func() {
+		wg.Wait()
+		close(errs)
+	}()
+	return errs
+}
+
+// StopAPIs gracefully shuts down every registered API
+func (e *EngineCore) StopAPIs() error {
+	var errs []error
+	for name, api := range e.apiSnapshot() {
+		if err := api.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("API %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apiSnapshot copies the API registry so APIs can be started and stopped without holding the lock
+func (e *EngineCore) apiSnapshot() map[string]ports.APIPort {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	apis := make(map[string]ports.APIPort, len(e.apis))
+	for name, api := range e.apis {
+		apis[name] = api
+	}
+	return apis
+}
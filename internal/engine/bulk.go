@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// Bulk applies document operations to the default index in request order. Consecutive index items are
+// added in batches of DefaultBatchSize; when a batch fails its documents are retried one by one so each
+// item reports its own outcome. Updates and deletes need an index supporting them (ports.MutableIndexPort).
+func (e *EngineCore) Bulk(ctx context.Context, items []ports.BulkItem) (ports.BulkResults, error) {
+	return e.BulkNamespace(ctx, "", items)
+}
+
+// BulkNamespace is Bulk against the index of a namespace ("": the default index)
+func (e *EngineCore) BulkNamespace(ctx context.Context, namespace string, items []ports.BulkItem) (ports.BulkResults, error) {
+	name, index, err := e.namespaceIndex(namespace)
+	if err != nil {
+		return ports.BulkResults{}, err
+	}
+	ctx, span := e.startSpan(ctx, "engine.Bulk", attrIndex.String(name), attrDocuments.Int(len(items)))
+	defer endSpan(span, nil)
+
+	results := make([]ports.BulkItemResult, len(items))
+	var pending []int // Index items not yet added, by position
+	var indexed []models.Document
+	var deleted []string
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		batch := make([]models.Document, len(pending))
+		for i, position := range pending {
+			batch[i] = items[position].Document
+		}
+		if err := e.writeBatch(ctx, index, name, batch); err != nil {
+			for _, position := range pending {
+				results[position].Err = index.AddDocument(ctx, items[position].Document)
+			}
+		}
+		for _, position := range pending {
+			if results[position].Err == nil {
+				indexed = append(indexed, items[position].Document)
+			}
+		}
+		pending = pending[:0]
+	}
+
+	mutable, _ := index.(ports.MutableIndexPort)
+	for position, item := range items {
+		results[position] = ports.BulkItemResult{Action: item.Action, ID: item.Document.ID}
+		if item.Document.ID == "" {
+			results[position].Err = fmt.Errorf("%w: document id is required", ports.ErrInvalid)
+			continue
+		}
+		switch item.Action {
+		case ports.BulkIndex:
+			pending = append(pending, position)
+			if len(pending) == DefaultBatchSize {
+				flush()
+			}
+			continue
+		case ports.BulkUpdate, ports.BulkDelete:
+			// Earlier adds are written first, as later items may depend on them
+			flush()
+			if mutable == nil {
+				results[position].Err = fmt.Errorf("%w: index %s cannot %s documents", ports.ErrNotSupported, name, item.Action)
+				continue
+			}
+		default:
+			results[position].Err = fmt.Errorf("%w: unknown action %q (known: %s, %s, %s)", ports.ErrInvalid, item.Action, ports.BulkIndex, ports.BulkUpdate, ports.BulkDelete)
+			continue
+		}
+
+		if item.Action == ports.BulkUpdate {
+			if results[position].Err = mutable.UpdateDocument(ctx, item.Document); results[position].Err == nil {
+				indexed = append(indexed, item.Document)
+			}
+		} else {
+			if results[position].Err = mutable.DeleteDocument(ctx, item.Document.ID); results[position].Err == nil {
+				deleted = append(deleted, item.Document.ID)
+			}
+		}
+	}
+	flush()
+
+	if len(indexed) > 0 {
+		e.publish(ports.Event{Type: ports.EventDocumentIndexed, Index: name, DocumentIDs: documentIDs(indexed), Documents: indexed})
+	}
+	if len(deleted) > 0 {
+		e.publish(ports.Event{Type: ports.EventDocumentDeleted, Index: name, DocumentIDs: deleted})
+	}
+	return ports.BulkResults{Items: results}, nil
+}
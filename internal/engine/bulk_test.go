@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// rejectingIndex fails batches containing a rejected document, and the document itself
+type rejectingIndex struct {
+	mutableRecorder
+	reject string
+}
+
+func (r *rejectingIndex) AddDocument(ctx context.Context, doc interface{}) error {
+	if doc.(models.Document).ID == r.reject {
+		return errors.New("rejected")
+	}
+	return r.mutableRecorder.AddDocument(ctx, doc)
+}
+
+func (r *rejectingIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	for _, doc := range docs {
+		if doc.ID == r.reject {
+			return errors.New("rejected")
+		}
+	}
+	return r.mutableRecorder.AddDocuments(ctx, docs)
+}
+
+func TestEngineCore_Bulk(t *testing.T) {
+	core := NewEngineCore()
+	idx := &mutableRecorder{}
+	core.RegisterIndex("idx", idx)
+
+	results, err := core.Bulk(context.Background(), []ports.BulkItem{
+		{Action: ports.BulkIndex, Document: models.Document{ID: "1"}},
+		{Action: ports.BulkIndex, Document: models.Document{ID: "2"}},
+		{Action: ports.BulkDelete, Document: models.Document{ID: "1"}},
+		{Action: ports.BulkIndex, Document: models.Document{ID: "3"}},
+		{Action: ports.BulkUpdate, Document: models.Document{ID: "2"}},
+		{Action: "upsert", Document: models.Document{ID: "4"}},
+		{Action: ports.BulkIndex},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, results.Failed())
+	assert.Len(t, results.Items, 7)
+	assert.ErrorIs(t, results.Items[5].Err, ports.ErrInvalid)
+	assert.ErrorIs(t, results.Items[6].Err, ports.ErrInvalid)
+
+	// Consecutive adds are batched, and written before the items following them
+	assert.Equal(t, [][]models.Document{{{ID: "1"}, {ID: "2"}}, {{ID: "3"}}}, idx.batches)
+	assert.Equal(t, []string{"1"}, idx.deleted)
+	assert.Equal(t, []string{"2"}, idx.updated)
+}
+
+func TestEngineCore_BulkReportsItemFailures(t *testing.T) {
+	core := NewEngineCore()
+	idx := &rejectingIndex{reject: "2"}
+	core.RegisterIndex("idx", idx)
+
+	results, err := core.Bulk(context.Background(), []ports.BulkItem{
+		{Action: ports.BulkIndex, Document: models.Document{ID: "1"}},
+		{Action: ports.BulkIndex, Document: models.Document{ID: "2"}},
+		{Action: ports.BulkIndex, Document: models.Document{ID: "3"}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, results.Items[0].Err)
+	assert.EqualError(t, results.Items[1].Err, "rejected")
+	assert.NoError(t, results.Items[2].Err)
+	// The failed batch is retried one document at a time
+	assert.Equal(t, [][]models.Document{{{ID: "1"}}, {{ID: "3"}}}, idx.batches)
+
+	// Indexes without updates and deletes fail those items only
+	core = NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+	results, err = core.Bulk(context.Background(), []ports.BulkItem{
+		{Action: ports.BulkDelete, Document: models.Document{ID: "1"}},
+		{Action: ports.BulkIndex, Document: models.Document{ID: "2"}},
+	})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, results.Items[0].Err, ports.ErrNotSupported)
+	assert.NoError(t, results.Items[1].Err)
+}
+
+// durableIndex records the durability of each update and delete
+type durableIndex struct {
+	mutableRecorder
+	durabilities []string
+}
+
+func (r *durableIndex) UpdateDocument(ctx context.Context, doc models.Document) error {
+	r.durabilities = append(r.durabilities, ports.DurabilityFrom(ctx))
+	return r.mutableRecorder.UpdateDocument(ctx, doc)
+}
+
+func (r *durableIndex) DeleteDocument(ctx context.Context, id string) error {
+	r.durabilities = append(r.durabilities, ports.DurabilityFrom(ctx))
+	return r.mutableRecorder.DeleteDocument(ctx, id)
+}
+
+func TestEngineCore_BulkPassesDurability(t *testing.T) {
+	core := NewEngineCore()
+	idx := &durableIndex{}
+	core.RegisterIndex("idx", idx)
+
+	ctx := ports.WithDurability(context.Background(), ports.DurabilityFsync)
+	results, err := core.Bulk(ctx, []ports.BulkItem{
+		{Action: ports.BulkUpdate, Document: models.Document{ID: "1"}},
+		{Action: ports.BulkDelete, Document: models.Document{ID: "2"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, results.Failed())
+	assert.Equal(t, []string{ports.DurabilityFsync, ports.DurabilityFsync}, idx.durabilities)
+}
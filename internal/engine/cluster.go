@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/internal/scoring"
+	"github.com/rs/zerolog/log"
+)
+
+/**
+ * Distributed search: searches are scattered to the peer nodes of the cluster and their results gathered
+ **/
+
+// ClusterOptions tune distributed searches
+type ClusterOptions struct {
+	// Name of this node in shard results (default "local")
+	NodeID string
+	// Deadline of each peer node's search (0: only the search's own deadline)
+	NodeTimeout time.Duration
+	// Fail searches a peer node fails, instead of returning the other nodes' results
+	RequireAllNodes bool
+}
+
+// clusterNode is a peer node searches are sent to
+type clusterNode struct {
+	id      string
+	address string
+}
+
+// SetClusterTransport distributes searches: every search is evaluated by the default index and, through
+// transport, by every node of the cluster manager, and their results are ranked together (nil: searches
+// stay local)
+func (e *EngineCore) SetClusterTransport(transport ports.ClusterTransportPort, options ClusterOptions) {
+	if options.NodeID == "" {
+		options.NodeID = "local"
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clusterTransport = transport
+	e.clusterOptions = options
+}
+
+// SearchShard evaluates a peer node's distributed search against the default index of this node only.
+// The peer already applied the search middlewares (rate limits, query rewrites) and the caller's filter.
+func (e *EngineCore) SearchShard(ctx context.Context, query ports.ShardQuery) (ports.ShardResults, error) {
+	_, index, err := e.defaultIndexPort()
+	if err != nil {
+		return ports.ShardResults{}, err
+	}
+	ctx, span := e.startSpan(ctx, "index.SearchShard", attrQuery.String(query.Query))
+	ctx = ports.WithPrincipals(ctx, query.Principals)
+	ctx = ports.WithLanguage(ctx, query.Language)
+	results, err := e.searchShard(ctx, index, query.Query)
+	span.SetAttributes(attrResults.Int(len(results.Documents)))
+	endSpan(span, err)
+	return results, err
+}
+
+// distributed reports whether searches are sent to peer nodes
+func (e *EngineCore) distributed() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.clusterTransport != nil && e.clusterManager != nil
+}
+
+// searchShard evaluates a query against a local index, with the term statistics it ranks the results by
+func (e *EngineCore) searchShard(ctx context.Context, index ports.IndexPort, query string) (ports.ShardResults, error) {
+	e.mu.RLock()
+	node := e.clusterOptions.NodeID
+	e.mu.RUnlock()
+
+	docs, err := searchIndex(ctx, index, query)
+	if err != nil {
+		return ports.ShardResults{}, err
+	}
+	results := ports.ShardResults{Node: node, Documents: docs}
+	if scorer, ok := index.(ports.TermStatsIndexPort); ok {
+		ids := make([]string, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.ID
+		}
+		if results.Stats, err = scorer.TermStats(query, ids); err != nil {
+			return ports.ShardResults{}, err
+		}
+	}
+	return results, nil
+}
+
+// searchCluster evaluates a query locally and on every peer node at once and ranks the results together.
+// Peer nodes that fail or time out are returned as failed, unless every node is required.
+func (e *EngineCore) searchCluster(ctx context.Context, index ports.IndexPort, query string) ([]models.Document, []string, error) {
+	e.mu.RLock()
+	transport, options := e.clusterTransport, e.clusterOptions
+	e.mu.RUnlock()
+	nodes, err := e.clusterNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peers := make([]ports.ShardResults, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peers[i], errs[i] = e.searchNode(ctx, transport, node, query, options.NodeTimeout)
+		}()
+	}
+	local, err := e.searchShard(ctx, index, query)
+	wg.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Peers failing because the search itself was cancelled or timed out fail the search
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	shards := []ports.ShardResults{local}
+	var failed []string
+	for i, node := range nodes {
+		if errs[i] != nil {
+			if options.RequireAllNodes {
+				return nil, nil, fmt.Errorf("node %s: %w", node.id, errs[i])
+			}
+			log.Warn().Msgf("Node %s failed to search, returning partial results: %s", node.id, errs[i])
+			failed = append(failed, node.id)
+			continue
+		}
+		shards = append(shards, peers[i])
+	}
+	return mergeShards(shards), failed, nil
+}
+
+// clusterNodes lists the peer nodes of the cluster manager
+func (e *EngineCore) clusterNodes() ([]clusterNode, error) {
+	e.mu.RLock()
+	manager := e.clusterManager
+	e.mu.RUnlock()
+
+	ids, err := manager.ListNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster nodes: %w", err)
+	}
+	nodes := make([]clusterNode, 0, len(ids))
+	for _, id := range ids {
+		address, err := manager.NodeAddress(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cluster node %s: %w", id, err)
+		}
+		nodes = append(nodes, clusterNode{id: id, address: address})
+	}
+	return nodes, nil
+}
+
+// searchNode sends a query to a peer node, bounded by timeout
+func (e *EngineCore) searchNode(ctx context.Context, transport ports.ClusterTransportPort, node clusterNode, query string, timeout time.Duration) (ports.ShardResults, error) {
+	ctx, span := e.startSpan(ctx, "cluster.SearchShard", attrNode.String(node.id), attrQuery.String(query))
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	principals, _ := ports.PrincipalsFrom(ctx)
+	results, err := transport.SearchShard(ctx, node.address, ports.ShardQuery{Query: query, Principals: principals, Language: ports.LanguageFrom(ctx)})
+	span.SetAttributes(attrResults.Int(len(results.Documents)))
+	endSpan(span, err)
+	results.Node = node.id
+	return results, err
+}
+
+// mergeShards ranks the documents of every shard together. Documents of ranked shards are scored by
+// TF-IDF over the statistics of all shards, as if one index held every document, so their scores are
+// comparable across nodes, and combined with their boosts and scoring script; unranked documents follow in shard order. Documents held by several nodes are
+// kept once.
+func mergeShards(shards []ports.ShardResults) []models.Document {
+	total := 0
+	docFreq := make(map[string]int)
+	for _, shard := range shards {
+		total += shard.Stats.Documents
+		for term, df := range shard.Stats.DocFreq {
+			docFreq[term] += df
+		}
+	}
+
+	type hit struct {
+		doc    models.Document
+		ranked bool
+		score  float64
+	}
+	var hits []hit
+	seen := make(map[string]bool)
+	scripts := make(shardScripts)
+	now := time.Now()
+	for _, shard := range shards {
+		for _, doc := range shard.Documents {
+			if doc.ID != "" {
+				if seen[doc.ID] {
+					continue
+				}
+				seen[doc.ID] = true
+			}
+			h := hit{doc: doc, ranked: len(shard.Stats.Terms) > 0}
+			for _, term := range shard.Stats.Terms {
+				if df := docFreq[term]; df > 0 {
+					h.score += float64(shard.Stats.TermFreq[doc.ID][term]) * math.Log(1+float64(total)/float64(df))
+				}
+			}
+			if boost, ok := shard.Stats.Boosts[doc.ID]; ok {
+				h.score = shard.Stats.TextWeight*h.score + boost
+				if script := scripts.get(shard.Stats.Script); script != nil {
+					h.score = script.Score(doc, h.score, now)
+				}
+			}
+			hits = append(hits, h)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].ranked != hits[j].ranked {
+			return hits[i].ranked
+		}
+		if !hits[i].ranked || hits[i].score == hits[j].score {
+			return hits[i].ranked && hits[i].doc.ID < hits[j].doc.ID
+		}
+		return hits[i].score > hits[j].score
+	})
+
+	docs := make([]models.Document, len(hits))
+	for i, h := range hits {
+		docs[i] = h.doc
+	}
+	return docs
+}
+
+// shardScripts compiles the scoring scripts of shards once per merge, by source
+type shardScripts map[string]*scoring.Script
+
+// get returns the compiled script of a source (nil: none, or invalid)
+func (s shardScripts) get(source string) *scoring.Script {
+	if source == "" {
+		return nil
+	}
+	script, ok := s[source]
+	if !ok {
+		var err error
+		if script, err = scoring.Compile(source); err != nil {
+			log.Warn().Err(err).Msg("Ignoring scoring script of shard")
+		}
+		s[source] = script
+	}
+	return script
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// peerList is a cluster manager of fixed nodes, addressed by their IDs
+type peerList []string
+
+func (p peerList) RegisterNode(nodeID string, address string) error { return nil }
+func (p peerList) DeregisterNode(nodeID string) error               { return nil }
+func (p peerList) ListNodes() ([]string, error)                     { return p, nil }
+func (p peerList) NodeAddress(nodeID string) (string, error)        { return nodeID, nil }
+
+// peerTransport answers shard searches from fixed results; "slow" never answers and "down" fails
+type peerTransport map[string]ports.ShardResults
+
+func (p peerTransport) SearchShard(ctx context.Context, address string, query ports.ShardQuery) (ports.ShardResults, error) {
+	switch address {
+	case "slow":
+		<-ctx.Done()
+		return ports.ShardResults{}, ctx.Err()
+	case "down":
+		return ports.ShardResults{}, errors.New("connection refused")
+	}
+	return p[address], nil
+}
+
+func (p peerTransport) Close() error { return nil }
+
+func TestMergeShards_RanksGlobally(t *testing.T) {
+	// "go" is rare on the first node, so it ranks its document first locally; over both nodes "go" is
+	// common and the document where it is more frequent ranks first
+	merged := mergeShards([]ports.ShardResults{
+		{Documents: []models.Document{{ID: "a"}}, Stats: ports.TermStats{
+			Terms: []string{"go"}, Documents: 100, DocFreq: map[string]int{"go": 1}, TermFreq: map[string]map[string]int{"a": {"go": 1}},
+		}},
+		{Documents: []models.Document{{ID: "b"}, {ID: "a"}}, Stats: ports.TermStats{
+			Terms: []string{"go"}, Documents: 1000, DocFreq: map[string]int{"go": 999}, TermFreq: map[string]map[string]int{"b": {"go": 2}, "a": {"go": 1}},
+		}},
+		{Documents: []models.Document{{ID: "unranked"}}},
+	})
+	ids := make([]string, len(merged))
+	for i, doc := range merged {
+		ids[i] = doc.ID
+	}
+	assert.Equal(t, []string{"b", "a", "unranked"}, ids)
+}
+
+func TestMergeShards_Boosts(t *testing.T) {
+	// "b" is less relevant, but its boost (e.g. recency) outweighs the difference
+	merged := mergeShards([]ports.ShardResults{
+		{Documents: []models.Document{{ID: "a"}, {ID: "b"}}, Stats: ports.TermStats{
+			Terms: []string{"go"}, Documents: 10, DocFreq: map[string]int{"go": 2}, TermFreq: map[string]map[string]int{"a": {"go": 2}, "b": {"go": 1}},
+			Boosts: map[string]float64{"a": 0, "b": 5}, TextWeight: 1,
+		}},
+	})
+	assert.Equal(t, []models.Document{{ID: "b"}, {ID: "a"}}, merged)
+
+	// The scoring script of the shard applies to the combined score
+	merged = mergeShards([]ports.ShardResults{
+		{Documents: []models.Document{{ID: "a"}, {ID: "b", Meta: map[string]string{"rank": "100"}}}, Stats: ports.TermStats{
+			Terms: []string{"go"}, Documents: 10, DocFreq: map[string]int{"go": 2}, TermFreq: map[string]map[string]int{"a": {"go": 2}, "b": {"go": 1}},
+			Boosts: map[string]float64{"a": 0, "b": 0}, TextWeight: 1, Script: "_score + rank",
+		}},
+	})
+	assert.Equal(t, "b", merged[0].ID)
+}
+
+func TestEngineCore_DistributedSearch(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{{ID: "local", Text: "hello"}}})
+	core.SetClusterManager(peerList{"b", "slow", "down"})
+	core.SetClusterTransport(peerTransport{"b": {Documents: []models.Document{{ID: "remote", Text: "hello"}, {ID: "secret", Text: "hello"}}}},
+		ClusterOptions{NodeTimeout: 20 * time.Millisecond})
+
+	results, err := core.Search(ports.SearchQuery{Query: "hello", Filter: func(doc models.Document) bool { return doc.ID != "secret" }})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "local", Text: "hello"}, {ID: "remote", Text: "hello"}}, results.Documents)
+	assert.Equal(t, []string{"slow", "down"}, results.FailedNodes)
+
+	core.SetClusterTransport(peerTransport{}, ClusterOptions{NodeTimeout: 20 * time.Millisecond, RequireAllNodes: true})
+	_, err = core.Search(ports.SearchQuery{Query: "hello"})
+	assert.ErrorContains(t, err, "node slow")
+}
+
+func TestEngineCore_SearchShard(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{{ID: "local"}}})
+	core.SetClusterTransport(peerTransport{}, ClusterOptions{NodeID: "a"})
+
+	results, err := core.SearchShard(context.Background(), ports.ShardQuery{Query: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", results.Node)
+	assert.Equal(t, []models.Document{{ID: "local"}}, results.Documents)
+}
@@ -1,7 +1,12 @@
 package engine
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/aawadall/bit-scout/internal/ports"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /**
@@ -11,12 +16,21 @@ import (
 // EngineCore defines the core of the search engine using hexagonal architecture.
 // It exposes ports for registering adapters such as indexes, loaders, configuration, persistence, feature extractors, and cluster management.
 type EngineCore struct {
+	// Guards the registries, pipelines and default index, which may change at runtime (config reload)
+	mu sync.RWMutex
+
 	// Index registry: maps index names to index implementations
 	indexes map[string]ports.IndexPort
 
 	// Loader registry: maps loader names to loader implementations
 	loaders map[string]ports.LoaderPort
 
+	// Streaming loader registry: maps loader names to loaders that emit documents incrementally
+	streamingLoaders map[string]ports.StreamingLoaderPort
+
+	// Incremental loader registry: maps loader names to loaders that report changes since their last run
+	incrementalLoaders map[string]ports.IncrementalLoaderPort
+
 	// Configuration registry: holds configuration for various components
 	configs map[string]ports.ConfigPort
 
@@ -26,55 +40,192 @@ type EngineCore struct {
 	// Feature extractor registry: maps extractor names to feature extractor adapters
 	featureExtractors map[string]ports.FeatureExtractorPort
 
-	// Cluster management port (optional, for future extension)
+	// Cluster management port: the peer nodes searches are distributed to (optional)
 	clusterManager ports.ClusterManagerPort
 
-	// API port (only one supported for now)
-	api ports.APIPort
+	// Sends searches to peer nodes (nil: searches stay local)
+	clusterTransport ports.ClusterTransportPort
+	clusterOptions   ClusterOptions
+
+	// API registry: maps API names to API adapters served by the engine
+	apis map[string]ports.APIPort
+
+	// Name of the index used by API searches and manual indexing
+	defaultIndex string
+
+	// Scheduler for periodic loader refreshes
+	scheduler *scheduler
+
+	// Pipelines wiring loaders to indexes, keyed by loader name, and the order they were added in
+	pipelines     map[string]Pipeline
+	pipelineOrder []string
+
+	// Start/stop state
+	state lifecycle
+
+	// Unix nanoseconds of Start (0: not started), read by Stats without waiting on the initial loads
+	startedAt atomic.Int64
+
+	// Search counters reported by Stats
+	queries queryCounters
+
+	// Event bus for index lifecycle events
+	events *eventBus
+
+	// Middlewares applied to every search, outermost first
+	searchMiddlewares []SearchMiddleware
+
+	// Log of every search (optional)
+	queryLog *QueryLog
+
+	// Deadline of each search (0: none)
+	searchTimeout time.Duration
+
+	// Limits of the liveness and readiness checks
+	health HealthOptions
+
+	// Index rebuilds and queries run by Start before the APIs start
+	warmUp WarmUpOptions
+
+	// Matches newly indexed documents against search subscriptions (nil: subscriptions unsupported)
+	matcher QueryMatcher
+
+	// Stored queries matched against newly indexed documents
+	percolator percolator
+
+	// Keeps the saved searches (nil: saved searches unsupported)
+	savedSearches ports.SavedSearchStorePort
+
+	// Instantiates indexes created at runtime (nil: unsupported)
+	indexProvider IndexProvider
+
+	// Directory index snapshots are written to (empty: snapshots unsupported)
+	snapshotDir string
+
+	// Scheduled snapshots and restores on Start
+	snapshots snapshotter
+
+	// Alternative names of indexes: alias -> index
+	aliases map[string]string
+
+	// Replicates index creation, drops and aliases to the whole cluster (nil: changes are local)
+	metadata ports.MetadataPort
+
+	// Indexes created from the replicated cluster metadata
+	replicated map[string]bool
+
+	// Records the engine's spans (nil: the global tracer provider); read without the lock by every span
+	tracerProvider atomic.Pointer[trace.TracerProvider]
 }
 
 // NewEngineCore creates a new EngineCore with empty registries.
 func NewEngineCore() *EngineCore {
 	return &EngineCore{
-		indexes:           make(map[string]ports.IndexPort),
-		loaders:           make(map[string]ports.LoaderPort),
-		configs:           make(map[string]ports.ConfigPort),
-		persistence:       make(map[string]ports.PersistencePort),
-		featureExtractors: make(map[string]ports.FeatureExtractorPort),
+		indexes:            make(map[string]ports.IndexPort),
+		loaders:            make(map[string]ports.LoaderPort),
+		streamingLoaders:   make(map[string]ports.StreamingLoaderPort),
+		incrementalLoaders: make(map[string]ports.IncrementalLoaderPort),
+		configs:            make(map[string]ports.ConfigPort),
+		persistence:        make(map[string]ports.PersistencePort),
+		featureExtractors:  make(map[string]ports.FeatureExtractorPort),
+		apis:               make(map[string]ports.APIPort),
+		scheduler:          newScheduler(),
+		pipelines:          make(map[string]Pipeline),
+		events:             newEventBus(),
+		aliases:            make(map[string]string),
+		replicated:         make(map[string]bool),
 	}
 }
 
-// RegisterIndex registers an index adapter.
+// RegisterIndex registers an index adapter. The first registered index becomes the default index.
 func (e *EngineCore) RegisterIndex(name string, index ports.IndexPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.indexes[name] = index
+	if e.defaultIndex == "" {
+		e.defaultIndex = name
+	}
 }
 
 // RegisterLoader registers a loader adapter.
 func (e *EngineCore) RegisterLoader(name string, loader ports.LoaderPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.loaders[name] = loader
 }
 
+// RegisterStreamingLoader registers a streaming loader adapter.
+func (e *EngineCore) RegisterStreamingLoader(name string, loader ports.StreamingLoaderPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.streamingLoaders[name] = loader
+}
+
+// RegisterIncrementalLoader registers an incremental loader adapter.
+func (e *EngineCore) RegisterIncrementalLoader(name string, loader ports.IncrementalLoaderPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.incrementalLoaders[name] = loader
+}
+
 // RegisterConfig registers a configuration adapter.
 func (e *EngineCore) RegisterConfig(name string, config ports.ConfigPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.configs[name] = config
 }
 
 // RegisterPersistence registers a persistence adapter.
 func (e *EngineCore) RegisterPersistence(name string, persistence ports.PersistencePort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.persistence[name] = persistence
 }
 
 // RegisterFeatureExtractor registers a feature extractor adapter.
 func (e *EngineCore) RegisterFeatureExtractor(name string, extractor ports.FeatureExtractorPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.featureExtractors[name] = extractor
 }
 
 // SetClusterManager sets the cluster manager adapter.
 func (e *EngineCore) SetClusterManager(manager ports.ClusterManagerPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.clusterManager = manager
 }
 
-// RegisterAPI registers an API adapter (only one supported for now)
-func (e *EngineCore) RegisterAPI(api ports.APIPort) {
-	e.api = api
+// RegisterAPI registers an API adapter.
+func (e *EngineCore) RegisterAPI(name string, api ports.APIPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.apis[name] = api
+}
+
+// index looks up a registered index by name or alias
+func (e *EngineCore) index(name string) (ports.IndexPort, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	index, ok := e.indexes[name]
+	if !ok {
+		index, ok = e.indexes[e.aliases[name]]
+	}
+	return index, ok
+}
+
+// streamingLoader looks up a registered streaming loader
+func (e *EngineCore) streamingLoader(name string) (ports.StreamingLoaderPort, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	loader, ok := e.streamingLoaders[name]
+	return loader, ok
+}
+
+// incrementalLoader looks up a registered incremental loader
+func (e *EngineCore) incrementalLoader(name string) (ports.IncrementalLoaderPort, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	loader, ok := e.incrementalLoaders[name]
+	return loader, ok
 }
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// Diagnostics returns a snapshot of the goroutines, memory, indexes, loaders and APIs of the engine.
+// Indexes that do not report their internals are described by their document count.
+func (e *EngineCore) Diagnostics() ports.Diagnostics {
+	diagnostics := ports.Diagnostics{
+		Time:       time.Now().UTC(),
+		Goroutines: runtime.NumGoroutine(),
+		Memory:     memoryDiagnostics(),
+		Indexes:    make(map[string]map[string]interface{}),
+		Loaders:    e.LoaderStatuses(),
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for name, index := range e.indexes {
+		if diagnoser, ok := index.(ports.DiagnosticsIndexPort); ok {
+			diagnostics.Indexes[name] = diagnoser.Diagnostics()
+			continue
+		}
+		count, err := index.Count()
+		if err != nil {
+			diagnostics.Indexes[name] = map[string]interface{}{"error": err.Error()}
+			continue
+		}
+		diagnostics.Indexes[name] = map[string]interface{}{"documents": count}
+	}
+	for name := range e.apis {
+		diagnostics.APIs = append(diagnostics.APIs, name)
+	}
+	sort.Strings(diagnostics.APIs)
+	return diagnostics
+}
+
+// memoryDiagnostics summarizes the Go runtime's memory statistics
+func memoryDiagnostics() ports.MemoryDiagnostics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return ports.MemoryDiagnostics{
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		LastGCPause:    time.Duration(mem.PauseNs[(mem.NumGC+255)%256]),
+	}
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// diagnosedIndex reports fixed diagnostics
+type diagnosedIndex struct {
+	batchRecorder
+}
+
+func (d *diagnosedIndex) Diagnostics() map[string]interface{} {
+	return map[string]interface{}{"queued_operations": 3}
+}
+
+func TestEngineCore_Diagnostics(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("queue", &diagnosedIndex{})
+	core.RegisterIndex("plain", &batchRecorder{})
+	core.RegisterAPI("b", newBlockingAPI())
+	core.RegisterAPI("a", newBlockingAPI())
+
+	diagnostics := core.Diagnostics()
+	assert.Positive(t, diagnostics.Goroutines)
+	assert.Positive(t, diagnostics.Memory.HeapAllocBytes)
+	assert.Equal(t, map[string]interface{}{"queued_operations": 3}, diagnostics.Indexes["queue"])
+	assert.Equal(t, map[string]interface{}{"documents": 0}, diagnostics.Indexes["plain"])
+	assert.Equal(t, []string{"a", "b"}, diagnostics.APIs)
+}
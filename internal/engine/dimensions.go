@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// ListDimensions returns the metadata keys of the documents in the index of a namespace that the caller
+// may see, with the number of documents and distinct values of each. Indexes that keep no catalog of
+// their metadata have none.
+func (e *EngineCore) ListDimensions(ctx context.Context, query ports.DimensionQuery) ([]ports.Dimension, error) {
+	_, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	dimensions := []ports.Dimension{}
+	if lister, ok := index.(ports.DimensionIndexPort); ok {
+		dimensions = append(dimensions, lister.ListDimensions(ports.WithPrincipals(ctx, query.Principals), query.Filter)...)
+	}
+	return dimensions, nil
+}
+
+// TopValues returns the n most frequent values of a dimension in the documents of the index of a
+// namespace that the caller may see (n <= 0: all), most frequent first
+func (e *EngineCore) TopValues(ctx context.Context, query ports.DimensionQuery, dimension string, n int) ([]ports.ValueCount, error) {
+	_, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	values := []ports.ValueCount{}
+	if lister, ok := index.(ports.DimensionIndexPort); ok {
+		values = append(values, lister.TopValues(ports.WithPrincipals(ctx, query.Principals), dimension, n, query.Filter)...)
+	}
+	return values, nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// catalogIndex describes one dimension, fileExtension, recording the principals and filter of the caller
+type catalogIndex struct {
+	docsIndex
+	principals []string
+	filtered   bool
+}
+
+func (c *catalogIndex) ListDimensions(ctx context.Context, filter func(models.Document) bool) []ports.Dimension {
+	c.principals, _ = ports.PrincipalsFrom(ctx)
+	c.filtered = filter != nil
+	return []ports.Dimension{{Name: "fileExtension", Documents: 3, Values: 2}}
+}
+
+func (c *catalogIndex) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ports.ValueCount {
+	c.principals, _ = ports.PrincipalsFrom(ctx)
+	c.filtered = filter != nil
+	if dimension != "fileExtension" {
+		return nil
+	}
+	values := []ports.ValueCount{{Value: "go", Count: 2}, {Value: "md", Count: 1}}
+	if n > 0 && n < len(values) {
+		values = values[:n]
+	}
+	return values
+}
+
+func TestEngineCore_Dimensions(t *testing.T) {
+	ctx := context.Background()
+	core := NewEngineCore()
+	catalog := &catalogIndex{}
+	core.RegisterIndex("idx", catalog)
+	core.RegisterIndex("plain", &docsIndex{})
+	assert.NoError(t, core.SetAlias("team-a", "plain"))
+
+	dimensions, err := core.ListDimensions(ctx, ports.DimensionQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.Dimension{{Name: "fileExtension", Documents: 3, Values: 2}}, dimensions)
+
+	values, err := core.TopValues(ctx, ports.DimensionQuery{}, "fileExtension", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.ValueCount{{Value: "go", Count: 2}}, values)
+
+	values, err = core.TopValues(ctx, ports.DimensionQuery{}, "author", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	// The index counts the documents the caller may see
+	filter := func(models.Document) bool { return true }
+	_, err = core.TopValues(ctx, ports.DimensionQuery{Principals: []string{"alice"}, Filter: filter}, "fileExtension", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, catalog.principals)
+	assert.True(t, catalog.filtered)
+	_, err = core.ListDimensions(ctx, ports.DimensionQuery{Principals: []string{"bob"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, catalog.principals)
+	assert.False(t, catalog.filtered)
+
+	// Indexes without a catalog have no dimensions
+	dimensions, err = core.ListDimensions(ctx, ports.DimensionQuery{Namespace: "team-a"})
+	assert.NoError(t, err)
+	assert.Empty(t, dimensions)
+
+	_, err = core.ListDimensions(ctx, ports.DimensionQuery{Namespace: "unknown"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
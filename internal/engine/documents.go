@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// GetDocuments fetches documents by ID from the index of the query's namespace, leaving out those the
+// index does not hold and those the caller may not see, with the fields of the query's projection
+func (e *EngineCore) GetDocuments(ctx context.Context, query ports.SearchQuery, ids []string) ([]models.Document, error) {
+	name, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	getter, ok := index.(ports.DocumentGetterIndexPort)
+	if !ok {
+		return nil, fmt.Errorf("%w: index %s does not fetch documents by ID", ports.ErrNotSupported, name)
+	}
+	results, err := getter.GetDocuments(ports.WithPrincipals(ctx, query.Principals), ids)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]models.Document, 0, len(results))
+	for _, doc := range results {
+		// Indexes may not enforce the access control lists themselves
+		if query.Filter != nil && !query.Filter(doc) || query.Principals != nil && !doc.VisibleTo(query.Principals) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	project(docs, query.Projection)
+	return docs, nil
+}
+
+// ListDocuments returns a page of the documents of an index (the default index if name is empty), in ID
+// order
+func (e *EngineCore) ListDocuments(ctx context.Context, name string, opts ports.ListOptions) (ports.DocumentPage, error) {
+	if name == "" {
+		defaultName, _, err := e.defaultIndexPort()
+		if err != nil {
+			return ports.DocumentPage{}, err
+		}
+		name = defaultName
+	}
+	index, ok := e.index(name)
+	if !ok {
+		return ports.DocumentPage{}, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	lister, ok := index.(ports.DocumentListerIndexPort)
+	if !ok {
+		return ports.DocumentPage{}, fmt.Errorf("%w: index %s does not list its documents", ports.ErrNotSupported, name)
+	}
+	page, err := lister.ListDocuments(ctx, opts)
+	if err != nil {
+		return ports.DocumentPage{}, fmt.Errorf("failed to list documents of index %s: %w", name, err)
+	}
+	return page, nil
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// gettingIndex returns its documents with the requested IDs, recording the principals it was asked with
+type gettingIndex struct {
+	docsIndex
+	principals []string
+}
+
+func (g *gettingIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	g.principals, _ = ports.PrincipalsFrom(ctx)
+	var docs []models.Document
+	for _, id := range ids {
+		for _, doc := range g.docs {
+			if doc.ID == id {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (g *gettingIndex) ListDocuments(ctx context.Context, opts ports.ListOptions) (ports.DocumentPage, error) {
+	if opts.Where == "bad" {
+		return ports.DocumentPage{}, fmt.Errorf("%w: bad where clause", ports.ErrInvalid)
+	}
+	return ports.DocumentPage{Documents: g.docs[:opts.Limit], Total: len(g.docs), Next: g.docs[opts.Limit-1].ID}, nil
+}
+
+func TestEngineCore_GetDocuments(t *testing.T) {
+	core := NewEngineCore()
+	idx := &gettingIndex{docsIndex: docsIndex{docs: []models.Document{
+		{ID: "1", Text: "one", Source: "a.go", Meta: map[string]string{"team": "a"}},
+		{ID: "2", Text: "two", Source: "b.go", Meta: map[string]string{"team": "b"}},
+		{ID: "3", Text: "three", AllowedPrincipals: []string{"alice"}},
+	}}}
+	core.RegisterIndex("idx", idx)
+	core.RegisterIndex("plain", &docsIndex{})
+	assert.NoError(t, core.SetAlias("team-a", "plain"))
+	ctx := context.Background()
+
+	docs, err := core.GetDocuments(ctx, ports.SearchQuery{Projection: models.Projection{Include: []string{"source"}}}, []string{"2", "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "2", Source: "b.go"}, {ID: "1", Source: "a.go"}}, docs)
+
+	// Filters and principals leave out documents the caller may not see
+	docs, err = core.GetDocuments(ctx, ports.SearchQuery{
+		Filter:     func(doc models.Document) bool { return doc.Meta["team"] != "b" },
+		Principals: []string{"bob"},
+	}, []string{"1", "2", "3"})
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, []string{"bob"}, idx.principals)
+
+	_, err = core.GetDocuments(ctx, ports.SearchQuery{Namespace: "team-a"}, []string{"1"})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+	_, err = core.GetDocuments(ctx, ports.SearchQuery{Namespace: "unknown"}, []string{"1"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_ListDocuments(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &gettingIndex{docsIndex: docsIndex{docs: []models.Document{{ID: "1"}, {ID: "2"}, {ID: "3"}}}})
+	core.RegisterIndex("plain", &docsIndex{})
+	ctx := context.Background()
+
+	// The default index is listed without a name
+	page, err := core.ListDocuments(ctx, "", ports.ListOptions{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, ports.DocumentPage{Documents: []models.Document{{ID: "1"}, {ID: "2"}}, Total: 3, Next: "2"}, page)
+
+	_, err = core.ListDocuments(ctx, "idx", ports.ListOptions{Limit: 1, Where: "bad"})
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+	_, err = core.ListDocuments(ctx, "plain", ports.ListOptions{})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+	_, err = core.ListDocuments(ctx, "unknown", ports.ListOptions{})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// EventQueueSize is the number of events buffered per subscriber before new events are dropped
+const EventQueueSize = 1024
+
+// eventBus fans engine events out to subscribers. Publishing never blocks the caller:
+// each subscriber has its own queue and goroutine, and events are dropped (and counted)
+// when a subscriber falls too far behind.
+type eventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]*subscription
+	closed bool
+}
+
+type subscription struct {
+	types   map[ports.EventType]struct{} // empty means all types
+	queue   chan ports.Event
+	done    chan struct{}
+	mu      sync.Mutex
+	dropped int
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[int]*subscription)}
+}
+
+// Subscribe registers a handler for the given event types (none means all) and returns a function
+// that unsubscribes it. Events already queued for the handler are delivered before it is removed.
+func (e *EngineCore) Subscribe(handler ports.EventHandler, types ...ports.EventType) (unsubscribe func()) {
+	return e.events.subscribe(handler, types)
+}
+
+// RegisterEventSubscriber subscribes an event subscriber adapter to the events it asks for
+func (e *EngineCore) RegisterEventSubscriber(subscriber ports.EventSubscriberPort) (unsubscribe func()) {
+	return e.events.subscribe(subscriber.Handle, subscriber.Events())
+}
+
+// publish stamps and delivers an event to every interested subscriber
+func (e *EngineCore) publish(event ports.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	e.events.publish(event)
+}
+
+func (b *eventBus) subscribe(handler ports.EventHandler, types []ports.EventType) func() {
+	sub := &subscription{
+		types: make(map[ports.EventType]struct{}, len(types)),
+		queue: make(chan ports.Event, EventQueueSize),
+		done:  make(chan struct{}),
+	}
+	for _, t := range types {
+		sub.types[t] = struct{}{}
+	}
+
+	go func() {
+		defer close(sub.done)
+		for event := range sub.queue {
+			handler(event)
+		}
+	}()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(sub.queue)
+		return func() {}
+	}
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			_, ok := b.subs[id]
+			delete(b.subs, id)
+			b.mu.Unlock()
+			if ok {
+				close(sub.queue)
+				<-sub.done
+			}
+		})
+	}
+}
+
+func (b *eventBus) publish(event ports.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if len(sub.types) > 0 {
+			if _, ok := sub.types[event.Type]; !ok {
+				continue
+			}
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			dropped := sub.dropped
+			sub.mu.Unlock()
+			log.Warn().Msgf("Event subscriber queue full, dropped %s event (%d dropped so far)", event.Type, dropped)
+		}
+	}
+}
+
+// close stops accepting subscribers and waits for every queued event to be handled
+func (b *eventBus) close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[int]*subscription)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		close(sub.queue)
+		<-sub.done
+	}
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+type eventCollector struct {
+	mu     sync.Mutex
+	events []ports.Event
+}
+
+func (c *eventCollector) handle(event ports.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *eventCollector) types() []ports.EventType {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	types := make([]ports.EventType, len(c.events))
+	for i, event := range c.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestEngineCore_PublishesLifecycleEvents(t *testing.T) {
+	core := NewEngineCore()
+	all := &eventCollector{}
+	searches := &eventCollector{}
+	core.Subscribe(all.handle)
+	core.Subscribe(searches.handle, ports.EventSearchExecuted)
+
+	core.RegisterIndex("idx", &mutableRecorder{})
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(2)})
+	core.RegisterIncrementalLoader("inc", &changeLoader{changes: []models.ChangeSet{{Deleted: []string{"doc-0"}}}})
+
+	_, err := core.StreamLoader(context.Background(), "fs", "idx", 10)
+	assert.NoError(t, err)
+	_, err = core.ApplyChanges(context.Background(), "inc", "idx", 10)
+	assert.NoError(t, err)
+	_, err = core.Search(ports.SearchQuery{Query: "doc"})
+	assert.NoError(t, err)
+
+	// Stop drains every subscriber's queue
+	assert.NoError(t, core.Stop(context.Background()))
+
+	assert.Equal(t, []ports.EventType{
+		ports.EventDocumentIndexed,
+		ports.EventLoaderCompleted,
+		ports.EventDocumentDeleted,
+		ports.EventLoaderCompleted,
+		ports.EventSearchExecuted,
+	}, all.types())
+	assert.Equal(t, []string{"doc-0", "doc-1"}, all.events[0].DocumentIDs)
+	assert.Equal(t, 2, all.events[1].Results)
+	assert.Equal(t, []ports.EventType{ports.EventSearchExecuted}, searches.types())
+}
+
+func TestEngineCore_Unsubscribe(t *testing.T) {
+	core := NewEngineCore()
+	collector := &eventCollector{}
+	core.RegisterIndex("idx", &batchRecorder{})
+	unsubscribe := core.Subscribe(collector.handle)
+
+	assert.NoError(t, core.Index(models.Document{ID: "a"}))
+	unsubscribe()
+	assert.NoError(t, core.Index(models.Document{ID: "b"}))
+
+	assert.Len(t, collector.types(), 1)
+}
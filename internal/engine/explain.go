@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// ExplainQuery plans a query with the index of its namespace without running it, leaving out its select
+// clause, which only shapes the results
+func (e *EngineCore) ExplainQuery(query ports.SearchQuery) (ports.QueryPlan, error) {
+	name, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return ports.QueryPlan{}, err
+	}
+	explainer, ok := index.(ports.QueryExplainerIndexPort)
+	if !ok {
+		return ports.QueryPlan{}, fmt.Errorf("%w: index %s does not explain queries", ports.ErrNotSupported, name)
+	}
+	text := query.Query
+	if match := selectClause.FindStringSubmatchIndex(text); match != nil {
+		text = text[:match[0]]
+	}
+	plan, err := explainer.ExplainQuery(strings.TrimSpace(text))
+	if err != nil {
+		return ports.QueryPlan{}, err
+	}
+	plan.Index = name
+	return plan, nil
+}
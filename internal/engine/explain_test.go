@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// explainingIndex plans every query as a scan of its conditions, and fails the queries holding "bad"
+type explainingIndex struct {
+	docsIndex
+}
+
+func (x *explainingIndex) ExplainQuery(query string) (ports.QueryPlan, error) {
+	if query == "bad" {
+		return ports.QueryPlan{}, fmt.Errorf("%w: bad query", ports.ErrInvalid)
+	}
+	return ports.QueryPlan{Plan: "conditions", Documents: 3, Steps: []ports.PlanStep{{Condition: query, Access: "scan", Estimate: 3}}}, nil
+}
+
+func TestEngineCore_ExplainQuery(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &explainingIndex{})
+	core.RegisterIndex("plain", &docsIndex{})
+	assert.NoError(t, core.SetAlias("team-a", "plain"))
+
+	// The select clause is left out
+	plan, err := core.ExplainQuery(ports.SearchQuery{Query: "fileExtension=go select id,source"})
+	assert.NoError(t, err)
+	assert.Equal(t, ports.QueryPlan{Index: "idx", Plan: "conditions", Documents: 3,
+		Steps: []ports.PlanStep{{Condition: "fileExtension=go", Access: "scan", Estimate: 3}}}, plan)
+
+	_, err = core.ExplainQuery(ports.SearchQuery{Query: "bad"})
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+	_, err = core.ExplainQuery(ports.SearchQuery{Query: "x", Namespace: "team-a"})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+	_, err = core.ExplainQuery(ports.SearchQuery{Query: "x", Namespace: "unknown"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
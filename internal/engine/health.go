@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// DefaultStuckAfter is how long a loader run may take before health checks report the loader stuck
+const DefaultStuckAfter = 30 * time.Minute
+
+// HealthOptions configures the engine's health checks
+type HealthOptions struct {
+	// MemoryLimit fails the memory check when the Go runtime holds more bytes (0: the GOMEMLIMIT soft
+	// limit if one is set, else no limit)
+	MemoryLimit uint64
+	// StuckAfter reports a loader stuck when a run takes longer (default DefaultStuckAfter)
+	StuckAfter time.Duration
+}
+
+// SetHealthOptions configures the liveness and readiness checks
+func (e *EngineCore) SetHealthOptions(options HealthOptions) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.health = options
+}
+
+// Liveness checks every index that can check itself (e.g. that a persisted index's database is open
+// and its async worker alive), that no scheduled loader is stuck and that memory is within the limit
+func (e *EngineCore) Liveness() ports.HealthReport {
+	e.mu.RLock()
+	options := e.health
+	indexes := make(map[string]ports.IndexPort, len(e.indexes))
+	for name, index := range e.indexes {
+		indexes[name] = index
+	}
+	e.mu.RUnlock()
+
+	var components []ports.ComponentHealth
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		component := ports.ComponentHealth{Name: "index:" + name, Status: ports.HealthOK}
+		if checker, ok := indexes[name].(ports.HealthCheckIndexPort); ok {
+			if err := checker.HealthCheck(); err != nil {
+				component = ports.ComponentHealth{Name: component.Name, Status: ports.HealthFail, Message: err.Error()}
+			}
+		}
+		components = append(components, component)
+	}
+	components = append(components, e.loadersHealth(options), memoryHealth(options))
+	return healthReport(components)
+}
+
+// Readiness checks that the engine has finished its initial loads and is serving, and is not
+// shutting down, on top of the Liveness checks
+func (e *EngineCore) Readiness() ports.HealthReport {
+	e.state.mu.Lock()
+	started, stopped := e.state.started, e.state.stopped
+	e.state.mu.Unlock()
+
+	engine := ports.ComponentHealth{Name: "engine", Status: ports.HealthOK}
+	switch {
+	case stopped:
+		engine.Status, engine.Message = ports.HealthFail, "shutting down"
+	case !started:
+		engine.Status, engine.Message = ports.HealthFail, "starting: initial loads in progress"
+	}
+	liveness := e.Liveness()
+	return healthReport(append([]ports.ComponentHealth{engine}, liveness.Components...))
+}
+
+// loadersHealth fails when a scheduled loader has been running for longer than options.StuckAfter
+func (e *EngineCore) loadersHealth(options HealthOptions) ports.ComponentHealth {
+	stuckAfter := options.StuckAfter
+	if stuckAfter <= 0 {
+		stuckAfter = DefaultStuckAfter
+	}
+	var stuck []string
+	for _, status := range e.LoaderStatuses() {
+		if status.Running && time.Since(status.RunningSince) > stuckAfter {
+			stuck = append(stuck, fmt.Sprintf("%s (running for %s)", status.Name, time.Since(status.RunningSince).Round(time.Second)))
+		}
+	}
+	if len(stuck) > 0 {
+		return ports.ComponentHealth{Name: "loaders", Status: ports.HealthFail, Message: "stuck: " + strings.Join(stuck, ", ")}
+	}
+	return ports.ComponentHealth{Name: "loaders", Status: ports.HealthOK}
+}
+
+// memoryHealth compares the memory held by the Go runtime with the limit
+func memoryHealth(options HealthOptions) ports.ComponentHealth {
+	samples := []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+	metrics.Read(samples)
+	used := samples[0].Value.Uint64() - samples[1].Value.Uint64()
+
+	limit := options.MemoryLimit
+	if limit == 0 {
+		// SetMemoryLimit with a negative value only reads the limit; MaxInt64 means none is set
+		if soft := debug.SetMemoryLimit(-1); soft > 0 && soft < 1<<63-1 {
+			limit = uint64(soft)
+		}
+	}
+	component := ports.ComponentHealth{Name: "memory", Status: ports.HealthOK, Message: fmt.Sprintf("%d MiB in use", used>>20)}
+	if limit > 0 {
+		component.Message = fmt.Sprintf("%d of %d MiB in use", used>>20, limit>>20)
+		if used > limit {
+			component.Status = ports.HealthFail
+		}
+	}
+	return component
+}
+
+func healthReport(components []ports.ComponentHealth) ports.HealthReport {
+	report := ports.HealthReport{Status: ports.HealthOK, Components: components}
+	for _, component := range components {
+		if component.Status != ports.HealthOK {
+			report.Status = ports.HealthFail
+		}
+	}
+	return report
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// checkedIndex reports a configurable health check error
+type checkedIndex struct {
+	batchRecorder
+	err error
+}
+
+func (c *checkedIndex) HealthCheck() error { return c.err }
+
+func componentStatuses(report ports.HealthReport) map[string]string {
+	statuses := map[string]string{}
+	for _, component := range report.Components {
+		statuses[component.Name] = component.Status
+	}
+	return statuses
+}
+
+func TestEngineCore_Liveness(t *testing.T) {
+	core := NewEngineCore()
+	db := &checkedIndex{}
+	core.RegisterIndex("db", db)
+	core.RegisterIndex("mem", &batchRecorder{})
+
+	report := core.Liveness()
+	assert.True(t, report.Healthy())
+	assert.Equal(t, map[string]string{"index:db": "ok", "index:mem": "ok", "loaders": "ok", "memory": "ok"}, componentStatuses(report))
+
+	db.err = errors.New("database is not open")
+	report = core.Liveness()
+	assert.False(t, report.Healthy())
+	assert.Equal(t, ports.ComponentHealth{Name: "index:db", Status: ports.HealthFail, Message: "database is not open"}, report.Components[0])
+
+	// A limit below the memory in use fails the memory check
+	db.err = nil
+	core.SetHealthOptions(HealthOptions{MemoryLimit: 1})
+	assert.Equal(t, ports.HealthFail, componentStatuses(core.Liveness())["memory"])
+}
+
+func TestEngineCore_LivenessReportsStuckLoaders(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+	release := make(chan struct{})
+	loader := &changeLoader{}
+	core.RegisterIncrementalLoader("slow", blockingLoader{changeLoader: loader, release: release})
+	assert.NoError(t, core.ScheduleLoader(ScheduledLoad{Loader: "slow", Index: "idx", Interval: time.Hour}))
+	core.SetHealthOptions(HealthOptions{StuckAfter: 10 * time.Millisecond})
+
+	done := make(chan error)
+	go func() { done <- core.RunLoader(context.Background(), "slow") }()
+	assert.Eventually(t, func() bool { return componentStatuses(core.Liveness())["loaders"] == ports.HealthFail }, time.Second, 5*time.Millisecond)
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, ports.HealthOK, componentStatuses(core.Liveness())["loaders"])
+}
+
+// blockingLoader waits for release before reporting its changes
+type blockingLoader struct {
+	*changeLoader
+	release chan struct{}
+}
+
+func (l blockingLoader) LoadChanges(ctx context.Context) (models.ChangeSet, error) {
+	<-l.release
+	return l.changeLoader.LoadChanges(ctx)
+}
+
+func TestEngineCore_Readiness(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+	assert.Equal(t, ports.HealthFail, componentStatuses(core.Readiness())["engine"])
+
+	assert.NoError(t, core.Start(context.Background()))
+	assert.True(t, core.Readiness().Healthy())
+
+	assert.NoError(t, core.Stop(context.Background()))
+	report := core.Readiness()
+	assert.False(t, report.Healthy())
+	assert.Equal(t, "shutting down", report.Components[0].Message)
+}
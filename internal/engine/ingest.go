@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultBatchSize is the number of documents buffered before they are handed to an index.
+const DefaultBatchSize = 500
+
+// StreamLoader pipes documents from a registered streaming loader into a registered index in batches,
+// so peak memory is bounded by batchSize rather than by the size of the corpus.
+// It returns the number of documents indexed and the first error reported by the loader or the index.
+func (e *EngineCore) StreamLoader(ctx context.Context, loaderName, indexName string, batchSize int) (indexed int, err error) {
+	ctx, span := e.startSpan(ctx, "loader.Stream", attrLoader.String(loaderName), attrIndex.String(indexName))
+	defer func() {
+		span.SetAttributes(attrDocuments.Int(indexed))
+		endSpan(span, err)
+	}()
+	loader, ok := e.streamingLoader(loaderName)
+	if !ok {
+		return 0, fmt.Errorf("streaming loader %s not registered", loaderName)
+	}
+	index, ok := e.index(indexName)
+	if !ok {
+		return 0, fmt.Errorf("index %s not registered", indexName)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	// Cancelling stops the loader when the stream ends early, e.g. on a failed write
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	started := time.Now()
+	docs, errs := loader.Load(ctx)
+	var loadErr error
+	defer func() {
+		e.publishLoaderCompleted(loaderName, indexName, indexed, started, loadErr)
+	}()
+
+	// A fresh slice is allocated per batch because indexes may retain the slice (e.g. async persistence)
+	batch := make([]models.Document, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.extractFeatures(ctx, loaderName, batch); err != nil {
+			return err
+		}
+		if err := e.writeBatch(ctx, index, indexName, batch); err != nil {
+			return fmt.Errorf("failed to index batch from loader %s: %w", loaderName, err)
+		}
+		e.publish(ports.Event{Type: ports.EventDocumentIndexed, Index: indexName, Loader: loaderName, DocumentIDs: documentIDs(batch), Documents: batch})
+		indexed += len(batch)
+		log.Debug().Msgf("StreamLoader: indexed batch of %d documents from %s into %s", len(batch), loaderName, indexName)
+		batch = make([]models.Document, 0, batchSize)
+		return nil
+	}
+
+	for docs != nil || errs != nil {
+		select {
+		case <-ctx.Done():
+			loadErr = ctx.Err()
+			return indexed, loadErr
+		case doc, ok := <-docs:
+			if !ok {
+				docs = nil
+				continue
+			}
+			batch = append(batch, doc)
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					loadErr = err
+					return indexed, err
+				}
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil && loadErr == nil {
+				loadErr = err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		loadErr = err
+		return indexed, err
+	}
+
+	log.Info().Msgf("StreamLoader: indexed %d documents from %s into %s", indexed, loaderName, indexName)
+	return indexed, loadErr
+}
+
+// writeBatch adds a batch to an index, traced as an index write
+func (e *EngineCore) writeBatch(ctx context.Context, index ports.IndexPort, indexName string, batch []models.Document) error {
+	ctx, span := e.startSpan(ctx, "index.AddDocuments", attrIndex.String(indexName), attrDocuments.Int(len(batch)))
+	err := addBatch(ctx, index, batch)
+	endSpan(span, err)
+	return err
+}
+
+// addBatch hands a batch to the index, using the batch path when the adapter supports it.
+func addBatch(ctx context.Context, index ports.IndexPort, batch []models.Document) error {
+	if batcher, ok := index.(ports.BatchIndexPort); ok {
+		return batcher.AddDocuments(ctx, batch)
+	}
+	for _, doc := range batch {
+		if err := index.AddDocument(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyChanges asks an incremental loader for the changes since its last run and applies them to an index:
+// new documents are added in batches, modified documents are updated, and removed documents are deleted.
+// The loader's state is only committed once every change has been applied, so a failed run is retried in full.
+func (e *EngineCore) ApplyChanges(ctx context.Context, loaderName, indexName string, batchSize int) (models.ChangeSet, error) {
+	started := time.Now()
+	changes, err := e.applyChanges(ctx, loaderName, indexName, batchSize)
+	e.publishLoaderCompleted(loaderName, indexName, len(changes.Added)+len(changes.Modified), started, err)
+	return changes, err
+}
+
+func (e *EngineCore) applyChanges(ctx context.Context, loaderName, indexName string, batchSize int) (changes models.ChangeSet, err error) {
+	ctx, span := e.startSpan(ctx, "loader.ApplyChanges", attrLoader.String(loaderName), attrIndex.String(indexName))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("bitscout.added", len(changes.Added)),
+			attribute.Int("bitscout.modified", len(changes.Modified)),
+			attribute.Int("bitscout.deleted", len(changes.Deleted)))
+		endSpan(span, err)
+	}()
+	loader, ok := e.incrementalLoader(loaderName)
+	if !ok {
+		return models.ChangeSet{}, fmt.Errorf("incremental loader %s not registered", loaderName)
+	}
+	index, ok := e.index(indexName)
+	if !ok {
+		return models.ChangeSet{}, fmt.Errorf("index %s not registered", indexName)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	changes, err = loader.LoadChanges(ctx)
+	if err != nil {
+		return models.ChangeSet{}, fmt.Errorf("failed to load changes from %s: %w", loaderName, err)
+	}
+	if changes.IsEmpty() {
+		log.Debug().Msgf("ApplyChanges: no changes from %s", loaderName)
+		return changes, loader.Commit()
+	}
+
+	if err := e.extractFeatures(ctx, loaderName, changes.Added); err != nil {
+		return changes, err
+	}
+	if err := e.extractFeatures(ctx, loaderName, changes.Modified); err != nil {
+		return changes, err
+	}
+
+	for start := 0; start < len(changes.Added); start += batchSize {
+		end := start + batchSize
+		if end > len(changes.Added) {
+			end = len(changes.Added)
+		}
+		if err := e.writeBatch(ctx, index, indexName, changes.Added[start:end]); err != nil {
+			return changes, fmt.Errorf("failed to add documents from %s: %w", loaderName, err)
+		}
+		e.publish(ports.Event{Type: ports.EventDocumentIndexed, Index: indexName, Loader: loaderName, DocumentIDs: documentIDs(changes.Added[start:end]), Documents: changes.Added[start:end]})
+	}
+
+	if len(changes.Modified) > 0 || len(changes.Deleted) > 0 {
+		mutable, ok := index.(ports.MutableIndexPort)
+		if !ok {
+			return changes, fmt.Errorf("index %s does not support updates or deletions", indexName)
+		}
+		_, writeSpan := e.startSpan(ctx, "index.UpdateDocuments", attrIndex.String(indexName),
+			attribute.Int("bitscout.modified", len(changes.Modified)), attribute.Int("bitscout.deleted", len(changes.Deleted)))
+		err := e.applyUpdates(ctx, mutable, loaderName, indexName, changes)
+		endSpan(writeSpan, err)
+		if err != nil {
+			return changes, err
+		}
+	}
+
+	if err := loader.Commit(); err != nil {
+		return changes, fmt.Errorf("failed to commit loader state for %s: %w", loaderName, err)
+	}
+
+	log.Info().Msgf("ApplyChanges: applied %d added, %d modified, %d deleted from %s to %s",
+		len(changes.Added), len(changes.Modified), len(changes.Deleted), loaderName, indexName)
+	return changes, nil
+}
+
+// applyUpdates updates the modified documents of a change set and deletes the removed ones
+func (e *EngineCore) applyUpdates(ctx context.Context, mutable ports.MutableIndexPort, loaderName, indexName string, changes models.ChangeSet) error {
+	for _, doc := range changes.Modified {
+		if err := mutable.UpdateDocument(ctx, doc); err != nil {
+			return fmt.Errorf("failed to update document %s: %w", doc.ID, err)
+		}
+	}
+	if len(changes.Modified) > 0 {
+		e.publish(ports.Event{Type: ports.EventDocumentIndexed, Index: indexName, Loader: loaderName, DocumentIDs: documentIDs(changes.Modified), Documents: changes.Modified})
+	}
+	for _, id := range changes.Deleted {
+		if err := mutable.DeleteDocument(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete document %s: %w", id, err)
+		}
+	}
+	if len(changes.Deleted) > 0 {
+		e.publish(ports.Event{Type: ports.EventDocumentDeleted, Index: indexName, Loader: loaderName, DocumentIDs: changes.Deleted})
+	}
+	return nil
+}
+
+// publishLoaderCompleted reports the outcome of a loader run
+func (e *EngineCore) publishLoaderCompleted(loaderName, indexName string, indexed int, started time.Time, err error) {
+	event := ports.Event{
+		Type:     ports.EventLoaderCompleted,
+		Index:    indexName,
+		Loader:   loaderName,
+		Results:  indexed,
+		Duration: time.Since(started),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	e.publish(event)
+}
+
+func documentIDs(docs []models.Document) []string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type sliceLoader struct {
+	docs []models.Document
+	err  error
+}
+
+func (l *sliceLoader) Load(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(docs)
+		for _, doc := range l.docs {
+			docs <- doc
+		}
+		if l.err != nil {
+			errs <- l.err
+		}
+	}()
+	return docs, errs
+}
+
+type batchRecorder struct {
+	batches [][]models.Document
+}
+
+func (r *batchRecorder) AddDocument(ctx context.Context, doc interface{}) error {
+	r.batches = append(r.batches, []models.Document{doc.(models.Document)})
+	return nil
+}
+
+func (r *batchRecorder) AddDocuments(ctx context.Context, docs []models.Document) error {
+	r.batches = append(r.batches, docs)
+	return nil
+}
+
+func (r *batchRecorder) Search(ctx context.Context, query string) ([]interface{}, error) {
+	return nil, nil
+}
+func (r *batchRecorder) Count() (int, error) { return 0, nil }
+func (r *batchRecorder) Close() error        { return nil }
+
+func makeDocs(n int) []models.Document {
+	docs := make([]models.Document, n)
+	for i := range docs {
+		docs[i] = models.Document{ID: fmt.Sprintf("doc-%d", i)}
+	}
+	return docs
+}
+
+func TestEngineCore_StreamLoader_Batches(t *testing.T) {
+	core := NewEngineCore()
+	recorder := &batchRecorder{}
+	core.RegisterStreamingLoader("test", &sliceLoader{docs: makeDocs(7)})
+	core.RegisterIndex("test", recorder)
+
+	indexed, err := core.StreamLoader(context.Background(), "test", "test", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, indexed)
+	assert.Len(t, recorder.batches, 3)
+	assert.Len(t, recorder.batches[0], 3)
+	assert.Len(t, recorder.batches[2], 1)
+	assert.Equal(t, "doc-0", recorder.batches[0][0].ID)
+	assert.Equal(t, "doc-6", recorder.batches[2][0].ID)
+}
+
+func TestEngineCore_StreamLoader_ReportsLoaderError(t *testing.T) {
+	core := NewEngineCore()
+	recorder := &batchRecorder{}
+	core.RegisterStreamingLoader("test", &sliceLoader{docs: makeDocs(2), err: errors.New("boom")})
+	core.RegisterIndex("test", recorder)
+
+	indexed, err := core.StreamLoader(context.Background(), "test", "test", 10)
+	assert.Error(t, err)
+	assert.Equal(t, 2, indexed)
+}
+
+// endlessLoader streams documents until its context is done, closing stopped when it returns
+type endlessLoader struct {
+	stopped chan struct{}
+}
+
+func (l *endlessLoader) Load(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errs := make(chan error)
+	go func() {
+		defer close(l.stopped)
+		defer close(errs)
+		defer close(docs)
+		for i := 0; ; i++ {
+			select {
+			case docs <- models.Document{ID: fmt.Sprintf("doc-%d", i)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return docs, errs
+}
+
+// failingRecorder fails the batches after the first
+type failingRecorder struct {
+	batchRecorder
+}
+
+func (r *failingRecorder) AddDocuments(ctx context.Context, docs []models.Document) error {
+	if len(r.batches) > 0 {
+		return errors.New("disk full")
+	}
+	return r.batchRecorder.AddDocuments(ctx, docs)
+}
+
+func TestEngineCore_StreamLoader_StopsLoaderOnWriteError(t *testing.T) {
+	core := NewEngineCore()
+	loader := &endlessLoader{stopped: make(chan struct{})}
+	core.RegisterStreamingLoader("test", loader)
+	core.RegisterIndex("test", &failingRecorder{})
+
+	indexed, err := core.StreamLoader(context.Background(), "test", "test", 3)
+	assert.ErrorContains(t, err, "disk full")
+	assert.Equal(t, 3, indexed)
+	select {
+	case <-loader.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the loader is still streaming after the failed write")
+	}
+}
+
+func TestEngineCore_StreamLoader_UnknownComponents(t *testing.T) {
+	core := NewEngineCore()
+	_, err := core.StreamLoader(context.Background(), "missing", "missing", 10)
+	assert.Error(t, err)
+
+	core.RegisterStreamingLoader("test", &sliceLoader{})
+	_, err = core.StreamLoader(context.Background(), "test", "missing", 10)
+	assert.Error(t, err)
+}
+
+type vectorProcessor struct {
+	batches int
+	err     error
+}
+
+func (p *vectorProcessor) ProcessDocuments(ctx context.Context, docs []models.Document) error {
+	p.batches++
+	for i := range docs {
+		docs[i].Vector = append(docs[i].Vector, float64(i))
+	}
+	return p.err
+}
+
+func TestEngineCore_StreamLoader_RunsPipelineProcessor(t *testing.T) {
+	core := NewEngineCore()
+	idx := &batchRecorder{}
+	processor := &vectorProcessor{}
+	core.RegisterIndex("idx", idx)
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(5)})
+	core.RegisterFeatureExtractor("tags", tagExtractor{})
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Processor: processor, Extractors: []string{"tags"}}))
+
+	indexed, err := core.StreamLoader(context.Background(), "fs", "idx", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, indexed)
+	assert.Equal(t, 2, processor.batches)
+	assert.Equal(t, []float64{1}, idx.batches[1][1].Vector)
+	assert.Equal(t, "true", idx.batches[1][1].Meta["tagged"])
+
+	// A failing processor stops the load before the batch is indexed
+	processor.err = errors.New("extractor down")
+	_, err = core.StreamLoader(context.Background(), "fs", "idx", 3)
+	assert.ErrorContains(t, err, "extractor down")
+	assert.Len(t, idx.batches, 2)
+}
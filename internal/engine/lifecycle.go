@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// Pipeline wires a loader into an index, optionally through feature extractors.
+// Pipelines with an Interval are loaded incrementally and refreshed by the scheduler;
+// the others are streamed into their index once when the engine starts.
+type Pipeline struct {
+	Loader     string                      // Name of a registered loader
+	Index      string                      // Name of the registered index documents go into (default: the default index)
+	Extractors []string                    // Names of registered feature extractors applied to each document, in order
+	Processor  ports.DocumentProcessorPort // Optional stage run over each batch before the Extractors
+	Interval   time.Duration               // Refresh interval (0: load once at start)
+	BatchSize  int                         // Batch size used while indexing (default: DefaultBatchSize)
+}
+
+// lifecycle tracks the engine's start/stop state
+type lifecycle struct {
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	apiErrs <-chan error
+}
+
+// AddPipeline validates a pipeline against the registries and adds it to the engine.
+// Pipelines added before Start are loaded by Start; use StartPipeline for pipelines added afterwards.
+func (e *EngineCore) AddPipeline(p Pipeline) error {
+	e.mu.RLock()
+	if p.Index == "" {
+		p.Index = e.defaultIndex
+	}
+	_, indexOK := e.indexes[p.Index]
+	_, streamingOK := e.streamingLoaders[p.Loader]
+	_, exists := e.pipelines[p.Loader]
+	var missingExtractor string
+	for _, name := range p.Extractors {
+		if _, ok := e.featureExtractors[name]; !ok {
+			missingExtractor = name
+			break
+		}
+	}
+	e.mu.RUnlock()
+
+	switch {
+	case !indexOK:
+		return fmt.Errorf("pipeline %s: index %s not registered", p.Loader, p.Index)
+	case missingExtractor != "":
+		return fmt.Errorf("pipeline %s: feature extractor %s not registered", p.Loader, missingExtractor)
+	case exists:
+		return fmt.Errorf("pipeline for loader %s already added", p.Loader)
+	case p.Interval <= 0 && !streamingOK:
+		return fmt.Errorf("pipeline %s: streaming loader %s not registered", p.Loader, p.Loader)
+	}
+
+	if p.Interval > 0 {
+		job := ScheduledLoad{Loader: p.Loader, Index: p.Index, Interval: p.Interval, BatchSize: p.BatchSize}
+		if err := e.ScheduleLoader(job); err != nil {
+			return fmt.Errorf("pipeline %s: %w", p.Loader, err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pipelines[p.Loader] = p
+	e.pipelineOrder = append(e.pipelineOrder, p.Loader)
+	return nil
+}
+
+// pipeline looks up the pipeline of a loader
+func (e *EngineCore) pipeline(loaderName string) (Pipeline, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	p, ok := e.pipelines[loaderName]
+	return p, ok
+}
+
+// StartPipeline runs the initial load of a single pipeline. Scheduled pipelines load incrementally
+// from the start so their periodic refreshes only apply diffs.
+func (e *EngineCore) StartPipeline(ctx context.Context, loaderName string) error {
+	p, ok := e.pipeline(loaderName)
+	if !ok {
+		return fmt.Errorf("no pipeline for loader %s", loaderName)
+	}
+	if p.Interval > 0 {
+		if err := e.RunLoader(ctx, p.Loader); err != nil {
+			return fmt.Errorf("initial load of %s: %w", p.Loader, err)
+		}
+		return nil
+	}
+	loaded, err := e.StreamLoader(ctx, p.Loader, p.Index, p.BatchSize)
+	if err != nil {
+		return fmt.Errorf("initial load of %s: %w", p.Loader, err)
+	}
+	log.Info().Msgf("Loaded %d documents from %s", loaded, p.Loader)
+	return nil
+}
+
+// Start restores empty indexes from their latest snapshots if enabled, runs the initial load of every
+// pipeline (in the order they were added), warms up the indexes, starts the loader scheduler and the
+// snapshot loop, and finally starts the APIs once the indexes are populated.
+// The scheduler stops when ctx is cancelled; use Stop for a full graceful shutdown.
+func (e *EngineCore) Start(ctx context.Context) error {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	if e.state.started {
+		return errors.New("engine already started")
+	}
+	if e.state.stopped {
+		return errors.New("engine already stopped")
+	}
+
+	if err := e.restoreSnapshots(); err != nil {
+		return fmt.Errorf("restoring snapshots: %w", err)
+	}
+	e.mu.RLock()
+	order := append([]string(nil), e.pipelineOrder...)
+	e.mu.RUnlock()
+	for _, name := range order {
+		if err := e.StartPipeline(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	if err := e.WarmUp(ctx); err != nil {
+		log.Warn().Err(err).Msg("Warm-up failed, starting anyway")
+	}
+	e.StartScheduler(ctx)
+	e.StartSnapshots(ctx)
+	e.state.apiErrs = e.StartAPIs()
+	e.state.started = true
+	e.startedAt.Store(time.Now().UnixNano())
+	e.mu.RLock()
+	log.Info().Msgf("Engine started with %d pipelines, %d indexes and %d APIs", len(e.pipelines), len(e.indexes), len(e.apis))
+	e.mu.RUnlock()
+	return nil
+}
+
+// Errors returns the channel API failures are reported on after Start.
+// The channel is closed once every API has exited; it is nil before Start.
+func (e *EngineCore) Errors() <-chan error {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.apiErrs
+}
+
+// Stop shuts the engine down in dependency order: APIs stop accepting requests, in-flight
+// loader runs finish, indexes are flushed and closed, and pending events are delivered. It is safe to call Stop without
+// Start (indexes are still closed), but only the first call has any effect.
+// If ctx expires before shutdown completes, Stop returns ctx.Err() while shutdown carries on in the background.
+func (e *EngineCore) Stop(ctx context.Context) error {
+	e.state.mu.Lock()
+	if e.state.stopped {
+		e.state.mu.Unlock()
+		return nil
+	}
+	e.state.stopped = true
+	e.state.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		if err := e.StopAPIs(); err != nil {
+			errs = append(errs, err)
+		}
+		e.StopScheduler()
+		e.StopSnapshots()
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+		for name, index := range e.indexes {
+			if err := index.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("index %s: %w", name, err))
+			}
+		}
+		// Deliver the events published during shutdown before returning
+		e.events.close()
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		log.Info().Msg("Engine stopped")
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// extractFeatures runs a loader's pipeline processor and extractors over a batch and merges the
+// extracted features into each document's metadata. Batches are modified in place.
+func (e *EngineCore) extractFeatures(ctx context.Context, loaderName string, docs []models.Document) (err error) {
+	p, ok := e.pipeline(loaderName)
+	if !ok || len(docs) == 0 {
+		return nil
+	}
+	ctx, span := e.startSpan(ctx, "pipeline.ExtractFeatures", attrLoader.String(loaderName), attrDocuments.Int(len(docs)))
+	defer func() { endSpan(span, err) }()
+	if p.Processor != nil {
+		if err := p.Processor.ProcessDocuments(ctx, docs); err != nil {
+			return fmt.Errorf("feature extraction failed for loader %s: %w", loaderName, err)
+		}
+	}
+	if len(p.Extractors) == 0 {
+		return nil
+	}
+	extractors := make([]ports.FeatureExtractorPort, len(p.Extractors))
+	e.mu.RLock()
+	for i, name := range p.Extractors {
+		extractors[i] = e.featureExtractors[name]
+	}
+	e.mu.RUnlock()
+
+	for i := range docs {
+		for j, extractor := range extractors {
+			if extractor == nil {
+				continue // removed since the pipeline was added
+			}
+			features, err := extractor.ExtractFeatures(docs[i])
+			if err != nil {
+				return fmt.Errorf("feature extractor %s failed on document %s: %w", p.Extractors[j], docs[i].ID, err)
+			}
+			if len(features) == 0 {
+				continue
+			}
+			meta := make(map[string]string, len(docs[i].Meta)+len(features))
+			for key, value := range docs[i].Meta {
+				meta[key] = value
+			}
+			for key, value := range features {
+				meta[key] = fmt.Sprint(value)
+			}
+			docs[i].Meta = meta
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// closingIndex records whether the engine closed it on shutdown
+type closingIndex struct {
+	batchRecorder
+	closed bool
+}
+
+func (c *closingIndex) Close() error {
+	c.closed = true
+	return nil
+}
+
+// blockingAPI serves until stopped
+type blockingAPI struct {
+	stop    chan struct{}
+	stopped bool
+}
+
+func newBlockingAPI() *blockingAPI { return &blockingAPI{stop: make(chan struct{})} }
+
+func (a *blockingAPI) Name() string { return "test" }
+func (a *blockingAPI) Start() error {
+	<-a.stop
+	return nil
+}
+func (a *blockingAPI) Stop() error {
+	a.stopped = true
+	close(a.stop)
+	return nil
+}
+func (a *blockingAPI) Search(query ports.SearchQuery) (ports.SearchResults, error) {
+	return ports.SearchResults{}, nil
+}
+func (a *blockingAPI) Stats() (ports.Stats, error)     { return ports.Stats{}, nil }
+func (a *blockingAPI) Index(doc models.Document) error { return nil }
+
+type tagExtractor struct{}
+
+func (tagExtractor) ExtractFeatures(doc interface{}) (map[string]interface{}, error) {
+	return map[string]interface{}{"tagged": true}, nil
+}
+
+func TestEngineCore_StartAndStop(t *testing.T) {
+	core := NewEngineCore()
+	idx := &closingIndex{}
+	api := newBlockingAPI()
+	core.RegisterIndex("idx", idx)
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(3)})
+	core.RegisterFeatureExtractor("tags", tagExtractor{})
+	core.RegisterAPI("test", api)
+
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Extractors: []string{"tags"}}))
+	assert.NoError(t, core.Start(context.Background()))
+	assert.Error(t, core.Start(context.Background()))
+
+	assert.Len(t, idx.batches, 1)
+	assert.Equal(t, "true", idx.batches[0][0].Meta["tagged"])
+
+	assert.NoError(t, core.Stop(context.Background()))
+	assert.True(t, api.stopped)
+	assert.True(t, idx.closed)
+
+	// The error channel closes once every API has exited
+	_, open := <-core.Errors()
+	assert.False(t, open)
+}
+
+func TestEngineCore_AddPipeline_Validates(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+	core.RegisterStreamingLoader("fs", &sliceLoader{})
+
+	assert.Error(t, core.AddPipeline(Pipeline{Loader: "missing"}))
+	assert.Error(t, core.AddPipeline(Pipeline{Loader: "fs", Index: "missing"}))
+	assert.Error(t, core.AddPipeline(Pipeline{Loader: "fs", Extractors: []string{"missing"}}))
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs"}))
+	assert.Error(t, core.AddPipeline(Pipeline{Loader: "fs"}))
+}
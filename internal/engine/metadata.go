@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Index aliases and cluster metadata: with a metadata store, indexes created at runtime and aliases are
+ * changed on every node of the cluster, in the same order
+ **/
+
+// SetMetadataStore replicates index creation, drops and aliases with store (nil: changes are local).
+// The store must report the metadata it applies to ApplyClusterMetadata.
+func (e *EngineCore) SetMetadataStore(store ports.MetadataPort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.metadata = store
+}
+
+// SetAlias makes alias another name of an index, or points an existing alias to another index
+func (e *EngineCore) SetAlias(alias, index string) error {
+	e.mu.RLock()
+	metadata := e.metadata
+	_, isIndex := e.indexes[alias]
+	_, exists := e.indexes[index]
+	e.mu.RUnlock()
+	switch {
+	case alias == "" || index == "":
+		return fmt.Errorf("%w: an alias needs a name and an index", ports.ErrInvalid)
+	case isIndex:
+		return fmt.Errorf("%w: %s is an index", ports.ErrConflict, alias)
+	case !exists:
+		return fmt.Errorf("index %s: %w", index, ports.ErrNotFound)
+	}
+	if metadata != nil {
+		return metadata.Propose(ports.MetadataChange{Op: ports.MetadataSetAlias, Name: alias, Target: index})
+	}
+	e.mu.Lock()
+	e.aliases[alias] = index
+	e.mu.Unlock()
+	log.Info().Msgf("Aliased index %s as %s", index, alias)
+	return nil
+}
+
+// RemoveAlias removes an alias; the index it points to is kept
+func (e *EngineCore) RemoveAlias(alias string) error {
+	e.mu.RLock()
+	metadata := e.metadata
+	_, exists := e.aliases[alias]
+	e.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("alias %s: %w", alias, ports.ErrNotFound)
+	}
+	if metadata != nil {
+		return metadata.Propose(ports.MetadataChange{Op: ports.MetadataRemoveAlias, Name: alias})
+	}
+	e.mu.Lock()
+	delete(e.aliases, alias)
+	e.mu.Unlock()
+	log.Info().Msgf("Removed alias %s", alias)
+	return nil
+}
+
+// Aliases returns the aliases of indexes: alias -> index
+func (e *EngineCore) Aliases() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	aliases := make(map[string]string, len(e.aliases))
+	for alias, index := range e.aliases {
+		aliases[alias] = index
+	}
+	return aliases
+}
+
+// ApplyClusterMetadata brings the engine in line with the cluster metadata: indexes it lists are created
+// with the index provider, indexes created from earlier metadata that it no longer lists are dropped, and
+// the aliases are replaced by its aliases. Failures are logged, as other nodes already applied the change.
+func (e *EngineCore) ApplyClusterMetadata(meta ports.ClusterMetadata) {
+	e.mu.RLock()
+	provider := e.indexProvider
+	var create []ports.IndexSpec
+	for name, spec := range meta.Indexes {
+		if _, exists := e.indexes[name]; !exists {
+			create = append(create, spec)
+		}
+	}
+	var drop []string
+	for name := range e.replicated {
+		if _, listed := meta.Indexes[name]; !listed {
+			drop = append(drop, name)
+		}
+	}
+	e.mu.RUnlock()
+	sort.Slice(create, func(i, j int) bool { return create[i].Name < create[j].Name })
+	sort.Strings(drop)
+
+	for _, spec := range create {
+		if provider == nil {
+			log.Error().Msgf("Cannot create replicated index %s: no index provider", spec.Name)
+			continue
+		}
+		if err := e.createIndex(provider, spec); err != nil {
+			log.Error().Err(err).Msgf("Failed to create replicated index %s", spec.Name)
+			continue
+		}
+		e.mu.Lock()
+		e.replicated[spec.Name] = true
+		e.mu.Unlock()
+	}
+	for _, name := range drop {
+		if err := e.dropIndex(name); err != nil {
+			log.Error().Err(err).Msgf("Failed to drop replicated index %s", name)
+		}
+	}
+
+	aliases := make(map[string]string, len(meta.Aliases))
+	for alias, index := range meta.Aliases {
+		aliases[alias] = index
+	}
+	e.mu.Lock()
+	e.aliases = aliases
+	e.mu.Unlock()
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// localMetadata applies proposed changes at once, as a single node cluster would, and reports the
+// metadata to the engine
+type localMetadata struct {
+	core     *EngineCore
+	meta     ports.ClusterMetadata
+	proposed []ports.MetadataOp
+}
+
+func newLocalMetadata(core *EngineCore) *localMetadata {
+	return &localMetadata{core: core, meta: ports.ClusterMetadata{Indexes: map[string]ports.IndexSpec{}, Aliases: map[string]string{}}}
+}
+
+func (m *localMetadata) Propose(change ports.MetadataChange) error {
+	m.proposed = append(m.proposed, change.Op)
+	switch change.Op {
+	case ports.MetadataCreateIndex:
+		m.meta.Indexes[change.Index.Name] = change.Index
+	case ports.MetadataDropIndex:
+		delete(m.meta.Indexes, change.Name)
+		for alias, target := range m.meta.Aliases {
+			if target == change.Name {
+				delete(m.meta.Aliases, alias)
+			}
+		}
+	case ports.MetadataSetAlias:
+		m.meta.Aliases[change.Name] = change.Target
+	case ports.MetadataRemoveAlias:
+		delete(m.meta.Aliases, change.Name)
+	default:
+		return fmt.Errorf("%w: %s", ports.ErrInvalid, change.Op)
+	}
+	m.core.ApplyClusterMetadata(m.meta)
+	return nil
+}
+
+func (m *localMetadata) Metadata() ports.ClusterMetadata { return m.meta }
+
+func TestEngineCore_Aliases(t *testing.T) {
+	core := NewEngineCore()
+	docs := &batchRecorder{}
+	core.RegisterIndex("docs", &batchRecorder{})
+	core.RegisterIndex("docs-v2", docs)
+
+	assert.ErrorIs(t, core.SetAlias("current", "missing"), ports.ErrNotFound)
+	assert.ErrorIs(t, core.SetAlias("docs", "docs-v2"), ports.ErrConflict)
+	assert.ErrorIs(t, core.RemoveAlias("current"), ports.ErrNotFound)
+
+	// Indexes are reachable by their aliases
+	assert.NoError(t, core.SetAlias("current", "docs-v2"))
+	assert.Equal(t, map[string]string{"current": "docs-v2"}, core.Aliases())
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(2)})
+	_, err := core.StreamLoader(context.Background(), "fs", "current", 10)
+	assert.NoError(t, err)
+	assert.Len(t, docs.batches, 1)
+
+	// Dropping an index removes its aliases
+	assert.NoError(t, core.DropIndex("docs-v2"))
+	assert.Empty(t, core.Aliases())
+	assert.NoError(t, core.SetAlias("current", "docs"))
+	assert.NoError(t, core.RemoveAlias("current"))
+	assert.Empty(t, core.Aliases())
+}
+
+func TestEngineCore_ClusterMetadata(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("docs", &batchRecorder{})
+	created := map[string]*closingIndex{}
+	core.SetIndexProvider(func(spec ports.IndexSpec) (ports.IndexPort, error) {
+		created[spec.Name] = &closingIndex{}
+		return created[spec.Name], nil
+	})
+	metadata := newLocalMetadata(core)
+	core.SetMetadataStore(metadata)
+
+	// Creation, aliases and drops go through the metadata store
+	assert.NoError(t, core.CreateIndex(ports.IndexSpec{Name: "logs", Type: "simple"}))
+	assert.Contains(t, created, "logs")
+	assert.ErrorIs(t, core.CreateIndex(ports.IndexSpec{Name: "logs", Type: "simple"}), ports.ErrConflict)
+	assert.NoError(t, core.SetAlias("current", "logs"))
+	_, ok := core.index("current")
+	assert.True(t, ok)
+	assert.NoError(t, core.DropIndex("logs"))
+	assert.True(t, created["logs"].closed)
+	assert.Empty(t, core.Aliases())
+	assert.Equal(t, []ports.MetadataOp{ports.MetadataCreateIndex, ports.MetadataSetAlias, ports.MetadataDropIndex}, metadata.proposed)
+
+	// Metadata applied by other nodes creates and drops indexes here
+	core.ApplyClusterMetadata(ports.ClusterMetadata{Indexes: map[string]ports.IndexSpec{"audit": {Name: "audit", Type: "simple"}}})
+	_, ok = core.index("audit")
+	assert.True(t, ok)
+	core.ApplyClusterMetadata(ports.ClusterMetadata{})
+	_, ok = core.index("audit")
+	assert.False(t, ok)
+
+	// Indexes of the config are not replicated and are dropped locally
+	core.RegisterIndex("scratch", &closingIndex{})
+	assert.NoError(t, core.DropIndex("scratch"))
+	assert.Len(t, metadata.proposed, 3)
+}
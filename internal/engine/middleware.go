@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// SearchHandler executes a search request
+type SearchHandler func(query ports.SearchQuery) (ports.SearchResults, error)
+
+// SearchMiddleware wraps a SearchHandler, e.g. to rewrite queries, filter results, log or rate limit.
+// Middlewares apply to every Search regardless of which API adapter received it.
+type SearchMiddleware func(next SearchHandler) SearchHandler
+
+// UseSearchMiddleware appends middlewares to the search pipeline. The first registered middleware
+// is the outermost: it sees the request first and the results last.
+func (e *EngineCore) UseSearchMiddleware(middlewares ...SearchMiddleware) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.searchMiddlewares = append(e.searchMiddlewares, middlewares...)
+}
+
+// searchChain builds the search pipeline ending in the default index search, traced as part of ctx.
+// The index search records how it evaluated the query in evaluation.
+func (e *EngineCore) searchChain(ctx context.Context, index ports.IndexPort, evaluation *searchEvaluation) SearchHandler {
+	e.mu.RLock()
+	middlewares := e.searchMiddlewares
+	e.mu.RUnlock()
+
+	handler := func(query ports.SearchQuery) (ports.SearchResults, error) {
+		return e.search(ctx, index, query, evaluation)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// RewriteQuery rewrites every query before it reaches the index (e.g. synonyms, default filters)
+func RewriteQuery(rewrite func(query string) string) SearchMiddleware {
+	return func(next SearchHandler) SearchHandler {
+		return func(query ports.SearchQuery) (ports.SearchResults, error) {
+			query.Query = rewrite(query.Query)
+			return next(query)
+		}
+	}
+}
+
+// FilterResults drops the results a caller may not see
+func FilterResults(allow func(doc models.Document) bool) SearchMiddleware {
+	return func(next SearchHandler) SearchHandler {
+		return func(query ports.SearchQuery) (ports.SearchResults, error) {
+			results, err := next(query)
+			if err != nil {
+				return results, err
+			}
+			kept := results.Documents[:0:0]
+			for _, doc := range results.Documents {
+				if allow(doc) {
+					kept = append(kept, doc)
+				}
+			}
+			results.Documents = kept
+			return results, nil
+		}
+	}
+}
+
+// LogSearches logs every query with its result count and latency
+func LogSearches() SearchMiddleware {
+	return func(next SearchHandler) SearchHandler {
+		return func(query ports.SearchQuery) (ports.SearchResults, error) {
+			started := time.Now()
+			results, err := next(query)
+			if err != nil {
+				log.Warn().Msgf("Search %q failed after %s: %s", query.Query, time.Since(started), err)
+			} else {
+				log.Info().Msgf("Search %q returned %d results in %s", query.Query, len(results.Documents), time.Since(started))
+			}
+			return results, err
+		}
+	}
+}
+
+// RateLimit rejects searches beyond ratePerSecond (with bursts up to burst) with ports.ErrRateLimited
+func RateLimit(ratePerSecond float64, burst int) SearchMiddleware {
+	bucket := newTokenBucket(ratePerSecond, burst)
+	return func(next SearchHandler) SearchHandler {
+		return func(query ports.SearchQuery) (ports.SearchResults, error) {
+			if !bucket.take() {
+				return ports.SearchResults{}, fmt.Errorf("%w: more than %g searches per second", ports.ErrRateLimited, ratePerSecond)
+			}
+			return next(query)
+		}
+	}
+}
+
+// tokenBucket is a minimal token bucket rate limiter
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // tokens added per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, capacity: float64(burst), tokens: float64(burst), last: time.Now(), now: time.Now}
+}
+
+// take consumes a token if one is available
+func (b *tokenBucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
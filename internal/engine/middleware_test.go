@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// docsIndex returns its documents for every search and records the last query
+type docsIndex struct {
+	batchRecorder
+	docs      []models.Document
+	lastQuery string
+}
+
+func (d *docsIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	d.lastQuery = query
+	out := make([]interface{}, len(d.docs))
+	for i, doc := range d.docs {
+		out[i] = doc
+	}
+	return out, nil
+}
+
+func TestEngineCore_SearchMiddlewareOrder(t *testing.T) {
+	core := NewEngineCore()
+	idx := &docsIndex{docs: []models.Document{{ID: "public"}, {ID: "secret"}}}
+	core.RegisterIndex("idx", idx)
+
+	var order []string
+	trace := func(name string) SearchMiddleware {
+		return func(next SearchHandler) SearchHandler {
+			return func(query ports.SearchQuery) (ports.SearchResults, error) {
+				order = append(order, name)
+				return next(query)
+			}
+		}
+	}
+	core.UseSearchMiddleware(
+		trace("outer"),
+		RewriteQuery(strings.ToLower),
+		FilterResults(func(doc models.Document) bool { return doc.ID != "secret" }),
+		trace("inner"),
+	)
+
+	results, err := core.Search(ports.SearchQuery{Query: "README"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.Equal(t, "readme", idx.lastQuery)
+	assert.Len(t, results.Documents, 1)
+	assert.Equal(t, "public", results.Documents[0].ID)
+}
+
+func TestEngineCore_SearchQueryFilter(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{
+		{ID: "1", Meta: map[string]string{"directory": "/public"}},
+		{ID: "2", Meta: map[string]string{"directory": "/private"}},
+	}})
+
+	filter := func(doc models.Document) bool { return strings.Contains(doc.Meta["directory"], "public") }
+	results, err := core.Search(ports.SearchQuery{Query: "doc", Filter: filter})
+	assert.NoError(t, err)
+	assert.Len(t, results.Documents, 1)
+	assert.Equal(t, "1", results.Documents[0].ID)
+}
+
+// principalsIndex records the principals its searches are restricted to
+type principalsIndex struct {
+	docsIndex
+	principals []string
+}
+
+func (p *principalsIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	p.principals, _ = ports.PrincipalsFrom(ctx)
+	return p.docsIndex.Search(ctx, query)
+}
+
+func TestEngineCore_SearchAccessControlLists(t *testing.T) {
+	core := NewEngineCore()
+	idx := &principalsIndex{docsIndex: docsIndex{docs: []models.Document{
+		{ID: "1"},
+		{ID: "2", AllowedPrincipals: []string{"finance"}},
+	}}}
+	core.RegisterIndex("idx", idx)
+
+	// The index receives the caller's principals; results it does not filter itself are dropped
+	results, err := core.Search(ports.SearchQuery{Query: "doc", Principals: []string{"sales"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sales"}, idx.principals)
+	assert.Equal(t, []models.Document{{ID: "1"}}, results.Documents)
+
+	results, err = core.Search(ports.SearchQuery{Query: "doc", Principals: []string{"finance"}})
+	assert.NoError(t, err)
+	assert.Len(t, results.Documents, 2)
+	results, err = core.Search(ports.SearchQuery{Query: "doc"})
+	assert.NoError(t, err)
+	assert.Nil(t, idx.principals)
+	assert.Len(t, results.Documents, 2)
+}
+
+// blockingIndex blocks every search until its context is done
+type blockingIndex struct{ batchRecorder }
+
+func (b *blockingIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestEngineCore_SearchTimeout(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &blockingIndex{})
+	core.SetSearchTimeout(10 * time.Millisecond)
+
+	_, err := core.Search(ports.SearchQuery{Query: "slow"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "timed out after 10ms")
+	stats, _ := core.Stats()
+	assert.Equal(t, int64(1), stats.Queries.Failed)
+}
+
+func TestEngineCore_SearchCancelled(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &blockingIndex{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := core.SearchContext(ctx, ports.SearchQuery{Query: "slow"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRateLimit(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{})
+	core.UseSearchMiddleware(RateLimit(0.001, 2))
+
+	_, err := core.Search(ports.SearchQuery{Query: "a"})
+	assert.NoError(t, err)
+	_, err = core.Search(ports.SearchQuery{Query: "b"})
+	assert.NoError(t, err)
+	_, err = core.Search(ports.SearchQuery{Query: "c"})
+	assert.True(t, errors.Is(err, ports.ErrRateLimited))
+}
+
+func TestTokenBucket_Refills(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(10, 1)
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	assert.True(t, bucket.take())
+	assert.False(t, bucket.take())
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, bucket.take())
+}
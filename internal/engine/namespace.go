@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Tenant namespaces: a namespace names an index (or alias) of its own, which the callers bound to it
+ * index into, search and count instead of the default index
+ **/
+
+// namespaceIndex returns the name and adapter of the index of a namespace, resolving aliases ("": the
+// default index)
+func (e *EngineCore) namespaceIndex(namespace string) (string, ports.IndexPort, error) {
+	if namespace == "" {
+		return e.defaultIndexPort()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	name := namespace
+	if _, ok := e.indexes[name]; !ok {
+		name = e.aliases[namespace]
+	}
+	index, ok := e.indexes[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: namespace %s has no index", ports.ErrNotFound, namespace)
+	}
+	return name, index, nil
+}
+
+// IndexNamespace adds a document to the index of a namespace ("": the default index)
+func (e *EngineCore) IndexNamespace(namespace string, doc models.Document) error {
+	return e.IndexContext(context.Background(), namespace, doc)
+}
+
+// IndexContext adds a document to the index of a namespace ("": the default index), passing ctx to it
+func (e *EngineCore) IndexContext(ctx context.Context, namespace string, doc models.Document) error {
+	name, index, err := e.namespaceIndex(namespace)
+	if err != nil {
+		return err
+	}
+	if err := index.AddDocument(ctx, doc); err != nil {
+		return err
+	}
+	e.publish(ports.Event{Type: ports.EventDocumentIndexed, Index: name, DocumentIDs: []string{doc.ID}, Documents: []models.Document{doc}})
+	return nil
+}
+
+// NamespaceStats returns the document count and size of a namespace's index and the engine's uptime.
+// Other indexes, loaders, memory usage and search counters are left out, as they concern every tenant.
+func (e *EngineCore) NamespaceStats(namespace string) (ports.Stats, error) {
+	var stats ports.Stats
+	if started := e.startedAt.Load(); started != 0 {
+		stats.StartedAt = time.Unix(0, started).UTC()
+		stats.Uptime = time.Since(stats.StartedAt)
+	}
+	name, index, err := e.namespaceIndex(namespace)
+	if err != nil {
+		return stats, err
+	}
+	count, err := index.Count()
+	if err != nil {
+		return stats, fmt.Errorf("failed to count documents in index %s: %w", name, err)
+	}
+	status := indexStatus(name, count, index)
+	stats.NumDocuments = count
+	stats.Indexes = []ports.IndexStatus{status}
+	return stats, nil
+}
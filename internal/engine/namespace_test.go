@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestEngineCore_NamespacesUseTheirIndex(t *testing.T) {
+	core := NewEngineCore()
+	shared := &docsIndex{docs: []models.Document{{ID: "shared"}}}
+	teamA := &docsIndex{docs: []models.Document{{ID: "a"}}}
+	core.RegisterIndex("shared", shared)
+	core.RegisterIndex("team-a", teamA)
+	assert.NoError(t, core.SetAlias("a", "team-a"))
+
+	results, err := core.Search(ports.SearchQuery{Query: "x", Namespace: "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "a"}}, results.Documents)
+	results, err = core.Search(ports.SearchQuery{Query: "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "shared"}}, results.Documents)
+	_, err = core.Search(ports.SearchQuery{Query: "x", Namespace: "team-b"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	assert.NoError(t, core.IndexNamespace("team-a", models.Document{ID: "1"}))
+	_, err = core.BulkNamespace(context.Background(), "a", []ports.BulkItem{{Action: ports.BulkIndex, Document: models.Document{ID: "2"}}})
+	assert.NoError(t, err)
+	assert.Len(t, teamA.batches, 2)
+	assert.Empty(t, shared.batches)
+	assert.ErrorIs(t, core.IndexNamespace("team-b", models.Document{ID: "1"}), ports.ErrNotFound)
+	_, err = core.BulkNamespace(context.Background(), "team-b", nil)
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_NamespaceStats(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("shared", &batchRecorder{})
+	core.RegisterIndex("team-a", &sizedIndex{})
+
+	stats, err := core.NamespaceStats("team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.IndexStatus{{Name: "team-a", NumDocuments: 0, SizeBytes: 4096}}, stats.Indexes)
+	assert.Empty(t, stats.Loaders)
+	assert.Zero(t, stats.Queries.Total)
+
+	_, err = core.NamespaceStats("team-b")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_SubscribeSearchInNamespace(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("shared", &batchRecorder{})
+	core.RegisterIndex("team-a", &batchRecorder{})
+	core.SetQueryMatcher(func(query string, doc models.Document) bool { return strings.Contains(doc.Text, query) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	matches, err := core.SubscribeSearch(ctx, ports.SearchQuery{Query: "go", Namespace: "team-a"})
+	assert.NoError(t, err)
+	_, err = core.SubscribeSearch(ctx, ports.SearchQuery{Query: "go", Namespace: "team-b"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	assert.NoError(t, core.Index(models.Document{ID: "shared", Text: "go"}))
+	assert.NoError(t, core.IndexNamespace("team-a", models.Document{ID: "a", Text: "go"}))
+	select {
+	case match := <-matches:
+		assert.Equal(t, "team-a", match.Index)
+		assert.Equal(t, "a", match.Document.ID)
+	case <-time.After(time.Second):
+		t.Fatal("no match pushed")
+	}
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Percolator (reverse search): stored queries are matched against documents as they are indexed, and
+ * every match is published as a query_matched event, e.g. for webhooks alerting on new content
+ **/
+
+// percolator holds the stored queries, by ID
+type percolator struct {
+	mu      sync.RWMutex
+	queries map[string]ports.StoredQuery
+	// Stops matching indexed documents (nil: not watching yet)
+	unsubscribe func()
+}
+
+// RegisterQuery stores a standing query, matched against every document indexed from now on
+func (e *EngineCore) RegisterQuery(query ports.StoredQuery) (ports.StoredQuery, error) {
+	e.mu.RLock()
+	matcher := e.matcher
+	e.mu.RUnlock()
+	if matcher == nil {
+		return ports.StoredQuery{}, fmt.Errorf("%w: stored queries need a query matcher", ports.ErrNotSupported)
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		return ports.StoredQuery{}, fmt.Errorf("%w: stored queries need a query", ports.ErrInvalid)
+	}
+	if query.Namespace != "" {
+		if _, _, err := e.namespaceIndex(query.Namespace); err != nil {
+			return ports.StoredQuery{}, err
+		}
+	}
+	if query.ID == "" {
+		query.ID = uuid.New().String()
+	}
+	query.Created = time.Now()
+
+	p := &e.percolator
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.queries[query.ID]; ok {
+		return ports.StoredQuery{}, fmt.Errorf("%w: stored query %s already exists", ports.ErrConflict, query.ID)
+	}
+	if p.queries == nil {
+		p.queries = make(map[string]ports.StoredQuery)
+	}
+	p.queries[query.ID] = query
+	if p.unsubscribe == nil {
+		p.unsubscribe = e.Subscribe(e.percolate, ports.EventDocumentIndexed)
+	}
+	log.Info().Msgf("Registered stored query %s: %q", query.ID, query.Query)
+	return query, nil
+}
+
+// DeleteQuery removes a stored query of a namespace ("": of any namespace)
+func (e *EngineCore) DeleteQuery(namespace, id string) error {
+	p := &e.percolator
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	query, ok := p.queries[id]
+	if !ok || (namespace != "" && query.Namespace != namespace) {
+		return fmt.Errorf("%w: stored query %s", ports.ErrNotFound, id)
+	}
+	delete(p.queries, id)
+	log.Info().Msgf("Deleted stored query %s", id)
+	return nil
+}
+
+// ListQueries returns the stored queries of a namespace ("": every query), oldest first
+func (e *EngineCore) ListQueries(namespace string) ([]ports.StoredQuery, error) {
+	return e.storedQueries(namespace), nil
+}
+
+// Percolate returns the stored queries of a namespace ("": every query) that a document matches, without
+// indexing it
+func (e *EngineCore) Percolate(namespace string, doc models.Document) ([]ports.StoredQuery, error) {
+	e.mu.RLock()
+	matcher := e.matcher
+	e.mu.RUnlock()
+	if matcher == nil {
+		return nil, fmt.Errorf("%w: stored queries need a query matcher", ports.ErrNotSupported)
+	}
+	matches := []ports.StoredQuery{}
+	for _, query := range e.storedQueries(namespace) {
+		if storedQueryMatches(matcher, query, doc) {
+			matches = append(matches, query)
+		}
+	}
+	return matches, nil
+}
+
+// storedQueries returns the stored queries of a namespace ("": every query), oldest first
+func (e *EngineCore) storedQueries(namespace string) []ports.StoredQuery {
+	p := &e.percolator
+	p.mu.RLock()
+	queries := make([]ports.StoredQuery, 0, len(p.queries))
+	for _, query := range p.queries {
+		if namespace == "" || query.Namespace == namespace {
+			queries = append(queries, query)
+		}
+	}
+	p.mu.RUnlock()
+	sort.Slice(queries, func(i, j int) bool {
+		if !queries[i].Created.Equal(queries[j].Created) {
+			return queries[i].Created.Before(queries[j].Created)
+		}
+		return queries[i].ID < queries[j].ID
+	})
+	return queries
+}
+
+// percolate publishes a query_matched event for every stored query matched by indexed documents
+func (e *EngineCore) percolate(event ports.Event) {
+	e.mu.RLock()
+	matcher := e.matcher
+	e.mu.RUnlock()
+	if matcher == nil {
+		return
+	}
+	for _, query := range e.storedQueries("") {
+		// The queries of a namespace only see the documents of its index
+		if query.Namespace != "" {
+			if index, _, err := e.namespaceIndex(query.Namespace); err != nil || index != event.Index {
+				continue
+			}
+		}
+		var matched []models.Document
+		for _, doc := range event.Documents {
+			if storedQueryMatches(matcher, query, doc) {
+				matched = append(matched, doc)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		e.publish(ports.Event{
+			Type:        ports.EventQueryMatched,
+			Index:       event.Index,
+			Query:       query.Query,
+			QueryID:     query.ID,
+			DocumentIDs: documentIDs(matched),
+			Documents:   matched,
+		})
+	}
+}
+
+// storedQueryMatches reports whether a document matches a stored query and is visible to its principals
+func storedQueryMatches(matcher QueryMatcher, query ports.StoredQuery, doc models.Document) bool {
+	return matcher(query.Query, doc) && (query.Principals == nil || doc.VisibleTo(query.Principals))
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestEngineCore_StoredQueries(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("shared", &docsIndex{})
+	core.RegisterIndex("team-a", &docsIndex{})
+
+	_, err := core.RegisterQuery(ports.StoredQuery{Query: "go"})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+
+	core.SetQueryMatcher(func(query string, doc models.Document) bool { return strings.Contains(doc.Text, query) })
+	golang, err := core.RegisterQuery(ports.StoredQuery{Query: "go"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, golang.ID)
+	rust, err := core.RegisterQuery(ports.StoredQuery{ID: "rust", Query: "rust", Namespace: "team-a"})
+	assert.NoError(t, err)
+	_, err = core.RegisterQuery(ports.StoredQuery{ID: "rust", Query: "rust"})
+	assert.ErrorIs(t, err, ports.ErrConflict)
+	_, err = core.RegisterQuery(ports.StoredQuery{Query: " "})
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+	_, err = core.RegisterQuery(ports.StoredQuery{Query: "go", Namespace: "team-b"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	queries, err := core.ListQueries("")
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.StoredQuery{golang, rust}, queries)
+	queries, err = core.ListQueries("team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.StoredQuery{rust}, queries)
+
+	matches, err := core.Percolate("", models.Document{ID: "1", Text: "go and rust"})
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.StoredQuery{golang, rust}, matches)
+	matches, err = core.Percolate("team-a", models.Document{ID: "1", Text: "go"})
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	assert.ErrorIs(t, core.DeleteQuery("team-a", golang.ID), ports.ErrNotFound)
+	assert.NoError(t, core.DeleteQuery("", golang.ID))
+	assert.ErrorIs(t, core.DeleteQuery("", golang.ID), ports.ErrNotFound)
+}
+
+func TestEngineCore_PercolateIndexedDocuments(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("shared", &docsIndex{})
+	core.RegisterIndex("team-a", &docsIndex{})
+	core.SetQueryMatcher(func(query string, doc models.Document) bool { return strings.Contains(doc.Text, query) })
+	matched := make(chan ports.Event, 10)
+	defer core.Subscribe(func(event ports.Event) { matched <- event }, ports.EventQueryMatched)()
+
+	_, err := core.RegisterQuery(ports.StoredQuery{ID: "any", Query: "go"})
+	assert.NoError(t, err)
+	_, err = core.RegisterQuery(ports.StoredQuery{ID: "team", Query: "go", Namespace: "team-a"})
+	assert.NoError(t, err)
+	_, err = core.RegisterQuery(ports.StoredQuery{ID: "private", Query: "go", Principals: []string{"bob"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, core.Index(models.Document{ID: "1", Text: "go", AllowedPrincipals: []string{"alice"}}))
+	assert.NoError(t, core.Index(models.Document{ID: "2", Text: "rust"}))
+	assert.NoError(t, core.IndexNamespace("team-a", models.Document{ID: "3", Text: "go"}))
+
+	var events []ports.Event
+	for len(events) < 4 {
+		select {
+		case event := <-matched:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d query_matched events, want 4", len(events))
+		}
+	}
+	// The namespace's query only matches its documents, and the private query only those bob may see
+	got := make(map[string][]string)
+	for _, event := range events {
+		assert.Equal(t, "go", event.Query)
+		got[event.QueryID+"@"+event.Index] = event.DocumentIDs
+	}
+	assert.Equal(t, map[string][]string{"any@shared": {"1"}, "any@team-a": {"3"}, "team@team-a": {"3"}, "private@team-a": {"3"}}, got)
+	select {
+	case event := <-matched:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
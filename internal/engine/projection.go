@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// selectClause matches the select clause ending a query, e.g. " select id,source,-text"
+var selectClause = regexp.MustCompile(`(?:^|\s)select\s+(-?[\w.]+(?:\s*,\s*-?[\w.]+)*)\s*$`)
+
+// withSelect moves the select clause ending a query into its projection
+func withSelect(query ports.SearchQuery) (ports.SearchQuery, error) {
+	match := selectClause.FindStringSubmatchIndex(query.Query)
+	if match == nil {
+		return query, nil
+	}
+	projection, err := models.ParseProjection(strings.Split(query.Query[match[2]:match[3]], ","))
+	if err != nil {
+		return query, fmt.Errorf("invalid select clause: %s: %w", err, ports.ErrInvalid)
+	}
+	query.Projection = query.Projection.Merge(projection)
+	query.Query = strings.TrimSpace(query.Query[:match[0]])
+	if query.Query == "" {
+		return query, fmt.Errorf("query has a select clause but nothing to search: %w", ports.ErrInvalid)
+	}
+	return query, nil
+}
+
+// project applies a projection to search results
+func project(docs []models.Document, projection models.Projection) {
+	if projection.IsZero() {
+		return
+	}
+	for i := range docs {
+		docs[i] = projection.Apply(docs[i])
+	}
+}
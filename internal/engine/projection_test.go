@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestEngineCore_SearchSelectsFields(t *testing.T) {
+	core := NewEngineCore()
+	meta := map[string]string{"filename": "a.go", "owner": "ops"}
+	idx := &docsIndex{docs: []models.Document{{ID: "1", Text: "a long text", Source: "/src/a.go", Meta: meta}}}
+	core.RegisterIndex("idx", idx)
+
+	// The select clause is removed from the query the index evaluates
+	results, err := core.Search(ports.SearchQuery{Query: "fileExtension=go select id,source,filename"})
+	assert.NoError(t, err)
+	assert.Equal(t, "fileExtension=go", idx.lastQuery)
+	assert.Equal(t, []models.Document{{ID: "1", Source: "/src/a.go", Meta: map[string]string{"filename": "a.go"}}}, results.Documents)
+
+	results, err = core.Search(ports.SearchQuery{Query: "go", Projection: models.Projection{Exclude: []string{"text", "owner"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "1", Source: "/src/a.go", Meta: map[string]string{"filename": "a.go"}}}, results.Documents)
+	assert.Equal(t, map[string]string{"filename": "a.go", "owner": "ops"}, meta, "the indexed document is unchanged")
+
+	for _, query := range []string{"select id", "go select -id", "select -text"} {
+		_, err = core.Search(ports.SearchQuery{Query: query})
+		assert.ErrorIs(t, err, ports.ErrInvalid, query)
+	}
+}
+
+func TestEngineCore_SubscribeSearchSelectsFields(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+	core.SetQueryMatcher(func(query string, doc models.Document) bool { return query == "go" })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	matches, err := core.SubscribeSearch(ctx, ports.SearchQuery{Query: "go select -text"})
+	assert.NoError(t, err)
+	assert.NoError(t, core.Index(models.Document{ID: "1", Text: "a long text", Source: "/src/a.go"}))
+	select {
+	case match := <-matches:
+		assert.Equal(t, models.Document{ID: "1", Source: "/src/a.go"}, match.Document)
+	case <-time.After(time.Second):
+		t.Fatal("no match")
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// QueryLogEntry is a line of the query log
+type QueryLogEntry struct {
+	Time       time.Time     `json:"time"`
+	Query      string        `json:"query"`
+	AST        interface{}   `json:"ast,omitempty"`         // The query as parsed by QueryLogOptions.Parse
+	ParseError string        `json:"parse_error,omitempty"` // Why the query did not parse (it ran as a text search)
+	Index      string        `json:"index"`
+	Caller     string        `json:"caller,omitempty"`
+	Results    int           `json:"results"`
+	LatencyMS  float64       `json:"latency_ms"`
+	Error      string        `json:"error,omitempty"`
+	Slow       bool          `json:"slow,omitempty"`
+	Details    *QueryDetails `json:"details,omitempty"` // Recorded for slow queries only
+}
+
+// QueryDetails describes how a slow query was evaluated
+type QueryDetails struct {
+	EvaluatedQuery string   `json:"evaluated_query"`  // The query as rewritten by the search middlewares
+	IndexLatencyMS float64  `json:"index_latency_ms"` // Time spent in the index; the rest went to the middlewares
+	IndexResults   int      `json:"index_results"`    // Hits of the index, before middlewares filtered them
+	DocumentIDs    []string `json:"document_ids"`     // The results returned, in order
+}
+
+// QueryLogOptions configures a QueryLog
+type QueryLogOptions struct {
+	// Queries taking at least SlowThreshold are logged with their evaluation details and reported as
+	// warnings (0 disables the slow-query log)
+	SlowThreshold time.Duration
+	// Parse returns the AST of a query for the log (nil: not recorded)
+	Parse func(query string) (interface{}, error)
+}
+
+// QueryLog writes a JSON line per search, with the query, its AST, result count, latency and caller
+type QueryLog struct {
+	mu      sync.Mutex
+	w       io.Writer
+	options QueryLogOptions
+}
+
+// NewQueryLog creates a query log writing to w (e.g. a rotating file)
+func NewQueryLog(w io.Writer, options QueryLogOptions) *QueryLog {
+	return &QueryLog{w: w, options: options}
+}
+
+// SetQueryLog records every search of the engine in the query log (nil stops logging)
+func (e *EngineCore) SetQueryLog(queryLog *QueryLog) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queryLog = queryLog
+}
+
+// searchEvaluation is filled in by the innermost search handler, for the query log
+type searchEvaluation struct {
+	query        string
+	duration     time.Duration
+	indexResults int
+}
+
+// record writes the entry of a finished search
+func (l *QueryLog) record(index string, query ports.SearchQuery, results ports.SearchResults, err error, latency time.Duration, evaluation *searchEvaluation) {
+	entry := QueryLogEntry{
+		Time:      time.Now().UTC(),
+		Query:     query.Query,
+		Index:     index,
+		Caller:    query.Caller,
+		Results:   len(results.Documents),
+		LatencyMS: milliseconds(latency),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if l.options.Parse != nil {
+		if ast, err := l.options.Parse(query.Query); err != nil {
+			entry.ParseError = err.Error()
+		} else {
+			entry.AST = ast
+		}
+	}
+	if l.options.SlowThreshold > 0 && latency >= l.options.SlowThreshold {
+		entry.Slow = true
+		ids := make([]string, len(results.Documents))
+		for i, doc := range results.Documents {
+			ids[i] = doc.ID
+		}
+		entry.Details = &QueryDetails{
+			EvaluatedQuery: evaluation.query,
+			IndexLatencyMS: milliseconds(evaluation.duration),
+			IndexResults:   evaluation.indexResults,
+			DocumentIDs:    ids,
+		}
+		log.Warn().Msgf("Slow search %q on %s took %s (threshold %s)", query.Query, index, latency, l.options.SlowThreshold)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to encode query log entry")
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Msg("Failed to write query log")
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
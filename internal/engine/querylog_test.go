@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func readQueryLog(t *testing.T, buf *bytes.Buffer) []QueryLogEntry {
+	var entries []QueryLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry QueryLogEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestEngineCore_QueryLog(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{{ID: "public"}, {ID: "secret"}}})
+	core.UseSearchMiddleware(
+		RewriteQuery(strings.ToLower),
+		FilterResults(func(doc models.Document) bool { return doc.ID != "secret" }))
+
+	var buf bytes.Buffer
+	parse := func(query string) (interface{}, error) {
+		if query == "bad" {
+			return nil, errors.New("unparsable")
+		}
+		return map[string]string{"text": query}, nil
+	}
+	core.SetQueryLog(NewQueryLog(&buf, QueryLogOptions{SlowThreshold: time.Hour, Parse: parse}))
+
+	_, err := core.Search(ports.SearchQuery{Query: "Hello", Caller: "REST 127.0.0.1:4000"})
+	assert.NoError(t, err)
+	_, err = core.Search(ports.SearchQuery{Query: "bad"})
+	assert.NoError(t, err)
+
+	entries := readQueryLog(t, &buf)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "Hello", entries[0].Query)
+	assert.Equal(t, map[string]interface{}{"text": "Hello"}, entries[0].AST)
+	assert.Equal(t, "idx", entries[0].Index)
+	assert.Equal(t, "REST 127.0.0.1:4000", entries[0].Caller)
+	assert.Equal(t, 1, entries[0].Results)
+	assert.False(t, entries[0].Slow)
+	assert.Nil(t, entries[0].Details)
+	assert.Equal(t, "unparsable", entries[1].ParseError)
+}
+
+func TestEngineCore_SlowQueryLogDetails(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{{ID: "public"}, {ID: "secret"}}})
+	core.UseSearchMiddleware(
+		RewriteQuery(strings.ToLower),
+		FilterResults(func(doc models.Document) bool { return doc.ID != "secret" }))
+
+	var buf bytes.Buffer
+	core.SetQueryLog(NewQueryLog(&buf, QueryLogOptions{SlowThreshold: time.Nanosecond}))
+	_, err := core.Search(ports.SearchQuery{Query: "Hello"})
+	assert.NoError(t, err)
+
+	entries := readQueryLog(t, &buf)
+	assert.Len(t, entries, 1)
+	assert.True(t, entries[0].Slow)
+	assert.Equal(t, "hello", entries[0].Details.EvaluatedQuery)
+	assert.Equal(t, 2, entries[0].Details.IndexResults)
+	assert.Equal(t, []string{"public"}, entries[0].Details.DocumentIDs)
+
+	// Unsetting the log stops logging
+	core.SetQueryLog(nil)
+	_, err = core.Search(ports.SearchQuery{Query: "Hello"})
+	assert.NoError(t, err)
+	assert.Len(t, readQueryLog(t, &buf), 1)
+}
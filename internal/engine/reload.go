@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+/**
+ * Runtime reconfiguration: used by config reloads to change the engine without a restart
+ **/
+
+// RemovePipeline stops a loader's pipeline: its refresh is unscheduled and it is no longer loaded on Start.
+// Documents it already indexed stay in the index.
+func (e *EngineCore) RemovePipeline(loaderName string) error {
+	p, ok := e.pipeline(loaderName)
+	if !ok {
+		return fmt.Errorf("no pipeline for loader %s", loaderName)
+	}
+	if p.Interval > 0 {
+		if err := e.UnscheduleLoader(loaderName); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.pipelines, loaderName)
+	for i, name := range e.pipelineOrder {
+		if name == loaderName {
+			e.pipelineOrder = append(e.pipelineOrder[:i], e.pipelineOrder[i+1:]...)
+			break
+		}
+	}
+	log.Info().Msgf("Removed pipeline for loader %s", loaderName)
+	return nil
+}
+
+// UnregisterLoader removes a loader from every loader registry. Remove its pipeline first.
+func (e *EngineCore) UnregisterLoader(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.pipelines[name]; ok {
+		return fmt.Errorf("loader %s still has a pipeline", name)
+	}
+	delete(e.loaders, name)
+	delete(e.streamingLoaders, name)
+	delete(e.incrementalLoaders, name)
+	return nil
+}
+
+// UnregisterIndex removes an index from the engine and returns it so the caller can close it.
+// Indexes that pipelines still load into, and the default index, cannot be removed. Aliases of the index
+// are removed with it.
+func (e *EngineCore) UnregisterIndex(name string) (ports.IndexPort, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.unregistrable(name); err != nil {
+		return nil, err
+	}
+	index := e.indexes[name]
+	delete(e.indexes, name)
+	delete(e.replicated, name)
+	for alias, target := range e.aliases {
+		if target == name {
+			delete(e.aliases, alias)
+		}
+	}
+	log.Info().Msgf("Unregistered index %s", name)
+	return index, nil
+}
+
+// unregistrable reports why an index cannot be unregistered (nil: it can). The caller holds e.mu.
+func (e *EngineCore) unregistrable(name string) error {
+	if _, ok := e.indexes[name]; !ok {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	if name == e.defaultIndex {
+		return fmt.Errorf("%w: index %s is the default index", ports.ErrConflict, name)
+	}
+	for _, p := range e.pipelines {
+		if p.Index == name {
+			return fmt.Errorf("%w: index %s is used by the pipeline of loader %s", ports.ErrConflict, name, p.Loader)
+		}
+	}
+	return nil
+}
+
+// ConfigureIndex applies a new configuration to a registered index
+func (e *EngineCore) ConfigureIndex(name string, config map[string]interface{}) error {
+	index, ok := e.index(name)
+	if !ok {
+		return fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	configurable, ok := index.(ports.ConfigurableIndexPort)
+	if !ok {
+		return fmt.Errorf("index %s: reconfiguration %w", name, ports.ErrNotSupported)
+	}
+	if err := configurable.Configure(config); err != nil {
+		return fmt.Errorf("%w: %w", ports.ErrInvalid, err)
+	}
+	return nil
+}
+
+// ConfigureFeatureExtractor applies a new configuration (e.g. weights) to a registered feature extractor
+func (e *EngineCore) ConfigureFeatureExtractor(name string, config map[string]interface{}) error {
+	e.mu.RLock()
+	extractor, ok := e.featureExtractors[name]
+	e.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("feature extractor %s not registered", name)
+	}
+	configurable, ok := extractor.(ports.ConfigPort)
+	if !ok {
+		return fmt.Errorf("feature extractor %s does not support reconfiguration", name)
+	}
+	return configurable.ApplyConfig(config)
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineCore_ScheduleWhileRunningAndRemovePipeline(t *testing.T) {
+	core := NewEngineCore()
+	recorder := &mutableRecorder{}
+	core.RegisterIndex("idx", recorder)
+	core.StartScheduler(context.Background())
+	defer core.StopScheduler()
+
+	loader := &changeLoader{changes: []models.ChangeSet{{Added: makeDocs(1)}}}
+	core.RegisterIncrementalLoader("fs", loader)
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Interval: 10 * time.Millisecond}))
+
+	// The job starts immediately because the scheduler is already running
+	assert.Eventually(t, func() bool {
+		statuses := core.LoaderStatuses()
+		return len(statuses) == 1 && statuses[0].Runs > 0
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Error(t, core.UnregisterLoader("fs"), "loader with a pipeline cannot be unregistered")
+	assert.NoError(t, core.RemovePipeline("fs"))
+	assert.Empty(t, core.LoaderStatuses())
+	assert.NoError(t, core.UnregisterLoader("fs"))
+	assert.Error(t, core.RemovePipeline("fs"))
+}
+
+func TestEngineCore_UnregisterIndex(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("default", &batchRecorder{})
+	core.RegisterIndex("extra", &batchRecorder{})
+	core.RegisterStreamingLoader("fs", &sliceLoader{})
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Index: "extra"}))
+
+	_, err := core.UnregisterIndex("default")
+	assert.Error(t, err)
+	_, err = core.UnregisterIndex("extra")
+	assert.Error(t, err)
+
+	assert.NoError(t, core.RemovePipeline("fs"))
+	removed, err := core.UnregisterIndex("extra")
+	assert.NoError(t, err)
+	assert.NotNil(t, removed)
+
+	assert.Error(t, core.ConfigureIndex("extra", nil))
+	assert.Error(t, core.ConfigureIndex("default", nil), "batchRecorder is not configurable")
+}
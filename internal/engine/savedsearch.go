@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// savedSearchName matches the names of saved searches, which are used in URLs and on the command line
+var savedSearchName = regexp.MustCompile(`^[\w.-]+$`)
+
+// SetSavedSearchStore enables saved searches, kept in store (nil: saved searches unsupported)
+func (e *EngineCore) SetSavedSearchStore(store ports.SavedSearchStorePort) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.savedSearches = store
+}
+
+func (e *EngineCore) savedSearchStore() (ports.SavedSearchStorePort, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.savedSearches == nil {
+		return nil, fmt.Errorf("%w: saved searches need a store", ports.ErrNotSupported)
+	}
+	return e.savedSearches, nil
+}
+
+// SaveSearch creates or replaces a saved search of its namespace
+func (e *EngineCore) SaveSearch(search ports.SavedSearch) (ports.SavedSearch, error) {
+	store, err := e.savedSearchStore()
+	if err != nil {
+		return ports.SavedSearch{}, err
+	}
+	if !savedSearchName.MatchString(search.Name) {
+		return ports.SavedSearch{}, fmt.Errorf("%w: saved search names are letters, digits, '_', '-' and '.', got %q", ports.ErrInvalid, search.Name)
+	}
+	if strings.TrimSpace(search.Query) == "" {
+		return ports.SavedSearch{}, fmt.Errorf("%w: saved search %s has no query", ports.ErrInvalid, search.Name)
+	}
+	if _, err := parseTemplate(search.Query); err != nil {
+		return ports.SavedSearch{}, fmt.Errorf("saved search %s: %w", search.Name, err)
+	}
+	if _, err := parseSort(search.Sort); err != nil {
+		return ports.SavedSearch{}, err
+	}
+	if _, err := models.ParseProjection(search.Fields); err != nil {
+		return ports.SavedSearch{}, fmt.Errorf("%w: saved search %s: %s", ports.ErrInvalid, search.Name, err)
+	}
+	search.Updated = time.Now()
+	if err := store.Put(search); err != nil {
+		return ports.SavedSearch{}, err
+	}
+	log.Info().Msgf("Saved search %s: %q", search.Name, search.Query)
+	return search, nil
+}
+
+// GetSavedSearch returns a saved search of a namespace
+func (e *EngineCore) GetSavedSearch(namespace, name string) (ports.SavedSearch, error) {
+	store, err := e.savedSearchStore()
+	if err != nil {
+		return ports.SavedSearch{}, err
+	}
+	return store.Get(namespace, name)
+}
+
+// DeleteSavedSearch removes a saved search of a namespace
+func (e *EngineCore) DeleteSavedSearch(namespace, name string) error {
+	store, err := e.savedSearchStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(namespace, name); err != nil {
+		return err
+	}
+	log.Info().Msgf("Deleted saved search %s", name)
+	return nil
+}
+
+// ListSavedSearches returns the saved searches of a namespace by name
+func (e *EngineCore) ListSavedSearches(namespace string) ([]ports.SavedSearch, error) {
+	store, err := e.savedSearchStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(namespace)
+}
+
+// SavedSearchQuery returns the search of a saved search, in its namespace, with its query rendered as a
+// template of params, falling back to its default parameters
+func (e *EngineCore) SavedSearchQuery(namespace, name string, params map[string]string) (ports.SearchQuery, error) {
+	search, err := e.GetSavedSearch(namespace, name)
+	if err != nil {
+		return ports.SearchQuery{}, err
+	}
+	values := make(map[string]string, len(search.Params)+len(params))
+	for param, value := range search.Params {
+		values[param] = value
+	}
+	for param, value := range params {
+		values[param] = value
+	}
+	query, err := renderTemplate(search.Query, values)
+	if err != nil {
+		return ports.SearchQuery{}, fmt.Errorf("saved search %s: %w", name, err)
+	}
+	projection, err := models.ParseProjection(search.Fields)
+	if err != nil {
+		return ports.SearchQuery{}, fmt.Errorf("%w: saved search %s: %s", ports.ErrInvalid, name, err)
+	}
+	return ports.SearchQuery{Query: query, Namespace: namespace, Sort: search.Sort, Projection: projection}, nil
+}
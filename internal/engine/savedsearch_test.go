@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/internal/searches"
+)
+
+func TestEngineCore_SavedSearches(t *testing.T) {
+	core := NewEngineCore()
+	idx := &docsIndex{docs: []models.Document{{ID: "1"}}}
+	core.RegisterIndex("idx", idx)
+
+	_, err := core.SaveSearch(ports.SavedSearch{Name: "my-todos", Query: "TODO"})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+
+	store, err := searches.OpenStore(filepath.Join(t.TempDir(), "searches.db"))
+	assert.NoError(t, err)
+	defer store.Close()
+	core.SetSavedSearchStore(store)
+
+	for _, invalid := range []ports.SavedSearch{
+		{Name: "my todos", Query: "TODO"},
+		{Name: "my-todos", Query: " "},
+		{Name: "my-todos", Query: "TODO", Sort: "-"},
+		{Name: "my-todos", Query: "TODO", Fields: []string{"text", "-id"}},
+	} {
+		_, err := core.SaveSearch(invalid)
+		assert.ErrorIs(t, err, ports.ErrInvalid, invalid)
+	}
+
+	saved, err := core.SaveSearch(ports.SavedSearch{Name: "my-todos", Query: "TODO fileExtension={{ ext }} size>{{size}}", Sort: "-id", Params: map[string]string{"size": "0"}})
+	assert.NoError(t, err)
+	assert.False(t, saved.Updated.IsZero())
+
+	search, err := core.SavedSearchQuery("", "my-todos", map[string]string{"ext": "go"})
+	assert.NoError(t, err)
+	assert.Equal(t, "TODO fileExtension=go size>0", search.Query)
+	assert.Equal(t, "-id", search.Sort)
+	search, err = core.SavedSearchQuery("", "my-todos", map[string]string{"ext": "md", "size": "10"})
+	assert.NoError(t, err)
+	assert.Equal(t, "TODO fileExtension=md size>10", search.Query)
+	_, err = core.SavedSearchQuery("", "my-todos", nil)
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+	_, err = core.SavedSearchQuery("", "unknown", nil)
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	_, err = core.Search(search)
+	assert.NoError(t, err)
+	assert.Equal(t, "TODO fileExtension=md size>10", idx.lastQuery)
+
+	// Saved searches belong to their namespace
+	_, err = core.GetSavedSearch("team-a", "my-todos")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+	list, err := core.ListSavedSearches("")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	assert.NoError(t, core.DeleteSavedSearch("", "my-todos"))
+	assert.ErrorIs(t, core.DeleteSavedSearch("", "my-todos"), ports.ErrNotFound)
+}
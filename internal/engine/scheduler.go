@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// ScheduledLoad describes an incremental loader that is periodically re-run against an index
+type ScheduledLoad struct {
+	Loader    string        // Name of a registered incremental loader
+	Index     string        // Name of the registered index the changes are applied to
+	Interval  time.Duration // Time between runs
+	BatchSize int           // Batch size used for added documents (default: DefaultBatchSize)
+}
+
+// scheduler tracks scheduled loads and their run status
+type scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]ScheduledLoad
+	status  map[string]*ports.LoaderStatus
+	ctx     context.Context               // Set while the scheduler is running
+	cancel  context.CancelFunc            // Stops every refresh loop
+	cancels map[string]context.CancelFunc // Stops a single job's refresh loop
+	wg      sync.WaitGroup
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		jobs:    make(map[string]ScheduledLoad),
+		status:  make(map[string]*ports.LoaderStatus),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// ScheduleLoader registers a periodic refresh for an incremental loader.
+// Jobs scheduled while the scheduler is running start immediately; rescheduling a loader replaces its job.
+func (e *EngineCore) ScheduleLoader(job ScheduledLoad) error {
+	if _, ok := e.incrementalLoader(job.Loader); !ok {
+		return fmt.Errorf("incremental loader %s not registered", job.Loader)
+	}
+	if _, ok := e.index(job.Index); !ok {
+		return fmt.Errorf("index %s not registered", job.Index)
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("schedule interval for loader %s must be positive", job.Loader)
+	}
+
+	s := e.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.cancels[job.Loader]; ok {
+		cancel()
+		delete(s.cancels, job.Loader)
+	}
+	s.jobs[job.Loader] = job
+	s.status[job.Loader] = &ports.LoaderStatus{
+		Name:     job.Loader,
+		Index:    job.Index,
+		Interval: job.Interval,
+	}
+	if s.ctx != nil {
+		e.startJob(job)
+	}
+	log.Info().Msgf("Scheduled loader %s into %s every %s", job.Loader, job.Index, job.Interval)
+	return nil
+}
+
+// UnscheduleLoader stops and removes a loader's periodic refresh. An in-flight run is allowed to finish.
+func (e *EngineCore) UnscheduleLoader(loaderName string) error {
+	s := e.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[loaderName]; !ok {
+		return fmt.Errorf("loader %s is not scheduled", loaderName)
+	}
+	if cancel, ok := s.cancels[loaderName]; ok {
+		cancel()
+		delete(s.cancels, loaderName)
+	}
+	delete(s.jobs, loaderName)
+	delete(s.status, loaderName)
+	log.Info().Msgf("Unscheduled loader %s", loaderName)
+	return nil
+}
+
+// startJob starts a job's refresh loop; the caller must hold the scheduler lock while it is running
+func (e *EngineCore) startJob(job ScheduledLoad) {
+	s := e.scheduler
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancels[job.Loader] = cancel
+	s.status[job.Loader].NextRun = time.Now().Add(job.Interval)
+	s.wg.Add(1)
+	go e.runSchedule(ctx, job)
+}
+
+// RunLoader runs a scheduled loader once, immediately, and records the outcome in its status.
+// A run is skipped (with an error) if the same loader is already running.
+func (e *EngineCore) RunLoader(ctx context.Context, loaderName string) error {
+	s := e.scheduler
+	s.mu.Lock()
+	job, ok := s.jobs[loaderName]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("loader %s is not scheduled", loaderName)
+	}
+	status := s.status[loaderName]
+	if status.Running {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: loader %s is already running", ports.ErrConflict, loaderName)
+	}
+	started := time.Now()
+	status.Running = true
+	status.RunningSince = started
+	s.mu.Unlock()
+
+	changes, err := e.ApplyChanges(ctx, job.Loader, job.Index, job.BatchSize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status.Running = false
+	status.RunningSince = time.Time{}
+	status.Runs++
+	status.LastRun = started
+	status.LastDuration = time.Since(started)
+	status.Added = len(changes.Added)
+	status.Modified = len(changes.Modified)
+	status.Deleted = len(changes.Deleted)
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+		log.Error().Msgf("Scheduled loader %s failed: %s", loaderName, err)
+	}
+	return err
+}
+
+// StartScheduler starts one background refresh loop per scheduled loader.
+// Loops stop when ctx is cancelled or StopScheduler is called.
+func (e *EngineCore) StartScheduler(ctx context.Context) {
+	s := e.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		e.startJob(job)
+	}
+	log.Info().Msgf("Loader scheduler started with %d jobs", len(s.jobs))
+}
+
+// StopScheduler stops all refresh loops and waits for in-flight runs to finish
+func (e *EngineCore) StopScheduler() {
+	s := e.scheduler
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.ctx = nil
+	s.cancels = make(map[string]context.CancelFunc)
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+	log.Info().Msg("Loader scheduler stopped")
+}
+
+// runSchedule re-runs a loader on every tick until ctx is cancelled
+func (e *EngineCore) runSchedule(ctx context.Context, job ScheduledLoad) {
+	defer e.scheduler.wg.Done()
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.RunLoader(ctx, job.Loader); err != nil {
+				log.Warn().Msgf("Scheduled run of %s: %s", job.Loader, err)
+			}
+			e.scheduler.mu.Lock()
+			if status, ok := e.scheduler.status[job.Loader]; ok {
+				status.NextRun = time.Now().Add(job.Interval)
+			}
+			e.scheduler.mu.Unlock()
+		}
+	}
+}
+
+// LoaderStatuses returns a snapshot of every scheduled loader's status, sorted by name
+func (e *EngineCore) LoaderStatuses() []ports.LoaderStatus {
+	s := e.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ports.LoaderStatus, 0, len(s.status))
+	for _, status := range s.status {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
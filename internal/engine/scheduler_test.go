@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type changeLoader struct {
+	changes []models.ChangeSet
+	err     error
+	commits int
+}
+
+func (l *changeLoader) LoadChanges(ctx context.Context) (models.ChangeSet, error) {
+	if l.err != nil {
+		return models.ChangeSet{}, l.err
+	}
+	if len(l.changes) == 0 {
+		return models.ChangeSet{}, nil
+	}
+	next := l.changes[0]
+	l.changes = l.changes[1:]
+	return next, nil
+}
+
+func (l *changeLoader) Commit() error {
+	l.commits++
+	return nil
+}
+
+type mutableRecorder struct {
+	batchRecorder
+	updated []string
+	deleted []string
+}
+
+func (r *mutableRecorder) UpdateDocument(ctx context.Context, doc models.Document) error {
+	r.updated = append(r.updated, doc.ID)
+	return nil
+}
+
+func (r *mutableRecorder) DeleteDocument(ctx context.Context, id string) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+func TestEngineCore_RunLoader_AppliesChangesAndRecordsStatus(t *testing.T) {
+	core := NewEngineCore()
+	loader := &changeLoader{changes: []models.ChangeSet{
+		{Added: makeDocs(3)},
+		{Modified: []models.Document{{ID: "doc-1"}}, Deleted: []string{"doc-2"}},
+	}}
+	recorder := &mutableRecorder{}
+	core.RegisterIncrementalLoader("fs", loader)
+	core.RegisterIndex("idx", recorder)
+
+	err := core.ScheduleLoader(ScheduledLoad{Loader: "fs", Index: "idx", Interval: time.Hour, BatchSize: 2})
+	assert.NoError(t, err)
+
+	assert.NoError(t, core.RunLoader(context.Background(), "fs"))
+	assert.Len(t, recorder.batches, 2)
+
+	assert.NoError(t, core.RunLoader(context.Background(), "fs"))
+	assert.Equal(t, []string{"doc-1"}, recorder.updated)
+	assert.Equal(t, []string{"doc-2"}, recorder.deleted)
+	assert.Equal(t, 2, loader.commits)
+
+	statuses := core.LoaderStatuses()
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "fs", statuses[0].Name)
+	assert.Equal(t, 2, statuses[0].Runs)
+	assert.Equal(t, 1, statuses[0].Modified)
+	assert.Equal(t, 1, statuses[0].Deleted)
+	assert.Empty(t, statuses[0].LastError)
+
+	stats, err := core.Stats()
+	assert.NoError(t, err)
+	assert.Len(t, stats.Loaders, 1)
+	assert.Len(t, stats.Indexes, 1)
+	assert.Equal(t, "idx", stats.Indexes[0].Name)
+}
+
+func TestEngineCore_RunLoader_RecordsFailure(t *testing.T) {
+	core := NewEngineCore()
+	loader := &changeLoader{err: errors.New("disk on fire")}
+	core.RegisterIncrementalLoader("fs", loader)
+	core.RegisterIndex("idx", &mutableRecorder{})
+	assert.NoError(t, core.ScheduleLoader(ScheduledLoad{Loader: "fs", Index: "idx", Interval: time.Hour}))
+
+	assert.Error(t, core.RunLoader(context.Background(), "fs"))
+	status := core.LoaderStatuses()[0]
+	assert.Contains(t, status.LastError, "disk on fire")
+	assert.Equal(t, 0, loader.commits)
+}
+
+func TestEngineCore_ScheduleLoader_Validation(t *testing.T) {
+	core := NewEngineCore()
+	assert.Error(t, core.ScheduleLoader(ScheduledLoad{Loader: "fs", Index: "idx", Interval: time.Minute}))
+
+	core.RegisterIncrementalLoader("fs", &changeLoader{})
+	core.RegisterIndex("idx", &mutableRecorder{})
+	assert.Error(t, core.ScheduleLoader(ScheduledLoad{Loader: "fs", Index: "idx"}))
+	assert.Error(t, core.RunLoader(context.Background(), "fs"))
+}
+
+func TestEngineCore_StartScheduler_RunsPeriodically(t *testing.T) {
+	core := NewEngineCore()
+	loader := &changeLoader{}
+	core.RegisterIncrementalLoader("fs", loader)
+	core.RegisterIndex("idx", &mutableRecorder{})
+	assert.NoError(t, core.ScheduleLoader(ScheduledLoad{Loader: "fs", Index: "idx", Interval: 5 * time.Millisecond}))
+
+	core.StartScheduler(context.Background())
+	assert.Eventually(t, func() bool {
+		return core.LoaderStatuses()[0].Runs >= 2
+	}, time.Second, 5*time.Millisecond)
+	core.StopScheduler()
+}
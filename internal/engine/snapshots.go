@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Scheduled snapshots: while the engine runs, the indexes are snapshotted into the snapshot directory
+ * on an interval and the oldest snapshots of each are pruned past a retention count. Start can restore
+ * empty indexes from their latest snapshots before the initial loads.
+ **/
+
+// snapshotTimeLayout is the UTC time in the name of a snapshot file, <index>-<time>.ndjson
+const snapshotTimeLayout = "20060102T150405.000Z"
+
+// SnapshotOptions configures scheduled snapshots and restores on Start
+type SnapshotOptions struct {
+	// Interval is the time between snapshots (0: none are scheduled)
+	Interval time.Duration
+	// Retain is the number of snapshots kept per index, the oldest pruned after each new one (0: all)
+	Retain int
+	// Indexes are the indexes snapshotted and restored (empty: every index that can be exported)
+	Indexes []string
+	// RestoreOnStart has Start import the latest snapshot of each empty index before the initial loads
+	RestoreOnStart bool
+}
+
+// snapshotter runs the snapshot loop
+type snapshotter struct {
+	mu      sync.Mutex
+	options SnapshotOptions
+	ctx     context.Context    // Set while the engine runs
+	cancel  context.CancelFunc // Stops the snapshot loop
+	wg      sync.WaitGroup
+}
+
+// SetSnapshotOptions configures scheduled snapshots and restores on Start; a running snapshot loop is
+// restarted with the new options
+func (e *EngineCore) SetSnapshotOptions(options SnapshotOptions) error {
+	if options.Interval < 0 {
+		return fmt.Errorf("%w: snapshot interval must not be negative", ports.ErrInvalid)
+	}
+	if options.Retain < 0 {
+		return fmt.Errorf("%w: snapshot retention must not be negative", ports.ErrInvalid)
+	}
+	s := &e.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = options
+	if s.ctx != nil {
+		s.cancel()
+		e.startSnapshotLoop(s.ctx)
+	}
+	return nil
+}
+
+// StartSnapshots starts the snapshot loop, if snapshots are scheduled. It stops when ctx is cancelled or
+// StopSnapshots is called.
+func (e *EngineCore) StartSnapshots(ctx context.Context) {
+	s := &e.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil {
+		return
+	}
+	s.ctx = ctx
+	e.startSnapshotLoop(ctx)
+}
+
+// startSnapshotLoop starts the snapshot loop under ctx; the caller holds the snapshotter lock
+func (e *EngineCore) startSnapshotLoop(ctx context.Context) {
+	s := &e.snapshots
+	loopCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	if s.options.Interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go e.runSnapshots(loopCtx, s.options)
+	log.Info().Msgf("Snapshotting indexes every %s", s.options.Interval)
+}
+
+// StopSnapshots stops the snapshot loop and waits for an in-flight snapshot to finish
+func (e *EngineCore) StopSnapshots() {
+	s := &e.snapshots
+	s.mu.Lock()
+	cancel := s.cancel
+	s.ctx, s.cancel = nil, nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+// runSnapshots snapshots the indexes on every tick until ctx is cancelled
+func (e *EngineCore) runSnapshots(ctx context.Context, options SnapshotOptions) {
+	defer e.snapshots.wg.Done()
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, name := range e.snapshotIndexes(options) {
+				if err := e.snapshotAndPrune(name, options.Retain); err != nil {
+					log.Warn().Msgf("Scheduled snapshot of %s: %s", name, err)
+				}
+			}
+		}
+	}
+}
+
+// snapshotAndPrune snapshots an index, then removes its oldest snapshots past retain (0: none)
+func (e *EngineCore) snapshotAndPrune(name string, retain int) error {
+	snapshot, err := e.SnapshotIndex(name)
+	if err != nil {
+		return err
+	}
+	if retain <= 0 {
+		return nil
+	}
+	snapshots, err := listSnapshots(filepath.Dir(snapshot.Path), name)
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > retain {
+		if err := os.Remove(snapshots[0].Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to prune snapshot: %w", err)
+		}
+		log.Debug().Msgf("Pruned snapshot %s", snapshots[0].Path)
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// snapshotIndexes returns the indexes snapshotted and restored with options, sorted by name
+func (e *EngineCore) snapshotIndexes(options SnapshotOptions) []string {
+	if len(options.Indexes) > 0 {
+		return options.Indexes
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var names []string
+	for name, index := range e.indexes {
+		if _, ok := index.(ports.TransferIndexPort); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snapshots returns the snapshots of an index in the snapshot directory, oldest first
+func (e *EngineCore) Snapshots(name string) ([]ports.Snapshot, error) {
+	e.mu.RLock()
+	dir := e.snapshotDir
+	e.mu.RUnlock()
+	if dir == "" {
+		return nil, fmt.Errorf("%w: snapshots need a snapshot directory", ports.ErrNotSupported)
+	}
+	return listSnapshots(dir, name)
+}
+
+// RestoreLatestSnapshot imports the latest snapshot of an index into it
+func (e *EngineCore) RestoreLatestSnapshot(name string) (ports.Snapshot, error) {
+	snapshots, err := e.Snapshots(name)
+	if err != nil {
+		return ports.Snapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return ports.Snapshot{}, fmt.Errorf("snapshot of index %s: %w", name, ports.ErrNotFound)
+	}
+	latest := snapshots[len(snapshots)-1]
+	file, err := os.Open(latest.Path)
+	if err != nil {
+		return ports.Snapshot{}, fmt.Errorf("failed to open snapshot of index %s: %w", name, err)
+	}
+	defer file.Close()
+	if err := e.ImportIndex(name, file); err != nil {
+		return ports.Snapshot{}, err
+	}
+	log.Info().Msgf("Restored index %s from snapshot %s", name, latest.Path)
+	return latest, nil
+}
+
+// restoreSnapshots restores the empty indexes from their latest snapshots, if restores on Start are
+// enabled; indexes without a snapshot are left empty
+func (e *EngineCore) restoreSnapshots() error {
+	e.snapshots.mu.Lock()
+	options := e.snapshots.options
+	e.snapshots.mu.Unlock()
+	if !options.RestoreOnStart {
+		return nil
+	}
+	var errs []error
+	for _, name := range e.snapshotIndexes(options) {
+		index, ok := e.index(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("index %s: %w", name, ports.ErrNotFound))
+			continue
+		}
+		if count, err := index.Count(); err != nil || count > 0 {
+			continue
+		}
+		_, err := e.RestoreLatestSnapshot(name)
+		switch {
+		case errors.Is(err, ports.ErrNotFound):
+			log.Info().Msgf("No snapshot of index %s to restore", name)
+		case err != nil:
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listSnapshots returns the snapshots of an index in dir, oldest first. Files are matched on the whole
+// name, so the snapshots of "docs" do not include those of "docs-v2".
+func listSnapshots(dir, name string) ([]ports.Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var snapshots []ports.Snapshot
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), name+"-")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ".ndjson")
+		if !ok {
+			continue
+		}
+		taken, err := time.Parse(snapshotTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		snapshot := ports.Snapshot{Index: name, Path: filepath.Join(dir, entry.Name()), Time: taken}
+		if info, err := entry.Info(); err == nil {
+			snapshot.Bytes = info.Size()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// writeSnapshot writes a snapshot file of an index taken at a given time
+func writeSnapshot(t *testing.T, dir, name string, taken time.Time, data string) string {
+	path := filepath.Join(dir, name+"-"+taken.Format(snapshotTimeLayout)+".ndjson")
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	return path
+}
+
+func TestEngineCore_Snapshots(t *testing.T) {
+	dir := t.TempDir()
+	core := NewEngineCore()
+	_, err := core.Snapshots("docs")
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+	core.SetSnapshotDir(dir)
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeSnapshot(t, dir, "docs", base.Add(time.Hour), "newer\n")
+	writeSnapshot(t, dir, "docs", base, "older\n")
+	writeSnapshot(t, dir, "docs-v2", base.Add(2*time.Hour), "other index\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docs-notes.ndjson"), nil, 0o644))
+
+	snapshots, err := core.Snapshots("docs")
+	assert.NoError(t, err)
+	if assert.Len(t, snapshots, 2) {
+		assert.Equal(t, base, snapshots[0].Time)
+		assert.Equal(t, base.Add(time.Hour), snapshots[1].Time)
+		assert.Equal(t, int64(len("newer\n")), snapshots[1].Bytes)
+	}
+
+	idx := &transferIndex{}
+	core.RegisterIndex("docs", idx)
+	restored, err := core.RestoreLatestSnapshot("docs")
+	assert.NoError(t, err)
+	assert.Equal(t, snapshots[1].Path, restored.Path)
+	assert.Equal(t, "newer\n", idx.imported)
+
+	core.RegisterIndex("empty", &transferIndex{})
+	_, err = core.RestoreLatestSnapshot("empty")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_SnapshotAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	core := NewEngineCore()
+	core.SetSnapshotDir(dir)
+	core.RegisterIndex("docs", &transferIndex{})
+
+	base := time.Now().UTC().Add(-time.Hour)
+	oldest := writeSnapshot(t, dir, "docs", base, "")
+	writeSnapshot(t, dir, "docs", base.Add(time.Minute), "")
+	other := writeSnapshot(t, dir, "docs-v2", base, "")
+
+	assert.NoError(t, core.snapshotAndPrune("docs", 2))
+	snapshots, err := core.Snapshots("docs")
+	assert.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.NoFileExists(t, oldest)
+	assert.FileExists(t, other, "snapshots of other indexes are kept")
+	assert.Equal(t, int64(len("export\n")), snapshots[1].Bytes, "the new snapshot is kept")
+
+	// Without retention every snapshot is kept
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, core.snapshotAndPrune("docs", 0))
+	snapshots, _ = core.Snapshots("docs")
+	assert.Len(t, snapshots, 3)
+}
+
+func TestEngineCore_ScheduledSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	core := NewEngineCore()
+	core.SetSnapshotDir(dir)
+	core.RegisterIndex("docs", &transferIndex{})
+	core.RegisterIndex("plain", &batchRecorder{})
+	assert.ErrorIs(t, core.SetSnapshotOptions(SnapshotOptions{Retain: -1}), ports.ErrInvalid)
+	assert.NoError(t, core.SetSnapshotOptions(SnapshotOptions{Interval: 5 * time.Millisecond, Retain: 2}))
+
+	core.StartSnapshots(context.Background())
+	assert.Eventually(t, func() bool {
+		snapshots, _ := core.Snapshots("docs")
+		return len(snapshots) == 2
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	core.StopSnapshots()
+
+	// Only the indexes that can be exported are snapshotted, and only the latest ones are kept
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"docs"}, core.snapshotIndexes(SnapshotOptions{}))
+}
+
+func TestEngineCore_Start_RestoresSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	core := NewEngineCore()
+	core.SetSnapshotDir(dir)
+	idx := &transferIndex{}
+	core.RegisterIndex("docs", idx)
+	core.RegisterIndex("fresh", &transferIndex{})
+	writeSnapshot(t, dir, "docs", time.Now().UTC(), "snapshot\n")
+	assert.NoError(t, core.SetSnapshotOptions(SnapshotOptions{RestoreOnStart: true}))
+
+	assert.NoError(t, core.Start(context.Background()))
+	defer core.Stop(context.Background())
+	assert.Equal(t, "snapshot\n", idx.imported)
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// Sort orders of SearchQuery.Sort; any other value names a document field
+const (
+	SortScore = "score" // Relevance, as ranked by the index (default)
+	SortID    = "id"
+)
+
+// resultOrder is a parsed SearchQuery.Sort
+type resultOrder struct {
+	field      string // "score", "id" or a document field
+	descending bool
+}
+
+// parseSort reads a sort order such as "score", "-id" or "source" ("": score)
+func parseSort(spec string) (resultOrder, error) {
+	order := resultOrder{field: strings.TrimSpace(spec)}
+	if field, ok := strings.CutPrefix(order.field, "-"); ok {
+		order.field, order.descending = field, true
+	}
+	switch {
+	case order.field == "" && order.descending:
+		return resultOrder{}, fmt.Errorf("sort %q names no field: %w", spec, ports.ErrInvalid)
+	case order.field == "":
+		order.field = SortScore
+	}
+	return order, nil
+}
+
+// sortDocuments orders the results of a search. Documents the index ranked equally keep its order
+// (indexes order ties by ID); field orders break ties by ID, so pages of a result list are stable.
+func sortDocuments(docs []models.Document, order resultOrder) {
+	if order.field == SortScore {
+		if order.descending {
+			for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+				docs[i], docs[j] = docs[j], docs[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, b := docs[i].Field(order.field), docs[j].Field(order.field)
+		if a == b {
+			return docs[i].ID < docs[j].ID
+		}
+		return lessFieldValue(a, b) != order.descending
+	})
+}
+
+// lessFieldValue orders the values of a field: missing (empty) values first, then numbers by value
+// ("9" before "10"), then the other values as strings
+func lessFieldValue(a, b string) bool {
+	numberA, isNumberA := parseNumber(a)
+	numberB, isNumberB := parseNumber(b)
+	switch {
+	case a == "" || b == "":
+		return a == ""
+	case isNumberA && isNumberB && numberA != numberB:
+		return numberA < numberB
+	case isNumberA != isNumberB:
+		return isNumberA
+	}
+	return a < b
+}
+
+// parseNumber parses a field value as a finite number
+func parseNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return number, err == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+}
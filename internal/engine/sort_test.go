@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestEngineCore_SearchSort(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{
+		{ID: "b", Source: "/x", Meta: map[string]string{"year": "2024"}},
+		{ID: "c", Source: "/a", Meta: map[string]string{"year": "2023"}},
+		{ID: "a", Source: "/x"},
+	}})
+	search := func(sort string) []string {
+		results, err := core.Search(ports.SearchQuery{Query: "doc", Sort: sort})
+		assert.NoError(t, err)
+		var ids []string
+		for _, doc := range results.Documents {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	// Score keeps the index's ranking; fields break ties by ID in both directions
+	assert.Equal(t, []string{"b", "c", "a"}, search(""))
+	assert.Equal(t, []string{"b", "c", "a"}, search("score"))
+	assert.Equal(t, []string{"a", "c", "b"}, search("-score"))
+	assert.Equal(t, []string{"a", "b", "c"}, search("id"))
+	assert.Equal(t, []string{"c", "b", "a"}, search("-id"))
+	assert.Equal(t, []string{"c", "a", "b"}, search("source"))
+	assert.Equal(t, []string{"a", "b", "c"}, search("-source"))
+	assert.Equal(t, []string{"a", "c", "b"}, search("year"))
+
+	// Numbers are compared by value, before the values that are not numbers
+	core.RegisterIndex("sizes", &docsIndex{docs: []models.Document{
+		{ID: "a", Meta: map[string]string{"fileSize": "100"}},
+		{ID: "b", Meta: map[string]string{"fileSize": "9"}},
+		{ID: "c", Meta: map[string]string{"fileSize": "unknown"}},
+		{ID: "d", Meta: map[string]string{"fileSize": "25.5"}},
+		{ID: "e"},
+		{ID: "f", Meta: map[string]string{"fileSize": "1e3"}},
+	}})
+	assert.NoError(t, core.SetDefaultIndex("sizes"))
+	assert.Equal(t, []string{"e", "b", "d", "a", "f", "c"}, search("fileSize"))
+	assert.Equal(t, []string{"c", "f", "a", "d", "b", "e"}, search("-fileSize"))
+
+	_, err := core.Search(ports.SearchQuery{Query: "doc", Sort: "-"})
+	assert.ErrorIs(t, err, ports.ErrInvalid)
+}
+
+// cappedIndex caps the results of each search like an index with max_results
+type cappedIndex struct {
+	docsIndex
+	maxResults int
+}
+
+func (c *cappedIndex) MaxResults() int {
+	return c.maxResults
+}
+
+func TestEngineCore_SearchMaxResults(t *testing.T) {
+	idx := &cappedIndex{maxResults: 2}
+	for i := 1; i <= 5; i++ {
+		doc := models.Document{ID: fmt.Sprintf("d%d", i), Meta: map[string]string{"n": fmt.Sprint(i)}}
+		if i == 4 {
+			doc.AllowedPrincipals = []string{"finance"}
+		}
+		idx.docs = append(idx.docs, doc)
+	}
+	core := NewEngineCore()
+	core.RegisterIndex("idx", idx)
+	search := func(query ports.SearchQuery) []string {
+		query.Query = "doc"
+		results, err := core.Search(query)
+		assert.NoError(t, err)
+		var ids []string
+		for _, doc := range results.Documents {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	// The cap keeps the first results the caller may see, in the order asked for
+	assert.Equal(t, []string{"d1", "d2"}, search(ports.SearchQuery{}))
+	assert.Equal(t, []string{"d5", "d4"}, search(ports.SearchQuery{Sort: "-n"}))
+	assert.Equal(t, []string{"d5", "d3"}, search(ports.SearchQuery{Sort: "-n", Principals: []string{"sales"}}))
+	onlyD5 := func(doc models.Document) bool { return doc.ID == "d5" }
+	assert.Equal(t, []string{"d5"}, search(ports.SearchQuery{Filter: onlyD5}))
+	byRole := func(doc models.Document) bool { return doc.ID != "d5" && doc.ID != "d4" }
+	assert.Equal(t, []string{"d3", "d2"}, search(ports.SearchQuery{Sort: "-n", Filter: byRole}))
+}
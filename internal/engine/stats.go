@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// queryCounters counts the searches served by the engine
+type queryCounters struct {
+	total   atomic.Int64
+	failed  atomic.Int64
+	latency atomic.Int64 // Nanoseconds
+	last    atomic.Int64 // Unix nanoseconds of the last search
+}
+
+func (c *queryCounters) record(latency time.Duration, err error) {
+	c.total.Add(1)
+	if err != nil {
+		c.failed.Add(1)
+	}
+	c.latency.Add(int64(latency))
+	c.last.Store(time.Now().UnixNano())
+}
+
+func (c *queryCounters) snapshot() ports.QueryStats {
+	stats := ports.QueryStats{
+		Total:        c.total.Load(),
+		Failed:       c.failed.Load(),
+		TotalLatency: time.Duration(c.latency.Load()),
+	}
+	if last := c.last.Load(); last != 0 {
+		stats.LastQuery = time.Unix(0, last).UTC()
+	}
+	return stats
+}
+
+// indexStatus reports the document count of an index with its size and failed async writes, if it
+// reports them
+func indexStatus(name string, count int, index ports.IndexPort) ports.IndexStatus {
+	status := ports.IndexStatus{Name: name, NumDocuments: count, SizeBytes: -1}
+	if sized, ok := index.(ports.SizedIndexPort); ok {
+		if size, err := sized.Size(); err == nil {
+			status.SizeBytes = size
+		}
+	}
+	if reporter, ok := index.(ports.PersistenceStatsIndexPort); ok {
+		if persistence, err := reporter.PersistenceStats(); err == nil {
+			status.Persistence = &persistence
+		}
+	}
+	return status
+}
+
+// Stats returns engine-wide statistics: the document count and size of every index (sorted by name),
+// their total, loader statuses, the feature extractors and the pipelines using them, uptime, memory
+// usage and search counters
+func (e *EngineCore) Stats() (ports.Stats, error) {
+	stats := ports.Stats{
+		Loaders: e.LoaderStatuses(),
+		Memory:  memoryDiagnostics(),
+		Queries: e.queries.snapshot(),
+	}
+	if started := e.startedAt.Load(); started != 0 {
+		stats.StartedAt = time.Unix(0, started).UTC()
+		stats.Uptime = time.Since(stats.StartedAt)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for name, index := range e.indexes {
+		count, err := index.Count()
+		if err != nil {
+			return stats, fmt.Errorf("failed to count documents in index %s: %w", name, err)
+		}
+		status := indexStatus(name, count, index)
+		stats.NumDocuments += count
+		stats.Indexes = append(stats.Indexes, status)
+	}
+	sort.Slice(stats.Indexes, func(i, j int) bool { return stats.Indexes[i].Name < stats.Indexes[j].Name })
+
+	for name := range e.featureExtractors {
+		status := ports.FeatureExtractorStatus{Name: name}
+		for loader, pipeline := range e.pipelines {
+			for _, extractor := range pipeline.Extractors {
+				if extractor == name {
+					status.Loaders = append(status.Loaders, loader)
+					break
+				}
+			}
+		}
+		sort.Strings(status.Loaders)
+		stats.FeatureExtractors = append(stats.FeatureExtractors, status)
+	}
+	sort.Slice(stats.FeatureExtractors, func(i, j int) bool { return stats.FeatureExtractors[i].Name < stats.FeatureExtractors[j].Name })
+	return stats, nil
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// sizedIndex reports a fixed size, and fails searches for "fail"
+type sizedIndex struct {
+	batchRecorder
+}
+
+func (s *sizedIndex) Size() (int, error) { return 4096, nil }
+
+func (s *sizedIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	if query == "fail" {
+		return nil, errors.New("broken")
+	}
+	return nil, nil
+}
+
+// persistingIndex reports failed async writes
+type persistingIndex struct {
+	batchRecorder
+}
+
+func (p *persistingIndex) PersistenceStats() (ports.PersistenceStats, error) {
+	return ports.PersistenceStats{Failed: 1, Retried: 3, LastError: "disk full"}, nil
+}
+
+func TestEngineCore_Stats(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("a", &sizedIndex{})
+	core.RegisterIndex("b", &batchRecorder{})
+	core.RegisterIndex("c", &persistingIndex{})
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(1)})
+	core.RegisterFeatureExtractor("tags", tagExtractor{})
+	core.RegisterFeatureExtractor("unused", tagExtractor{})
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Index: "a", Extractors: []string{"tags"}}))
+
+	stats, err := core.Stats()
+	assert.NoError(t, err)
+	assert.True(t, stats.StartedAt.IsZero())
+	assert.Zero(t, stats.Uptime)
+
+	assert.NoError(t, core.Start(context.Background()))
+	defer core.Stop(context.Background())
+	_, err = core.Search(ports.SearchQuery{Query: "hello"})
+	assert.NoError(t, err)
+	_, err = core.Search(ports.SearchQuery{Query: "fail"})
+	assert.Error(t, err)
+
+	stats, err = core.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.IndexStatus{
+		{Name: "a", SizeBytes: 4096},
+		{Name: "b", SizeBytes: -1},
+		{Name: "c", SizeBytes: -1, Persistence: &ports.PersistenceStats{Failed: 1, Retried: 3, LastError: "disk full"}},
+	}, stats.Indexes)
+	assert.Equal(t, []ports.FeatureExtractorStatus{{Name: "tags", Loaders: []string{"fs"}}, {Name: "unused"}}, stats.FeatureExtractors)
+	assert.False(t, stats.StartedAt.IsZero())
+	assert.Positive(t, stats.Uptime)
+	assert.NotZero(t, stats.Memory.SysBytes)
+	assert.Equal(t, int64(2), stats.Queries.Total)
+	assert.Equal(t, int64(1), stats.Queries.Failed)
+	assert.False(t, stats.Queries.LastQuery.IsZero())
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// SubscriptionQueueSize is the number of matches buffered per search subscription before new ones are dropped
+const SubscriptionQueueSize = 256
+
+// QueryMatcher reports whether a document matches a query, the way the indexes' searches do
+type QueryMatcher func(query string, doc models.Document) bool
+
+// SetQueryMatcher enables search subscriptions and stored queries, matching newly indexed documents with matcher
+func (e *EngineCore) SetQueryMatcher(matcher QueryMatcher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.matcher = matcher
+}
+
+// SubscribeSearch pushes the documents added to or updated in any index (only the index of the query's
+// namespace, if any) that match the query (and its filter, if any), with the fields it selects, until ctx
+// is done. Search middlewares do not apply. Matches are dropped, with a warning, while the subscriber is
+// SubscriptionQueueSize matches behind.
+func (e *EngineCore) SubscribeSearch(ctx context.Context, query ports.SearchQuery) (<-chan ports.SearchMatch, error) {
+	e.mu.RLock()
+	matcher := e.matcher
+	e.mu.RUnlock()
+	if matcher == nil {
+		return nil, fmt.Errorf("%w: search subscriptions need a query matcher", ports.ErrNotSupported)
+	}
+
+	query, err := withSelect(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subscriptions of a namespace only see the documents of its index
+	var index string
+	if query.Namespace != "" {
+		name, _, err := e.namespaceIndex(query.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		index = name
+	}
+
+	matches := make(chan ports.SearchMatch, SubscriptionQueueSize)
+	dropped := 0
+	unsubscribe := e.Subscribe(func(event ports.Event) {
+		if index != "" && event.Index != index {
+			return
+		}
+		for _, doc := range event.Documents {
+			if !matcher(query.Query, doc) || (query.Filter != nil && !query.Filter(doc)) || (query.Principals != nil && !doc.VisibleTo(query.Principals)) {
+				continue
+			}
+			select {
+			case matches <- ports.SearchMatch{Index: event.Index, Document: query.Projection.Apply(doc), Time: event.Time}:
+			default:
+				dropped++
+				log.Warn().Msgf("Search subscription %q (%s) is behind, dropped %d matches so far", query.Query, query.Caller, dropped)
+			}
+		}
+	}, ports.EventDocumentIndexed)
+
+	go func() {
+		<-ctx.Done()
+		// Unsubscribing waits for the handler, so nothing is sent on the closed channel
+		unsubscribe()
+		close(matches)
+	}()
+	log.Debug().Msgf("Search subscription %q opened by %s", query.Query, query.Caller)
+	return matches, nil
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestEngineCore_SubscribeSearch(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &batchRecorder{})
+
+	_, err := core.SubscribeSearch(context.Background(), ports.SearchQuery{Query: "go"})
+	assert.ErrorIs(t, err, ports.ErrNotSupported)
+
+	core.SetQueryMatcher(func(query string, doc models.Document) bool { return strings.Contains(doc.Text, query) })
+	ctx, cancel := context.WithCancel(context.Background())
+	public := func(doc models.Document) bool { return doc.Source == "public" }
+	matches, err := core.SubscribeSearch(ctx, ports.SearchQuery{Query: "go", Filter: public})
+	assert.NoError(t, err)
+
+	assert.NoError(t, core.Index(models.Document{ID: "1", Text: "rust", Source: "public"}))
+	assert.NoError(t, core.Index(models.Document{ID: "2", Text: "go", Source: "private"}))
+	_, err = core.Bulk(context.Background(), []ports.BulkItem{{Action: ports.BulkIndex, Document: models.Document{ID: "3", Text: "go", Source: "public"}}})
+	assert.NoError(t, err)
+
+	select {
+	case match := <-matches:
+		assert.Equal(t, "idx", match.Index)
+		assert.Equal(t, "3", match.Document.ID)
+	case <-time.After(time.Second):
+		t.Fatal("no match pushed")
+	}
+
+	cancel()
+	for range matches {
+	}
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+/**
+ * Search templates: saved search queries are mustache-style templates rendered with the caller's
+ * parameters, so clients pass values instead of building query strings.
+ *   {{name}}                a value; values holding query syntax (and, select, where) are rejected
+ *   {{{name}}}              a value inserted verbatim, e.g. a whole condition
+ *   {{#name}}...{{/name}}   rendered when the parameter is set (and not "" or "false")
+ *   {{^name}}...{{/name}}   rendered when it is not
+ **/
+
+// templateTag matches the {{{name}}}, {{name}}, {{#name}}, {{^name}} and {{/name}} tags of templates
+var templateTag = regexp.MustCompile(`\{\{\{\s*(\w+)\s*\}\}\}|\{\{\s*([#^/]?)\s*(\w+)\s*\}\}`)
+
+// querySyntax matches the keywords a value may not hold: they would add conditions or clauses to the
+// query rendered from a template
+var querySyntax = regexp.MustCompile(`(?i)(^|\s)(and|select|where)(\s|$)|[\r\n]`)
+
+// templateToken is a tag of a template, or the text between tags (tag empty)
+type templateToken struct {
+	tag  string // "", "{" (verbatim value), "=" (value), "#", "^" or "/"
+	name string // Parameter name, or the text of text tokens
+}
+
+// parseTemplate splits a template into text and tags, checking that its sections are closed in order
+func parseTemplate(source string) ([]templateToken, error) {
+	var tokens []templateToken
+	var open []string
+	last := 0
+	for _, m := range templateTag.FindAllStringSubmatchIndex(source, -1) {
+		if m[0] > last {
+			tokens = append(tokens, templateToken{name: source[last:m[0]]})
+		}
+		last = m[1]
+		if m[2] >= 0 {
+			tokens = append(tokens, templateToken{tag: "{", name: source[m[2]:m[3]]})
+			continue
+		}
+		token := templateToken{tag: source[m[4]:m[5]], name: source[m[6]:m[7]]}
+		switch token.tag {
+		case "":
+			token.tag = "="
+		case "#", "^":
+			open = append(open, token.name)
+		case "/":
+			if len(open) == 0 || open[len(open)-1] != token.name {
+				return nil, fmt.Errorf("%w: template closes section %s, which is not open", ports.ErrInvalid, token.name)
+			}
+			open = open[:len(open)-1]
+		}
+		tokens = append(tokens, token)
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("%w: template section %s is not closed", ports.ErrInvalid, open[len(open)-1])
+	}
+	if last < len(source) {
+		tokens = append(tokens, templateToken{name: source[last:]})
+	}
+	return tokens, nil
+}
+
+// renderTemplate renders a template with params. The values of params that are used must be set.
+func renderTemplate(source string, params map[string]string) (string, error) {
+	tokens, err := parseTemplate(source)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	var missing []string
+	// skipped holds the depths of the open sections not rendered
+	var skipped []int
+	depth := 0
+	for _, token := range tokens {
+		switch token.tag {
+		case "#", "^":
+			depth++
+			value := params[token.name]
+			set := value != "" && !strings.EqualFold(value, "false")
+			if len(skipped) > 0 || set != (token.tag == "#") {
+				skipped = append(skipped, depth)
+			}
+			continue
+		case "/":
+			if len(skipped) > 0 && skipped[len(skipped)-1] == depth {
+				skipped = skipped[:len(skipped)-1]
+			}
+			depth--
+			continue
+		}
+		if len(skipped) > 0 {
+			continue
+		}
+		if token.tag == "" {
+			out.WriteString(token.name)
+			continue
+		}
+		value, ok := params[token.name]
+		switch {
+		case !ok:
+			missing = append(missing, token.name)
+		case token.tag == "=" && querySyntax.MatchString(value):
+			return "", fmt.Errorf("%w: parameter %s holds query syntax (and, select, where or line breaks); templates insert such values with {{{%s}}}", ports.ErrInvalid, token.name, token.name)
+		}
+		out.WriteString(value)
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: missing parameters %s", ports.ErrInvalid, strings.Join(missing, ", "))
+	}
+	return out.String(), nil
+}
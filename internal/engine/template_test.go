@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]string
+		want     string
+		err      bool
+	}{
+		{"value", "fileExtension={{ext}}", map[string]string{"ext": "go"}, "fileExtension=go", false},
+		{"spaced tag", "fileExtension={{ ext }}", map[string]string{"ext": "go"}, "fileExtension=go", false},
+		{"missing value", "fileExtension={{ext}}", nil, "", true},
+		{"injected condition", "fileExtension={{ext}}", map[string]string{"ext": "go and fileSize>0"}, "", true},
+		{"injected select", "fileExtension={{ext}}", map[string]string{"ext": "go SELECT text"}, "", true},
+		{"value with keyword", "filename contains {{name}}", map[string]string{"name": "android"}, "filename contains android", false},
+		{"verbatim value", "fileExtension=go and {{{extra}}}", map[string]string{"extra": "fileSize>0 and fileSize<10"}, "fileExtension=go and fileSize>0 and fileSize<10", false},
+		{"section set", "fileExtension=go{{#max}} and fileSize<{{max}}{{/max}}", map[string]string{"max": "10"}, "fileExtension=go and fileSize<10", false},
+		{"section unset", "fileExtension=go{{#max}} and fileSize<{{max}}{{/max}}", nil, "fileExtension=go", false},
+		{"section false", "fileExtension=go{{#max}} and fileSize<{{max}}{{/max}}", map[string]string{"max": "false"}, "fileExtension=go", false},
+		{"inverted section", "{{^ext}}fileExtension=md{{/ext}}{{#ext}}fileExtension={{ext}}{{/ext}}", nil, "fileExtension=md", false},
+		{"nested sections", "a=1{{#x}} and b=2{{#y}} and c={{y}}{{/y}}{{/x}}", map[string]string{"y": "3"}, "a=1", false},
+		{"unclosed section", "a=1{{#x}} and b=2", nil, "", true},
+		{"misnested sections", "{{#x}}{{#y}}a=1{{/x}}{{/y}}", nil, "", true},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate(tt.template, tt.params)
+		if tt.err {
+			assert.ErrorIs(t, err, ports.ErrInvalid, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
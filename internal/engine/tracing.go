@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys of the engine's spans
+const (
+	attrIndex     = attribute.Key("bitscout.index")
+	attrLoader    = attribute.Key("bitscout.loader")
+	attrQuery     = attribute.Key("bitscout.query")
+	attrResults   = attribute.Key("bitscout.results")
+	attrDocuments = attribute.Key("bitscout.documents")
+	attrNode      = attribute.Key("bitscout.node")
+)
+
+// tracerName is the instrumentation scope of the spans of document loading, feature extraction, index
+// writes and searches
+const tracerName = "github.com/aawadall/bit-scout/internal/engine"
+
+// SetTracerProvider records the engine's spans with provider. Without one they go to the global tracer
+// provider, where they are dropped until one is installed (see the telemetry package).
+func (e *EngineCore) SetTracerProvider(provider trace.TracerProvider) {
+	e.tracerProvider.Store(&provider)
+}
+
+// startSpan starts a child span of the span in ctx
+func (e *EngineCore) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider := otel.GetTracerProvider()
+	if configured := e.tracerProvider.Load(); configured != nil {
+		provider = *configured
+	}
+	return provider.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan marks a span failed when err is set and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
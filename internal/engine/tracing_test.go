@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEngineCore_TracesLoadingAndSearch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	core := NewEngineCore()
+	core.SetTracerProvider(provider)
+	core.RegisterIndex("idx", &docsIndex{docs: []models.Document{{ID: "a"}}})
+	core.RegisterStreamingLoader("fs", &sliceLoader{docs: makeDocs(3)})
+	core.RegisterFeatureExtractor("tags", tagExtractor{})
+	assert.NoError(t, core.AddPipeline(Pipeline{Loader: "fs", Extractors: []string{"tags"}}))
+
+	_, err := core.StreamLoader(context.Background(), "fs", "idx", 2)
+	assert.NoError(t, err)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	_, err = core.SearchContext(ctx, ports.SearchQuery{Query: "hello"})
+	assert.NoError(t, err)
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	var names []string
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{
+		"pipeline.ExtractFeatures", "index.AddDocuments", "pipeline.ExtractFeatures", "index.AddDocuments", "loader.Stream",
+		"index.Search", "engine.Search", "request",
+	}, names)
+
+	// Extraction and index writes belong to the load, the index search to the search of the caller's trace
+	load := spans["loader.Stream"].SpanContext()
+	assert.Equal(t, load.SpanID(), spans["index.AddDocuments"].Parent().SpanID())
+	assert.Equal(t, load.SpanID(), spans["pipeline.ExtractFeatures"].Parent().SpanID())
+	assert.Equal(t, spans["engine.Search"].SpanContext().SpanID(), spans["index.Search"].Parent().SpanID())
+	assert.Equal(t, parent.SpanContext().TraceID(), spans["index.Search"].SpanContext().TraceID())
+	assert.Contains(t, spans["loader.Stream"].Attributes(), attrDocuments.Int(3))
+	assert.Contains(t, spans["index.Search"].Attributes(), attrResults.Int(1))
+}
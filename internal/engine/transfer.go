@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportIndex writes the configuration and documents of an index (the default index if name is empty)
+// as NDJSON
+func (e *EngineCore) ExportIndex(name string, w io.Writer) error {
+	name, index, err := e.transferIndex(name)
+	if err != nil {
+		return err
+	}
+	if err := index.Export(w); err != nil {
+		return fmt.Errorf("failed to export index %s: %w", name, err)
+	}
+	return nil
+}
+
+// ImportIndex adds the documents of an export to an index (the default index if name is empty),
+// replacing documents with the same ID
+func (e *EngineCore) ImportIndex(name string, r io.Reader) error {
+	name, index, err := e.transferIndex(name)
+	if err != nil {
+		return err
+	}
+	if err := index.Import(r); err != nil {
+		return fmt.Errorf("failed to import into index %s: %w", name, err)
+	}
+	log.Info().Msgf("Imported documents into index %s", name)
+	return nil
+}
+
+// transferIndex looks up an index that supports export and import
+func (e *EngineCore) transferIndex(name string) (string, ports.TransferIndexPort, error) {
+	if name == "" {
+		defaultName, _, err := e.defaultIndexPort()
+		if err != nil {
+			return "", nil, err
+		}
+		name = defaultName
+	}
+	index, ok := e.index(name)
+	if !ok {
+		return name, nil, fmt.Errorf("index %s: %w", name, ports.ErrNotFound)
+	}
+	transfer, ok := index.(ports.TransferIndexPort)
+	if !ok {
+		return name, nil, fmt.Errorf("index %s cannot be exported or imported: %w", name, ports.ErrNotSupported)
+	}
+	return name, transfer, nil
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// transferIndex is a batchRecorder whose export is fixed and whose import is recorded
+type transferIndex struct {
+	batchRecorder
+	imported string
+}
+
+func (t *transferIndex) Export(w io.Writer) error {
+	_, err := io.WriteString(w, "export\n")
+	return err
+}
+
+func (t *transferIndex) Import(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	t.imported = string(data)
+	return err
+}
+
+func TestEngineCore_ExportImportIndex(t *testing.T) {
+	core := NewEngineCore()
+	idx := &transferIndex{}
+	core.RegisterIndex("docs", idx)
+	core.RegisterIndex("plain", &batchRecorder{})
+
+	var buf bytes.Buffer
+	assert.NoError(t, core.ExportIndex("", &buf), "the default index")
+	assert.Equal(t, "export\n", buf.String())
+
+	assert.NoError(t, core.ImportIndex("docs", bytes.NewBufferString("data")))
+	assert.Equal(t, "data", idx.imported)
+
+	assert.ErrorIs(t, core.ExportIndex("missing", &buf), ports.ErrNotFound)
+	assert.ErrorIs(t, core.ImportIndex("plain", &buf), ports.ErrNotSupported)
+}
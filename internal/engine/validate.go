@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// ValidateQuery checks a query without running it: its select clause, then the rest with the index of
+// its namespace, when the index can. Problems of the query are reported in the validation; the error is
+// for queries that cannot be checked (e.g. an unknown namespace).
+func (e *EngineCore) ValidateQuery(query ports.SearchQuery) (ports.QueryValidation, error) {
+	_, index, err := e.namespaceIndex(query.Namespace)
+	if err != nil {
+		return ports.QueryValidation{}, err
+	}
+	errs := []ports.QueryError{}
+	text := query.Query
+	if strings.TrimSpace(text) == "" {
+		errs = append(errs, ports.QueryError{Code: "syntax", Message: "the query is empty"})
+		return ports.QueryValidation{Errors: errs}, nil
+	}
+	if match := selectClause.FindStringSubmatchIndex(text); match != nil {
+		fields := text[match[2]:match[3]]
+		if _, err := models.ParseProjection(strings.Split(fields, ",")); err != nil {
+			errs = append(errs, ports.QueryError{
+				Offset:  utf8.RuneCountInString(text[:match[2]]),
+				Length:  utf8.RuneCountInString(fields),
+				Code:    "bad_select",
+				Message: "invalid select clause: " + err.Error(),
+			})
+		}
+		text = text[:match[0]]
+		if strings.TrimSpace(text) == "" {
+			errs = append(errs, ports.QueryError{Code: "syntax", Message: "the query has a select clause but nothing to search"})
+		}
+	}
+	if validator, ok := index.(ports.QueryValidatorIndexPort); ok && strings.TrimSpace(text) != "" {
+		errs = append(errs, validator.ValidateQuery(text)...)
+	}
+	return ports.QueryValidation{Valid: len(errs) == 0, Errors: errs}, nil
+}
+
+// dimensionWarnings returns the problems of a query that matched nothing which explain why: the
+// dimensions it names that no document of the index has
+func dimensionWarnings(index ports.IndexPort, query string) []ports.QueryError {
+	validator, ok := index.(ports.QueryValidatorIndexPort)
+	if !ok {
+		return nil
+	}
+	var warnings []ports.QueryError
+	for _, problem := range validator.ValidateQuery(query) {
+		if problem.Code == "unknown_dimension" {
+			warnings = append(warnings, problem)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// validatingIndex reports the queries holding "bad" as invalid there
+type validatingIndex struct {
+	docsIndex
+}
+
+func (v *validatingIndex) ValidateQuery(query string) []ports.QueryError {
+	var errs []ports.QueryError
+	if offset := strings.Index(query, "bad"); offset >= 0 {
+		errs = append(errs, ports.QueryError{Offset: offset, Length: 3, Code: "syntax", Message: "bad query"})
+	}
+	if offset := strings.Index(query, "author"); offset >= 0 {
+		errs = append(errs, ports.QueryError{Offset: offset, Length: 6, Code: "unknown_dimension", Message: `no document has the dimension "author"`})
+	}
+	return errs
+}
+
+func TestEngineCore_ValidateQuery(t *testing.T) {
+	core := NewEngineCore()
+	core.RegisterIndex("idx", &validatingIndex{})
+	core.RegisterIndex("plain", &docsIndex{})
+	assert.NoError(t, core.SetAlias("team-a", "plain"))
+
+	validation, err := core.ValidateQuery(ports.SearchQuery{Query: "fileExtension=go select id,source"})
+	assert.NoError(t, err)
+	assert.True(t, validation.Valid)
+	assert.Empty(t, validation.Errors)
+
+	validation, err = core.ValidateQuery(ports.SearchQuery{Query: "a bad query"})
+	assert.NoError(t, err)
+	assert.False(t, validation.Valid)
+	assert.Equal(t, []ports.QueryError{{Offset: 2, Length: 3, Code: "syntax", Message: "bad query"}}, validation.Errors)
+
+	// The select clause is checked by the engine
+	validation, err = core.ValidateQuery(ports.SearchQuery{Query: "fileExtension=go select text,-id"})
+	assert.NoError(t, err)
+	if assert.Len(t, validation.Errors, 1) {
+		assert.Equal(t, "bad_select", validation.Errors[0].Code)
+		assert.Equal(t, 24, validation.Errors[0].Offset)
+		assert.Equal(t, 8, validation.Errors[0].Length)
+	}
+	validation, err = core.ValidateQuery(ports.SearchQuery{Query: " "})
+	assert.NoError(t, err)
+	assert.False(t, validation.Valid)
+
+	// Indexes that cannot validate queries accept them all
+	validation, err = core.ValidateQuery(ports.SearchQuery{Query: "a bad query", Namespace: "team-a"})
+	assert.NoError(t, err)
+	assert.True(t, validation.Valid)
+
+	_, err = core.ValidateQuery(ports.SearchQuery{Query: "x", Namespace: "team-b"})
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+}
+
+func TestEngineCore_SearchWarnings(t *testing.T) {
+	core := NewEngineCore()
+	idx := &validatingIndex{}
+	core.RegisterIndex("idx", idx)
+
+	// Searches matching nothing report the dimensions no document has, not other problems
+	results, err := core.Search(ports.SearchQuery{Query: "bad author=alice"})
+	assert.NoError(t, err)
+	assert.Empty(t, results.Documents)
+	assert.Equal(t, []ports.QueryError{{Offset: 4, Length: 6, Code: "unknown_dimension", Message: `no document has the dimension "author"`}}, results.Warnings)
+
+	idx.docs = []models.Document{{ID: "1"}}
+	results, err = core.Search(ports.SearchQuery{Query: "author=alice"})
+	assert.NoError(t, err)
+	assert.Len(t, results.Documents, 1)
+	assert.Nil(t, results.Warnings)
+}
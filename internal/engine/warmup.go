@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// WarmUpOptions configures the warm-up phase of Start, run after the initial loads and before the APIs
+// start, so the engine reports ready only once it is warm
+type WarmUpOptions struct {
+	// Rebuild has every index rebuild the structures derived from its documents (ACL bitmaps, metadata
+	// catalog, postings), releasing the memory of the documents removed while loading
+	Rebuild bool
+	// Parallelism is the number of indexes rebuilt at once (0: one per CPU)
+	Parallelism int
+	// Queries are run against the default index, in order, e.g. to page in the documents they read
+	Queries []string
+	// Timeout bounds the whole warm-up (0: none); queries still to run when it expires are skipped
+	Timeout time.Duration
+}
+
+// SetWarmUpOptions configures the warm-up phase of Start
+func (e *EngineCore) SetWarmUpOptions(options WarmUpOptions) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.warmUp = options
+}
+
+// WarmUp rebuilds the indexes and runs the warm-up queries, as configured, logging their progress.
+// Failures are returned once every step ran; Start logs them without failing.
+func (e *EngineCore) WarmUp(ctx context.Context) error {
+	e.mu.RLock()
+	options := e.warmUp
+	indexes := make(map[string]ports.IndexPort, len(e.indexes))
+	for name, index := range e.indexes {
+		indexes[name] = index
+	}
+	e.mu.RUnlock()
+	if !options.Rebuild && len(options.Queries) == 0 {
+		return nil
+	}
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	started := time.Now()
+	var errs []error
+	if options.Rebuild {
+		errs = append(errs, rebuildIndexes(ctx, indexes, options.Parallelism)...)
+	}
+	errs = append(errs, e.runWarmUpQueries(ctx, options.Queries)...)
+	log.Info().Msgf("Warm-up finished in %s", time.Since(started).Round(time.Millisecond))
+	return errors.Join(errs...)
+}
+
+// rebuildIndexes warms up the indexes that support it, parallelism at a time
+func rebuildIndexes(ctx context.Context, indexes map[string]ports.IndexPort, parallelism int) []error {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	names := make([]string, 0, len(indexes))
+	for name, index := range indexes {
+		if _, ok := index.(ports.WarmUpIndexPort); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	log.Info().Msgf("Warming up %d indexes", len(names))
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	for i, name := range names {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			started := time.Now()
+			if err := indexes[name].(ports.WarmUpIndexPort).WarmUp(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("warm-up of index %s: %w", name, err))
+				mu.Unlock()
+				return
+			}
+			log.Info().Msgf("Warmed up index %s (%d of %d) in %s", name, i+1, len(names), time.Since(started).Round(time.Millisecond))
+		}(i, name)
+	}
+	wg.Wait()
+	return errs
+}
+
+// runWarmUpQueries runs queries against the default index, bypassing the search middlewares and the
+// query log so they do not count as traffic
+func (e *EngineCore) runWarmUpQueries(ctx context.Context, queries []string) []error {
+	if len(queries) == 0 {
+		return nil
+	}
+	_, index, err := e.defaultIndexPort()
+	if err != nil {
+		return []error{fmt.Errorf("warm-up queries: %w", err)}
+	}
+	var errs []error
+	for i, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return append(errs, fmt.Errorf("warm-up queries: %d of %d skipped: %w", len(queries)-i, len(queries), err))
+		}
+		started := time.Now()
+		results, err := searchIndex(ctx, index, query)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("warm-up query %q: %w", query, err))
+			continue
+		}
+		log.Info().Msgf("Warm-up query %d of %d matched %d documents in %s", i+1, len(queries), len(results), time.Since(started).Round(time.Millisecond))
+	}
+	return errs
+}
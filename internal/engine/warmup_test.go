@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// warmingIndex records its warm-ups and the queries searched in it
+type warmingIndex struct {
+	batchRecorder
+	mu      sync.Mutex
+	warmed  int
+	queries []string
+	err     error
+}
+
+func (w *warmingIndex) WarmUp(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warmed++
+	return w.err
+}
+
+func (w *warmingIndex) Search(ctx context.Context, query string) ([]interface{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.queries = append(w.queries, query)
+	return nil, nil
+}
+
+func TestEngineCore_StartWarmsUp(t *testing.T) {
+	core := NewEngineCore()
+	main, other := &warmingIndex{}, &warmingIndex{err: errors.New("disk full")}
+	core.RegisterIndex("main", main)
+	core.RegisterIndex("other", other)
+	core.RegisterIndex("plain", &batchRecorder{})
+	core.SetWarmUpOptions(WarmUpOptions{Rebuild: true, Parallelism: 1, Queries: []string{"fileExtension=go", "report"}})
+
+	// A failed warm-up is logged; the engine starts anyway
+	assert.NoError(t, core.Start(context.Background()))
+	defer core.Stop(context.Background())
+	assert.Equal(t, 1, main.warmed)
+	assert.Equal(t, 1, other.warmed)
+	assert.Equal(t, []string{"fileExtension=go", "report"}, main.queries)
+	assert.Empty(t, other.queries)
+	// Warm-up queries do not count as searches
+	stats, err := core.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Queries.Total)
+}
+
+func TestEngineCore_WarmUp(t *testing.T) {
+	core := NewEngineCore()
+	idx := &warmingIndex{err: errors.New("disk full")}
+	core.RegisterIndex("main", idx)
+
+	// Nothing is warmed up unless configured
+	assert.NoError(t, core.WarmUp(context.Background()))
+	assert.Equal(t, 0, idx.warmed)
+
+	core.SetWarmUpOptions(WarmUpOptions{Rebuild: true, Queries: []string{"report"}})
+	assert.ErrorContains(t, core.WarmUp(context.Background()), "warm-up of index main: disk full")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	core.SetWarmUpOptions(WarmUpOptions{Queries: []string{"report", "invoice"}})
+	assert.ErrorContains(t, core.WarmUp(ctx), "2 of 2 skipped")
+}
@@ -0,0 +1,184 @@
+package features
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+// DefaultFeatureCacheSize is the number of feature sets a FeatureCache keeps in memory
+const DefaultFeatureCacheSize = 10000
+
+// featureCacheBucket holds the persisted feature sets in a FeatureCache database
+var featureCacheBucket = []byte("features")
+
+func init() {
+	// Feature values are interfaces; gob needs the concrete types that are not registered by default
+	gob.Register(SparseVector{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// FeatureCache stores extracted feature sets keyed by extractor and document content (see CacheKey),
+// so documents that did not change since the last index run skip extraction. The most recent entries
+// are kept in memory; a cache opened with OpenFeatureCache also persists every entry in a bbolt bucket,
+// so they survive restarts. It is safe for concurrent use.
+type FeatureCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*FeatureSet
+	order   []string // Oldest first
+	db      *bbolt.DB
+}
+
+// NewFeatureCache creates an in-memory cache holding up to size feature sets
+func NewFeatureCache(size int) *FeatureCache {
+	if size <= 0 {
+		size = DefaultFeatureCacheSize
+	}
+	return &FeatureCache{size: size, entries: make(map[string]*FeatureSet)}
+}
+
+// OpenFeatureCache creates a cache backed by the bbolt database at path, which is created if missing
+func OpenFeatureCache(path string, size int) (*FeatureCache, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feature cache %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(featureCacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open feature cache %s: %w", path, err)
+	}
+	cache := NewFeatureCache(size)
+	cache.db = db
+	return cache, nil
+}
+
+// CacheKey identifies the features an extractor produces for a document: it hashes the extractor name,
+// its configuration and the document's text, source and metadata, so changing any of them is a miss.
+func CacheKey(extractorName string, cfg ExtractorConfig, doc models.Document) string {
+	h := sha256.New()
+	configText := fmt.Sprintf("%v", cfg) // Map keys are printed sorted, so equal configs hash equally
+	for _, part := range []string{extractorName, configText, doc.Source, doc.Text} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	keys := make([]string, 0, len(doc.Meta))
+	for key := range doc.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(key), key, len(doc.Meta[key]), doc.Meta[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the feature set cached under key, with its DocumentID set to documentID
+func (c *FeatureCache) Get(key, documentID string) (*FeatureSet, bool) {
+	c.mu.Lock()
+	featureSet, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if !ok && c.db != nil {
+		var data []byte
+		c.db.View(func(tx *bbolt.Tx) error {
+			data = append(data, tx.Bucket(featureCacheBucket).Get([]byte(key))...)
+			return nil
+		})
+		if len(data) == 0 {
+			return nil, false
+		}
+		var decoded FeatureSet
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+			log.Warn().Err(err).Msgf("Ignoring unreadable feature cache entry %s", key)
+			return nil, false
+		}
+		featureSet = &decoded
+		c.remember(key, featureSet)
+	} else if !ok {
+		return nil, false
+	}
+
+	copied := *featureSet
+	copied.DocumentID = documentID
+	return &copied, true
+}
+
+// Put caches a feature set under key
+func (c *FeatureCache) Put(key string, featureSet *FeatureSet) {
+	c.PutAll(map[string]*FeatureSet{key: featureSet})
+}
+
+// PutAll caches feature sets by key, persisting them in a single transaction. Entries that cannot be
+// encoded (a feature value of an unregistered type) are only kept in memory.
+func (c *FeatureCache) PutAll(entries map[string]*FeatureSet) {
+	encoded := make(map[string][]byte, len(entries))
+	for key, featureSet := range entries {
+		c.remember(key, featureSet)
+		if c.db == nil {
+			continue
+		}
+		var data bytes.Buffer
+		if err := gob.NewEncoder(&data).Encode(featureSet); err != nil {
+			log.Debug().Err(err).Msgf("Not persisting feature cache entry of document %s", featureSet.DocumentID)
+			continue
+		}
+		encoded[key] = data.Bytes()
+	}
+	if len(encoded) == 0 {
+		return
+	}
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(featureCacheBucket)
+		for key, data := range encoded {
+			if err := bucket.Put([]byte(key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed to persist %d feature cache entries", len(encoded))
+	}
+}
+
+// remember keeps an entry in memory, evicting the oldest entries beyond the cache size
+func (c *FeatureCache) remember(key string, featureSet *FeatureSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = featureSet
+	for len(c.order) > c.size {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// Len returns the number of entries held in memory
+func (c *FeatureCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Close closes the cache database, if any
+func (c *FeatureCache) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
@@ -0,0 +1,98 @@
+package features
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingExtractor counts the documents the keyword extractor actually runs on
+type countingExtractor struct {
+	*KeywordExtractor
+	extracted atomic.Int32
+}
+
+func (e *countingExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	e.extracted.Add(int32(len(docs)))
+	return e.KeywordExtractor.ExtractBatch(docs)
+}
+
+func TestCacheKey(t *testing.T) {
+	cfg := NewConfigBuilder().Build()
+	doc := models.Document{ID: "a", Text: "hello", Source: "/a.txt", Meta: map[string]string{"size": "5"}}
+	key := CacheKey("keywords", cfg, doc)
+
+	renamed := doc
+	renamed.ID = "b"
+	assert.Equal(t, key, CacheKey("keywords", cfg, renamed), "the document ID is not content")
+
+	edited := doc
+	edited.Text = "hello!"
+	assert.NotEqual(t, key, CacheKey("keywords", cfg, edited))
+	touched := doc
+	touched.Meta = map[string]string{"size": "6"}
+	assert.NotEqual(t, key, CacheKey("keywords", cfg, touched))
+	assert.NotEqual(t, key, CacheKey("content", cfg, doc))
+	assert.NotEqual(t, key, CacheKey("keywords", NewConfigBuilder().Weight(2).Build(), doc))
+}
+
+func TestFeatureCache_Persisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.db")
+	cache, err := OpenFeatureCache(path, 0)
+	assert.NoError(t, err)
+	cache.Put("k", &FeatureSet{DocumentID: "a", Features: map[string]Feature{
+		"keywords": {Name: "keywords", Value: []string{"search"}, Type: "json"},
+		"count":    {Name: "count", Value: 3, Type: "number"},
+	}, Vector: []float64{1, 0}})
+	assert.NoError(t, cache.Close())
+
+	reopened, err := OpenFeatureCache(path, 0)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, 0, reopened.Len())
+	featureSet, ok := reopened.Get("k", "b")
+	assert.True(t, ok)
+	assert.Equal(t, "b", featureSet.DocumentID)
+	assert.Equal(t, []string{"search"}, featureSet.Features["keywords"].Value, "value types survive persistence")
+	assert.Equal(t, 3, featureSet.Features["count"].Value)
+	assert.Equal(t, []float64{1, 0}, featureSet.Vector)
+	assert.Equal(t, 1, reopened.Len())
+
+	_, ok = reopened.Get("missing", "a")
+	assert.False(t, ok)
+}
+
+func TestFeatureCache_Evicts(t *testing.T) {
+	cache := NewFeatureCache(2)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Put(key, &FeatureSet{DocumentID: key})
+	}
+	assert.Equal(t, 2, cache.Len())
+	_, ok := cache.Get("a", "a")
+	assert.False(t, ok, "the oldest entry is evicted")
+	_, ok = cache.Get("c", "c")
+	assert.True(t, ok)
+}
+
+func TestFeatureRegistry_MergeCached(t *testing.T) {
+	extractor := &countingExtractor{KeywordExtractor: NewKeywordExtractor()}
+	registry := NewFeatureRegistry()
+	assert.NoError(t, registry.Register(extractor))
+	assert.NoError(t, registry.Configure("keywords", NewConfigBuilder().Build()))
+	assert.NoError(t, registry.SetMergeOptions("keywords", MergeOptions{Meta: true, Vector: true, Cache: true}))
+	registry.SetCache(NewFeatureCache(0))
+
+	docs := []models.Document{{ID: "a", Text: "mail ops@example.com"}, {ID: "b", Text: "nothing"}}
+	assert.NoError(t, registry.Merge(docs, []string{"keywords"}))
+	assert.Equal(t, int32(2), extractor.extracted.Load())
+
+	// Re-indexing the same content only extracts the changed document
+	again := []models.Document{{ID: "a", Text: "mail ops@example.com"}, {ID: "b", Text: "something new"}}
+	assert.NoError(t, registry.Merge(again, []string{"keywords"}))
+	assert.Equal(t, int32(3), extractor.extracted.Load())
+	assert.Equal(t, docs[0].Meta, again[0].Meta)
+	assert.Equal(t, docs[0].Vector, again[0].Vector)
+}
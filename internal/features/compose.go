@@ -0,0 +1,158 @@
+package features
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Compose merges the feature sets extracted from one document by different extractors (e.g. the result of
+// ExtractAll) into a single set.
+//
+// Features are namespaced as "<extractor>.<feature>", except those renamed by their extractor's FeatureMap,
+// which keep the mapped name; mapping is therefore how features are shared or collisions resolved, and two
+// features ending up under the same name is an error. Vectors are concatenated in extractor name order.
+// When an extractor's Normalize flag is set its vector is scaled first: with the extractor's corpus
+// statistics once they are fitted (see MergeOptions.Normalization), to unit length otherwise.
+func (r *FeatureRegistry) Compose(sets []*FeatureSet) (*FeatureSet, error) {
+	if len(sets) == 0 {
+		return &FeatureSet{Features: make(map[string]Feature), Vector: []float64{}}, nil
+	}
+	byExtractor := make(map[string]*FeatureSet, len(sets))
+	for _, set := range sets {
+		if set.DocumentID != sets[0].DocumentID {
+			return nil, fmt.Errorf("cannot compose feature sets of documents %s and %s", sets[0].DocumentID, set.DocumentID)
+		}
+		if err := addComposed(byExtractor, set); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.compose(sets[0].DocumentID, sortedExtractors(byExtractor), byExtractor, nil)
+}
+
+// ComposeBatch composes the per-extractor results of ExtractAllBatch into one set per document, in the
+// order documents first appear. A document an extractor produced no set for gets zeros in that
+// extractor's part of the vector, so every composed vector has the same layout.
+func (r *FeatureRegistry) ComposeBatch(batches [][]*FeatureSet) ([]*FeatureSet, error) {
+	var order []string
+	perDocument := make(map[string]map[string]*FeatureSet)
+	widths := make(map[string]int)
+	for _, batch := range batches {
+		for _, set := range batch {
+			if _, seen := perDocument[set.DocumentID]; !seen {
+				perDocument[set.DocumentID] = make(map[string]*FeatureSet)
+				order = append(order, set.DocumentID)
+			}
+			if err := addComposed(perDocument[set.DocumentID], set); err != nil {
+				return nil, err
+			}
+			if len(set.Vector) > widths[set.Extractor] {
+				widths[set.Extractor] = len(set.Vector)
+			}
+		}
+	}
+
+	extractors := make([]string, 0, len(widths))
+	for name := range widths {
+		extractors = append(extractors, name)
+	}
+	sort.Strings(extractors)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	composed := make([]*FeatureSet, 0, len(order))
+	for _, documentID := range order {
+		set, err := r.compose(documentID, extractors, perDocument[documentID], widths)
+		if err != nil {
+			return nil, err
+		}
+		composed = append(composed, set)
+	}
+	return composed, nil
+}
+
+func addComposed(byExtractor map[string]*FeatureSet, set *FeatureSet) error {
+	if set.Extractor == "" {
+		return fmt.Errorf("feature set of document %s has no extractor name", set.DocumentID)
+	}
+	if _, exists := byExtractor[set.Extractor]; exists {
+		return fmt.Errorf("document %s has two feature sets from extractor %s", set.DocumentID, set.Extractor)
+	}
+	byExtractor[set.Extractor] = set
+	return nil
+}
+
+func sortedExtractors(byExtractor map[string]*FeatureSet) []string {
+	names := make([]string, 0, len(byExtractor))
+	for name := range byExtractor {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compose builds the composed set of one document; widths (optional) pads missing or short vectors.
+// The caller must hold the read lock.
+func (r *FeatureRegistry) compose(documentID string, extractors []string, sets map[string]*FeatureSet, widths map[string]int) (*FeatureSet, error) {
+	composed := &FeatureSet{DocumentID: documentID, Features: make(map[string]Feature), Vector: []float64{}}
+	owners := make(map[string]string) // Composed feature name -> extractor
+	for _, extractor := range extractors {
+		set := sets[extractor]
+		cfg := r.configs[extractor]
+		if set != nil {
+			mapped := make(map[string]bool, len(cfg.FeatureMap))
+			for _, output := range cfg.FeatureMap {
+				mapped[output] = true
+			}
+			for name, feature := range set.Features {
+				if !mapped[name] {
+					name = extractor + "." + name
+				}
+				if owner, exists := owners[name]; exists {
+					return nil, fmt.Errorf("feature %s of document %s is produced by both %s and %s; rename one with a FeatureMap",
+						name, documentID, owner, extractor)
+				}
+				owners[name] = extractor
+				feature.Name = name
+				composed.Features[name] = feature
+			}
+		}
+
+		var block []float64
+		if set != nil {
+			block = set.Vector
+		}
+		if cfg.Normalize {
+			if normalizer, ok := r.normalizers[extractor]; ok && normalizer.Fitted() {
+				block = normalizer.Transform(block)
+			} else {
+				block = unitLength(block)
+			}
+		}
+		composed.Vector = append(composed.Vector, block...)
+		for i := len(block); i < widths[extractor]; i++ {
+			composed.Vector = append(composed.Vector, 0)
+		}
+	}
+	return composed, nil
+}
+
+// unitLength returns a copy of vector scaled to an L2 norm of 1 (zero vectors are returned unchanged)
+func unitLength(vector []float64) []float64 {
+	norm := 0.0
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	scaled := make([]float64, len(vector))
+	for i, v := range vector {
+		scaled[i] = v / norm
+	}
+	return scaled
+}
@@ -0,0 +1,65 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureRegistry_Compose(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"content":  {"normalize": false},
+		"keywords": {"feature_map": map[string]interface{}{"keywords": "tags"}},
+	}, "keywords", "content")
+
+	sets, err := registry.ExtractAll(models.Document{ID: "a", Text: "Write to ops@example.com today."})
+	assert.NoError(t, err)
+	composed, err := registry.Compose(sets)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "a", composed.DocumentID)
+	assert.Contains(t, composed.Features, "content.sentence_count")
+	assert.Equal(t, "keywords.has_email", composed.Features["keywords.has_email"].Name)
+	assert.Contains(t, composed.Features, "tags", "mapped features keep their name")
+	assert.NotContains(t, composed.Features, "keywords.tags")
+
+	// Content's raw vector comes first (sorted by extractor), then keywords' unit-length vector
+	contentSet, _ := registry.extractors["content"].Extract(models.Document{ID: "a", Text: "Write to ops@example.com today."})
+	assert.InDeltaSlice(t, append(contentSet.Vector, 1, 0, 0, 0), composed.Vector, 1e-9)
+}
+
+func TestFeatureRegistry_ComposeCollisions(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"hash":     {"feature_map": map[string]interface{}{"md5": "checksum"}},
+		"keywords": {"feature_map": map[string]interface{}{"keywords": "checksum"}},
+	}, "hash", "keywords")
+
+	sets, err := registry.ExtractAll(models.Document{ID: "a", Text: "text"})
+	assert.NoError(t, err)
+	_, err = registry.Compose(sets)
+	assert.ErrorContains(t, err, "checksum")
+
+	_, err = registry.Compose([]*FeatureSet{{DocumentID: "a", Extractor: "hash"}, {DocumentID: "b", Extractor: "keywords"}})
+	assert.Error(t, err)
+}
+
+func TestFeatureRegistry_ComposeBatchPadsMissingSets(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"keywords": {"normalize": false},
+		"mime":     {"normalize": false},
+	}, "keywords", "mime")
+
+	batches := [][]*FeatureSet{
+		{{DocumentID: "a", Extractor: "mime", Vector: []float64{1}}},
+		{
+			{DocumentID: "a", Extractor: "keywords", Vector: []float64{0, 1, 0, 0}},
+			{DocumentID: "b", Extractor: "keywords", Vector: []float64{1, 0, 0, 0}},
+		},
+	}
+	composed, err := registry.ComposeBatch(batches)
+	assert.NoError(t, err)
+	assert.Len(t, composed, 2)
+	assert.Equal(t, []float64{0, 1, 0, 0, 1}, composed[0].Vector)
+	assert.Equal(t, []float64{1, 0, 0, 0, 0}, composed[1].Vector)
+}
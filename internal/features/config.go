@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/aawadall/bit-scout/internal/config"
 )
 
 // ConfigBuilder provides a fluent interface for building extractor configurations
@@ -80,6 +82,63 @@ func (b *ConfigBuilder) Build() ExtractorConfig {
 	return b.config
 }
 
+// FromMap builds a configuration from a starter config map with the keys "enabled", "weight",
+// "normalize", "vectorize", "parameters" and "feature_map". Merge options ("meta", "vector",
+// "normalization", "normalizer_path", "parallelism", "timeout" and "cache") are skipped, and any other key is treated as a parameter.
+func FromMap(cfg map[string]interface{}) (ExtractorConfig, error) {
+	builder := NewConfigBuilder()
+	for key, value := range cfg {
+		switch key {
+		case "enabled":
+			enabled, err := config.Bool(cfg, key, true)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			builder.Enabled(enabled)
+		case "weight":
+			weight, err := config.Float(cfg, key, 1.0)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			builder.Weight(weight)
+		case "normalize":
+			normalize, err := config.Bool(cfg, key, true)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			builder.Normalize(normalize)
+		case "vectorize":
+			vectorize, err := config.Bool(cfg, key, true)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			builder.Vectorize(vectorize)
+		case "parameters":
+			params, err := config.Map(cfg, key)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			builder.Parameters(params)
+		case "feature_map":
+			mapping, err := config.Map(cfg, key)
+			if err != nil {
+				return ExtractorConfig{}, err
+			}
+			for internal, output := range mapping {
+				name, ok := output.(string)
+				if !ok {
+					return ExtractorConfig{}, fmt.Errorf("config key feature_map.%s must be a string, got %T", internal, output)
+				}
+				builder.MapFeature(internal, name)
+			}
+		case "meta", "vector", "normalization", "normalizer_path", "parallelism", "timeout", "cache":
+		default:
+			builder.Parameter(key, value)
+		}
+	}
+	return builder.Build(), nil
+}
+
 // PresetConfigs provides common configuration presets
 type PresetConfigs struct{}
 
@@ -88,24 +147,28 @@ func NewPresetConfigs() *PresetConfigs {
 	return &PresetConfigs{}
 }
 
-// Minimal creates a minimal configuration with only basic features
+// Minimal creates a minimal configuration with only basic features (file name, extension and size)
 func (p *PresetConfigs) Minimal() ExtractorConfig {
 	return NewConfigBuilder().
 		Weight(0.5).
 		Parameters(map[string]interface{}{
-			"include_content_features":   false,
-			"include_timestamp_features": false,
+			"include_content_features":    false,
+			"include_timestamp_features":  false,
+			"include_path_features":       false,
+			"include_permission_features": false,
 		}).
 		Build()
 }
 
-// Standard creates a standard configuration with most features enabled
+// Standard creates a standard configuration with most features enabled (all but permissions)
 func (p *PresetConfigs) Standard() ExtractorConfig {
 	return NewConfigBuilder().
 		Weight(1.0).
 		Parameters(map[string]interface{}{
-			"include_content_features":   true,
-			"include_timestamp_features": true,
+			"include_content_features":    true,
+			"include_timestamp_features":  true,
+			"include_path_features":       true,
+			"include_permission_features": false,
 		}).
 		Build()
 }
@@ -203,7 +266,7 @@ func (rc *RegistryConfig) GetExtractorConfig(name string) (ExtractorConfig, bool
 // ApplyToRegistry applies this configuration to a feature registry
 func (rc *RegistryConfig) ApplyToRegistry(registry *FeatureRegistry) error {
 	// Apply global defaults
-	for name := range registry.extractors {
+	for _, name := range registry.ListExtractors() {
 		config, exists := rc.Extractors[name]
 		if !exists {
 			// Use global defaults
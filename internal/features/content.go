@@ -0,0 +1,293 @@
+package features
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// ContentExtractor extracts text statistics from document content
+type ContentExtractor struct {
+	config ExtractorConfig
+}
+
+// NewContentExtractor creates a new content feature extractor
+func NewContentExtractor() *ContentExtractor {
+	return &ContentExtractor{
+		config: ExtractorConfig{
+			Enabled:    true,
+			Weight:     1.0,
+			Parameters: make(map[string]interface{}),
+			FeatureMap: make(map[string]string),
+			Normalize:  true,
+			Vectorize:  true,
+		},
+	}
+}
+
+// contentNumericFeatures lists the features produced by ContentExtractor, in vector order
+var contentNumericFeatures = []string{
+	"sentence_count", "average_word_length", "average_sentence_length", "unique_term_ratio",
+	"entropy", "flesch_reading_ease", "automated_readability_index",
+	"letter_ratio", "digit_ratio", "whitespace_ratio", "punctuation_ratio", "symbol_ratio",
+	"uppercase_ratio", "other_ratio",
+}
+
+// Name returns the name of this extractor
+func (e *ContentExtractor) Name() string {
+	return "content"
+}
+
+// Configure sets the configuration for this extractor
+func (e *ContentExtractor) Configure(config ExtractorConfig) error {
+	e.config = config
+	log.Debug().Msgf("ContentExtractor configured with enabled=%v, weight=%f", config.Enabled, config.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *ContentExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract extracts text statistics from a single document
+func (e *ContentExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	stats := analyzeContent(doc.Text)
+	values := map[string]interface{}{
+		"sentence_count":              stats.sentences,
+		"average_word_length":         ratio(stats.wordChars, stats.words),
+		"average_sentence_length":     ratio(stats.words, stats.sentences),
+		"unique_term_ratio":           ratio(stats.uniqueWords, stats.words),
+		"entropy":                     stats.entropy,
+		"flesch_reading_ease":         stats.fleschReadingEase(),
+		"automated_readability_index": stats.automatedReadabilityIndex(),
+		"letter_ratio":                ratio(stats.letters, stats.chars),
+		"digit_ratio":                 ratio(stats.digits, stats.chars),
+		"whitespace_ratio":            ratio(stats.spaces, stats.chars),
+		"punctuation_ratio":           ratio(stats.punctuation, stats.chars),
+		"symbol_ratio":                ratio(stats.symbols, stats.chars),
+		"uppercase_ratio":             ratio(stats.uppercase, stats.letters),
+		"other_ratio":                 ratio(stats.other, stats.chars),
+	}
+
+	features := make(map[string]Feature, len(values))
+	for name, value := range values {
+		features[name] = Feature{
+			Name:   name,
+			Value:  value,
+			Type:   "number",
+			Weight: e.config.Weight,
+		}
+	}
+
+	// Generate the vector before mapping so its layout does not depend on output names
+	var vector []float64
+	if e.config.Vectorize {
+		vector = e.generateVector(features)
+	}
+
+	// Apply feature mapping if configured
+	if len(e.config.FeatureMap) > 0 {
+		mappedFeatures := make(map[string]Feature)
+		for name, feature := range features {
+			if mappedName, exists := e.config.FeatureMap[name]; exists {
+				feature.Name = mappedName
+				mappedFeatures[mappedName] = feature
+			} else {
+				mappedFeatures[name] = feature
+			}
+		}
+		features = mappedFeatures
+	}
+
+	featureSet := &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}
+
+	log.Debug().Msgf("Extracted %d content features from document %s", len(features), doc.ID)
+	return featureSet, nil
+}
+
+// ExtractBatch extracts content features from multiple documents
+func (e *ContentExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted content features from %d documents", len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *ContentExtractor) GetSupportedFeatures() []string {
+	return append([]string(nil), contentNumericFeatures...)
+}
+
+// Validate checks if the extractor is properly configured
+func (e *ContentExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
+
+// generateVector creates a vector representation of the features
+func (e *ContentExtractor) generateVector(features map[string]Feature) []float64 {
+	var vector []float64
+	for _, featureName := range contentNumericFeatures {
+		if feature, exists := features[featureName]; exists {
+			if value, ok := feature.Value.(float64); ok {
+				vector = append(vector, value*feature.Weight)
+			} else if value, ok := feature.Value.(int); ok {
+				vector = append(vector, float64(value)*feature.Weight)
+			}
+		}
+	}
+	return vector
+}
+
+// contentStats holds the raw counts text statistics are derived from
+type contentStats struct {
+	chars, letters, digits, spaces, punctuation, symbols, uppercase, other int
+	words, wordChars, uniqueWords, syllables, sentences                    int
+	entropy                                                                float64 // Shannon entropy in bits per character
+}
+
+// analyzeContent counts characters, words and sentences in a single pass over the text
+func analyzeContent(text string) contentStats {
+	var stats contentStats
+	runeCounts := make(map[rune]int)
+	unique := make(map[string]struct{})
+	var word strings.Builder
+	inSentence := false
+
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToLower(word.String())
+		stats.words++
+		stats.wordChars += len([]rune(w))
+		stats.syllables += countSyllables(w)
+		unique[w] = struct{}{}
+		word.Reset()
+	}
+
+	for _, r := range text {
+		stats.chars++
+		runeCounts[r]++
+		switch {
+		case unicode.IsLetter(r):
+			stats.letters++
+			if unicode.IsUpper(r) {
+				stats.uppercase++
+			}
+		case unicode.IsDigit(r):
+			stats.digits++
+		case unicode.IsSpace(r):
+			stats.spaces++
+		case unicode.IsPunct(r):
+			stats.punctuation++
+		case unicode.IsSymbol(r):
+			stats.symbols++
+		default:
+			stats.other++
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || (r == '\'' && word.Len() > 0) {
+			word.WriteRune(r)
+			inSentence = true
+			continue
+		}
+		endWord()
+		if isSentenceTerminator(r) && inSentence {
+			stats.sentences++
+			inSentence = false
+		}
+	}
+	endWord()
+	if inSentence {
+		stats.sentences++ // Trailing text without a terminator
+	}
+	stats.uniqueWords = len(unique)
+
+	for _, count := range runeCounts {
+		p := float64(count) / float64(stats.chars)
+		stats.entropy -= p * math.Log2(p)
+	}
+	return stats
+}
+
+// fleschReadingEase scores English-like text from 0 (hard) to 100 (easy); values outside that range are possible
+func (s contentStats) fleschReadingEase() float64 {
+	if s.words == 0 || s.sentences == 0 {
+		return 0
+	}
+	return 206.835 - 1.015*ratio(s.words, s.sentences) - 84.6*ratio(s.syllables, s.words)
+}
+
+// automatedReadabilityIndex approximates the US grade level needed to read the text; it only uses
+// character and word counts, so it does not depend on English syllable rules
+func (s contentStats) automatedReadabilityIndex() float64 {
+	if s.words == 0 || s.sentences == 0 {
+		return 0
+	}
+	return 4.71*ratio(s.wordChars, s.words) + 0.5*ratio(s.words, s.sentences) - 21.43
+}
+
+func isSentenceTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// countSyllables estimates syllables as groups of vowels, ignoring a silent trailing "e"
+func countSyllables(word string) int {
+	count := 0
+	previousVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+// ratio divides two counts, returning 0 for an empty denominator
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
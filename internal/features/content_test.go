@@ -0,0 +1,82 @@
+package features
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentExtractor_Extract(t *testing.T) {
+	extractor := NewContentExtractor()
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: "The cat sat. The dog ran!"})
+	assert.NoError(t, err)
+
+	value := func(name string) interface{} { return featureSet.Features[name].Value }
+	assert.Equal(t, 2, value("sentence_count"))
+	assert.InDelta(t, 3.0, value("average_word_length"), 1e-9)
+	assert.InDelta(t, 3.0, value("average_sentence_length"), 1e-9)
+	assert.InDelta(t, 5.0/6.0, value("unique_term_ratio"), 1e-9) // "the" repeats
+	assert.InDelta(t, 18.0/25.0, value("letter_ratio"), 1e-9)
+	assert.InDelta(t, 5.0/25.0, value("whitespace_ratio"), 1e-9)
+	assert.InDelta(t, 2.0/25.0, value("punctuation_ratio"), 1e-9)
+	assert.InDelta(t, 2.0/18.0, value("uppercase_ratio"), 1e-9)
+	assert.Greater(t, value("flesch_reading_ease").(float64), 100.0) // one-syllable words, short sentences
+	assert.Equal(t, "number", featureSet.Features["entropy"].Type)
+	assert.Len(t, featureSet.Vector, len(contentNumericFeatures))
+}
+
+func TestContentExtractor_Entropy(t *testing.T) {
+	extractor := NewContentExtractor()
+	uniform, err := extractor.Extract(models.Document{Text: "abcd"})
+	assert.NoError(t, err)
+	assert.InDelta(t, 2.0, uniform.Features["entropy"].Value, 1e-9)
+
+	constant, err := extractor.Extract(models.Document{Text: "aaaa"})
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0, constant.Features["entropy"].Value, 1e-9)
+}
+
+func TestContentExtractor_EmptyText(t *testing.T) {
+	featureSet, err := NewContentExtractor().Extract(models.Document{ID: "empty"})
+	assert.NoError(t, err)
+	for _, name := range contentNumericFeatures {
+		assert.Contains(t, featureSet.Features, name)
+	}
+	for _, v := range featureSet.Vector {
+		assert.False(t, math.IsNaN(v))
+		assert.Zero(t, v)
+	}
+}
+
+func TestContentExtractor_UnterminatedAndUnicodeSentences(t *testing.T) {
+	featureSet, err := NewContentExtractor().Extract(models.Document{Text: "Première phrase... Deuxième phrase? 第三句。 trailing words"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, featureSet.Features["sentence_count"].Value)
+}
+
+func TestContentExtractor_FeatureMapAndWeight(t *testing.T) {
+	extractor := NewContentExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Weight(2).MapFeature("entropy", "text_entropy").Build()))
+
+	featureSet, err := extractor.Extract(models.Document{Text: "abcd"})
+	assert.NoError(t, err)
+	assert.Contains(t, featureSet.Features, "text_entropy")
+	assert.NotContains(t, featureSet.Features, "entropy")
+	assert.Equal(t, 2.0, featureSet.Features["text_entropy"].Weight)
+	assert.InDelta(t, 4.0, featureSet.Vector[4], 1e-9) // entropy 2 bits, weighted
+}
+
+func TestFeatureRegistry_RegisterBuiltins(t *testing.T) {
+	registry := NewFeatureRegistry()
+	assert.NoError(t, registry.RegisterBuiltins())
+	assert.ElementsMatch(t, []string{"filesystem", "content", "mime", "hash", "keywords"}, registry.ListExtractors())
+	assert.Error(t, registry.RegisterBuiltins())
+}
+
+func TestCountSyllables(t *testing.T) {
+	for word, want := range map[string]int{"cat": 1, "table": 2, "make": 1, "reading": 2, "rhythm": 1, "xyz": 1, "": 1} {
+		assert.Equal(t, want, countSyllables(word), word)
+	}
+}
@@ -0,0 +1,307 @@
+package features
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultEmbeddingBatchSize is the number of texts sent to a provider per request
+	DefaultEmbeddingBatchSize = 32
+	// DefaultEmbeddingCacheSize is the number of embeddings kept in memory
+	DefaultEmbeddingCacheSize = 10000
+)
+
+// EmbeddingProvider turns texts into dense vectors, one per text and in the same order
+type EmbeddingProvider interface {
+	// Name identifies the provider and model (e.g. "ollama/nomic-embed-text"); it is part of cache keys
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbeddingExtractor produces a dense embedding of each document's text using a configurable provider.
+// Texts are sent in batches, failed requests are retried, and embeddings are cached by content hash
+// so unchanged documents are not re-embedded.
+//
+// Parameters: "provider" (see EmbeddingProviderTypes, default "openai"), the provider's own options
+// ("base_url", "model", "api_key", "timeout", "retries", "backoff"), "batch_size", "cache_size",
+// "dimensions" (expected vector size, checked when set) and "max_chars" (truncate longer texts).
+type EmbeddingExtractor struct {
+	config     ExtractorConfig
+	provider   EmbeddingProvider
+	batchSize  int
+	dimensions int
+	maxChars   int
+	cache      *embeddingCache
+}
+
+// NewEmbeddingExtractor creates an embedding extractor using the default OpenAI-compatible provider
+func NewEmbeddingExtractor() *EmbeddingExtractor {
+	e := &EmbeddingExtractor{}
+	// The default configuration has no parameters, so it cannot fail
+	_ = e.Configure(NewConfigBuilder().Build())
+	return e
+}
+
+// Name returns the name of this extractor
+func (e *EmbeddingExtractor) Name() string {
+	return "embedding"
+}
+
+// Configure sets the configuration for this extractor and creates its provider.
+// Cached embeddings are kept unless the provider or model changes.
+func (e *EmbeddingExtractor) Configure(cfg ExtractorConfig) error {
+	params := cfg.Parameters
+	providerType, err := config.String(params, "provider", "openai")
+	if err != nil {
+		return err
+	}
+	provider, err := NewEmbeddingProvider(providerType, params)
+	if err != nil {
+		return err
+	}
+	batchSize, err := config.Int(params, "batch_size", DefaultEmbeddingBatchSize)
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive, got %d", batchSize)
+	}
+	cacheSize, err := config.Int(params, "cache_size", DefaultEmbeddingCacheSize)
+	if err != nil {
+		return err
+	}
+	dimensions, err := config.Int(params, "dimensions", 0)
+	if err != nil {
+		return err
+	}
+	maxChars, err := config.Int(params, "max_chars", 0)
+	if err != nil {
+		return err
+	}
+
+	if e.cache == nil || e.provider == nil || e.provider.Name() != provider.Name() {
+		e.cache = newEmbeddingCache(cacheSize)
+	} else {
+		e.cache.resize(cacheSize)
+	}
+	e.config = cfg
+	e.provider = provider
+	e.batchSize = batchSize
+	e.dimensions = dimensions
+	e.maxChars = maxChars
+	log.Debug().Msgf("EmbeddingExtractor configured with provider=%s, enabled=%v, weight=%f", provider.Name(), cfg.Enabled, cfg.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *EmbeddingExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract embeds a single document
+func (e *EmbeddingExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	embeddings, err := e.embed([]models.Document{doc})
+	if err != nil {
+		return nil, err
+	}
+	return e.featureSet(doc, embeddings[0]), nil
+}
+
+// ExtractBatch embeds multiple documents; only cache misses are sent to the provider.
+// Documents in a failed provider request are skipped.
+func (e *EmbeddingExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+	if !e.config.Enabled {
+		for _, doc := range docs {
+			featureSet, _ := e.Extract(doc)
+			results = append(results, featureSet)
+		}
+		return results, nil
+	}
+
+	for start := 0; start < len(docs); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch := docs[start:end]
+		embeddings, err := e.embed(batch)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to embed %d documents", len(batch))
+			continue
+		}
+		for i, doc := range batch {
+			results = append(results, e.featureSet(doc, embeddings[i]))
+		}
+	}
+
+	log.Info().Msgf("Extracted embeddings from %d documents", len(results))
+	return results, nil
+}
+
+// embed returns one embedding per document, calling the provider for the texts not in the cache
+func (e *EmbeddingExtractor) embed(docs []models.Document) ([][]float64, error) {
+	embeddings := make([][]float64, len(docs))
+	var missing []string
+	missingKeys := make(map[string][]int) // Cache key -> positions of the documents with that text
+	for i, doc := range docs {
+		text := e.text(doc)
+		key := e.cacheKey(text)
+		if vector, ok := e.cache.get(key); ok {
+			embeddings[i] = vector
+			continue
+		}
+		if _, pending := missingKeys[key]; !pending {
+			missing = append(missing, text)
+		}
+		missingKeys[key] = append(missingKeys[key], i)
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	vectors, err := e.provider.Embed(context.Background(), missing)
+	if err != nil {
+		return nil, fmt.Errorf("%s embedding request failed: %w", e.provider.Name(), err)
+	}
+	if len(vectors) != len(missing) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", e.provider.Name(), len(vectors), len(missing))
+	}
+	for i, text := range missing {
+		if e.dimensions > 0 && len(vectors[i]) != e.dimensions {
+			return nil, fmt.Errorf("%s returned a %d-dimensional embedding, expected %d", e.provider.Name(), len(vectors[i]), e.dimensions)
+		}
+		key := e.cacheKey(text)
+		e.cache.put(key, vectors[i])
+		for _, position := range missingKeys[key] {
+			embeddings[position] = vectors[i]
+		}
+	}
+	return embeddings, nil
+}
+
+func (e *EmbeddingExtractor) text(doc models.Document) string {
+	if e.maxChars > 0 {
+		if runes := []rune(doc.Text); len(runes) > e.maxChars {
+			return string(runes[:e.maxChars])
+		}
+	}
+	return doc.Text
+}
+
+// cacheKey identifies a text embedded by the current provider and model
+func (e *EmbeddingExtractor) cacheKey(text string) string {
+	sum := sha256.Sum256([]byte(e.provider.Name() + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *EmbeddingExtractor) featureSet(doc models.Document, embedding []float64) *FeatureSet {
+	features := make(map[string]Feature, 2)
+	for name, feature := range map[string]Feature{
+		"embedding":            {Value: embedding, Type: "vector"},
+		"embedding_dimensions": {Value: len(embedding), Type: "number"},
+	} {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature.Name = name
+		feature.Weight = e.config.Weight
+		features[name] = feature
+	}
+
+	var vector []float64
+	if e.config.Vectorize {
+		vector = make([]float64, len(embedding))
+		for i, v := range embedding {
+			vector[i] = v * e.config.Weight
+		}
+	}
+	return &FeatureSet{DocumentID: doc.ID, Features: features, Vector: vector}
+}
+
+// NeedsWholeBatch reports that ExtractBatch must see the whole batch: cache misses are embedded in bulk provider requests
+func (e *EmbeddingExtractor) NeedsWholeBatch() bool {
+	return true
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *EmbeddingExtractor) GetSupportedFeatures() []string {
+	return []string{"embedding", "embedding_dimensions"}
+}
+
+// Validate checks if the extractor is properly configured
+func (e *EmbeddingExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
+
+// embeddingCache is a bounded in-memory cache; the oldest entries are evicted first
+type embeddingCache struct {
+	mu      sync.Mutex
+	size    int
+	vectors map[string][]float64
+	order   []string
+}
+
+func newEmbeddingCache(size int) *embeddingCache {
+	return &embeddingCache{size: size, vectors: make(map[string][]float64)}
+}
+
+func (c *embeddingCache) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vector, ok := c.vectors[key]
+	return vector, ok
+}
+
+func (c *embeddingCache) put(key string, vector []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if _, exists := c.vectors[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.vectors[key] = vector
+	c.evict()
+}
+
+func (c *embeddingCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	c.evict()
+}
+
+// evict drops the oldest entries beyond the cache size; the caller must hold the lock
+func (c *embeddingCache) evict() {
+	for len(c.order) > c.size && len(c.order) > 0 {
+		delete(c.vectors, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *embeddingCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.vectors)
+}
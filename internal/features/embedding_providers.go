@@ -0,0 +1,303 @@
+package features
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+)
+
+// EmbeddingProviderConstructor builds a provider from the embedding extractor's parameters
+type EmbeddingProviderConstructor func(params map[string]interface{}) (EmbeddingProvider, error)
+
+// embeddingProviders holds the provider types known to NewEmbeddingProvider
+var embeddingProviders = map[string]EmbeddingProviderConstructor{
+	"openai": newOpenAIProvider,
+	"ollama": newOllamaProvider,
+	"onnx":   newONNXProvider,
+}
+
+// RegisterEmbeddingProvider adds (or replaces) an embedding provider type, e.g. one running a model
+// in-process through native libraries
+func RegisterEmbeddingProvider(typeName string, constructor EmbeddingProviderConstructor) {
+	embeddingProviders[typeName] = constructor
+}
+
+// EmbeddingProviderTypes returns the registered provider types, sorted
+func EmbeddingProviderTypes() []string {
+	types := make([]string, 0, len(embeddingProviders))
+	for typeName := range embeddingProviders {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewEmbeddingProvider creates a provider of the given type
+func NewEmbeddingProvider(typeName string, params map[string]interface{}) (EmbeddingProvider, error) {
+	constructor, ok := embeddingProviders[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding provider %s (known providers: %v)", typeName, EmbeddingProviderTypes())
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return constructor(params)
+}
+
+// httpEmbedder holds the connection settings shared by HTTP providers
+type httpEmbedder struct {
+	name    string
+	url     string
+	model   string
+	headers map[string]string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// newHTTPEmbedder reads "base_url", "model", "timeout" (default 30s), "retries" (default 2) and "backoff" (default 1s)
+func newHTTPEmbedder(provider string, params map[string]interface{}, defaultURL, path, defaultModel string) (*httpEmbedder, error) {
+	baseURL, err := config.String(params, "base_url", defaultURL)
+	if err != nil {
+		return nil, err
+	}
+	model, err := config.String(params, "model", defaultModel)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := config.Duration(params, "timeout", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	retries, err := config.Int(params, "retries", 2)
+	if err != nil {
+		return nil, err
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("retries must not be negative, got %d", retries)
+	}
+	backoff, err := config.Duration(params, "backoff", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &httpEmbedder{
+		name:    provider + "/" + model,
+		url:     strings.TrimSuffix(baseURL, "/") + path,
+		model:   model,
+		headers: map[string]string{},
+		client:  &http.Client{Timeout: timeout},
+		retries: retries,
+		backoff: backoff,
+	}, nil
+}
+
+func (h *httpEmbedder) Name() string {
+	return h.name
+}
+
+// call POSTs a JSON request and decodes the JSON response, retrying on network errors, 429 and 5xx
+func (h *httpEmbedder) call(ctx context.Context, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	backoff := h.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := h.post(ctx, body, response)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= h.retries {
+			return fmt.Errorf("after %d attempts: %w", attempt+1, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one request; retry reports whether a failure is worth retrying
+func (h *httpEmbedder) post(ctx context.Context, body []byte, response interface{}) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return false, fmt.Errorf("invalid response: %w", err)
+	}
+	return false, nil
+}
+
+// openAIProvider calls an OpenAI-compatible /embeddings endpoint (OpenAI, Azure proxies, vLLM, LM Studio, ...).
+// The API key comes from "api_key" or the OPENAI_API_KEY environment variable.
+type openAIProvider struct {
+	*httpEmbedder
+}
+
+func newOpenAIProvider(params map[string]interface{}) (EmbeddingProvider, error) {
+	h, err := newHTTPEmbedder("openai", params, "https://api.openai.com/v1", "/embeddings", "text-embedding-3-small")
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := config.String(params, "api_key", os.Getenv("OPENAI_API_KEY"))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		h.headers["Authorization"] = "Bearer " + apiKey
+	}
+	return &openAIProvider{h}, nil
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	request := map[string]interface{}{"model": p.model, "input": texts}
+	if err := p.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+
+	// The API does not promise to return embeddings in input order
+	embeddings := make([][]float64, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("response has an out of range index %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("response is missing the embedding of input %d", i)
+		}
+	}
+	return embeddings, nil
+}
+
+// ollamaProvider calls a local Ollama server's /api/embed endpoint
+type ollamaProvider struct {
+	*httpEmbedder
+}
+
+func newOllamaProvider(params map[string]interface{}) (EmbeddingProvider, error) {
+	h, err := newHTTPEmbedder("ollama", params, "http://localhost:11434", "/api/embed", "nomic-embed-text")
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaProvider{h}, nil
+}
+
+func (p *ollamaProvider) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var response struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	request := map[string]interface{}{"model": p.model, "input": texts}
+	if err := p.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	return response.Embeddings, nil
+}
+
+// onnxProvider runs an ONNX sentence-transformer model on an inference server speaking the Open Inference
+// Protocol (v2), such as Triton or an ONNX Runtime server: the model (with its tokenizer, e.g. a Triton
+// ensemble) takes the texts as the BYTES tensor "input" and returns their embeddings as the [texts,
+// dimensions] tensor "output". The runtime stays out of process, so bitscout needs no native libraries.
+type onnxProvider struct {
+	*httpEmbedder
+	input  string
+	output string
+}
+
+func newONNXProvider(params map[string]interface{}) (EmbeddingProvider, error) {
+	h, err := newHTTPEmbedder("onnx", params, "http://localhost:8000", "", "all-MiniLM-L6-v2")
+	if err != nil {
+		return nil, err
+	}
+	h.url += "/v2/models/" + url.PathEscape(h.model) + "/infer"
+	input, err := config.String(params, "input", "text")
+	if err != nil {
+		return nil, err
+	}
+	output, err := config.String(params, "output", "embedding")
+	if err != nil {
+		return nil, err
+	}
+	return &onnxProvider{httpEmbedder: h, input: input, output: output}, nil
+}
+
+func (p *onnxProvider) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	type tensor struct {
+		Name     string        `json:"name"`
+		Shape    []int         `json:"shape,omitempty"`
+		Datatype string        `json:"datatype,omitempty"`
+		Data     []interface{} `json:"data,omitempty"`
+	}
+	data := make([]interface{}, len(texts))
+	for i, text := range texts {
+		data[i] = text
+	}
+	request := map[string]interface{}{
+		"inputs":  []tensor{{Name: p.input, Shape: []int{len(texts)}, Datatype: "BYTES", Data: data}},
+		"outputs": []tensor{{Name: p.output}},
+	}
+	var response struct {
+		Outputs []struct {
+			Name  string    `json:"name"`
+			Shape []int     `json:"shape"`
+			Data  []float64 `json:"data"` // Row-major
+		} `json:"outputs"`
+	}
+	if err := p.call(ctx, request, &response); err != nil {
+		return nil, err
+	}
+	for _, output := range response.Outputs {
+		if output.Name != p.output {
+			continue
+		}
+		if len(output.Shape) != 2 || output.Shape[0] != len(texts) || len(output.Data) != output.Shape[0]*output.Shape[1] {
+			return nil, fmt.Errorf("output %s has shape %v and %d values, want [%d, dimensions]", p.output, output.Shape, len(output.Data), len(texts))
+		}
+		dimensions := output.Shape[1]
+		embeddings := make([][]float64, len(texts))
+		for i := range embeddings {
+			embeddings[i] = output.Data[i*dimensions : (i+1)*dimensions]
+		}
+		return embeddings, nil
+	}
+	return nil, fmt.Errorf("response has no output %s", p.output)
+}
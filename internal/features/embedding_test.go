@@ -0,0 +1,180 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// embeddingServer fakes an OpenAI-compatible endpoint embedding each text as [len(text), 1];
+// the first failures requests return 503
+func embeddingServer(t *testing.T, failures int32) (*httptest.Server, *int32, *[][]string) {
+	var calls int32
+	var inputs [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "test-model", request.Model)
+		inputs = append(inputs, request.Input)
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		var data []item
+		for i := len(request.Input) - 1; i >= 0; i-- { // Out of order on purpose
+			data = append(data, item{Index: i, Embedding: []float64{float64(len(request.Input[i])), 1}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls, &inputs
+}
+
+func newTestEmbeddingExtractor(t *testing.T, url string, params map[string]interface{}) *EmbeddingExtractor {
+	extractor := NewEmbeddingExtractor()
+	config := NewConfigBuilder().Parameters(map[string]interface{}{
+		"base_url": url + "/v1",
+		"model":    "test-model",
+		"api_key":  "secret",
+		"backoff":  "1ms",
+	}).Parameters(params).Build()
+	assert.NoError(t, extractor.Configure(config))
+	return extractor
+}
+
+func TestEmbeddingExtractor_Extract(t *testing.T) {
+	server, _, _ := embeddingServer(t, 0)
+	extractor := newTestEmbeddingExtractor(t, server.URL, nil)
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{5, 1}, featureSet.Features["embedding"].Value)
+	assert.Equal(t, "vector", featureSet.Features["embedding"].Type)
+	assert.Equal(t, 2, featureSet.Features["embedding_dimensions"].Value)
+	assert.Equal(t, []float64{5, 1}, featureSet.Vector)
+}
+
+func TestEmbeddingExtractor_BatchesAndCaches(t *testing.T) {
+	server, calls, inputs := embeddingServer(t, 0)
+	extractor := newTestEmbeddingExtractor(t, server.URL, map[string]interface{}{"batch_size": 2})
+	docs := []models.Document{{ID: "1", Text: "a"}, {ID: "2", Text: "bb"}, {ID: "3", Text: "a"}, {ID: "4", Text: "dddd"}, {ID: "5", Text: "eeeee"}}
+
+	featureSets, err := extractor.ExtractBatch(docs)
+	assert.NoError(t, err)
+	assert.Len(t, featureSets, 5)
+	for i, featureSet := range featureSets {
+		assert.Equal(t, docs[i].ID, featureSet.DocumentID)
+		assert.Equal(t, float64(len(docs[i].Text)), featureSet.Vector[0])
+	}
+	// Batches of two; "a" is embedded once and reused from the cache for document 3
+	assert.Equal(t, [][]string{{"a", "bb"}, {"dddd"}, {"eeeee"}}, *inputs)
+	assert.Equal(t, 4, extractor.cache.len())
+
+	// Re-indexing unchanged documents does not call the provider again
+	before := atomic.LoadInt32(calls)
+	_, err = extractor.ExtractBatch(docs)
+	assert.NoError(t, err)
+	assert.Equal(t, before, atomic.LoadInt32(calls))
+}
+
+func TestEmbeddingExtractor_Retries(t *testing.T) {
+	server, calls, _ := embeddingServer(t, 2)
+	extractor := newTestEmbeddingExtractor(t, server.URL, map[string]interface{}{"retries": 2})
+	_, err := extractor.Extract(models.Document{Text: "retry me"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	server, _, _ = embeddingServer(t, 5)
+	extractor = newTestEmbeddingExtractor(t, server.URL, map[string]interface{}{"retries": 1})
+	_, err = extractor.Extract(models.Document{Text: "give up"})
+	assert.ErrorContains(t, err, "after 2 attempts")
+}
+
+func TestEmbeddingExtractor_ChecksDimensions(t *testing.T) {
+	server, _, _ := embeddingServer(t, 0)
+	extractor := newTestEmbeddingExtractor(t, server.URL, map[string]interface{}{"dimensions": 3})
+	_, err := extractor.Extract(models.Document{Text: "hello"})
+	assert.ErrorContains(t, err, "expected 3")
+}
+
+func TestEmbeddingExtractor_Ollama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "nomic-embed-text", request.Model)
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float64{{0.1, 0.2, 0.3}}})
+	}))
+	defer server.Close()
+
+	extractor := NewEmbeddingExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("provider", "ollama").Parameter("base_url", server.URL).Build()))
+	featureSet, err := extractor.Extract(models.Document{Text: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.1, 0.2, 0.3}, featureSet.Vector)
+	assert.Equal(t, "ollama/nomic-embed-text", extractor.provider.Name())
+}
+
+func TestEmbeddingExtractor_ONNX(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/models/minilm/infer", r.URL.Path)
+		var request struct {
+			Inputs []struct {
+				Name     string   `json:"name"`
+				Shape    []int    `json:"shape"`
+				Datatype string   `json:"datatype"`
+				Data     []string `json:"data"`
+			} `json:"inputs"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "text", request.Inputs[0].Name)
+		assert.Equal(t, "BYTES", request.Inputs[0].Datatype)
+		assert.Equal(t, []int{2}, request.Inputs[0].Shape)
+		assert.Equal(t, []string{"hello", "world"}, request.Inputs[0].Data)
+		json.NewEncoder(w).Encode(map[string]interface{}{"outputs": []map[string]interface{}{
+			{"name": "token_count", "shape": []int{2}, "data": []float64{1, 1}},
+			{"name": "embedding", "shape": []int{2, 3}, "datatype": "FP32", "data": []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}},
+		}})
+	}))
+	defer server.Close()
+
+	provider, err := NewEmbeddingProvider("onnx", map[string]interface{}{"base_url": server.URL, "model": "minilm"})
+	assert.NoError(t, err)
+	assert.Equal(t, "onnx/minilm", provider.Name())
+	embeddings, err := provider.Embed(context.Background(), []string{"hello", "world"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}, embeddings)
+
+	// Outputs that are missing or of another shape
+	for _, output := range []string{"pooled", "token_count"} {
+		provider, err = NewEmbeddingProvider("onnx", map[string]interface{}{"base_url": server.URL, "model": "minilm", "output": output})
+		assert.NoError(t, err)
+		_, err = provider.Embed(context.Background(), []string{"hello", "world"})
+		assert.Error(t, err, output)
+	}
+}
+
+func TestNewEmbeddingProvider_UnknownType(t *testing.T) {
+	_, err := NewEmbeddingProvider("bert", nil)
+	assert.ErrorContains(t, err, "known providers: [ollama onnx openai]")
+}
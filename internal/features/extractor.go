@@ -2,6 +2,7 @@ package features
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
@@ -18,6 +19,7 @@ type Feature struct {
 // FeatureSet represents a collection of features extracted from a document
 type FeatureSet struct {
 	DocumentID string             // ID of the document these features belong to
+	Extractor  string             // Name of the extractor that produced the set (set by FeatureRegistry)
 	Features   map[string]Feature // Map of feature name to feature
 	Vector     []float64          // Optional vector representation of all features
 }
@@ -56,10 +58,19 @@ type FeatureExtractor interface {
 	Validate() error
 }
 
-// FeatureRegistry manages multiple feature extractors
+// FeatureRegistry manages multiple feature extractors. It is safe for concurrent use: extraction
+// waits for reconfigurations (e.g. a config reload) to finish and vice versa.
 type FeatureRegistry struct {
+	mu         sync.RWMutex
 	extractors map[string]FeatureExtractor
 	configs    map[string]ExtractorConfig
+	merge      map[string]MergeOptions
+	order      []string // Registration order
+
+	normalizers map[string]*Normalizer // Corpus statistics of extractors with a normalization
+	widthsMu    sync.Mutex
+	widths      map[string]int // Vector width each extractor last merged, for NormalizeQuery
+	cache       *FeatureCache  // Results of extractors merged with MergeOptions.Cache
 }
 
 // NewFeatureRegistry creates a new feature registry
@@ -67,23 +78,53 @@ func NewFeatureRegistry() *FeatureRegistry {
 	return &FeatureRegistry{
 		extractors: make(map[string]FeatureExtractor),
 		configs:    make(map[string]ExtractorConfig),
+		merge:      make(map[string]MergeOptions),
+
+		normalizers: make(map[string]*Normalizer),
+		widths:      make(map[string]int),
+	}
+}
+
+// NewBuiltinExtractors creates one instance of every built-in extractor
+func NewBuiltinExtractors() []FeatureExtractor {
+	return []FeatureExtractor{
+		NewFilesystemExtractor(),
+		NewContentExtractor(),
+		NewMimeExtractor(),
+		NewHashExtractor(),
+		NewKeywordExtractor(),
+	}
+}
+
+// RegisterBuiltins registers every built-in extractor
+func (r *FeatureRegistry) RegisterBuiltins() error {
+	for _, extractor := range NewBuiltinExtractors() {
+		if err := r.Register(extractor); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Register adds a feature extractor to the registry
 func (r *FeatureRegistry) Register(extractor FeatureExtractor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	name := extractor.Name()
 	if _, exists := r.extractors[name]; exists {
 		return fmt.Errorf("extractor %s already registered", name)
 	}
 
 	r.extractors[name] = extractor
+	r.order = append(r.order, name)
 	log.Info().Msgf("Registered feature extractor: %s", name)
 	return nil
 }
 
 // Configure sets configuration for a specific extractor
 func (r *FeatureRegistry) Configure(extractorName string, config ExtractorConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	extractor, exists := r.extractors[extractorName]
 	if !exists {
 		return fmt.Errorf("extractor %s not found", extractorName)
@@ -100,6 +141,8 @@ func (r *FeatureRegistry) Configure(extractorName string, config ExtractorConfig
 
 // ExtractAll extracts features from a document using all enabled extractors
 func (r *FeatureRegistry) ExtractAll(doc models.Document) ([]*FeatureSet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	var results []*FeatureSet
 
 	for name, extractor := range r.extractors {
@@ -114,6 +157,7 @@ func (r *FeatureRegistry) ExtractAll(doc models.Document) ([]*FeatureSet, error)
 			continue
 		}
 
+		featureSet.Extractor = name
 		results = append(results, featureSet)
 	}
 
@@ -122,6 +166,8 @@ func (r *FeatureRegistry) ExtractAll(doc models.Document) ([]*FeatureSet, error)
 
 // ExtractAllBatch extracts features from multiple documents using all enabled extractors
 func (r *FeatureRegistry) ExtractAllBatch(docs []models.Document) ([][]*FeatureSet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	var results [][]*FeatureSet
 
 	for name, extractor := range r.extractors {
@@ -136,6 +182,9 @@ func (r *FeatureRegistry) ExtractAllBatch(docs []models.Document) ([][]*FeatureS
 			continue
 		}
 
+		for _, featureSet := range featureSets {
+			featureSet.Extractor = name
+		}
 		results = append(results, featureSets)
 	}
 
@@ -144,21 +193,23 @@ func (r *FeatureRegistry) ExtractAllBatch(docs []models.Document) ([][]*FeatureS
 
 // GetExtractor returns a specific extractor by name
 func (r *FeatureRegistry) GetExtractor(name string) (FeatureExtractor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	extractor, exists := r.extractors[name]
 	return extractor, exists
 }
 
-// ListExtractors returns all registered extractor names
+// ListExtractors returns all registered extractor names, in registration order
 func (r *FeatureRegistry) ListExtractors() []string {
-	var names []string
-	for name := range r.extractors {
-		names = append(names, name)
-	}
-	return names
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.order...)
 }
 
 // GetEnabledExtractors returns names of all enabled extractors
 func (r *FeatureRegistry) GetEnabledExtractors() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	var names []string
 	for name, config := range r.configs {
 		if config.Enabled {
@@ -0,0 +1,62 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ExtractorConstructor creates a feature extractor with its default configuration
+type ExtractorConstructor func() FeatureExtractor
+
+// ExtractorFactory instantiates feature extractors by name
+type ExtractorFactory struct {
+	constructors map[string]ExtractorConstructor
+}
+
+// NewExtractorFactory creates a factory with every extractor of this package registered
+func NewExtractorFactory() *ExtractorFactory {
+	f := &ExtractorFactory{constructors: make(map[string]ExtractorConstructor)}
+	f.RegisterType("filesystem", func() FeatureExtractor { return NewFilesystemExtractor() })
+	f.RegisterType("content", func() FeatureExtractor { return NewContentExtractor() })
+	f.RegisterType("mime", func() FeatureExtractor { return NewMimeExtractor() })
+	f.RegisterType("hash", func() FeatureExtractor { return NewHashExtractor() })
+	f.RegisterType("keywords", func() FeatureExtractor { return NewKeywordExtractor() })
+	f.RegisterType("media", func() FeatureExtractor { return NewMediaExtractor() })
+	f.RegisterType("tfidf", func() FeatureExtractor { return NewTFIDFExtractor() })
+	f.RegisterType("embedding", func() FeatureExtractor { return NewEmbeddingExtractor() })
+	return f
+}
+
+// RegisterType adds (or replaces) the constructor for an extractor type
+func (f *ExtractorFactory) RegisterType(typeName string, constructor ExtractorConstructor) {
+	f.constructors[typeName] = constructor
+}
+
+// Has reports whether an extractor type is registered
+func (f *ExtractorFactory) Has(typeName string) bool {
+	_, ok := f.constructors[typeName]
+	return ok
+}
+
+// Types returns the registered extractor type names, sorted
+func (f *ExtractorFactory) Types() []string {
+	types := make([]string, 0, len(f.constructors))
+	for typeName := range f.constructors {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Create instantiates an extractor of the given type and configures it
+func (f *ExtractorFactory) Create(typeName string, cfg ExtractorConfig) (FeatureExtractor, error) {
+	constructor, ok := f.constructors[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown feature extractor type %s (known types: %v)", typeName, f.Types())
+	}
+	extractor := constructor()
+	if err := extractor.Configure(cfg); err != nil {
+		return nil, fmt.Errorf("failed to configure %s extractor: %w", typeName, err)
+	}
+	return extractor, nil
+}
@@ -7,13 +7,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aawadall/bit-scout/internal/config"
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
 )
 
-// FilesystemExtractor extracts filesystem-related features from documents
+// FilesystemExtractor extracts filesystem-related features from documents.
+//
+// File name, extension and size features are always produced. The other groups can be turned off with
+// the boolean parameters "include_path_features" (path, directory, path_depth),
+// "include_timestamp_features" (modified_*), "include_permission_features" (file mode, is_*) and
+// "include_content_features" (content_length, line_count, word_count); each defaults to true.
 type FilesystemExtractor struct {
-	config ExtractorConfig
+	config             ExtractorConfig
+	includePath        bool
+	includeTimestamps  bool
+	includePermissions bool
+	includeContent     bool
 }
 
 // NewFilesystemExtractor creates a new filesystem feature extractor
@@ -27,6 +37,10 @@ func NewFilesystemExtractor() *FilesystemExtractor {
 			Normalize:  true,
 			Vectorize:  true,
 		},
+		includePath:        true,
+		includeTimestamps:  true,
+		includePermissions: true,
+		includeContent:     true,
 	}
 }
 
@@ -36,9 +50,23 @@ func (e *FilesystemExtractor) Name() string {
 }
 
 // Configure sets the configuration for this extractor
-func (e *FilesystemExtractor) Configure(config ExtractorConfig) error {
-	e.config = config
-	log.Debug().Msgf("FilesystemExtractor configured with enabled=%v, weight=%f", config.Enabled, config.Weight)
+func (e *FilesystemExtractor) Configure(cfg ExtractorConfig) error {
+	includes := make(map[string]bool, 4)
+	for _, group := range []string{"path", "timestamp", "permission", "content"} {
+		key := "include_" + group + "_features"
+		include, err := config.Bool(cfg.Parameters, key, true)
+		if err != nil {
+			return err
+		}
+		includes[group] = include
+	}
+
+	e.config = cfg
+	e.includePath = includes["path"]
+	e.includeTimestamps = includes["timestamp"]
+	e.includePermissions = includes["permission"]
+	e.includeContent = includes["content"]
+	log.Debug().Msgf("FilesystemExtractor configured with enabled=%v, weight=%f, groups=%v", cfg.Enabled, cfg.Weight, includes)
 	return nil
 }
 
@@ -85,13 +113,6 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 				return filepath.Ext(info.Name())
 			},
 		},
-		{
-			Name: "path",
-			Type: "string",
-			Value: func() string {
-				return doc.Source
-			},
-		},
 	}
 
 	// Extract features using descriptors
@@ -103,12 +124,6 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 			Weight: e.config.Weight,
 		}
 	}
-	features["directory"] = Feature{
-		Name:   "directory",
-		Value:  filepath.Dir(doc.Source),
-		Type:   "string",
-		Weight: e.config.Weight,
-	}
 
 	// Extract file size features
 	fileSize := info.Size()
@@ -133,7 +148,76 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 		Weight: e.config.Weight,
 	}
 
-	// Extract timestamp features
+	if e.includePath {
+		e.addPathFeatures(features, doc)
+	}
+	if e.includeTimestamps {
+		e.addTimestampFeatures(features, info)
+	}
+	if e.includePermissions {
+		e.addModeFeatures(features, info)
+	}
+	if e.includeContent {
+		e.addContentFeatures(features, doc)
+	}
+
+	// Apply feature mapping if configured
+	if len(e.config.FeatureMap) > 0 {
+		mappedFeatures := make(map[string]Feature)
+		for name, feature := range features {
+			if mappedName, exists := e.config.FeatureMap[name]; exists {
+				feature.Name = mappedName
+				mappedFeatures[mappedName] = feature
+			} else {
+				mappedFeatures[name] = feature
+			}
+		}
+		features = mappedFeatures
+	}
+
+	// Generate vector representation if requested
+	var vector []float64
+	if e.config.Vectorize {
+		vector = e.generateVector(features)
+	}
+
+	featureSet := &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}
+
+	log.Debug().Msgf("Extracted %d filesystem features from document %s", len(features), doc.ID)
+	return featureSet, nil
+}
+
+// addPathFeatures adds the location of the file
+func (e *FilesystemExtractor) addPathFeatures(features map[string]Feature, doc models.Document) {
+	features["path"] = Feature{
+		Name:   "path",
+		Value:  doc.Source,
+		Type:   "string",
+		Weight: e.config.Weight,
+	}
+
+	features["directory"] = Feature{
+		Name:   "directory",
+		Value:  filepath.Dir(doc.Source),
+		Type:   "string",
+		Weight: e.config.Weight,
+	}
+
+	pathDepth := strings.Count(filepath.Clean(doc.Source), string(filepath.Separator)) + 1
+	features["path_depth"] = Feature{
+		Name:   "path_depth",
+		Value:  pathDepth,
+		Type:   "number",
+		Weight: e.config.Weight,
+	}
+}
+
+// addTimestampFeatures adds the modification time of the file
+func (e *FilesystemExtractor) addTimestampFeatures(features map[string]Feature, info os.FileInfo) {
 	modTime := info.ModTime()
 	features["modified_time"] = Feature{
 		Name:   "modified_time",
@@ -169,8 +253,10 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 		Type:   "number",
 		Weight: e.config.Weight,
 	}
+}
 
-	// Extract file mode features
+// addModeFeatures adds the file type and permission bits of the file
+func (e *FilesystemExtractor) addModeFeatures(features map[string]Feature, info os.FileInfo) {
 	mode := info.Mode()
 	features["is_directory"] = Feature{
 		Name:   "is_directory",
@@ -234,8 +320,10 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 		Type:   "boolean",
 		Weight: e.config.Weight,
 	}
+}
 
-	// Extract content-based features
+// addContentFeatures adds statistics of the document text
+func (e *FilesystemExtractor) addContentFeatures(features map[string]Feature, doc models.Document) {
 	contentLength := len(doc.Text)
 	features["content_length"] = Feature{
 		Name:   "content_length",
@@ -257,44 +345,6 @@ func (e *FilesystemExtractor) Extract(doc models.Document) (*FeatureSet, error)
 		Type:   "number",
 		Weight: e.config.Weight,
 	}
-
-	// Extract path depth
-	pathDepth := strings.Count(filepath.Clean(doc.Source), string(filepath.Separator)) + 1
-	features["path_depth"] = Feature{
-		Name:   "path_depth",
-		Value:  pathDepth,
-		Type:   "number",
-		Weight: e.config.Weight,
-	}
-
-	// Apply feature mapping if configured
-	if len(e.config.FeatureMap) > 0 {
-		mappedFeatures := make(map[string]Feature)
-		for name, feature := range features {
-			if mappedName, exists := e.config.FeatureMap[name]; exists {
-				feature.Name = mappedName
-				mappedFeatures[mappedName] = feature
-			} else {
-				mappedFeatures[name] = feature
-			}
-		}
-		features = mappedFeatures
-	}
-
-	// Generate vector representation if requested
-	var vector []float64
-	if e.config.Vectorize {
-		vector = e.generateVector(features)
-	}
-
-	featureSet := &FeatureSet{
-		DocumentID: doc.ID,
-		Features:   features,
-		Vector:     vector,
-	}
-
-	log.Debug().Msgf("Extracted %d filesystem features from document %s", len(features), doc.ID)
-	return featureSet, nil
 }
 
 // ExtractBatch extracts filesystem features from multiple documents
@@ -316,16 +366,24 @@ func (e *FilesystemExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSe
 
 // GetSupportedFeatures returns a list of feature names this extractor can produce
 func (e *FilesystemExtractor) GetSupportedFeatures() []string {
-	return []string{
-		"filename", "extension", "path", "directory",
-		"file_size", "file_size_kb", "file_size_mb",
-		"modified_time", "modified_unix", "modified_year", "modified_month", "modified_day",
-		"is_directory", "is_regular_file", "is_symlink",
-		"is_executable", "is_writable", "is_readable",
-		"is_hidden", "is_system", "is_archive",
-		"content_length", "line_count", "word_count",
-		"path_depth",
+	supported := []string{"filename", "extension", "file_size", "file_size_kb", "file_size_mb"}
+	if e.includePath {
+		supported = append(supported, "path", "directory", "path_depth")
+	}
+	if e.includeTimestamps {
+		supported = append(supported, "modified_time", "modified_unix", "modified_year", "modified_month", "modified_day")
+	}
+	if e.includePermissions {
+		supported = append(supported,
+			"is_directory", "is_regular_file", "is_symlink",
+			"is_executable", "is_writable", "is_readable",
+			"is_hidden", "is_system", "is_archive",
+		)
+	}
+	if e.includeContent {
+		supported = append(supported, "content_length", "line_count", "word_count")
 	}
+	return supported
 }
 
 // Validate checks if the extractor is properly configured
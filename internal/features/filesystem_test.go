@@ -0,0 +1,77 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func extractFilesystem(t *testing.T, cfg ExtractorConfig) (*FilesystemExtractor, *FeatureSet) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("two words\nand more"), 0644))
+
+	extractor := NewFilesystemExtractor()
+	assert.NoError(t, extractor.Configure(cfg))
+	featureSet, err := extractor.Extract(models.Document{ID: "a", Source: path, Text: "two words\nand more"})
+	assert.NoError(t, err)
+	return extractor, featureSet
+}
+
+func featureNames(featureSet *FeatureSet) []string {
+	names := make([]string, 0, len(featureSet.Features))
+	for name := range featureSet.Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFilesystemExtractor_Presets(t *testing.T) {
+	presets := NewPresetConfigs()
+	basic := []string{"extension", "file_size", "file_size_kb", "file_size_mb", "filename"}
+
+	extractor, featureSet := extractFilesystem(t, presets.Minimal())
+	assert.Equal(t, basic, featureNames(featureSet))
+	assert.ElementsMatch(t, basic, extractor.GetSupportedFeatures())
+	assert.Len(t, featureSet.Vector, 3, "only the size features are vectorized")
+	assert.Equal(t, 0.5*18, featureSet.Vector[0], "weighted file size")
+
+	extractor, featureSet = extractFilesystem(t, presets.Standard())
+	assert.Contains(t, featureSet.Features, "path_depth")
+	assert.Contains(t, featureSet.Features, "modified_unix")
+	assert.Equal(t, 4, featureSet.Features["word_count"].Value)
+	assert.NotContains(t, featureSet.Features, "is_readable")
+	assert.ElementsMatch(t, featureNames(featureSet), extractor.GetSupportedFeatures())
+	assert.Len(t, featureSet.Vector, 11)
+
+	extractor, featureSet = extractFilesystem(t, presets.Comprehensive())
+	assert.Len(t, featureSet.Features, 25)
+	assert.Equal(t, true, featureSet.Features["is_readable"].Value)
+	assert.ElementsMatch(t, featureNames(featureSet), extractor.GetSupportedFeatures())
+	assert.Len(t, featureSet.Vector, 20)
+}
+
+func TestFilesystemExtractor_Groups(t *testing.T) {
+	// Groups default to on, so an empty configuration extracts everything
+	_, featureSet := extractFilesystem(t, NewConfigBuilder().Build())
+	assert.Len(t, featureSet.Features, 25)
+
+	_, featureSet = extractFilesystem(t, NewConfigBuilder().Parameter("include_path_features", "false").Build())
+	assert.NotContains(t, featureSet.Features, "path")
+	assert.NotContains(t, featureSet.Features, "directory")
+	assert.NotContains(t, featureSet.Features, "path_depth")
+	assert.Contains(t, featureSet.Features, "modified_time")
+
+	_, featureSet = extractFilesystem(t, NewConfigBuilder().Parameter("include_timestamp_features", false).Build())
+	assert.NotContains(t, featureSet.Features, "modified_time")
+	assert.Contains(t, featureSet.Features, "is_hidden")
+
+	_, featureSet = extractFilesystem(t, NewConfigBuilder().Parameter("include_content_features", false).Build())
+	assert.NotContains(t, featureSet.Features, "line_count")
+
+	assert.Error(t, NewFilesystemExtractor().Configure(NewConfigBuilder().Parameter("include_path_features", "sometimes").Build()))
+}
@@ -0,0 +1,278 @@
+package features
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultShingleSize is the number of consecutive words hashed together by SimHash
+const DefaultShingleSize = 3
+
+// hashAlgorithms holds the digests HashExtractor can emit, keyed by feature name
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// HashExtractor emits content digests for integrity checks and exact-duplicate detection, and a 64-bit
+// SimHash fingerprint for near-duplicate and change detection.
+//
+// Parameters: "algorithms" (default ["md5", "sha256"]), "source" ("text" hashes Document.Text, "file"
+// hashes the bytes of the file at Document.Source and falls back to the text) and "shingle_size".
+type HashExtractor struct {
+	config      ExtractorConfig
+	algorithms  []string
+	source      string
+	shingleSize int
+}
+
+// NewHashExtractor creates a new hash feature extractor
+func NewHashExtractor() *HashExtractor {
+	return &HashExtractor{
+		config: ExtractorConfig{
+			Enabled:    true,
+			Weight:     1.0,
+			Parameters: make(map[string]interface{}),
+			FeatureMap: make(map[string]string),
+			Normalize:  true,
+			Vectorize:  true,
+		},
+		algorithms:  []string{"md5", "sha256"},
+		source:      "text",
+		shingleSize: DefaultShingleSize,
+	}
+}
+
+// Name returns the name of this extractor
+func (e *HashExtractor) Name() string {
+	return "hash"
+}
+
+// Configure sets the configuration for this extractor
+func (e *HashExtractor) Configure(cfg ExtractorConfig) error {
+	algorithms, err := config.Strings(cfg.Parameters, "algorithms")
+	if err != nil {
+		return err
+	}
+	if len(algorithms) == 0 {
+		algorithms = []string{"md5", "sha256"}
+	}
+	for _, algorithm := range algorithms {
+		if _, ok := hashAlgorithms[algorithm]; !ok {
+			return fmt.Errorf("unknown hash algorithm %s (known algorithms: md5, sha1, sha256, sha512)", algorithm)
+		}
+	}
+	source, err := config.String(cfg.Parameters, "source", "text")
+	if err != nil {
+		return err
+	}
+	if source != "text" && source != "file" {
+		return fmt.Errorf("source must be text or file, got %s", source)
+	}
+	shingleSize, err := config.Int(cfg.Parameters, "shingle_size", DefaultShingleSize)
+	if err != nil {
+		return err
+	}
+	if shingleSize <= 0 {
+		return fmt.Errorf("shingle_size must be positive, got %d", shingleSize)
+	}
+
+	e.config = cfg
+	e.algorithms = algorithms
+	e.source = source
+	e.shingleSize = shingleSize
+	log.Debug().Msgf("HashExtractor configured with enabled=%v, weight=%f, algorithms=%v", cfg.Enabled, cfg.Weight, algorithms)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *HashExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract computes the digests and SimHash of a single document
+func (e *HashExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	digests, err := e.digests(doc)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := SimHash(doc.Text, e.shingleSize)
+
+	values := make(map[string]Feature, len(digests)+1)
+	for algorithm, digest := range digests {
+		values[algorithm] = Feature{Value: digest, Type: "string"}
+	}
+	values["simhash"] = Feature{Value: FormatSimHash(fingerprint), Type: "string"}
+
+	features := make(map[string]Feature, len(values))
+	for name, feature := range values {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature.Name = name
+		feature.Weight = e.config.Weight
+		features[name] = feature
+	}
+
+	// One dimension per fingerprint bit, so near-duplicates have nearby vectors
+	var vector []float64
+	if e.config.Vectorize {
+		vector = make([]float64, 64)
+		for i := range vector {
+			if fingerprint&(1<<uint(63-i)) != 0 {
+				vector[i] = e.config.Weight
+			}
+		}
+	}
+
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// digests hashes the document content with every configured algorithm in a single pass
+func (e *HashExtractor) digests(doc models.Document) (map[string]string, error) {
+	hashes := make(map[string]hash.Hash, len(e.algorithms))
+	writers := make([]io.Writer, 0, len(e.algorithms))
+	for _, algorithm := range e.algorithms {
+		h := hashAlgorithms[algorithm]()
+		hashes[algorithm] = h
+		writers = append(writers, h)
+	}
+	w := io.MultiWriter(writers...)
+
+	hashedFile := false
+	if e.source == "file" && doc.Source != "" {
+		file, err := os.Open(doc.Source)
+		if err == nil {
+			defer file.Close()
+			if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+				if _, err := io.Copy(w, file); err != nil {
+					return nil, fmt.Errorf("failed to hash %s: %w", doc.Source, err)
+				}
+				hashedFile = true
+			}
+		}
+	}
+	if !hashedFile {
+		io.WriteString(w, doc.Text)
+	}
+
+	digests := make(map[string]string, len(hashes))
+	for algorithm, h := range hashes {
+		digests[algorithm] = hex.EncodeToString(h.Sum(nil))
+	}
+	return digests, nil
+}
+
+// SimHash computes a 64-bit fingerprint of text from its overlapping word shingles (Charikar's SimHash).
+// Similar texts get fingerprints with a small HammingDistance; empty text yields 0.
+func SimHash(text string, shingleSize int) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+	if shingleSize <= 0 {
+		shingleSize = DefaultShingleSize
+	}
+	if shingleSize > len(words) {
+		shingleSize = len(words)
+	}
+
+	var counts [64]int
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				counts[bit]++
+			} else {
+				counts[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if counts[bit] > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance returns the number of differing bits between two SimHash fingerprints
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatSimHash renders a fingerprint as the 16-digit hex string used by the "simhash" feature
+func FormatSimHash(fingerprint uint64) string {
+	return fmt.Sprintf("%016x", fingerprint)
+}
+
+// ParseSimHash parses a fingerprint produced by FormatSimHash
+func ParseSimHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// ExtractBatch computes hashes for multiple documents
+func (e *HashExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted hash features from %d documents", len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *HashExtractor) GetSupportedFeatures() []string {
+	return append(append([]string{}, e.algorithms...), "simhash")
+}
+
+// Validate checks if the extractor is properly configured
+func (e *HashExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
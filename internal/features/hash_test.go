@@ -0,0 +1,57 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashExtractor_Digests(t *testing.T) {
+	featureSet, err := NewHashExtractor().Extract(models.Document{ID: "doc-1", Text: "hello world"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", featureSet.Features["md5"].Value)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", featureSet.Features["sha256"].Value)
+	assert.Len(t, featureSet.Features["simhash"].Value, 16)
+	assert.Len(t, featureSet.Vector, 64)
+}
+
+func TestHashExtractor_FileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello world"), 0644))
+
+	extractor := NewHashExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().
+		Parameter("algorithms", []interface{}{"sha1"}).
+		Parameter("source", "file").
+		Build()))
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Source: path, Text: "extracted text"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", featureSet.Features["sha1"].Value)
+	assert.NotContains(t, featureSet.Features, "md5")
+	assert.Equal(t, []string{"sha1", "simhash"}, extractor.GetSupportedFeatures())
+}
+
+func TestHashExtractor_ConfigureRejectsUnknownAlgorithm(t *testing.T) {
+	extractor := NewHashExtractor()
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("algorithms", "crc32").Build()))
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("source", "url").Build()))
+}
+
+func TestSimHash_NearDuplicates(t *testing.T) {
+	original := "the quick brown fox jumps over the lazy dog while the cat sleeps on the warm mat by the door"
+	edited := "the quick brown fox jumps over the lazy dog while the cat sleeps on the warm rug by the door"
+	unrelated := "quarterly revenue grew eleven percent driven by strong demand for cloud storage products"
+
+	a, b, c := SimHash(original, 3), SimHash(edited, 3), SimHash(unrelated, 3)
+	assert.Equal(t, a, SimHash("The QUICK brown fox, jumps over the lazy dog; while the cat sleeps on the warm mat by the door!", 3))
+	assert.Less(t, HammingDistance(a, b), HammingDistance(a, c))
+	assert.Equal(t, uint64(0), SimHash("", 3))
+
+	parsed, err := ParseSimHash(FormatSimHash(a))
+	assert.NoError(t, err)
+	assert.Equal(t, a, parsed)
+}
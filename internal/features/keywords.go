@@ -0,0 +1,336 @@
+package features
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultMaxKeywords is the number of keywords kept per document
+	DefaultMaxKeywords = 10
+	// DefaultMaxPhraseWords is the longest keyword phrase, in words
+	DefaultMaxPhraseWords = 3
+)
+
+// keywordStopwords split text into candidate phrases for RAKE; they are never part of a keyword
+var keywordStopwords = []string{
+	"a", "about", "above", "after", "again", "against", "all", "also", "am", "an", "and", "any", "are",
+	"as", "at", "be", "because", "been", "before", "being", "below", "between", "both", "but", "by",
+	"can", "could", "did", "do", "does", "doing", "down", "during", "each", "few", "for", "from",
+	"further", "had", "has", "have", "having", "he", "her", "here", "hers", "him", "his", "how", "i",
+	"if", "in", "into", "is", "it", "its", "itself", "just", "me", "more", "most", "my", "no", "nor",
+	"not", "now", "of", "off", "on", "once", "only", "or", "other", "our", "ours", "out", "over", "own",
+	"same", "she", "should", "so", "some", "such", "than", "that", "the", "their", "them", "then",
+	"there", "these", "they", "this", "those", "through", "to", "too", "under", "until", "up", "very",
+	"was", "we", "were", "what", "when", "where", "which", "while", "who", "whom", "why", "will",
+	"with", "would", "you", "your", "yours",
+}
+
+// entityPatterns are the named entities detected by KeywordExtractor, in feature order
+var entityPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)},
+	{"url", regexp.MustCompile(`\b(?:https?|ftp)://[^\s<>"'()\[\]]+`)},
+	{"ip", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{1,4}\b`)},
+	{"date", regexp.MustCompile(`(?i)\b\d{4}-\d{2}-\d{2}\b|\b\d{1,2}/\d{1,2}/\d{2,4}\b|` +
+		`\b(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.? \d{1,2}(?:st|nd|rd|th)?,? \d{4}\b|` +
+		`\b\d{1,2}(?:st|nd|rd|th)? (?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.? \d{4}\b`)},
+}
+
+// KeywordExtractor produces the top keywords of a document (RAKE) and the emails, URLs, IP addresses
+// and dates it mentions.
+//
+// Parameters: "max_keywords", "max_phrase_words" and "stopwords" (list, replaces the defaults).
+type KeywordExtractor struct {
+	config         ExtractorConfig
+	maxKeywords    int
+	maxPhraseWords int
+	stopwords      map[string]struct{}
+}
+
+// NewKeywordExtractor creates a new keyword and entity feature extractor
+func NewKeywordExtractor() *KeywordExtractor {
+	return &KeywordExtractor{
+		config: ExtractorConfig{
+			Enabled:    true,
+			Weight:     1.0,
+			Parameters: make(map[string]interface{}),
+			FeatureMap: make(map[string]string),
+			Normalize:  true,
+			Vectorize:  true,
+		},
+		maxKeywords:    DefaultMaxKeywords,
+		maxPhraseWords: DefaultMaxPhraseWords,
+		stopwords:      stopwordSet(keywordStopwords),
+	}
+}
+
+// Name returns the name of this extractor
+func (e *KeywordExtractor) Name() string {
+	return "keywords"
+}
+
+// Configure sets the configuration for this extractor
+func (e *KeywordExtractor) Configure(cfg ExtractorConfig) error {
+	maxKeywords, err := config.Int(cfg.Parameters, "max_keywords", DefaultMaxKeywords)
+	if err != nil {
+		return err
+	}
+	if maxKeywords < 0 {
+		return fmt.Errorf("max_keywords must not be negative, got %d", maxKeywords)
+	}
+	maxPhraseWords, err := config.Int(cfg.Parameters, "max_phrase_words", DefaultMaxPhraseWords)
+	if err != nil {
+		return err
+	}
+	if maxPhraseWords <= 0 {
+		return fmt.Errorf("max_phrase_words must be positive, got %d", maxPhraseWords)
+	}
+	stopwords := stopwordSet(keywordStopwords)
+	if _, ok := cfg.Parameters["stopwords"]; ok {
+		words, err := config.Strings(cfg.Parameters, "stopwords")
+		if err != nil {
+			return err
+		}
+		stopwords = stopwordSet(words)
+	}
+
+	e.config = cfg
+	e.maxKeywords = maxKeywords
+	e.maxPhraseWords = maxPhraseWords
+	e.stopwords = stopwords
+	log.Debug().Msgf("KeywordExtractor configured with enabled=%v, weight=%f", cfg.Enabled, cfg.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *KeywordExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract finds the keywords and entities of a single document
+func (e *KeywordExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	text := doc.Text
+	values := map[string]Feature{}
+	var vector []float64
+	for _, entity := range entityPatterns {
+		matches := findEntities(entity.name, entity.pattern, text)
+		// Blank out entities so their parts (e.g. "com") do not become keywords
+		text = entity.pattern.ReplaceAllString(text, ".")
+
+		values[entity.name+"s"] = Feature{Value: matches, Type: "json"}
+		values["has_"+entity.name] = Feature{Value: len(matches) > 0, Type: "boolean"}
+		if len(matches) > 0 {
+			vector = append(vector, e.config.Weight)
+		} else {
+			vector = append(vector, 0.0)
+		}
+	}
+	values["keywords"] = Feature{Value: e.keywords(text), Type: "json"}
+
+	features := make(map[string]Feature, len(values))
+	for name, feature := range values {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature.Name = name
+		feature.Weight = e.config.Weight
+		features[name] = feature
+	}
+	if !e.config.Vectorize {
+		vector = nil
+	}
+
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// findEntities returns the distinct matches of an entity pattern in order of appearance
+func findEntities(name string, pattern *regexp.Regexp, text string) []string {
+	matches := []string{}
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllString(text, -1) {
+		switch name {
+		case "url":
+			match = strings.TrimRight(match, ".,;:!?")
+		case "ip":
+			if net.ParseIP(match) == nil {
+				continue
+			}
+		}
+		if !seen[match] {
+			seen[match] = true
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// keywords ranks candidate phrases with RAKE (Rose et al., 2010): phrases are runs of words between
+// stopwords and punctuation, each word scores degree/frequency, and a phrase scores the sum of its words
+func (e *KeywordExtractor) keywords(text string) []string {
+	var phrases [][]string
+	var phrase []string
+	endPhrase := func() {
+		for len(phrase) > 0 {
+			n := len(phrase)
+			if n > e.maxPhraseWords {
+				n = e.maxPhraseWords
+			}
+			phrases = append(phrases, phrase[:n])
+			phrase = phrase[n:]
+		}
+		phrase = nil
+	}
+	for _, token := range keywordTokens(text) {
+		_, stop := e.stopwords[token]
+		if token == "" || stop || isNumber(token) {
+			endPhrase()
+			continue
+		}
+		phrase = append(phrase, token)
+	}
+	endPhrase()
+
+	frequency := make(map[string]int)
+	degree := make(map[string]int)
+	for _, words := range phrases {
+		for _, word := range words {
+			frequency[word]++
+			degree[word] += len(words)
+		}
+	}
+
+	type candidate struct {
+		phrase string
+		score  float64
+		first  int
+	}
+	var candidates []candidate
+	seen := make(map[string]bool)
+	for i, words := range phrases {
+		joined := strings.Join(words, " ")
+		if seen[joined] {
+			continue
+		}
+		seen[joined] = true
+		score := 0.0
+		for _, word := range words {
+			score += float64(degree[word]) / float64(frequency[word])
+		}
+		candidates = append(candidates, candidate{joined, score, i})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].first < candidates[j].first
+	})
+
+	keywords := []string{}
+	for i := 0; i < len(candidates) && i < e.maxKeywords; i++ {
+		keywords = append(keywords, candidates[i].phrase)
+	}
+	return keywords
+}
+
+// keywordTokens lowercases the words of text; an empty token marks punctuation, which ends a phrase
+func keywordTokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if token := strings.Trim(word.String(), "'-"); token != "" {
+			tokens = append(tokens, token)
+		}
+		word.Reset()
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(unicode.ToLower(r))
+		case (r == '\'' || r == '-') && word.Len() > 0:
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens = append(tokens, "")
+		}
+	}
+	flush()
+	return tokens
+}
+
+func isNumber(token string) bool {
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func stopwordSet(words []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[strings.ToLower(word)] = struct{}{}
+	}
+	return set
+}
+
+// ExtractBatch finds keywords and entities for multiple documents
+func (e *KeywordExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted keyword features from %d documents", len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *KeywordExtractor) GetSupportedFeatures() []string {
+	return []string{
+		"keywords",
+		"emails", "has_email",
+		"urls", "has_url",
+		"ips", "has_ip",
+		"dates", "has_date",
+	}
+}
+
+// Validate checks if the extractor is properly configured
+func (e *KeywordExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordExtractor_Entities(t *testing.T) {
+	doc := models.Document{ID: "doc-1", Text: "Contact ops@example.com or see https://status.example.com/incidents. " +
+		"The server 10.0.0.12 (fe80::1:2) went down on 2024-03-15 and again on March 3rd, 2024; ops@example.com replied. " +
+		"Version 1.2.3 and 999.1.1.1 are not addresses. Meeting at 10:30:00."}
+
+	featureSet, err := NewKeywordExtractor().Extract(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ops@example.com"}, featureSet.Features["emails"].Value)
+	assert.Equal(t, []string{"https://status.example.com/incidents"}, featureSet.Features["urls"].Value)
+	assert.Equal(t, []string{"10.0.0.12", "fe80::1:2"}, featureSet.Features["ips"].Value)
+	assert.Equal(t, []string{"2024-03-15", "March 3rd, 2024"}, featureSet.Features["dates"].Value)
+	assert.Equal(t, true, featureSet.Features["has_email"].Value)
+	assert.Equal(t, []float64{1, 1, 1, 1}, featureSet.Vector)
+	assert.NotContains(t, featureSet.Features["keywords"].Value, "com")
+}
+
+func TestKeywordExtractor_RAKE(t *testing.T) {
+	text := "Compatibility of systems of linear constraints over the set of natural numbers. " +
+		"Criteria of compatibility of a system of linear Diophantine equations are considered. " +
+		"Upper bounds for components of a minimal set of solutions are given."
+
+	extractor := NewKeywordExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("max_keywords", 3).Build()))
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: text})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"linear diophantine equations", "linear constraints", "natural numbers"}, featureSet.Features["keywords"].Value)
+	assert.Equal(t, false, featureSet.Features["has_email"].Value)
+	assert.Equal(t, []string{}, featureSet.Features["emails"].Value)
+}
+
+func TestKeywordExtractor_Configure(t *testing.T) {
+	extractor := NewKeywordExtractor()
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("max_phrase_words", 0).Build()))
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().
+		Parameter("stopwords", []interface{}{"quick"}).
+		Parameter("max_phrase_words", 1).
+		Build()))
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: "the quick brown fox"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"the", "brown", "fox"}, featureSet.Features["keywords"].Value)
+}
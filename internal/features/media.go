@@ -0,0 +1,197 @@
+package features
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMediaHeaderBytes is how much of a file is read to find its tags
+const DefaultMediaHeaderBytes = 1 << 20
+
+// mediaFeatureTypes lists the features MediaExtractor can produce and their types
+var mediaFeatureTypes = map[string]string{
+	"media_type":    "string",
+	"media_format":  "string",
+	"width":         "number",
+	"height":        "number",
+	"orientation":   "number",
+	"camera_make":   "string",
+	"camera_model":  "string",
+	"taken_at":      "string",
+	"gps_latitude":  "number",
+	"gps_longitude": "number",
+	"duration":      "number",
+	"sample_rate":   "number",
+	"channels":      "number",
+	"bitrate":       "number",
+	"title":         "string",
+	"artist":        "string",
+	"album":         "string",
+	"year":          "string",
+	"genre":         "string",
+}
+
+// MediaExtractor reads image and audio metadata from the file at doc.Source: dimensions, camera and GPS
+// position from EXIF (JPEG), and duration, stream properties and ID3/Vorbis tags from MP3, FLAC and WAV.
+// Files in other formats produce no features, so it is safe to run on mixed corpora.
+//
+// Parameters: "header_bytes" (how much of the file is read for tags, default 1 MiB).
+type MediaExtractor struct {
+	config      ExtractorConfig
+	headerBytes int
+}
+
+// NewMediaExtractor creates a new media metadata feature extractor
+func NewMediaExtractor() *MediaExtractor {
+	return &MediaExtractor{
+		config: ExtractorConfig{
+			Enabled:    true,
+			Weight:     1.0,
+			Parameters: make(map[string]interface{}),
+			FeatureMap: make(map[string]string),
+			Normalize:  true,
+			Vectorize:  true,
+		},
+		headerBytes: DefaultMediaHeaderBytes,
+	}
+}
+
+// Name returns the name of this extractor
+func (e *MediaExtractor) Name() string {
+	return "media"
+}
+
+// Configure sets the configuration for this extractor
+func (e *MediaExtractor) Configure(cfg ExtractorConfig) error {
+	headerBytes, err := config.Int(cfg.Parameters, "header_bytes", DefaultMediaHeaderBytes)
+	if err != nil {
+		return err
+	}
+	if headerBytes < 64 {
+		return fmt.Errorf("header_bytes must be at least 64, got %d", headerBytes)
+	}
+	e.config = cfg
+	e.headerBytes = headerBytes
+	log.Debug().Msgf("MediaExtractor configured with enabled=%v, weight=%f", cfg.Enabled, cfg.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *MediaExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract reads the media metadata of a single document
+func (e *MediaExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	values, err := e.read(doc.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make(map[string]Feature, len(values))
+	for name, value := range values {
+		feature := Feature{Value: value, Type: mediaFeatureTypes[name], Weight: e.config.Weight}
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature.Name = name
+		features[name] = feature
+	}
+
+	// Fixed layout: width, height, duration, has GPS position
+	var vector []float64
+	if e.config.Vectorize {
+		vector = make([]float64, 4)
+		for i, name := range []string{"width", "height", "duration"} {
+			if value, ok := values[name].(float64); ok {
+				vector[i] = value * e.config.Weight
+			} else if value, ok := values[name].(int); ok {
+				vector[i] = float64(value) * e.config.Weight
+			}
+		}
+		if _, ok := values["gps_latitude"]; ok {
+			vector[3] = e.config.Weight
+		}
+	}
+
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// read returns the metadata of a media file; files that are missing or not media yield no values
+func (e *MediaExtractor) read(path string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if path == "" {
+		return values, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return values, nil
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return values, nil
+	}
+
+	head := make([]byte, e.headerBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	media := &mediaFile{file: file, size: info.Size(), head: head[:n]}
+	if err := media.parse(values); err != nil {
+		log.Debug().Err(err).Msgf("Incomplete media metadata for %s", path)
+	}
+	return values, nil
+}
+
+// ExtractBatch reads media metadata for multiple documents
+func (e *MediaExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted media features from %d documents", len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *MediaExtractor) GetSupportedFeatures() []string {
+	return []string{
+		"media_type", "media_format",
+		"width", "height", "orientation", "camera_make", "camera_model", "taken_at", "gps_latitude", "gps_longitude",
+		"duration", "sample_rate", "channels", "bitrate", "title", "artist", "album", "year", "genre",
+	}
+}
+
+// Validate checks if the extractor is properly configured
+func (e *MediaExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,642 @@
+package features
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Registered for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var errTruncated = errors.New("metadata is truncated")
+
+// mediaFile is a file being parsed for metadata: its leading bytes are in head, the rest is read on demand
+type mediaFile struct {
+	file io.ReaderAt
+	size int64
+	head []byte
+}
+
+// parse detects the format from the magic bytes and adds whatever metadata it finds to values
+func (m *mediaFile) parse(values map[string]interface{}) error {
+	head := m.head
+	switch {
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		values["media_type"], values["media_format"] = "image", "jpeg"
+		m.imageConfig(values)
+		return parseJPEGExif(head, values)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		values["media_type"], values["media_format"] = "image", "png"
+		m.imageConfig(values)
+	case bytes.HasPrefix(head, []byte("GIF87a")) || bytes.HasPrefix(head, []byte("GIF89a")):
+		values["media_type"], values["media_format"] = "image", "gif"
+		m.imageConfig(values)
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		values["media_type"], values["media_format"] = "audio", "flac"
+		return m.parseFLAC(values)
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		values["media_type"], values["media_format"] = "audio", "wav"
+		return parseWAV(head, values)
+	case bytes.HasPrefix(head, []byte("ID3")) || parseMPEGHeader(head) != nil:
+		values["media_type"], values["media_format"] = "audio", "mp3"
+		return m.parseMP3(values)
+	}
+	return nil
+}
+
+// readAt returns n bytes at offset, or fewer at the end of the file
+func (m *mediaFile) readAt(offset int64, n int) []byte {
+	if offset+int64(n) <= int64(len(m.head)) {
+		return m.head[offset : offset+int64(n)]
+	}
+	buf := make([]byte, n)
+	read, _ := m.file.ReadAt(buf, offset)
+	return buf[:read]
+}
+
+func (m *mediaFile) imageConfig(values map[string]interface{}) {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(m.head)); err == nil {
+		values["width"], values["height"] = cfg.Width, cfg.Height
+	}
+}
+
+// parseJPEGExif walks the JPEG segments up to the image data looking for an APP1 Exif segment
+func parseJPEGExif(data []byte, values map[string]interface{}) error {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return fmt.Errorf("invalid JPEG marker at offset %d", i)
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff: // Fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd8): // Markers without a length
+			i += 2
+			continue
+		case marker == 0xda || marker == 0xd9: // Start of scan or end of image: no more metadata
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 {
+			return fmt.Errorf("invalid JPEG segment length at offset %d", i)
+		}
+		end := i + 2 + length
+		if end > len(data) {
+			end = len(data)
+		}
+		if segment := data[i+4 : end]; marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExif(segment[6:], values)
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// tiffEntry is one IFD entry; data holds the raw value bytes
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// tiffTypeSizes maps TIFF field types to their size in bytes
+var tiffTypeSizes = map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// parseExif reads camera, date, dimension and GPS tags from a TIFF-structured Exif block
+func parseExif(data []byte, values map[string]interface{}) error {
+	if len(data) < 8 {
+		return errTruncated
+	}
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return errors.New("invalid Exif byte order")
+	}
+
+	ifd0, err := t.ifd(t.order.Uint32(data[4:]))
+	if err != nil {
+		return err
+	}
+	if s := t.string(ifd0[0x010f]); s != "" {
+		values["camera_make"] = s
+	}
+	if s := t.string(ifd0[0x0110]); s != "" {
+		values["camera_model"] = s
+	}
+	if v, ok := t.uint(ifd0[0x0112]); ok {
+		values["orientation"] = int(v)
+	}
+	takenAt := t.string(ifd0[0x0132])
+
+	if offset, ok := t.uint(ifd0[0x8769]); ok {
+		exif, err := t.ifd(offset)
+		if err != nil {
+			return err
+		}
+		if s := t.string(exif[0x9003]); s != "" {
+			takenAt = s
+		}
+		if _, ok := values["width"]; !ok {
+			width, okWidth := t.uint(exif[0xa002])
+			height, okHeight := t.uint(exif[0xa003])
+			if okWidth && okHeight {
+				values["width"], values["height"] = int(width), int(height)
+			}
+		}
+	}
+	if takenAt != "" {
+		if parsed, err := time.Parse("2006:01:02 15:04:05", takenAt); err == nil {
+			takenAt = parsed.Format("2006-01-02T15:04:05")
+		}
+		values["taken_at"] = takenAt
+	}
+
+	if offset, ok := t.uint(ifd0[0x8825]); ok {
+		gps, err := t.ifd(offset)
+		if err != nil {
+			return err
+		}
+		latitude, okLatitude := t.coordinate(gps[0x0002], t.string(gps[0x0001]), "S")
+		longitude, okLongitude := t.coordinate(gps[0x0004], t.string(gps[0x0003]), "W")
+		if okLatitude && okLongitude {
+			values["gps_latitude"], values["gps_longitude"] = latitude, longitude
+		}
+	}
+	return nil
+}
+
+// ifd reads the image file directory at offset
+func (t *tiffReader) ifd(offset uint32) (map[uint16]tiffEntry, error) {
+	start := int64(offset)
+	if start+2 > int64(len(t.data)) {
+		return nil, errTruncated
+	}
+	count := int64(t.order.Uint16(t.data[start:]))
+	entries := make(map[uint16]tiffEntry, count)
+	for i := int64(0); i < count; i++ {
+		entry := start + 2 + i*12
+		if entry+12 > int64(len(t.data)) {
+			return entries, errTruncated
+		}
+		tag := t.order.Uint16(t.data[entry:])
+		typ := t.order.Uint16(t.data[entry+2:])
+		n := t.order.Uint32(t.data[entry+4:])
+		size := tiffTypeSizes[typ] * int64(n)
+		if size == 0 {
+			continue
+		}
+		valueOffset := entry + 8
+		if size > 4 {
+			valueOffset = int64(t.order.Uint32(t.data[entry+8:]))
+		}
+		if valueOffset+size > int64(len(t.data)) {
+			continue
+		}
+		entries[tag] = tiffEntry{typ: typ, count: n, data: t.data[valueOffset : valueOffset+size]}
+	}
+	return entries, nil
+}
+
+func (t *tiffReader) string(entry tiffEntry) string {
+	if entry.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(entry.data), "\x00"))
+}
+
+func (t *tiffReader) uint(entry tiffEntry) (uint32, bool) {
+	switch entry.typ {
+	case 3:
+		return uint32(t.order.Uint16(entry.data)), true
+	case 4:
+		return t.order.Uint32(entry.data), true
+	}
+	return 0, false
+}
+
+// coordinate converts degrees, minutes and seconds rationals to signed decimal degrees
+func (t *tiffReader) coordinate(entry tiffEntry, ref, negativeRef string) (float64, bool) {
+	if entry.typ != 5 || entry.count < 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		numerator := t.order.Uint32(entry.data[i*8:])
+		denominator := t.order.Uint32(entry.data[i*8+4:])
+		if denominator == 0 {
+			return 0, false
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+	degrees := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		degrees = -degrees
+	}
+	return math.Round(degrees*1e6) / 1e6, true
+}
+
+// id3Frames maps ID3v2.2 and v2.3/v2.4 text frames to feature names
+var id3Frames = map[string]string{
+	"TT2": "title", "TIT2": "title",
+	"TP1": "artist", "TPE1": "artist",
+	"TAL": "album", "TALB": "album",
+	"TYE": "year", "TYER": "year", "TDRC": "year",
+	"TCO": "genre", "TCON": "genre",
+	"TLE": "length", "TLEN": "length",
+}
+
+// parseMP3 reads the ID3 tags and the first MPEG frame, from which the duration is computed
+func (m *mediaFile) parseMP3(values map[string]interface{}) error {
+	var audioStart int64
+	var tagLength string
+	if head := m.head; bytes.HasPrefix(head, []byte("ID3")) && len(head) >= 10 {
+		audioStart = int64(syncsafe(head[6:10])) + 10
+		if head[5]&0x10 != 0 { // Footer present
+			audioStart += 10
+		}
+		tagLength = parseID3v2(head[:minInt64(audioStart, int64(len(head)))], values)
+	}
+	audioEnd := m.size
+	if m.size >= 128 {
+		if tail := m.readAt(m.size-128, 128); bytes.HasPrefix(tail, []byte("TAG")) {
+			parseID3v1(tail, values)
+			audioEnd -= 128
+		}
+	}
+
+	buf := m.readAt(audioStart, 4096)
+	for i := 0; i+4 <= len(buf); i++ {
+		frame := parseMPEGHeader(buf[i:])
+		if frame == nil {
+			continue
+		}
+		values["sample_rate"] = frame.sampleRate
+		values["channels"] = frame.channels
+		audioBytes := audioEnd - audioStart - int64(i)
+		var duration float64
+		if frames := frame.vbrFrames(buf[i:]); frames > 0 {
+			duration = float64(frames) * float64(frame.samplesPerFrame) / float64(frame.sampleRate)
+		} else if frame.bitrate > 0 {
+			duration = float64(audioBytes) * 8 / float64(frame.bitrate*1000)
+		}
+		if duration > 0 {
+			values["duration"] = math.Round(duration*1000) / 1000
+			values["bitrate"] = int(math.Round(float64(audioBytes) * 8 / duration / 1000))
+		}
+		return nil
+	}
+
+	// No audio frame found: fall back to the tagged length, in milliseconds
+	var ms int
+	if _, err := fmt.Sscanf(tagLength, "%d", &ms); err == nil && ms > 0 {
+		values["duration"] = float64(ms) / 1000
+	}
+	return nil
+}
+
+// parseID3v2 reads the text frames of an ID3v2 tag and returns the TLEN frame, if any
+func parseID3v2(tag []byte, values map[string]interface{}) (length string) {
+	if len(tag) < 10 {
+		return ""
+	}
+	major, flags := tag[3], tag[5]
+	data := tag[10:]
+	if flags&0x40 != 0 && len(data) >= 4 { // Extended header
+		size := int(binary.BigEndian.Uint32(data)) + 4
+		if major == 4 {
+			size = syncsafe(data)
+		}
+		if size > len(data) {
+			return ""
+		}
+		data = data[size:]
+	}
+
+	idLength, headerLength := 4, 10
+	if major == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(data) >= headerLength && data[0] != 0 {
+		id := string(data[:idLength])
+		var size int
+		switch major {
+		case 2:
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 4:
+			size = syncsafe(data[4:8])
+		default:
+			size = int(binary.BigEndian.Uint32(data[4:8]))
+		}
+		if size <= 0 || headerLength+size > len(data) {
+			break
+		}
+		if name, ok := id3Frames[id]; ok {
+			if text := id3Text(data[headerLength : headerLength+size]); text != "" {
+				switch name {
+				case "length":
+					length = text
+				case "year":
+					values[name] = truncate(text, 4)
+				default:
+					values[name] = text
+				}
+			}
+		}
+		data = data[headerLength+size:]
+	}
+	return length
+}
+
+// parseID3v1 fills in the fields missing from the ID3v2 tag from the fixed-size tag at the end of the file
+func parseID3v1(tag []byte, values map[string]interface{}) {
+	for name, field := range map[string][]byte{"title": tag[3:33], "artist": tag[33:63], "album": tag[63:93], "year": tag[93:97]} {
+		if _, exists := values[name]; exists {
+			continue
+		}
+		if text := strings.TrimSpace(latin1(bytes.TrimRight(field, "\x00"))); text != "" {
+			values[name] = text
+		}
+	}
+}
+
+// id3Text decodes a text frame body; only the first of multiple values is kept
+func id3Text(body []byte) string {
+	if len(body) < 2 {
+		return ""
+	}
+	var text string
+	switch encoding, rest := body[0], body[1:]; encoding {
+	case 0:
+		text = latin1(rest)
+	case 1, 2:
+		var order binary.ByteOrder = binary.BigEndian
+		if len(rest) >= 2 && rest[0] == 0xff && rest[1] == 0xfe {
+			order, rest = binary.LittleEndian, rest[2:]
+		} else if len(rest) >= 2 && rest[0] == 0xfe && rest[1] == 0xff {
+			rest = rest[2:]
+		}
+		units := make([]uint16, 0, len(rest)/2)
+		for i := 0; i+1 < len(rest); i += 2 {
+			units = append(units, order.Uint16(rest[i:]))
+		}
+		text = string(utf16.Decode(units))
+	default:
+		text = string(rest)
+	}
+	if i := strings.IndexByte(text, 0); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
+
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func syncsafe(data []byte) int {
+	return int(data[0]&0x7f)<<21 | int(data[1]&0x7f)<<14 | int(data[2]&0x7f)<<7 | int(data[3]&0x7f)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mpegFrame is the decoded header of an MPEG audio frame
+type mpegFrame struct {
+	version         int // 1, 2, or 25 for MPEG 2.5
+	layer           int
+	bitrate         int // kbit/s
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+}
+
+var mpegBitrates = map[[2]int][]int{
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// parseMPEGHeader decodes a frame header, returning nil when data does not start with a valid one
+func parseMPEGHeader(data []byte) *mpegFrame {
+	if len(data) < 4 || data[0] != 0xff || data[1]&0xe0 != 0xe0 {
+		return nil
+	}
+	versionBits, layerBits := (data[1]>>3)&3, (data[1]>>1)&3
+	bitrateIndex, sampleRateIndex := int(data[2]>>4), int((data[2]>>2)&3)
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return nil
+	}
+
+	frame := &mpegFrame{layer: 4 - int(layerBits), channels: 2}
+	sampleRate := []int{44100, 48000, 32000}[sampleRateIndex]
+	switch versionBits {
+	case 3:
+		frame.version, frame.sampleRate = 1, sampleRate
+	case 2:
+		frame.version, frame.sampleRate = 2, sampleRate/2
+	default:
+		frame.version, frame.sampleRate = 25, sampleRate/4
+	}
+	table := 1
+	if frame.version != 1 {
+		table = 2
+	}
+	frame.bitrate = mpegBitrates[[2]int{table, frame.layer}][bitrateIndex]
+	if data[3]>>6 == 3 {
+		frame.channels = 1
+	}
+	switch {
+	case frame.layer == 1:
+		frame.samplesPerFrame = 384
+	case frame.layer == 3 && frame.version != 1:
+		frame.samplesPerFrame = 576
+	default:
+		frame.samplesPerFrame = 1152
+	}
+	return frame
+}
+
+// vbrFrames returns the frame count of a Xing/Info or VBRI header in the first frame, or 0
+func (f *mpegFrame) vbrFrames(data []byte) int {
+	sideInfo := 32
+	switch {
+	case f.version == 1 && f.channels == 1, f.version != 1 && f.channels == 2:
+		sideInfo = 17
+	case f.version != 1:
+		sideInfo = 9
+	}
+	if xing := 4 + sideInfo; len(data) >= xing+12 {
+		if tag := string(data[xing : xing+4]); tag == "Xing" || tag == "Info" {
+			if binary.BigEndian.Uint32(data[xing+4:])&1 != 0 {
+				return int(binary.BigEndian.Uint32(data[xing+8:]))
+			}
+		}
+	}
+	if len(data) >= 36+18 && string(data[36:40]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(data[36+14:]))
+	}
+	return 0
+}
+
+// parseFLAC reads the STREAMINFO and VORBIS_COMMENT metadata blocks
+func (m *mediaFile) parseFLAC(values map[string]interface{}) error {
+	data := m.head[4:]
+	for len(data) >= 4 {
+		last, blockType := data[0]&0x80 != 0, data[0]&0x7f
+		length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		if 4+length > len(data) {
+			return errTruncated
+		}
+		block := data[4 : 4+length]
+		switch blockType {
+		case 0:
+			if len(block) < 18 {
+				return errTruncated
+			}
+			sampleRate := int(block[10])<<12 | int(block[11])<<4 | int(block[12])>>4
+			channels := int(block[12]>>1&7) + 1
+			samples := uint64(block[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(block[14:18]))
+			values["sample_rate"], values["channels"] = sampleRate, channels
+			if sampleRate > 0 && samples > 0 {
+				duration := float64(samples) / float64(sampleRate)
+				values["duration"] = math.Round(duration*1000) / 1000
+				values["bitrate"] = int(math.Round(float64(m.size) * 8 / duration / 1000))
+			}
+		case 4:
+			parseVorbisComment(block, values)
+		}
+		if last {
+			break
+		}
+		data = data[4+length:]
+	}
+	return nil
+}
+
+// parseVorbisComment reads KEY=value comments (little-endian length-prefixed strings after the vendor string)
+func parseVorbisComment(block []byte, values map[string]interface{}) {
+	if len(block) < 8 {
+		return
+	}
+	vendor := int64(binary.LittleEndian.Uint32(block))
+	if 8+vendor > int64(len(block)) {
+		return
+	}
+	count := binary.LittleEndian.Uint32(block[4+vendor:])
+	data := block[8+vendor:]
+	for i := uint32(0); i < count && len(data) >= 4; i++ {
+		length := int64(binary.LittleEndian.Uint32(data))
+		if 4+length > int64(len(data)) {
+			return
+		}
+		key, value, ok := strings.Cut(string(data[4:4+length]), "=")
+		data = data[4+length:]
+		if !ok || value == "" {
+			continue
+		}
+		switch name := strings.ToLower(key); name {
+		case "title", "artist", "album", "genre":
+			if _, exists := values[name]; !exists {
+				values[name] = value
+			}
+		case "date":
+			values["year"] = truncate(value, 4)
+		}
+	}
+}
+
+// wavInfoTags maps RIFF INFO chunks to feature names
+var wavInfoTags = map[string]string{"INAM": "title", "IART": "artist", "IPRD": "album", "ICRD": "year", "IGNR": "genre"}
+
+// parseWAV reads the format, LIST/INFO and data chunk headers of a RIFF WAVE file
+func parseWAV(head []byte, values map[string]interface{}) error {
+	var byteRate uint32
+	data := head[12:]
+	for len(data) >= 8 {
+		id, size := string(data[:4]), int64(binary.LittleEndian.Uint32(data[4:8]))
+		switch id {
+		case "fmt ":
+			if len(data) < 24 {
+				return errTruncated
+			}
+			values["channels"] = int(binary.LittleEndian.Uint16(data[10:]))
+			values["sample_rate"] = int(binary.LittleEndian.Uint32(data[12:]))
+			byteRate = binary.LittleEndian.Uint32(data[16:])
+			values["bitrate"] = int(math.Round(float64(byteRate) * 8 / 1000))
+		case "LIST":
+			if len(data) >= 12 && string(data[8:12]) == "INFO" {
+				parseWAVInfo(data[12:minInt64(8+size, int64(len(data)))], values)
+			}
+		case "data":
+			// The samples follow; only the chunk size is needed
+			if byteRate > 0 {
+				values["duration"] = math.Round(float64(size)/float64(byteRate)*1000) / 1000
+			}
+			return nil
+		}
+		next := 8 + size + size%2 // Chunks are padded to an even size
+		if next > int64(len(data)) {
+			return errTruncated
+		}
+		data = data[next:]
+	}
+	return nil
+}
+
+func parseWAVInfo(data []byte, values map[string]interface{}) {
+	for len(data) >= 8 {
+		id, size := string(data[:4]), int64(binary.LittleEndian.Uint32(data[4:8]))
+		if 8+size > int64(len(data)) {
+			return
+		}
+		if name, ok := wavInfoTags[id]; ok {
+			if text := strings.TrimSpace(strings.TrimRight(string(data[8:8+size]), "\x00")); text != "" {
+				if name == "year" {
+					text = truncate(text, 4)
+				}
+				values[name] = text
+			}
+		}
+		next := 8 + size + size%2
+		if next > int64(len(data)) {
+			return
+		}
+		data = data[next:]
+	}
+}
@@ -0,0 +1,220 @@
+package features
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type testIFDEntry struct {
+	tag, typ uint16
+	count    uint32
+	data     []byte
+}
+
+// testIFD encodes a big-endian IFD located at base, followed by its out-of-line values
+func testIFD(base int, entries []testIFDEntry) []byte {
+	var ifd, extra bytes.Buffer
+	dataOffset := base + 2 + 12*len(entries) + 4
+	binary.Write(&ifd, binary.BigEndian, uint16(len(entries)))
+	for _, entry := range entries {
+		testWrite(&ifd, binary.BigEndian, entry.tag, entry.typ, entry.count)
+		if len(entry.data) <= 4 {
+			ifd.Write(append(entry.data, make([]byte, 4-len(entry.data))...))
+			continue
+		}
+		binary.Write(&ifd, binary.BigEndian, uint32(dataOffset+extra.Len()))
+		extra.Write(entry.data)
+	}
+	binary.Write(&ifd, binary.BigEndian, uint32(0))
+	return append(ifd.Bytes(), extra.Bytes()...)
+}
+
+func testWrite(w *bytes.Buffer, order binary.ByteOrder, values ...interface{}) {
+	for _, value := range values {
+		binary.Write(w, order, value)
+	}
+}
+
+func testRationals(values ...uint32) []byte {
+	data := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		data = binary.BigEndian.AppendUint32(data, v)
+		data = binary.BigEndian.AppendUint32(data, 1)
+	}
+	return data
+}
+
+func testJPEGWithExif(t *testing.T) []byte {
+	pointer := func(offset int) []byte { return binary.BigEndian.AppendUint32(nil, uint32(offset)) }
+	ifd0 := func(exifOffset, gpsOffset int) []byte {
+		return testIFD(8, []testIFDEntry{
+			{0x010f, 2, 6, []byte("Canon\x00")},
+			{0x0110, 2, 7, []byte("EOS R5\x00")},
+			{0x0112, 3, 1, []byte{0, 6}},
+			{0x8769, 4, 1, pointer(exifOffset)},
+			{0x8825, 4, 1, pointer(gpsOffset)},
+		})
+	}
+	exifOffset := 8 + len(ifd0(0, 0))
+	exif := testIFD(exifOffset, []testIFDEntry{{0x9003, 2, 20, []byte("2023:07:14 18:30:05\x00")}})
+	gpsOffset := exifOffset + len(exif)
+	gps := testIFD(gpsOffset, []testIFDEntry{
+		{0x0001, 2, 2, []byte("N\x00")},
+		{0x0002, 5, 3, testRationals(48, 51, 36)},
+		{0x0003, 2, 2, []byte("W\x00")},
+		{0x0004, 5, 3, testRationals(2, 17, 24)},
+	})
+	tiff := append(append(append([]byte("MM\x00\x2a\x00\x00\x00\x08"), ifd0(exifOffset, gpsOffset)...), exif...), gps...)
+
+	var encoded bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 40, 30)), nil))
+	app1 := append([]byte{0xff, 0xe1}, binary.BigEndian.AppendUint16(nil, uint16(2+6+len(tiff)))...)
+	app1 = append(append(app1, "Exif\x00\x00"...), tiff...)
+	return append(append([]byte{0xff, 0xd8}, app1...), encoded.Bytes()[2:]...)
+}
+
+func testMP3() []byte {
+	var frames bytes.Buffer
+	for _, frame := range []struct{ id, text string }{{"TIT2", "Night Drive"}, {"TPE1", "The Examples"}, {"TYER", "2021"}} {
+		frames.WriteString(frame.id)
+		binary.Write(&frames, binary.BigEndian, uint32(len(frame.text)+1))
+		frames.Write([]byte{0, 0, 0})
+		frames.WriteString(frame.text)
+	}
+	size := frames.Len()
+	mp3 := append([]byte("ID3\x03\x00\x00"), byte(size>>21&0x7f), byte(size>>14&0x7f), byte(size>>7&0x7f), byte(size&0x7f))
+	mp3 = append(mp3, frames.Bytes()...)
+
+	// 100 MPEG-1 Layer III frames at 128 kbit/s, 44.1 kHz, stereo (417 bytes each)
+	for i := 0; i < 100; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+		mp3 = append(mp3, frame...)
+	}
+
+	id3v1 := make([]byte, 128)
+	copy(id3v1, "TAG")
+	copy(id3v1[63:], "Test Album")
+	return append(mp3, id3v1...)
+}
+
+func testWAV() []byte {
+	var wav bytes.Buffer
+	info := []byte("INFOINAM\x05\x00\x00\x00Take\x00\x00")
+	body := 4 + (8 + 16) + (8 + len(info)) + (8 + 176400)
+	wav.WriteString("RIFF")
+	binary.Write(&wav, binary.LittleEndian, uint32(body))
+	wav.WriteString("WAVEfmt ")
+	testWrite(&wav, binary.LittleEndian, uint32(16), uint16(1), uint16(2), uint32(44100), uint32(176400), uint16(4), uint16(16))
+	wav.WriteString("LIST")
+	binary.Write(&wav, binary.LittleEndian, uint32(len(info)))
+	wav.Write(info)
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, uint32(176400))
+	wav.Write(make([]byte, 176400))
+	return wav.Bytes()
+}
+
+func testFLAC() []byte {
+	var flac bytes.Buffer
+	flac.WriteString("fLaC")
+	flac.Write([]byte{0x00, 0, 0, 34}) // STREAMINFO
+	flac.Write(make([]byte, 10))
+	packed := uint64(48000)<<44 | uint64(2-1)<<41 | uint64(16-1)<<36 | 96000
+	binary.Write(&flac, binary.BigEndian, packed)
+	flac.Write(make([]byte, 16))
+
+	var comments bytes.Buffer
+	binary.Write(&comments, binary.LittleEndian, uint32(4))
+	comments.WriteString("test")
+	binary.Write(&comments, binary.LittleEndian, uint32(2))
+	for _, comment := range []string{"ARTIST=Band", "DATE=2019-05-01"} {
+		binary.Write(&comments, binary.LittleEndian, uint32(len(comment)))
+		comments.WriteString(comment)
+	}
+	flac.Write([]byte{0x84, 0, 0, byte(comments.Len())}) // Last block: VORBIS_COMMENT
+	flac.Write(comments.Bytes())
+	return flac.Bytes()
+}
+
+func extractMedia(t *testing.T, name string, content []byte) *FeatureSet {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+	featureSet, err := NewMediaExtractor().Extract(models.Document{ID: name, Source: path})
+	assert.NoError(t, err)
+	return featureSet
+}
+
+func featureValues(featureSet *FeatureSet) map[string]interface{} {
+	values := make(map[string]interface{}, len(featureSet.Features))
+	for name, feature := range featureSet.Features {
+		values[name] = feature.Value
+	}
+	return values
+}
+
+func TestMediaExtractor_JPEGExif(t *testing.T) {
+	featureSet := extractMedia(t, "photo.jpg", testJPEGWithExif(t))
+	assert.Equal(t, map[string]interface{}{
+		"media_type":    "image",
+		"media_format":  "jpeg",
+		"width":         40,
+		"height":        30,
+		"camera_make":   "Canon",
+		"camera_model":  "EOS R5",
+		"orientation":   6,
+		"taken_at":      "2023-07-14T18:30:05",
+		"gps_latitude":  48.86,
+		"gps_longitude": -2.29,
+	}, featureValues(featureSet))
+	assert.Equal(t, []float64{40, 30, 0, 1}, featureSet.Vector)
+}
+
+func TestMediaExtractor_Audio(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{
+		"media_type":   "audio",
+		"media_format": "mp3",
+		"title":        "Night Drive",
+		"artist":       "The Examples",
+		"album":        "Test Album",
+		"year":         "2021",
+		"sample_rate":  44100,
+		"channels":     2,
+		"duration":     2.606,
+		"bitrate":      128,
+	}, featureValues(extractMedia(t, "song.mp3", testMP3())))
+
+	assert.Equal(t, map[string]interface{}{
+		"media_type":   "audio",
+		"media_format": "wav",
+		"title":        "Take",
+		"sample_rate":  44100,
+		"channels":     2,
+		"duration":     1.0,
+		"bitrate":      1411,
+	}, featureValues(extractMedia(t, "take.wav", testWAV())))
+
+	flac := featureValues(extractMedia(t, "track.flac", testFLAC()))
+	assert.Equal(t, "Band", flac["artist"])
+	assert.Equal(t, "2019", flac["year"])
+	assert.Equal(t, 48000, flac["sample_rate"])
+	assert.Equal(t, 2.0, flac["duration"])
+}
+
+func TestMediaExtractor_IgnoresOtherFiles(t *testing.T) {
+	featureSet := extractMedia(t, "notes.txt", []byte("just some text"))
+	assert.Empty(t, featureSet.Features)
+	assert.Equal(t, []float64{0, 0, 0, 0}, featureSet.Vector)
+
+	featureSet, err := NewMediaExtractor().Extract(models.Document{ID: "no-file", Text: "text"})
+	assert.NoError(t, err)
+	assert.Empty(t, featureSet.Features)
+}
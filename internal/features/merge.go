@@ -0,0 +1,400 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// MergeOptions controls how an extractor's features are merged into documents by FeatureRegistry.Merge
+type MergeOptions struct {
+	Meta   bool // Store each feature in Document.Meta under its (mapped) name; "vector" features are left out
+	Vector bool // Append the extractor's vector to Document.Vector
+
+	// Normalization scales the appended vector with corpus statistics (NormalizeZScore or NormalizeMinMax)
+	// when the extractor's Normalize flag is set; empty leaves vectors unscaled. The statistics are fitted
+	// on the first merged batch and, when NormalizerPath is set, saved there and reused after a restart.
+	Normalization  string
+	NormalizerPath string
+
+	// Parallelism and Timeout run the extractor with a BatchExecutor when either is set: documents are
+	// extracted by Parallelism workers (0: one per CPU), each bounded by Timeout (0: unbounded).
+	// Documents that fail or time out are skipped like any other failure.
+	Parallelism int
+	Timeout     time.Duration
+
+	// Cache reuses the features of documents whose content did not change (see CacheKey) from the
+	// registry's FeatureCache; the extractor only runs on the other documents.
+	Cache bool
+}
+
+// parallel reports whether the extractor runs with a BatchExecutor
+func (o MergeOptions) parallel() bool {
+	return o.Parallelism != 0 || o.Timeout != 0
+}
+
+// DefaultMergeOptions merges features into both Meta and Vector
+var DefaultMergeOptions = MergeOptions{Meta: true, Vector: true}
+
+// MergeOptionsFromMap reads the "meta" and "vector" keys (both default true) and the "normalization",
+// "normalizer_path", "parallelism", "timeout" and "cache" keys of a starter config map
+func MergeOptionsFromMap(cfg map[string]interface{}) (MergeOptions, error) {
+	meta, err := config.Bool(cfg, "meta", true)
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	vector, err := config.Bool(cfg, "vector", true)
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	normalization, err := config.String(cfg, "normalization", "")
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	if normalization != "" {
+		if err := validNormalization(normalization); err != nil {
+			return MergeOptions{}, err
+		}
+	}
+	path, err := config.String(cfg, "normalizer_path", "")
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	parallelism, err := config.Int(cfg, "parallelism", 0)
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	if parallelism < 0 {
+		return MergeOptions{}, fmt.Errorf("parallelism must not be negative, got %d", parallelism)
+	}
+	timeout, err := config.Duration(cfg, "timeout", 0)
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	if timeout < 0 {
+		return MergeOptions{}, fmt.Errorf("timeout must not be negative, got %s", timeout)
+	}
+	cache, err := config.Bool(cfg, "cache", false)
+	if err != nil {
+		return MergeOptions{}, err
+	}
+	return MergeOptions{
+		Meta:           meta,
+		Vector:         vector,
+		Normalization:  normalization,
+		NormalizerPath: path,
+		Parallelism:    parallelism,
+		Timeout:        timeout,
+		Cache:          cache,
+	}, nil
+}
+
+// SetMergeOptions sets how the features of an extractor are merged into documents. Statistics saved at
+// options.NormalizerPath are loaded; those already fitted are kept as long as the normalization and path
+// do not change.
+func (r *FeatureRegistry) SetMergeOptions(extractorName string, options MergeOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.extractors[extractorName]; !exists {
+		return fmt.Errorf("extractor %s not found", extractorName)
+	}
+
+	previous := r.merge[extractorName]
+	switch {
+	case options.Normalization == "":
+		delete(r.normalizers, extractorName)
+	case r.normalizers[extractorName] != nil && previous.Normalization == options.Normalization &&
+		previous.NormalizerPath == options.NormalizerPath:
+	default:
+		normalizer, err := openNormalizer(options)
+		if err != nil {
+			return fmt.Errorf("extractor %s: %w", extractorName, err)
+		}
+		r.normalizers[extractorName] = normalizer
+	}
+	r.merge[extractorName] = options
+	return nil
+}
+
+// openNormalizer loads the statistics saved at options.NormalizerPath, or creates an unfitted normalizer
+func openNormalizer(options MergeOptions) (*Normalizer, error) {
+	if options.NormalizerPath != "" {
+		normalizer, err := LoadNormalizer(options.NormalizerPath)
+		switch {
+		case err == nil && normalizer.Method() != options.Normalization:
+			log.Warn().Msgf("Ignoring %s statistics in %s, refitting for %s normalization",
+				normalizer.Method(), options.NormalizerPath, options.Normalization)
+		case err == nil:
+			log.Info().Msgf("Loaded %s statistics for %d dimensions from %s",
+				normalizer.Method(), normalizer.Dimensions(), options.NormalizerPath)
+			return normalizer, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+	return NewNormalizer(options.Normalization)
+}
+
+// SetCache sets the cache used by extractors merged with MergeOptions.Cache; without one they always run
+func (r *FeatureRegistry) SetCache(cache *FeatureCache) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = cache
+}
+
+// Normalizer returns the corpus statistics used to scale an extractor's vectors, if it has a normalization
+func (r *FeatureRegistry) Normalizer(extractorName string) (*Normalizer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	normalizer, ok := r.normalizers[extractorName]
+	return normalizer, ok
+}
+
+// Merge runs the named extractors over a batch, in order, and merges their features into the documents
+// in place. Disabled or unconfigured extractors are skipped. Vectors are appended in extractor order; a
+// document an extractor failed on gets zeros in its place so every document keeps the same layout.
+func (r *FeatureRegistry) Merge(docs []models.Document, extractorNames []string) error {
+	return r.MergeContext(context.Background(), docs, extractorNames)
+}
+
+// MergeContext is Merge with cancellation: once ctx is done, no further extractor is started, extractors
+// run with a BatchExecutor stop taking documents, and ctx.Err() is returned with the batch half-merged.
+func (r *FeatureRegistry) MergeContext(ctx context.Context, docs []models.Document, extractorNames []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(docs) == 0 {
+		return nil
+	}
+
+	for _, name := range extractorNames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		extractor, exists := r.extractors[name]
+		if !exists {
+			return fmt.Errorf("extractor %s not found", name)
+		}
+		if config, configured := r.configs[name]; !configured || !config.Enabled {
+			continue
+		}
+		options, ok := r.merge[name]
+		if !ok {
+			options = DefaultMergeOptions
+		}
+
+		featureSets, err := r.extractBatch(ctx, extractor, options, docs)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]*FeatureSet, len(featureSets))
+		width := 0
+		for _, featureSet := range featureSets {
+			featureSet.Extractor = name
+			byID[featureSet.DocumentID] = featureSet
+			if len(featureSet.Vector) > width {
+				width = len(featureSet.Vector)
+			}
+		}
+
+		blocks := make([][]float64, len(docs))
+		for i := range docs {
+			featureSet := byID[docs[i].ID]
+			if options.Meta && featureSet != nil {
+				mergeMeta(&docs[i], featureSet)
+			}
+			blocks[i] = make([]float64, width)
+			if featureSet != nil {
+				copy(blocks[i], featureSet.Vector)
+			}
+		}
+		if !options.Vector || width == 0 {
+			continue
+		}
+		if err := r.normalizeBlocks(name, blocks); err != nil {
+			return err
+		}
+		r.widthsMu.Lock()
+		r.widths[name] = width
+		r.widthsMu.Unlock()
+		for i := range docs {
+			vector := make([]float64, len(docs[i].Vector), len(docs[i].Vector)+width)
+			copy(vector, docs[i].Vector)
+			docs[i].Vector = append(vector, blocks[i]...)
+		}
+	}
+	return nil
+}
+
+// extractBatch runs an extractor over the documents of the batch that are not cached, then caches
+// their results. The caller must hold the read lock.
+func (r *FeatureRegistry) extractBatch(ctx context.Context, extractor FeatureExtractor, options MergeOptions, docs []models.Document) ([]*FeatureSet, error) {
+	if !options.Cache || r.cache == nil {
+		return r.runExtractor(ctx, extractor, options, docs)
+	}
+
+	name := extractor.Name()
+	cfg := r.configs[name]
+	var featureSets []*FeatureSet
+	var missing []models.Document
+	keys := make(map[string]string, len(docs)) // Document ID -> cache key of the missing documents
+	for _, doc := range docs {
+		key := CacheKey(name, cfg, doc)
+		if featureSet, ok := r.cache.Get(key, doc.ID); ok {
+			featureSets = append(featureSets, featureSet)
+			continue
+		}
+		keys[doc.ID] = key
+		missing = append(missing, doc)
+	}
+	log.Debug().Msgf("Feature cache of extractor %s: %d hits, %d misses", name, len(featureSets), len(missing))
+	if len(missing) == 0 {
+		return featureSets, nil
+	}
+
+	extracted, err := r.runExtractor(ctx, extractor, options, missing)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*FeatureSet, len(extracted))
+	for _, featureSet := range extracted {
+		if key, ok := keys[featureSet.DocumentID]; ok {
+			entries[key] = featureSet
+		}
+	}
+	r.cache.PutAll(entries)
+	return append(featureSets, extracted...), nil
+}
+
+// runExtractor runs an extractor over documents, with a BatchExecutor if its options ask for one
+func (r *FeatureRegistry) runExtractor(ctx context.Context, extractor FeatureExtractor, options MergeOptions, docs []models.Document) ([]*FeatureSet, error) {
+	if !options.parallel() {
+		featureSets, err := extractor.ExtractBatch(docs)
+		if err != nil {
+			return nil, fmt.Errorf("extractor %s failed: %w", extractor.Name(), err)
+		}
+		return featureSets, nil
+	}
+
+	featureSets, err := NewBatchExecutor(options.Parallelism, options.Timeout).Run(ctx, extractor, docs)
+	var batchErr *BatchError
+	switch {
+	case errors.As(err, &batchErr):
+		for _, failure := range batchErr.Failures {
+			log.Warn().Err(failure.Err).Msgf("Failed to extract %s features from document %s", extractor.Name(), failure.DocumentID)
+		}
+	case err != nil && ctx.Err() != nil:
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("extractor %s failed: %w", extractor.Name(), err)
+	}
+	return featureSets, nil
+}
+
+// normalizeBlocks scales an extractor's vectors in place, first fitting its statistics on them if it has
+// none yet. The caller must hold the read lock.
+func (r *FeatureRegistry) normalizeBlocks(name string, blocks [][]float64) error {
+	normalizer, ok := r.normalizers[name]
+	if !ok || !r.configs[name].Normalize {
+		return nil
+	}
+	if normalizer.fitOnce(blocks) {
+		log.Info().Msgf("Fitted %s statistics of extractor %s on %d documents", normalizer.Method(), name, len(blocks))
+		if path := r.merge[name].NormalizerPath; path != "" {
+			if err := normalizer.Save(path); err != nil {
+				return fmt.Errorf("failed to save normalizer of extractor %s: %w", name, err)
+			}
+		}
+	}
+	for i, block := range blocks {
+		blocks[i] = normalizer.Transform(block)
+	}
+	return nil
+}
+
+// NormalizeQuery applies the corpus statistics of the named extractors to a query vector laid out like the
+// documents Merge produced with the same extractors: any loader vector first, then one block per extractor
+// in order. Blocks are located from the end of the vector, using the widths of the last merged batch, so a
+// leading extractor that has not merged anything since startup only stops the extractors before it from
+// being scaled.
+func (r *FeatureRegistry) NormalizeQuery(vector []float64, extractorNames []string) ([]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.widthsMu.Lock()
+	defer r.widthsMu.Unlock()
+
+	scaled := make([]float64, len(vector))
+	copy(scaled, vector)
+	end := len(scaled)
+	for i := len(extractorNames) - 1; i >= 0; i-- {
+		name := extractorNames[i]
+		if cfg, configured := r.configs[name]; !configured || !cfg.Enabled {
+			continue
+		}
+		if options, ok := r.merge[name]; ok && !options.Vector {
+			continue
+		}
+		width, ok := r.widths[name]
+		if !ok {
+			if normalizer, normalized := r.normalizers[name]; normalized && normalizer.Fitted() {
+				width = normalizer.Dimensions()
+			} else {
+				break
+			}
+		}
+		if width > end {
+			return nil, fmt.Errorf("query vector has %d dimensions, fewer than the %d of its features", len(vector), len(vector)-end+width)
+		}
+		if normalizer, ok := r.normalizers[name]; ok && r.configs[name].Normalize {
+			copy(scaled[end-width:end], normalizer.Transform(scaled[end-width:end]))
+		}
+		end -= width
+	}
+	return scaled, nil
+}
+
+// mergeMeta copies features into a fresh metadata map, since loaders may share maps between documents
+func mergeMeta(doc *models.Document, featureSet *FeatureSet) {
+	meta := make(map[string]string, len(doc.Meta)+len(featureSet.Features))
+	for key, value := range doc.Meta {
+		meta[key] = value
+	}
+	for name, feature := range featureSet.Features {
+		if value, ok := metaValue(feature); ok {
+			meta[name] = value
+		}
+	}
+	doc.Meta = meta
+}
+
+// metaValue renders a feature as a metadata string; vectors are not stored in metadata
+func metaValue(feature Feature) (string, bool) {
+	if feature.Type == "vector" {
+		return "", false
+	}
+	switch v := feature.Value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case []string:
+		return strings.Join(v, ","), true
+	case int, int64, uint64:
+		return fmt.Sprint(v), true
+	}
+	encoded, err := json.Marshal(feature.Value)
+	if err != nil {
+		return fmt.Sprint(feature.Value), true
+	}
+	return string(encoded), true
+}
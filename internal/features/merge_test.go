@@ -0,0 +1,81 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMergeRegistry(t *testing.T, configs map[string]map[string]interface{}, names ...string) *FeatureRegistry {
+	factory := NewExtractorFactory()
+	registry := NewFeatureRegistry()
+	for _, name := range names {
+		cfg, err := FromMap(configs[name])
+		assert.NoError(t, err)
+		extractor, err := factory.Create(name, cfg)
+		assert.NoError(t, err)
+		assert.NoError(t, registry.Register(extractor))
+		assert.NoError(t, registry.Configure(name, cfg))
+		options, err := MergeOptionsFromMap(configs[name])
+		assert.NoError(t, err)
+		assert.NoError(t, registry.SetMergeOptions(name, options))
+	}
+	return registry
+}
+
+func TestFeatureRegistry_Merge(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"hash":     {"algorithms": []interface{}{"md5"}, "vector": false},
+		"keywords": {"max_keywords": 2, "feature_map": map[string]interface{}{"keywords": "tags"}},
+	}, "hash", "keywords")
+
+	shared := map[string]string{"filename": "a.txt"}
+	docs := []models.Document{
+		{ID: "a", Text: "mail ops@example.com about the search engine", Vector: []float64{7}, Meta: shared},
+		{ID: "b", Text: "nothing to see", Meta: shared},
+	}
+	assert.NoError(t, registry.Merge(docs, []string{"hash", "keywords"}))
+
+	assert.Equal(t, "a.txt", docs[0].Meta["filename"])
+	assert.Len(t, docs[0].Meta["md5"], 32)
+	assert.Equal(t, "true", docs[0].Meta["has_email"])
+	assert.Equal(t, "ops@example.com", docs[0].Meta["emails"])
+	assert.Equal(t, "search engine,mail", docs[0].Meta["tags"])
+	assert.Equal(t, "false", docs[1].Meta["has_email"])
+	assert.Len(t, shared, 1, "shared loader metadata is not modified")
+
+	// Only the keyword vector (has_email, has_url, has_ip, has_date) is appended
+	assert.Equal(t, []float64{7, 1, 0, 0, 0}, docs[0].Vector)
+	assert.Equal(t, []float64{0, 0, 0, 0}, docs[1].Vector)
+}
+
+func TestFeatureRegistry_MergeSkipsDisabledAndUnknown(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"content": {"enabled": false},
+	}, "content")
+
+	docs := []models.Document{{ID: "a", Text: "Some text."}}
+	assert.NoError(t, registry.Merge(docs, []string{"content"}))
+	assert.Empty(t, docs[0].Meta)
+	assert.Empty(t, docs[0].Vector)
+
+	assert.Error(t, registry.Merge(docs, []string{"missing"}))
+}
+
+func TestExtractorFactory(t *testing.T) {
+	factory := NewExtractorFactory()
+	assert.True(t, factory.Has("tfidf"))
+	assert.False(t, factory.Has("wordcount"))
+
+	_, err := factory.Create("wordcount", NewConfigBuilder().Build())
+	assert.Error(t, err)
+	_, err = factory.Create("hash", NewConfigBuilder().Parameter("algorithms", "crc32").Build())
+	assert.Error(t, err)
+
+	cfg, err := FromMap(map[string]interface{}{"weight": 0.5, "vectorize": false, "max_keywords": 3, "meta": false})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, cfg.Weight)
+	assert.False(t, cfg.Vectorize)
+	assert.Equal(t, map[string]interface{}{"max_keywords": 3}, cfg.Parameters)
+}
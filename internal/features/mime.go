@@ -0,0 +1,244 @@
+package features
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultSniffBytes is the number of leading bytes inspected, as in http.DetectContentType
+const DefaultSniffBytes = 512
+
+// MimeExtractor detects a document's media type from its content (magic bytes) rather than its extension.
+// The content is read from the file at doc.Source when it exists, and from doc.Text otherwise.
+type MimeExtractor struct {
+	config     ExtractorConfig
+	sniffBytes int
+}
+
+// NewMimeExtractor creates a new MIME type feature extractor
+func NewMimeExtractor() *MimeExtractor {
+	return &MimeExtractor{
+		config: ExtractorConfig{
+			Enabled:    true,
+			Weight:     1.0,
+			Parameters: make(map[string]interface{}),
+			FeatureMap: make(map[string]string),
+			Normalize:  true,
+			Vectorize:  true,
+		},
+		sniffBytes: DefaultSniffBytes,
+	}
+}
+
+// magicSignatures covers common formats http.DetectContentType reports as application/octet-stream
+var magicSignatures = []struct {
+	prefix   string
+	mimeType string
+}{
+	{"\x7fELF", "application/x-elf"},
+	{"MZ", "application/vnd.microsoft.portable-executable"},
+	{"\xcf\xfa\xed\xfe", "application/x-mach-binary"},
+	{"\xfe\xed\xfa\xcf", "application/x-mach-binary"},
+	{"SQLite format 3\x00", "application/vnd.sqlite3"},
+	{"BZh", "application/x-bzip2"},
+	{"7z\xbc\xaf\x27\x1c", "application/x-7z-compressed"},
+	{"\xfd7zXZ\x00", "application/x-xz"},
+	{"\x28\xb5\x2f\xfd", "application/zstd"},
+	{"fLaC", "audio/flac"},
+}
+
+// Name returns the name of this extractor
+func (e *MimeExtractor) Name() string {
+	return "mime"
+}
+
+// Configure sets the configuration for this extractor; "sniff_bytes" changes how much content is inspected
+func (e *MimeExtractor) Configure(cfg ExtractorConfig) error {
+	sniffBytes, err := config.Int(cfg.Parameters, "sniff_bytes", DefaultSniffBytes)
+	if err != nil {
+		return err
+	}
+	if sniffBytes <= 0 {
+		return fmt.Errorf("sniff_bytes must be positive, got %d", sniffBytes)
+	}
+	e.config = cfg
+	e.sniffBytes = sniffBytes
+	log.Debug().Msgf("MimeExtractor configured with enabled=%v, weight=%f", cfg.Enabled, cfg.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *MimeExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract detects the media type and charset of a single document
+func (e *MimeExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	sample, err := e.sample(doc)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, charset := DetectMimeType(sample)
+	category := mimeType
+	if slash := strings.IndexByte(mimeType, '/'); slash >= 0 {
+		category = mimeType[:slash]
+	}
+	isBinary := charset == "binary"
+
+	features := make(map[string]Feature)
+	for name, feature := range map[string]Feature{
+		"mime_type":     {Value: mimeType, Type: "string"},
+		"mime_category": {Value: category, Type: "string"},
+		"charset":       {Value: charset, Type: "string"},
+		"is_binary":     {Value: isBinary, Type: "boolean"},
+	} {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature.Name = name
+		feature.Weight = e.config.Weight
+		features[name] = feature
+	}
+
+	var vector []float64
+	if e.config.Vectorize {
+		vector = []float64{0.0}
+		if isBinary {
+			vector[0] = e.config.Weight
+		}
+	}
+
+	log.Debug().Msgf("Detected %s (%s) for document %s", mimeType, charset, doc.ID)
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// sample returns the leading bytes of the document's file, or of its text when there is no readable file
+func (e *MimeExtractor) sample(doc models.Document) ([]byte, error) {
+	if doc.Source != "" {
+		file, err := os.Open(doc.Source)
+		if err == nil {
+			defer file.Close()
+			info, err := file.Stat()
+			if err == nil && info.Mode().IsRegular() {
+				buf := make([]byte, e.sniffBytes)
+				n, err := io.ReadFull(file, buf)
+				if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+					return nil, fmt.Errorf("failed to read %s: %w", doc.Source, err)
+				}
+				return buf[:n], nil
+			}
+		}
+	}
+	text := doc.Text
+	if len(text) > e.sniffBytes {
+		text = text[:e.sniffBytes]
+	}
+	return []byte(text), nil
+}
+
+// DetectMimeType returns the media type (without parameters) and charset of a content sample.
+// The charset is "binary" for non-text content, "utf-16le"/"utf-16be" for text with a byte order mark,
+// "utf-8" for valid UTF-8 and "windows-1252" for other 8-bit text.
+func DetectMimeType(sample []byte) (mimeType, charset string) {
+	detected := http.DetectContentType(sample)
+	mimeType, params, err := mime.ParseMediaType(detected)
+	if err != nil {
+		mimeType = detected
+	}
+
+	if mimeType == "application/octet-stream" {
+		for _, signature := range magicSignatures {
+			if bytes.HasPrefix(sample, []byte(signature.prefix)) {
+				mimeType = signature.mimeType
+				break
+			}
+		}
+	}
+
+	switch {
+	case params["charset"] == "utf-16le" || params["charset"] == "utf-16be":
+		charset = params["charset"]
+	case !strings.HasPrefix(mimeType, "text/") || hasBinaryBytes(sample):
+		charset = "binary"
+	case validUTF8Prefix(sample):
+		charset = "utf-8"
+	default:
+		charset = "windows-1252"
+	}
+	return mimeType, charset
+}
+
+// hasBinaryBytes reports control bytes that never occur in text, as defined by the WHATWG sniffing spec
+func hasBinaryBytes(sample []byte) bool {
+	for _, b := range sample {
+		if b <= 0x08 || b == 0x0B || (b >= 0x0E && b <= 0x1A) || (b >= 0x1C && b <= 0x1F) {
+			return true
+		}
+	}
+	return false
+}
+
+// validUTF8Prefix checks a sample that may end in the middle of a multi-byte character
+func validUTF8Prefix(sample []byte) bool {
+	for i := len(sample) - 1; i >= 0 && i >= len(sample)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(sample[i]) {
+			if !utf8.FullRune(sample[i:]) {
+				sample = sample[:i] // Drop the character cut off by the sample size
+			}
+			break
+		}
+	}
+	return utf8.Valid(sample)
+}
+
+// ExtractBatch detects media types for multiple documents
+func (e *MimeExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted mime features from %d documents", len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *MimeExtractor) GetSupportedFeatures() []string {
+	return []string{"mime_type", "mime_category", "charset", "is_binary"}
+}
+
+// Validate checks if the extractor is properly configured
+func (e *MimeExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMimeType(t *testing.T) {
+	tests := []struct {
+		name, sample, mimeType, charset string
+	}{
+		{"plain text", "hello world\n", "text/plain", "utf-8"},
+		{"utf-8 text", "héllo wörld", "text/plain", "utf-8"},
+		{"latin-1 text", "h\xe9llo w\xf6rld", "text/plain", "windows-1252"},
+		{"truncated utf-8", "abc\xe2\x82", "text/plain", "utf-8"},
+		{"utf-16 bom", "\xff\xfeh\x00i\x00", "text/plain", "utf-16le"},
+		{"html", "<!DOCTYPE html><html></html>", "text/html", "utf-8"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png", "binary"},
+		{"pdf", "%PDF-1.7\n", "application/pdf", "binary"},
+		{"elf", "\x7fELF\x02\x01\x01\x00", "application/x-elf", "binary"},
+		{"sqlite", "SQLite format 3\x00\x10\x00", "application/vnd.sqlite3", "binary"},
+		{"unknown binary", "\x00\x01\x02\x03", "application/octet-stream", "binary"},
+	}
+	for _, tt := range tests {
+		mimeType, charset := DetectMimeType([]byte(tt.sample))
+		assert.Equal(t, tt.mimeType, mimeType, tt.name)
+		assert.Equal(t, tt.charset, charset, tt.name)
+	}
+}
+
+func TestMimeExtractor_SniffsFileContent(t *testing.T) {
+	// A PNG named .txt is still detected as an image
+	path := filepath.Join(t.TempDir(), "image.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644))
+
+	featureSet, err := NewMimeExtractor().Extract(models.Document{ID: "doc-1", Source: path, Text: "ignored"})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", featureSet.Features["mime_type"].Value)
+	assert.Equal(t, "image", featureSet.Features["mime_category"].Value)
+	assert.Equal(t, true, featureSet.Features["is_binary"].Value)
+	assert.Equal(t, "binary", featureSet.Features["charset"].Value)
+	assert.Equal(t, []float64{1.0}, featureSet.Vector)
+}
+
+func TestMimeExtractor_FallsBackToText(t *testing.T) {
+	featureSet, err := NewMimeExtractor().Extract(models.Document{ID: "mail-1", Source: "imap://inbox/1", Text: "Subject: hi\n\nhello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", featureSet.Features["mime_type"].Value)
+	assert.Equal(t, false, featureSet.Features["is_binary"].Value)
+	assert.Equal(t, "utf-8", featureSet.Features["charset"].Value)
+}
+
+func TestMimeExtractor_Configure(t *testing.T) {
+	extractor := NewMimeExtractor()
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("sniff_bytes", 0).Build()))
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("sniff_bytes", 4).Build()))
+
+	// Only the first 4 bytes are inspected, so the later NUL byte is not seen
+	featureSet, err := extractor.Extract(models.Document{Text: "text\x00binary"})
+	assert.NoError(t, err)
+	assert.Equal(t, false, featureSet.Features["is_binary"].Value)
+}
@@ -0,0 +1,206 @@
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Normalization methods supported by Normalizer
+const (
+	NormalizeZScore = "zscore" // (v - mean) / std
+	NormalizeMinMax = "minmax" // (v - min) / (max - min)
+)
+
+// Normalizer scales vectors with per-dimension statistics computed over a corpus, so the same scaling
+// is applied to documents at index time and to query vectors at search time. Statistics are fitted once
+// (usually on the first batch an extractor sees) and can be persisted with Save; dimensions without
+// spread are only shifted. It is safe for concurrent use.
+type Normalizer struct {
+	mu     sync.RWMutex
+	method string
+	stats  normalizerStats
+}
+
+// normalizerStats is the persisted form of a Normalizer; Mean and M2 are Welford's running moments
+type normalizerStats struct {
+	Method string    `json:"method"`
+	Count  int       `json:"count"`
+	Mean   []float64 `json:"mean"`
+	M2     []float64 `json:"m2"`
+	Min    []float64 `json:"min"`
+	Max    []float64 `json:"max"`
+}
+
+// NewNormalizer creates an unfitted normalizer using method (NormalizeZScore or NormalizeMinMax)
+func NewNormalizer(method string) (*Normalizer, error) {
+	if err := validNormalization(method); err != nil {
+		return nil, err
+	}
+	return &Normalizer{method: method, stats: normalizerStats{Method: method}}, nil
+}
+
+func validNormalization(method string) error {
+	if method != NormalizeZScore && method != NormalizeMinMax {
+		return fmt.Errorf("unknown normalization %s (known normalizations: %s, %s)", method, NormalizeMinMax, NormalizeZScore)
+	}
+	return nil
+}
+
+// Method returns the normalization method
+func (n *Normalizer) Method() string {
+	return n.method
+}
+
+// Fitted reports whether statistics have been computed
+func (n *Normalizer) Fitted() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.stats.Count > 0
+}
+
+// Dimensions returns the number of dimensions the statistics cover
+func (n *Normalizer) Dimensions() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.stats.Mean)
+}
+
+// Version identifies the fitted statistics, so vectors normalized with different statistics can be told
+// apart; it is empty until the normalizer is fitted
+func (n *Normalizer) Version() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.stats.Count == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(n.stats)
+	return digest(string(data))
+}
+
+// Fit replaces the statistics with those of vectors; vectors shorter than the longest are zero-padded
+func (n *Normalizer) Fit(vectors [][]float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats = normalizerStats{Method: n.method}
+	for _, vector := range vectors {
+		n.observe(vector)
+	}
+}
+
+// fitOnce fits the statistics on vectors unless they have been fitted already, and reports whether it did
+func (n *Normalizer) fitOnce(vectors [][]float64) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stats.Count > 0 {
+		return false
+	}
+	for _, vector := range vectors {
+		n.observe(vector)
+	}
+	return n.stats.Count > 0
+}
+
+// Observe adds one vector to the statistics
+func (n *Normalizer) Observe(vector []float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.observe(vector)
+}
+
+func (n *Normalizer) observe(vector []float64) {
+	s := &n.stats
+	for len(s.Mean) < len(vector) {
+		// A dimension seen for the first time was zero in every earlier vector
+		s.Mean = append(s.Mean, 0)
+		s.M2 = append(s.M2, 0)
+		s.Min = append(s.Min, 0)
+		s.Max = append(s.Max, 0)
+		if s.Count == 0 {
+			s.Min[len(s.Min)-1] = math.Inf(1)
+			s.Max[len(s.Max)-1] = math.Inf(-1)
+		}
+	}
+	s.Count++
+	for i := range s.Mean {
+		v := 0.0
+		if i < len(vector) {
+			v = vector[i]
+		}
+		delta := v - s.Mean[i]
+		s.Mean[i] += delta / float64(s.Count)
+		s.M2[i] += delta * (v - s.Mean[i])
+		s.Min[i] = math.Min(s.Min[i], v)
+		s.Max[i] = math.Max(s.Max[i], v)
+	}
+}
+
+// Transform returns a scaled copy of vector. Dimensions beyond the fitted ones, and every dimension of
+// an unfitted normalizer, are copied unchanged.
+func (n *Normalizer) Transform(vector []float64) []float64 {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	scaled := make([]float64, len(vector))
+	copy(scaled, vector)
+	if n.stats.Count == 0 {
+		return scaled
+	}
+	for i := 0; i < len(scaled) && i < len(n.stats.Mean); i++ {
+		switch n.method {
+		case NormalizeZScore:
+			scaled[i] -= n.stats.Mean[i]
+			if std := math.Sqrt(n.stats.M2[i] / float64(n.stats.Count)); std > 0 {
+				scaled[i] /= std
+			}
+		case NormalizeMinMax:
+			scaled[i] -= n.stats.Min[i]
+			if spread := n.stats.Max[i] - n.stats.Min[i]; spread > 0 {
+				scaled[i] /= spread
+			}
+		}
+	}
+	return scaled
+}
+
+// Save writes the statistics to a JSON file, replacing it atomically
+func (n *Normalizer) Save(path string) error {
+	n.mu.RLock()
+	data, err := json.Marshal(n.stats)
+	n.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadNormalizer reads statistics written by Save
+func LoadNormalizer(path string) (*Normalizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var stats normalizerStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("invalid normalizer file %s: %w", path, err)
+	}
+	if err := validNormalization(stats.Method); err != nil {
+		return nil, fmt.Errorf("invalid normalizer file %s: %w", path, err)
+	}
+	dims := len(stats.Mean)
+	if len(stats.M2) != dims || len(stats.Min) != dims || len(stats.Max) != dims {
+		return nil, fmt.Errorf("invalid normalizer file %s: statistics cover different numbers of dimensions", path)
+	}
+	return &Normalizer{method: stats.Method, stats: stats}, nil
+}
@@ -0,0 +1,99 @@
+package features
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizer(t *testing.T) {
+	vectors := [][]float64{{1, 10, 5}, {3, 30, 5}, {5}}
+
+	zscore, err := NewNormalizer(NormalizeZScore)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 2}, zscore.Transform([]float64{1, 2}), "unfitted normalizers copy vectors")
+	zscore.Fit(vectors)
+	assert.True(t, zscore.Fitted())
+	assert.Equal(t, 3, zscore.Dimensions())
+	// Means 3, 40/3, 10/3; the third vector counts as zero in the dimensions it lacks
+	scaled := zscore.Transform([]float64{3, 40.0 / 3, 10.0 / 3, 9})
+	assert.InDeltaSlice(t, []float64{0, 0, 0, 9}, scaled, 1e-9)
+	assert.InDelta(t, 1.2247, zscore.Transform([]float64{5})[0], 1e-4)
+
+	minmax, err := NewNormalizer(NormalizeMinMax)
+	assert.NoError(t, err)
+	minmax.Fit(vectors)
+	assert.InDeltaSlice(t, []float64{0.5, 1, 0, 7}, minmax.Transform([]float64{3, 30, 0, 7}), 1e-9)
+	assert.InDeltaSlice(t, []float64{1.5}, minmax.Transform([]float64{7}), 1e-9, "query values may leave the fitted range")
+
+	constant, _ := NewNormalizer(NormalizeMinMax)
+	constant.Fit([][]float64{{4}, {4}})
+	assert.Equal(t, []float64{1}, constant.Transform([]float64{5}), "dimensions without spread are only shifted")
+
+	_, err = NewNormalizer("l2")
+	assert.Error(t, err)
+}
+
+func TestNormalizer_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats", "keywords.json")
+	normalizer, _ := NewNormalizer(NormalizeZScore)
+	normalizer.Observe([]float64{1, 2})
+	normalizer.Observe([]float64{3, 6})
+	assert.NoError(t, normalizer.Save(path))
+
+	loaded, err := LoadNormalizer(path)
+	assert.NoError(t, err)
+	assert.Equal(t, NormalizeZScore, loaded.Method())
+	assert.Equal(t, normalizer.Transform([]float64{2, 5}), loaded.Transform([]float64{2, 5}))
+
+	_, err = LoadNormalizer(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestFeatureRegistry_MergeNormalizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keywords.json")
+	configs := map[string]map[string]interface{}{
+		"keywords": {"weight": 2.0, "normalization": "minmax", "normalizer_path": path},
+		"hash":     {"vector": false},
+	}
+	registry := newMergeRegistry(t, configs, "hash", "keywords")
+
+	docs := []models.Document{
+		{ID: "a", Text: "mail ops@example.com", Vector: []float64{5}},
+		{ID: "b", Text: "see https://example.com", Vector: []float64{6}},
+	}
+	assert.NoError(t, registry.Merge(docs, []string{"hash", "keywords"}))
+	// Weighted flags (0 or 2) are scaled to 0..1; loader vectors are left alone
+	assert.Equal(t, []float64{5, 1, 0, 0, 0}, docs[0].Vector)
+	assert.Equal(t, []float64{6, 0, 1, 0, 0}, docs[1].Vector)
+	assert.FileExists(t, path)
+
+	// Later batches reuse the fitted statistics
+	later := []models.Document{{ID: "c", Text: "ops@example.com and https://example.com"}}
+	assert.NoError(t, registry.Merge(later, []string{"hash", "keywords"}))
+	assert.Equal(t, []float64{1, 1, 0, 0}, later[0].Vector)
+
+	query, err := registry.NormalizeQuery([]float64{9, 2, 2, 0, 0}, []string{"hash", "keywords"})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{9, 1, 1, 0, 0}, query)
+	_, err = registry.NormalizeQuery([]float64{2, 2}, []string{"keywords"})
+	assert.Error(t, err)
+
+	// A restarted registry loads the saved statistics, so queries are scaled before anything is merged
+	restarted := newMergeRegistry(t, configs, "hash", "keywords")
+	query, err = restarted.NormalizeQuery([]float64{2, 0, 0, 0}, []string{"keywords"})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 0, 0, 0}, query)
+
+	// normalize: false opts out
+	configs["keywords"]["normalize"] = false
+	unscaled := newMergeRegistry(t, configs, "keywords")
+	docs = []models.Document{{ID: "a", Text: "mail ops@example.com"}}
+	assert.NoError(t, unscaled.Merge(docs, []string{"keywords"}))
+	assert.Equal(t, []float64{2, 0, 0, 0}, docs[0].Vector)
+
+	_, err = MergeOptionsFromMap(map[string]interface{}{"normalization": "l2"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// WholeBatchExtractor is implemented by extractors whose ExtractBatch must see the whole batch, e.g. to fit
+// a vocabulary or to send provider requests in bulk. BatchExecutor runs them with a single ExtractBatch call
+// instead of spreading their documents across workers.
+type WholeBatchExtractor interface {
+	NeedsWholeBatch() bool
+}
+
+// DocumentError is the failure of an extractor on a single document
+type DocumentError struct {
+	DocumentID string
+	Err        error
+}
+
+func (e DocumentError) Error() string {
+	return fmt.Sprintf("document %s: %s", e.DocumentID, e.Err)
+}
+
+func (e DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError reports the documents of a batch an extractor failed on; the others were extracted
+type BatchError struct {
+	Extractor string
+	Total     int // Documents in the batch
+	Failures  []DocumentError
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("extractor %s failed on %d of %d documents, first: %s", e.Extractor, len(e.Failures), e.Total, e.Failures[0])
+}
+
+// Unwrap returns the per-document errors, so errors.Is(err, context.DeadlineExceeded) finds timeouts
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure
+	}
+	return errs
+}
+
+// BatchExecutor runs an extractor over a batch with a pool of workers, one document at a time each.
+//
+// An extractor's Extract cannot be interrupted, so a document that exceeds Timeout, or is still running
+// when ctx is cancelled, is abandoned: its result is discarded once it arrives. Extractors run by an
+// executor with Parallelism above 1 must be safe for concurrent use, which the built-in ones are.
+type BatchExecutor struct {
+	Parallelism int           // Concurrent documents (default: the number of CPUs)
+	Timeout     time.Duration // Per-document timeout (0: none)
+}
+
+// NewBatchExecutor creates a batch executor; parallelism <= 0 uses one worker per CPU
+func NewBatchExecutor(parallelism int, timeout time.Duration) *BatchExecutor {
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	return &BatchExecutor{Parallelism: parallelism, Timeout: timeout}
+}
+
+// Run extracts features from docs and returns the feature sets of the documents that succeeded, in
+// document order. If some documents failed the error is a *BatchError; if ctx was cancelled it is
+// ctx.Err() and the results are discarded.
+func (b *BatchExecutor) Run(ctx context.Context, extractor FeatureExtractor, docs []models.Document) ([]*FeatureSet, error) {
+	if whole, ok := extractor.(WholeBatchExtractor); ok && whole.NeedsWholeBatch() {
+		return b.runWhole(ctx, extractor, docs)
+	}
+
+	parallelism := b.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > len(docs) {
+		parallelism = len(docs)
+	}
+
+	results := make([]*FeatureSet, len(docs))
+	errs := make([]error, len(docs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = b.extract(ctx, extractor, docs[i])
+			}
+		}()
+	}
+feed:
+	for i := range docs {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var featureSets []*FeatureSet
+	batchErr := &BatchError{Extractor: extractor.Name(), Total: len(docs)}
+	for i, doc := range docs {
+		if errs[i] != nil {
+			batchErr.Failures = append(batchErr.Failures, DocumentError{DocumentID: doc.ID, Err: errs[i]})
+			continue
+		}
+		featureSets = append(featureSets, results[i])
+	}
+	log.Info().Msgf("Extracted %s features from %d documents with %d workers", extractor.Name(), len(featureSets), parallelism)
+	if len(batchErr.Failures) > 0 {
+		return featureSets, batchErr
+	}
+	return featureSets, nil
+}
+
+// extract runs Extract on one document, giving up when the timeout expires or ctx is cancelled
+func (b *BatchExecutor) extract(ctx context.Context, extractor FeatureExtractor, doc models.Document) (*FeatureSet, error) {
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		featureSet *FeatureSet
+		err        error
+	}
+	done := make(chan result, 1) // Buffered so an abandoned extraction does not block forever
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("extractor panicked: %v", r)}
+			}
+		}()
+		featureSet, err := extractor.Extract(doc)
+		if err == nil && featureSet == nil {
+			err = errors.New("extractor returned no feature set")
+		}
+		done <- result{featureSet, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.featureSet, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runWhole runs ExtractBatch in one call, bounded by ctx only
+func (b *BatchExecutor) runWhole(ctx context.Context, extractor FeatureExtractor, docs []models.Document) ([]*FeatureSet, error) {
+	type result struct {
+		featureSets []*FeatureSet
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		featureSets, err := extractor.ExtractBatch(docs)
+		done <- result{featureSets, err}
+	}()
+	select {
+	case r := <-done:
+		return r.featureSets, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
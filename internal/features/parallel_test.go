@@ -0,0 +1,114 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowExtractor sleeps per document, fails documents with ID "bad" and records its peak concurrency
+type slowExtractor struct {
+	*ContentExtractor
+	delay   map[string]time.Duration
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (e *slowExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	running := e.running.Add(1)
+	defer e.running.Add(-1)
+	for {
+		peak := e.peak.Load()
+		if running <= peak || e.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	time.Sleep(e.delay[doc.ID])
+	if doc.ID == "bad" {
+		return nil, errors.New("unreadable")
+	}
+	return &FeatureSet{DocumentID: doc.ID, Features: map[string]Feature{}, Vector: []float64{1}}, nil
+}
+
+func testDocs(ids ...string) []models.Document {
+	docs := make([]models.Document, len(ids))
+	for i, id := range ids {
+		docs[i] = models.Document{ID: id, Text: "text of " + id}
+	}
+	return docs
+}
+
+func TestBatchExecutor_Run(t *testing.T) {
+	extractor := &slowExtractor{ContentExtractor: NewContentExtractor(), delay: map[string]time.Duration{
+		"a": 20 * time.Millisecond, "b": 20 * time.Millisecond, "c": 20 * time.Millisecond, "d": 20 * time.Millisecond,
+	}}
+
+	featureSets, err := NewBatchExecutor(2, 0).Run(context.Background(), extractor, testDocs("a", "bad", "b", "c", "d"))
+	assert.Len(t, featureSets, 4)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, []string{
+		featureSets[0].DocumentID, featureSets[1].DocumentID, featureSets[2].DocumentID, featureSets[3].DocumentID,
+	}, "results keep document order")
+	assert.Equal(t, int32(2), extractor.peak.Load())
+
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 5, batchErr.Total)
+	assert.Len(t, batchErr.Failures, 1)
+	assert.Equal(t, "bad", batchErr.Failures[0].DocumentID)
+	assert.Contains(t, err.Error(), "failed on 1 of 5 documents")
+}
+
+func TestBatchExecutor_Timeout(t *testing.T) {
+	extractor := &slowExtractor{ContentExtractor: NewContentExtractor(), delay: map[string]time.Duration{"slow": time.Second}}
+
+	started := time.Now()
+	featureSets, err := NewBatchExecutor(4, 20*time.Millisecond).Run(context.Background(), extractor, testDocs("a", "slow", "b"))
+	assert.Less(t, time.Since(started), 500*time.Millisecond, "slow documents are abandoned")
+	assert.Len(t, featureSets, 2)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestBatchExecutor_Cancel(t *testing.T) {
+	extractor := &slowExtractor{ContentExtractor: NewContentExtractor(), delay: map[string]time.Duration{
+		"a": time.Second, "b": time.Second, "c": time.Second,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	featureSets, err := NewBatchExecutor(1, 0).Run(ctx, extractor, testDocs("a", "b", "c"))
+	assert.Nil(t, featureSets)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBatchExecutor_WholeBatch(t *testing.T) {
+	tfidf := NewTFIDFExtractor()
+	featureSets, err := NewBatchExecutor(4, time.Second).Run(context.Background(), tfidf, testDocs("a", "b"))
+	assert.NoError(t, err)
+	assert.Len(t, featureSets, 2)
+	assert.NotNil(t, tfidf.Vocabulary(), "the vocabulary is fitted on the batch")
+}
+
+func TestFeatureRegistry_MergeParallel(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"keywords": {"parallelism": 3, "timeout": "1s"},
+	}, "keywords")
+
+	docs := testDocs("a", "b", "c", "d")
+	docs[2].Text = "mail ops@example.com"
+	assert.NoError(t, registry.Merge(docs, []string{"keywords"}))
+	assert.Equal(t, "true", docs[2].Meta["has_email"])
+	assert.Equal(t, []float64{1, 0, 0, 0}, docs[2].Vector)
+	assert.Equal(t, []float64{0, 0, 0, 0}, docs[3].Vector)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, registry.MergeContext(ctx, testDocs("e"), []string{"keywords"}))
+
+	_, err := MergeOptionsFromMap(map[string]interface{}{"parallelism": -1})
+	assert.Error(t, err)
+}
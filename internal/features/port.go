@@ -0,0 +1,108 @@
+package features
+
+import (
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// ExtractorPort exposes a single extractor as a ports.FeatureExtractorPort, so it can be registered with
+// EngineCore.RegisterFeatureExtractor and run by the engine pipeline. Features are returned as the
+// strings FeatureRegistry.Merge stores in Document.Meta; vectors are left out.
+type ExtractorPort struct {
+	extractor FeatureExtractor
+}
+
+// NewExtractorPort wraps an extractor, which must already be configured
+func NewExtractorPort(extractor FeatureExtractor) *ExtractorPort {
+	return &ExtractorPort{extractor: extractor}
+}
+
+// ExtractFeatures extracts the features of a models.Document (or *models.Document)
+func (p *ExtractorPort) ExtractFeatures(doc interface{}) (map[string]interface{}, error) {
+	d, err := portDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	featureSet, err := p.extractor.Extract(d)
+	if err != nil {
+		return nil, fmt.Errorf("extractor %s failed: %w", p.extractor.Name(), err)
+	}
+	features := make(map[string]interface{}, len(featureSet.Features))
+	addPortFeatures(features, featureSet)
+	return features, nil
+}
+
+// RegistryPort exposes extractors of a FeatureRegistry as one ports.FeatureExtractorPort. The named
+// extractors run in order with their registry configuration; disabled or unconfigured ones are skipped
+// and, as with Merge, a later extractor's feature replaces an earlier one of the same name.
+type RegistryPort struct {
+	registry   *FeatureRegistry
+	extractors []string
+}
+
+// NewRegistryPort wraps the named extractors of a registry; without names every registered extractor
+// is used, in registration order
+func NewRegistryPort(registry *FeatureRegistry, extractorNames ...string) *RegistryPort {
+	return &RegistryPort{registry: registry, extractors: extractorNames}
+}
+
+// ExtractFeatures extracts the features of a models.Document (or *models.Document)
+func (p *RegistryPort) ExtractFeatures(doc interface{}) (map[string]interface{}, error) {
+	d, err := portDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	names := p.extractors
+	if len(names) == 0 {
+		names = p.registry.ListExtractors()
+	}
+
+	p.registry.mu.RLock()
+	defer p.registry.mu.RUnlock()
+	features := make(map[string]interface{})
+	for _, name := range names {
+		extractor, exists := p.registry.extractors[name]
+		if !exists {
+			return nil, fmt.Errorf("extractor %s not found", name)
+		}
+		if cfg, configured := p.registry.configs[name]; !configured || !cfg.Enabled {
+			continue
+		}
+		featureSet, err := extractor.Extract(d)
+		if err != nil {
+			return nil, fmt.Errorf("extractor %s failed: %w", name, err)
+		}
+		addPortFeatures(features, featureSet)
+	}
+	return features, nil
+}
+
+var (
+	_ ports.FeatureExtractorPort = (*ExtractorPort)(nil)
+	_ ports.FeatureExtractorPort = (*RegistryPort)(nil)
+)
+
+// portDocument accepts the document types the engine passes to feature extractor ports
+func portDocument(doc interface{}) (models.Document, error) {
+	switch d := doc.(type) {
+	case models.Document:
+		return d, nil
+	case *models.Document:
+		if d == nil {
+			return models.Document{}, fmt.Errorf("expected models.Document, got nil")
+		}
+		return *d, nil
+	}
+	return models.Document{}, fmt.Errorf("expected models.Document, got %T", doc)
+}
+
+// addPortFeatures renders the features of a set into a port result, like mergeMeta does for Meta
+func addPortFeatures(features map[string]interface{}, featureSet *FeatureSet) {
+	for name, feature := range featureSet.Features {
+		if value, ok := metaValue(feature); ok {
+			features[name] = value
+		}
+	}
+}
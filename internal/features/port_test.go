@@ -0,0 +1,43 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractorPort(t *testing.T) {
+	extractor := NewKeywordExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().MapFeature("keywords", "tags").Build()))
+	port := NewExtractorPort(extractor)
+
+	doc := models.Document{ID: "a", Text: "mail ops@example.com about the search engine"}
+	features, err := port.ExtractFeatures(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", features["has_email"])
+	assert.Equal(t, "ops@example.com", features["emails"])
+	assert.Contains(t, features, "tags")
+
+	features, err = port.ExtractFeatures(&doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", features["has_email"])
+
+	_, err = port.ExtractFeatures("not a document")
+	assert.Error(t, err)
+}
+
+func TestRegistryPort(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"hash":    {"algorithms": []interface{}{"md5"}},
+		"content": {"enabled": false},
+	}, "hash", "content")
+
+	features, err := NewRegistryPort(registry).ExtractFeatures(models.Document{ID: "a", Text: "Some text."})
+	assert.NoError(t, err)
+	assert.Len(t, features["md5"], 32)
+	assert.NotContains(t, features, "word_count", "disabled extractors are skipped")
+
+	_, err = NewRegistryPort(registry, "unknown").ExtractFeatures(models.Document{ID: "a"})
+	assert.Error(t, err)
+}
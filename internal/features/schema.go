@@ -0,0 +1,176 @@
+package features
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// ExtractorSchema describes what an extractor contributes to the documents merged by FeatureRegistry.Merge
+type ExtractorSchema struct {
+	Name              string   `json:"name"`
+	Features          []string `json:"features"`                     // Supported features, under their mapped names
+	Config            string   `json:"config"`                       // Digest of the configuration and merge options that shape the output
+	Normalization     string   `json:"normalization,omitempty"`      // Normalization method, if any
+	NormalizerVersion string   `json:"normalizer_version,omitempty"` // Digest of the fitted statistics; empty until fitted
+}
+
+// Schema describes the features and vector layout documents were built with: one entry per extractor,
+// in merge order (which is also the order of their blocks in Document.Vector)
+type Schema struct {
+	Extractors []ExtractorSchema `json:"extractors"`
+}
+
+// Schema returns the schema of documents merged with the named extractors; disabled and unconfigured
+// extractors contribute nothing, as in Merge
+func (r *FeatureRegistry) Schema(extractorNames []string) Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schema := Schema{Extractors: []ExtractorSchema{}}
+	for _, name := range extractorNames {
+		extractor, exists := r.extractors[name]
+		cfg, configured := r.configs[name]
+		if !exists || !configured || !cfg.Enabled {
+			continue
+		}
+		options, ok := r.merge[name]
+		if !ok {
+			options = DefaultMergeOptions
+		}
+
+		features := extractor.GetSupportedFeatures()
+		mapped := make([]string, len(features))
+		for i, feature := range features {
+			mapped[i] = feature
+			if output, ok := cfg.FeatureMap[feature]; ok {
+				mapped[i] = output
+			}
+		}
+		entry := ExtractorSchema{
+			Name:     name,
+			Features: mapped,
+			// Execution settings (parallelism, timeout, cache) do not change what is merged
+			Config: digest(fmt.Sprintf("%v|meta=%v|vector=%v|normalization=%s", cfg, options.Meta, options.Vector, options.Normalization)),
+		}
+		if normalizer, ok := r.normalizers[name]; ok && cfg.Normalize && options.Vector {
+			entry.Normalization = normalizer.Method()
+			entry.NormalizerVersion = normalizer.Version()
+		}
+		schema.Extractors = append(schema.Extractors, entry)
+	}
+	return schema
+}
+
+// Diff lists the differences that make documents built with schema s incompatible with current,
+// e.g. "extractor keywords: configuration changed"; it is empty when they match
+func (s Schema) Diff(current Schema) []string {
+	var diffs []string
+	previous := make(map[string]ExtractorSchema, len(s.Extractors))
+	for _, entry := range s.Extractors {
+		previous[entry.Name] = entry
+	}
+	seen := make(map[string]bool, len(current.Extractors))
+	for _, entry := range current.Extractors {
+		seen[entry.Name] = true
+		old, exists := previous[entry.Name]
+		switch {
+		case !exists:
+			diffs = append(diffs, fmt.Sprintf("extractor %s added", entry.Name))
+			continue
+		case !reflect.DeepEqual(old.Features, entry.Features):
+			diffs = append(diffs, fmt.Sprintf("extractor %s: features changed", entry.Name))
+		case old.Config != entry.Config:
+			diffs = append(diffs, fmt.Sprintf("extractor %s: configuration changed", entry.Name))
+		}
+		if old.Normalization != entry.Normalization || old.NormalizerVersion != entry.NormalizerVersion {
+			diffs = append(diffs, fmt.Sprintf("extractor %s: normalizer changed", entry.Name))
+		}
+	}
+	for _, entry := range s.Extractors {
+		if !seen[entry.Name] {
+			diffs = append(diffs, fmt.Sprintf("extractor %s removed", entry.Name))
+		}
+	}
+	if len(diffs) == 0 && !sameOrder(s, current) {
+		diffs = append(diffs, "extractors reordered")
+	}
+	return diffs
+}
+
+func sameOrder(a, b Schema) bool {
+	if len(a.Extractors) != len(b.Extractors) {
+		return false
+	}
+	for i := range a.Extractors {
+		if a.Extractors[i].Name != b.Extractors[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SchemaStore persists the schema each loader's documents were indexed with, per index, in a JSON file.
+// It is safe for concurrent use.
+type SchemaStore struct {
+	mu      sync.Mutex
+	path    string
+	Indexes map[string]map[string]Schema `json:"indexes"` // Index -> loader -> schema
+}
+
+// OpenSchemaStore reads the schema store at path; a missing file yields an empty store
+func OpenSchemaStore(path string) (*SchemaStore, error) {
+	store := &SchemaStore{path: path, Indexes: make(map[string]map[string]Schema)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	if store.Indexes == nil {
+		store.Indexes = make(map[string]map[string]Schema)
+	}
+	return store, nil
+}
+
+// Get returns the schema recorded for the documents a loader put into an index
+func (s *SchemaStore) Get(indexName, loaderName string) (Schema, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schema, ok := s.Indexes[indexName][loaderName]
+	return schema, ok
+}
+
+// Record replaces the schemas recorded for an index (keyed by loader) and saves the store
+func (s *SchemaStore) Record(indexName string, schemas map[string]Schema) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Indexes[indexName] = schemas
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
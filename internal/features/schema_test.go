@@ -0,0 +1,50 @@
+package features
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureRegistry_Schema(t *testing.T) {
+	registry := newMergeRegistry(t, map[string]map[string]interface{}{
+		"keywords": {"feature_map": map[string]interface{}{"keywords": "tags"}},
+		"content":  {"normalization": NormalizeZScore},
+	}, "keywords", "content")
+
+	schema := registry.Schema([]string{"keywords", "content", "unknown"})
+	assert.Len(t, schema.Extractors, 2)
+	assert.Equal(t, "keywords", schema.Extractors[0].Name)
+	assert.Contains(t, schema.Extractors[0].Features, "tags")
+	assert.Equal(t, NormalizeZScore, schema.Extractors[1].Normalization)
+	assert.Empty(t, schema.Extractors[1].NormalizerVersion, "not fitted yet")
+	assert.Empty(t, schema.Diff(registry.Schema([]string{"keywords", "content"})))
+
+	assert.NoError(t, registry.Merge(testDocs("a", "b"), []string{"content"}))
+	assert.Equal(t, []string{"extractor content: normalizer changed"}, schema.Diff(registry.Schema([]string{"keywords", "content"})))
+
+	reordered := Schema{Extractors: []ExtractorSchema{schema.Extractors[1], schema.Extractors[0]}}
+	assert.Equal(t, []string{"extractors reordered"}, schema.Diff(reordered))
+	weighted := NewConfigBuilder().Weight(2).MapFeature("keywords", "tags").Build()
+	assert.NoError(t, registry.Configure("keywords", weighted))
+	assert.Equal(t, []string{"extractor keywords: configuration changed", "extractor content removed"},
+		schema.Diff(registry.Schema([]string{"keywords"})))
+}
+
+func TestSchemaStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema", "features.json")
+	store, err := OpenSchemaStore(path)
+	assert.NoError(t, err)
+	_, ok := store.Get("docs", "fs")
+	assert.False(t, ok)
+
+	schema := Schema{Extractors: []ExtractorSchema{{Name: "keywords", Features: []string{"keywords"}, Config: "abc"}}}
+	assert.NoError(t, store.Record("docs", map[string]Schema{"fs": schema}))
+
+	reopened, err := OpenSchemaStore(path)
+	assert.NoError(t, err)
+	recorded, ok := reopened.Get("docs", "fs")
+	assert.True(t, ok)
+	assert.Equal(t, schema, recorded)
+}
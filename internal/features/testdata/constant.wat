@@ -0,0 +1,11 @@
+;; Test fixture for WasmExtractor; constant.wasm is this module assembled
+(module
+  (memory (export "memory") 1)
+  (global $next (mut i32) (i32.const 1024))
+  (func (export "alloc") (param $size i32) (result i32)
+    global.get $next
+    (global.set $next (i32.add (global.get $next) (local.get $size))))
+  (func (export "extract") (param $ptr i32) (param $len i32) (result i64)
+    ;; Returns the JSON object stored at offset 16
+    (i64.const 0x1000000046))
+  (data (i32.const 16) "{\"language\":\"en\",\"score\":0.5,\"tags\":[1,2],\"spam\":false,\"missing\":null}"))
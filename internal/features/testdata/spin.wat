@@ -0,0 +1,11 @@
+;; Test fixture for WasmExtractor; spin.wasm is this module assembled
+(module
+  (memory (export "memory") 1)
+  (global $next (mut i32) (i32.const 1024))
+  (func (export "alloc") (param $size i32) (result i32)
+    global.get $next
+    (global.set $next (i32.add (global.get $next) (local.get $size))))
+  (func (export "extract") (param $ptr i32) (param $len i32) (result i64)
+    ;; Never returns; the host must stop it with a timeout
+    (loop $forever (br $forever))
+    unreachable))
@@ -0,0 +1,9 @@
+;; Test fixture for WasmExtractor; trap.wasm is this module assembled
+(module
+  (memory (export "memory") 1)
+  (global $next (mut i32) (i32.const 1024))
+  (func (export "alloc") (param $size i32) (result i32)
+    global.get $next
+    (global.set $next (i32.add (global.get $next) (local.get $size))))
+  (func (export "extract") (param $ptr i32) (param $len i32) (result i64)
+    unreachable))
@@ -0,0 +1,387 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/index"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTFIDFMaxFeatures caps the vocabulary size unless "max_features" is configured
+const DefaultTFIDFMaxFeatures = 1000
+
+// ErrNoVocabulary is returned when a document is transformed before any vocabulary was built or loaded
+var ErrNoVocabulary = errors.New("tfidf vocabulary not built: fit a batch or load a saved vocabulary first")
+
+// SparseVector holds the non-zero components of a vector, by ascending index
+type SparseVector struct {
+	Indices []int     `json:"indices"`
+	Values  []float64 `json:"values"`
+}
+
+// Vocabulary maps terms to vector dimensions and their inverse document frequencies
+type Vocabulary struct {
+	Terms     []string  `json:"terms"`     // Term of each dimension, sorted
+	IDF       []float64 `json:"idf"`       // Smoothed IDF of each dimension
+	Documents int       `json:"documents"` // Number of documents the vocabulary was fitted on
+	positions map[string]int
+}
+
+func (v *Vocabulary) index() {
+	v.positions = make(map[string]int, len(v.Terms))
+	for i, term := range v.Terms {
+		v.positions[term] = i
+	}
+}
+
+// TFIDFExtractor vectorizes document text against a vocabulary shared by the whole corpus.
+// The vocabulary is fitted on the first batch (or every batch with "refit"), optionally persisted,
+// and then used to transform single documents so incremental updates stay consistent.
+//
+// Parameters: "analyzer", "stopwords" and "min_token_length" (as for inverted indexes),
+// "max_features", "min_df" (minimum document frequency), "sublinear_tf", "output"
+// ("dense", "sparse" or "both"), "refit" and "vocabulary_path".
+type TFIDFExtractor struct {
+	config      ExtractorConfig
+	analyzer    *index.Analyzer
+	maxFeatures int
+	minDF       int
+	sublinearTF bool
+	output      string
+	refit       bool
+	path        string
+
+	mu         sync.RWMutex
+	vocabulary *Vocabulary
+}
+
+// NewTFIDFExtractor creates a new TF-IDF extractor with the standard analyzer and no vocabulary
+func NewTFIDFExtractor() *TFIDFExtractor {
+	e := &TFIDFExtractor{}
+	// The default configuration has no parameters, so it cannot fail
+	_ = e.Configure(NewConfigBuilder().Build())
+	return e
+}
+
+// Name returns the name of this extractor
+func (e *TFIDFExtractor) Name() string {
+	return "tfidf"
+}
+
+// Configure sets the configuration for this extractor, loading the vocabulary from "vocabulary_path" if it exists
+func (e *TFIDFExtractor) Configure(cfg ExtractorConfig) error {
+	params := cfg.Parameters
+	analyzer, err := index.NewAnalyzerFromConfig(params)
+	if err != nil {
+		return err
+	}
+	maxFeatures, err := config.Int(params, "max_features", DefaultTFIDFMaxFeatures)
+	if err != nil {
+		return err
+	}
+	minDF, err := config.Int(params, "min_df", 1)
+	if err != nil {
+		return err
+	}
+	sublinearTF, err := config.Bool(params, "sublinear_tf", false)
+	if err != nil {
+		return err
+	}
+	output, err := config.String(params, "output", "dense")
+	if err != nil {
+		return err
+	}
+	if output != "dense" && output != "sparse" && output != "both" {
+		return fmt.Errorf("unknown tfidf output %s (expected dense, sparse or both)", output)
+	}
+	refit, err := config.Bool(params, "refit", false)
+	if err != nil {
+		return err
+	}
+	path, err := config.String(params, "vocabulary_path", "")
+	if err != nil {
+		return err
+	}
+
+	e.config = cfg
+	e.analyzer = analyzer
+	e.maxFeatures = maxFeatures
+	e.minDF = minDF
+	e.sublinearTF = sublinearTF
+	e.output = output
+	e.refit = refit
+	e.path = path
+
+	if path != "" {
+		vocabulary, err := LoadVocabulary(path)
+		switch {
+		case err == nil:
+			e.setVocabulary(vocabulary)
+			log.Info().Msgf("TFIDFExtractor loaded a vocabulary of %d terms from %s", len(vocabulary.Terms), path)
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+	log.Debug().Msgf("TFIDFExtractor configured with enabled=%v, weight=%f, max_features=%d", cfg.Enabled, cfg.Weight, maxFeatures)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *TFIDFExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Vocabulary returns the current vocabulary, or nil if none was built
+func (e *TFIDFExtractor) Vocabulary() *Vocabulary {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.vocabulary
+}
+
+func (e *TFIDFExtractor) setVocabulary(vocabulary *Vocabulary) {
+	vocabulary.index()
+	e.mu.Lock()
+	e.vocabulary = vocabulary
+	e.mu.Unlock()
+}
+
+// Fit builds the vocabulary from a batch of documents, keeping the max_features terms with the
+// highest document frequency, and saves it to vocabulary_path when configured
+func (e *TFIDFExtractor) Fit(docs []models.Document) (*Vocabulary, error) {
+	df := make(map[string]int)
+	for _, doc := range docs {
+		for term := range e.termCounts(doc) {
+			df[term]++
+		}
+	}
+
+	terms := make([]string, 0, len(df))
+	for term, count := range df {
+		if count >= e.minDF {
+			terms = append(terms, term)
+		}
+	}
+	// Highest document frequency first, ties broken alphabetically, so the cut is deterministic
+	sort.Slice(terms, func(i, j int) bool {
+		if df[terms[i]] != df[terms[j]] {
+			return df[terms[i]] > df[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if e.maxFeatures > 0 && len(terms) > e.maxFeatures {
+		terms = terms[:e.maxFeatures]
+	}
+	sort.Strings(terms)
+
+	vocabulary := &Vocabulary{Terms: terms, IDF: make([]float64, len(terms)), Documents: len(docs)}
+	n := float64(len(docs))
+	for i, term := range terms {
+		vocabulary.IDF[i] = math.Log((1+n)/(1+float64(df[term]))) + 1
+	}
+	e.setVocabulary(vocabulary)
+
+	if e.path != "" {
+		if err := vocabulary.Save(e.path); err != nil {
+			return vocabulary, err
+		}
+	}
+	log.Info().Msgf("TFIDFExtractor fitted a vocabulary of %d terms on %d documents", len(terms), len(docs))
+	return vocabulary, nil
+}
+
+// Transform vectorizes a single document against the current vocabulary; unknown terms are ignored.
+// The vector is L2-normalized.
+func (e *TFIDFExtractor) Transform(doc models.Document) (SparseVector, error) {
+	sparse, _, err := e.transform(doc)
+	return sparse, err
+}
+
+// transform also returns the vocabulary size, so callers expanding the vector are not affected by a concurrent refit
+func (e *TFIDFExtractor) transform(doc models.Document) (SparseVector, int, error) {
+	e.mu.RLock()
+	vocabulary := e.vocabulary
+	e.mu.RUnlock()
+	if vocabulary == nil {
+		return SparseVector{}, 0, ErrNoVocabulary
+	}
+
+	weights := make(map[int]float64)
+	for term, count := range e.termCounts(doc) {
+		position, ok := vocabulary.positions[term]
+		if !ok {
+			continue
+		}
+		tf := float64(count)
+		if e.sublinearTF {
+			tf = 1 + math.Log(tf)
+		}
+		weights[position] = tf * vocabulary.IDF[position]
+	}
+
+	var norm float64
+	for _, w := range weights {
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+
+	sparse := SparseVector{Indices: make([]int, 0, len(weights)), Values: make([]float64, 0, len(weights))}
+	for position := range weights {
+		sparse.Indices = append(sparse.Indices, position)
+	}
+	sort.Ints(sparse.Indices)
+	for _, position := range sparse.Indices {
+		sparse.Values = append(sparse.Values, weights[position]/norm)
+	}
+	return sparse, len(vocabulary.Terms), nil
+}
+
+// Dense expands a sparse vector to the full vocabulary dimension
+func (v SparseVector) Dense(size int) []float64 {
+	dense := make([]float64, size)
+	for i, position := range v.Indices {
+		dense[position] = v.Values[i]
+	}
+	return dense
+}
+
+func (e *TFIDFExtractor) termCounts(doc models.Document) map[string]int {
+	counts := make(map[string]int)
+	for _, term := range e.analyzer.Analyze(doc.Text) {
+		counts[term]++
+	}
+	return counts
+}
+
+// Extract vectorizes a single document against the existing vocabulary
+func (e *TFIDFExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	sparse, size, err := e.transform(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make(map[string]Feature)
+	addFeature := func(name, featureType string, value interface{}) {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		features[name] = Feature{Name: name, Value: value, Type: featureType, Weight: e.config.Weight}
+	}
+	if e.output == "dense" || e.output == "both" {
+		addFeature("tfidf", "vector", sparse.Dense(size))
+	}
+	if e.output == "sparse" || e.output == "both" {
+		addFeature("tfidf_sparse", "sparse_vector", sparse)
+	}
+	addFeature("tfidf_terms", "number", len(sparse.Indices))
+
+	var vector []float64
+	if e.config.Vectorize {
+		vector = sparse.Dense(size)
+		for i := range vector {
+			vector[i] *= e.config.Weight
+		}
+	}
+
+	log.Debug().Msgf("Extracted tfidf features with %d matching terms from document %s", len(sparse.Indices), doc.ID)
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// ExtractBatch fits the vocabulary on the batch if there is none yet (or "refit" is set),
+// then vectorizes every document
+func (e *TFIDFExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	if e.Vocabulary() == nil || e.refit {
+		if _, err := e.Fit(docs); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []*FeatureSet
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted tfidf features from %d documents", len(results))
+	return results, nil
+}
+
+// NeedsWholeBatch reports that ExtractBatch must see the whole batch: the vocabulary is fitted on the whole batch
+func (e *TFIDFExtractor) NeedsWholeBatch() bool {
+	return true
+}
+
+// GetSupportedFeatures returns a list of feature names this extractor can produce
+func (e *TFIDFExtractor) GetSupportedFeatures() []string {
+	return []string{"tfidf", "tfidf_sparse", "tfidf_terms"}
+}
+
+// Validate checks if the extractor is properly configured
+func (e *TFIDFExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	if e.minDF < 1 {
+		return fmt.Errorf("min_df must be at least 1")
+	}
+	return nil
+}
+
+// Save writes the vocabulary to a JSON file, replacing it atomically
+func (v *Vocabulary) Save(path string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadVocabulary reads a vocabulary written by Save
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var vocabulary Vocabulary
+	if err := json.Unmarshal(data, &vocabulary); err != nil {
+		return nil, fmt.Errorf("invalid vocabulary file %s: %w", path, err)
+	}
+	if len(vocabulary.IDF) != len(vocabulary.Terms) {
+		return nil, fmt.Errorf("invalid vocabulary file %s: %d terms but %d idf values", path, len(vocabulary.Terms), len(vocabulary.IDF))
+	}
+	vocabulary.index()
+	return &vocabulary, nil
+}
@@ -0,0 +1,109 @@
+package features
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var tfidfCorpus = []models.Document{
+	{ID: "1", Text: "the quick brown fox"},
+	{ID: "2", Text: "the lazy dog"},
+	{ID: "3", Text: "the quick dog jumps"},
+}
+
+func TestTFIDFExtractor_Fit(t *testing.T) {
+	extractor := NewTFIDFExtractor()
+	vocabulary, err := extractor.Fit(tfidfCorpus)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"brown", "dog", "fox", "jumps", "lazy", "quick", "the"}, vocabulary.Terms)
+	assert.Equal(t, 3, vocabulary.Documents)
+
+	// "the" is in every document, so it gets the lowest idf
+	the, fox := vocabulary.IDF[6], vocabulary.IDF[2]
+	assert.InDelta(t, 1.0, the, 1e-9)
+	assert.Greater(t, fox, the)
+}
+
+func TestTFIDFExtractor_FitLimitsVocabulary(t *testing.T) {
+	extractor := NewTFIDFExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("max_features", 2).Build()))
+	vocabulary, err := extractor.Fit(tfidfCorpus)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dog", "the"}, vocabulary.Terms) // df 3 and 2; "quick" (df 2) loses the alphabetical tie
+
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("min_df", 2).Build()))
+	vocabulary, err = extractor.Fit(tfidfCorpus)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dog", "quick", "the"}, vocabulary.Terms)
+}
+
+func TestTFIDFExtractor_Transform(t *testing.T) {
+	extractor := NewTFIDFExtractor()
+	_, err := extractor.Transform(tfidfCorpus[0])
+	assert.ErrorIs(t, err, ErrNoVocabulary)
+
+	_, err = extractor.Fit(tfidfCorpus)
+	assert.NoError(t, err)
+	sparse, err := extractor.Transform(models.Document{Text: "quick quick cat"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, sparse.Indices) // "cat" is unknown
+	assert.InDelta(t, 1.0, sparse.Values[0], 1e-9)
+
+	sparse, err = extractor.Transform(tfidfCorpus[1])
+	assert.NoError(t, err)
+	var norm float64
+	for _, v := range sparse.Values {
+		norm += v * v
+	}
+	assert.InDelta(t, 1.0, math.Sqrt(norm), 1e-9)
+	assert.Equal(t, []float64{0, sparse.Values[0], 0, 0, sparse.Values[1], 0, sparse.Values[2]}, sparse.Dense(7))
+}
+
+func TestTFIDFExtractor_ExtractBatchFitsOnce(t *testing.T) {
+	extractor := NewTFIDFExtractor()
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("output", "both").Build()))
+
+	featureSets, err := extractor.ExtractBatch(tfidfCorpus)
+	assert.NoError(t, err)
+	assert.Len(t, featureSets, 3)
+	assert.Len(t, featureSets[0].Vector, 7)
+	assert.Equal(t, "vector", featureSets[0].Features["tfidf"].Type)
+	assert.Equal(t, "sparse_vector", featureSets[0].Features["tfidf_sparse"].Type)
+	assert.Equal(t, 4, featureSets[0].Features["tfidf_terms"].Value)
+
+	// A later batch reuses the vocabulary so vectors stay comparable
+	featureSets, err = extractor.ExtractBatch([]models.Document{{ID: "4", Text: "a brand new vocabulary"}})
+	assert.NoError(t, err)
+	assert.Len(t, featureSets[0].Vector, 7)
+	assert.Equal(t, 0, featureSets[0].Features["tfidf_terms"].Value)
+}
+
+func TestTFIDFExtractor_PersistsVocabulary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab", "tfidf.json")
+	config := NewConfigBuilder().Parameter("vocabulary_path", path).Build()
+
+	first := NewTFIDFExtractor()
+	assert.NoError(t, first.Configure(config))
+	_, err := first.Fit(tfidfCorpus)
+	assert.NoError(t, err)
+	want, err := first.Transform(tfidfCorpus[2])
+	assert.NoError(t, err)
+
+	// A new extractor (e.g. after a restart) transforms documents identically without refitting
+	second := NewTFIDFExtractor()
+	assert.NoError(t, second.Configure(config))
+	got, err := second.Transform(tfidfCorpus[2])
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestTFIDFExtractor_ConfigureRejectsBadParameters(t *testing.T) {
+	extractor := NewTFIDFExtractor()
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("output", "bogus").Build()))
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("max_features", "many").Build()))
+	assert.Error(t, extractor.Configure(NewConfigBuilder().Parameter("analyzer", "unknown").Build()))
+}
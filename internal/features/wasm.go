@@ -0,0 +1,367 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+/*
+WASM feature extractors let users supply extraction logic as a WebAssembly module instead of Go code.
+A module implements the following ABI:
+
+	memory                          the module's exported linear memory
+	alloc(size i32) -> i32          returns a buffer of size bytes the host writes the input into
+	extract(ptr i32, len i32) -> i64
+
+extract receives a UTF-8 JSON object {"document": {...}, "parameters": {...}} where document has the
+models.Document JSON shape and parameters are the extractor's configured parameters. It returns the
+location of a JSON object of features packed as ptr<<32 | len; 0 means no features. A trap fails the
+extraction for that document.
+
+Every document is processed by a fresh module instance, so no state leaks between documents.
+Modules have no filesystem, network, environment or clock access beyond WASI's stubs, memory is capped
+and each call is bounded by a timeout. WASI preview 1 is available for toolchains that need it
+(TinyGo, Rust wasm32-wasi); such modules must be built as reactors, since _start is not called.
+*/
+
+const (
+	// WasmMemoryLimitPages caps a module's memory (64 KiB pages, 16 MiB in total)
+	WasmMemoryLimitPages = 256
+	// WasmExtractTimeout bounds the processing of a single document
+	WasmExtractTimeout = 5 * time.Second
+)
+
+// WasmExtractor runs a WebAssembly module as a feature extractor
+type WasmExtractor struct {
+	name     string
+	config   ExtractorConfig
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	Timeout  time.Duration // Per-document timeout (default: WasmExtractTimeout)
+}
+
+// NewWasmExtractor compiles a WebAssembly module into a feature extractor.
+// The module is validated against the ABI up front so broken modules are rejected at load time.
+func NewWasmExtractor(name string, module []byte) (*WasmExtractor, error) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(WasmMemoryLimitPages).
+		WithCloseOnContextDone(true))
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to provide WASI to extractor %s: %w", name, err)
+	}
+	compiled, err := runtime.CompileModule(ctx, module)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile extractor %s: %w", name, err)
+	}
+	if err := checkWasmABI(compiled); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("extractor %s: %w", name, err)
+	}
+
+	return &WasmExtractor{
+		name:     name,
+		config:   NewConfigBuilder().Build(),
+		runtime:  runtime,
+		compiled: compiled,
+		Timeout:  WasmExtractTimeout,
+	}, nil
+}
+
+// LoadWasmExtractor compiles a .wasm file into a feature extractor named after the file
+func LoadWasmExtractor(path string) (*WasmExtractor, error) {
+	module, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWasmExtractor(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), module)
+}
+
+// checkWasmABI verifies that a module exports the memory and functions the host calls
+func checkWasmABI(compiled wazero.CompiledModule) error {
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return fmt.Errorf("module does not export memory")
+	}
+	functions := compiled.ExportedFunctions()
+	signatures := map[string]struct{ params, results []api.ValueType }{
+		"alloc":   {[]api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}},
+		"extract": {[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}},
+	}
+	for name, want := range signatures {
+		fn, ok := functions[name]
+		if !ok {
+			return fmt.Errorf("module does not export %s", name)
+		}
+		if !equalValueTypes(fn.ParamTypes(), want.params) || !equalValueTypes(fn.ResultTypes(), want.results) {
+			return fmt.Errorf("exported function %s has the wrong signature", name)
+		}
+	}
+	return nil
+}
+
+func equalValueTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the name of this extractor
+func (e *WasmExtractor) Name() string {
+	return e.name
+}
+
+// Configure sets the configuration for this extractor; Parameters are passed to the module
+func (e *WasmExtractor) Configure(config ExtractorConfig) error {
+	e.config = config
+	log.Debug().Msgf("WasmExtractor %s configured with enabled=%v, weight=%f", e.name, config.Enabled, config.Weight)
+	return nil
+}
+
+// GetConfig returns the current configuration
+func (e *WasmExtractor) GetConfig() ExtractorConfig {
+	return e.config
+}
+
+// Extract runs the module over a single document in a fresh instance
+func (e *WasmExtractor) Extract(doc models.Document) (*FeatureSet, error) {
+	if !e.config.Enabled {
+		return &FeatureSet{
+			DocumentID: doc.ID,
+			Features:   make(map[string]Feature),
+			Vector:     []float64{},
+		}, nil
+	}
+
+	raw, err := e.run(doc)
+	if err != nil {
+		return nil, fmt.Errorf("extractor %s failed on document %s: %w", e.name, doc.ID, err)
+	}
+
+	features := make(map[string]Feature, len(raw))
+	for name, value := range raw {
+		if mappedName, exists := e.config.FeatureMap[name]; exists {
+			name = mappedName
+		}
+		feature, ok := wasmFeature(name, value, e.config.Weight)
+		if !ok {
+			continue
+		}
+		features[name] = feature
+	}
+
+	var vector []float64
+	if e.config.Vectorize {
+		vector = e.generateVector(features)
+	}
+
+	log.Debug().Msgf("Extracted %d features from document %s with %s", len(features), doc.ID, e.name)
+	return &FeatureSet{
+		DocumentID: doc.ID,
+		Features:   features,
+		Vector:     vector,
+	}, nil
+}
+
+// run instantiates the module, hands it the document and decodes the features it returns
+func (e *WasmExtractor) run(doc models.Document) (map[string]interface{}, error) {
+	input, err := json.Marshal(struct {
+		Document   models.Document        `json:"document"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}{doc, e.config.Parameters})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = WasmExtractTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// An anonymous instance per document: no shared state and no name clashes between concurrent calls
+	mod, err := e.runtime.InstantiateModule(ctx, e.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, err
+	}
+	defer mod.Close(context.Background())
+
+	allocated, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc: %w", err)
+	}
+	ptr := uint32(allocated[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("alloc returned an out of range buffer")
+	}
+
+	result, err := mod.ExportedFunction("extract").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("extract: %w", err)
+	}
+	if result[0] == 0 {
+		return nil, nil
+	}
+	outPtr, outLen := uint32(result[0]>>32), uint32(result[0])
+	output, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("extract returned an out of range result")
+	}
+
+	var features map[string]interface{}
+	if err := json.Unmarshal(output, &features); err != nil {
+		return nil, fmt.Errorf("extract did not return a JSON object: %w", err)
+	}
+	return features, nil
+}
+
+// wasmFeature types a JSON value returned by a module; null values are dropped
+func wasmFeature(name string, value interface{}, weight float64) (Feature, bool) {
+	feature := Feature{Name: name, Value: value, Weight: weight}
+	switch v := value.(type) {
+	case nil:
+		return feature, false
+	case string:
+		feature.Type = "string"
+	case float64:
+		feature.Type = "number"
+	case bool:
+		feature.Type = "boolean"
+	case []interface{}:
+		vector := make([]float64, 0, len(v))
+		for _, element := range v {
+			number, ok := element.(float64)
+			if !ok {
+				feature.Type = "json"
+				return feature, true
+			}
+			vector = append(vector, number)
+		}
+		feature.Type = "vector"
+		feature.Value = vector
+	default:
+		feature.Type = "json"
+	}
+	return feature, true
+}
+
+// ExtractBatch extracts features from multiple documents
+func (e *WasmExtractor) ExtractBatch(docs []models.Document) ([]*FeatureSet, error) {
+	var results []*FeatureSet
+
+	for _, doc := range docs {
+		featureSet, err := e.Extract(doc)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to extract features from document %s", doc.ID)
+			continue
+		}
+		results = append(results, featureSet)
+	}
+
+	log.Info().Msgf("Extracted %s features from %d documents", e.name, len(results))
+	return results, nil
+}
+
+// GetSupportedFeatures returns nil: a module's features are only known once it has run
+func (e *WasmExtractor) GetSupportedFeatures() []string {
+	return nil
+}
+
+// Validate checks if the extractor is properly configured
+func (e *WasmExtractor) Validate() error {
+	if e.config.Weight < 0 {
+		return fmt.Errorf("weight must be non-negative")
+	}
+	return nil
+}
+
+// Close releases the compiled module and its runtime
+func (e *WasmExtractor) Close() error {
+	return e.runtime.Close(context.Background())
+}
+
+// generateVector weights numeric, boolean and vector features in feature name order
+func (e *WasmExtractor) generateVector(features map[string]Feature) []float64 {
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var vector []float64
+	for _, name := range names {
+		feature := features[name]
+		switch value := feature.Value.(type) {
+		case float64:
+			vector = append(vector, value*feature.Weight)
+		case bool:
+			if value {
+				vector = append(vector, feature.Weight)
+			} else {
+				vector = append(vector, 0.0)
+			}
+		case []float64:
+			for _, v := range value {
+				vector = append(vector, v*feature.Weight)
+			}
+		}
+	}
+	return vector
+}
+
+// LoadWasmDir compiles every .wasm module in dir and registers it, enabled, under its file name.
+// A missing directory is not an error; modules that fail to load are logged and skipped.
+func (r *FeatureRegistry) LoadWasmDir(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []string
+	for _, path := range paths {
+		extractor, err := LoadWasmExtractor(path)
+		if err != nil {
+			log.Error().Msgf("Failed to load WASM extractor %s: %s", path, err)
+			continue
+		}
+		if err := r.Register(extractor); err != nil {
+			extractor.Close()
+			log.Error().Msgf("Failed to register WASM extractor %s: %s", path, err)
+			continue
+		}
+		if err := r.Configure(extractor.Name(), extractor.GetConfig()); err != nil {
+			return loaded, err
+		}
+		loaded = append(loaded, extractor.Name())
+	}
+	return loaded, nil
+}
@@ -0,0 +1,107 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestExtractor(t *testing.T, name string) *WasmExtractor {
+	extractor, err := LoadWasmExtractor(filepath.Join("testdata", name+".wasm"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { extractor.Close() })
+	return extractor
+}
+
+func TestWasmExtractor_Extract(t *testing.T) {
+	extractor := loadTestExtractor(t, "constant")
+	assert.Equal(t, "constant", extractor.Name())
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-1", featureSet.DocumentID)
+	assert.Equal(t, Feature{Name: "language", Value: "en", Type: "string", Weight: 1.0}, featureSet.Features["language"])
+	assert.Equal(t, Feature{Name: "score", Value: 0.5, Type: "number", Weight: 1.0}, featureSet.Features["score"])
+	assert.Equal(t, Feature{Name: "tags", Value: []float64{1, 2}, Type: "vector", Weight: 1.0}, featureSet.Features["tags"])
+	assert.Equal(t, "boolean", featureSet.Features["spam"].Type)
+	assert.NotContains(t, featureSet.Features, "missing")
+	// Vector components are in feature name order: score, spam, tags
+	assert.Equal(t, []float64{0.5, 0, 1, 2}, featureSet.Vector)
+}
+
+func TestWasmExtractor_PassesDocumentAndParameters(t *testing.T) {
+	extractor := loadTestExtractor(t, "echo")
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Parameter("lang", "fr").MapFeature("parameters", "params").Build()))
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1", Text: "bonjour", Source: "a.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "doc-1", "text": "bonjour", "source": "a.txt"}, featureSet.Features["document"].Value)
+	assert.Equal(t, map[string]interface{}{"lang": "fr"}, featureSet.Features["params"].Value)
+	assert.Equal(t, "json", featureSet.Features["params"].Type)
+}
+
+func TestWasmExtractor_Disabled(t *testing.T) {
+	extractor := loadTestExtractor(t, "trap")
+	assert.NoError(t, extractor.Configure(NewConfigBuilder().Enabled(false).Build()))
+
+	featureSet, err := extractor.Extract(models.Document{ID: "doc-1"})
+	assert.NoError(t, err)
+	assert.Empty(t, featureSet.Features)
+}
+
+func TestWasmExtractor_Trap(t *testing.T) {
+	extractor := loadTestExtractor(t, "trap")
+	_, err := extractor.Extract(models.Document{ID: "doc-1"})
+	assert.ErrorContains(t, err, "doc-1")
+}
+
+func TestWasmExtractor_Timeout(t *testing.T) {
+	extractor := loadTestExtractor(t, "spin")
+	extractor.Timeout = 50 * time.Millisecond
+
+	started := time.Now()
+	_, err := extractor.Extract(models.Document{ID: "doc-1"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), 5*time.Second)
+
+	// The extractor stays usable after a timed-out document
+	_, err = extractor.Extract(models.Document{ID: "doc-2"})
+	assert.Error(t, err)
+}
+
+func TestNewWasmExtractor_RejectsInvalidModules(t *testing.T) {
+	_, err := NewWasmExtractor("garbage", []byte("not wasm"))
+	assert.Error(t, err)
+
+	// A valid module without the extractor exports
+	_, err = NewWasmExtractor("empty", []byte("\x00asm\x01\x00\x00\x00"))
+	assert.ErrorContains(t, err, "does not export")
+}
+
+func TestFeatureRegistry_LoadWasmDir(t *testing.T) {
+	dir := t.TempDir()
+	module, err := os.ReadFile(filepath.Join("testdata", "constant.wasm"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "language.wasm"), module, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.wasm"), []byte("not wasm"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644))
+
+	registry := NewFeatureRegistry()
+	loaded, err := registry.LoadWasmDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"language"}, loaded)
+	assert.Equal(t, []string{"language"}, registry.GetEnabledExtractors())
+
+	featureSets, err := registry.ExtractAll(models.Document{ID: "doc-1"})
+	assert.NoError(t, err)
+	assert.Len(t, featureSets, 1)
+	assert.Equal(t, "en", featureSets[0].Features["language"].Value)
+
+	loaded, err = NewFeatureRegistry().LoadWasmDir(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+}
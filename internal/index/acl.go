@@ -0,0 +1,137 @@
+package index
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Document-level access control. Documents with AllowedPrincipals are only found by callers holding one
+ * of them. Each restricted document gets an ordinal, and each principal a bitmap of the ordinals it may
+ * see, so a search ORs the caller's bitmaps once and then tests a bit per candidate.
+ **/
+
+type principalsKey struct{}
+
+// WithPrincipals makes searches with ctx return only the documents visible to principals. Without it
+// (or with nil principals) access control is not enforced.
+func WithPrincipals(ctx context.Context, principals []string) context.Context {
+	if principals == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, principalsKey{}, principals)
+}
+
+// principalsFrom returns the principals set by WithPrincipals
+func principalsFrom(ctx context.Context) ([]string, bool) {
+	principals, ok := ctx.Value(principalsKey{}).([]string)
+	return principals, ok
+}
+
+// bitmap is a set of document ordinals
+type bitmap []uint64
+
+func (b *bitmap) set(i uint32) {
+	word := int(i / 64)
+	for len(*b) <= word {
+		*b = append(*b, 0)
+	}
+	(*b)[word] |= 1 << (i % 64)
+}
+
+func (b bitmap) clear(i uint32) {
+	if word := int(i / 64); word < len(b) {
+		b[word] &^= 1 << (i % 64)
+	}
+}
+
+func (b bitmap) has(i uint32) bool {
+	word := int(i / 64)
+	return word < len(b) && b[word]&(1<<(i%64)) != 0
+}
+
+// or adds the ordinals of other to b
+func (b *bitmap) or(other bitmap) {
+	for len(*b) < len(other) {
+		*b = append(*b, 0)
+	}
+	for i, word := range other {
+		(*b)[i] |= word
+	}
+}
+
+// aclBitmaps tracks which principals may see the restricted documents of an index. It is not safe for
+// concurrent use; the index guards it with its own lock.
+type aclBitmaps struct {
+	ordinals map[string]uint32 // Restricted document ID -> ordinal
+	free     []uint32          // Ordinals of removed documents, reused first
+	next     uint32
+	allowed  map[string]bitmap // Principal -> ordinals of the documents it may see
+}
+
+func newACLBitmaps() aclBitmaps {
+	return aclBitmaps{ordinals: make(map[string]uint32), allowed: make(map[string]bitmap)}
+}
+
+// add records the principals allowed to see doc; the previous version of doc must have been removed
+func (a *aclBitmaps) add(doc models.Document) {
+	if len(doc.AllowedPrincipals) == 0 {
+		return
+	}
+	var ordinal uint32
+	if n := len(a.free); n > 0 {
+		ordinal, a.free = a.free[n-1], a.free[:n-1]
+	} else {
+		ordinal = a.next
+		a.next++
+	}
+	a.ordinals[doc.ID] = ordinal
+	for _, principal := range doc.AllowedPrincipals {
+		b := a.allowed[principal]
+		b.set(ordinal)
+		a.allowed[principal] = b
+	}
+}
+
+// remove forgets the principals allowed to see doc
+func (a *aclBitmaps) remove(doc models.Document) {
+	ordinal, ok := a.ordinals[doc.ID]
+	if !ok {
+		return
+	}
+	delete(a.ordinals, doc.ID)
+	a.free = append(a.free, ordinal)
+	for _, principal := range doc.AllowedPrincipals {
+		a.allowed[principal].clear(ordinal)
+	}
+}
+
+// visibility returns the documents the caller of a search with ctx may see
+func (a *aclBitmaps) visibility(ctx context.Context) aclMask {
+	principals, ok := principalsFrom(ctx)
+	if !ok {
+		return aclMask{}
+	}
+	mask := aclMask{enforced: true, ordinals: a.ordinals}
+	for _, principal := range principals {
+		mask.visible.or(a.allowed[principal])
+	}
+	return mask
+}
+
+// aclMask is the visibility of the documents to one caller; the zero value sees every document
+type aclMask struct {
+	enforced bool
+	ordinals map[string]uint32
+	visible  bitmap
+}
+
+// allows reports whether the caller may see the document id
+func (m aclMask) allows(id string) bool {
+	if !m.enforced {
+		return true
+	}
+	ordinal, restricted := m.ordinals[id]
+	return !restricted || m.visible.has(ordinal)
+}
@@ -0,0 +1,98 @@
+package index
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func resultIDs(docs []models.Document) []string {
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSimpleIndex_AccessControlLists(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		{ID: "1", Text: "report"},
+		{ID: "2", Text: "report", AllowedPrincipals: []string{"alice"}},
+		{ID: "3", Text: "report", AllowedPrincipals: []string{"finance", "alice"}},
+	}))
+	search := func(principals []string) []string {
+		docs, err := idx.Search(WithPrincipals(ctx, principals), "report")
+		assert.NoError(t, err)
+		return resultIDs(docs)
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, search(nil))
+	assert.Equal(t, []string{"1"}, search([]string{}))
+	assert.Equal(t, []string{"1", "2", "3"}, search([]string{"alice"}))
+	assert.Equal(t, []string{"1", "3"}, search([]string{"bob", "finance"}))
+
+	// Updates and deletions keep the bitmaps in step, reusing the ordinals of removed documents
+	assert.NoError(t, idx.UpdateDocument(ctx, "3", models.Document{ID: "3", Text: "report", AllowedPrincipals: []string{"legal"}}))
+	assert.Equal(t, []string{"1"}, search([]string{"finance"}))
+	assert.NoError(t, idx.DeleteDocument(ctx, "2"))
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "4", Text: "report", AllowedPrincipals: []string{"finance"}}))
+	assert.Equal(t, []string{"1", "4"}, search([]string{"finance"}))
+	assert.Equal(t, []string{"1", "3"}, search([]string{"alice", "legal"}))
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "4", Text: "report"}))
+	assert.Equal(t, []string{"1", "4"}, search([]string{}))
+
+	// Dimension queries are filtered too
+	docs, err := idx.Search(WithPrincipals(ctx, []string{}), "text contains report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "4"}, resultIDs(docs))
+}
+
+func TestInvertedAndVectorIndexes_AccessControlLists(t *testing.T) {
+	ctx := context.Background()
+	docs := []models.Document{
+		{ID: "1", Text: "quarterly report", Vector: []float64{1, 0}},
+		{ID: "2", Text: "quarterly report", Vector: []float64{1, 0.1}, AllowedPrincipals: []string{"finance"}},
+	}
+	inverted := NewInvertedIndex(nil)
+	assert.NoError(t, inverted.AddDocuments(ctx, docs))
+	found, err := inverted.Search(WithPrincipals(ctx, []string{"sales"}), "report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, resultIDs(found))
+	found, err = inverted.Search(WithPrincipals(ctx, []string{"finance"}), "report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, resultIDs(found))
+
+	vector, err := NewVectorIndex("cosine", 2, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, vector.AddDocuments(ctx, docs))
+	found, err = vector.Search(WithPrincipals(ctx, []string{"sales"}), "[1, 0]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, resultIDs(found))
+}
+
+func TestBitmap(t *testing.T) {
+	var b bitmap
+	b.set(3)
+	b.set(130)
+	assert.True(t, b.has(3))
+	assert.True(t, b.has(130))
+	assert.False(t, b.has(64))
+	assert.False(t, b.has(1000))
+	b.clear(130)
+	assert.False(t, b.has(130))
+
+	var mask bitmap
+	other := bitmap{}
+	other.set(70)
+	mask.or(b)
+	mask.or(other)
+	assert.True(t, mask.has(3))
+	assert.True(t, mask.has(70))
+}
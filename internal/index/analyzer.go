@@ -0,0 +1,335 @@
+package index
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Analyzer turns text into the terms stored in (and looked up from) an inverted index. Text is normalized,
+// split into tokens, and the tokens lowercased (or case folded), stripped of accents and split into
+// bigrams or n-grams before short tokens and stopwords are dropped. Protected terms are kept whole.
+type Analyzer struct {
+	Name           string
+	Lowercase      bool
+	CaseFold       bool   // Lowercase with full Unicode case folding (e.g. "Straße" → "strasse")
+	FoldAccents    bool   // Strip diacritics, so "café" and "cafe" are the same term
+	Normalization  string // Unicode normalization form of the text: nfc, nfd, nfkc or nfkd ("": none)
+	CJKBigrams     bool   // Split runs of Chinese, Japanese and Korean characters into overlapping bigrams
+	NGram          int    // Split tokens into overlapping character n-grams of this length (0: not split)
+	MinTokenLength int
+	splitter       func(rune) bool
+	stopwords      map[string]struct{}
+	protected      map[string]struct{} // Terms never split, dropped as stopwords or as too short
+}
+
+// normalizationForms are the Unicode normalization forms of the "normalization" option
+var normalizationForms = map[string]norm.Form{"nfc": norm.NFC, "nfd": norm.NFD, "nfkc": norm.NFKC, "nfkd": norm.NFKD}
+
+// defaultStopwords is a small English stopword list used by the "english" analyzer
+var defaultStopwords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in", "into", "is", "it",
+	"no", "not", "of", "on", "or", "such", "that", "the", "their", "then", "there", "these",
+	"they", "this", "to", "was", "will", "with",
+}
+
+// englishStopwords names the built-in English list in the "stopwords" option
+const englishStopwords = "_english_"
+
+// NewAnalyzer creates one of the built-in analyzers:
+// "standard" (split on non letters/digits, lowercase), "english" (standard plus stopword removal),
+// "whitespace" (split on whitespace, case preserved), or "keyword" (the whole text is a single term)
+func NewAnalyzer(name string) (*Analyzer, error) {
+	switch name {
+	case "", "standard":
+		return &Analyzer{Name: "standard", Lowercase: true, splitter: isNotWordRune}, nil
+	case "english":
+		a := &Analyzer{Name: "english", Lowercase: true, splitter: isNotWordRune}
+		a.SetStopwords(defaultStopwords)
+		return a, nil
+	case "whitespace":
+		return &Analyzer{Name: "whitespace", splitter: unicode.IsSpace}, nil
+	case "keyword":
+		return &Analyzer{Name: "keyword"}, nil
+	default:
+		return nil, fmt.Errorf("unknown analyzer %s", name)
+	}
+}
+
+// NewAnalyzerFromConfig builds an analyzer from index options:
+// "analyzer" (name), "stopwords" (list, replaces the analyzer's defaults; "_english_" adds the built-in
+// English list), "stopword_files" (files of stopwords added to them), "protected_terms" (list),
+// "min_token_length", "normalization" (nfc, nfd, nfkc or nfkd), "fold_accents", "case_fold", "cjk_bigrams"
+// and "ngram"
+func NewAnalyzerFromConfig(cfg map[string]interface{}) (*Analyzer, error) {
+	name, err := config.String(cfg, "analyzer", "standard")
+	if err != nil {
+		return nil, err
+	}
+	a, err := NewAnalyzer(name)
+	if err != nil {
+		return nil, err
+	}
+	if a.Normalization, err = config.String(cfg, "normalization", ""); err != nil {
+		return nil, err
+	}
+	a.Normalization = strings.ToLower(a.Normalization)
+	if _, ok := normalizationForms[a.Normalization]; !ok && a.Normalization != "" {
+		return nil, fmt.Errorf("unknown normalization %s (known: nfc, nfd, nfkc, nfkd)", a.Normalization)
+	}
+	if a.FoldAccents, err = config.Bool(cfg, "fold_accents", false); err != nil {
+		return nil, err
+	}
+	if a.CaseFold, err = config.Bool(cfg, "case_fold", false); err != nil {
+		return nil, err
+	}
+	if a.CJKBigrams, err = config.Bool(cfg, "cjk_bigrams", false); err != nil {
+		return nil, err
+	}
+	if a.NGram, err = config.Int(cfg, "ngram", 0); err != nil {
+		return nil, err
+	}
+	if a.NGram < 0 {
+		return nil, fmt.Errorf("ngram must not be negative, got %d", a.NGram)
+	}
+	// The default stopwords are folded again, the way terms now are
+	stopwords := make([]string, 0, len(a.stopwords))
+	for word := range a.stopwords {
+		stopwords = append(stopwords, word)
+	}
+	if _, ok := cfg["stopwords"]; ok {
+		if stopwords, err = config.Strings(cfg, "stopwords"); err != nil {
+			return nil, err
+		}
+	}
+	files, err := config.Strings(cfg, "stopword_files")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		words, err := readStopwords(path)
+		if err != nil {
+			return nil, err
+		}
+		stopwords = append(stopwords, words...)
+	}
+	a.SetStopwords(stopwords)
+	protected, err := config.Strings(cfg, "protected_terms")
+	if err != nil {
+		return nil, err
+	}
+	a.SetProtectedTerms(protected)
+	if a.MinTokenLength, err = config.Int(cfg, "min_token_length", 0); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetStopwords replaces the terms dropped during analysis; "_english_" stands for the built-in English
+// list. They are folded like the terms analyzed, so options must be set first.
+func (a *Analyzer) SetStopwords(words []string) {
+	a.stopwords = make(map[string]struct{}, len(words))
+	folder := a.folder()
+	for _, word := range words {
+		if word == englishStopwords {
+			for _, word := range defaultStopwords {
+				a.stopwords[a.fold(folder, word)] = struct{}{}
+			}
+			continue
+		}
+		a.stopwords[a.fold(folder, word)] = struct{}{}
+	}
+}
+
+// SetProtectedTerms replaces the terms kept as they are: never split into bigrams or n-grams, nor dropped
+// as stopwords or for being shorter than MinTokenLength (e.g. "go" or "c"). They are folded like the
+// terms analyzed, so options must be set first.
+func (a *Analyzer) SetProtectedTerms(terms []string) {
+	a.protected = make(map[string]struct{}, len(terms))
+	folder := a.folder()
+	for _, term := range terms {
+		a.protected[a.fold(folder, term)] = struct{}{}
+	}
+}
+
+// isProtected reports whether a folded token is a protected term
+func (a *Analyzer) isProtected(token string) bool {
+	_, ok := a.protected[token]
+	return ok
+}
+
+// readStopwords reads a stopword file: words separated by whitespace, lines starting with # ignored
+func readStopwords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("stopword file: %w", err)
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(line, "#") {
+			words = append(words, strings.Fields(line)...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("stopword file %s: %w", path, err)
+	}
+	return words, nil
+}
+
+// folder returns the case folder of an analyzer, if it folds case; casers are not safe for concurrent use
+func (a *Analyzer) folder() cases.Caser {
+	if a.Lowercase && a.CaseFold {
+		return cases.Fold()
+	}
+	return cases.Caser{}
+}
+
+// fold lowercases (or case folds) a token and strips its accents, as the analyzer is configured to
+func (a *Analyzer) fold(folder cases.Caser, token string) string {
+	switch {
+	case a.Lowercase && a.CaseFold:
+		token = folder.String(token)
+	case a.Lowercase:
+		token = strings.ToLower(token)
+	}
+	if a.FoldAccents {
+		token = foldAccents(token)
+	}
+	return token
+}
+
+// foldAccents strips the combining marks of the canonical decomposition of s, e.g. "café" → "cafe"
+func foldAccents(s string) string {
+	decomposed := norm.NFD.String(s)
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	return norm.NFC.String(stripped)
+}
+
+// Analyze splits text into terms
+func (a *Analyzer) Analyze(text string) []string {
+	if form, ok := normalizationForms[a.Normalization]; ok {
+		text = form.String(text)
+	}
+	folder := a.folder()
+	var tokens []string
+	if a.splitter == nil {
+		if text = strings.TrimSpace(text); text != "" {
+			tokens = []string{text}
+		}
+	} else {
+		tokens = strings.FieldsFunc(text, a.splitter)
+	}
+
+	tokens = a.split(tokens, folder)
+	terms := tokens[:0]
+	for _, token := range tokens {
+		if a.isProtected(token) {
+			terms = append(terms, token)
+			continue
+		}
+		if len([]rune(token)) < a.MinTokenLength {
+			continue
+		}
+		if _, stop := a.stopwords[token]; stop {
+			continue
+		}
+		terms = append(terms, token)
+	}
+	return terms
+}
+
+// split folds tokens and splits them into the bigrams of their CJK runs and into n-grams, as configured;
+// protected terms are not split
+func (a *Analyzer) split(tokens []string, folder cases.Caser) []string {
+	out := tokens[:0]
+	for _, token := range tokens {
+		if token = a.fold(folder, token); token != "" {
+			out = append(out, token)
+		}
+	}
+	if a.CJKBigrams {
+		var split []string
+		for _, token := range out {
+			if a.isProtected(token) {
+				split = append(split, token)
+				continue
+			}
+			split = append(split, cjkBigrams(token)...)
+		}
+		out = split
+	}
+	if a.NGram > 0 {
+		var split []string
+		for _, token := range out {
+			if a.isProtected(token) {
+				split = append(split, token)
+				continue
+			}
+			split = append(split, ngrams(token, a.NGram)...)
+		}
+		out = split
+	}
+	return out
+}
+
+// isCJK reports whether a rune is of a script written without spaces between words: Han, Hiragana,
+// Katakana or Hangul
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// cjkBigrams splits the runs of CJK characters of a token into overlapping bigrams ("東京都" into "東京"
+// and "京都"), so words can be found without a dictionary. Lone CJK characters and the rest of the token
+// are kept as they are.
+func cjkBigrams(token string) []string {
+	runes := []rune(token)
+	var out []string
+	for start := 0; start < len(runes); {
+		cjk := isCJK(runes[start])
+		end := start + 1
+		for end < len(runes) && isCJK(runes[end]) == cjk {
+			end++
+		}
+		if run := runes[start:end]; !cjk || len(run) == 1 {
+			out = append(out, string(run))
+		} else {
+			for i := 0; i+2 <= len(run); i++ {
+				out = append(out, string(run[i:i+2]))
+			}
+		}
+		start = end
+	}
+	return out
+}
+
+// ngrams splits a token into its overlapping character n-grams; shorter tokens are kept whole
+func ngrams(token string, n int) []string {
+	runes := []rune(token)
+	if len(runes) <= n {
+		return []string{token}
+	}
+	out := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		out = append(out, string(runes[i:i+n]))
+	}
+	return out
+}
+
+// isNotWordRune splits words on runes that are not letters, digits or combining marks (which belong to
+// the letter before them in decomposed text)
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+}
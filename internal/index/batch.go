@@ -0,0 +1,121 @@
+package index
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Batched indexing: the documents of a batch are analyzed in parallel into posting deltas, one per
+ * worker, without the write lock; the deltas are then merged into the postings in one locked operation.
+ **/
+
+// analyzeChunk is the fewest documents a worker analyzes, below which batches are analyzed inline
+const analyzeChunk = 64
+
+// postingDelta is the terms of some documents of a batch, to merge into the postings
+type postingDelta struct {
+	postings  map[string]map[string]int // Term -> document ID -> term frequency
+	docTerms  map[string][]string
+	languages map[string]string
+}
+
+// analyze returns the language of a document and the frequencies of the terms of its searchable fields;
+// the caller must hold a lock
+func (idx *InvertedIndex) analyze(doc models.Document) (string, map[string]int) {
+	language := idx.languages.of(doc)
+	analyzer := idx.analyzerOf(language)
+	frequencies := make(map[string]int)
+	for _, field := range idx.fields.searchable(doc) {
+		for _, term := range analyzer.Analyze(field) {
+			frequencies[term]++
+		}
+	}
+	return language, frequencies
+}
+
+// analyzeBatch analyzes documents in parallel into deltas; of documents with the same ID, the last one
+// is analyzed, as it is the one stored. The caller must hold a lock.
+func (idx *InvertedIndex) analyzeBatch(docs []models.Document) []postingDelta {
+	last := make(map[string]int, len(docs))
+	for i, doc := range docs {
+		last[doc.ID] = i
+	}
+	unique := docs
+	if len(last) < len(docs) {
+		unique = make([]models.Document, 0, len(last))
+		for i, doc := range docs {
+			if last[doc.ID] == i {
+				unique = append(unique, doc)
+			}
+		}
+	}
+
+	workers := max(1, min(runtime.GOMAXPROCS(0), len(unique)/analyzeChunk))
+	deltas := make([]postingDelta, workers)
+	size := (len(unique) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Each worker analyzes a contiguous chunk of the documents
+			chunk := unique[min(w*size, len(unique)):min((w+1)*size, len(unique))]
+			delta := postingDelta{
+				postings:  make(map[string]map[string]int),
+				docTerms:  make(map[string][]string, len(chunk)),
+				languages: make(map[string]string),
+			}
+			for _, doc := range chunk {
+				language, frequencies := idx.analyze(doc)
+				if language != "" {
+					delta.languages[doc.ID] = language
+				}
+				terms := make([]string, 0, len(frequencies))
+				for term, tf := range frequencies {
+					postings, ok := delta.postings[term]
+					if !ok {
+						postings = make(map[string]int)
+						delta.postings[term] = postings
+					}
+					postings[doc.ID] = tf
+					terms = append(terms, term)
+				}
+				delta.docTerms[doc.ID] = terms
+			}
+			deltas[w] = delta
+		}(w)
+	}
+	wg.Wait()
+	return deltas
+}
+
+// mergeDeltas replaces the terms of the documents of the deltas with theirs; the caller must hold the
+// write lock. The deltas' maps are adopted, so they must not be used afterwards.
+func (idx *InvertedIndex) mergeDeltas(deltas []postingDelta) {
+	for _, delta := range deltas {
+		for id := range delta.docTerms {
+			idx.unindexTerms(id)
+		}
+	}
+	for _, delta := range deltas {
+		for id, terms := range delta.docTerms {
+			idx.docTerms[id] = terms
+		}
+		for id, language := range delta.languages {
+			idx.docLanguages[id] = language
+		}
+		for term, docs := range delta.postings {
+			postings, ok := idx.postings[term]
+			if !ok {
+				idx.postings[term] = docs
+				continue
+			}
+			for id, tf := range docs {
+				postings[id] = tf
+			}
+		}
+	}
+}
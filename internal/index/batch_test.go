@@ -0,0 +1,90 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// batchDocs are n documents in English or French, with shared and distinct terms
+func batchDocs(n int) []models.Document {
+	docs := make([]models.Document, n)
+	for i := range docs {
+		lang := "en"
+		if i%3 == 0 {
+			lang = "fr"
+		}
+		docs[i] = makeTestDoc(fmt.Sprint(i), fmt.Sprintf("Café notes %d on the budget review %d", i, i%7), fmt.Sprintf("/docs/%d.txt", i),
+			map[string]string{"lang": lang, "team": fmt.Sprintf("team-%d", i%5)}, nil)
+	}
+	return docs
+}
+
+func batchIndex(t testing.TB) *InvertedIndex {
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"language_field": "lang",
+		"languages":      map[string]interface{}{"fr": map[string]interface{}{"fold_accents": true}},
+	})
+	assert.NoError(t, err)
+	return idx.(*InvertedIndex)
+}
+
+// sortedTerms is the terms of each document, sorted for comparison
+func sortedTerms(idx *InvertedIndex) map[string][]string {
+	terms := make(map[string][]string, len(idx.docTerms))
+	for id, docTerms := range idx.docTerms {
+		sorted := append([]string(nil), docTerms...)
+		sort.Strings(sorted)
+		terms[id] = sorted
+	}
+	return terms
+}
+
+func TestInvertedIndex_AddDocumentsMatchesOneByOne(t *testing.T) {
+	docs := batchDocs(500)
+	// Re-added documents replace their terms, in the batch or before it
+	docs = append(docs, makeTestDoc("7", "replaced", "/docs/7.txt", map[string]string{"lang": "fr"}, nil),
+		makeTestDoc("8", "first", "/docs/8.txt", nil, nil), makeTestDoc("8", "second", "/docs/8.txt", nil, nil))
+
+	single := batchIndex(t)
+	batched := batchIndex(t)
+	assert.NoError(t, batched.AddDocument(context.Background(), makeTestDoc("9", "previous", "/docs/9.txt", nil, nil)))
+	for _, doc := range append([]models.Document{makeTestDoc("9", "previous", "/docs/9.txt", nil, nil)}, docs...) {
+		assert.NoError(t, single.AddDocument(context.Background(), doc))
+	}
+	assert.NoError(t, batched.AddDocuments(context.Background(), docs))
+
+	assert.Equal(t, single.postings, batched.postings)
+	assert.Equal(t, sortedTerms(single), sortedTerms(batched))
+	assert.Equal(t, single.docLanguages, batched.docLanguages)
+	assert.NotContains(t, batched.postings, "previous")
+	assert.NotContains(t, batched.postings, "first")
+
+	results, err := batched.Search(context.Background(), "cafe")
+	assert.NoError(t, err)
+	assert.Len(t, results, 167) // The French documents, whose analyzer folds accents
+	count, err := batched.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 500, count)
+}
+
+func BenchmarkInvertedIndex_AddDocuments(b *testing.B) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	b.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	docs := batchDocs(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx := batchIndex(b)
+		if err := idx.AddDocuments(context.Background(), docs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Memory budget: an inverted index given a budget keeps the approximate size of its documents and
+ * postings below it by evicting the text bodies of the documents indexed first to its field store. Their
+ * postings and metadata stay in memory, so they are still found, and their text is read back for the
+ * search results returning them.
+ **/
+
+// memoryBudget tracks the memory held by the documents of an index against a limit; the index guards it
+// with its own lock
+type memoryBudget struct {
+	limit    int            // Bytes (0: no budget)
+	used     int            // Approximate bytes held by the documents and their postings
+	docBytes map[string]int // Document ID -> approximate bytes held
+	resident []string       // IDs of the documents whose text may be in memory, oldest first
+	warned   bool           // Set once the budget was exceeded with nothing left to evict
+
+	evictions    int // Eviction rounds run
+	evictedDocs  int // Text bodies evicted
+	evictedBytes int // Bytes of the text bodies evicted
+}
+
+func (b *memoryBudget) enabled() bool {
+	return b.limit > 0
+}
+
+// SetMemoryBudget evicts the text of documents to the field store once the documents and postings of
+// the index take more than limit bytes (0: no budget). Without a field store set by SetFields, one is
+// opened at storePath. Set the budget before adding documents.
+func (idx *InvertedIndex) SetMemoryBudget(limit int, storePath string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(idx.docTerms) > 0 {
+		return fmt.Errorf("the memory budget must be set before documents are added")
+	}
+	if limit < 0 {
+		return fmt.Errorf("memory budget must not be negative, got %d", limit)
+	}
+	if limit > 0 && idx.stored == nil {
+		if storePath == "" {
+			return fmt.Errorf("a memory budget needs a field store to evict text to (field_store_path)")
+		}
+		stored, err := openFieldStore(storePath)
+		if err != nil {
+			return err
+		}
+		idx.stored = stored
+	}
+	idx.budget = memoryBudget{limit: limit, docBytes: make(map[string]int)}
+	return nil
+}
+
+// account records the memory held by a document added with its postings, then evicts text bodies if the
+// budget is exceeded; the caller must hold the write lock
+func (idx *InvertedIndex) account(kept models.Document) error {
+	if !idx.budget.enabled() {
+		return nil
+	}
+	idx.unaccount(kept.ID)
+	bytes := documentBytes(kept) + len(idx.docTerms[kept.ID])*(len(kept.ID)+8)
+	idx.budget.docBytes[kept.ID] = bytes
+	idx.budget.used += bytes
+	if kept.Text != "" {
+		idx.budget.resident = append(idx.budget.resident, kept.ID)
+	}
+	// Updates queue documents again: drop the entries of removed documents and repeated ones
+	if len(idx.budget.resident) > 2*len(idx.budget.docBytes)+64 {
+		seen := make(map[string]bool, len(idx.budget.docBytes))
+		resident := idx.budget.resident[:0]
+		for _, id := range idx.budget.resident {
+			if _, ok := idx.budget.docBytes[id]; ok && !seen[id] {
+				seen[id] = true
+				resident = append(resident, id)
+			}
+		}
+		idx.budget.resident = resident
+	}
+	return idx.evict()
+}
+
+// unaccount forgets the memory held by a removed document; the caller must hold the write lock
+func (idx *InvertedIndex) unaccount(id string) {
+	if !idx.budget.enabled() {
+		return
+	}
+	idx.budget.used -= idx.budget.docBytes[id]
+	delete(idx.budget.docBytes, id)
+}
+
+// evict moves the text of the oldest documents holding one to the field store until the budget is met;
+// the caller must hold the write lock
+func (idx *InvertedIndex) evict() error {
+	b := &idx.budget
+	if b.used <= b.limit {
+		b.warned = false
+		return nil
+	}
+	idx.store.mu.Lock()
+	defer idx.store.mu.Unlock()
+	texts := make(map[string]string)
+	freed, next := 0, 0
+	for ; next < len(b.resident) && b.used-freed > b.limit; next++ {
+		id := b.resident[next]
+		doc, ok := idx.store.documents[id]
+		if _, pending := texts[id]; !ok || pending || doc.Text == "" {
+			continue
+		}
+		texts[id] = doc.Text
+		freed += len(doc.Text)
+	}
+	if len(texts) > 0 {
+		if err := idx.stored.spill(texts); err != nil {
+			return fmt.Errorf("failed to evict text to the field store: %w", err)
+		}
+		for id, text := range texts {
+			doc := idx.store.documents[id]
+			doc.Text = ""
+			idx.store.documents[id] = doc
+			idx.store.lowered[id] = lowerDocument(doc)
+			b.docBytes[id] -= len(text)
+		}
+		b.used -= freed
+		b.evictions++
+		b.evictedDocs += len(texts)
+		b.evictedBytes += freed
+	}
+	b.resident = append(b.resident[:0], b.resident[next:]...)
+	if b.used > b.limit && !b.warned {
+		b.warned = true
+		log.Warn().Msgf("Inverted index holds %d bytes over its memory budget of %d with no text left to evict", b.used-b.limit, b.limit)
+	}
+	return nil
+}
+
+// budgetDiagnostics reports the memory budget and the evictions it caused; the caller must hold the
+// read lock
+func (idx *InvertedIndex) budgetDiagnostics(diagnostics map[string]interface{}) {
+	b := idx.budget
+	if !b.enabled() {
+		return
+	}
+	diagnostics["memory_budget_bytes"] = b.limit
+	diagnostics["memory_used_bytes"] = b.used
+	diagnostics["evictions"] = b.evictions
+	diagnostics["evicted_documents"] = b.evictedDocs
+	diagnostics["evicted_bytes"] = b.evictedBytes
+	if idx.stored != nil {
+		diagnostics["field_store_reads"] = idx.stored.reads.Load()
+	}
+}
+
+// documentBytes is the approximate memory held by a document
+func documentBytes(doc models.Document) int {
+	size := len(doc.ID) + len(doc.Text) + len(doc.Source)
+	for key, value := range doc.Meta {
+		size += len(key) + len(value)
+	}
+	size += len(doc.Vector) * 8 // 8 bytes per float64
+	for _, principal := range doc.AllowedPrincipals {
+		size += len(principal)
+	}
+	return size
+}
+
+// spill adds the text of documents to their unstored fields
+func (s *fieldStore) spill(texts map[string]string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(fieldsBucket)
+		for id, text := range texts {
+			rest := models.Document{ID: id}
+			if data := bucket.Get([]byte(id)); data != nil {
+				if err := json.Unmarshal(data, &rest); err != nil {
+					return fmt.Errorf("document %s: %w", id, err)
+				}
+			}
+			rest.Text = text
+			data, err := json.Marshal(rest)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(id), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
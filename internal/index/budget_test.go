@@ -0,0 +1,62 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestInvertedIndex_MemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	idx := NewInvertedIndex(nil)
+	defer idx.Close()
+	assert.NoError(t, idx.SetMemoryBudget(5000, filepath.Join(t.TempDir(), "fields.db")))
+	docs := make([]models.Document, 10)
+	for i := range docs {
+		docs[i] = models.Document{ID: fmt.Sprint(i), Text: strings.Repeat(fmt.Sprintf("report%d ", i), 100), Meta: map[string]string{"team": "finance"}}
+	}
+	assert.NoError(t, idx.AddDocuments(ctx, docs))
+
+	// The oldest texts were evicted; the documents are still found and returned whole
+	diagnostics := idx.Diagnostics()
+	assert.LessOrEqual(t, diagnostics["memory_used_bytes"], 5000)
+	assert.Greater(t, diagnostics["evicted_documents"], 0)
+	assert.Empty(t, idx.store.documents["0"].Text)
+	assert.NotEmpty(t, idx.store.documents["9"].Text)
+	found, err := idx.Search(ctx, "report0")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{docs[0]}, found)
+	found, err = idx.Search(ctx, "team=finance")
+	assert.NoError(t, err)
+	assert.Len(t, found, 10)
+	assert.Equal(t, docs[0], found[0])
+	assert.Greater(t, idx.Diagnostics()["field_store_reads"], int64(0))
+
+	// An update replaces the evicted text, and deletes release their memory
+	updated := models.Document{ID: "0", Text: "annual summary"}
+	assert.NoError(t, idx.UpdateDocument(ctx, "0", updated))
+	found, err = idx.Search(ctx, "summary")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{updated}, found)
+	for _, doc := range docs {
+		assert.NoError(t, idx.DeleteDocument(ctx, doc.ID))
+	}
+	assert.Equal(t, 0, idx.Diagnostics()["memory_used_bytes"])
+}
+
+func TestInvertedIndex_MemoryBudgetNeedsFieldStore(t *testing.T) {
+	_, err := NewIndexFactory().Create("inverted", map[string]interface{}{"memory_budget_mb": 64})
+	assert.ErrorContains(t, err, "field_store_path")
+
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"memory_budget_mb": 64, "field_store_path": filepath.Join(t.TempDir(), "fields.db"),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Close())
+}
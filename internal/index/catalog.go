@@ -0,0 +1,143 @@
+package index
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// Dimension is a metadata key of the indexed documents and its cardinality
+type Dimension struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"` // Documents holding the key
+	Values    int    `json:"values"`    // Distinct values of the key
+}
+
+// ValueCount is a value of a dimension and the number of documents holding it
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// DimensionLister is implemented by indexes that describe the metadata of their documents, for faceted
+// user interfaces and query builders. Only the documents visible to the principals of ctx (see
+// WithPrincipals) and matching filter (nil: all) are counted.
+type DimensionLister interface {
+	// Returns the metadata keys of the indexed documents, by name
+	ListDimensions(ctx context.Context, filter func(models.Document) bool) []Dimension
+	// Returns the n most frequent values of a dimension (n <= 0: all), most frequent first; none for
+	// dimensions no document has
+	TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ValueCount
+}
+
+// metaCatalog counts the indexed documents holding each value of each metadata key, so queries naming a
+// dimension no document has can be told apart from queries that match nothing
+type metaCatalog map[string]map[string]int
+
+func (c metaCatalog) add(doc models.Document) {
+	for key, value := range doc.Meta {
+		if c[key] == nil {
+			c[key] = make(map[string]int)
+		}
+		c[key][value]++
+	}
+}
+
+func (c metaCatalog) remove(doc models.Document) {
+	for key, value := range doc.Meta {
+		values := c[key]
+		if values[value]--; values[value] <= 0 {
+			delete(values, value)
+		}
+		if len(values) == 0 {
+			delete(c, key)
+		}
+	}
+}
+
+// catalogOf returns the catalog of the documents visible to the principals of ctx and matching filter,
+// counting them unless every document is. The caller must hold the read lock and not modify it.
+func (idx *SimpleIndex) catalogOf(ctx context.Context, filter func(models.Document) bool) metaCatalog {
+	principals, restricted := principalsFrom(ctx)
+	if !restricted && filter == nil {
+		return idx.catalog
+	}
+	catalog := make(metaCatalog)
+	for _, doc := range idx.documents {
+		if restricted && !doc.VisibleTo(principals) || filter != nil && !filter(doc) {
+			continue
+		}
+		catalog.add(doc)
+	}
+	return catalog
+}
+
+// ListDimensions returns the metadata keys of the indexed documents, by name
+func (idx *SimpleIndex) ListDimensions(ctx context.Context, filter func(models.Document) bool) []Dimension {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	catalog := idx.catalogOf(ctx, filter)
+	dimensions := make([]Dimension, 0, len(catalog))
+	for key, values := range catalog {
+		dimension := Dimension{Name: key, Values: len(values)}
+		for _, count := range values {
+			dimension.Documents += count
+		}
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Slice(dimensions, func(i, j int) bool { return dimensions[i].Name < dimensions[j].Name })
+	return dimensions
+}
+
+// TopValues returns the n most frequent values of a dimension (n <= 0: all); values as frequent are
+// ordered by value
+func (idx *SimpleIndex) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ValueCount {
+	idx.mu.RLock()
+	values := idx.catalogOf(ctx, filter)[dimension]
+	counts := make([]ValueCount, 0, len(values))
+	for value, count := range values {
+		counts = append(counts, ValueCount{Value: value, Count: count})
+	}
+	idx.mu.RUnlock()
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// ListDimensions returns the metadata keys of the indexed documents, by name
+func (p *PersistedSimpleIndex) ListDimensions(ctx context.Context, filter func(models.Document) bool) []Dimension {
+	return p.index.ListDimensions(ctx, filter)
+}
+
+// TopValues returns the n most frequent values of a dimension (n <= 0: all)
+func (p *PersistedSimpleIndex) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ValueCount {
+	return p.index.TopValues(ctx, dimension, n, filter)
+}
+
+// ListDimensions returns the metadata keys of the indexed documents, by name
+func (idx *InvertedIndex) ListDimensions(ctx context.Context, filter func(models.Document) bool) []Dimension {
+	return idx.store.ListDimensions(ctx, filter)
+}
+
+// TopValues returns the n most frequent values of a dimension (n <= 0: all)
+func (idx *InvertedIndex) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ValueCount {
+	return idx.store.TopValues(ctx, dimension, n, filter)
+}
+
+// ListDimensions returns the metadata keys of the indexed documents, by name
+func (idx *VectorIndex) ListDimensions(ctx context.Context, filter func(models.Document) bool) []Dimension {
+	return idx.store.ListDimensions(ctx, filter)
+}
+
+// TopValues returns the n most frequent values of a dimension (n <= 0: all)
+func (idx *VectorIndex) TopValues(ctx context.Context, dimension string, n int, filter func(models.Document) bool) []ValueCount {
+	return idx.store.TopValues(ctx, dimension, n, filter)
+}
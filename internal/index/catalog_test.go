@@ -0,0 +1,66 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleIndex_ListDimensions(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.Empty(t, idx.ListDimensions(ctx, nil))
+
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		makeTestDoc("1", "", "a.go", map[string]string{"fileExtension": "go", "fileSize": "10"}, nil),
+		makeTestDoc("2", "", "b.md", map[string]string{"fileExtension": "md"}, nil),
+		makeTestDoc("3", "", "c.go", map[string]string{"fileExtension": "go"}, nil),
+	}))
+	assert.Equal(t, []Dimension{{Name: "fileExtension", Documents: 3, Values: 2}, {Name: "fileSize", Documents: 1, Values: 1}}, idx.ListDimensions(ctx, nil))
+
+	// Keys are dropped with the last document holding them
+	assert.NoError(t, idx.UpdateDocument(ctx, "1", makeTestDoc("1", "", "a.go", map[string]string{"fileExtension": "go", "author": "alice"}, nil)))
+	assert.Equal(t, []Dimension{{Name: "author", Documents: 1, Values: 1}, {Name: "fileExtension", Documents: 3, Values: 2}}, idx.ListDimensions(ctx, nil))
+	assert.NoError(t, idx.AddDocument(ctx, makeTestDoc("1", "", "a.go", map[string]string{"fileExtension": "md"}, nil)))
+	assert.Equal(t, []Dimension{{Name: "fileExtension", Documents: 3, Values: 2}}, idx.ListDimensions(ctx, nil))
+	assert.NoError(t, idx.DeleteDocuments(ctx, []string{"1", "2", "3"}))
+	assert.Empty(t, idx.ListDimensions(ctx, nil))
+}
+
+func TestSimpleIndex_TopValues(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		makeTestDoc("1", "", "a.go", map[string]string{"fileExtension": "go"}, nil),
+		makeTestDoc("2", "", "b.md", map[string]string{"fileExtension": "md"}, nil),
+		makeTestDoc("3", "", "c.go", map[string]string{"fileExtension": "go"}, nil),
+		makeTestDoc("4", "", "d.txt", map[string]string{"fileExtension": "txt"}, nil),
+	}))
+
+	assert.Equal(t, []ValueCount{{Value: "go", Count: 2}, {Value: "md", Count: 1}}, idx.TopValues(ctx, "fileExtension", 2, nil))
+	assert.Len(t, idx.TopValues(ctx, "fileExtension", 0, nil), 3)
+	assert.Empty(t, idx.TopValues(ctx, "author", 10, nil))
+}
+
+func TestSimpleIndex_DimensionsOfVisibleDocuments(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		makeTestDoc("1", "", "a.go", map[string]string{"fileExtension": "go", "team": "public"}, nil),
+		{ID: "2", Meta: map[string]string{"fileExtension": "pdf", "merger": "acme"}, AllowedPrincipals: []string{"legal"}},
+		makeTestDoc("3", "", "c.md", map[string]string{"fileExtension": "md", "team": "internal"}, nil),
+	}))
+
+	// Documents hidden by their access control lists or the filter are not counted
+	alice := WithPrincipals(ctx, []string{"alice"})
+	assert.Equal(t, []Dimension{{Name: "fileExtension", Documents: 2, Values: 2}, {Name: "team", Documents: 2, Values: 2}}, idx.ListDimensions(alice, nil))
+	assert.Empty(t, idx.TopValues(alice, "merger", 0, nil))
+	public := func(doc models.Document) bool { return doc.Meta["team"] == "public" }
+	assert.Equal(t, []ValueCount{{Value: "go", Count: 1}}, idx.TopValues(alice, "fileExtension", 0, public))
+
+	legal := WithPrincipals(ctx, []string{"legal"})
+	assert.Equal(t, []ValueCount{{Value: "acme", Count: 1}}, idx.TopValues(legal, "merger", 0, nil))
+	assert.Len(t, idx.ListDimensions(ctx, nil), 3)
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Clearing: Clear removes every document of an index and resets the structures derived from them, keeping
+ * the index's configuration. A persisted index clears its database in one transaction, after the writes
+ * queued before, so the database never holds part of the documents.
+ **/
+
+// Clear removes every document and resets the bitmaps, catalog and value index
+func (idx *SimpleIndex) Clear() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	count := len(idx.documents)
+	idx.documents = make(map[string]models.Document)
+	idx.lowered = make(map[string]loweredDocument)
+	idx.acl = newACLBitmaps()
+	idx.catalog = make(metaCatalog)
+	idx.values = newValueIndex()
+	log.Info().Msgf("Cleared %d documents from index", count)
+	return nil
+}
+
+// Clear removes every document from the database and then from memory. It runs on the async worker
+// after the writes queued before it; without a database, only memory is cleared.
+func (p *PersistedSimpleIndex) Clear() error {
+	if p.readOnly {
+		return ErrReadOnly
+	}
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db == nil {
+		return p.index.Clear()
+	}
+	if !p.workerRunning.Load() {
+		return fmt.Errorf("async database worker is not running")
+	}
+	result := make(chan error, 1)
+	p.opChan <- dbOperation{opType: "clear", data: result}
+	return <-result
+}
+
+// clearDatabase empties the documents bucket in one transaction, then clears memory; it runs on the async
+// worker, so no write is in flight
+func (p *PersistedSimpleIndex) clearDatabase() error {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket([]byte("documents")); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte("documents"))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear database: %w", err)
+	}
+	return p.index.Clear()
+}
+
+// Clear removes every document, its postings and its fields in the field store, and resets the memory
+// budget's accounting
+func (idx *InvertedIndex) Clear() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.store.Clear(); err != nil {
+		return err
+	}
+	idx.postings = make(map[string]map[string]int)
+	idx.docTerms = make(map[string][]string)
+	idx.docLanguages = make(map[string]string)
+	if idx.budget.enabled() {
+		idx.budget.used = 0
+		idx.budget.docBytes = make(map[string]int)
+		idx.budget.resident = nil
+		idx.budget.warned = false
+	}
+	// Fields left behind are replaced or removed when documents with their IDs are added again
+	if idx.stored != nil {
+		if err := idx.stored.clear(); err != nil {
+			return fmt.Errorf("failed to clear field store: %w", err)
+		}
+	}
+	return nil
+}
+
+// Clear removes every document
+func (idx *VectorIndex) Clear() error {
+	return idx.store.Clear()
+}
+
+// Clear removes every document of the remote index
+func (idx *RemoteIndex) Clear() error {
+	remote := idx.endpoint()
+	return remote.call(context.Background(), http.MethodPost, "/indexes/"+url.PathEscape(remote.index)+"/clear", nil, "", nil)
+}
@@ -0,0 +1,67 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestIndexes_Clear(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	persisted, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	inverted := NewInvertedIndex(nil)
+	assert.NoError(t, inverted.SetMemoryBudget(1<<20, filepath.Join(t.TempDir(), "fields.db")))
+	vector, err := NewVectorIndex("cosine", 2, 1)
+	assert.NoError(t, err)
+	indexes := map[string]Index{
+		"simple":    NewSimpleIndex(),
+		"persisted": persisted,
+		"inverted":  inverted,
+		"vector":    vector,
+	}
+	ctx := context.Background()
+	for name, idx := range indexes {
+		assert.NoError(t, idx.Configure(map[string]interface{}{"collation": CollationCaseSensitive}), name)
+		docs := []models.Document{
+			{ID: "1", Text: "hello world", Vector: []float64{1}, Meta: map[string]string{"team": "a"}},
+			{ID: "2", Text: "hello there", Vector: []float64{2}, Meta: map[string]string{"team": "b"}, AllowedPrincipals: []string{"alice"}},
+		}
+		assert.NoError(t, idx.AddDocuments(ctx, docs), name)
+
+		assert.NoError(t, idx.Clear(), name)
+		count, _ := idx.Count()
+		assert.Equal(t, 0, count, name)
+		results, err := idx.Search(ctx, "team=a")
+		assert.NoError(t, err, name)
+		assert.Empty(t, results, name)
+		config, err := idx.ShowConfig()
+		assert.NoError(t, err, name)
+		assert.Equal(t, CollationCaseSensitive, config["collation"], name)
+
+		// The index takes documents again
+		assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "2", Text: "hello again", Vector: []float64{3}, Meta: map[string]string{"team": "a"}}), name)
+		results, err = idx.Search(ctx, "team=a")
+		assert.NoError(t, err, name)
+		if assert.Len(t, results, 1, name) {
+			assert.Empty(t, results[0].AllowedPrincipals, name)
+		}
+	}
+	assert.Empty(t, inverted.postings["world"])
+
+	// The database was cleared after the writes queued before
+	assert.NoError(t, persisted.Close())
+	reopened, err := NewPersistedSimpleIndexWithDatabaseAndLoad(dbPath)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	count, _ := reopened.Count()
+	assert.Equal(t, 1, count)
+
+	readOnly := NewSimpleIndex()
+	readOnly.SetReadOnly(true)
+	assert.ErrorIs(t, readOnly.Clear(), ErrReadOnly)
+}
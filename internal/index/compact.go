@@ -0,0 +1,102 @@
+package index
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Compaction: bbolt reuses the pages freed by deletes and updates but never gives them back, so database
+ * files never shrink. Compact copies the live data into a fresh file and swaps it in.
+ **/
+
+// compactTxSize is the bytes bbolt.Compact copies per transaction
+const compactTxSize = 64 << 20
+
+// Compact copies the live documents and configuration into a fresh database file and atomically renames
+// it over the database. It runs on the async worker after the writes queued before it, and blocks them
+// meanwhile.
+func (p *PersistedSimpleIndex) Compact() error {
+	if p.readOnly {
+		return ErrReadOnly
+	}
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db == nil {
+		return fmt.Errorf("database not open")
+	}
+	if !p.workerRunning.Load() {
+		return fmt.Errorf("async database worker is not running")
+	}
+	result := make(chan error, 1)
+	p.opChan <- dbOperation{opType: "compact", data: result}
+	return <-result
+}
+
+// compactDatabase copies the database into a fresh file, without the free pages left by deleted and
+// updated documents, and swaps it in; it runs on the async worker, so no write is in flight
+func (p *PersistedSimpleIndex) compactDatabase() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path := p.db.Path()
+	before, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	compactPath := path + ".compact"
+	os.Remove(compactPath)
+	dst, err := bbolt.Open(compactPath, 0600, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bbolt.Compact(dst, p.db, compactTxSize); err != nil {
+		dst.Close()
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+
+	if err := p.db.Close(); err != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	renamed := os.Rename(compactPath, path)
+	// Reopen the database, compacted or, if it could not be swapped in, as it was
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		p.db = nil
+		return fmt.Errorf("failed to reopen database: %w", err)
+	}
+	p.db = db
+	if renamed != nil {
+		os.Remove(compactPath)
+		return fmt.Errorf("failed to replace database with its compacted copy: %w", renamed)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	log.Info().Msgf("Compacted database %s from %d to %d bytes", path, before.Size(), after.Size())
+	return nil
+}
+
+// bucketStats reports the keys, depth and pages of a bucket
+func bucketStats(bucket *bbolt.Bucket) map[string]interface{} {
+	stats := bucket.Stats()
+	return map[string]interface{}{
+		"keys":             stats.KeyN,
+		"depth":            stats.Depth,
+		"branch_pages":     stats.BranchPageN,
+		"leaf_pages":       stats.LeafPageN,
+		"overflow_pages":   stats.BranchOverflowN + stats.LeafOverflowN,
+		"leaf_inuse_bytes": stats.LeafInuse,
+		"leaf_alloc_bytes": stats.LeafAlloc,
+	}
+}
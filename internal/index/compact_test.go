@@ -0,0 +1,54 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestPersistedSimpleIndex_Compact(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx := NewPersistedSimpleIndex()
+	assert.ErrorContains(t, idx.Compact(), "not open")
+	assert.NoError(t, idx.OpenDatabase(dbPath))
+	assert.NoError(t, idx.Configure(map[string]interface{}{"collation": CollationCaseSensitive}))
+	docs := make([]models.Document, 1000)
+	ids := make([]string, 0, len(docs))
+	for i := range docs {
+		docs[i] = models.Document{ID: fmt.Sprint(i), Text: strings.Repeat("padding ", 100)}
+		if i%100 != 0 {
+			ids = append(ids, docs[i].ID)
+		}
+	}
+	assert.NoError(t, idx.AddDocuments(ctx, docs))
+	assert.NoError(t, idx.DeleteDocuments(ctx, ids))
+
+	// Compact runs after the queued writes, which leave free pages behind
+	assert.NoError(t, idx.Compact())
+	stats, err := idx.GetDatabaseStats()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, stats["document_count"])
+	assert.Equal(t, true, stats["has_config"])
+	assert.Less(t, stats["file_size_bytes"], int64(100<<10))
+	assert.Contains(t, stats, "free_pages")
+	assert.Contains(t, stats, "page_size")
+	buckets := stats["buckets"].(map[string]interface{})
+	assert.Equal(t, 10, buckets["documents"].(map[string]interface{})["keys"])
+	assert.Equal(t, 1, buckets["config"].(map[string]interface{})["keys"])
+
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "new", Text: "after"}))
+	assert.NoError(t, idx.Close())
+	reader, err := NewPersistedSimpleIndexReadOnly(dbPath)
+	assert.NoError(t, err)
+	count, _ := reader.Count()
+	assert.Equal(t, 11, count)
+	assert.ErrorIs(t, reader.Compact(), ErrReadOnly)
+	assert.NoError(t, reader.Close())
+}
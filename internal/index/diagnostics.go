@@ -0,0 +1,88 @@
+package index
+
+import "go.etcd.io/bbolt"
+
+// Diagnoser is implemented by indexes that report their internals for operators: a breakdown of the
+// memory they hold and the state of any background work
+type Diagnoser interface {
+	Diagnostics() map[string]interface{}
+}
+
+// Diagnostics breaks the approximate memory held by the documents down by field
+func (idx *SimpleIndex) Diagnostics() map[string]interface{} {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	var ids, text, meta, vectors int
+	for _, doc := range idx.documents {
+		ids += len(doc.ID) + len(doc.Source)
+		text += len(doc.Text)
+		for key, value := range doc.Meta {
+			meta += len(key) + len(value)
+		}
+		vectors += len(doc.Vector) * 8 // 8 bytes per float64
+	}
+	return map[string]interface{}{
+		"documents":    len(idx.documents),
+		"id_bytes":     ids,
+		"text_bytes":   text,
+		"meta_bytes":   meta,
+		"vector_bytes": vectors,
+		"dimensions":   len(idx.catalog),
+	}
+}
+
+// Diagnostics adds the size of the postings to the stored documents' breakdown
+func (idx *InvertedIndex) Diagnostics() map[string]interface{} {
+	diagnostics := idx.store.Diagnostics()
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	postings, postingBytes := 0, 0
+	for term, docs := range idx.postings {
+		postings += len(docs)
+		postingBytes += len(term)
+		for id := range docs {
+			postingBytes += len(id) + 8 // document ID plus term frequency
+		}
+	}
+	diagnostics["analyzer"] = idx.analyzer.Name
+	diagnostics["terms"] = len(idx.postings)
+	diagnostics["postings"] = postings
+	diagnostics["posting_bytes"] = postingBytes
+	idx.budgetDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// Diagnostics adds the similarity settings to the stored documents' breakdown
+func (idx *VectorIndex) Diagnostics() map[string]interface{} {
+	diagnostics := idx.store.Diagnostics()
+	diagnostics["metric"] = idx.metricName
+	diagnostics["k"] = idx.k
+	diagnostics["vector_size"] = idx.size
+	return diagnostics
+}
+
+// Diagnostics adds the async worker's queue and the database size and free pages to the in-memory
+// documents' breakdown
+func (p *PersistedSimpleIndex) Diagnostics() map[string]interface{} {
+	diagnostics := p.index.Diagnostics()
+	diagnostics["read_only"] = p.readOnly
+	diagnostics["worker_running"] = p.workerRunning.Load()
+	diagnostics["queued_operations"] = len(p.opChan)
+	diagnostics["queue_capacity"] = cap(p.opChan)
+
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db != nil {
+		diagnostics["db_path"] = db.Path()
+		db.View(func(tx *bbolt.Tx) error {
+			diagnostics["db_size_bytes"] = tx.Size()
+			diagnostics["db_free_pages"] = db.Stats().FreePageN
+			if bucket := tx.Bucket([]byte("documents")); bucket != nil {
+				diagnostics["db_documents"] = bucket.Stats().KeyN
+			}
+			return nil
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,33 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics_MemoryBreakdown(t *testing.T) {
+	doc := models.Document{ID: "a", Text: "hello world", Meta: map[string]string{"k": "v"}, Vector: []float64{1, 2}}
+
+	inverted := NewInvertedIndex(nil)
+	assert.NoError(t, inverted.AddDocument(context.Background(), doc))
+	diagnostics := inverted.Diagnostics()
+	assert.Equal(t, 1, diagnostics["documents"])
+	assert.Equal(t, 11, diagnostics["text_bytes"])
+	assert.Equal(t, 2, diagnostics["meta_bytes"])
+	assert.Equal(t, 16, diagnostics["vector_bytes"])
+	assert.Equal(t, 3, diagnostics["terms"]) // hello, world and the meta value
+
+	persisted := NewPersistedSimpleIndex()
+	assert.NoError(t, persisted.OpenDatabase(filepath.Join(t.TempDir(), "index.db")))
+	defer persisted.Close()
+	assert.NoError(t, persisted.AddDocument(context.Background(), doc))
+	diagnostics = persisted.Diagnostics()
+	assert.Equal(t, 1, diagnostics["documents"])
+	assert.Equal(t, true, diagnostics["worker_running"])
+	assert.Equal(t, 1000, diagnostics["queue_capacity"])
+	assert.Contains(t, diagnostics, "db_size_bytes")
+}
@@ -0,0 +1,154 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+/**
+ * Durability: a persisted index applies writes to memory, then queues them for its async database worker.
+ * By default writes return once they are in memory; callers that need more can wait for the worker to
+ * commit them, or to commit them and sync the database to disk.
+ **/
+
+// Durability is when a write to a persisted index returns
+type Durability int
+
+const (
+	DurabilityNone      Durability = iota // Once the write is in memory, dropping it if the worker's queue is full (fire-and-forget)
+	DurabilityPersisted                   // Once the worker committed the write to the database
+	DurabilityFsync                       // Once the worker committed the write and synced the database to disk
+)
+
+// ParseDurability reads a durability: "none" (or ""), "persisted" or "fsync"
+func ParseDurability(s string) (Durability, error) {
+	switch s {
+	case "", "none":
+		return DurabilityNone, nil
+	case "persisted":
+		return DurabilityPersisted, nil
+	case "fsync":
+		return DurabilityFsync, nil
+	}
+	return DurabilityNone, fmt.Errorf("unknown durability %q (want none, persisted or fsync)", s)
+}
+
+func (d Durability) String() string {
+	switch d {
+	case DurabilityPersisted:
+		return "persisted"
+	case DurabilityFsync:
+		return "fsync"
+	}
+	return "none"
+}
+
+type durabilityKey struct{}
+
+// WithDurability makes writes with ctx to a persisted index return once they have the durability,
+// instead of the index's default
+func WithDurability(ctx context.Context, durability Durability) context.Context {
+	return context.WithValue(ctx, durabilityKey{}, durability)
+}
+
+// durabilityOf returns the durability of writes with ctx: set by WithDurability, or the index's default
+func (p *PersistedSimpleIndex) durabilityOf(ctx context.Context) Durability {
+	if durability, ok := ctx.Value(durabilityKey{}).(Durability); ok {
+		return durability
+	}
+	return p.durability
+}
+
+// SetDurability sets the durability of the writes without one set by WithDurability, including updates;
+// set it before writing
+func (p *PersistedSimpleIndex) SetDurability(durability Durability) {
+	p.durability = durability
+}
+
+// SetNoSync stops the database from syncing to disk on each commit, trading the durability of writes
+// without DurabilityFsync on a crash for throughput
+func (p *PersistedSimpleIndex) SetNoSync(noSync bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.noSync = noSync
+	if p.db != nil {
+		p.db.NoSync = noSync
+	}
+}
+
+// queue hands op to the async worker with the next write ID if the database is open. With DurabilityNone it returns at once,
+// dropping op if the queue is full; otherwise it waits for the worker to apply it (and sync the
+// database), and returns its error. The write is in memory either way.
+func (p *PersistedSimpleIndex) queue(ctx context.Context, op dbOperation, what string) error {
+	p.mu.RLock()
+	open := p.db != nil
+	p.mu.RUnlock()
+	if !open {
+		return nil
+	}
+
+	durability := p.durabilityOf(ctx)
+	if durability != DurabilityNone {
+		if !p.workerRunning.Load() {
+			return fmt.Errorf("%s not persisted: async database worker is not running", what)
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s not persisted: %w", what, err)
+		}
+		op.done = make(chan error, 1)
+		op.sync = durability == DurabilityFsync
+	}
+	queued, err := p.enqueue(ctx, op)
+	if err != nil {
+		return fmt.Errorf("%s not persisted: %w", what, err)
+	}
+	if !queued {
+		log.Warn().Msgf("Async operation queue full, %s operation dropped", what)
+		return nil
+	}
+	log.Debug().Msgf("Queued async %s operation", what)
+	if op.done == nil {
+		return nil
+	}
+	select {
+	case err := <-op.done:
+		if err != nil {
+			return fmt.Errorf("%s not persisted: %w", what, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s not yet persisted: %w", what, ctx.Err())
+	}
+}
+
+// enqueue gives op the next write ID and hands it to the async worker, reporting whether it did. With a
+// write-ahead log, op is logged first and waits for room in the queue; otherwise writes waited on wait
+// until ctx is done, and the others are dropped if the queue is full.
+func (p *PersistedSimpleIndex) enqueue(ctx context.Context, op dbOperation) (bool, error) {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	op.id = p.lastQueued + 1
+	switch {
+	case p.wal != nil:
+		if err := p.wal.append(op); err != nil {
+			return false, err
+		}
+		p.opChan <- op
+	case op.done != nil:
+		select {
+		case p.opChan <- op:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	default:
+		select {
+		case p.opChan <- op:
+		default:
+			return false, nil
+		}
+	}
+	p.lastQueued = op.id
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// storedText reads the text of a document from the database of idx, reporting whether it is stored
+func storedText(t *testing.T, idx *PersistedSimpleIndex, id string) (string, bool) {
+	var doc models.Document
+	found := false
+	assert.NoError(t, idx.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket([]byte("documents")).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &doc)
+	}))
+	return doc.Text, found
+}
+
+func TestParseDurability(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Durability
+		wantErr  bool
+	}{
+		{"", DurabilityNone, false},
+		{"none", DurabilityNone, false},
+		{"persisted", DurabilityPersisted, false},
+		{"fsync", DurabilityFsync, false},
+		{"FSYNC", DurabilityNone, true},
+		{"always", DurabilityNone, true},
+	}
+	for _, test := range tests {
+		durability, err := ParseDurability(test.input)
+		assert.Equal(t, test.wantErr, err != nil, test.input)
+		assert.Equal(t, test.expected, durability, test.input)
+	}
+	assert.Equal(t, "fsync", DurabilityFsync.String())
+}
+
+func TestPersistedSimpleIndex_Durability(t *testing.T) {
+	idx, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer idx.Close()
+
+	// Persisted and fsynced writes are in the database once they return
+	persisted := WithDurability(context.Background(), DurabilityPersisted)
+	assert.NoError(t, idx.AddDocument(persisted, models.Document{ID: "1", Text: "one"}))
+	text, found := storedText(t, idx, "1")
+	assert.True(t, found)
+	assert.Equal(t, "one", text)
+
+	fsynced := WithDurability(context.Background(), DurabilityFsync)
+	assert.NoError(t, idx.AddDocuments(fsynced, []models.Document{{ID: "2", Text: "two"}, {ID: "3", Text: "three"}}))
+	_, found = storedText(t, idx, "3")
+	assert.True(t, found)
+	assert.NoError(t, idx.DeleteDocument(fsynced, "2"))
+	_, found = storedText(t, idx, "2")
+	assert.False(t, found)
+
+	created, err := idx.Upsert(persisted, models.Document{ID: "1", Text: "uno"})
+	assert.NoError(t, err)
+	assert.False(t, created)
+	text, _ = storedText(t, idx, "1")
+	assert.Equal(t, "uno", text)
+
+	// Without syncing on commit, fsynced writes still reach the disk
+	idx.SetNoSync(true)
+	assert.True(t, idx.db.NoSync)
+	assert.NoError(t, idx.AddDocument(fsynced, models.Document{ID: "4", Text: "four"}))
+	_, found = storedText(t, idx, "4")
+	assert.True(t, found)
+
+	// Updates wait as the other writes do
+	assert.NoError(t, idx.UpdateDocument(persisted, "3", models.Document{ID: "3", Text: "tres"}))
+	text, _ = storedText(t, idx, "3")
+	assert.Equal(t, "tres", text)
+	assert.NoError(t, idx.UpdateDocuments(fsynced, []models.Document{{ID: "1", Text: "one"}, {ID: "4", Text: "cuatro"}}))
+	text, _ = storedText(t, idx, "4")
+	assert.Equal(t, "cuatro", text)
+
+	// Writes without a durability take the index's default
+	idx.SetDurability(DurabilityPersisted)
+	assert.NoError(t, idx.UpdateDocument(context.Background(), "3", models.Document{ID: "3", Text: "drei"}))
+	text, _ = storedText(t, idx, "3")
+	assert.Equal(t, "drei", text)
+
+	// Waiting ends with the context
+	cancelled, cancel := context.WithCancel(persisted)
+	cancel()
+	err = idx.queue(cancelled, dbOperation{opType: "add_document", data: models.Document{ID: "5"}}, "add document operation for 5")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, found = storedText(t, idx, "5")
+	assert.False(t, found)
+}
+
+func TestPersistedSimpleIndex_DurabilityConfig(t *testing.T) {
+	cfg := map[string]interface{}{"db_path": filepath.Join(t.TempDir(), "index.db"), "durability": "fsync", "no_sync": true}
+	created, err := NewIndexFactory().Create("persisted", cfg)
+	assert.NoError(t, err)
+	defer created.Close()
+	idx := created.(*PersistedSimpleIndex)
+	assert.Equal(t, DurabilityFsync, idx.durability)
+	assert.True(t, idx.db.NoSync)
+
+	assert.NoError(t, idx.AddDocument(context.Background(), models.Document{ID: "1", Text: "one"}))
+	_, found := storedText(t, idx, "1")
+	assert.True(t, found)
+
+	_, err = NewIndexFactory().Create("persisted", map[string]interface{}{"db_path": filepath.Join(t.TempDir(), "other.db"), "durability": "always"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+package index
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ExportFormat identifies bitscout index exports in their header line
+	ExportFormat = "bitscout-index"
+	// ExportVersion is the export format version written by Export; Import accepts it and older ones
+	ExportVersion = 1
+	// ImportBatchSize is the number of documents Import adds per AddDocuments call
+	ImportBatchSize = 1000
+)
+
+// ExportHeader is the first line of an export. It is followed by one JSON document per line.
+type ExportHeader struct {
+	Format     string                 `json:"format"`
+	Version    int                    `json:"version"`
+	Type       string                 `json:"type"`             // Index type the documents were exported from (e.g. "inverted")
+	Config     map[string]interface{} `json:"config,omitempty"` // Configuration of the exported index, for reference
+	Count      int                    `json:"count"`
+	ExportedAt time.Time              `json:"exportedAt"`
+}
+
+// exportDocuments writes the header and the documents of an index as NDJSON, documents sorted by ID.
+// eachID passes the IDs of the documents to its function; for large indexes they are spilled to a
+// temporary file (in the directory of the config's spill_dir) in sorted runs as they are. The documents
+// are then read with fetch ImportBatchSize at a time; documents deleted meanwhile are left out.
+func exportDocuments(w io.Writer, indexType string, cfg map[string]interface{}, eachID func(func(id string) error) error, fetch func([]string) ([]models.Document, error)) error {
+	dir, err := config.String(cfg, "spill_dir", "")
+	if err != nil {
+		return err
+	}
+	sorter := newIDSorter(dir)
+	if err := eachID(sorter.add); err != nil {
+		sorter.Close()
+		return err
+	}
+	count := sorter.count
+	source, err := sorter.sorted()
+	if err != nil {
+		sorter.Close()
+		return err
+	}
+	defer source.Close()
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	header := ExportHeader{
+		Format:     ExportFormat,
+		Version:    ExportVersion,
+		Type:       indexType,
+		Config:     cfg,
+		Count:      count,
+		ExportedAt: time.Now().UTC(),
+	}
+	if err := encoder.Encode(header); err != nil {
+		return fmt.Errorf("failed to write export header: %w", err)
+	}
+	exported := 0
+	err = source.batches(ImportBatchSize, func(batch []string) error {
+		docs, err := fetch(batch)
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err := encoder.Encode(doc); err != nil {
+				return fmt.Errorf("failed to export document %s: %w", doc.ID, err)
+			}
+		}
+		exported += len(docs)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if exported != count {
+		log.Info().Msgf("%d documents were deleted while exporting the %s index", count-exported, indexType)
+	}
+	log.Info().Msgf("Exported %d documents from %s index", exported, indexType)
+	return nil
+}
+
+// importDocuments reads an export and passes its documents to add in batches of ImportBatchSize.
+// Documents replace existing ones with the same ID; the header config is not applied.
+func importDocuments(r io.Reader, indexType string, add func(context.Context, []models.Document) error) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	var header ExportHeader
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("failed to read export header: %w", err)
+	}
+	if header.Format != ExportFormat {
+		return fmt.Errorf("not a bitscout index export (format %q)", header.Format)
+	}
+	if header.Version < 1 || header.Version > ExportVersion {
+		return fmt.Errorf("unsupported export version %d (supported: 1 to %d)", header.Version, ExportVersion)
+	}
+	if header.Type != indexType {
+		log.Info().Msgf("Importing documents exported from a %s index into a %s index", header.Type, indexType)
+	}
+
+	imported := 0
+	batch := make([]models.Document, 0, ImportBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := add(context.Background(), batch); err != nil {
+			return fmt.Errorf("failed to import documents %d to %d: %w", imported+1, imported+len(batch), err)
+		}
+		imported += len(batch)
+		batch = make([]models.Document, 0, ImportBatchSize)
+		return nil
+	}
+	for line := 2; ; line++ {
+		var doc models.Document
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid document on line %d: %w", line, err)
+		}
+		if doc.ID == "" {
+			return fmt.Errorf("document on line %d has no id", line)
+		}
+		batch = append(batch, doc)
+		if len(batch) == ImportBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if imported != header.Count {
+		log.Warn().Msgf("Export header lists %d documents, imported %d", header.Count, imported)
+	}
+	log.Info().Msgf("Imported %d documents into %s index", imported, indexType)
+	return nil
+}
+
+// eachDocumentID calls fn with the ID of every stored document, until it fails. Writes wait until it
+// returns.
+func (idx *SimpleIndex) eachDocumentID(fn func(id string) error) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	for id := range idx.documents {
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch returns the stored documents of ids, in order, leaving out those no longer stored
+func (idx *SimpleIndex) fetch(ids []string) ([]models.Document, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	docs := make([]models.Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := idx.documents[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// Export writes the configuration and every document of the index as NDJSON
+func (idx *SimpleIndex) Export(w io.Writer) error {
+	return idx.export(w, "simple")
+}
+
+func (idx *SimpleIndex) export(w io.Writer, indexType string) error {
+	config, err := idx.ShowConfig()
+	if err != nil {
+		return err
+	}
+	return exportDocuments(w, indexType, config, idx.eachDocumentID, idx.fetch)
+}
+
+// Import adds the documents of an export, replacing documents with the same ID
+func (idx *SimpleIndex) Import(r io.Reader) error {
+	return importDocuments(r, "simple", idx.AddDocuments)
+}
+
+// Export writes the configuration and every document of the index as NDJSON, with the fields kept in
+// its field store
+func (idx *InvertedIndex) Export(w io.Writer) error {
+	config, err := idx.store.ShowConfig()
+	if err != nil {
+		return err
+	}
+	return exportDocuments(w, "inverted", config, idx.store.eachDocumentID, func(ids []string) ([]models.Document, error) {
+		idx.mu.RLock()
+		defer idx.mu.RUnlock()
+		docs, err := idx.store.fetch(ids)
+		if err != nil {
+			return nil, err
+		}
+		return loadFields(idx.stored, docs)
+	})
+}
+
+// Import adds the documents of an export and indexes their terms, replacing documents with the same ID
+func (idx *InvertedIndex) Import(r io.Reader) error {
+	return importDocuments(r, "inverted", idx.AddDocuments)
+}
+
+// Export writes the configuration and every document of the index as NDJSON
+func (idx *VectorIndex) Export(w io.Writer) error {
+	return idx.store.export(w, "vector")
+}
+
+// Import adds the documents of an export, replacing documents with the same ID. Documents whose vectors
+// do not match the index's vector_size fail the import.
+func (idx *VectorIndex) Import(r io.Reader) error {
+	return importDocuments(r, "vector", idx.AddDocuments)
+}
+
+// Export writes the configuration and every document of the index as NDJSON
+func (p *PersistedSimpleIndex) Export(w io.Writer) error {
+	return p.index.export(w, "persisted")
+}
+
+// Import adds the documents of an export and persists them asynchronously, replacing documents with the
+// same ID
+func (p *PersistedSimpleIndex) Import(r io.Reader) error {
+	return importDocuments(r, "persisted", p.AddDocuments)
+}
@@ -0,0 +1,117 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	source := NewSimpleIndex()
+	assert.NoError(t, source.Configure(map[string]interface{}{"max_results": 10}))
+	docs := []models.Document{
+		makeTestDoc("2", "second document", "b.txt", map[string]string{"fileExtension": "txt"}, []float64{0, 1}),
+		makeTestDoc("1", "first document", "a.go", map[string]string{"fileExtension": "go"}, []float64{1, 0}),
+	}
+	assert.NoError(t, source.AddDocuments(context.Background(), docs))
+
+	var buf bytes.Buffer
+	assert.NoError(t, source.Export(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"format":"bitscout-index"`)
+	assert.Contains(t, lines[0], `"type":"simple"`)
+	assert.Contains(t, lines[1], `"id":"1"`, "documents are sorted by ID")
+
+	target := NewInvertedIndex(nil)
+	assert.NoError(t, target.Import(bytes.NewReader(buf.Bytes())))
+	count, _ := target.Count()
+	assert.Equal(t, 2, count)
+	results, err := target.Search(context.Background(), "first")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, docs[1], results[0])
+}
+
+func TestImport_Invalid(t *testing.T) {
+	idx := NewSimpleIndex()
+	assert.Error(t, idx.Import(strings.NewReader(`{"id":"1"}`+"\n")), "missing header")
+	assert.Error(t, idx.Import(strings.NewReader(`{"format":"bitscout-index","version":99}`+"\n")))
+
+	header := `{"format":"bitscout-index","version":1,"type":"simple","count":2}` + "\n"
+	err := idx.Import(strings.NewReader(header + `{"id":"1","text":"ok"}` + "\n" + `{"text":"no id"}` + "\n"))
+	assert.ErrorContains(t, err, "line 3")
+}
+
+func TestVectorIndex_ImportChecksVectorSize(t *testing.T) {
+	source := NewSimpleIndex()
+	assert.NoError(t, source.AddDocument(context.Background(), makeTestDoc("1", "a", "a.txt", nil, []float64{1, 2, 3})))
+	var buf bytes.Buffer
+	assert.NoError(t, source.Export(&buf))
+
+	idx, err := NewVectorIndex("cosine", 1, 2)
+	assert.NoError(t, err)
+	assert.Error(t, idx.Import(&buf))
+}
+
+func TestExport_SpillsLargeIndexes(t *testing.T) {
+	threshold := spillThreshold
+	spillThreshold = 3
+	t.Cleanup(func() { spillThreshold = threshold })
+	dir := t.TempDir()
+
+	source := NewSimpleIndex()
+	assert.NoError(t, source.Configure(map[string]interface{}{"spill_dir": dir}))
+	var want []string
+	for i := 0; i < 7; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		want = append(want, id)
+		assert.NoError(t, source.AddDocument(context.Background(), makeTestDoc(id, "text", id+".txt", nil, nil)))
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, source.Export(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Contains(t, lines[0], `"count":7`)
+	var ids []string
+	for _, line := range lines[1:] {
+		var doc models.Document
+		assert.NoError(t, json.Unmarshal([]byte(line), &doc))
+		ids = append(ids, doc.ID)
+	}
+	assert.Equal(t, want, ids)
+
+	// The spill file is removed once the export is written
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// IDs are spilled in sorted runs as they are added, and merged when read
+	sorter := newIDSorter(dir)
+	for _, id := range []string{"d", "ünïcode", "a", "", "b", "c"} {
+		assert.NoError(t, sorter.add(id))
+		assert.LessOrEqual(t, len(sorter.held), spillThreshold)
+	}
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "the first run is spilled before the IDs are all added")
+	spilled, err := sorter.sorted()
+	assert.NoError(t, err)
+	var batches [][]string
+	assert.NoError(t, spilled.batches(4, func(batch []string) error {
+		batches = append(batches, append([]string{}, batch...))
+		return nil
+	}))
+	assert.Equal(t, [][]string{{"", "a", "b", "c"}, {"d", "ünïcode"}}, batches)
+	assert.NoError(t, spilled.Close())
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
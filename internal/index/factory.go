@@ -0,0 +1,203 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// IndexConstructor builds an index from the "config" map of an index entry in the starter config
+type IndexConstructor func(cfg map[string]interface{}) (Index, error)
+
+// IndexFactory instantiates indexes by type name
+type IndexFactory struct {
+	constructors map[string]IndexConstructor
+}
+
+// NewIndexFactory creates a factory with the built-in index types registered.
+// The struct names (e.g. "SimpleIndex") are accepted as aliases of the short type names.
+func NewIndexFactory() *IndexFactory {
+	f := &IndexFactory{constructors: make(map[string]IndexConstructor)}
+	f.RegisterType("simple", newSimpleIndexFromConfig)
+	f.RegisterType("persisted", newPersistedSimpleIndexFromConfig)
+	f.RegisterType("inverted", newInvertedIndexFromConfig)
+	f.RegisterType("vector", newVectorIndexFromConfig)
+	f.RegisterType("remote", newRemoteIndexFromConfig)
+	f.RegisterType("SimpleIndex", newSimpleIndexFromConfig)
+	f.RegisterType("PersistedSimpleIndex", newPersistedSimpleIndexFromConfig)
+	f.RegisterType("InvertedIndex", newInvertedIndexFromConfig)
+	f.RegisterType("VectorIndex", newVectorIndexFromConfig)
+	f.RegisterType("RemoteIndex", newRemoteIndexFromConfig)
+	return f
+}
+
+// RegisterType adds (or replaces) the constructor for an index type
+func (f *IndexFactory) RegisterType(typeName string, constructor IndexConstructor) {
+	f.constructors[typeName] = constructor
+}
+
+// Types returns the registered index type names, sorted
+func (f *IndexFactory) Types() []string {
+	types := make([]string, 0, len(f.constructors))
+	for typeName := range f.constructors {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Create instantiates an index of the given type and configures it with its config map
+func (f *IndexFactory) Create(typeName string, cfg map[string]interface{}) (Index, error) {
+	constructor, ok := f.constructors[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown index type %s (known types: %v)", typeName, f.Types())
+	}
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	idx, err := constructor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s index: %w", typeName, err)
+	}
+	if err := idx.Configure(cfg); err != nil {
+		idx.Close()
+		return nil, fmt.Errorf("failed to configure %s index: %w", typeName, err)
+	}
+	log.Info().Msgf("IndexFactory: created %s index", typeName)
+	return idx, nil
+}
+
+func newSimpleIndexFromConfig(cfg map[string]interface{}) (Index, error) {
+	readOnly, err := config.Bool(cfg, "read_only", false)
+	if err != nil {
+		return nil, err
+	}
+	idx := NewSimpleIndex()
+	idx.SetReadOnly(readOnly)
+	return idx, nil
+}
+
+func newPersistedSimpleIndexFromConfig(cfg map[string]interface{}) (Index, error) {
+	dbPath, err := config.String(cfg, "db_path", "./data/index.db")
+	if err != nil {
+		return nil, err
+	}
+	readOnly, err := config.Bool(cfg, "read_only", false)
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		return NewPersistedSimpleIndexReadOnly(dbPath)
+	}
+	load, err := config.Bool(cfg, "load", true)
+	if err != nil {
+		return nil, err
+	}
+	durabilityName, err := config.String(cfg, "durability", "none")
+	if err != nil {
+		return nil, err
+	}
+	durability, err := ParseDurability(durabilityName)
+	if err != nil {
+		return nil, err
+	}
+	noSync, err := config.Bool(cfg, "no_sync", false)
+	if err != nil {
+		return nil, err
+	}
+	retries, err := config.Int(cfg, "write_retries", defaultWriteRetries)
+	if err != nil {
+		return nil, err
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("write_retries must not be negative, got %d", retries)
+	}
+	backoff, err := config.Duration(cfg, "write_retry_backoff", defaultWriteRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+	wal, err := config.Bool(cfg, "wal", false)
+	if err != nil {
+		return nil, err
+	}
+	var idx *PersistedSimpleIndex
+	if load {
+		idx, err = NewPersistedSimpleIndexWithDatabaseAndLoad(dbPath)
+	} else {
+		idx, err = NewPersistedSimpleIndexWithDatabase(dbPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx.SetDurability(durability)
+	idx.SetNoSync(noSync)
+	idx.SetWriteRetries(retries, backoff)
+	if wal {
+		if err := idx.EnableWAL(); err != nil {
+			idx.Close()
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+func newInvertedIndexFromConfig(cfg map[string]interface{}) (Index, error) {
+	analyzer, err := NewAnalyzerFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFieldMapping(cfg)
+	if err != nil {
+		return nil, err
+	}
+	storePath, err := config.String(cfg, "field_store_path", "")
+	if err != nil {
+		return nil, err
+	}
+	ranking, err := parseRanking(cfg)
+	if err != nil {
+		return nil, err
+	}
+	languages, err := parseLanguageAnalyzers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	idx := NewInvertedIndex(analyzer)
+	idx.SetRanking(ranking)
+	if err := idx.SetLanguages(languages); err != nil {
+		return nil, err
+	}
+	if err := idx.SetFields(fields, storePath); err != nil {
+		return nil, err
+	}
+	budget, err := config.Int(cfg, "memory_budget_mb", 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.SetMemoryBudget(budget<<20, storePath); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func newVectorIndexFromConfig(cfg map[string]interface{}) (Index, error) {
+	metric, err := config.String(cfg, "metric", "cosine")
+	if err != nil {
+		return nil, err
+	}
+	k, err := config.Int(cfg, "k", 10)
+	if err != nil {
+		return nil, err
+	}
+	size, err := config.Int(cfg, "vector_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewVectorIndex(metric, k, size)
+}
+
+func newRemoteIndexFromConfig(cfg map[string]interface{}) (Index, error) {
+	return NewRemoteIndex(cfg)
+}
@@ -0,0 +1,58 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexFactory_CreateBuiltInTypes(t *testing.T) {
+	factory := NewIndexFactory()
+
+	idx, err := factory.Create("simple", map[string]interface{}{"max_results": 10.0})
+	assert.NoError(t, err)
+	assert.IsType(t, &SimpleIndex{}, idx)
+	cfg, _ := idx.ShowConfig()
+	assert.Equal(t, 10.0, cfg["max_results"])
+
+	idx, err = factory.Create("inverted", map[string]interface{}{"analyzer": "english"})
+	assert.NoError(t, err)
+	assert.Equal(t, "english", idx.(*InvertedIndex).analyzer.Name)
+
+	idx, err = factory.Create("vector", map[string]interface{}{"metric": "dot", "k": 3.0})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, idx.(*VectorIndex).k)
+
+	idx, err = factory.Create("persisted", map[string]interface{}{"db_path": filepath.Join(t.TempDir(), "index.db")})
+	assert.NoError(t, err)
+	assert.IsType(t, &PersistedSimpleIndex{}, idx)
+	assert.NoError(t, idx.Close())
+}
+
+func TestIndexFactory_CreateReadOnly(t *testing.T) {
+	factory := NewIndexFactory()
+
+	idx, err := factory.Create("simple", map[string]interface{}{"read_only": true})
+	assert.NoError(t, err)
+	assert.True(t, idx.(*SimpleIndex).ReadOnly())
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx, err = factory.Create("persisted", map[string]interface{}{"db_path": dbPath})
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Close())
+	idx, err = factory.Create("persisted", map[string]interface{}{"db_path": dbPath, "read_only": true})
+	assert.NoError(t, err)
+	assert.True(t, idx.(*PersistedSimpleIndex).readOnly)
+	assert.NoError(t, idx.Close())
+}
+
+func TestIndexFactory_CreateErrors(t *testing.T) {
+	factory := NewIndexFactory()
+
+	_, err := factory.Create("btree", nil)
+	assert.Error(t, err)
+
+	_, err = factory.Create("inverted", map[string]interface{}{"analyzer": "klingon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Stored vs. indexed fields. A field can be searchable without being kept in memory with its document
+ * (e.g. huge text bodies), or kept without being searchable. Fields that are not stored are written to a
+ * bbolt field store and read back for the documents a search returns.
+ **/
+
+// FieldOptions sets whether a field's terms are searchable and whether it is kept in memory with its
+// document. Fields without options are both.
+type FieldOptions struct {
+	Index bool
+	Store bool
+}
+
+// FieldMapping holds the options of fields by name: "text", "source", "vector" or a metadata key
+type FieldMapping map[string]FieldOptions
+
+// parseFieldMapping reads the "fields" config of an index, e.g. {"text": {"store": false}}
+func parseFieldMapping(cfg map[string]interface{}) (FieldMapping, error) {
+	fields, err := config.Map(cfg, "fields")
+	if err != nil || fields == nil {
+		return nil, err
+	}
+	mapping := make(FieldMapping, len(fields))
+	for name, value := range fields {
+		options, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("options of field %s must be an object, got %T", name, value)
+		}
+		if name == "id" {
+			return nil, fmt.Errorf("the id is always indexed and stored")
+		}
+		var field FieldOptions
+		if field.Index, err = config.Bool(options, "index", true); err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		if field.Store, err = config.Bool(options, "store", true); err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		mapping[name] = field
+	}
+	return mapping, nil
+}
+
+// indexed reports whether the terms of a field are searchable
+func (m FieldMapping) indexed(name string) bool {
+	options, ok := m[name]
+	return !ok || options.Index
+}
+
+// unstored reports whether some field is not kept in memory
+func (m FieldMapping) unstored() bool {
+	for _, options := range m {
+		if !options.Store {
+			return true
+		}
+	}
+	return false
+}
+
+// split separates the fields of doc kept in memory from those that are not (nil: every field is kept)
+func (m FieldMapping) split(doc models.Document) (models.Document, *models.Document) {
+	var rest *models.Document
+	take := func() *models.Document {
+		if rest == nil {
+			rest = &models.Document{ID: doc.ID}
+		}
+		return rest
+	}
+	for name, options := range m {
+		if options.Store {
+			continue
+		}
+		switch name {
+		case "text":
+			if doc.Text != "" {
+				take().Text, doc.Text = doc.Text, ""
+			}
+		case "source":
+			if doc.Source != "" {
+				take().Source, doc.Source = doc.Source, ""
+			}
+		case "vector":
+			if doc.Vector != nil {
+				take().Vector, doc.Vector = doc.Vector, nil
+			}
+		default:
+			value, ok := doc.Meta[name]
+			if !ok {
+				continue
+			}
+			r := take()
+			if r.Meta == nil {
+				r.Meta = make(map[string]string)
+				// The indexed document's metadata is shared with the caller
+				doc.Meta = copyMetadata(doc.Meta)
+			}
+			r.Meta[name] = value
+			delete(doc.Meta, name)
+		}
+	}
+	return doc, rest
+}
+
+// mergeFields adds the fields read back from a field store to a document
+func mergeFields(doc, rest models.Document) models.Document {
+	if rest.Text != "" {
+		doc.Text = rest.Text
+	}
+	if rest.Source != "" {
+		doc.Source = rest.Source
+	}
+	if rest.Vector != nil {
+		doc.Vector = rest.Vector
+	}
+	if len(rest.Meta) > 0 {
+		doc.Meta = copyMetadata(doc.Meta)
+		for key, value := range rest.Meta {
+			doc.Meta[key] = value
+		}
+	}
+	return doc
+}
+
+func copyMetadata(meta map[string]string) map[string]string {
+	out := make(map[string]string, len(meta))
+	for key, value := range meta {
+		out[key] = value
+	}
+	return out
+}
+
+var fieldsBucket = []byte("fields")
+
+// fieldStore keeps the fields that are not stored in memory in a bbolt database, by document ID
+type fieldStore struct {
+	db    *bbolt.DB
+	reads atomic.Int64 // Documents whose fields were read back
+}
+
+// openFieldStore opens (or creates) the field store at path. Its previous content is dropped, as the
+// in-memory index it belongs to starts empty.
+func openFieldStore(path string) (*fieldStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create field store directory: %w", err)
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open field store: %w", err)
+	}
+	store := &fieldStore{db: db}
+	if err := store.clear(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create fields bucket: %w", err)
+	}
+	log.Info().Msgf("Opened field store at %s", path)
+	return store, nil
+}
+
+// clear drops the fields of every document
+func (s *fieldStore) clear() error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(fieldsBucket) != nil {
+			if err := tx.DeleteBucket(fieldsBucket); err != nil {
+				return err
+			}
+		}
+		_, err := tx.CreateBucket(fieldsBucket)
+		return err
+	})
+}
+
+// put writes the unstored fields of documents, and removes those of the documents without any (nil)
+func (s *fieldStore) put(ids []string, rests []*models.Document) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(fieldsBucket)
+		for i, rest := range rests {
+			if rest == nil {
+				if err := bucket.Delete([]byte(ids[i])); err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := json.Marshal(rest)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(ids[i]), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// delete removes the unstored fields of documents
+func (s *fieldStore) delete(ids []string) error {
+	return s.put(ids, make([]*models.Document, len(ids)))
+}
+
+// load adds their unstored fields to docs
+func (s *fieldStore) load(docs []models.Document) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(fieldsBucket)
+		for i, doc := range docs {
+			data := bucket.Get([]byte(doc.ID))
+			if data == nil {
+				continue
+			}
+			var rest models.Document
+			if err := json.Unmarshal(data, &rest); err != nil {
+				return fmt.Errorf("field store: document %s: %w", doc.ID, err)
+			}
+			docs[i] = mergeFields(doc, rest)
+			s.reads.Add(1)
+		}
+		return nil
+	})
+}
+
+func (s *fieldStore) close() error {
+	return s.db.Close()
+}
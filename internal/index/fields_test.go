@@ -0,0 +1,72 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestInvertedIndex_UnstoredFields(t *testing.T) {
+	ctx := context.Background()
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"fields": map[string]interface{}{
+			"text":  map[string]interface{}{"store": false},
+			"owner": map[string]interface{}{"index": false},
+		},
+		"field_store_path": filepath.Join(t.TempDir(), "fields.db"),
+	})
+	assert.NoError(t, err)
+	defer idx.Close()
+	doc := models.Document{ID: "1", Text: strings.Repeat("quarterly report ", 100), Source: "/a.txt", Meta: map[string]string{"owner": "finance"}}
+	assert.NoError(t, idx.AddDocument(ctx, doc))
+
+	// The text is searchable and read back from the field store; the owner is kept but not searchable
+	found, err := idx.Search(ctx, "quarterly")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{doc}, found)
+	found, err = idx.Search(ctx, "finance")
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+	found, err = idx.Search(ctx, "owner=finance")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{doc}, found)
+
+	size, err := idx.Size()
+	assert.NoError(t, err)
+	assert.Less(t, size, len(doc.Text))
+
+	var export bytes.Buffer
+	assert.NoError(t, idx.Export(&export))
+	assert.Contains(t, export.String(), "quarterly report")
+
+	doc.Text = "annual report"
+	assert.NoError(t, idx.UpdateDocument(ctx, "1", doc))
+	found, err = idx.Search(ctx, "annual")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{doc}, found)
+	assert.NoError(t, idx.DeleteDocument(ctx, "1"))
+	found, err = idx.Search(ctx, "annual")
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestInvertedIndex_DroppedFields(t *testing.T) {
+	ctx := context.Background()
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.SetFields(FieldMapping{"text": {Index: true, Store: false}}, ""))
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "1", Text: "quarterly report", Source: "/a.txt"}))
+
+	found, err := idx.Search(ctx, "quarterly")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "1", Source: "/a.txt"}}, found)
+	assert.ErrorContains(t, idx.SetFields(nil, ""), "before documents are added")
+
+	_, err = NewIndexFactory().Create("inverted", map[string]interface{}{"fields": map[string]interface{}{"id": map[string]interface{}{"store": false}}})
+	assert.ErrorContains(t, err, "always indexed and stored")
+}
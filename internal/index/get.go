@@ -0,0 +1,101 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// firstDocument returns the document of a get of one ID, failing with ErrDocumentNotFound if there is none
+func firstDocument(id string, docs []models.Document, err error) (models.Document, error) {
+	if err != nil {
+		return models.Document{}, err
+	}
+	if len(docs) == 0 {
+		return models.Document{}, fmt.Errorf("document %s %w", id, ErrDocumentNotFound)
+	}
+	return docs[0], nil
+}
+
+// GetDocument returns the document with an ID, failing with ErrDocumentNotFound if it is not indexed or
+// not visible to the principals of ctx
+func (idx *SimpleIndex) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	docs, err := idx.GetDocuments(ctx, []string{id})
+	return firstDocument(id, docs, err)
+}
+
+// GetDocuments returns the documents with the IDs, in their order, leaving out those not indexed or not
+// visible to the principals of ctx
+func (idx *SimpleIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	principals, restricted := principalsFrom(ctx)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	docs := make([]models.Document, 0, len(ids))
+	for _, id := range ids {
+		doc, ok := idx.documents[id]
+		if !ok || restricted && !doc.VisibleTo(principals) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// GetDocument returns the document with an ID from memory
+func (p *PersistedSimpleIndex) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	return p.index.GetDocument(ctx, id)
+}
+
+// GetDocuments returns the documents with the IDs from memory
+func (p *PersistedSimpleIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	return p.index.GetDocuments(ctx, ids)
+}
+
+// GetDocument returns the document with an ID, with the fields kept in the field store
+func (idx *InvertedIndex) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	docs, err := idx.GetDocuments(ctx, []string{id})
+	return firstDocument(id, docs, err)
+}
+
+// GetDocuments returns the documents with the IDs, with the fields kept in the field store
+func (idx *InvertedIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	docs, err := idx.store.GetDocuments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	idx.mu.RLock()
+	stored := idx.stored
+	idx.mu.RUnlock()
+	return loadFields(stored, docs)
+}
+
+// GetDocument returns the document with an ID
+func (idx *VectorIndex) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	return idx.store.GetDocument(ctx, id)
+}
+
+// GetDocuments returns the documents with the IDs
+func (idx *VectorIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	return idx.store.GetDocuments(ctx, ids)
+}
+
+// GetDocument returns the document with an ID from the remote index
+func (idx *RemoteIndex) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	docs, err := idx.GetDocuments(ctx, []string{id})
+	return firstDocument(id, docs, err)
+}
+
+// GetDocuments returns the documents with the IDs from the remote index, in one request
+func (idx *RemoteIndex) GetDocuments(ctx context.Context, ids []string) ([]models.Document, error) {
+	var response struct {
+		Documents []models.Document `json:"documents"`
+	}
+	if err := idx.endpoint().post(ctx, "/documents/mget", map[string][]string{"ids": ids}, &response); err != nil {
+		return nil, err
+	}
+	return response.Documents, nil
+}
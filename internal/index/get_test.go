@@ -0,0 +1,58 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestIndexes_GetDocuments(t *testing.T) {
+	persisted, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer persisted.Close()
+	vector, err := NewVectorIndex("cosine", 2, 2)
+	assert.NoError(t, err)
+	indexes := map[string]Index{
+		"simple":    NewSimpleIndex(),
+		"persisted": persisted,
+		"inverted":  NewInvertedIndex(nil),
+		"vector":    vector,
+	}
+	ctx := context.Background()
+	for name, idx := range indexes {
+		docs := []models.Document{
+			{ID: "1", Text: "first", Vector: []float64{1, 0}},
+			{ID: "2", Text: "second", Vector: []float64{0, 1}},
+			{ID: "3", Text: "secret", Vector: []float64{1, 1}, AllowedPrincipals: []string{"alice"}},
+		}
+		assert.NoError(t, idx.AddDocuments(ctx, docs), name)
+
+		doc, err := idx.GetDocument(ctx, "2")
+		assert.NoError(t, err, name)
+		assert.Equal(t, "second", doc.Text, name)
+		_, err = idx.GetDocument(ctx, "missing")
+		assert.ErrorIs(t, err, ErrDocumentNotFound, name)
+
+		// Documents come back in the order of their IDs, without the missing ones
+		got, err := idx.GetDocuments(ctx, []string{"3", "missing", "1"})
+		assert.NoError(t, err, name)
+		if assert.Len(t, got, 2, name) {
+			assert.Equal(t, "3", got[0].ID, name)
+			assert.Equal(t, "1", got[1].ID, name)
+		}
+
+		// Principals see only the documents they are allowed to
+		got, err = idx.GetDocuments(WithPrincipals(ctx, []string{"bob"}), []string{"1", "3"})
+		assert.NoError(t, err, name)
+		assert.Len(t, got, 1, name)
+		_, err = idx.GetDocument(WithPrincipals(ctx, []string{"bob"}), "3")
+		assert.ErrorIs(t, err, ErrDocumentNotFound, name)
+		doc, err = idx.GetDocument(WithPrincipals(ctx, []string{"alice"}), "3")
+		assert.NoError(t, err, name)
+		assert.Equal(t, "secret", doc.Text, name)
+	}
+}
@@ -1,38 +1,105 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"io"
+
 	"github.com/aawadall/bit-scout/internal/models"
 )
 
 /* Index Interface */
 
+// ErrReadOnly is returned by the mutations of an index opened or configured read-only
+var ErrReadOnly = errors.New("index is read-only")
+
+// Index is implemented by every index type. Searches stop early, returning ctx.Err(), once ctx is
+// cancelled or its deadline passes; adds and deletes fail without changing the index if it is.
 type Index interface {
 	// Configures the index
 	Configure(config map[string]interface{}) error
 	// Shows the current index configuration
 	ShowConfig() (map[string]interface{}, error)
 	// Adds document to current index
-	AddDocument(models.Document) error
+	AddDocument(ctx context.Context, doc models.Document) error
 	// Adds multiple documents to current index
-	AddDocuments([]models.Document) error
+	AddDocuments(ctx context.Context, docs []models.Document) error
 	// Searches for documents matching the query
-	Search(query string) ([]models.Document, error)
+	Search(ctx context.Context, query string) ([]models.Document, error)
+	// Returns the document with an ID (ErrDocumentNotFound: none visible to the principals of ctx)
+	GetDocument(ctx context.Context, id string) (models.Document, error)
+	// Returns the documents with the IDs, in their order, leaving out those not found
+	GetDocuments(ctx context.Context, ids []string) ([]models.Document, error)
+	// Returns a page of the documents visible to the principals of ctx, in ID order
+	ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error)
+	// Parses, validates and plans a query once, to be executed many times
+	PrepareQuery(query string) (PreparedQuery, error)
 	// Deletes a document from the index
-	DeleteDocument(id string) error
+	DeleteDocument(ctx context.Context, id string) error
 	// Deletes multiple documents from the index
-	DeleteDocuments([]string) error
+	DeleteDocuments(ctx context.Context, ids []string) error
 	// Updates a document in the index
-	UpdateDocument(id string, document models.Document) error
+	UpdateDocument(ctx context.Context, id string, document models.Document) error
 	// Updates multiple documents in the index
-	UpdateDocuments([]models.Document) error
+	UpdateDocuments(ctx context.Context, docs []models.Document) error
 	// Closes the index
 	Close() error
 	// Flushes the index to disk
 	Flush() error
 	// Optimizes the index for faster search
 	Optimize() error
+	// Removes every document, keeping the configuration
+	Clear() error
 	// Returns the number of documents in the index
 	Count() (int, error)
 	// Returns the size of the index in bytes
 	Size() (int, error)
+	// Writes the index configuration and documents as NDJSON (see ExportHeader)
+	Export(w io.Writer) error
+	// Adds the documents of an export, replacing documents with the same ID
+	Import(r io.Reader) error
+}
+
+// TermStats are the statistics an index ranks a free-text query with (TF-IDF), so the results of
+// several indexes (e.g. the nodes of a cluster) can be ranked together
+type TermStats struct {
+	Terms      []string                  // Analyzed query terms (none: the query is not ranked)
+	Documents  int                       // Documents in the index
+	DocFreq    map[string]int            // Documents containing each term
+	TermFreq   map[string]map[string]int // Frequency of each term in each requested document, by ID
+	Boosts     map[string]float64        // Function score of each requested document, added to its weighted TF-IDF (nil: none)
+	TextWeight float64                   // Weight of the TF-IDF of boosted documents
+	Script     string                    // Scoring script applied to the score of boosted documents ("": none)
+}
+
+// TermScorer is implemented by indexes that rank free-text queries by term statistics
+type TermScorer interface {
+	TermStats(query string, ids []string) (TermStats, error)
+}
+
+// ResultCapper is implemented by indexes whose config caps the results of each search (max_results).
+// The engine applies the cap once it has filtered the results for the caller and sorted them, so the
+// index returns every match.
+type ResultCapper interface {
+	// Returns the most results a search returns (0: all)
+	MaxResults() int
+}
+
+// HealthChecker is implemented by indexes that depend on resources which can fail at runtime
+// (e.g. a database and its background writer)
+type HealthChecker interface {
+	// Returns an error describing what is unhealthy, or nil
+	HealthCheck() error
+}
+
+// cancelCheckInterval is the number of documents scans evaluate between checks of their context
+const cancelCheckInterval = 256
+
+// checkCancelled returns ctx.Err() on every cancelCheckInterval-th document of a scan, so long scans
+// stop promptly without checking the context for every document
+func checkCancelled(ctx context.Context, scanned int) error {
+	if scanned%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
 }
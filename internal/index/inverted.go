@@ -0,0 +1,561 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// InvertedIndex is an in-memory index that keeps a term -> document posting list built by an Analyzer.
+// Free-text queries are answered from the postings and ranked by TF-IDF, combined with the boosts of
+// its Ranking; dimension queries
+// (e.g. "fileExtension=go") fall back to the stored documents like SimpleIndex.
+type InvertedIndex struct {
+	store    *SimpleIndex
+	analyzer *Analyzer
+	postings map[string]map[string]int // term -> document ID -> term frequency
+	docTerms map[string][]string       // document ID -> distinct terms, used to unindex documents
+	// Analyzers of the documents of each language, and the languages of the documents analyzed with one
+	languages    LanguageAnalyzers
+	docLanguages map[string]string // document ID -> language
+	fields       FieldMapping      // Fields that are not searchable or not kept in store (nil: all are both)
+	stored       *fieldStore       // Fields that are not kept in store (nil: dropped)
+	ranking      Ranking
+	budget       memoryBudget // Memory the documents may hold before their text is evicted to stored
+	mu           sync.RWMutex
+}
+
+// NewInvertedIndex creates a new InvertedIndex using the given analyzer (nil means the standard analyzer)
+func NewInvertedIndex(analyzer *Analyzer) *InvertedIndex {
+	if analyzer == nil {
+		analyzer, _ = NewAnalyzer("standard")
+	}
+	return &InvertedIndex{
+		store:        NewSimpleIndex(),
+		analyzer:     analyzer,
+		postings:     make(map[string]map[string]int),
+		docTerms:     make(map[string][]string),
+		languages:    LanguageAnalyzers{Field: defaultLanguageField},
+		docLanguages: make(map[string]string),
+		ranking:      DefaultRanking(),
+	}
+}
+
+// SetRanking sets how free-text results are ranked: the weight of their text relevance, the boosts of
+// their fields and the script scoring them
+func (idx *InvertedIndex) SetRanking(ranking Ranking) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ranking = ranking
+}
+
+// Configure validates and sets the index configuration, warning about the keys it does not know
+func (idx *InvertedIndex) Configure(config map[string]interface{}) error {
+	return idx.store.configure(config, invertedConfigKeys)
+}
+
+// MaxResults returns the max_results of the index config (0: none)
+func (idx *InvertedIndex) MaxResults() int {
+	return idx.store.MaxResults()
+}
+
+// ShowConfig returns the current index configuration
+func (idx *InvertedIndex) ShowConfig() (map[string]interface{}, error) {
+	return idx.store.ShowConfig()
+}
+
+// SetFields sets which fields are searchable and which are kept in memory with their documents. Fields
+// that are not kept are written to a field store at storePath and read back for search results ("": they
+// are dropped, so documents are found by them but returned without them). Set the fields before adding
+// documents.
+func (idx *InvertedIndex) SetFields(fields FieldMapping, storePath string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(idx.docTerms) > 0 {
+		return fmt.Errorf("fields must be set before documents are added")
+	}
+	var stored *fieldStore
+	if storePath != "" && fields.unstored() {
+		var err error
+		if stored, err = openFieldStore(storePath); err != nil {
+			return err
+		}
+	}
+	if idx.stored != nil {
+		idx.stored.close()
+	}
+	idx.fields, idx.stored = fields, stored
+	return nil
+}
+
+// SetLanguages analyzes the documents of each language with its analyzer. Set them before adding
+// documents.
+func (idx *InvertedIndex) SetLanguages(languages LanguageAnalyzers) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(idx.docTerms) > 0 {
+		return fmt.Errorf("languages must be set before documents are added")
+	}
+	if languages.Field == "" {
+		languages.Field = defaultLanguageField
+	}
+	idx.languages = languages
+	return nil
+}
+
+// AddDocument adds a single document to the index
+func (idx *InvertedIndex) AddDocument(ctx context.Context, doc models.Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.addDocument(ctx, doc)
+}
+
+// addDocument adds a document; the caller must hold the write lock
+func (idx *InvertedIndex) addDocument(ctx context.Context, doc models.Document) error {
+	if err := idx.checkNew(ctx, []models.Document{doc}); err != nil {
+		return err
+	}
+	kept, err := idx.splitFields([]models.Document{doc})
+	if err != nil {
+		return err
+	}
+	if err := idx.store.AddDocument(ctx, kept[0]); err != nil {
+		return err
+	}
+	idx.indexTerms(doc)
+	return idx.account(kept[0])
+}
+
+// AddDocuments adds multiple documents to the index. They are analyzed in parallel under the read lock,
+// so searches go on meanwhile, then their terms are merged into the postings under the write lock.
+func (idx *InvertedIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// The languages and their analyzers are set before documents are added, so the terms stay valid
+	idx.mu.RLock()
+	deltas := idx.analyzeBatch(docs)
+	idx.mu.RUnlock()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.checkNew(ctx, docs); err != nil {
+		return err
+	}
+	kept, err := idx.splitFields(docs)
+	if err != nil {
+		return err
+	}
+	if err := idx.store.AddDocuments(ctx, kept); err != nil {
+		return err
+	}
+	idx.mergeDeltas(deltas)
+	for _, doc := range kept {
+		if err := idx.account(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitFields returns the documents with the fields kept in memory, writing the others to the field
+// store; the caller must hold the write lock
+func (idx *InvertedIndex) splitFields(docs []models.Document) ([]models.Document, error) {
+	// Under a memory budget, documents re-added replace the text evicted from their previous version
+	if !idx.fields.unstored() && !idx.budget.enabled() {
+		return docs, nil
+	}
+	kept := make([]models.Document, len(docs))
+	ids := make([]string, len(docs))
+	rests := make([]*models.Document, len(docs))
+	for i, doc := range docs {
+		kept[i], rests[i] = idx.fields.split(doc)
+		ids[i] = doc.ID
+	}
+	if idx.stored != nil {
+		if err := idx.stored.put(ids, rests); err != nil {
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// loadFields adds the fields kept in the field store (if any) to search results
+func loadFields(stored *fieldStore, docs []models.Document) ([]models.Document, error) {
+	if stored == nil || len(docs) == 0 {
+		return docs, nil
+	}
+	if err := stored.load(docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Search answers free-text queries from the postings (all terms must match, and no -term term) and
+// dimension queries by scanning. The free text before the conditions of a query is answered from the
+// postings, among the documents matching the conditions.
+func (idx *InvertedIndex) Search(ctx context.Context, query string) ([]models.Document, error) {
+	return idx.prepare(query).search(ctx)
+}
+
+// searchTerms answers the free text of a query from the postings, with its terms as analyzed for each
+// language (terms missing from the map are analyzed), keeping the documents matching filter (nil: all)
+func (idx *InvertedIndex) searchTerms(ctx context.Context, query string, text textQuery, terms map[string][]string, filter *Query) ([]models.Document, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// The query is analyzed as the documents of each language are, and matches those
+	scores := make(map[string]float64)
+	for _, language := range idx.searchLanguages(languageFrom(ctx)) {
+		analyzer := idx.analyzerOf(language)
+		languageTerms, ok := terms[language]
+		if !ok {
+			languageTerms = text.terms(analyzer)
+		}
+		matched, err := idx.scoreTerms(ctx, languageTerms, language)
+		if err != nil {
+			return nil, err
+		}
+		idx.exclude(matched, text.excluded, analyzer)
+		for id, score := range matched {
+			scores[id] = score
+		}
+	}
+
+	idx.store.mu.RLock()
+	if filter != nil {
+		// Executions may run concurrently: each evaluates a copy with the current collation. Conditions
+		// more selective than the text are answered from the stored documents' structures.
+		conditions := *filter
+		conditions.CaseSensitive = idx.store.caseSensitive
+		steps := idx.store.planConditions(&conditions, min(len(scores)-1, idx.store.indexedLimit()))
+		candidates, indexed := idx.store.values.candidates(steps)
+		for id := range scores {
+			if ordinal, ok := idx.store.values.ordinals[id]; indexed && (!ok || !candidates.has(ordinal)) {
+				delete(scores, id)
+				continue
+			}
+			if matches, err := matchesSteps(idx.store.documents[id], steps, conditions.CaseSensitive); err != nil || !matches {
+				delete(scores, id)
+			}
+		}
+	}
+	ids := make([]string, 0, len(scores))
+	now := time.Now()
+	for id := range scores {
+		if idx.ranking.custom() {
+			scores[id] = idx.ranking.score(scores[id], idx.store.documents[id], now)
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	visibility := idx.store.acl.visibility(ctx)
+	results := make([]models.Document, 0, len(ids))
+	for _, id := range ids {
+		if visibility.allows(id) {
+			results = append(results, idx.store.documents[id])
+		}
+	}
+	idx.store.mu.RUnlock()
+
+	log.Info().Msgf("Inverted search for '%s' returned %d results", query, len(results))
+	return loadFields(idx.stored, results)
+}
+
+// scoreTerms scores the documents of a language ("": of no configured language) that contain every term
+// by TF-IDF; the caller must hold the read lock
+func (idx *InvertedIndex) scoreTerms(ctx context.Context, terms []string, language string) (map[string]float64, error) {
+	total := float64(len(idx.docTerms))
+	scores := make(map[string]float64)
+	for i, term := range terms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			return nil, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		idf := math.Log(1 + total/float64(len(postings)))
+		next := make(map[string]float64, len(postings))
+		for id, tf := range postings {
+			if _, ok := scores[id]; i > 0 && !ok || idx.docLanguages[id] != language {
+				continue
+			}
+			next[id] = scores[id] + float64(tf)*idf
+		}
+		scores = next
+	}
+	return scores, nil
+}
+
+// searchLanguages returns the languages a query with a language hint searches: the configured language
+// of the hint (or "", the documents of no configured language), or every language without a hint
+func (idx *InvertedIndex) searchLanguages(hint string) []string {
+	if hint != "" {
+		return []string{idx.languages.resolve(hint)}
+	}
+	return idx.languages.languages()
+}
+
+// analyzerOf returns the analyzer of the documents of a language ("": the analyzer of the index)
+func (idx *InvertedIndex) analyzerOf(language string) *Analyzer {
+	if analyzer, ok := idx.languages.Analyzers[language]; ok {
+		return analyzer
+	}
+	return idx.analyzer
+}
+
+// exclude drops the documents holding an excluded term from scores. Terms the analyzer splits (e.g.
+// "-mysql-server") exclude the documents holding all their parts.
+func (idx *InvertedIndex) exclude(scores map[string]float64, excluded []string, analyzer *Analyzer) {
+	for _, term := range excluded {
+		parts := analyzer.Analyze(term)
+		if len(parts) == 0 {
+			continue
+		}
+		for id := range scores {
+			holdsAll := true
+			for _, part := range parts {
+				if _, ok := idx.postings[part][id]; !ok {
+					holdsAll = false
+					break
+				}
+			}
+			if holdsAll {
+				delete(scores, id)
+			}
+		}
+	}
+}
+
+// queryTerms returns the terms of a query as analyzed for every language, each once
+func (idx *InvertedIndex) queryTerms(text textQuery) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, language := range idx.languages.languages() {
+		for _, term := range text.terms(idx.analyzerOf(language)) {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// TermStats returns the statistics Search ranks query with, and the term frequencies and boosts of the
+// documents ids. Dimension queries are not ranked, so they have no terms, except for their free text.
+func (idx *InvertedIndex) TermStats(query string, ids []string) (TermStats, error) {
+	if parsedQuery, err := ParseQuery(query); err == nil && len(parsedQuery.Conditions) > 0 {
+		if parsedQuery.Text == "" {
+			return TermStats{}, nil
+		}
+		query = parsedQuery.Text
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	stats := TermStats{
+		Terms:     idx.queryTerms(parseTextQuery(query)),
+		Documents: len(idx.docTerms),
+		DocFreq:   make(map[string]int),
+		TermFreq:  make(map[string]map[string]int, len(ids)),
+	}
+	for _, term := range stats.Terms {
+		stats.DocFreq[term] = len(idx.postings[term])
+	}
+	for _, id := range ids {
+		freqs := make(map[string]int, len(stats.Terms))
+		for _, term := range stats.Terms {
+			if tf, ok := idx.postings[term][id]; ok {
+				freqs[term] = tf
+			}
+		}
+		stats.TermFreq[id] = freqs
+	}
+	if idx.ranking.custom() && len(stats.Terms) > 0 {
+		stats.TextWeight = idx.ranking.TextWeight
+		if idx.ranking.Script != nil {
+			stats.Script = idx.ranking.Script.String()
+		}
+		stats.Boosts = make(map[string]float64, len(ids))
+		now := time.Now()
+		idx.store.mu.RLock()
+		for _, id := range ids {
+			stats.Boosts[id] = idx.ranking.boost(idx.store.documents[id], now)
+		}
+		idx.store.mu.RUnlock()
+	}
+	return stats, nil
+}
+
+// DeleteDocument removes a document from the index
+func (idx *InvertedIndex) DeleteDocument(ctx context.Context, id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.store.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+	idx.unindexTerms(id)
+	idx.unaccount(id)
+	if idx.stored != nil {
+		return idx.stored.delete([]string{id})
+	}
+	return nil
+}
+
+// DeleteDocuments removes multiple documents from the index, failing before removing any if one is
+// not indexed
+func (idx *InvertedIndex) DeleteDocuments(ctx context.Context, ids []string) error {
+	if err := idx.checkExisting(ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := idx.DeleteDocument(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateDocument replaces an existing document and re-indexes its terms
+func (idx *InvertedIndex) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	kept, rest := idx.fields.split(doc)
+	if err := idx.store.UpdateDocument(ctx, id, kept); err != nil {
+		return err
+	}
+	if idx.stored != nil {
+		if err := idx.stored.put([]string{id}, []*models.Document{rest}); err != nil {
+			return err
+		}
+	}
+	idx.unindexTerms(id)
+	idx.indexTerms(doc)
+	return idx.account(kept)
+}
+
+// UpdateDocuments updates multiple documents in the index, failing before updating any if one is not
+// indexed
+func (idx *InvertedIndex) UpdateDocuments(ctx context.Context, docs []models.Document) error {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	if err := idx.checkExisting(ids); err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := idx.UpdateDocument(ctx, doc.ID, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close performs cleanup operations, closing the field store if any
+func (idx *InvertedIndex) Close() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.stored != nil {
+		if err := idx.stored.close(); err != nil {
+			return err
+		}
+		idx.stored = nil
+	}
+	return idx.store.Close()
+}
+
+// Flush writes the index to disk (no-op for the in-memory inverted index)
+func (idx *InvertedIndex) Flush() error {
+	return idx.store.Flush()
+}
+
+// Count returns the number of documents in the index
+func (idx *InvertedIndex) Count() (int, error) {
+	return idx.store.Count()
+}
+
+// Size returns the approximate size of the stored documents and postings in bytes
+func (idx *InvertedIndex) Size() (int, error) {
+	size, err := idx.store.Size()
+	if err != nil {
+		return 0, err
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	for term, postings := range idx.postings {
+		size += len(term)
+		for id := range postings {
+			size += len(id) + 8 // document ID plus term frequency
+		}
+	}
+	return size, nil
+}
+
+// indexTerms adds a document's terms to the postings; the caller must hold the write lock.
+// Re-adding an existing document replaces its previous terms.
+func (idx *InvertedIndex) indexTerms(doc models.Document) {
+	idx.unindexTerms(doc.ID)
+
+	language, frequencies := idx.analyze(doc)
+	if language != "" {
+		idx.docLanguages[doc.ID] = language
+	}
+
+	terms := make([]string, 0, len(frequencies))
+	for term, tf := range frequencies {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			idx.postings[term] = postings
+		}
+		postings[doc.ID] = tf
+		terms = append(terms, term)
+	}
+	idx.docTerms[doc.ID] = terms
+}
+
+// unindexTerms removes a document's terms from the postings; the caller must hold the write lock
+func (idx *InvertedIndex) unindexTerms(id string) {
+	for _, term := range idx.docTerms[id] {
+		postings := idx.postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docTerms, id)
+	delete(idx.docLanguages, id)
+}
+
+// searchable returns the searchable text of a document: its text, source and metadata values, except
+// those of the fields that are not indexed
+func (m FieldMapping) searchable(doc models.Document) []string {
+	fields := make([]string, 0, 2+len(doc.Meta))
+	if m.indexed("text") {
+		fields = append(fields, doc.Text)
+	}
+	if m.indexed("source") {
+		fields = append(fields, doc.Source)
+	}
+	for key, value := range doc.Meta {
+		if m.indexed(key) {
+			fields = append(fields, value)
+		}
+	}
+	return fields
+}
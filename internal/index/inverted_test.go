@@ -0,0 +1,199 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	standard, _ := NewAnalyzer("standard")
+	assert.Equal(t, []string{"hello", "world", "42"}, standard.Analyze("Hello, World! 42"))
+
+	english, _ := NewAnalyzer("english")
+	assert.Equal(t, []string{"quick", "fox"}, english.Analyze("The quick fox"))
+
+	keyword, _ := NewAnalyzer("keyword")
+	assert.Equal(t, []string{"Hello World"}, keyword.Analyze(" Hello World "))
+
+	_, err := NewAnalyzer("klingon")
+	assert.Error(t, err)
+}
+
+func TestAnalyzer_StopwordsAndProtectedTerms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stopwords.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# project noise\nlorem ipsum\n\nDolor\n"), 0o644))
+	analyzer, err := NewAnalyzerFromConfig(map[string]interface{}{
+		"stopwords":        []interface{}{"_english_", "via"},
+		"stopword_files":   []interface{}{path},
+		"protected_terms":  []interface{}{"C", "it", "golang"},
+		"min_token_length": 3,
+	})
+	assert.NoError(t, err)
+	// "it" is an English stopword and "c" too short, but both are protected
+	assert.Equal(t, []string{"c", "it", "golang"}, analyzer.Analyze("The C of IT via Lorem dolor Golang"))
+
+	ngrams, err := NewAnalyzerFromConfig(map[string]interface{}{"ngram": 3, "protected_terms": []interface{}{"golang"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rus", "ust", "golang"}, ngrams.Analyze("rust golang"))
+
+	_, err = NewAnalyzerFromConfig(map[string]interface{}{"stopword_files": []interface{}{filepath.Join(t.TempDir(), "missing.txt")}})
+	assert.Error(t, err)
+}
+
+func TestAnalyzer_Folding(t *testing.T) {
+	analyzer := func(cfg map[string]interface{}) *Analyzer {
+		a, err := NewAnalyzerFromConfig(cfg)
+		assert.NoError(t, err)
+		return a
+	}
+	decomposed := "Cafe\u0301 Crème"
+
+	// Marks belong to the letter before them rather than splitting words
+	assert.Equal(t, []string{"cafe\u0301", "crème"}, analyzer(nil).Analyze(decomposed))
+	assert.Equal(t, []string{"café", "crème"}, analyzer(map[string]interface{}{"normalization": "NFC"}).Analyze(decomposed))
+	assert.Equal(t, []string{"cafe", "creme"}, analyzer(map[string]interface{}{"fold_accents": true}).Analyze(decomposed))
+	assert.Equal(t, []string{"ﬁ"}, analyzer(nil).Analyze("ﬁ"))
+	assert.Equal(t, []string{"fi"}, analyzer(map[string]interface{}{"normalization": "nfkc"}).Analyze("ﬁ"))
+
+	assert.Equal(t, []string{"straße", "σοφος"}, analyzer(nil).Analyze("Straße σοφος"))
+	assert.Equal(t, []string{"strasse", "σοφοσ"}, analyzer(map[string]interface{}{"case_fold": true}).Analyze("Straße σοφος"))
+
+	// Stopwords are folded like terms
+	english := analyzer(map[string]interface{}{"analyzer": "english", "fold_accents": true, "stopwords": []interface{}{"Déjà"}})
+	assert.Equal(t, []string{"vu"}, english.Analyze("deja vu"))
+
+	_, err := NewAnalyzerFromConfig(map[string]interface{}{"normalization": "nfx"})
+	assert.Error(t, err)
+}
+
+func TestAnalyzer_Bigrams(t *testing.T) {
+	cjk, err := NewAnalyzerFromConfig(map[string]interface{}{"cjk_bigrams": true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"東京", "京都", "都に", "に住", "住む"}, cjk.Analyze("東京都に住む"))
+	assert.Equal(t, []string{"iphone", "東京", "猫"}, cjk.Analyze("iPhone東京 猫"))
+	assert.Equal(t, []string{"한국", "국어", "search"}, cjk.Analyze("한국어 search"))
+
+	ngram, err := NewAnalyzerFromConfig(map[string]interface{}{"ngram": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sea", "ear", "arc", "rch", "go"}, ngram.Analyze("Search go"))
+
+	_, err = NewAnalyzerFromConfig(map[string]interface{}{"ngram": -1})
+	assert.Error(t, err)
+}
+
+func TestInvertedIndex_SearchCJK(t *testing.T) {
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{"cjk_bigrams": true})
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("1", "東京都に住む", "a.txt", nil, nil),
+		makeTestDoc("2", "京都の寺", "b.txt", nil, nil),
+	}))
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"京都", 2},
+		{"東京", 1},
+		{"東京都", 1},
+		{"大阪", 0},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(context.Background(), tt.query)
+		assert.NoError(t, err, tt.query)
+		assert.Len(t, results, tt.want, tt.query)
+	}
+}
+
+func TestInvertedIndex_SearchFoldsAccents(t *testing.T) {
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{"fold_accents": true})
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "Un café crème", "a.txt", nil, nil)))
+	for _, query := range []string{"cafe", "CAFÉ", "creme"} {
+		results, err := idx.Search(context.Background(), query)
+		assert.NoError(t, err, query)
+		assert.Len(t, results, 1, query)
+	}
+}
+
+func TestInvertedIndex_SearchRanksByTermFrequency(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("1", "go is fun", "a.txt", nil, nil),
+		makeTestDoc("2", "go go go, search in go", "b.txt", nil, nil),
+		makeTestDoc("3", "rust is fun", "c.txt", nil, nil),
+	}))
+
+	results, err := idx.Search(context.Background(), "go")
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].ID)
+
+	results, err = idx.Search(context.Background(), "is fun")
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = idx.Search(context.Background(), "go rust")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestInvertedIndex_TermStats(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("1", "go is fun", "a.txt", nil, nil),
+		makeTestDoc("2", "go go go, search in go", "b.txt", nil, nil),
+		makeTestDoc("3", "rust is fun", "c.txt", nil, nil),
+	}))
+
+	stats, err := idx.TermStats("go fun", []string{"1", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "fun"}, stats.Terms)
+	assert.Equal(t, 3, stats.Documents)
+	assert.Equal(t, map[string]int{"go": 2, "fun": 2}, stats.DocFreq)
+	assert.Equal(t, map[string]int{"go": 1, "fun": 1}, stats.TermFreq["1"])
+	assert.Equal(t, map[string]int{"go": 4}, stats.TermFreq["2"])
+
+	stats, err = idx.TermStats("fileExtension=go", []string{"1"})
+	assert.NoError(t, err)
+	assert.Empty(t, stats.Terms)
+}
+
+func TestInvertedIndex_SearchCancelled(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "go is fun", "a.txt", nil, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := idx.Search(ctx, "go")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestInvertedIndex_UpdateAndDeleteMaintainPostings(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "alpha", "a.txt", nil, nil)))
+
+	assert.NoError(t, idx.UpdateDocument(context.Background(), "1", makeTestDoc("1", "beta", "a.txt", nil, nil)))
+	results, _ := idx.Search(context.Background(), "alpha")
+	assert.Empty(t, results)
+	results, _ = idx.Search(context.Background(), "beta")
+	assert.Len(t, results, 1)
+
+	assert.NoError(t, idx.DeleteDocument(context.Background(), "1"))
+	results, _ = idx.Search(context.Background(), "beta")
+	assert.Empty(t, results)
+	assert.Empty(t, idx.postings)
+}
+
+func TestInvertedIndex_DimensionQueryFallsBackToScan(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "text", "a.go", map[string]string{"fileExtension": "go"}, nil)))
+
+	results, err := idx.Search(context.Background(), "fileExtension=go")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
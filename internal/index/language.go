@@ -0,0 +1,103 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Per-document languages: an inverted index can analyze each document with the analyzer of its language,
+ * detected by a feature extractor into a metadata field. Queries are analyzed with the analyzer of each
+ * language and match the documents of that language, unless a language hint picks one.
+ **/
+
+type languageKey struct{}
+
+// WithLanguage makes free-text searches with ctx analyze the query as the documents of a language are
+// analyzed, and find only those. Without it (or with "") every language is searched.
+func WithLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// languageFrom returns the language hint set by WithLanguage, "" if none
+func languageFrom(ctx context.Context) string {
+	language, _ := ctx.Value(languageKey{}).(string)
+	return language
+}
+
+// defaultLanguageField is the metadata key the language of documents is read from unless configured
+const defaultLanguageField = "language"
+
+// LanguageAnalyzers are the analyzers of documents by language. Documents whose Field metadata names a
+// language of Analyzers are analyzed with its analyzer; others with the analyzer of the index.
+type LanguageAnalyzers struct {
+	Field     string               // Metadata key holding the language of documents, e.g. "en" or "de-CH"
+	Analyzers map[string]*Analyzer // Analyzers by lowercase language code
+}
+
+// parseLanguageAnalyzers reads the "languages" option of an index, analyzer options by language code
+// (e.g. {"en": {"analyzer": "english"}}), and the "language_field" option
+func parseLanguageAnalyzers(cfg map[string]interface{}) (LanguageAnalyzers, error) {
+	field, err := config.String(cfg, "language_field", defaultLanguageField)
+	if err != nil {
+		return LanguageAnalyzers{}, err
+	}
+	languages, err := config.Map(cfg, "languages")
+	if err != nil || languages == nil {
+		return LanguageAnalyzers{Field: field}, err
+	}
+	analyzers := make(map[string]*Analyzer, len(languages))
+	for language, value := range languages {
+		options, ok := value.(map[string]interface{})
+		if !ok {
+			return LanguageAnalyzers{}, fmt.Errorf("analyzer options of language %s must be an object, got %T", language, value)
+		}
+		analyzer, err := NewAnalyzerFromConfig(options)
+		if err != nil {
+			return LanguageAnalyzers{}, fmt.Errorf("language %s: %w", language, err)
+		}
+		analyzers[strings.ToLower(language)] = analyzer
+	}
+	return LanguageAnalyzers{Field: field, Analyzers: analyzers}, nil
+}
+
+// resolve returns the configured language of a language code: the code itself or its primary subtag
+// ("de-CH" and "de_CH" are "de"), "" if neither has an analyzer
+func (l LanguageAnalyzers) resolve(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if _, ok := l.Analyzers[code]; ok {
+		return code
+	}
+	if primary, _, found := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-"); found {
+		if _, ok := l.Analyzers[primary]; ok {
+			return primary
+		}
+	}
+	return ""
+}
+
+// of returns the configured language of a document, "" if it has none
+func (l LanguageAnalyzers) of(doc models.Document) string {
+	if len(l.Analyzers) == 0 {
+		return ""
+	}
+	return l.resolve(doc.Meta[l.Field])
+}
+
+// languages returns the configured languages, sorted, after "" (the documents of no configured language)
+func (l LanguageAnalyzers) languages() []string {
+	languages := make([]string, 0, len(l.Analyzers)+1)
+	for language := range l.Analyzers {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return append([]string{""}, languages...)
+}
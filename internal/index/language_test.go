@@ -0,0 +1,77 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestLanguageAnalyzers_Resolve(t *testing.T) {
+	languages, err := parseLanguageAnalyzers(map[string]interface{}{
+		"languages": map[string]interface{}{"EN": map[string]interface{}{"analyzer": "english"}, "de": map[string]interface{}{}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultLanguageField, languages.Field)
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"en", "en"},
+		{"en-GB", "en"},
+		{"de_CH", "de"},
+		{"fr", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, languages.resolve(tt.code), tt.code)
+	}
+	assert.Equal(t, []string{"", "de", "en"}, languages.languages())
+
+	_, err = parseLanguageAnalyzers(map[string]interface{}{"languages": map[string]interface{}{"en": "english"}})
+	assert.Error(t, err)
+}
+
+func TestInvertedIndex_SearchByLanguage(t *testing.T) {
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"language_field": "lang",
+		"languages": map[string]interface{}{
+			"fr": map[string]interface{}{"fold_accents": true},
+			"de": map[string]interface{}{},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("fr", "un café crème", "fr.txt", map[string]string{"lang": "fr-FR"}, nil),
+		makeTestDoc("de", "ein café", "de.txt", map[string]string{"lang": "de"}, nil),
+		makeTestDoc("none", "cafe", "none.txt", nil, nil),
+	}))
+	tests := []struct {
+		query    string
+		language string
+		want     []string
+	}{
+		// Only the french analyzer folds accents, of the documents and of the query
+		{"cafe", "", []string{"fr", "none"}},
+		{"café", "", []string{"fr", "de"}},
+		{"café", "de", []string{"de"}},
+		{"café", "fr-CA", []string{"fr"}},
+		{"cafe", "it", []string{"none"}},
+		{"café -crème", "", []string{"de"}},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(WithLanguage(context.Background(), tt.language), tt.query)
+		assert.NoError(t, err, tt.query)
+		var ids []string
+		for _, doc := range results {
+			ids = append(ids, doc.ID)
+		}
+		assert.ElementsMatch(t, tt.want, ids, tt.query+" "+tt.language)
+	}
+
+	stats, err := idx.(*InvertedIndex).TermStats("café", nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"café", "cafe"}, stats.Terms)
+}
@@ -0,0 +1,144 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Listing: ListDocuments pages through every document of an index in ID order, for admin tools that
+ * audit an index or reconcile it with its database. Pages are read with a cursor (After, the ID of the
+ * last document of the previous page), so documents added or deleted meanwhile do not shift later pages.
+ **/
+
+// ErrInvalidListOptions is returned (wrapped) by ListDocuments for a negative offset or limit or an
+// invalid where clause
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// ListOptions selects a page of the documents of an index, in ID order
+type ListOptions struct {
+	After  string // Lists the documents with IDs after this one ("": from the first)
+	Offset int    // Documents to skip after After
+	Limit  int    // Most documents to list (0: all)
+	Where  string // Conditions the documents must match, e.g. "fileExtension=go" ("": all)
+}
+
+// DocumentPage is a page of the documents of an index
+type DocumentPage struct {
+	Documents []models.Document
+	Total     int    // Documents matching the options, before Offset and Limit
+	Next      string // After of the next page ("": this is the last page)
+}
+
+// listFilter parses the where clause of opts, which must hold conditions, into a filter (nil: all)
+func listFilter(opts ListOptions, caseSensitive bool) (func(models.Document) bool, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("%w: offset and limit must not be negative, got %d and %d", ErrInvalidListOptions, opts.Offset, opts.Limit)
+	}
+	if opts.Where == "" {
+		return nil, nil
+	}
+	query, err := ParseQuery(opts.Where)
+	if err != nil {
+		return nil, fmt.Errorf("%w: where %q: %s", ErrInvalidListOptions, opts.Where, err)
+	}
+	if len(query.Conditions) == 0 {
+		return nil, fmt.Errorf("%w: where %q holds no conditions", ErrInvalidListOptions, opts.Where)
+	}
+	query.CaseSensitive = caseSensitive
+	return func(doc models.Document) bool {
+		matches, err := query.Evaluate(doc)
+		return err == nil && matches
+	}, nil
+}
+
+// pageIDs sorts the IDs matching the options and returns those of the page, the total and the cursor
+// of the next page
+func pageIDs(ids []string, opts ListOptions) ([]string, int, string) {
+	sort.Strings(ids)
+	total := len(ids)
+	ids = ids[min(opts.Offset, len(ids)):]
+	if opts.Limit == 0 || opts.Limit >= len(ids) {
+		return ids, total, ""
+	}
+	ids = ids[:opts.Limit]
+	return ids, total, ids[len(ids)-1]
+}
+
+// ListDocuments returns a page of the documents visible to the principals of ctx, in ID order
+func (idx *SimpleIndex) ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return DocumentPage{}, err
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	filter, err := listFilter(opts, idx.caseSensitive)
+	if err != nil {
+		return DocumentPage{}, err
+	}
+	principals, restricted := principalsFrom(ctx)
+	ids := make([]string, 0, len(idx.documents))
+	for id, doc := range idx.documents {
+		if opts.After != "" && id <= opts.After || restricted && !doc.VisibleTo(principals) || filter != nil && !filter(doc) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	ids, total, next := pageIDs(ids, opts)
+	docs := make([]models.Document, len(ids))
+	for i, id := range ids {
+		docs[i] = idx.documents[id]
+	}
+	return DocumentPage{Documents: docs, Total: total, Next: next}, nil
+}
+
+// ListDocuments returns a page of the documents held in memory
+func (p *PersistedSimpleIndex) ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error) {
+	return p.index.ListDocuments(ctx, opts)
+}
+
+// ListDocuments returns a page of the documents, with the fields kept in the field store; like boolean
+// queries, the where clause only sees stored fields
+func (idx *InvertedIndex) ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error) {
+	page, err := idx.store.ListDocuments(ctx, opts)
+	if err != nil {
+		return DocumentPage{}, err
+	}
+	idx.mu.RLock()
+	stored := idx.stored
+	idx.mu.RUnlock()
+	page.Documents, err = loadFields(stored, page.Documents)
+	return page, err
+}
+
+// ListDocuments returns a page of the documents
+func (idx *VectorIndex) ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error) {
+	return idx.store.ListDocuments(ctx, opts)
+}
+
+// ListDocuments returns a page of the documents of the remote index
+func (idx *RemoteIndex) ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error) {
+	remote := idx.endpoint()
+	params := url.Values{}
+	params.Set("after", opts.After)
+	params.Set("offset", strconv.Itoa(opts.Offset))
+	params.Set("limit", strconv.Itoa(opts.Limit))
+	params.Set("where", opts.Where)
+	var response struct {
+		Documents []models.Document `json:"documents"`
+		Total     int               `json:"total"`
+		Next      string            `json:"next"`
+	}
+	path := "/indexes/" + url.PathEscape(remote.index) + "/documents?" + params.Encode()
+	if err := remote.call(ctx, http.MethodGet, path, nil, "", &response); err != nil {
+		return DocumentPage{}, err
+	}
+	return DocumentPage{Documents: response.Documents, Total: response.Total, Next: response.Next}, nil
+}
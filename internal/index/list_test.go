@@ -0,0 +1,76 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestIndexes_ListDocuments(t *testing.T) {
+	persisted, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer persisted.Close()
+	vector, err := NewVectorIndex("cosine", 2, 1)
+	assert.NoError(t, err)
+	indexes := map[string]Index{
+		"simple":    NewSimpleIndex(),
+		"persisted": persisted,
+		"inverted":  NewInvertedIndex(nil),
+		"vector":    vector,
+	}
+	ctx := context.Background()
+	for name, idx := range indexes {
+		docs := make([]models.Document, 25)
+		for i := range docs {
+			ext := "go"
+			if i%5 == 0 {
+				ext = "md"
+			}
+			docs[i] = models.Document{ID: fmt.Sprintf("doc-%02d", i), Text: "text", Vector: []float64{float64(i)}, Meta: map[string]string{"fileExtension": ext}}
+		}
+		docs[3].AllowedPrincipals = []string{"alice"}
+		assert.NoError(t, idx.AddDocuments(ctx, docs), name)
+
+		// Pages follow each other by their cursor
+		var ids []string
+		opts := ListOptions{Limit: 10}
+		for {
+			page, err := idx.ListDocuments(ctx, opts)
+			assert.NoError(t, err, name)
+			assert.Equal(t, 25-len(ids), page.Total, name)
+			for _, doc := range page.Documents {
+				ids = append(ids, doc.ID)
+			}
+			if page.Next == "" {
+				break
+			}
+			opts.After = page.Next
+		}
+		assert.Len(t, ids, 25, name)
+		assert.Equal(t, "doc-00", ids[0], name)
+		assert.Equal(t, "doc-24", ids[24], name)
+
+		page, err := idx.ListDocuments(ctx, ListOptions{Offset: 1, Limit: 2, Where: "fileExtension=MD"})
+		assert.NoError(t, err, name)
+		assert.Equal(t, 5, page.Total, name)
+		if assert.Len(t, page.Documents, 2, name) {
+			assert.Equal(t, "doc-05", page.Documents[0].ID, name)
+		}
+		assert.Equal(t, "doc-10", page.Next, name)
+
+		page, err = idx.ListDocuments(WithPrincipals(ctx, []string{"bob"}), ListOptions{})
+		assert.NoError(t, err, name)
+		assert.Equal(t, 24, page.Total, name)
+		assert.Empty(t, page.Next, name)
+
+		_, err = idx.ListDocuments(ctx, ListOptions{Where: "just text"})
+		assert.ErrorIs(t, err, ErrInvalidListOptions, name)
+		_, err = idx.ListDocuments(ctx, ListOptions{Limit: -1})
+		assert.ErrorIs(t, err, ErrInvalidListOptions, name)
+	}
+}
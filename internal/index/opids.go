@@ -0,0 +1,155 @@
+package index
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Operation IDs: every write queued for the database of a persisted index gets the next of a sequence of
+ * IDs, and the ID of the last write applied is committed with it. The worker skips writes whose ID is not
+ * past it, and Replay applies a write of a log (e.g. a write-ahead log or a replica's stream) only if its
+ * ID is past every write queued, so replaying a log again or after a crash changes nothing.
+ **/
+
+// Types of the operations Replay applies
+const (
+	OpAddDocuments    = "add_documents"    // Adds or replaces Operation.Documents
+	OpDeleteDocuments = "delete_documents" // Deletes the documents of Operation.IDs that are indexed
+)
+
+var (
+	metaBucket     = []byte("meta")
+	lastAppliedKey = []byte("last_applied_op")
+)
+
+// Operation is a write to a persisted index, with its position among the index's writes
+type Operation struct {
+	ID        uint64 // From 1, increasing with each write
+	Type      string // OpAddDocuments or OpDeleteDocuments
+	Documents []models.Document
+	IDs       []string
+}
+
+// LastAppliedOp returns the ID of the last write committed to the database (0: none)
+func (p *PersistedSimpleIndex) LastAppliedOp() uint64 {
+	return p.lastApplied.Load()
+}
+
+// Replay applies op to memory and the database and reports whether it did: writes whose ID is not past
+// every write queued so far were applied already and are skipped. It returns once op is committed, and
+// later writes get IDs past op's.
+func (p *PersistedSimpleIndex) Replay(op Operation) (bool, error) {
+	if p.readOnly {
+		return false, ErrReadOnly
+	}
+	if op.ID == 0 {
+		return false, fmt.Errorf("operation has no ID")
+	}
+	queued := dbOperation{id: op.ID, opType: op.Type}
+	switch op.Type {
+	case OpAddDocuments:
+		queued.data = op.Documents
+	case OpDeleteDocuments:
+		queued.data = op.IDs
+	default:
+		return false, fmt.Errorf("unknown operation type %q (want %q or %q)", op.Type, OpAddDocuments, OpDeleteDocuments)
+	}
+
+	// Holding the queue keeps writes from taking IDs past op's before it is queued
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	if op.ID <= p.lastQueued {
+		log.Debug().Msgf("Skipped replay of operation %d: already applied", op.ID)
+		return false, nil
+	}
+	p.mu.RLock()
+	open := p.db != nil
+	p.mu.RUnlock()
+	if open && !p.workerRunning.Load() {
+		return false, fmt.Errorf("async database worker is not running")
+	}
+
+	var err error
+	if op.Type == OpAddDocuments {
+		err = p.index.AddDocuments(context.Background(), op.Documents)
+	} else {
+		err = p.index.deletePresent(op.IDs)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to replay operation %d: %w", op.ID, err)
+	}
+	p.lastQueued = op.ID
+	if !open {
+		return true, nil
+	}
+	queued.done = make(chan error, 1)
+	p.opChan <- queued
+	if err := <-queued.done; err != nil {
+		return true, fmt.Errorf("replayed operation %d not persisted: %w", op.ID, err)
+	}
+	return true, nil
+}
+
+// deletePresent deletes the documents of ids that are indexed, ignoring the others
+func (idx *SimpleIndex) deletePresent(ids []string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	for _, id := range ids {
+		if _, exists := idx.documents[id]; !exists {
+			continue
+		}
+		if err := idx.deleteDocument(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applied wraps the transaction of the write with ID id so it also records id as the last applied (0:
+// a write without an ID, e.g. one queued before IDs were assigned)
+func applied(id uint64, write func(tx *bbolt.Tx) error) func(tx *bbolt.Tx) error {
+	return func(tx *bbolt.Tx) error {
+		if err := write(tx); err != nil || id == 0 {
+			return err
+		}
+		bucket, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, id)
+		return bucket.Put(lastAppliedKey, value)
+	}
+}
+
+// loadLastApplied reads the ID of the last write applied to db and continues the sequence after it
+func (p *PersistedSimpleIndex) loadLastApplied(db *bbolt.DB) error {
+	var id uint64
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return nil
+		}
+		if value := bucket.Get(lastAppliedKey); len(value) == 8 {
+			id = binary.BigEndian.Uint64(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read last applied operation: %w", err)
+	}
+	p.lastApplied.Store(id)
+	p.queueMu.Lock()
+	p.lastQueued = id
+	p.queueMu.Unlock()
+	return nil
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestPersistedSimpleIndex_OperationIDs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	persisted := WithDurability(context.Background(), DurabilityPersisted)
+
+	assert.NoError(t, idx.AddDocument(persisted, models.Document{ID: "1"}))
+	assert.Equal(t, uint64(1), idx.LastAppliedOp())
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{{ID: "2"}, {ID: "3"}}))
+	assert.NoError(t, idx.DeleteDocument(persisted, "3"))
+	assert.Equal(t, uint64(3), idx.LastAppliedOp())
+
+	// Writes with the ID of one applied already are skipped
+	done := make(chan error, 1)
+	idx.opChan <- dbOperation{id: 2, opType: "add_document", data: models.Document{ID: "stale"}, done: done}
+	assert.NoError(t, <-done)
+	_, found := storedText(t, idx, "stale")
+	assert.False(t, found)
+	assert.NoError(t, idx.Close())
+
+	// The sequence goes on after a restart
+	idx, err = NewPersistedSimpleIndexWithDatabaseAndLoad(dbPath)
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.Equal(t, uint64(3), idx.LastAppliedOp())
+	assert.NoError(t, idx.AddDocument(persisted, models.Document{ID: "4"}))
+	assert.Equal(t, uint64(4), idx.LastAppliedOp())
+}
+
+func TestPersistedSimpleIndex_Replay(t *testing.T) {
+	idx, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.NoError(t, idx.AddDocument(WithDurability(context.Background(), DurabilityPersisted), models.Document{ID: "1"}))
+
+	ops := []Operation{
+		{ID: 1, Type: OpAddDocuments, Documents: []models.Document{{ID: "1", Text: "replayed"}}},
+		{ID: 5, Type: OpAddDocuments, Documents: []models.Document{{ID: "5", Text: "five"}, {ID: "6", Text: "six"}}},
+		{ID: 7, Type: OpDeleteDocuments, IDs: []string{"6", "missing"}},
+	}
+	// Replaying the log twice applies each write once
+	for round, expected := range [][]bool{{false, true, true}, {false, false, false}} {
+		for i, op := range ops {
+			replayed, err := idx.Replay(op)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[i], replayed, "round %d, operation %d", round, op.ID)
+		}
+	}
+	assert.Equal(t, uint64(7), idx.LastAppliedOp())
+	count, err := idx.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	text, found := storedText(t, idx, "5")
+	assert.True(t, found)
+	assert.Equal(t, "five", text)
+	_, found = storedText(t, idx, "6")
+	assert.False(t, found)
+	text, _ = storedText(t, idx, "1")
+	assert.Empty(t, text)
+
+	// Later writes get IDs past the replayed ones
+	assert.NoError(t, idx.AddDocument(WithDurability(context.Background(), DurabilityPersisted), models.Document{ID: "8"}))
+	assert.Equal(t, uint64(8), idx.LastAppliedOp())
+
+	_, err = idx.Replay(Operation{Type: OpAddDocuments})
+	assert.Error(t, err)
+	_, err = idx.Replay(Operation{ID: 9, Type: "update"})
+	assert.Error(t, err)
+}
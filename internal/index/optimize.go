@@ -0,0 +1,113 @@
+package index
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Optimizing: Go maps keep the buckets of their removed entries and slices their spare capacity, so after
+ * updates and deletes an index holds more memory than its documents need. Optimize copies the structures
+ * into ones sized for what they hold, and the persisted index compacts its database.
+ **/
+
+// Optimize copies the documents into a map sized for them and rebuilds the structures derived from them,
+// so the ordinals of the bitmaps are dense again
+func (idx *SimpleIndex) Optimize() error {
+	idx.mu.Lock()
+	documents := make(map[string]models.Document, len(idx.documents))
+	for id, doc := range idx.documents {
+		documents[id] = doc
+	}
+	idx.documents = documents
+	idx.mu.Unlock()
+	return idx.WarmUp(context.Background())
+}
+
+// Optimize optimizes the documents held in memory, then compacts the database into a fresh file
+func (p *PersistedSimpleIndex) Optimize() error {
+	if err := p.index.Optimize(); err != nil {
+		return err
+	}
+	if p.readOnly || !p.workerRunning.Load() {
+		return nil
+	}
+	return p.Compact()
+}
+
+// Optimize optimizes the stored documents, copies the postings and the terms and languages of the
+// documents into structures sized for them, sorting each document's terms, and recomputes the memory
+// the documents hold against the budget
+func (idx *InvertedIndex) Optimize() error {
+	if err := idx.store.Optimize(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	postings := make(map[string]map[string]int, len(idx.postings))
+	for term, docs := range idx.postings {
+		copied := make(map[string]int, len(docs))
+		for id, tf := range docs {
+			copied[id] = tf
+		}
+		postings[term] = copied
+	}
+	docTerms := make(map[string][]string, len(idx.docTerms))
+	for id, terms := range idx.docTerms {
+		docTerms[id] = sortedUnique(terms)
+	}
+	docLanguages := make(map[string]string, len(idx.docLanguages))
+	for id, language := range idx.docLanguages {
+		docLanguages[id] = language
+	}
+	idx.postings, idx.docTerms, idx.docLanguages = postings, docTerms, docLanguages
+	idx.rebudget()
+	return nil
+}
+
+// sortedUnique returns a sorted copy of terms without repeats, with no spare capacity
+func sortedUnique(terms []string) []string {
+	sorted := append([]string(nil), terms...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, term := range sorted {
+		if i == 0 || term != sorted[i-1] {
+			unique = append(unique, term)
+		}
+	}
+	return append(make([]string, 0, len(unique)), unique...)
+}
+
+// rebudget recomputes the bytes the documents hold from the stored documents and drops the removed and
+// repeated documents from those whose text may be evicted; the caller must hold the write lock
+func (idx *InvertedIndex) rebudget() {
+	b := &idx.budget
+	if !b.enabled() {
+		return
+	}
+	idx.store.mu.RLock()
+	defer idx.store.mu.RUnlock()
+	b.used = 0
+	b.docBytes = make(map[string]int, len(idx.store.documents))
+	for id, doc := range idx.store.documents {
+		bytes := documentBytes(doc) + len(idx.docTerms[id])*(len(id)+8)
+		b.docBytes[id] = bytes
+		b.used += bytes
+	}
+	seen := make(map[string]bool, len(b.resident))
+	resident := make([]string, 0, len(b.resident))
+	for _, id := range b.resident {
+		if doc, ok := idx.store.documents[id]; ok && doc.Text != "" && !seen[id] {
+			seen[id] = true
+			resident = append(resident, id)
+		}
+	}
+	b.resident = resident
+}
+
+// Optimize optimizes the stored documents; the brute-force vector index has no other structure
+func (idx *VectorIndex) Optimize() error {
+	return idx.store.Optimize()
+}
@@ -0,0 +1,102 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestSimpleIndex_Optimize(t *testing.T) {
+	idx := NewSimpleIndex()
+	docs := plannerDocs()
+	docs[1].AllowedPrincipals = []string{"alice"}
+	assert.NoError(t, idx.AddDocuments(context.Background(), docs))
+	for i := 0; i < 100; i += 2 {
+		assert.NoError(t, idx.DeleteDocument(context.Background(), fmt.Sprint(i)))
+	}
+	assert.NotEmpty(t, idx.values.free)
+
+	assert.NoError(t, idx.Optimize())
+	assert.Empty(t, idx.values.free, "ordinals are dense again")
+	assert.Len(t, idx.values.ids, 50)
+	results, err := idx.Search(context.Background(), "size<10")
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	results, err = idx.Search(WithPrincipals(context.Background(), []string{"alice"}), "report 1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+}
+
+func TestInvertedIndex_Optimize(t *testing.T) {
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.SetMemoryBudget(1<<20, filepath.Join(t.TempDir(), "fields.db")))
+	assert.NoError(t, idx.AddDocuments(context.Background(), plannerDocs()))
+	for i := 0; i < 100; i++ {
+		// Updates queue documents to evict again
+		assert.NoError(t, idx.UpdateDocument(context.Background(), "7", makeTestDoc("7", fmt.Sprintf("zeta alpha %d", i), "/docs/7.txt", nil, nil)))
+	}
+	assert.NoError(t, idx.DeleteDocument(context.Background(), "8"))
+	used := idx.budget.used
+
+	assert.NoError(t, idx.Optimize())
+	assert.Equal(t, used, idx.budget.used)
+	assert.Len(t, idx.budget.resident, 99)
+	assert.Equal(t, []string{"7", "99", "alpha", "docs", "txt", "zeta"}, idx.docTerms["7"])
+	for _, terms := range idx.docTerms {
+		assert.True(t, sort.StringsAreSorted(terms))
+	}
+	results, err := idx.Search(context.Background(), "alpha")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	results, err = idx.Search(context.Background(), "report")
+	assert.NoError(t, err)
+	assert.Len(t, results, 98)
+}
+
+func TestPersistedSimpleIndex_OptimizeCompactsDatabase(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	docs := make([]models.Document, 2000)
+	ids := make([]string, 0, len(docs))
+	for i := range docs {
+		docs[i] = models.Document{ID: fmt.Sprint(i), Text: strings.Repeat("padding ", 100)}
+		if i >= 10 {
+			ids = append(ids, docs[i].ID)
+		}
+	}
+	assert.NoError(t, idx.AddDocuments(ctx, docs))
+	assert.NoError(t, idx.DeleteDocuments(ctx, ids))
+	assert.NoError(t, idx.Flush())
+	// Optimize compacts after the queued writes
+	assert.NoError(t, idx.Optimize())
+	before := fileSize(t, dbPath)
+	assert.NoError(t, idx.Optimize())
+	assert.Equal(t, before, fileSize(t, dbPath), "a compacted database stays as it is")
+	assert.Less(t, before, int64(200<<10))
+
+	// The database is written to after it is swapped in
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "new", Text: "after"}))
+	assert.NoError(t, idx.HealthCheck())
+	assert.NoError(t, idx.Close())
+	idx, err = NewPersistedSimpleIndexReadOnly(dbPath)
+	assert.NoError(t, err)
+	count, _ := idx.Count()
+	assert.Equal(t, 11, count)
+	assert.NoError(t, idx.Close())
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	return info.Size()
+}
@@ -1,11 +1,14 @@
 package index
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
@@ -18,8 +21,11 @@ A simple index that persists to disk. in boltDB
 
 // dbOperation represents a database operation to be performed asynchronously
 type dbOperation struct {
+	id     uint64 // Position among the index's writes (0: none, e.g. maintenance)
 	opType string
 	data   interface{}
+	done   chan error // Receives the result of a write when set (nil: fire-and-forget)
+	sync   bool       // Syncs the database to disk after the write, before done receives
 }
 
 type PersistedSimpleIndex struct {
@@ -29,6 +35,18 @@ type PersistedSimpleIndex struct {
 	done   chan struct{}
 	wg     sync.WaitGroup
 	mu     sync.RWMutex
+
+	workerRunning atomic.Bool // Set while the async database worker runs
+	readOnly      bool        // Set when the database is opened read-only: no worker, mutations rejected
+	durability    Durability  // Durability of writes without one in their context
+	noSync        bool        // Set when commits do not sync the database to disk
+	failures      writeFailures
+
+	queueMu     sync.Mutex     // Held while a write takes an ID and is queued, so IDs are queued in order
+	lastQueued  uint64         // ID of the last write queued
+	lastApplied atomic.Uint64  // ID of the last write committed to the database
+	wal         *writeAheadLog // Log of the writes queued (nil: none); guarded by queueMu
+	recovery    RecoveryReport // Recovery run when the database was opened
 }
 
 func NewPersistedSimpleIndex() *PersistedSimpleIndex {
@@ -37,6 +55,10 @@ func NewPersistedSimpleIndex() *PersistedSimpleIndex {
 		db:     nil,                          // Will be initialized when database is opened
 		opChan: make(chan dbOperation, 1000), // Buffer for async operations
 		done:   make(chan struct{}),
+		failures: writeFailures{
+			retries: defaultWriteRetries,
+			backoff: defaultWriteRetryBackoff,
+		},
 	}
 }
 
@@ -80,6 +102,12 @@ func (p *PersistedSimpleIndex) OpenDatabase(dbPath string) error {
 		return err
 	}
 
+	// Replay the writes a crash kept from being applied
+	if err := p.recover(db); err != nil {
+		db.Close()
+		return err
+	}
+	db.NoSync = p.noSync
 	p.db = db
 
 	// Start the async database worker
@@ -93,18 +121,55 @@ func (p *PersistedSimpleIndex) OpenDatabase(dbPath string) error {
 	return nil
 }
 
+// OpenDatabaseReadOnly opens an existing BoltDB database read-only, e.g. a snapshot or the database of
+// another node's replica. No async worker is started; load the documents with LoadAllFromDatabase.
+// Other processes may open the database read-only too, but not for writing while it is open.
+func (p *PersistedSimpleIndex) OpenDatabaseReadOnly(dbPath string) error {
+	if p.db != nil {
+		return fmt.Errorf("database already open")
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("failed to open database read-only: %w", err)
+	}
+
+	// Fail instead of waiting forever while a writer holds the database's lock
+	db, err := bbolt.Open(dbPath, 0600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open database read-only: %w", err)
+	}
+	if err := p.loadLastApplied(db); err != nil {
+		db.Close()
+		return err
+	}
+
+	p.db = db
+	p.readOnly = true
+	log.Info().Msgf("Opened persistent database at %s read-only", dbPath)
+	return nil
+}
+
 // startAsyncWorker starts the goroutine that handles database operations asynchronously
 func (p *PersistedSimpleIndex) startAsyncWorker() {
 	p.wg.Add(1)
+	p.workerRunning.Store(true)
 	go func() {
 		defer p.wg.Done()
+		defer p.workerRunning.Store(false)
 		for {
 			select {
 			case op := <-p.opChan:
 				p.processDBOperation(op)
 			case <-p.done:
-				log.Info().Msg("Async database worker shutting down")
-				return
+				// Drain operations queued before shutdown so they are not lost
+				for {
+					select {
+					case op := <-p.opChan:
+						p.processDBOperation(op)
+					default:
+						log.Info().Msg("Async database worker shutting down")
+						return
+					}
+				}
 			}
 		}
 	}()
@@ -119,76 +184,84 @@ func (p *PersistedSimpleIndex) processDBOperation(op dbOperation) {
 
 	if db == nil {
 		log.Warn().Msg("Database not available for async operation")
+		if op.done != nil {
+			op.done <- fmt.Errorf("database is not open")
+		}
+		if result, ok := op.data.(chan error); ok {
+			result <- fmt.Errorf("database is not open")
+		}
+		if request, ok := op.data.(verifyRequest); ok {
+			request.result <- verifyResult{err: fmt.Errorf("database is not open")}
+		}
 		return
 	}
 
+	var err error
 	switch op.opType {
-	case "add_document":
-		if doc, ok := op.data.(models.Document); ok {
-			p.asyncAddDocument(doc)
-		}
-	case "add_documents":
-		if docs, ok := op.data.([]models.Document); ok {
-			p.asyncAddDocuments(docs)
-		}
-	case "update_document":
-		if data, ok := op.data.(map[string]interface{}); ok {
-			if id, ok := data["id"].(string); ok {
-				if doc, ok := data["document"].(models.Document); ok {
-					p.asyncUpdateDocument(id, doc)
-				}
-			}
-		}
-	case "delete_document":
-		if id, ok := op.data.(string); ok {
-			p.asyncDeleteDocument(id)
-		}
-	case "delete_documents":
-		if ids, ok := op.data.([]string); ok {
-			p.asyncDeleteDocuments(ids)
-		}
-	case "update_documents":
-		if docs, ok := op.data.([]models.Document); ok {
-			p.asyncUpdateDocuments(docs)
-		}
+	case "add_document", "add_documents", "update_document", "delete_document", "delete_documents", "update_documents":
+		err = p.persistWrite(op)
 	case "configure":
 		if config, ok := op.data.(map[string]interface{}); ok {
 			p.asyncConfigure(config)
 		}
+	case "compact":
+		if result, ok := op.data.(chan error); ok {
+			result <- p.compactDatabase()
+		}
+	case "clear":
+		if result, ok := op.data.(chan error); ok {
+			result <- p.clearDatabase()
+		}
+	case "verify":
+		if request, ok := op.data.(verifyRequest); ok {
+			report, err := p.verifyDatabase(request.repair)
+			request.result <- verifyResult{report: report, err: err}
+		}
 	default:
 		log.Warn().Msgf("Unknown async operation type: %s", op.opType)
 	}
+
+	// Writes waited on return once applied, and synced to disk if asked
+	if op.done != nil {
+		if err == nil && op.sync {
+			if err = db.Sync(); err != nil {
+				err = fmt.Errorf("failed to sync database: %w", err)
+			}
+		}
+		op.done <- err
+	}
 }
 
 // asyncAddDocument performs the actual database operation for adding a document
-func (p *PersistedSimpleIndex) asyncAddDocument(doc models.Document) {
+func (p *PersistedSimpleIndex) asyncAddDocument(opID uint64, doc models.Document) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		docData, err := json.Marshal(doc)
 		if err != nil {
 			return fmt.Errorf("failed to marshal document: %w", err)
 		}
 		return bucket.Put([]byte(doc.ID), docData)
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async add document failed for %s", doc.ID)
 	} else {
 		log.Debug().Msgf("Async added document %s to database", doc.ID)
 	}
+	return err
 }
 
 // asyncAddDocuments performs the actual database operation for adding multiple documents
-func (p *PersistedSimpleIndex) asyncAddDocuments(docs []models.Document) {
+func (p *PersistedSimpleIndex) asyncAddDocuments(opID uint64, docs []models.Document) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		for _, doc := range docs {
 			docData, err := json.Marshal(doc)
@@ -200,62 +273,65 @@ func (p *PersistedSimpleIndex) asyncAddDocuments(docs []models.Document) {
 			}
 		}
 		return nil
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async add documents failed for %d documents", len(docs))
 	} else {
 		log.Debug().Msgf("Async added %d documents to database", len(docs))
 	}
+	return err
 }
 
 // asyncUpdateDocument performs the actual database operation for updating a document
-func (p *PersistedSimpleIndex) asyncUpdateDocument(id string, doc models.Document) {
+func (p *PersistedSimpleIndex) asyncUpdateDocument(opID uint64, id string, doc models.Document) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		docData, err := json.Marshal(doc)
 		if err != nil {
 			return fmt.Errorf("failed to marshal document: %w", err)
 		}
 		return bucket.Put([]byte(id), docData)
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async update document failed for %s", id)
 	} else {
 		log.Debug().Msgf("Async updated document %s in database", id)
 	}
+	return err
 }
 
 // asyncDeleteDocument performs the actual database operation for deleting a document
-func (p *PersistedSimpleIndex) asyncDeleteDocument(id string) {
+func (p *PersistedSimpleIndex) asyncDeleteDocument(opID uint64, id string) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		return bucket.Delete([]byte(id))
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async delete document failed for %s", id)
 	} else {
 		log.Debug().Msgf("Async deleted document %s from database", id)
 	}
+	return err
 }
 
 // asyncDeleteDocuments performs the actual database operation for deleting multiple documents
-func (p *PersistedSimpleIndex) asyncDeleteDocuments(ids []string) {
+func (p *PersistedSimpleIndex) asyncDeleteDocuments(opID uint64, ids []string) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		for _, id := range ids {
 			if err := bucket.Delete([]byte(id)); err != nil {
@@ -263,22 +339,23 @@ func (p *PersistedSimpleIndex) asyncDeleteDocuments(ids []string) {
 			}
 		}
 		return nil
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async delete documents failed for %d documents", len(ids))
 	} else {
 		log.Debug().Msgf("Async deleted %d documents from database", len(ids))
 	}
+	return err
 }
 
 // asyncUpdateDocuments performs the actual database operation for updating multiple documents
-func (p *PersistedSimpleIndex) asyncUpdateDocuments(docs []models.Document) {
+func (p *PersistedSimpleIndex) asyncUpdateDocuments(opID uint64, docs []models.Document) error {
 	p.mu.RLock()
 	db := p.db
 	p.mu.RUnlock()
 
-	err := db.Update(func(tx *bbolt.Tx) error {
+	err := db.Update(applied(opID, func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		for _, doc := range docs {
 			docData, err := json.Marshal(doc)
@@ -290,13 +367,14 @@ func (p *PersistedSimpleIndex) asyncUpdateDocuments(docs []models.Document) {
 			}
 		}
 		return nil
-	})
+	}))
 
 	if err != nil {
 		log.Error().Err(err).Msgf("Async update documents failed for %d documents", len(docs))
 	} else {
 		log.Debug().Msgf("Async updated %d documents in database", len(docs))
 	}
+	return err
 }
 
 // asyncConfigure performs the actual database operation for configuration
@@ -321,16 +399,16 @@ func (p *PersistedSimpleIndex) asyncConfigure(config map[string]interface{}) {
 	}
 }
 
-// Configure sets the index configuration and persists it asynchronously
+// Configure sets the index configuration and persists it asynchronously, unless the database is read-only
 func (p *PersistedSimpleIndex) Configure(config map[string]interface{}) error {
 	// Configure the in-memory index
-	if err := p.index.Configure(config); err != nil {
+	if err := p.index.configure(config, persistedConfigKeys); err != nil {
 		return err
 	}
 
 	// Queue async database operation if database is open
 	p.mu.RLock()
-	if p.db != nil {
+	if p.db != nil && !p.readOnly {
 		select {
 		case p.opChan <- dbOperation{opType: "configure", data: config}:
 			log.Debug().Msg("Queued async configure operation")
@@ -343,151 +421,90 @@ func (p *PersistedSimpleIndex) Configure(config map[string]interface{}) error {
 	return nil
 }
 
+// MaxResults returns the max_results of the index config (0: none)
+func (p *PersistedSimpleIndex) MaxResults() int {
+	return p.index.MaxResults()
+}
+
 // ShowConfig returns the current index configuration (memory-only operation)
 func (p *PersistedSimpleIndex) ShowConfig() (map[string]interface{}, error) {
 	return p.index.ShowConfig()
 }
 
 // AddDocument adds a single document to the index and persists it asynchronously
-func (p *PersistedSimpleIndex) AddDocument(doc models.Document) error {
+func (p *PersistedSimpleIndex) AddDocument(ctx context.Context, doc models.Document) error {
 	// Add to in-memory index
-	if err := p.index.AddDocument(doc); err != nil {
+	if err := p.index.AddDocument(ctx, doc); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		select {
-		case p.opChan <- dbOperation{opType: "add_document", data: doc}:
-			log.Debug().Msgf("Queued async add document operation for %s", doc.ID)
-		default:
-			log.Warn().Msgf("Async operation queue full, add document operation dropped for %s", doc.ID)
-		}
-	}
-	p.mu.RUnlock()
-
-	return nil
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return p.queue(ctx, dbOperation{opType: "add_document", data: doc}, fmt.Sprintf("add document operation for %s", doc.ID))
 }
 
 // AddDocuments adds multiple documents to the index and persists them asynchronously
-func (p *PersistedSimpleIndex) AddDocuments(docs []models.Document) error {
+func (p *PersistedSimpleIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
 	// Add to in-memory index
-	if err := p.index.AddDocuments(docs); err != nil {
+	if err := p.index.AddDocuments(ctx, docs); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		select {
-		case p.opChan <- dbOperation{opType: "add_documents", data: docs}:
-			log.Debug().Msgf("Queued async add documents operation for %d documents", len(docs))
-		default:
-			log.Warn().Msgf("Async operation queue full, add documents operation dropped for %d documents", len(docs))
-		}
-	}
-	p.mu.RUnlock()
-
-	return nil
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return p.queue(ctx, dbOperation{opType: "add_documents", data: docs}, fmt.Sprintf("add documents operation for %d documents", len(docs)))
 }
 
 // Search performs search using only the in-memory index (no database access)
-func (p *PersistedSimpleIndex) Search(query string) ([]models.Document, error) {
+func (p *PersistedSimpleIndex) Search(ctx context.Context, query string) ([]models.Document, error) {
 	// Search operations work purely from memory for maximum performance
-	return p.index.Search(query)
+	return p.index.Search(ctx, query)
 }
 
 // DeleteDocument removes a document from the index and database asynchronously
-func (p *PersistedSimpleIndex) DeleteDocument(id string) error {
+func (p *PersistedSimpleIndex) DeleteDocument(ctx context.Context, id string) error {
 	// Delete from in-memory index
-	if err := p.index.DeleteDocument(id); err != nil {
+	if err := p.index.DeleteDocument(ctx, id); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		select {
-		case p.opChan <- dbOperation{opType: "delete_document", data: id}:
-			log.Debug().Msgf("Queued async delete document operation for %s", id)
-		default:
-			log.Warn().Msgf("Async operation queue full, delete document operation dropped for %s", id)
-		}
-	}
-	p.mu.RUnlock()
-
-	return nil
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return p.queue(ctx, dbOperation{opType: "delete_document", data: id}, fmt.Sprintf("delete document operation for %s", id))
 }
 
 // DeleteDocuments removes multiple documents from the index and database asynchronously
-func (p *PersistedSimpleIndex) DeleteDocuments(ids []string) error {
+func (p *PersistedSimpleIndex) DeleteDocuments(ctx context.Context, ids []string) error {
 	// Delete from in-memory index
-	if err := p.index.DeleteDocuments(ids); err != nil {
+	if err := p.index.DeleteDocuments(ctx, ids); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		select {
-		case p.opChan <- dbOperation{opType: "delete_documents", data: ids}:
-			log.Debug().Msgf("Queued async delete documents operation for %d documents", len(ids))
-		default:
-			log.Warn().Msgf("Async operation queue full, delete documents operation dropped for %d documents", len(ids))
-		}
-	}
-	p.mu.RUnlock()
-
-	return nil
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return p.queue(ctx, dbOperation{opType: "delete_documents", data: ids}, fmt.Sprintf("delete documents operation for %d documents", len(ids)))
 }
 
 // UpdateDocument updates a document in the index and database asynchronously
-func (p *PersistedSimpleIndex) UpdateDocument(id string, doc models.Document) error {
+func (p *PersistedSimpleIndex) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
 	// Update in-memory index
-	if err := p.index.UpdateDocument(id, doc); err != nil {
+	if err := p.index.UpdateDocument(ctx, id, doc); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		data := map[string]interface{}{
-			"id":       id,
-			"document": doc,
-		}
-		select {
-		case p.opChan <- dbOperation{opType: "update_document", data: data}:
-			log.Debug().Msgf("Queued async update document operation for %s", id)
-		default:
-			log.Warn().Msgf("Async operation queue full, update document operation dropped for %s", id)
-		}
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	data := map[string]interface{}{
+		"id":       id,
+		"document": doc,
 	}
-	p.mu.RUnlock()
-
-	return nil
+	return p.queue(ctx, dbOperation{opType: "update_document", data: data}, fmt.Sprintf("update document operation for %s", id))
 }
 
 // UpdateDocuments updates multiple documents in the index and database asynchronously
-func (p *PersistedSimpleIndex) UpdateDocuments(docs []models.Document) error {
+func (p *PersistedSimpleIndex) UpdateDocuments(ctx context.Context, docs []models.Document) error {
 	// Update in-memory index
-	if err := p.index.UpdateDocuments(docs); err != nil {
+	if err := p.index.UpdateDocuments(ctx, docs); err != nil {
 		return err
 	}
 
-	// Queue async database operation if database is open
-	p.mu.RLock()
-	if p.db != nil {
-		select {
-		case p.opChan <- dbOperation{opType: "update_documents", data: docs}:
-			log.Debug().Msgf("Queued async update documents operation for %d documents", len(docs))
-		default:
-			log.Warn().Msgf("Async operation queue full, update documents operation dropped for %d documents", len(docs))
-		}
-	}
-	p.mu.RUnlock()
-
-	return nil
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return p.queue(ctx, dbOperation{opType: "update_documents", data: docs}, fmt.Sprintf("update documents operation for %d documents", len(docs)))
 }
 
 // Close closes the database connection and shuts down the async worker
@@ -497,10 +514,19 @@ func (p *PersistedSimpleIndex) Close() error {
 
 	// Wait for the async worker to finish
 	p.wg.Wait()
+	if err := p.closeWAL(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close write-ahead log")
+	}
 
 	// Close the database
 	p.mu.Lock()
 	if p.db != nil {
+		// Every queued write is applied, so the next open needs no recovery
+		if !p.readOnly {
+			if err := markClean(p.db); err != nil {
+				log.Warn().Err(err).Msg("Failed to mark database closed cleanly")
+			}
+		}
 		if err := p.db.Close(); err != nil {
 			p.mu.Unlock()
 			return fmt.Errorf("failed to close database: %w", err)
@@ -513,19 +539,38 @@ func (p *PersistedSimpleIndex) Close() error {
 	return p.index.Close()
 }
 
+// HealthCheck reports whether the database is open and readable and, unless it is read-only, the async
+// worker is writing to it without giving up on writes
+func (p *PersistedSimpleIndex) HealthCheck() error {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db == nil {
+		return fmt.Errorf("database is not open")
+	}
+	if err := db.View(func(tx *bbolt.Tx) error { return nil }); err != nil {
+		return fmt.Errorf("database is not readable: %w", err)
+	}
+	if p.readOnly {
+		return nil
+	}
+	if !p.workerRunning.Load() {
+		return fmt.Errorf("async database worker is not running")
+	}
+	if queued := len(p.opChan); queued == cap(p.opChan) {
+		return fmt.Errorf("async database worker is behind: %d operations queued", queued)
+	}
+	return p.failingWrites()
+}
+
 // Flush ensures all data is written to disk
 func (p *PersistedSimpleIndex) Flush() error {
-	if p.db != nil {
+	if p.db != nil && !p.readOnly {
 		return p.db.Sync()
 	}
 	return p.index.Flush()
 }
 
-// Optimize optimizes the index for faster search
-func (p *PersistedSimpleIndex) Optimize() error {
-	return p.index.Optimize()
-}
-
 // Count returns the number of documents in the index (memory-only operation)
 func (p *PersistedSimpleIndex) Count() (int, error) {
 	return p.index.Count()
@@ -546,11 +591,8 @@ func (p *PersistedSimpleIndex) LoadDocumentsFromDatabase() error {
 		return fmt.Errorf("database not open")
 	}
 
-	// Clear the in-memory index first to avoid duplicates
-	p.index = NewSimpleIndex()
-
-	var documents []models.Document
-
+	// Copy the stored documents out of the transaction, then decode them in parallel
+	var stored []storedDocument
 	err := db.View(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket([]byte("documents"))
 		if bucket == nil {
@@ -558,23 +600,26 @@ func (p *PersistedSimpleIndex) LoadDocumentsFromDatabase() error {
 		}
 
 		return bucket.ForEach(func(k, v []byte) error {
-			var doc models.Document
-			if err := json.Unmarshal(v, &doc); err != nil {
-				return fmt.Errorf("failed to unmarshal document %s: %w", string(k), err)
-			}
-			documents = append(documents, doc)
+			stored = append(stored, storedDocument{key: append([]byte(nil), k...), value: append([]byte(nil), v...)})
 			return nil
 		})
 	})
 
+	if err != nil {
+		return err
+	}
+	documents, err := decodeDocuments(stored)
 	if err != nil {
 		return err
 	}
 
-	// Add all documents to the in-memory index at once
-	if err := p.index.AddDocuments(documents); err != nil {
+	// Replace the in-memory index to avoid duplicates, adding all documents at once
+	index := NewSimpleIndex()
+	if err := index.AddDocuments(context.Background(), documents); err != nil {
 		return fmt.Errorf("failed to add documents to memory index: %w", err)
 	}
+	index.SetReadOnly(p.readOnly || p.index.ReadOnly())
+	p.index = index
 
 	log.Info().Msgf("Loaded %d documents from database into memory", len(documents))
 	return nil
@@ -611,7 +656,7 @@ func (p *PersistedSimpleIndex) LoadConfigFromDatabase() error {
 	}
 
 	// Apply the configuration to the in-memory index
-	if err := p.index.Configure(config); err != nil {
+	if err := p.index.configure(config, persistedConfigKeys); err != nil {
 		return fmt.Errorf("failed to apply configuration to memory index: %w", err)
 	}
 
@@ -674,6 +719,22 @@ func NewPersistedSimpleIndexWithDatabaseAndLoad(dbPath string) (*PersistedSimple
 	return index, nil
 }
 
+// NewPersistedSimpleIndexReadOnly creates an index serving the documents of an existing database, which
+// it opens read-only; mutations fail with ErrReadOnly
+func NewPersistedSimpleIndexReadOnly(dbPath string) (*PersistedSimpleIndex, error) {
+	index := NewPersistedSimpleIndex()
+
+	if err := index.OpenDatabaseReadOnly(dbPath); err != nil {
+		return nil, err
+	}
+	if err := index.LoadAllFromDatabase(); err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return index, nil
+}
+
 // IsDatabaseEmpty checks if the database has any documents
 func (p *PersistedSimpleIndex) IsDatabaseEmpty() (bool, error) {
 	p.mu.RLock()
@@ -701,19 +762,41 @@ func (p *PersistedSimpleIndex) IsDatabaseEmpty() (bool, error) {
 	return isEmpty, err
 }
 
-// GetDatabaseStats returns statistics about the database
+// GetDatabaseStats returns statistics about the database: its documents and configuration, the size of
+// its file, the free pages Compact would release and the keys and pages of each bucket
 func (p *PersistedSimpleIndex) GetDatabaseStats() (map[string]interface{}, error) {
 	p.mu.RLock()
+	defer p.mu.RUnlock()
 	db := p.db
-	p.mu.RUnlock()
 
 	if db == nil {
 		return nil, fmt.Errorf("database not open")
 	}
 
 	stats := make(map[string]interface{})
+	info, err := os.Stat(db.Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat database file: %w", err)
+	}
+	stats["file_size_bytes"] = info.Size()
+	stats["page_size"] = db.Info().PageSize
+	dbStats := db.Stats()
+	stats["free_pages"] = dbStats.FreePageN
+	stats["pending_pages"] = dbStats.PendingPageN
+	stats["free_bytes"] = dbStats.FreeAlloc
+	stats["freelist_bytes"] = dbStats.FreelistInuse
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		stats["data_size_bytes"] = tx.Size()
+		buckets := make(map[string]interface{})
+		if err := tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			buckets[string(name)] = bucketStats(bucket)
+			return nil
+		}); err != nil {
+			return err
+		}
+		stats["buckets"] = buckets
 
-	err := db.View(func(tx *bbolt.Tx) error {
 		// Count documents
 		docBucket := tx.Bucket([]byte("documents"))
 		if docBucket != nil {
@@ -0,0 +1,60 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersistedSimpleIndex_HealthCheck(t *testing.T) {
+	idx := NewPersistedSimpleIndex()
+	assert.ErrorContains(t, idx.HealthCheck(), "not open")
+
+	assert.NoError(t, idx.OpenDatabase(filepath.Join(t.TempDir(), "index.db")))
+	assert.NoError(t, idx.HealthCheck())
+
+	assert.NoError(t, idx.Close())
+	assert.ErrorContains(t, idx.HealthCheck(), "not open")
+}
+
+func TestPersistedSimpleIndex_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	_, err := NewPersistedSimpleIndexReadOnly(dbPath)
+	assert.Error(t, err, "a missing database is not created")
+
+	writer, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.AddDocuments(ctx, []models.Document{{ID: "1", Text: "foo"}, {ID: "2", Text: "bar"}}))
+	assert.NoError(t, writer.Close())
+
+	idx, err := NewPersistedSimpleIndexReadOnly(dbPath)
+	assert.NoError(t, err)
+	count, _ := idx.Count()
+	assert.Equal(t, 2, count)
+	assert.False(t, idx.workerRunning.Load(), "no async worker for a read-only database")
+	assert.NoError(t, idx.HealthCheck())
+
+	assert.ErrorIs(t, idx.AddDocument(ctx, models.Document{ID: "3", Text: "baz"}), ErrReadOnly)
+	assert.ErrorIs(t, idx.UpdateDocument(ctx, "1", models.Document{ID: "1", Text: "baz"}), ErrReadOnly)
+	assert.ErrorIs(t, idx.DeleteDocuments(ctx, []string{"1"}), ErrReadOnly)
+	var export bytes.Buffer
+	assert.NoError(t, idx.Export(&export))
+	assert.ErrorIs(t, idx.Import(&export), ErrReadOnly)
+	assert.NoError(t, idx.Configure(map[string]interface{}{"k": "v"}))
+	assert.NoError(t, idx.Flush())
+
+	results, err := idx.Search(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// A second reader shares the database
+	other, err := NewPersistedSimpleIndexReadOnly(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, other.Close())
+	assert.NoError(t, idx.Close())
+}
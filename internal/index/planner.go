@@ -0,0 +1,412 @@
+package index
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Query planning. Each condition of a query is answered from the cheapest structure the index keeps for
+ * it: equality from the ordinals of the documents holding each value of a dimension (ORed into a
+ * bitmap), numeric comparisons from the values of the dimension sorted by number, free text from the
+ * postings of an inverted index, and anything else by evaluating it on every candidate document.
+ * Structures are only used for selective conditions; conditions run most selective first.
+ **/
+
+// Access paths of the steps of a query plan
+const (
+	AccessInverted = "inverted" // Free text, from the postings of an inverted index
+	AccessBitmap   = "bitmap"   // Equality, from the documents holding each value of the dimension
+	AccessRange    = "range"    // Numeric comparison, from the values of the dimension sorted by number
+	AccessScan     = "scan"     // Evaluated on every candidate document
+)
+
+// indexedSelectivity is the largest share of the documents a condition answered from a structure may
+// match; less selective conditions are evaluated on the candidates of the others
+const indexedSelectivity = 0.5
+
+// PlanStep is a condition (or the free text) of a planned query and how it is answered
+type PlanStep struct {
+	Condition string `json:"condition"`
+	Access    string `json:"access"`   // One of the Access constants
+	Estimate  int    `json:"estimate"` // Documents expected to match the step alone
+}
+
+// QueryPlan explains how an index answers a query, for the Explain API
+type QueryPlan struct {
+	Plan      string     `json:"plan"`      // One of the Plan constants
+	Documents int        `json:"documents"` // Documents in the index
+	Steps     []PlanStep `json:"steps"`     // In the order they run
+}
+
+// String formats a condition as queries write it
+func (c QueryCondition) String() string {
+	if c.Operator == OpContains {
+		return c.Dimension + " contains " + c.Value
+	}
+	return c.Dimension + string(c.Operator) + c.Value
+}
+
+// conditionStep is a condition of a query as planned
+type conditionStep struct {
+	condition QueryCondition
+	access    string
+	estimate  int
+	values    []string // Values of the dimension the condition matches, when answered from a structure
+}
+
+// planConditions picks the structure each condition of a query is answered from, using those of the
+// conditions expected to match at most limit documents, and orders them: conditions answered from a
+// structure first, then the others, each most selective first. The caller must hold the read lock.
+func (idx *SimpleIndex) planConditions(query *Query, limit int) []conditionStep {
+	steps := make([]conditionStep, len(query.Conditions))
+	for i, condition := range query.Conditions {
+		step := conditionStep{condition: condition, access: AccessScan, estimate: len(idx.documents)}
+		if values, access, ok := idx.values.lookup(condition, query.CaseSensitive); ok {
+			step.estimate = idx.values.count(condition.Dimension, values)
+			if step.estimate <= limit {
+				step.access, step.values = access, values
+			}
+		} else if !isBuiltinDimension(condition.Dimension) {
+			// Only the documents holding the dimension can match
+			step.estimate = 0
+			for _, count := range idx.catalog[condition.Dimension] {
+				step.estimate += count
+			}
+		}
+		steps[i] = step
+	}
+	slices.SortStableFunc(steps, func(a, b conditionStep) int {
+		if scanA, scanB := a.access == AccessScan, b.access == AccessScan; scanA != scanB {
+			if scanA {
+				return 1
+			}
+			return -1
+		}
+		return a.estimate - b.estimate
+	})
+	return steps
+}
+
+// indexedLimit is the number of documents conditions answered from a structure may match; the caller
+// must hold the read lock
+func (idx *SimpleIndex) indexedLimit() int {
+	return int(indexedSelectivity * float64(len(idx.documents)))
+}
+
+// eachCandidate calls fn with the documents that may match the planned steps, until it returns false:
+// those matching every step answered from a structure, or every document when none is. The caller must
+// hold the read lock.
+func (idx *SimpleIndex) eachCandidate(steps []conditionStep, fn func(id string, doc models.Document) bool) {
+	candidates, ok := idx.values.candidates(steps)
+	if !ok {
+		for id, doc := range idx.documents {
+			if !fn(id, doc) {
+				return
+			}
+		}
+		return
+	}
+	candidates.each(func(ordinal uint32) bool {
+		id := idx.values.ids[ordinal]
+		return fn(id, idx.documents[id])
+	})
+}
+
+// matchesSteps evaluates the planned steps not answered from a structure on a document
+func matchesSteps(doc models.Document, steps []conditionStep, caseSensitive bool) (bool, error) {
+	for _, step := range steps {
+		if step.access != AccessScan {
+			continue
+		}
+		matches, err := step.condition.evaluate(doc, caseSensitive)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// explainSteps describes planned steps
+func explainSteps(steps []conditionStep) []PlanStep {
+	explained := make([]PlanStep, len(steps))
+	for i, step := range steps {
+		explained[i] = PlanStep{Condition: step.condition.String(), Access: step.access, Estimate: step.estimate}
+	}
+	return explained
+}
+
+// valueIndex gives every document an ordinal and keeps the ordinals of the documents holding each value
+// of each metadata key. The index guards it with its own lock, except for the numeric ranges searches
+// build on first use.
+type valueIndex struct {
+	ordinals map[string]uint32                         // Document ID -> ordinal
+	ids      []string                                  // Ordinal -> document ID ("": free)
+	free     []uint32                                  // Ordinals of removed documents, reused first
+	values   map[string]map[string]map[uint32]struct{} // Key -> value -> ordinals of the documents holding it
+
+	rangesMu sync.Mutex
+	ranges   map[string]*numericRange // Key -> its values sorted by number, dropped when they change
+}
+
+func newValueIndex() *valueIndex {
+	return &valueIndex{
+		ordinals: make(map[string]uint32),
+		values:   make(map[string]map[string]map[uint32]struct{}),
+		ranges:   make(map[string]*numericRange),
+	}
+}
+
+// add indexes the metadata values of doc; the previous version of doc must have been removed
+func (v *valueIndex) add(doc models.Document) {
+	var ordinal uint32
+	if n := len(v.free); n > 0 {
+		ordinal, v.free = v.free[n-1], v.free[:n-1]
+		v.ids[ordinal] = doc.ID
+	} else {
+		ordinal = uint32(len(v.ids))
+		v.ids = append(v.ids, doc.ID)
+	}
+	v.ordinals[doc.ID] = ordinal
+	for key, value := range doc.Meta {
+		// Conditions never match empty values
+		if value == "" {
+			continue
+		}
+		if v.values[key] == nil {
+			v.values[key] = make(map[string]map[uint32]struct{})
+		}
+		if v.values[key][value] == nil {
+			v.values[key][value] = make(map[uint32]struct{})
+			v.dropRange(key)
+		}
+		v.values[key][value][ordinal] = struct{}{}
+	}
+}
+
+// remove forgets the metadata values of doc
+func (v *valueIndex) remove(doc models.Document) {
+	ordinal, ok := v.ordinals[doc.ID]
+	if !ok {
+		return
+	}
+	delete(v.ordinals, doc.ID)
+	v.ids[ordinal] = ""
+	v.free = append(v.free, ordinal)
+	for key, value := range doc.Meta {
+		holding := v.values[key][value]
+		if holding == nil {
+			continue
+		}
+		delete(holding, ordinal)
+		if len(holding) == 0 {
+			delete(v.values[key], value)
+			v.dropRange(key)
+		}
+		if len(v.values[key]) == 0 {
+			delete(v.values, key)
+		}
+	}
+}
+
+func (v *valueIndex) dropRange(key string) {
+	v.rangesMu.Lock()
+	defer v.rangesMu.Unlock()
+	delete(v.ranges, key)
+}
+
+// lookup returns the values of its dimension a condition matches, and the structure they are found
+// with; conditions answered by evaluating them on documents are not looked up
+func (v *valueIndex) lookup(c QueryCondition, caseSensitive bool) ([]string, string, bool) {
+	// Conditions on the path and text may read the document's source and text
+	if c.Dimension == "path" || c.Dimension == "text" {
+		return nil, "", false
+	}
+	number, numeric := parseNumber(c.Value)
+	numeric = numeric && !math.IsNaN(number)
+	switch c.Operator {
+	case OpExact:
+		if _, ok := v.values[c.Dimension][c.Value]; ok {
+			return []string{c.Value}, AccessBitmap, true
+		}
+		return nil, AccessBitmap, true
+	case OpEquals:
+		if numeric {
+			return v.numericRange(c.Dimension).matching(c, number, caseSensitive), AccessRange, true
+		}
+		if caseSensitive {
+			if _, ok := v.values[c.Dimension][c.Value]; ok {
+				return []string{c.Value}, AccessBitmap, true
+			}
+			return nil, AccessBitmap, true
+		}
+		var values []string
+		for value := range v.values[c.Dimension] {
+			if c.equals(value, false) {
+				values = append(values, value)
+			}
+		}
+		return values, AccessBitmap, true
+	case OpLess, OpLessEq, OpGreater, OpGreaterEq:
+		// Documents with numbers compared with other values fail the condition with an error, which
+		// searches log: those are scanned
+		if numeric {
+			return v.numericRange(c.Dimension).matching(c, number, caseSensitive), AccessRange, true
+		}
+	}
+	return nil, "", false
+}
+
+// count returns the number of documents holding values of a key
+func (v *valueIndex) count(key string, values []string) int {
+	count := 0
+	for _, value := range values {
+		count += len(v.values[key][value])
+	}
+	return count
+}
+
+// candidates returns the ordinals of the documents matching every planned step answered from a
+// structure, or false if none is
+func (v *valueIndex) candidates(steps []conditionStep) (bitmap, bool) {
+	var result bitmap
+	indexed := false
+	for _, step := range steps {
+		if step.access == AccessScan {
+			continue
+		}
+		var matched bitmap
+		for _, value := range step.values {
+			for ordinal := range v.values[step.condition.Dimension][value] {
+				matched.set(ordinal)
+			}
+		}
+		if !indexed {
+			result, indexed = matched, true
+		} else {
+			result.and(matched)
+		}
+	}
+	return result, indexed
+}
+
+// numericRange holds the values of a metadata key sorted by number, so comparisons with a number find
+// the values they match by binary search
+type numericRange struct {
+	numbers []float64 // The numeric values, ascending (NaN left out: no comparison matches it)
+	values  []string  // The value of each number
+	text    []string  // The values that are not numbers, which comparisons compare as text
+}
+
+// numericRange returns the values of a key sorted by number, building them on first use
+func (v *valueIndex) numericRange(key string) *numericRange {
+	v.rangesMu.Lock()
+	defer v.rangesMu.Unlock()
+	if r, ok := v.ranges[key]; ok {
+		return r
+	}
+	type entry struct {
+		number float64
+		value  string
+	}
+	var entries []entry
+	r := &numericRange{}
+	for value := range v.values[key] {
+		number, ok := parseNumber(value)
+		switch {
+		case !ok:
+			r.text = append(r.text, value)
+		case !math.IsNaN(number):
+			entries = append(entries, entry{number, value})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].number != entries[j].number {
+			return entries[i].number < entries[j].number
+		}
+		return entries[i].value < entries[j].value
+	})
+	r.numbers = make([]float64, len(entries))
+	r.values = make([]string, len(entries))
+	for i, e := range entries {
+		r.numbers[i], r.values[i] = e.number, e.value
+	}
+	v.ranges[key] = r
+	return r
+}
+
+// matching returns the values a condition comparing with a number (not NaN) matches
+func (r *numericRange) matching(c QueryCondition, number float64, caseSensitive bool) []string {
+	first := sort.SearchFloat64s(r.numbers, number)
+	after := sort.Search(len(r.numbers), func(i int) bool { return r.numbers[i] > number })
+	lo, hi := 0, len(r.numbers)
+	switch c.Operator {
+	case OpEquals:
+		lo, hi = first, after
+	case OpLess:
+		hi = first
+	case OpLessEq:
+		hi = after
+	case OpGreater:
+		lo = after
+	case OpGreaterEq:
+		lo = first
+	}
+	values := append([]string{}, r.values[lo:hi]...)
+	for _, text := range r.text {
+		if c.Operator == OpEquals && c.equals(text, caseSensitive) || c.Operator != OpEquals && compareText(c.Operator, text, c.Value) {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+// compareText compares a value that is not a number with a condition's value, as evaluateNumeric does
+func compareText(operator QueryOperator, value, with string) bool {
+	switch operator {
+	case OpLess:
+		return value < with
+	case OpLessEq:
+		return value <= with
+	case OpGreater:
+		return value > with
+	case OpGreaterEq:
+		return value >= with
+	}
+	return false
+}
+
+// and keeps the ordinals of b that other also has
+func (b bitmap) and(other bitmap) {
+	for i := range b {
+		if i < len(other) {
+			b[i] &= other[i]
+		} else {
+			b[i] = 0
+		}
+	}
+}
+
+// each calls fn with the ordinals of b in ascending order, until it returns false
+func (b bitmap) each(fn func(uint32) bool) {
+	for i, word := range b {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			if !fn(uint32(i*64 + bit)) {
+				return
+			}
+			word &^= 1 << bit
+		}
+	}
+}
+
+// explainText describes the free text of a query matched on the candidates of its conditions
+func explainText(text string, documents int) PlanStep {
+	return PlanStep{Condition: strings.TrimSpace(text), Access: AccessScan, Estimate: documents}
+}
@@ -0,0 +1,152 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// plannerDocs are 100 documents: a tenth of kind "a", sizes 0 to 99 (one "n/a") and a name each
+func plannerDocs() []models.Document {
+	docs := make([]models.Document, 100)
+	for i := range docs {
+		kind, size := "b", fmt.Sprint(i)
+		if i%10 == 0 {
+			kind = "a"
+		}
+		if i == 50 {
+			size = "n/a"
+		}
+		docs[i] = makeTestDoc(fmt.Sprint(i), fmt.Sprintf("report %d", i), fmt.Sprintf("/docs/%d.txt", i),
+			map[string]string{"kind": kind, "size": size, "name": fmt.Sprintf("Doc-%d", i)}, nil)
+	}
+	return docs
+}
+
+func TestSimpleIndex_ExplainQuery(t *testing.T) {
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocuments(context.Background(), plannerDocs()))
+
+	tests := []struct {
+		query string
+		want  []PlanStep
+	}{
+		{"kind=b and size<5", []PlanStep{
+			{Condition: "size<5", Access: AccessRange, Estimate: 5},
+			{Condition: "kind=b", Access: AccessScan, Estimate: 90},
+		}},
+		{"kind=a and name contains doc-1 and size>=90", []PlanStep{
+			{Condition: "kind=a", Access: AccessBitmap, Estimate: 10},
+			{Condition: "size>=90", Access: AccessRange, Estimate: 11}, // "n/a" compares as text
+			{Condition: "name contains doc-1", Access: AccessScan, Estimate: 100},
+		}},
+		{"author=alice and kind=a", []PlanStep{
+			{Condition: "author=alice", Access: AccessBitmap, Estimate: 0},
+			{Condition: "kind=a", Access: AccessBitmap, Estimate: 10},
+		}},
+		{"report size=7.0", []PlanStep{
+			{Condition: "size=7.0", Access: AccessRange, Estimate: 1},
+			{Condition: "report", Access: AccessScan, Estimate: 100},
+		}},
+		{"path contains docs and size<abc", []PlanStep{
+			// Conditions expected to match as many documents run in query order
+			{Condition: "path contains docs", Access: AccessScan, Estimate: 100},
+			{Condition: "size<abc", Access: AccessScan, Estimate: 100},
+		}},
+	}
+	for _, tt := range tests {
+		prepared, err := idx.PrepareQuery(tt.query)
+		assert.NoError(t, err, tt.query)
+		plan := prepared.Explain()
+		assert.Equal(t, 100, plan.Documents, tt.query)
+		assert.Equal(t, tt.want, plan.Steps, tt.query)
+	}
+}
+
+func TestSimpleIndex_PlannedSearch(t *testing.T) {
+	queries := []string{
+		"kind=a", "kind=A", "kind==A", "kind!=a", "kind=a and size<30", "size<=10 and size>5", "size>95",
+		"size>=n", "size<abc", "size=5.0", "size=5.0 and kind=b", "name contains doc-9 and kind=b",
+		"author=alice", "path contains 7 and kind=a", "report 4 kind=a", "name=doc-42", "name==Doc-42",
+	}
+	for _, collation := range []string{CollationCaseInsensitive, CollationCaseSensitive} {
+		idx := NewSimpleIndex()
+		assert.NoError(t, idx.Configure(map[string]interface{}{"collation": collation}))
+		docs := plannerDocs()
+		assert.NoError(t, idx.AddDocuments(context.Background(), docs))
+		// Updates and deletes keep the values of the planner current
+		assert.NoError(t, idx.UpdateDocument(context.Background(), "3", makeTestDoc("3", "report 3", "/docs/3.txt", map[string]string{"kind": "a", "size": "3"}, nil)))
+		docs[3] = makeTestDoc("3", "report 3", "/docs/3.txt", map[string]string{"kind": "a", "size": "3"}, nil)
+		assert.NoError(t, idx.DeleteDocument(context.Background(), "20"))
+		docs = append(docs[:20], docs[21:]...)
+
+		for _, query := range queries {
+			parsed, err := ParseQuery(query)
+			assert.NoError(t, err, query)
+			parsed.CaseSensitive = collation == CollationCaseSensitive
+			want := []string{}
+			for _, doc := range docs {
+				if matches, err := parsed.Evaluate(doc); err == nil && matches {
+					want = append(want, doc.ID)
+				}
+			}
+			results, err := idx.Search(context.Background(), query)
+			assert.NoError(t, err, query)
+			ids := []string{}
+			for _, doc := range results {
+				ids = append(ids, doc.ID)
+			}
+			sort.Strings(want)
+			sort.Strings(ids)
+			assert.Equal(t, want, ids, collation+" "+query)
+		}
+	}
+}
+
+func TestPreparedQuery_ExplainInvertedAndVector(t *testing.T) {
+	inverted, err := NewIndexFactory().Create("inverted", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, inverted.AddDocuments(context.Background(), plannerDocs()))
+	prepared, err := inverted.PrepareQuery("report kind=a")
+	assert.NoError(t, err)
+	assert.Equal(t, QueryPlan{Plan: PlanTextConditions, Documents: 100, Steps: []PlanStep{
+		{Condition: "report", Access: AccessInverted, Estimate: 100},
+		{Condition: "kind=a", Access: AccessBitmap, Estimate: 10},
+	}}, prepared.Explain())
+	results, err := prepared.Execute(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 10)
+
+	vectors, err := NewVectorIndex("cosine", 3, 2)
+	assert.NoError(t, err)
+	for i, doc := range plannerDocs() {
+		doc.Vector = []float64{float64(i), 1}
+		assert.NoError(t, vectors.AddDocument(context.Background(), doc))
+	}
+	prepared, err = vectors.PrepareQuery("[1, 0] where kind=a")
+	assert.NoError(t, err)
+	assert.Equal(t, []PlanStep{
+		{Condition: "kind=a", Access: AccessBitmap, Estimate: 10},
+		{Condition: "[1, 0]", Access: AccessScan, Estimate: 3},
+	}, prepared.Explain().Steps)
+	results, err = prepared.Execute(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"90", "80", "70"}, preparedIDs(results))
+}
+
+func BenchmarkSimpleIndex_SearchSelectiveCondition(b *testing.B) {
+	idx := benchmarkIndex(b, 10000)
+	assert.NoError(b, idx.UpdateDocument(context.Background(), "42", makeTestDoc("42", "Quarterly Report", "/Docs/Report.PDF", map[string]string{"fileExtension": "pdf"}, nil)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := idx.Search(context.Background(), "fileExtension=pdf"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// Plans of prepared queries: how an index answers them
+const (
+	PlanEmpty          = "empty"           // The empty query, which matches nothing
+	PlanText           = "text"            // Free text, matched against the text, metadata and source
+	PlanConditions     = "conditions"      // Conditions on dimensions, answered as Explain shows
+	PlanTextConditions = "text_conditions" // Free text among the documents matching conditions
+	PlanVector         = "vector"          // Nearest neighbours of a vector, among those matching a where clause
+	PlanRemote         = "remote"          // Sent as it is to a remote index
+)
+
+// PreparedQuery is a query parsed, validated and planned once by PrepareQuery, which can then be executed
+// many times, e.g. by dashboards refreshing the same panels. Executions see the documents indexed at the
+// time and may run concurrently.
+type PreparedQuery struct {
+	query   string
+	plan    string
+	search  func(ctx context.Context) ([]models.Document, error)
+	explain func() QueryPlan // Plans the query against the documents indexed at the time (nil: no steps)
+}
+
+// Query returns the query as written
+func (p PreparedQuery) Query() string {
+	return p.query
+}
+
+// Plan returns how the index answers the query (one of the Plan constants)
+func (p PreparedQuery) Plan() string {
+	return p.plan
+}
+
+// Explain returns how the query would be answered now: the structure each condition is answered from
+// and how many documents it is expected to match, in the order they run
+func (p PreparedQuery) Explain() QueryPlan {
+	if p.explain == nil {
+		return QueryPlan{Plan: p.plan, Steps: []PlanStep{}}
+	}
+	plan := p.explain()
+	plan.Plan = p.plan
+	return plan
+}
+
+// Execute runs the query and returns the page of limit results (0: all) after the first offset ones
+func (p PreparedQuery) Execute(ctx context.Context, offset, limit int) ([]models.Document, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("offset and limit must not be negative, got %d and %d", offset, limit)
+	}
+	if p.search == nil {
+		return nil, fmt.Errorf("query was not prepared by an index")
+	}
+	results, err := p.search(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(results) {
+		return []models.Document{}, nil
+	}
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// InvalidQueryError is returned by PrepareQuery for queries with problems
+type InvalidQueryError struct {
+	Query  string
+	Errors []QueryError
+}
+
+func (e *InvalidQueryError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return fmt.Sprintf("invalid query %q: %s", e.Query, strings.Join(messages, "; "))
+}
+
+// checkQuery fails with the problems ValidateQuery found in a query, except unknown dimensions: documents
+// indexed after the query is prepared may have them
+func checkQuery(query string, problems []QueryError) error {
+	var errs []QueryError
+	for _, problem := range problems {
+		if problem.Code != QueryErrorUnknownDimension {
+			errs = append(errs, problem)
+		}
+	}
+	if len(errs) > 0 {
+		return &InvalidQueryError{Query: query, Errors: errs}
+	}
+	return nil
+}
+
+// emptyQuery is the plan of the empty query
+func emptyQuery() PreparedQuery {
+	return PreparedQuery{plan: PlanEmpty, search: func(ctx context.Context) ([]models.Document, error) {
+		return []models.Document{}, nil
+	}}
+}
+
+// PrepareQuery parses, validates and plans a query, for Search or repeated executions
+func (idx *SimpleIndex) PrepareQuery(query string) (PreparedQuery, error) {
+	if err := checkQuery(query, idx.ValidateQuery(query)); err != nil {
+		return PreparedQuery{}, err
+	}
+	return idx.prepare(query), nil
+}
+
+// prepare plans a query: conditions (and the free text before them) or a free-text search
+func (idx *SimpleIndex) prepare(query string) PreparedQuery {
+	if query == "" {
+		return emptyQuery()
+	}
+	if parsed, err := ParseQuery(query); err == nil && len(parsed.Conditions) > 0 {
+		plan := PlanConditions
+		if parsed.Text != "" {
+			plan = PlanTextConditions
+		}
+		text := parseTextQuery(strings.ToLower(parsed.Text))
+		return PreparedQuery{query: query, plan: plan, search: func(ctx context.Context) ([]models.Document, error) {
+			idx.mu.RLock()
+			defer idx.mu.RUnlock()
+			// Executions may run concurrently: each evaluates a copy with the current collation
+			conditions := *parsed
+			conditions.CaseSensitive = idx.caseSensitive
+			if err := idx.checkDimensions(&conditions); err != nil {
+				return nil, err
+			}
+			return idx.searchAdvanced(ctx, &conditions, text)
+		}, explain: func() QueryPlan {
+			idx.mu.RLock()
+			defer idx.mu.RUnlock()
+			conditions := *parsed
+			conditions.CaseSensitive = idx.caseSensitive
+			steps := explainSteps(idx.planConditions(&conditions, idx.indexedLimit()))
+			if parsed.Text != "" {
+				steps = append(steps, explainText(parsed.Text, len(idx.documents)))
+			}
+			return QueryPlan{Documents: len(idx.documents), Steps: steps}
+		}}
+	}
+	// Fall back to simple text search for backward compatibility
+	lowered := strings.ToLower(query)
+	text := parseTextQuery(lowered)
+	return PreparedQuery{query: query, plan: PlanText, search: func(ctx context.Context) ([]models.Document, error) {
+		idx.mu.RLock()
+		defer idx.mu.RUnlock()
+		return idx.searchSimple(ctx, lowered, text)
+	}, explain: func() QueryPlan {
+		count, _ := idx.Count()
+		return QueryPlan{Documents: count, Steps: []PlanStep{explainText(query, count)}}
+	}}
+}
+
+// PrepareQuery parses, validates and plans a query against the documents held in memory
+func (p *PersistedSimpleIndex) PrepareQuery(query string) (PreparedQuery, error) {
+	return p.index.PrepareQuery(query)
+}
+
+// PrepareQuery parses, validates and plans a query, analyzing its free text for every language once
+func (idx *InvertedIndex) PrepareQuery(query string) (PreparedQuery, error) {
+	if err := checkQuery(query, idx.ValidateQuery(query)); err != nil {
+		return PreparedQuery{}, err
+	}
+	return idx.prepare(query), nil
+}
+
+// prepare plans a query: conditions are answered by scanning the stored documents, free text (before
+// conditions or not) from the postings
+func (idx *InvertedIndex) prepare(query string) PreparedQuery {
+	if query == "" {
+		return emptyQuery()
+	}
+	var filter *Query
+	text := query
+	if parsed, err := ParseQuery(query); err == nil && len(parsed.Conditions) > 0 {
+		if parsed.Text == "" {
+			scan := idx.store.prepare(query)
+			return PreparedQuery{query: query, plan: PlanConditions, search: func(ctx context.Context) ([]models.Document, error) {
+				idx.mu.RLock()
+				stored := idx.stored
+				idx.mu.RUnlock()
+				results, err := scan.search(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return loadFields(stored, results)
+			}, explain: scan.explain}
+		}
+		// Free text followed by conditions: the text is searched for, the conditions filter
+		filter = parsed
+		text = parsed.Text
+	}
+	plan := PlanText
+	if filter != nil {
+		plan = PlanTextConditions
+	}
+	parsed := parseTextQuery(text)
+	// The languages and their analyzers are set before documents are added, so the terms stay valid
+	idx.mu.RLock()
+	terms := make(map[string][]string)
+	for _, language := range idx.languages.languages() {
+		terms[language] = parsed.terms(idx.analyzerOf(language))
+	}
+	idx.mu.RUnlock()
+	return PreparedQuery{query: query, plan: plan, search: func(ctx context.Context) ([]models.Document, error) {
+		return idx.searchTerms(ctx, text, parsed, terms, filter)
+	}, explain: func() QueryPlan {
+		return idx.explainTerms(text, terms, filter)
+	}}
+}
+
+// explainTerms plans a free-text query: its text is answered from the postings, with as many documents
+// as the rarest term of each language has, then the conditions after it among those
+func (idx *InvertedIndex) explainTerms(text string, terms map[string][]string, filter *Query) QueryPlan {
+	idx.mu.RLock()
+	estimate := 0
+	for _, languageTerms := range terms {
+		rarest := -1
+		for _, term := range languageTerms {
+			if rarest < 0 || len(idx.postings[term]) < rarest {
+				rarest = len(idx.postings[term])
+			}
+		}
+		estimate += max(rarest, 0)
+	}
+	idx.mu.RUnlock()
+
+	idx.store.mu.RLock()
+	defer idx.store.mu.RUnlock()
+	documents := len(idx.store.documents)
+	estimate = min(estimate, documents)
+	steps := []PlanStep{{Condition: strings.TrimSpace(text), Access: AccessInverted, Estimate: estimate}}
+	if filter != nil {
+		conditions := *filter
+		conditions.CaseSensitive = idx.store.caseSensitive
+		steps = append(steps, explainSteps(idx.store.planConditions(&conditions, min(estimate-1, idx.store.indexedLimit())))...)
+	}
+	return QueryPlan{Documents: documents, Steps: steps}
+}
+
+// PrepareQuery parses, validates and plans vector queries (the vector and where clause), and others as
+// SimpleIndex does
+func (idx *VectorIndex) PrepareQuery(query string) (PreparedQuery, error) {
+	if err := checkQuery(query, idx.ValidateQuery(query)); err != nil {
+		return PreparedQuery{}, err
+	}
+	return idx.prepare(query)
+}
+
+// prepare plans kNN searches for vector queries, and searches of the stored documents for others
+func (idx *VectorIndex) prepare(query string) (PreparedQuery, error) {
+	vector, where, ok, err := ParseVectorQuery(query)
+	if err != nil {
+		return PreparedQuery{}, err
+	}
+	if !ok {
+		return idx.store.prepare(query), nil
+	}
+	var filter *Query
+	if where != "" {
+		if filter, err = ParseQuery(where); err != nil {
+			return PreparedQuery{}, fmt.Errorf("invalid where clause: %w", err)
+		}
+	}
+	return PreparedQuery{query: query, plan: PlanVector, search: func(ctx context.Context) ([]models.Document, error) {
+		if filter == nil {
+			return idx.SearchVector(ctx, vector, idx.k, nil)
+		}
+		idx.store.mu.RLock()
+		conditions := *filter
+		conditions.CaseSensitive = idx.store.caseSensitive
+		idx.store.mu.RUnlock()
+		return idx.SearchVector(ctx, vector, idx.k, &conditions)
+	}, explain: func() QueryPlan {
+		idx.store.mu.RLock()
+		defer idx.store.mu.RUnlock()
+		documents := len(idx.store.documents)
+		var steps []PlanStep
+		if filter != nil {
+			conditions := *filter
+			conditions.CaseSensitive = idx.store.caseSensitive
+			steps = explainSteps(idx.store.planConditions(&conditions, idx.store.indexedLimit()))
+			if filter.Text != "" {
+				steps = append(steps, explainText(filter.Text, documents))
+			}
+		}
+		// The k nearest of the candidates are found by comparing the query vector with each
+		steps = append(steps, PlanStep{Condition: FormatVectorLiteral(vector), Access: AccessScan, Estimate: min(idx.k, documents)})
+		return QueryPlan{Documents: documents, Steps: steps}
+	}}, nil
+}
+
+// PrepareQuery plans a query that is sent as it is on every execution; the remote index parses and
+// validates it
+func (idx *RemoteIndex) PrepareQuery(query string) (PreparedQuery, error) {
+	return PreparedQuery{query: query, plan: PlanRemote, search: func(ctx context.Context) ([]models.Document, error) {
+		return idx.Search(ctx, query)
+	}}, nil
+}
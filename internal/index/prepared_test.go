@@ -0,0 +1,122 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func preparedIDs(docs []models.Document) []string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	return ids
+}
+
+func TestPrepareQuery_Plans(t *testing.T) {
+	factory := NewIndexFactory()
+	docs := []models.Document{
+		makeTestDoc("1", "kafka consumer", "a.go", map[string]string{"fileExtension": "go"}, []float64{1, 0}),
+		makeTestDoc("2", "kafka producer", "b.md", map[string]string{"fileExtension": "md"}, []float64{0, 1}),
+	}
+	tests := []struct {
+		kind, query, plan string
+		want              []string
+	}{
+		{"simple", "", PlanEmpty, []string{}},
+		{"simple", "kafka", PlanText, []string{"1", "2"}},
+		{"simple", "fileExtension=md", PlanConditions, []string{"2"}},
+		{"simple", "kafka fileExtension=go", PlanTextConditions, []string{"1"}},
+		{"inverted", "kafka -producer", PlanText, []string{"1"}},
+		{"inverted", "fileExtension=go", PlanConditions, []string{"1"}},
+		{"inverted", "kafka fileExtension=md", PlanTextConditions, []string{"2"}},
+		{"vector", "[0.9, 0.1] where fileExtension=md", PlanVector, []string{"2"}},
+		{"vector", "producer", PlanText, []string{"2"}},
+	}
+	for _, tt := range tests {
+		idx, err := factory.Create(tt.kind, map[string]interface{}{"vector_size": 2, "k": 1})
+		assert.NoError(t, err, tt.kind)
+		assert.NoError(t, idx.AddDocuments(context.Background(), docs))
+
+		prepared, err := idx.PrepareQuery(tt.query)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.plan, prepared.Plan(), tt.query)
+		assert.Equal(t, tt.query, prepared.Query(), tt.query)
+		results, err := prepared.Execute(context.Background(), 0, 0)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, preparedIDs(results), tt.kind+" "+tt.query)
+
+		// Executions agree with Search
+		searched, err := idx.Search(context.Background(), tt.query)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, preparedIDs(searched), preparedIDs(results), tt.kind+" "+tt.query)
+	}
+}
+
+func TestPreparedQuery_Execute(t *testing.T) {
+	idx := NewSimpleIndex()
+	prepared, err := idx.PrepareQuery("fileExtension=go")
+	assert.NoError(t, err)
+
+	// Executions see the documents indexed after the query was prepared
+	results, err := prepared.Execute(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc(id, "text", id+".go", map[string]string{"fileExtension": "go"}, nil)))
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 0, []string{"1", "2", "3", "4", "5"}},
+		{0, 2, []string{"1", "2"}},
+		{2, 2, []string{"3", "4"}},
+		{4, 2, []string{"5"}},
+		{5, 2, []string{}},
+		{3, 0, []string{"4", "5"}},
+	}
+	for _, tt := range tests {
+		results, err := prepared.Execute(context.Background(), tt.offset, tt.limit)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, preparedIDs(results), "offset %d limit %d", tt.offset, tt.limit)
+	}
+
+	_, err = prepared.Execute(context.Background(), -1, 0)
+	assert.Error(t, err)
+	_, err = PreparedQuery{}.Execute(context.Background(), 0, 0)
+	assert.Error(t, err)
+
+	// The collation applies when the query is executed
+	assert.NoError(t, idx.Configure(map[string]interface{}{"collation": CollationCaseSensitive}))
+	prepared, err = idx.PrepareQuery("fileExtension=GO")
+	assert.NoError(t, err)
+	results, err = prepared.Execute(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestPrepareQuery_Invalid(t *testing.T) {
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "hello", "a.go", map[string]string{"fileSize": "10"}, nil)))
+
+	_, err := idx.PrepareQuery("fileSize=<10")
+	var invalid *InvalidQueryError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, QueryErrorBadOperator, invalid.Errors[0].Code)
+
+	// Dimensions no document has yet are allowed
+	_, err = idx.PrepareQuery("author=alice")
+	assert.NoError(t, err)
+
+	vectors, err := NewVectorIndex("cosine", 1, 2)
+	assert.NoError(t, err)
+	_, err = vectors.PrepareQuery("[1, 2, 3]")
+	assert.Error(t, err)
+}
@@ -5,7 +5,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/aawadall/bit-scout/internal/config"
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
 )
@@ -15,6 +17,7 @@ type QueryOperator string
 
 const (
 	OpEquals    QueryOperator = "="
+	OpExact     QueryOperator = "==" // Case-sensitive, exact equality whatever the index's collation
 	OpNotEquals QueryOperator = "!="
 	OpLess      QueryOperator = "<"
 	OpLessEq    QueryOperator = "<="
@@ -32,11 +35,35 @@ type QueryCondition struct {
 
 // Query represents a parsed query with conditions
 type Query struct {
-	Conditions []QueryCondition
-	RawQuery   string
+	Conditions    []QueryCondition
+	Text          string // Free text before the conditions, e.g. "kafka consumer" ("": none)
+	RawQuery      string
+	CaseSensitive bool // Compare text values case-sensitively (the collation of the index searched)
 }
 
-// ParseQuery parses a query string into a Query struct
+// Collations of indexes: how =, != and contains compare text values
+const (
+	CollationCaseInsensitive = "case_insensitive" // The default
+	CollationCaseSensitive   = "case_sensitive"   // For code search, where Main.go and main.go differ
+)
+
+// parseCollation reads the collation setting of an index config, reporting whether it is case-sensitive
+func parseCollation(cfg map[string]interface{}) (bool, error) {
+	collation, err := config.String(cfg, "collation", CollationCaseInsensitive)
+	if err != nil {
+		return false, err
+	}
+	switch collation {
+	case CollationCaseInsensitive:
+		return false, nil
+	case CollationCaseSensitive:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown collation %q (known collations: %s, %s)", collation, CollationCaseInsensitive, CollationCaseSensitive)
+}
+
+// ParseQuery parses a query string into a Query struct. The first condition may follow free text, as in
+// "kafka consumer extension=go and path contains internal".
 func ParseQuery(queryStr string) (*Query, error) {
 	query := &Query{
 		RawQuery:   queryStr,
@@ -47,13 +74,19 @@ func ParseQuery(queryStr string) (*Query, error) {
 	// This is a simple implementation - can be extended for OR logic
 	parts := strings.Split(queryStr, " and ")
 
-	for _, part := range parts {
+	for i, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue
 		}
 
 		condition, err := parseCondition(part)
+		if err != nil && i == 0 {
+			if text, rest, ok := splitLeadingText(part); ok {
+				query.Text = text
+				condition, err = parseCondition(rest)
+			}
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse condition '%s': %w", part, err)
 		}
@@ -65,12 +98,28 @@ func ParseQuery(queryStr string) (*Query, error) {
 	return query, nil
 }
 
-// parseCondition parses a single condition like "fileExtension=go" or "fileSize<10"
+// conditionPattern matches: dimension operator value. Dimensions may be namespaced with dots, as
+// features composed from several extractors are (e.g. "content.word_count").
+// Supports: =, ==, !=, <, <=, >, >=, contains
+var conditionPattern = regexp.MustCompile(`^([\w.]+)\s*(==|=|!=|<=|>=|<|>|contains)\s*(.+)$`)
+
+// splitLeadingText splits a part of a query into free text and the condition after it: the shortest
+// text the rest of which is a condition, e.g. "kafka consumer" and "extension=go"
+func splitLeadingText(part string) (text, condition string, ok bool) {
+	for i, r := range part {
+		if !unicode.IsSpace(r) {
+			continue
+		}
+		if rest := strings.TrimLeftFunc(part[i:], unicode.IsSpace); conditionPattern.MatchString(rest) {
+			return strings.TrimSpace(part[:i]), rest, true
+		}
+	}
+	return "", "", false
+}
+
+// parseCondition parses a single condition like "fileExtension=go", "filename==Main.go" or "fileSize<10"
 func parseCondition(conditionStr string) (QueryCondition, error) {
-	// Regex to match: dimension operator value
-	// Supports: =, !=, <, <=, >, >=, contains
-	re := regexp.MustCompile(`^(\w+)\s*(=|!=|<=|>=|<|>|contains)\s*(.+)$`)
-	matches := re.FindStringSubmatch(conditionStr)
+	matches := conditionPattern.FindStringSubmatch(conditionStr)
 
 	if len(matches) != 4 {
 		return QueryCondition{}, fmt.Errorf("invalid condition format: %s", conditionStr)
@@ -93,10 +142,19 @@ func parseCondition(conditionStr string) (QueryCondition, error) {
 	}, nil
 }
 
-// Evaluate evaluates a query against a document
+// Evaluate evaluates a query against a document: its conditions, then its free text (see Matches)
 func (q *Query) Evaluate(doc models.Document) (bool, error) {
+	matches, err := q.matchesConditions(doc)
+	if err != nil || !matches || q.Text == "" {
+		return matches, err
+	}
+	return parseTextQuery(strings.ToLower(q.Text)).matches(doc) > 0, nil
+}
+
+// matchesConditions evaluates the conditions of a query against a document, leaving out its free text
+func (q *Query) matchesConditions(doc models.Document) (bool, error) {
 	for _, condition := range q.Conditions {
-		matches, err := condition.Evaluate(doc)
+		matches, err := condition.evaluate(doc, q.CaseSensitive)
 		if err != nil {
 			return false, fmt.Errorf("condition evaluation failed: %w", err)
 		}
@@ -109,8 +167,13 @@ func (q *Query) Evaluate(doc models.Document) (bool, error) {
 	return true, nil
 }
 
-// Evaluate evaluates a single condition against a document
+// Evaluate evaluates a single condition against a document, comparing text case-insensitively
 func (c *QueryCondition) Evaluate(doc models.Document) (bool, error) {
+	return c.evaluate(doc, false)
+}
+
+// evaluate evaluates a condition, comparing text values with =, != and contains case-sensitively or not
+func (c *QueryCondition) evaluate(doc models.Document, caseSensitive bool) (bool, error) {
 	// Get the value from document metadata
 	docValue, exists := doc.Meta[c.Dimension]
 	if !exists {
@@ -133,12 +196,18 @@ func (c *QueryCondition) Evaluate(doc models.Document) (bool, error) {
 
 	switch c.Operator {
 	case OpEquals:
-		return strings.EqualFold(docValue, c.Value), nil
+		return c.equals(docValue, caseSensitive), nil
+
+	case OpExact:
+		return docValue == c.Value, nil
 
 	case OpNotEquals:
-		return !strings.EqualFold(docValue, c.Value), nil
+		return !c.equals(docValue, caseSensitive), nil
 
 	case OpContains:
+		if caseSensitive {
+			return strings.Contains(docValue, c.Value), nil
+		}
 		return strings.Contains(strings.ToLower(docValue), strings.ToLower(c.Value)), nil
 
 	case OpLess, OpLessEq, OpGreater, OpGreaterEq:
@@ -150,6 +219,36 @@ func (c *QueryCondition) Evaluate(doc models.Document) (bool, error) {
 	}
 }
 
+// equals compares numbers by value, so numeric features match however they were formatted
+// (e.g. "0.5" and "0.50"); other values, including booleans, are compared case-insensitively unless
+// caseSensitive
+func (c *QueryCondition) equals(docValue string, caseSensitive bool) bool {
+	docNum, docOK := parseNumber(docValue)
+	queryNum, queryOK := parseNumber(c.Value)
+	if docOK && queryOK {
+		return docNum == queryNum
+	}
+	if caseSensitive {
+		return docValue == c.Value
+	}
+	return strings.EqualFold(docValue, c.Value)
+}
+
+// parseNumber parses a number as strconv.ParseFloat does. Values that cannot start one are rejected
+// without calling it, as its errors allocate and most text values are compared on every search.
+func parseNumber(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	switch c := value[0]; {
+	case c >= '0' && c <= '9', c == '+', c == '-', c == '.', c == 'i', c == 'I', c == 'n', c == 'N':
+	default:
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	return number, err == nil
+}
+
 // evaluateNumeric handles numeric comparisons
 func (c *QueryCondition) evaluateNumeric(docValue string) (bool, error) {
 	// Try to parse as float64 for numeric comparison
@@ -188,3 +287,17 @@ func (c *QueryCondition) evaluateNumeric(docValue string) (bool, error) {
 		return false, fmt.Errorf("unsupported numeric operator: %s", c.Operator)
 	}
 }
+
+// Matches reports whether a document matches a query the way SimpleIndex.Search does: boolean queries
+// are evaluated, other queries are matched as text against the text, metadata and source, with their
+// +term and -term terms
+func Matches(query string, doc models.Document) bool {
+	if query == "" {
+		return false
+	}
+	if parsed, err := ParseQuery(query); err == nil && len(parsed.Conditions) > 0 {
+		matches, err := parsed.Evaluate(doc)
+		return err == nil && matches
+	}
+	return parseTextQuery(strings.ToLower(query)).matches(doc) > 0
+}
@@ -76,3 +76,119 @@ func TestParseQuery_InvalidFormat(t *testing.T) {
 	_, err := ParseQuery("invalidquery")
 	assert.Error(t, err)
 }
+
+func TestParseQuery_NamespacedDimension(t *testing.T) {
+	q, err := ParseQuery("content.word_count>500")
+	assert.NoError(t, err)
+	assert.Len(t, q.Conditions, 1)
+	assert.Equal(t, "content.word_count", q.Conditions[0].Dimension)
+	assert.Equal(t, OpGreater, q.Conditions[0].Operator)
+}
+
+func TestQueryCondition_Evaluate_NumericEquals(t *testing.T) {
+	doc := models.Document{Meta: map[string]string{"entropy": "0.5"}}
+	cond := QueryCondition{Dimension: "entropy", Operator: OpEquals, Value: "0.50"}
+	match, err := cond.Evaluate(doc)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	cond = QueryCondition{Dimension: "entropy", Operator: OpNotEquals, Value: "0.50"}
+	match, err = cond.Evaluate(doc)
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestQuery_Evaluate_FeatureDimensions(t *testing.T) {
+	// Features merged into Meta by the feature pipeline
+	doc := models.Document{Meta: map[string]string{"word_count": "812", "is_executable": "true", "mime_type": "text/x-shellscript"}}
+	q, err := ParseQuery("word_count>500 and is_executable=true and mime_type contains shell")
+	assert.NoError(t, err)
+	match, err := q.Evaluate(doc)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	q, _ = ParseQuery("word_count>500 and is_executable=false")
+	match, err = q.Evaluate(doc)
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestMatches(t *testing.T) {
+	doc := models.Document{Text: "Release notes", Source: "/docs/CHANGELOG.md", Meta: map[string]string{"fileSize": "120"}}
+	assert.True(t, Matches("release", doc))
+	assert.True(t, Matches("changelog", doc))
+	assert.True(t, Matches("fileSize<200", doc))
+	assert.False(t, Matches("fileSize>200", doc))
+	assert.False(t, Matches("roadmap", doc))
+	assert.False(t, Matches("", doc))
+}
+
+func TestParseQuery_Exact(t *testing.T) {
+	q, err := ParseQuery("filename==Main.go")
+	assert.NoError(t, err)
+	assert.Len(t, q.Conditions, 1)
+	assert.Equal(t, OpExact, q.Conditions[0].Operator)
+	assert.Equal(t, "Main.go", q.Conditions[0].Value)
+}
+
+func TestQuery_Evaluate_Collation(t *testing.T) {
+	doc := models.Document{Meta: map[string]string{"filename": "Main.go", "fileSize": "10"}}
+	tests := []struct {
+		name          string
+		query         string
+		caseSensitive bool
+		want          bool
+	}{
+		{"equals ignores case", "filename=main.go", false, true},
+		{"exact is case-sensitive", "filename==main.go", false, false},
+		{"exact matches", "filename==Main.go", false, true},
+		{"exact compares text, not numbers", "fileSize==10.0", false, false},
+		{"case-sensitive equals", "filename=main.go", true, false},
+		{"case-sensitive equals matches", "filename=Main.go", true, true},
+		{"case-sensitive not equals", "filename!=main.go", true, true},
+		{"case-sensitive contains", "filename contains main", true, false},
+		{"case-sensitive numbers by value", "fileSize=10.0", true, true},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		assert.NoError(t, err, tt.name)
+		q.CaseSensitive = tt.caseSensitive
+		match, err := q.Evaluate(doc)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, match, tt.name)
+	}
+}
+
+func TestParseQuery_LeadingText(t *testing.T) {
+	q, err := ParseQuery("kafka consumer extension=go and path contains internal")
+	assert.NoError(t, err)
+	assert.Equal(t, "kafka consumer", q.Text)
+	assert.Equal(t, []QueryCondition{
+		{Dimension: "extension", Operator: OpEquals, Value: "go"},
+		{Dimension: "path", Operator: OpContains, Value: "internal"},
+	}, q.Conditions)
+
+	// Only the first condition may follow text
+	_, err = ParseQuery("extension=go and kafka consumer path contains internal")
+	assert.Error(t, err)
+
+	q, err = ParseQuery("kafka consumer")
+	assert.Error(t, err)
+	assert.Nil(t, q)
+}
+
+func TestQuery_Evaluate_LeadingText(t *testing.T) {
+	doc := models.Document{Text: "a Kafka consumer", Source: "internal/queue.go", Meta: map[string]string{"extension": "go"}}
+	for query, want := range map[string]bool{
+		"kafka extension=go and path contains internal": true,
+		"kafka -consumer extension=go":                  false,
+		"rabbitmq extension=go":                         false,
+		"kafka extension=md":                            false,
+	} {
+		q, err := ParseQuery(query)
+		assert.NoError(t, err, query)
+		match, err := q.Evaluate(doc)
+		assert.NoError(t, err, query)
+		assert.Equal(t, want, match, query)
+	}
+}
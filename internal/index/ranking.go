@@ -0,0 +1,154 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/internal/scoring"
+)
+
+/**
+ * Function scoring. Free-text results are ranked by their weighted text relevance (TF-IDF) plus boosts
+ * computed from the values of their fields, e.g. the recency of lastModified, so fresh documents can
+ * outrank slightly more relevant stale ones.
+ **/
+
+// Boost functions
+const (
+	BoostDecay  = "decay"  // Halves every scale since the time of the field (RFC 3339, e.g. lastModified)
+	BoostLinear = "linear" // The numeric value of the field
+	BoostLog    = "log"    // ln(1 + value) of the numeric field, for values spanning magnitudes (e.g. fileSize)
+)
+
+// Boost scores a document by the value of one of its fields
+type Boost struct {
+	Function string
+	Weight   float64
+	Scale    time.Duration // Half-life of decay boosts
+}
+
+// Ranking combines the text relevance of documents with the boosts of their fields, and optionally
+// computes their final score with a script
+type Ranking struct {
+	TextWeight float64          // Weight of the text relevance
+	Boosts     map[string]Boost // Boosts by field: "text", "source" or a metadata key
+	Script     *scoring.Script  // Scores documents from their combined score as _score (nil: none)
+}
+
+// DefaultRanking ranks by text relevance alone
+func DefaultRanking() Ranking {
+	return Ranking{TextWeight: 1}
+}
+
+// parseRanking reads the "text_weight", "boosts" and "score_script" config of an index, e.g.
+// {"boosts": {"lastModified": {"function": "decay", "scale": "720h"}}}
+func parseRanking(cfg map[string]interface{}) (Ranking, error) {
+	ranking := DefaultRanking()
+	var err error
+	if ranking.TextWeight, err = config.Float(cfg, "text_weight", 1); err != nil {
+		return Ranking{}, err
+	}
+	if ranking.TextWeight < 0 {
+		return Ranking{}, fmt.Errorf("text_weight must not be negative, got %g", ranking.TextWeight)
+	}
+	script, err := config.String(cfg, "score_script", "")
+	if err != nil {
+		return Ranking{}, err
+	}
+	if script != "" {
+		if ranking.Script, err = scoring.Compile(script); err != nil {
+			return Ranking{}, err
+		}
+	}
+	boosts, err := config.Map(cfg, "boosts")
+	if err != nil || boosts == nil {
+		return ranking, err
+	}
+	ranking.Boosts = make(map[string]Boost, len(boosts))
+	for field, value := range boosts {
+		options, ok := value.(map[string]interface{})
+		if !ok {
+			return Ranking{}, fmt.Errorf("boost of field %s must be an object, got %T", field, value)
+		}
+		boost, err := parseBoost(options)
+		if err != nil {
+			return Ranking{}, fmt.Errorf("boost of field %s: %w", field, err)
+		}
+		ranking.Boosts[field] = boost
+	}
+	return ranking, nil
+}
+
+func parseBoost(options map[string]interface{}) (Boost, error) {
+	var boost Boost
+	var err error
+	if boost.Function, err = config.String(options, "function", ""); err != nil {
+		return Boost{}, err
+	}
+	if boost.Weight, err = config.Float(options, "weight", 1); err != nil {
+		return Boost{}, err
+	}
+	if boost.Scale, err = config.Duration(options, "scale", 0); err != nil {
+		return Boost{}, err
+	}
+	switch boost.Function {
+	case BoostDecay:
+		if boost.Scale <= 0 {
+			return Boost{}, fmt.Errorf("decay boosts need a positive scale")
+		}
+	case BoostLinear, BoostLog:
+	default:
+		return Boost{}, fmt.Errorf("unknown function %q (known functions: %s, %s, %s)", boost.Function, BoostDecay, BoostLinear, BoostLog)
+	}
+	return boost, nil
+}
+
+// custom reports whether the ranking boosts any field or has a script, so it differs from ranking by
+// text relevance alone
+func (r Ranking) custom() bool {
+	return len(r.Boosts) > 0 || r.Script != nil
+}
+
+// score combines the text relevance of a document with its boosts at time now, and applies the script
+func (r Ranking) score(text float64, doc models.Document, now time.Time) float64 {
+	score := r.TextWeight*text + r.boost(doc, now)
+	if r.Script != nil {
+		score = r.Script.Score(doc, score, now)
+	}
+	return score
+}
+
+// boost sums the boosts of a document's fields at time now. Fields that are missing or hold values the
+// function cannot read add nothing.
+func (r Ranking) boost(doc models.Document, now time.Time) float64 {
+	total := 0.0
+	for field, boost := range r.Boosts {
+		value := doc.Field(field)
+		if value == "" {
+			continue
+		}
+		switch boost.Function {
+		case BoostDecay:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				continue
+			}
+			age := math.Max(now.Sub(t).Seconds(), 0)
+			total += boost.Weight * math.Pow(0.5, age/boost.Scale.Seconds())
+		case BoostLinear, BoostLog:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			if boost.Function == BoostLog {
+				n = math.Log1p(math.Max(n, 0))
+			}
+			total += boost.Weight * n
+		}
+	}
+	return total
+}
@@ -0,0 +1,128 @@
+package index
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestRanking_Boost(t *testing.T) {
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	ranking := Ranking{TextWeight: 1, Boosts: map[string]Boost{
+		"lastModified": {Function: BoostDecay, Weight: 2, Scale: 24 * time.Hour},
+		"fileSize":     {Function: BoostLog, Weight: 1},
+	}}
+
+	fresh := models.Document{ID: "1", Meta: map[string]string{"lastModified": now.Format(time.RFC3339)}}
+	assert.InDelta(t, 2, ranking.boost(fresh, now), 1e-9)
+	// The decay halves every scale
+	dayOld := models.Document{ID: "2", Meta: map[string]string{"lastModified": now.Add(-24 * time.Hour).Format(time.RFC3339)}}
+	assert.InDelta(t, 1, ranking.boost(dayOld, now), 1e-9)
+	sized := models.Document{ID: "3", Meta: map[string]string{"fileSize": "1000"}}
+	assert.InDelta(t, 6.909, ranking.boost(sized, now), 1e-3)
+	// Fields that are missing or unreadable add nothing
+	unreadable := models.Document{ID: "4", Meta: map[string]string{"lastModified": "yesterday", "fileSize": "big"}}
+	assert.Zero(t, ranking.boost(unreadable, now))
+	assert.InDelta(t, 1.5+1, ranking.score(1.5, dayOld, now), 1e-9)
+}
+
+func TestParseRanking(t *testing.T) {
+	ranking, err := parseRanking(map[string]interface{}{
+		"text_weight": 0.5,
+		"boosts": map[string]interface{}{
+			"lastModified": map[string]interface{}{"function": "decay", "scale": "720h"},
+			"fileSize":     map[string]interface{}{"function": "linear", "weight": 0.01},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Ranking{TextWeight: 0.5, Boosts: map[string]Boost{
+		"lastModified": {Function: BoostDecay, Weight: 1, Scale: 720 * time.Hour},
+		"fileSize":     {Function: BoostLinear, Weight: 0.01},
+	}}, ranking)
+
+	ranking, err = parseRanking(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRanking(), ranking)
+
+	for _, boost := range []map[string]interface{}{
+		{"function": "decay"},
+		{"function": "square"},
+		{"function": "linear", "weight": "high"},
+	} {
+		_, err := parseRanking(map[string]interface{}{"boosts": map[string]interface{}{"fileSize": boost}})
+		assert.Error(t, err, boost)
+	}
+	_, err = parseRanking(map[string]interface{}{"text_weight": -1})
+	assert.Error(t, err)
+	_, err = parseRanking(map[string]interface{}{"score_script": "_score *"})
+	assert.Error(t, err)
+}
+
+func TestInvertedIndex_ScoreScript(t *testing.T) {
+	ctx := context.Background()
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"score_script": "_score + log(word_count)",
+	})
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		{ID: "short", Text: "report report", Meta: map[string]string{"word_count": "2"}},
+		{ID: "long", Text: "report", Meta: map[string]string{"word_count": "5000"}},
+	}))
+
+	// The long document's word count outweighs its lower relevance
+	found, err := idx.Search(ctx, "report")
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, "long", found[0].ID)
+
+	stats, err := idx.(TermScorer).TermStats("report", []string{"long"})
+	assert.NoError(t, err)
+	assert.Equal(t, "_score + log(word_count)", stats.Script)
+	assert.Equal(t, map[string]float64{"long": 0}, stats.Boosts)
+}
+
+func TestInvertedIndex_RecencyBoost(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	idx, err := NewIndexFactory().Create("inverted", map[string]interface{}{
+		"boosts": map[string]interface{}{
+			"lastModified": map[string]interface{}{"function": "decay", "scale": "24h", "weight": 5},
+		},
+	})
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		{ID: "stale", Text: "report report report", Meta: map[string]string{"lastModified": now.AddDate(0, -1, 0).Format(time.RFC3339)}},
+		{ID: "fresh", Text: "report", Meta: map[string]string{"lastModified": now.Format(time.RFC3339)}},
+		{ID: "other", Text: "memo"},
+	}))
+	ids := func(results []models.Document) []string {
+		var out []string
+		for _, doc := range results {
+			out = append(out, doc.ID)
+		}
+		return out
+	}
+
+	// The fresh document outranks the more relevant stale one
+	found, err := idx.Search(ctx, "report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fresh", "stale"}, ids(found))
+
+	stats, err := idx.(TermScorer).TermStats("report", []string{"fresh", "stale"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, stats.TextWeight)
+	assert.Greater(t, stats.Boosts["fresh"], stats.Boosts["stale"])
+
+	// Without boosts the more relevant document ranks first
+	plain := NewInvertedIndex(nil)
+	assert.NoError(t, plain.AddDocuments(ctx, []models.Document{{ID: "stale", Text: "report report report"}, {ID: "fresh", Text: "report"}}))
+	found, err = plain.Search(ctx, "report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stale", "fresh"}, ids(found))
+}
@@ -0,0 +1,273 @@
+package index
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Recovery: a persisted index marks its database dirty while it is open for writing and clean once Close
+ * has drained the async worker, so opening a dirty database reveals a crash. Writes queued but not yet
+ * applied at a crash are lost, unless the index keeps a write-ahead log: each write is appended to it,
+ * next to the database, before it is queued. Opening the database replays the logged writes past the last
+ * one applied, in one transaction, before the documents are loaded into memory, then empties the log.
+ **/
+
+var dirtyKey = []byte("dirty")
+
+// RecoveryReport describes the recovery of a persisted index's database when it was opened
+type RecoveryReport struct {
+	Unclean     bool   // The database was not closed cleanly, e.g. the process was killed
+	Logged      int    // Writes in the write-ahead log
+	Replayed    int    // Logged writes applied by the recovery, past the last one applied before
+	Torn        bool   // The last logged write was cut short by the crash and discarded
+	LastApplied uint64 // ID of the last write applied once recovered
+}
+
+// Recovery returns the report of the recovery run when the database was opened
+func (p *PersistedSimpleIndex) Recovery() RecoveryReport {
+	return p.recovery
+}
+
+// walPath is the path of the write-ahead log of a database
+func walPath(dbPath string) string {
+	return dbPath + ".wal"
+}
+
+// EnableWAL appends each write to a write-ahead log next to the database before queuing it, so writes a
+// crash keeps from being applied are replayed when the database is next opened. With a log, writes wait
+// for room in a full queue instead of being dropped.
+func (p *PersistedSimpleIndex) EnableWAL() error {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db == nil {
+		return fmt.Errorf("database not open")
+	}
+	if p.readOnly {
+		return ErrReadOnly
+	}
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	if p.wal != nil {
+		return nil
+	}
+	wal, err := openWriteAheadLog(walPath(db.Path()))
+	if err != nil {
+		return err
+	}
+	p.wal = wal
+	log.Info().Msgf("Logging writes to %s before queuing them", wal.file.Name())
+	return nil
+}
+
+// writeAheadLog is an append-only file of writes, one JSON Operation per line
+type writeAheadLog struct {
+	file *os.File
+}
+
+func openWriteAheadLog(path string) (*writeAheadLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	return &writeAheadLog{file: file}, nil
+}
+
+// append logs a queued write; the caller holds the queue
+func (w *writeAheadLog) append(op dbOperation) error {
+	data, err := json.Marshal(loggedOperation(op))
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation %d: %w", op.id, err)
+	}
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to log operation %d: %w", op.id, err)
+	}
+	return nil
+}
+
+// truncate empties the log once every write in it is applied
+func (w *writeAheadLog) truncate() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// loggedOperation is the Operation a write is logged and replayed as
+func loggedOperation(op dbOperation) Operation {
+	logged := Operation{ID: op.id, Type: OpAddDocuments}
+	switch data := op.data.(type) {
+	case models.Document:
+		logged.Documents = []models.Document{data}
+	case []models.Document:
+		logged.Documents = data
+	case map[string]interface{}:
+		if doc, ok := data["document"].(models.Document); ok {
+			logged.Documents = []models.Document{doc}
+		}
+	case string:
+		logged.Type, logged.IDs = OpDeleteDocuments, []string{data}
+	case []string:
+		logged.Type, logged.IDs = OpDeleteDocuments, data
+	}
+	return logged
+}
+
+// checkpointWAL empties the write-ahead log once every write queued is applied; it runs on the async
+// worker, and skips the checkpoint rather than wait while a write is being queued
+func (p *PersistedSimpleIndex) checkpointWAL() {
+	if len(p.opChan) > 0 || !p.queueMu.TryLock() {
+		return
+	}
+	defer p.queueMu.Unlock()
+	if p.wal == nil || p.lastApplied.Load() != p.lastQueued {
+		return
+	}
+	if err := p.wal.truncate(); err != nil {
+		log.Warn().Err(err).Msg("Write-ahead log checkpoint failed")
+	}
+}
+
+// closeWAL empties the write-ahead log if every write in it was applied and closes it; it runs once the
+// async worker has stopped
+func (p *PersistedSimpleIndex) closeWAL() error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	if p.wal == nil {
+		return nil
+	}
+	if p.lastApplied.Load() == p.lastQueued {
+		if err := p.wal.truncate(); err != nil {
+			return err
+		}
+	} else {
+		log.Warn().Msgf("Keeping write-ahead log %s: writes up to %d are logged, up to %d applied", p.wal.file.Name(), p.lastQueued, p.lastApplied.Load())
+	}
+	err := p.wal.file.Close()
+	p.wal = nil
+	return err
+}
+
+// readWriteAheadLog reads the writes of a log, reporting whether its last line was cut short
+func readWriteAheadLog(path string) ([]Operation, bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	defer file.Close()
+
+	var ops []Operation
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	for scanner.Scan() {
+		var op Operation
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			// Only the last write can be cut short: the crash stopped the log there
+			return ops, true, nil
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+	return ops, false, nil
+}
+
+// recover reads the last write applied to a database opened for writing, replays the write-ahead log
+// past it and marks the database dirty until Close
+func (p *PersistedSimpleIndex) recover(db *bbolt.DB) error {
+	if err := p.loadLastApplied(db); err != nil {
+		return err
+	}
+	report := RecoveryReport{LastApplied: p.lastApplied.Load()}
+	err := db.View(func(tx *bbolt.Tx) error {
+		if bucket := tx.Bucket(metaBucket); bucket != nil {
+			report.Unclean = bucket.Get(dirtyKey) != nil
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read database state: %w", err)
+	}
+
+	path := walPath(db.Path())
+	ops, torn, err := readWriteAheadLog(path)
+	if err != nil {
+		return err
+	}
+	report.Logged, report.Torn = len(ops), torn
+	var replay []Operation
+	for _, op := range ops {
+		if op.ID > report.LastApplied {
+			replay = append(replay, op)
+			report.LastApplied = max(report.LastApplied, op.ID)
+		}
+	}
+
+	err = db.Update(applied(report.LastApplied, func(tx *bbolt.Tx) error {
+		documents := tx.Bucket([]byte("documents"))
+		for _, op := range replay {
+			for _, doc := range op.Documents {
+				data, err := json.Marshal(doc)
+				if err != nil {
+					return fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+				}
+				if err := documents.Put([]byte(doc.ID), data); err != nil {
+					return fmt.Errorf("failed to replay operation %d: %w", op.ID, err)
+				}
+			}
+			for _, id := range op.IDs {
+				if err := documents.Delete([]byte(id)); err != nil {
+					return fmt.Errorf("failed to replay operation %d: %w", op.ID, err)
+				}
+			}
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		return meta.Put(dirtyKey, []byte{1})
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to recover database: %w", err)
+	}
+	report.Replayed = len(replay)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove replayed write-ahead log: %w", err)
+	}
+	if err := p.loadLastApplied(db); err != nil {
+		return err
+	}
+	p.recovery = report
+
+	switch {
+	case report.Unclean && report.Logged == 0:
+		log.Warn().Msgf("Database %s was not closed cleanly: writes queued at the time may be lost (a write-ahead log, \"wal\": true, would replay them)", db.Path())
+	case report.Unclean || report.Replayed > 0:
+		log.Warn().Msgf("Recovered database %s: replayed %d of %d logged writes, last applied write %d (unclean shutdown: %t, torn write discarded: %t)",
+			db.Path(), report.Replayed, report.Logged, report.LastApplied, report.Unclean, report.Torn)
+	}
+	return nil
+}
+
+// markClean clears the dirty mark of a database closed cleanly
+func markClean(db *bbolt.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(dirtyKey)
+	})
+}
@@ -0,0 +1,80 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// crash leaves a database as a killed process would: marked dirty, with writes logged but not applied
+func crash(t *testing.T, dbPath string, logged ...dbOperation) {
+	db, err := bbolt.Open(dbPath, 0600, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(dirtyKey, []byte{1})
+	}))
+	assert.NoError(t, db.Close())
+
+	wal, err := openWriteAheadLog(walPath(dbPath))
+	assert.NoError(t, err)
+	for _, op := range logged {
+		assert.NoError(t, wal.append(op))
+	}
+	_, err = wal.file.WriteString(`{"ID":9,"Type":"add_doc`) // Cut short by the crash
+	assert.NoError(t, err)
+	assert.NoError(t, wal.file.Close())
+}
+
+func TestPersistedSimpleIndex_Recovery(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.EnableWAL())
+	persisted := WithDurability(context.Background(), DurabilityPersisted)
+	assert.NoError(t, idx.AddDocuments(persisted, []models.Document{{ID: "1", Text: "one"}, {ID: "2", Text: "two"}}))
+	assert.Equal(t, RecoveryReport{}, idx.Recovery())
+	assert.NoError(t, idx.Close())
+
+	// A clean close applies and empties the log
+	info, err := os.Stat(walPath(dbPath))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	crash(t, dbPath,
+		dbOperation{id: 1, opType: "add_document", data: models.Document{ID: "1", Text: "stale"}},
+		dbOperation{id: 2, opType: "add_document", data: models.Document{ID: "3", Text: "three"}},
+		dbOperation{id: 3, opType: "update_document", data: map[string]interface{}{"id": "2", "document": models.Document{ID: "2", Text: "dos"}}},
+		dbOperation{id: 4, opType: "delete_documents", data: []string{"1", "missing"}},
+	)
+	idx, err = NewPersistedSimpleIndexWithDatabaseAndLoad(dbPath)
+	assert.NoError(t, err)
+	assert.Equal(t, RecoveryReport{Unclean: true, Logged: 4, Replayed: 3, Torn: true, LastApplied: 4}, idx.Recovery())
+	assert.Equal(t, uint64(4), idx.LastAppliedOp())
+	docs, err := idx.GetDocuments(context.Background(), []string{"1", "2", "3"})
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	text, _ := storedText(t, idx, "2")
+	assert.Equal(t, "dos", text)
+	_, err = os.Stat(walPath(dbPath))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, idx.AddDocument(persisted, models.Document{ID: "5"}))
+	assert.Equal(t, uint64(5), idx.LastAppliedOp())
+	assert.NoError(t, idx.Close())
+
+	// A clean shutdown needs no recovery
+	idx, err = NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.Equal(t, RecoveryReport{LastApplied: 5}, idx.Recovery())
+}
@@ -0,0 +1,332 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// RemoteIndex proxies every operation to the REST API of another bit-scout node, e.g. so a thin query
+// frontend can search a heavier index server. Searches and writes go to the remote node's default index,
+// which "index" must name for counts, exports, imports, flushes and optimizations.
+type RemoteIndex struct {
+	mu     sync.RWMutex
+	config map[string]interface{}
+	remote *remoteEndpoint
+}
+
+// remoteEndpoint holds the connection settings of a RemoteIndex
+type remoteEndpoint struct {
+	url    string // Base URL of the REST API, without trailing slash
+	index  string
+	apiKey string
+	client *http.Client
+}
+
+// NewRemoteIndex creates a RemoteIndex from its config: "url" of the remote REST API (required), "index"
+// (name of the remote default index, required), "api_key" (sent as bearer token) and "timeout" of each
+// request (default 30s)
+func NewRemoteIndex(config map[string]interface{}) (*RemoteIndex, error) {
+	idx := &RemoteIndex{}
+	if err := idx.Configure(config); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Configure sets the remote node and the credentials to reach it with
+func (idx *RemoteIndex) Configure(cfg map[string]interface{}) error {
+	base, err := config.String(cfg, "url", "")
+	if err != nil {
+		return err
+	}
+	if parsed, err := url.Parse(base); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("remote index needs the url of a bit-scout REST API, e.g. http://10.0.0.5:8081, got %q", base)
+	}
+	name, err := config.String(cfg, "index", "")
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("remote index needs the name of the remote default index")
+	}
+	apiKey, err := config.String(cfg, "api_key", "")
+	if err != nil {
+		return err
+	}
+	timeout, err := config.Duration(cfg, "timeout", 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.remote != nil {
+		idx.remote.client.CloseIdleConnections()
+	}
+	idx.config = cfg
+	idx.remote = &remoteEndpoint{
+		url:    strings.TrimSuffix(base, "/"),
+		index:  name,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+	log.Info().Msgf("RemoteIndex configured for index %s at %s", name, base)
+	return nil
+}
+
+// ShowConfig returns the current index configuration
+func (idx *RemoteIndex) ShowConfig() (map[string]interface{}, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	configCopy := make(map[string]interface{}, len(idx.config))
+	for key, value := range idx.config {
+		configCopy[key] = value
+	}
+	return configCopy, nil
+}
+
+// endpoint returns the current connection settings
+func (idx *RemoteIndex) endpoint() *remoteEndpoint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.remote
+}
+
+// AddDocument adds a document to the remote index
+func (idx *RemoteIndex) AddDocument(ctx context.Context, doc models.Document) error {
+	return idx.endpoint().post(ctx, "/documents", doc, nil)
+}
+
+// AddDocuments adds documents to the remote index in one bulk request
+func (idx *RemoteIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	items := make([]remoteBulkItem, len(docs))
+	for i := range docs {
+		items[i] = remoteBulkItem{Action: "index", Document: &docs[i]}
+	}
+	return idx.endpoint().bulk(ctx, items)
+}
+
+// Search evaluates a query on the remote index
+func (idx *RemoteIndex) Search(ctx context.Context, query string) ([]models.Document, error) {
+	var response struct {
+		Results []models.Document `json:"results"`
+	}
+	if err := idx.endpoint().call(ctx, http.MethodGet, "/search?q="+url.QueryEscape(query), nil, "", &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// DeleteDocument removes a document from the remote index
+func (idx *RemoteIndex) DeleteDocument(ctx context.Context, id string) error {
+	return idx.DeleteDocuments(ctx, []string{id})
+}
+
+// DeleteDocuments removes documents from the remote index in one bulk request
+func (idx *RemoteIndex) DeleteDocuments(ctx context.Context, ids []string) error {
+	items := make([]remoteBulkItem, len(ids))
+	for i, id := range ids {
+		items[i] = remoteBulkItem{Action: "delete", ID: id}
+	}
+	return idx.endpoint().bulk(ctx, items)
+}
+
+// UpdateDocument replaces a document of the remote index
+func (idx *RemoteIndex) UpdateDocument(ctx context.Context, id string, document models.Document) error {
+	document.ID = id
+	return idx.UpdateDocuments(ctx, []models.Document{document})
+}
+
+// UpdateDocuments replaces documents of the remote index in one bulk request
+func (idx *RemoteIndex) UpdateDocuments(ctx context.Context, docs []models.Document) error {
+	items := make([]remoteBulkItem, len(docs))
+	for i := range docs {
+		items[i] = remoteBulkItem{Action: "update", Document: &docs[i]}
+	}
+	return idx.endpoint().bulk(ctx, items)
+}
+
+// Close closes the idle connections to the remote node; the remote index stays open
+func (idx *RemoteIndex) Close() error {
+	idx.endpoint().client.CloseIdleConnections()
+	return nil
+}
+
+// Flush has the remote node write the index's pending changes to storage
+func (idx *RemoteIndex) Flush() error {
+	remote := idx.endpoint()
+	return remote.call(context.Background(), http.MethodPost, "/indexes/"+url.PathEscape(remote.index)+"/flush", nil, "", nil)
+}
+
+// Optimize has the remote node optimize the index
+func (idx *RemoteIndex) Optimize() error {
+	remote := idx.endpoint()
+	return remote.call(context.Background(), http.MethodPost, "/indexes/"+url.PathEscape(remote.index)+"/optimize", nil, "", nil)
+}
+
+// Count returns the number of documents in the remote index
+func (idx *RemoteIndex) Count() (int, error) {
+	status, err := idx.endpoint().status()
+	return status.NumDocuments, err
+}
+
+// Size returns the size of the remote index in bytes, as reported by the remote node
+func (idx *RemoteIndex) Size() (int, error) {
+	status, err := idx.endpoint().status()
+	return status.SizeBytes, err
+}
+
+// Export writes the export of the remote index
+func (idx *RemoteIndex) Export(w io.Writer) error {
+	remote := idx.endpoint()
+	resp, err := remote.send(context.Background(), http.MethodGet, "/indexes/"+url.PathEscape(remote.index)+"/export", nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import sends an export to the remote index
+func (idx *RemoteIndex) Import(r io.Reader) error {
+	remote := idx.endpoint()
+	return remote.call(context.Background(), http.MethodPost, "/indexes/"+url.PathEscape(remote.index)+"/import", r, "application/x-ndjson", nil)
+}
+
+// HealthCheck reports whether the remote node answers
+func (idx *RemoteIndex) HealthCheck() error {
+	return idx.endpoint().call(context.Background(), http.MethodGet, "/ping", nil, "", nil)
+}
+
+// remoteBulkItem is an item of a bulk request to the remote node
+type remoteBulkItem struct {
+	Action   string           `json:"action"`
+	ID       string           `json:"id,omitempty"`
+	Document *models.Document `json:"document,omitempty"`
+}
+
+// bulk applies items on the remote node, failing with the error of the first failed item
+func (e *remoteEndpoint) bulk(ctx context.Context, items []remoteBulkItem) error {
+	if len(items) == 0 {
+		return ctx.Err()
+	}
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []struct {
+			ID     string `json:"id"`
+			Status int    `json:"status"`
+			Error  string `json:"error"`
+		} `json:"items"`
+	}
+	if err := e.post(ctx, "/documents/bulk", items, &response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range response.Items {
+		if item.Error != "" {
+			if failed == 0 {
+				first = fmt.Sprintf("document %s: %s", item.ID, item.Error)
+			}
+			failed++
+		}
+	}
+	return fmt.Errorf("remote index %s: %d of %d documents failed, first %s", e.index, failed, len(items), first)
+}
+
+// remoteIndexStatus is an index of the statistics of the remote node
+type remoteIndexStatus struct {
+	Name         string
+	NumDocuments int
+	SizeBytes    int
+}
+
+// status returns the statistics of the remote index
+func (e *remoteEndpoint) status() (remoteIndexStatus, error) {
+	var stats struct {
+		Indexes []remoteIndexStatus
+	}
+	if err := e.call(context.Background(), http.MethodGet, "/stats", nil, "", &stats); err != nil {
+		return remoteIndexStatus{}, err
+	}
+	for _, status := range stats.Indexes {
+		if status.Name == e.index {
+			return status, nil
+		}
+	}
+	return remoteIndexStatus{}, fmt.Errorf("remote node at %s has no index %s", e.url, e.index)
+}
+
+// call sends a request (body of contentType, if any) and decodes the JSON response into response (nil: discarded)
+func (e *remoteEndpoint) call(ctx context.Context, method, path string, body io.Reader, contentType string, response interface{}) error {
+	resp, err := e.send(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("remote index %s: invalid response to %s %s: %w", e.index, method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, failing with the remote error for non-2xx statuses
+func (e *remoteEndpoint) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, e.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("remote index %s: %w", e.index, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var remoteErr struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &remoteErr) != nil || remoteErr.Error == "" {
+		remoteErr.Error = strings.TrimSpace(string(data))
+	}
+	return nil, fmt.Errorf("remote index %s: %s %s: %s (HTTP %d)", e.index, method, strings.SplitN(path, "?", 2)[0], remoteErr.Error, resp.StatusCode)
+}
+
+// post sends request as JSON and decodes the JSON response into response (nil: discarded)
+func (e *remoteEndpoint) post(ctx context.Context, path string, request, response interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("remote index %s: %w", e.index, err)
+	}
+	return e.call(ctx, http.MethodPost, path, bytes.NewReader(data), "application/json", response)
+}
@@ -0,0 +1,180 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// remoteNode serves the REST routes a RemoteIndex uses over a SimpleIndex named "docs", requiring a key
+func remoteNode(t *testing.T) (*httptest.Server, *SimpleIndex) {
+	store := NewSimpleIndex()
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	mux.HandleFunc("POST /documents", func(w http.ResponseWriter, r *http.Request) {
+		var doc models.Document
+		json.NewDecoder(r.Body).Decode(&doc)
+		reply(w, http.StatusCreated, map[string]string{"id": doc.ID})
+		store.AddDocument(r.Context(), doc)
+	})
+	mux.HandleFunc("POST /documents/bulk", func(w http.ResponseWriter, r *http.Request) {
+		var items []remoteBulkItem
+		json.NewDecoder(r.Body).Decode(&items)
+		type result struct {
+			ID     string `json:"id"`
+			Status int    `json:"status"`
+			Error  string `json:"error,omitempty"`
+		}
+		var results []result
+		failed := false
+		for _, item := range items {
+			var err error
+			switch item.Action {
+			case "index":
+				err = store.AddDocument(r.Context(), *item.Document)
+				item.ID = item.Document.ID
+			case "update":
+				err = store.UpdateDocument(r.Context(), item.Document.ID, *item.Document)
+				item.ID = item.Document.ID
+			case "delete":
+				err = store.DeleteDocument(r.Context(), item.ID)
+			}
+			res := result{ID: item.ID, Status: http.StatusOK}
+			if err != nil {
+				res, failed = result{ID: item.ID, Status: http.StatusNotFound, Error: err.Error()}, true
+			}
+			results = append(results, res)
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"errors": failed, "items": results})
+	})
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		docs, _ := store.Search(r.Context(), r.URL.Query().Get("q"))
+		reply(w, http.StatusOK, map[string]interface{}{"results": docs, "totalCount": len(docs)})
+	})
+	mux.HandleFunc("POST /documents/mget", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		docs, _ := store.GetDocuments(r.Context(), request.IDs)
+		reply(w, http.StatusOK, map[string]interface{}{"documents": docs})
+	})
+	mux.HandleFunc("GET /indexes/docs/documents", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit, _ := strconv.Atoi(params.Get("limit"))
+		page, _ := store.ListDocuments(r.Context(), ListOptions{After: params.Get("after"), Limit: limit, Where: params.Get("where")})
+		reply(w, http.StatusOK, map[string]interface{}{"documents": page.Documents, "total": page.Total, "next": page.Next})
+	})
+	mux.HandleFunc("POST /indexes/docs/clear", func(w http.ResponseWriter, r *http.Request) {
+		store.Clear()
+		reply(w, http.StatusOK, map[string]string{"index": "docs", "status": "cleared"})
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		count, _ := store.Count()
+		reply(w, http.StatusOK, map[string]interface{}{"Indexes": []map[string]interface{}{{"Name": "docs", "NumDocuments": count, "SizeBytes": 64}}})
+	})
+	mux.HandleFunc("GET /indexes/{name}/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "docs" {
+			reply(w, http.StatusNotFound, map[string]string{"error": "index " + r.PathValue("name") + ": not found"})
+			return
+		}
+		store.Export(w)
+	})
+	mux.HandleFunc("POST /indexes/docs/import", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		store.Import(r.Body)
+		reply(w, http.StatusOK, map[string]string{"status": "imported"})
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			reply(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid credentials"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func TestRemoteIndex(t *testing.T) {
+	server, store := remoteNode(t)
+	idx, err := NewRemoteIndex(map[string]interface{}{"url": server.URL + "/", "index": "docs", "api_key": "s3cret"})
+	assert.NoError(t, err)
+	defer idx.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "1", Text: "hello world"}))
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{{ID: "2", Text: "hello there"}, {ID: "3", Text: "goodbye"}}))
+	docs, err := idx.Search(ctx, "hello")
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	count, err := idx.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	size, err := idx.Size()
+	assert.NoError(t, err)
+	assert.Equal(t, 64, size)
+
+	got, err := idx.GetDocuments(ctx, []string{"3", "missing", "1"})
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "goodbye", got[0].Text)
+	}
+	_, err = idx.GetDocument(ctx, "missing")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	page, err := idx.ListDocuments(ctx, ListOptions{After: "1", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, DocumentPage{Documents: []models.Document{{ID: "2", Text: "hello there"}}, Total: 2, Next: "2"}, page)
+
+	assert.NoError(t, idx.UpdateDocument(ctx, "3", models.Document{Text: "hello again"}))
+	assert.Equal(t, "hello again", store.documents["3"].Text)
+	assert.NoError(t, idx.DeleteDocuments(ctx, []string{"1", "2"}))
+	assert.ErrorContains(t, idx.DeleteDocument(ctx, "1"), "1 of 1 documents failed")
+	assert.Len(t, store.documents, 1)
+
+	// Exports of the remote index import into another
+	var export bytes.Buffer
+	assert.NoError(t, idx.Export(&export))
+	assert.NoError(t, store.DeleteDocument(ctx, "3"))
+	assert.NoError(t, idx.Import(&export))
+	assert.Contains(t, store.documents, "3")
+
+	assert.NoError(t, idx.Clear())
+	assert.Empty(t, store.documents)
+}
+
+func TestRemoteIndex_Errors(t *testing.T) {
+	_, err := NewRemoteIndex(map[string]interface{}{"index": "docs"})
+	assert.ErrorContains(t, err, "needs the url")
+	_, err = NewRemoteIndex(map[string]interface{}{"url": "http://localhost:8081"})
+	assert.ErrorContains(t, err, "remote default index")
+
+	server, _ := remoteNode(t)
+	idx, err := NewRemoteIndex(map[string]interface{}{"url": server.URL, "index": "docs", "api_key": "wrong"})
+	assert.NoError(t, err)
+	_, err = idx.Search(context.Background(), "hello")
+	assert.EqualError(t, err, "remote index docs: GET /search: missing or invalid credentials (HTTP 401)")
+
+	assert.NoError(t, idx.Configure(map[string]interface{}{"url": server.URL, "index": "logs", "api_key": "s3cret"}))
+	assert.ErrorContains(t, idx.Export(&strings.Builder{}), "index logs: not found (HTTP 404)")
+	_, err = idx.Count()
+	assert.ErrorContains(t, err, "has no index logs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = idx.Search(ctx, "hello")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,36 +1,68 @@
 package index
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
 )
 
-// SimpleIndex is a basic in-memory index implementation
+// SimpleIndex is a basic in-memory index implementation.
+// It is safe for concurrent use: searches take a read lock, mutations take a write lock.
 type SimpleIndex struct {
-	documents map[string]models.Document
-	config    map[string]interface{}
+	documents     map[string]models.Document
+	lowered       map[string]loweredDocument // Lowercase searchable fields of the documents, matched by text searches
+	config        map[string]interface{}
+	acl           aclBitmaps  // Principals allowed to see the documents with AllowedPrincipals
+	catalog       metaCatalog // Documents holding each value of each metadata key
+	values        *valueIndex // Ordinals of the documents holding each value of each metadata key, for query plans
+	readOnly      bool        // Set to reject mutations, e.g. when serving a snapshot
+	settings      SimpleIndexConfig
+	allowed       map[string]bool // Dimensions conditions may use (nil: any), from settings
+	caseSensitive bool            // Conditions compare text values case-sensitively (collation case_sensitive)
+	mu            sync.RWMutex
 }
 
 // NewSimpleIndex creates a new SimpleIndex instance
 func NewSimpleIndex() *SimpleIndex {
 	return &SimpleIndex{
 		documents: make(map[string]models.Document),
+		lowered:   make(map[string]loweredDocument),
 		config:    make(map[string]interface{}),
+		acl:       newACLBitmaps(),
+		catalog:   make(metaCatalog),
+		values:    newValueIndex(),
 	}
 }
 
-// Configure sets the index configuration
+// Configure validates and sets the index configuration (see SimpleIndexConfig), warning about the keys
+// it does not know
 func (idx *SimpleIndex) Configure(config map[string]interface{}) error {
-	idx.config = config
-	log.Info().Msgf("SimpleIndex configured with %d settings", len(config))
-	return nil
+	return idx.configure(config, nil)
+}
+
+// SetReadOnly sets whether the index rejects mutations with ErrReadOnly
+func (idx *SimpleIndex) SetReadOnly(readOnly bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.readOnly = readOnly
+}
+
+// ReadOnly reports whether the index rejects mutations
+func (idx *SimpleIndex) ReadOnly() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.readOnly
 }
 
 // ShowConfig returns the current index configuration
 func (idx *SimpleIndex) ShowConfig() (map[string]interface{}, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
 	// Return a copy of the config to prevent external modification
 	configCopy := make(map[string]interface{})
 	for key, value := range idx.config {
@@ -41,16 +73,52 @@ func (idx *SimpleIndex) ShowConfig() (map[string]interface{}, error) {
 }
 
 // AddDocument adds a single document to the index
-func (idx *SimpleIndex) AddDocument(doc models.Document) error {
+func (idx *SimpleIndex) AddDocument(ctx context.Context, doc models.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	if err := idx.checkNew(ctx, []models.Document{doc}); err != nil {
+		return err
+	}
+	return idx.addDocument(doc)
+}
+
+// addDocument adds a document; the caller must hold the write lock
+func (idx *SimpleIndex) addDocument(doc models.Document) error {
+	if previous, exists := idx.documents[doc.ID]; exists {
+		idx.acl.remove(previous)
+		idx.catalog.remove(previous)
+		idx.values.remove(previous)
+	}
 	idx.documents[doc.ID] = doc
+	idx.lowered[doc.ID] = lowerDocument(doc)
+	idx.acl.add(doc)
+	idx.catalog.add(doc)
+	idx.values.add(doc)
 	log.Debug().Msgf("Added document %s to index", doc.ID)
 	return nil
 }
 
 // AddDocuments adds multiple documents to the index
-func (idx *SimpleIndex) AddDocuments(docs []models.Document) error {
+func (idx *SimpleIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	if err := idx.checkNew(ctx, docs); err != nil {
+		return err
+	}
 	for _, doc := range docs {
-		if err := idx.AddDocument(doc); err != nil {
+		if err := idx.addDocument(doc); err != nil {
 			return err
 		}
 	}
@@ -58,88 +126,176 @@ func (idx *SimpleIndex) AddDocuments(docs []models.Document) error {
 	return nil
 }
 
-// Search performs advanced query search with boolean operations and dimension filtering
-func (idx *SimpleIndex) Search(query string) ([]models.Document, error) {
-	if query == "" {
-		return []models.Document{}, nil
+// Search performs advanced query search with boolean operations and dimension filtering. Text matches
+// are ordered by their number of occurrences of the query, boolean matches by ID.
+func (idx *SimpleIndex) Search(ctx context.Context, query string) ([]models.Document, error) {
+	return idx.prepare(query).search(ctx)
+}
+
+// searchAdvanced performs search using parsed query conditions, as planned by planConditions. Queries
+// with free text are ordered like text searches, by occurrences of their lowercase text in the documents
+// matching the conditions. The caller must hold the read lock.
+func (idx *SimpleIndex) searchAdvanced(ctx context.Context, query *Query, text textQuery) ([]models.Document, error) {
+	// Plans may rule every document out without scanning one
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	steps := idx.planConditions(query, idx.indexedLimit())
+	buffer := getMatches()
+	defer putMatches(buffer)
+	matched := *buffer
 
-	// Try to parse as advanced query first
-	parsedQuery, err := ParseQuery(query)
-	if err == nil && len(parsedQuery.Conditions) > 0 {
-		// Use advanced query evaluation
-		return idx.searchAdvanced(parsedQuery)
+	visibility := idx.acl.visibility(ctx)
+	scanned := 0
+	var err error
+	idx.eachCandidate(steps, func(id string, doc models.Document) bool {
+		if err = checkCancelled(ctx, scanned); err != nil {
+			return false
+		}
+		scanned++
+		if !visibility.allows(doc.ID) {
+			return true
+		}
+		matches, evalErr := matchesSteps(doc, steps, query.CaseSensitive)
+		if evalErr != nil {
+			log.Warn().Msgf("Error evaluating query for document %s: condition evaluation failed: %s", doc.ID, evalErr)
+			return true
+		}
+		if !matches {
+			return true
+		}
+		count := 0
+		if query.Text != "" {
+			if count = text.matchesLowered(idx.lowered[id]); count == 0 {
+				return true
+			}
+		}
+		matched = append(matched, textMatch{doc: doc, count: count})
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
+	// Boolean matches are equally good: order them by ID, after their free text matches if any
+	*buffer = matched
+	results := sortMatches(matched)
 
-	// Fall back to simple text search for backward compatibility
-	return idx.searchSimple(query)
+	log.Info().Msgf("Advanced search for '%s' returned %d results", query.RawQuery, len(results))
+	return results, nil
 }
 
-// searchAdvanced performs search using parsed query conditions
-func (idx *SimpleIndex) searchAdvanced(query *Query) ([]models.Document, error) {
-	var results []models.Document
+// searchSimple performs the original simple text search of a lowercase query. Words of the query prefixed
+// with + must also occur in the documents found, words prefixed with - must not. The caller must hold the
+// read lock.
+func (idx *SimpleIndex) searchSimple(ctx context.Context, query string, text textQuery) ([]models.Document, error) {
+	buffer := getMatches()
+	defer putMatches(buffer)
+	matched := *buffer
 
-	for _, doc := range idx.documents {
-		matches, err := query.Evaluate(doc)
-		if err != nil {
-			log.Warn().Msgf("Error evaluating query for document %s: %s", doc.ID, err)
+	visibility := idx.acl.visibility(ctx)
+	scanned := 0
+	for id, doc := range idx.documents {
+		if err := checkCancelled(ctx, scanned); err != nil {
+			return nil, err
+		}
+		scanned++
+		if !visibility.allows(doc.ID) {
 			continue
 		}
-
-		if matches {
-			results = append(results, doc)
+		if count := text.matchesLowered(idx.lowered[id]); count > 0 {
+			matched = append(matched, textMatch{doc: doc, count: count})
 		}
 	}
+	// Most occurrences of the query first, then by ID
+	*buffer = matched
+	results := sortMatches(matched)
 
-	log.Info().Msgf("Advanced search for '%s' returned %d results", query.RawQuery, len(results))
+	log.Info().Msgf("Simple search for '%s' returned %d results", query, len(results))
 	return results, nil
 }
 
-// searchSimple performs the original simple text search
-func (idx *SimpleIndex) searchSimple(query string) ([]models.Document, error) {
-	query = strings.ToLower(query)
-	var results []models.Document
+// textMatch is a document found by a search and its number of occurrences of the query
+type textMatch struct {
+	doc   models.Document
+	count int
+}
+
+// matchPool recycles the buffers searches collect their matches in, which grow to the size of the
+// largest result sets
+var matchPool = sync.Pool{New: func() interface{} { return new([]textMatch) }}
 
-	for _, doc := range idx.documents {
-		// Search in document text
-		if strings.Contains(strings.ToLower(doc.Text), query) {
-			results = append(results, doc)
-			continue
-		}
+func getMatches() *[]textMatch {
+	buffer := matchPool.Get().(*[]textMatch)
+	*buffer = (*buffer)[:0]
+	return buffer
+}
 
-		// Search in metadata
-		for key, value := range doc.Meta {
-			if strings.Contains(strings.ToLower(key), query) ||
-				strings.Contains(strings.ToLower(value), query) {
-				results = append(results, doc)
-				break
-			}
-		}
+// putMatches returns a buffer to the pool, dropping its documents so they can be collected
+func putMatches(buffer *[]textMatch) {
+	clear(*buffer)
+	matchPool.Put(buffer)
+}
 
-		// Search in source path
-		if strings.Contains(strings.ToLower(doc.Source), query) {
-			results = append(results, doc)
+// sortMatches orders matches by occurrences of the query, most first, then by ID, and returns their
+// documents
+func sortMatches(matched []textMatch) []models.Document {
+	slices.SortFunc(matched, func(a, b textMatch) int {
+		if a.count != b.count {
+			return b.count - a.count
 		}
+		return strings.Compare(a.doc.ID, b.doc.ID)
+	})
+	results := make([]models.Document, len(matched))
+	for i, match := range matched {
+		results[i] = match.doc
 	}
-
-	log.Info().Msgf("Simple search for '%s' returned %d results", query, len(results))
-	return results, nil
+	return results
 }
 
 // DeleteDocument removes a document from the index
-func (idx *SimpleIndex) DeleteDocument(id string) error {
-	if _, exists := idx.documents[id]; !exists {
-		return fmt.Errorf("document %s not found in index", id)
+func (idx *SimpleIndex) DeleteDocument(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	return idx.deleteDocument(id)
+}
+
+// deleteDocument removes a document; the caller must hold the write lock
+func (idx *SimpleIndex) deleteDocument(id string) error {
+	previous, exists := idx.documents[id]
+	if !exists {
+		return fmt.Errorf("document %s %w", id, ErrDocumentNotFound)
 	}
+	idx.acl.remove(previous)
+	idx.catalog.remove(previous)
+	idx.values.remove(previous)
 	delete(idx.documents, id)
+	delete(idx.lowered, id)
 	log.Debug().Msgf("Deleted document %s from index", id)
 	return nil
 }
 
 // DeleteDocuments removes multiple documents from the index
-func (idx *SimpleIndex) DeleteDocuments(ids []string) error {
+func (idx *SimpleIndex) DeleteDocuments(ctx context.Context, ids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	// Fail before deleting any, so the persisted index's database stays in step
+	if err := idx.checkExisting(ids); err != nil {
+		return err
+	}
 	for _, id := range ids {
-		if err := idx.DeleteDocument(id); err != nil {
+		if err := idx.deleteDocument(id); err != nil {
 			return err
 		}
 	}
@@ -148,19 +304,56 @@ func (idx *SimpleIndex) DeleteDocuments(ids []string) error {
 }
 
 // UpdateDocument updates an existing document in the index
-func (idx *SimpleIndex) UpdateDocument(id string, doc models.Document) error {
-	if _, exists := idx.documents[id]; !exists {
-		return fmt.Errorf("document %s not found in index", id)
+func (idx *SimpleIndex) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
 	}
+	return idx.updateDocument(id, doc)
+}
+
+// updateDocument replaces a document; the caller must hold the write lock
+func (idx *SimpleIndex) updateDocument(id string, doc models.Document) error {
+	previous, exists := idx.documents[id]
+	if !exists {
+		return fmt.Errorf("document %s %w", id, ErrDocumentNotFound)
+	}
+	idx.acl.remove(previous)
+	idx.catalog.remove(previous)
+	idx.values.remove(previous)
 	idx.documents[id] = doc
+	idx.lowered[id] = lowerDocument(doc)
+	idx.acl.add(doc)
+	idx.catalog.add(doc)
+	idx.values.add(doc)
 	log.Debug().Msgf("Updated document %s in index", id)
 	return nil
 }
 
 // UpdateDocuments updates multiple documents in the index
-func (idx *SimpleIndex) UpdateDocuments(docs []models.Document) error {
+func (idx *SimpleIndex) UpdateDocuments(ctx context.Context, docs []models.Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return ErrReadOnly
+	}
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+	}
+	// Fail before updating any, so the persisted index's database stays in step
+	if err := idx.checkExisting(ids); err != nil {
+		return err
+	}
 	for _, doc := range docs {
-		if err := idx.UpdateDocument(doc.ID, doc); err != nil {
+		if err := idx.updateDocument(doc.ID, doc); err != nil {
 			return err
 		}
 	}
@@ -180,29 +373,20 @@ func (idx *SimpleIndex) Flush() error {
 	return nil
 }
 
-// Optimize optimizes the index for faster search (not implemented for simple index)
-func (idx *SimpleIndex) Optimize() error {
-	log.Info().Msg("SimpleIndex optimize called (no-op for in-memory index)")
-	return nil
-}
-
 // Count returns the number of documents in the index
 func (idx *SimpleIndex) Count() (int, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
 	return len(idx.documents), nil
 }
 
 // Size returns the approximate size of the index in bytes
 func (idx *SimpleIndex) Size() (int, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
 	size := 0
 	for _, doc := range idx.documents {
-		size += len(doc.ID)
-		size += len(doc.Text)
-		size += len(doc.Source)
-		for key, value := range doc.Meta {
-			size += len(key)
-			size += len(value)
-		}
-		size += len(doc.Vector) * 8 // 8 bytes per float64
+		size += documentBytes(doc)
 	}
 	return size, nil
 }
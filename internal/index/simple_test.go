@@ -1,9 +1,12 @@
 package index
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,7 +23,7 @@ func makeTestDoc(id, text, source string, meta map[string]string, vector []float
 func TestSimpleIndex_AddAndGetDocument(t *testing.T) {
 	idx := NewSimpleIndex()
 	doc := makeTestDoc("1", "hello world", "file1.txt", map[string]string{"author": "alice"}, []float64{1.0, 2.0})
-	err := idx.AddDocument(doc)
+	err := idx.AddDocument(context.Background(), doc)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(idx.documents))
 	assert.Equal(t, doc, idx.documents[doc.ID])
@@ -32,7 +35,7 @@ func TestSimpleIndex_AddDocuments(t *testing.T) {
 		makeTestDoc("1", "foo", "a.txt", nil, nil),
 		makeTestDoc("2", "bar", "b.txt", nil, nil),
 	}
-	err := idx.AddDocuments(docs)
+	err := idx.AddDocuments(context.Background(), docs)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(idx.documents))
 }
@@ -40,12 +43,12 @@ func TestSimpleIndex_AddDocuments(t *testing.T) {
 func TestSimpleIndex_DeleteDocument(t *testing.T) {
 	idx := NewSimpleIndex()
 	doc := makeTestDoc("1", "text", "src", nil, nil)
-	_ = idx.AddDocument(doc)
-	err := idx.DeleteDocument("1")
+	_ = idx.AddDocument(context.Background(), doc)
+	err := idx.DeleteDocument(context.Background(), "1")
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(idx.documents))
 	// Try deleting non-existent
-	err = idx.DeleteDocument("notfound")
+	err = idx.DeleteDocument(context.Background(), "notfound")
 	assert.Error(t, err)
 }
 
@@ -55,8 +58,8 @@ func TestSimpleIndex_DeleteDocuments(t *testing.T) {
 		makeTestDoc("1", "foo", "a.txt", nil, nil),
 		makeTestDoc("2", "bar", "b.txt", nil, nil),
 	}
-	_ = idx.AddDocuments(docs)
-	err := idx.DeleteDocuments([]string{"1", "2"})
+	_ = idx.AddDocuments(context.Background(), docs)
+	err := idx.DeleteDocuments(context.Background(), []string{"1", "2"})
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(idx.documents))
 }
@@ -64,19 +67,19 @@ func TestSimpleIndex_DeleteDocuments(t *testing.T) {
 func TestSimpleIndex_UpdateDocument(t *testing.T) {
 	idx := NewSimpleIndex()
 	doc := makeTestDoc("1", "old", "src", nil, nil)
-	_ = idx.AddDocument(doc)
+	_ = idx.AddDocument(context.Background(), doc)
 	updated := makeTestDoc("1", "new", "src", nil, nil)
-	err := idx.UpdateDocument("1", updated)
+	err := idx.UpdateDocument(context.Background(), "1", updated)
 	assert.NoError(t, err)
 	assert.Equal(t, "new", idx.documents["1"].Text)
 	// Update non-existent
-	err = idx.UpdateDocument("notfound", updated)
+	err = idx.UpdateDocument(context.Background(), "notfound", updated)
 	assert.Error(t, err)
 }
 
 func TestSimpleIndex_UpdateDocuments(t *testing.T) {
 	idx := NewSimpleIndex()
-	_ = idx.AddDocuments([]models.Document{
+	_ = idx.AddDocuments(context.Background(), []models.Document{
 		makeTestDoc("1", "a", "a.txt", nil, nil),
 		makeTestDoc("2", "b", "b.txt", nil, nil),
 	})
@@ -84,7 +87,7 @@ func TestSimpleIndex_UpdateDocuments(t *testing.T) {
 		makeTestDoc("1", "A", "a.txt", nil, nil),
 		makeTestDoc("2", "B", "b.txt", nil, nil),
 	}
-	err := idx.UpdateDocuments(updates)
+	err := idx.UpdateDocuments(context.Background(), updates)
 	assert.NoError(t, err)
 	assert.Equal(t, "A", idx.documents["1"].Text)
 	assert.Equal(t, "B", idx.documents["2"].Text)
@@ -104,10 +107,74 @@ func TestSimpleIndex_ConfigureAndShowConfig(t *testing.T) {
 	assert.Equal(t, 1, conf2["foo"])
 }
 
+func TestSimpleIndex_Collation(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocument(ctx, makeTestDoc("1", "", "Main.go", map[string]string{"filename": "Main.go"}, nil)))
+
+	results, err := idx.Search(ctx, "filename=main.go")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	assert.NoError(t, idx.Configure(map[string]interface{}{"collation": CollationCaseSensitive}))
+	results, err = idx.Search(ctx, "filename=main.go")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	results, err = idx.Search(ctx, "filename=Main.go")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	assert.Error(t, idx.Configure(map[string]interface{}{"collation": "binary"}))
+}
+
+func TestSimpleIndex_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocument(ctx, makeTestDoc("1", "foo", "a.txt", nil, nil)))
+	idx.SetReadOnly(true)
+	assert.True(t, idx.ReadOnly())
+
+	assert.ErrorIs(t, idx.AddDocument(ctx, makeTestDoc("2", "bar", "b.txt", nil, nil)), ErrReadOnly)
+	assert.ErrorIs(t, idx.AddDocuments(ctx, []models.Document{makeTestDoc("2", "bar", "b.txt", nil, nil)}), ErrReadOnly)
+	assert.ErrorIs(t, idx.UpdateDocument(ctx, "1", makeTestDoc("1", "baz", "a.txt", nil, nil)), ErrReadOnly)
+	assert.ErrorIs(t, idx.UpdateDocuments(ctx, []models.Document{makeTestDoc("1", "baz", "a.txt", nil, nil)}), ErrReadOnly)
+	assert.ErrorIs(t, idx.DeleteDocument(ctx, "1"), ErrReadOnly)
+	assert.ErrorIs(t, idx.DeleteDocuments(ctx, []string{"1"}), ErrReadOnly)
+
+	results, err := idx.Search(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	idx.SetReadOnly(false)
+	assert.NoError(t, idx.DeleteDocument(ctx, "1"))
+}
+
+func TestSimpleIndex_Cancellation(t *testing.T) {
+	idx := NewSimpleIndex()
+	docs := make([]models.Document, 2*cancelCheckInterval)
+	for i := range docs {
+		docs[i] = makeTestDoc(fmt.Sprint(i), "hello world", "src", nil, nil)
+	}
+	assert.NoError(t, idx.AddDocuments(context.Background(), docs))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := idx.Search(ctx, "hello")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = idx.Search(ctx, "Text=hello")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Cancelled writes leave the index unchanged
+	assert.ErrorIs(t, idx.AddDocument(ctx, makeTestDoc("new", "hello", "src", nil, nil)), context.Canceled)
+	assert.ErrorIs(t, idx.DeleteDocument(ctx, "0"), context.Canceled)
+	count, _ := idx.Count()
+	assert.Equal(t, len(docs), count)
+}
+
 func TestSimpleIndex_CountAndSize(t *testing.T) {
 	idx := NewSimpleIndex()
 	doc := makeTestDoc("1", "abc", "src", map[string]string{"k": "v"}, []float64{1.1, 2.2})
-	_ = idx.AddDocument(doc)
+	_ = idx.AddDocument(context.Background(), doc)
 	count, err := idx.Count()
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
@@ -129,14 +196,84 @@ func TestSimpleIndex_SearchSimple(t *testing.T) {
 		makeTestDoc("1", "hello world", "src1", map[string]string{"author": "alice"}, nil),
 		makeTestDoc("2", "foo bar", "src2", map[string]string{"author": "bob"}, nil),
 	}
-	_ = idx.AddDocuments(docs)
-	results, err := idx.Search("hello")
+	_ = idx.AddDocuments(context.Background(), docs)
+	results, err := idx.Search(context.Background(), "hello")
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, "1", results[0].ID)
-	results, _ = idx.Search("bob")
+	results, _ = idx.Search(context.Background(), "bob")
 	assert.Len(t, results, 1)
 	assert.Equal(t, "2", results[0].ID)
-	results, _ = idx.Search("")
+	results, _ = idx.Search(context.Background(), "")
 	assert.Len(t, results, 0)
 }
+
+func TestSimpleIndex_SearchOrder(t *testing.T) {
+	idx := NewSimpleIndex()
+	docs := []models.Document{
+		makeTestDoc("c", "report", "src", map[string]string{"type": "txt"}, nil),
+		makeTestDoc("a", "report", "src", map[string]string{"type": "txt"}, nil),
+		makeTestDoc("b", "report on the report", "src", map[string]string{"type": "txt"}, nil),
+		makeTestDoc("d", "report", "report.txt", map[string]string{"type": "txt"}, nil),
+	}
+	_ = idx.AddDocuments(context.Background(), docs)
+	ids := func(results []models.Document) []string {
+		var out []string
+		for _, doc := range results {
+			out = append(out, doc.ID)
+		}
+		return out
+	}
+
+	// Most occurrences first, ties by ID, on every search
+	for i := 0; i < 5; i++ {
+		results, err := idx.Search(context.Background(), "report")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "d", "a", "c"}, ids(results))
+	}
+	results, err := idx.Search(context.Background(), "type=txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids(results))
+}
+
+// benchmarkIndex holds n documents of mixed-case text and metadata, a tenth of them mentioning "Quarterly"
+func benchmarkIndex(b *testing.B, n int) *SimpleIndex {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	b.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+	idx := NewSimpleIndex()
+	docs := make([]models.Document, n)
+	for i := range docs {
+		text := fmt.Sprintf("Meeting Notes %d: Budget Review and Hiring Plan for the Platform Team", i)
+		if i%10 == 0 {
+			text += " with the Quarterly Report"
+		}
+		docs[i] = makeTestDoc(fmt.Sprint(i), text, fmt.Sprintf("/Docs/Notes-%d.TXT", i), map[string]string{"Author": "Alice", "fileExtension": "txt"}, nil)
+	}
+	if err := idx.AddDocuments(context.Background(), docs); err != nil {
+		b.Fatal(err)
+	}
+	return idx
+}
+
+func BenchmarkSimpleIndex_SearchText(b *testing.B) {
+	idx := benchmarkIndex(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := idx.Search(context.Background(), "quarterly report"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSimpleIndex_SearchConditionsAndText(b *testing.B) {
+	idx := benchmarkIndex(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := idx.Search(context.Background(), "quarterly fileExtension=txt"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
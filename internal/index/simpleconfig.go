@@ -0,0 +1,138 @@
+package index
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// SimpleIndexConfig holds the settings of an index config that SimpleIndex searches apply
+type SimpleIndexConfig struct {
+	CaseSensitive bool     // Conditions compare text values case-sensitively (collation case_sensitive)
+	MaxResults    int      // Most results a search returns, once filtered and sorted by the engine (0: all)
+	Dimensions    []string // Metadata keys conditions may use, besides filename, path and text (none: any)
+}
+
+// Config keys of the index types, for the warnings about unknown keys. Every type also has the keys of
+// simpleConfigKeys, which its SimpleIndex reads.
+var (
+	simpleConfigKeys    = []string{"collation", "dimensions", "max_results", "read_only", "spill_dir"}
+	persistedConfigKeys = []string{"db_path", "durability", "load", "no_sync", "wal", "write_retries", "write_retry_backoff"}
+	invertedConfigKeys  = []string{
+		"analyzer", "boosts", "case_fold", "cjk_bigrams", "field_store_path", "fields", "fold_accents", "language_field",
+		"languages", "memory_budget_mb", "min_token_length", "ngram", "normalization", "protected_terms",
+		"score_script", "stopword_files", "stopwords", "text_weight",
+	}
+	vectorConfigKeys = []string{"k", "metric", "vector_size"}
+)
+
+// ParseSimpleIndexConfig reads and validates the settings of an index config
+func ParseSimpleIndexConfig(cfg map[string]interface{}) (SimpleIndexConfig, error) {
+	var parsed SimpleIndexConfig
+	var err error
+	if parsed.CaseSensitive, err = parseCollation(cfg); err != nil {
+		return SimpleIndexConfig{}, err
+	}
+	if parsed.MaxResults, err = config.Int(cfg, "max_results", 0); err != nil {
+		return SimpleIndexConfig{}, err
+	}
+	if parsed.MaxResults < 0 {
+		return SimpleIndexConfig{}, fmt.Errorf("max_results must not be negative, got %d", parsed.MaxResults)
+	}
+	if parsed.Dimensions, err = config.Strings(cfg, "dimensions"); err != nil {
+		return SimpleIndexConfig{}, err
+	}
+	for _, dimension := range parsed.Dimensions {
+		if strings.TrimSpace(dimension) == "" {
+			return SimpleIndexConfig{}, fmt.Errorf("dimensions must not be empty")
+		}
+	}
+	return parsed, nil
+}
+
+// unknownConfigKeys returns the keys of cfg that are neither simpleConfigKeys nor known, sorted
+func unknownConfigKeys(cfg map[string]interface{}, known []string) []string {
+	var unknown []string
+	for key := range cfg {
+		if !slices.Contains(simpleConfigKeys, key) && !slices.Contains(known, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// configure validates and applies the config of an index whose type reads the known keys besides those
+// of SimpleIndex, warning about the keys no one reads
+func (idx *SimpleIndex) configure(cfg map[string]interface{}, known []string) error {
+	parsed, err := ParseSimpleIndexConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if unknown := unknownConfigKeys(cfg, known); len(unknown) > 0 {
+		keys := append(append([]string{}, simpleConfigKeys...), known...)
+		sort.Strings(keys)
+		log.Warn().Msgf("Ignoring unknown index config keys %s (known keys: %s)", strings.Join(unknown, ", "), strings.Join(keys, ", "))
+	}
+	var allowed map[string]bool
+	if len(parsed.Dimensions) > 0 {
+		allowed = make(map[string]bool, len(parsed.Dimensions))
+		for _, dimension := range parsed.Dimensions {
+			allowed[dimension] = true
+		}
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.config = cfg
+	idx.settings = parsed
+	idx.allowed = allowed
+	idx.caseSensitive = parsed.CaseSensitive
+	log.Info().Msgf("SimpleIndex configured with %d settings", len(cfg))
+	return nil
+}
+
+// Settings returns the settings of the index config that searches apply
+func (idx *SimpleIndex) Settings() SimpleIndexConfig {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.settings
+}
+
+// checkDimensions fails with the conditions of a query on dimensions the config does not list. The
+// caller must hold the read lock.
+func (idx *SimpleIndex) checkDimensions(query *Query) error {
+	if idx.allowed == nil {
+		return nil
+	}
+	var errs []QueryError
+	for _, condition := range query.Conditions {
+		if !idx.allowed[condition.Dimension] && !isBuiltinDimension(condition.Dimension) {
+			offset := max(strings.Index(query.RawQuery, condition.Dimension), 0)
+			errs = append(errs, dimensionNotAllowed(condition.Dimension, offset, idx.allowed))
+		}
+	}
+	if len(errs) > 0 {
+		return &InvalidQueryError{Query: query.RawQuery, Errors: runeOffsets(query.RawQuery, errs)}
+	}
+	return nil
+}
+
+// dimensionNotAllowed is the problem of a condition on a dimension the index config does not list
+func dimensionNotAllowed(dimension string, offset int, allowed map[string]bool) QueryError {
+	dimensions := append([]string{}, builtinDimensions...)
+	for key := range allowed {
+		dimensions = append(dimensions, key)
+	}
+	sort.Strings(dimensions)
+	return QueryError{Offset: offset, Length: len(dimension), Code: QueryErrorDimensionNotAllowed,
+		Message: fmt.Sprintf("dimension %q is not searchable in this index (dimensions: %s)", dimension, strings.Join(dimensions, ", "))}
+}
+
+// MaxResults returns the max_results of the index config (0: none)
+func (idx *SimpleIndex) MaxResults() int {
+	return idx.Settings().MaxResults
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSimpleIndexConfig(t *testing.T) {
+	parsed, err := ParseSimpleIndexConfig(map[string]interface{}{
+		"collation":   CollationCaseSensitive,
+		"max_results": 10.0,
+		"dimensions":  []interface{}{"fileSize", "fileExtension"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, SimpleIndexConfig{CaseSensitive: true, MaxResults: 10, Dimensions: []string{"fileSize", "fileExtension"}}, parsed)
+
+	parsed, err = ParseSimpleIndexConfig(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, SimpleIndexConfig{}, parsed)
+
+	tests := []struct {
+		cfg     map[string]interface{}
+		message string
+	}{
+		{map[string]interface{}{"max_results": -1}, "max_results must not be negative"},
+		{map[string]interface{}{"max_results": 2.5}, "must be a whole number"},
+		{map[string]interface{}{"dimensions": []interface{}{"fileSize", 3}}, "config key dimensions[1] must be a string"},
+		{map[string]interface{}{"dimensions": []interface{}{" "}}, "dimensions must not be empty"},
+		{map[string]interface{}{"collation": "binary"}, "unknown collation"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSimpleIndexConfig(tt.cfg)
+		assert.ErrorContains(t, err, tt.message, tt.cfg)
+		assert.Error(t, NewSimpleIndex().Configure(tt.cfg), tt.cfg)
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	cfg := map[string]interface{}{"max_results": 10, "db_path": "./index.db", "max_result": 5, "analyser": "english"}
+	assert.Equal(t, []string{"analyser", "db_path", "max_result"}, unknownConfigKeys(cfg, nil))
+	assert.Equal(t, []string{"analyser", "max_result"}, unknownConfigKeys(cfg, persistedConfigKeys))
+}
+
+func TestSimpleIndex_MaxResults(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, idx.AddDocument(ctx, makeTestDoc(fmt.Sprint(i), "hello", "", map[string]string{"fileExtension": "go"}, nil)))
+	}
+	assert.NoError(t, idx.Configure(map[string]interface{}{"max_results": 3}))
+	assert.Equal(t, 3, idx.MaxResults())
+
+	// Searches return every match: the engine caps them once they are filtered and sorted
+	for _, query := range []string{"hello", "fileExtension=go", "hello fileExtension=go"} {
+		results, err := idx.Search(ctx, query)
+		assert.NoError(t, err)
+		assert.Len(t, results, 5, query)
+	}
+
+	inverted := NewInvertedIndex(nil)
+	assert.NoError(t, inverted.Configure(map[string]interface{}{"max_results": 2}))
+	vector, err := NewVectorIndex("cosine", 3, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, vector.Configure(map[string]interface{}{"max_results": 4}))
+	for want, capper := range map[int]ResultCapper{2: inverted, 4: vector} {
+		assert.Equal(t, want, capper.MaxResults())
+	}
+
+	assert.NoError(t, idx.Configure(map[string]interface{}{}))
+	assert.Equal(t, 0, idx.MaxResults())
+}
+
+func TestSimpleIndex_Dimensions(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocument(ctx, makeTestDoc("1", "hello", "a.go", map[string]string{"fileExtension": "go", "author": "alice"}, nil)))
+	prepared, err := idx.PrepareQuery("author=alice")
+	assert.NoError(t, err)
+	assert.NoError(t, idx.Configure(map[string]interface{}{"dimensions": []string{"fileExtension", "fileSize"}}))
+
+	results, err := idx.Search(ctx, "fileExtension=go and text contains hell")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	results, err = idx.Search(ctx, "hello")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1, "free text is not restricted")
+
+	// Queries prepared before the dimensions were configured are checked when they run
+	for _, run := range []func() error{
+		func() error { _, err := idx.Search(ctx, "hello author=alice"); return err },
+		func() error { _, err := prepared.Execute(ctx, 0, 0); return err },
+	} {
+		var invalid *InvalidQueryError
+		assert.True(t, errors.As(run(), &invalid))
+		assert.Equal(t, QueryErrorDimensionNotAllowed, invalid.Errors[0].Code)
+	}
+
+	_, err = idx.PrepareQuery("fileExtension=go and author=alice")
+	assert.EqualError(t, err, `invalid query "fileExtension=go and author=alice": dimension "author" is not searchable in this index (dimensions: fileExtension, fileSize, filename, path, text)`)
+	assert.Equal(t, []QueryError{{Offset: 21, Length: 6, Code: QueryErrorDimensionNotAllowed,
+		Message: `dimension "author" is not searchable in this index (dimensions: fileExtension, fileSize, filename, path, text)`}},
+		idx.ValidateQuery("fileExtension=go and author=alice"))
+}
@@ -0,0 +1,221 @@
+package index
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+/**
+ * Spilling large result sets: the IDs of the documents of a result set too large to hold are written to
+ * a temporary file in sorted runs as they are collected, and the documents are read back from the index a
+ * batch of merged IDs at a time, so only one run of IDs or batch of documents is in memory.
+ **/
+
+// spillThreshold is the number of IDs above which result sets are spilled to a temporary file
+var spillThreshold = 100000
+
+// idSource is the ordered IDs of a result set, read a batch at a time
+type idSource interface {
+	// Calls fn with the IDs in batches of up to size, in order, until it fails
+	batches(size int, fn func([]string) error) error
+	Close() error
+}
+
+// idList is a result set held in memory
+type idList []string
+
+func (l idList) batches(size int, fn func([]string) error) error {
+	for start := 0; start < len(l); start += size {
+		if err := fn(l[start:min(start+size, len(l))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l idList) Close() error {
+	return nil
+}
+
+// idSorter sorts the IDs of a result set as they are added. Once more than spillThreshold are held, they
+// are sorted and written to a temporary file as a run, so at most spillThreshold IDs are in memory; the
+// runs are merged when read.
+type idSorter struct {
+	dir    string   // Directory of the spill file ("": the system's)
+	held   []string // IDs not written yet
+	count  int
+	file   *os.File // Spill file, once a run is written
+	writer *bufio.Writer
+	runs   []idRun
+	offset int64 // End of the last run in the file
+}
+
+// idRun is a sorted run of IDs in a spill file, each ID as its length (uvarint) and bytes
+type idRun struct {
+	offset, size int64
+	count        int
+}
+
+func newIDSorter(dir string) *idSorter {
+	return &idSorter{dir: dir}
+}
+
+// add adds an ID, spilling the IDs held once there are more than spillThreshold
+func (s *idSorter) add(id string) error {
+	s.held = append(s.held, id)
+	s.count++
+	if len(s.held) > spillThreshold {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the IDs held to the spill file as a sorted run
+func (s *idSorter) spill() error {
+	if s.file == nil {
+		file, err := os.CreateTemp(s.dir, "bitscout-spill-*")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %w", err)
+		}
+		s.file, s.writer = file, bufio.NewWriter(file)
+	}
+	sort.Strings(s.held)
+	run := idRun{offset: s.offset, count: len(s.held)}
+	var length [binary.MaxVarintLen64]byte
+	for _, id := range s.held {
+		n := binary.PutUvarint(length[:], uint64(len(id)))
+		if _, err := s.writer.Write(length[:n]); err != nil {
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+		if _, err := s.writer.WriteString(id); err != nil {
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+		run.size += int64(n + len(id))
+	}
+	s.runs = append(s.runs, run)
+	s.offset += run.size
+	s.held = s.held[:0]
+	return nil
+}
+
+// sorted returns the IDs added, in order. The sorter must not be used afterwards; on error, it must be
+// closed.
+func (s *idSorter) sorted() (idSource, error) {
+	if s.file == nil {
+		sort.Strings(s.held)
+		return idList(s.held), nil
+	}
+	if len(s.held) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write spill file: %w", err)
+	}
+	spill := &idSpill{file: s.file, runs: s.runs, count: s.count}
+	s.file, s.held = nil, nil
+	return spill, nil
+}
+
+// Close removes the spill file of a sorter whose IDs were not returned by sorted
+func (s *idSorter) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// idSpill is a result set written to a temporary file as sorted runs
+type idSpill struct {
+	file  *os.File
+	runs  []idRun
+	count int
+}
+
+func (s *idSpill) batches(size int, fn func([]string) error) error {
+	// Merge the runs: the smallest next ID of any run is the next one
+	heads := make(runHeads, 0, len(s.runs))
+	for _, run := range s.runs {
+		head := &runHead{reader: bufio.NewReader(io.NewSectionReader(s.file, run.offset, run.size)), left: run.count}
+		if ok, err := head.next(); err != nil {
+			return err
+		} else if ok {
+			heads = append(heads, head)
+		}
+	}
+	heap.Init(&heads)
+	batch := make([]string, 0, size)
+	for len(heads) > 0 {
+		head := heads[0]
+		if batch = append(batch, head.id); len(batch) == size {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		ok, err := head.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&heads, 0)
+		} else {
+			heap.Pop(&heads)
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// Close removes the spill file
+func (s *idSpill) Close() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// runHead is the next ID of a run being merged
+type runHead struct {
+	reader *bufio.Reader
+	left   int // IDs of the run not read yet
+	id     string
+}
+
+// next reads the next ID of the run, if any
+func (h *runHead) next() (bool, error) {
+	if h.left == 0 {
+		return false, nil
+	}
+	length, err := binary.ReadUvarint(h.reader)
+	if err != nil {
+		return false, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	id := make([]byte, length)
+	if _, err := io.ReadFull(h.reader, id); err != nil {
+		return false, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	h.id, h.left = string(id), h.left-1
+	return true, nil
+}
+
+// runHeads is a min-heap of the runs being merged, by their next ID
+type runHeads []*runHead
+
+func (h runHeads) Len() int           { return len(h) }
+func (h runHeads) Less(i, j int) bool { return h[i].id < h[j].id }
+func (h runHeads) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeads) Push(x any)        { *h = append(*h, x.(*runHead)) }
+func (h *runHeads) Pop() any {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// textQuery is a free-text query split into the text searched for, the +term terms documents must also
+// contain and the -term terms they must not, e.g. "database -mysql"
+type textQuery struct {
+	text     string   // The query without its +term and -term terms
+	required []string // Terms documents must contain
+	excluded []string // Terms documents must not contain
+}
+
+// parseTextQuery splits the +term and -term terms out of a free-text query. A lone + or - and words like
+// "well-known" are text; queries without such terms are kept as they are.
+func parseTextQuery(query string) textQuery {
+	var q textQuery
+	var text []string
+	for _, word := range strings.Fields(query) {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			q.required = append(q.required, word[1:])
+		case len(word) > 1 && word[0] == '-':
+			q.excluded = append(q.excluded, word[1:])
+		default:
+			text = append(text, word)
+		}
+	}
+	if len(q.required) == 0 && len(q.excluded) == 0 {
+		q.text = query
+		return q
+	}
+	q.text = strings.Join(text, " ")
+	return q
+}
+
+// matches counts the occurrences of the text and required terms of a lowercase query in a document's text,
+// metadata and source: 0 if one of them does not occur or an excluded term does. Queries of excluded
+// terms only match nothing.
+func (q textQuery) matches(doc models.Document) int {
+	return q.matchesLowered(lowerDocument(doc))
+}
+
+// matchesLowered counts the occurrences of a lowercase query in the lowercase fields of a document, as
+// matches does
+func (q textQuery) matchesLowered(fields loweredDocument) int {
+	count := 0
+	if q.text != "" {
+		if count = fields.count(q.text); count == 0 {
+			return 0
+		}
+	}
+	for _, term := range q.required {
+		n := fields.count(term)
+		if n == 0 {
+			return 0
+		}
+		count += n
+	}
+	for _, term := range q.excluded {
+		if fields.contains(term) {
+			return 0
+		}
+	}
+	return count
+}
+
+// loweredDocument holds the lowercase text, source, and metadata keys and values of a document. Simple
+// indexes keep one per document, so searches do not lowercase every document on every query.
+type loweredDocument []string
+
+// lowerDocument lowercases the searchable fields of a document. strings.ToLower returns fields that are
+// lowercase already as they are, so only mixed-case documents take more memory.
+func lowerDocument(doc models.Document) loweredDocument {
+	fields := make(loweredDocument, 0, 2+2*len(doc.Meta))
+	fields = append(fields, strings.ToLower(doc.Text), strings.ToLower(doc.Source))
+	for key, value := range doc.Meta {
+		fields = append(fields, strings.ToLower(key), strings.ToLower(value))
+	}
+	return fields
+}
+
+// count counts the occurrences of a lowercase query in the fields
+func (l loweredDocument) count(query string) int {
+	count := 0
+	for _, field := range l {
+		count += strings.Count(field, query)
+	}
+	return count
+}
+
+// contains reports whether a lowercase query occurs in a field
+func (l loweredDocument) contains(query string) bool {
+	for _, field := range l {
+		if strings.Contains(field, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// terms analyzes the text and required terms of a query: the terms documents must contain
+func (q textQuery) terms(analyzer *Analyzer) []string {
+	terms := analyzer.Analyze(q.text)
+	for _, term := range q.required {
+		terms = append(terms, analyzer.Analyze(term)...)
+	}
+	return terms
+}
@@ -0,0 +1,101 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTextQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  textQuery
+	}{
+		{"plain text is kept", " hello  world ", textQuery{text: " hello  world "}},
+		{"excluded term", "database -mysql", textQuery{text: "database", excluded: []string{"mysql"}}},
+		{"required term", "+go  search", textQuery{text: "search", required: []string{"go"}}},
+		{"lone signs and hyphens are text", "well-known - + c++", textQuery{text: "well-known - + c++"}},
+		{"modifiers only", "+go -rust", textQuery{required: []string{"go"}, excluded: []string{"rust"}}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseTextQuery(tt.query), tt.name)
+	}
+}
+
+func textQueryDocs() []models.Document {
+	return []models.Document{
+		makeTestDoc("1", "database on mysql", "a.txt", nil, nil),
+		makeTestDoc("2", "database on postgres, a database", "b.txt", nil, nil),
+		makeTestDoc("3", "postgres tuning", "c.txt", nil, nil),
+	}
+}
+
+func TestSimpleIndex_SearchTextModifiers(t *testing.T) {
+	ctx := context.Background()
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocuments(ctx, textQueryDocs()))
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"database", []string{"2", "1"}},
+		{"database -mysql", []string{"2"}},
+		{"database -MySQL", []string{"2"}},
+		{"+postgres database", []string{"2"}},
+		{"+postgres", []string{"2", "3"}},
+		{"-mysql", nil},
+		// Free text before conditions, ranked by its occurrences
+		{"database path contains .txt", []string{"2", "1"}},
+		{"database -mysql path contains .txt", []string{"2"}},
+		{"postgres path=c.txt", []string{"3"}},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(ctx, tt.query)
+		assert.NoError(t, err, tt.query)
+		var ids []string
+		for _, doc := range results {
+			ids = append(ids, doc.ID)
+		}
+		assert.Equal(t, tt.want, ids, tt.query)
+	}
+	assert.True(t, Matches("database -mysql", textQueryDocs()[1]))
+	assert.False(t, Matches("database -mysql", textQueryDocs()[0]))
+}
+
+func TestInvertedIndex_SearchTextModifiers(t *testing.T) {
+	ctx := context.Background()
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocuments(ctx, textQueryDocs()))
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"database -mysql", []string{"2"}},
+		{"+postgres database", []string{"2"}},
+		{"postgres -database", []string{"3"}},
+		{"database -mysql-server", []string{"2", "1"}},
+		{"-mysql", nil},
+		// Free text before conditions is answered from the postings
+		{"database path contains b.txt", []string{"2"}},
+		{"postgres -database path contains .txt", []string{"3"}},
+	}
+	for _, tt := range tests {
+		results, err := idx.Search(ctx, tt.query)
+		assert.NoError(t, err, tt.query)
+		var ids []string
+		for _, doc := range results {
+			ids = append(ids, doc.ID)
+		}
+		assert.Equal(t, tt.want, ids, tt.query)
+	}
+
+	stats, err := idx.TermStats("database -mysql", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"database"}, stats.Terms)
+	stats, err = idx.TermStats("database path contains b.txt", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"database"}, stats.Terms)
+}
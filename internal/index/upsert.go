@@ -0,0 +1,127 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Write semantics: AddDocument adds a document or replaces the one with its ID, unless the context is
+ * create-only; UpdateDocument replaces a document and fails if there is none; Upsert adds or replaces
+ * explicitly and reports which it did. The persisted index behaves as its in-memory index does.
+ **/
+
+var (
+	// ErrDocumentExists is returned (wrapped) by create-only adds of documents already in the index
+	ErrDocumentExists = errors.New("already exists in index")
+	// ErrDocumentNotFound is returned (wrapped) by updates and deletes of documents not in the index
+	ErrDocumentNotFound = errors.New("not found in index")
+)
+
+// Upserter is implemented by indexes that can add or replace a document explicitly
+type Upserter interface {
+	// Adds doc or replaces the document with its ID, reporting whether it was added
+	Upsert(ctx context.Context, doc models.Document) (bool, error)
+}
+
+type createOnlyKey struct{}
+
+// WithCreateOnly makes AddDocument and AddDocuments with ctx fail with ErrDocumentExists, without
+// changing the index, when a document's ID is already indexed or repeated in the batch, instead of
+// replacing the document
+func WithCreateOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, createOnlyKey{}, true)
+}
+
+// createOnly reports whether adds with ctx are create-only
+func createOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(createOnlyKey{}).(bool)
+	return only
+}
+
+// checkNew fails with ErrDocumentExists when adds with ctx are create-only and a document's ID is indexed
+// or repeated; the caller must hold a lock
+func (idx *SimpleIndex) checkNew(ctx context.Context, docs []models.Document) error {
+	if !createOnly(ctx) {
+		return nil
+	}
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if _, exists := idx.documents[doc.ID]; exists || seen[doc.ID] {
+			return fmt.Errorf("document %s %w", doc.ID, ErrDocumentExists)
+		}
+		seen[doc.ID] = true
+	}
+	return nil
+}
+
+// checkExisting fails with ErrDocumentNotFound when a document is not indexed; the caller must hold a lock
+func (idx *SimpleIndex) checkExisting(ids []string) error {
+	for _, id := range ids {
+		if _, exists := idx.documents[id]; !exists {
+			return fmt.Errorf("document %s %w", id, ErrDocumentNotFound)
+		}
+	}
+	return nil
+}
+
+// Upsert adds doc or replaces the document with its ID, reporting whether it was added; it replaces
+// documents even in a create-only context
+func (idx *SimpleIndex) Upsert(ctx context.Context, doc models.Document) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.readOnly {
+		return false, ErrReadOnly
+	}
+	_, exists := idx.documents[doc.ID]
+	return !exists, idx.addDocument(doc)
+}
+
+// Upsert adds doc or replaces the document with its ID in memory, then persists it asynchronously
+func (p *PersistedSimpleIndex) Upsert(ctx context.Context, doc models.Document) (bool, error) {
+	created, err := p.index.Upsert(ctx, doc)
+	if err != nil {
+		return false, err
+	}
+
+	// Queue async database operation if database is open, waiting for it per the durability of ctx
+	return created, p.queue(ctx, dbOperation{opType: "add_document", data: doc}, fmt.Sprintf("upsert document operation for %s", doc.ID))
+}
+
+// Upsert adds doc or replaces the document with its ID and its terms, reporting whether it was added
+func (idx *InvertedIndex) Upsert(ctx context.Context, doc models.Document) (bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	_, exists := idx.docTerms[doc.ID]
+	// Upserts replace documents even in a create-only context
+	return !exists, idx.addDocument(context.WithValue(ctx, createOnlyKey{}, false), doc)
+}
+
+// checkNew checks create-only adds before their fields are written to the field store; the caller must
+// hold the write lock
+func (idx *InvertedIndex) checkNew(ctx context.Context, docs []models.Document) error {
+	idx.store.mu.RLock()
+	defer idx.store.mu.RUnlock()
+	return idx.store.checkNew(ctx, docs)
+}
+
+// Upsert adds doc or replaces the document with its ID, reporting whether it was added
+func (idx *VectorIndex) Upsert(ctx context.Context, doc models.Document) (bool, error) {
+	if err := idx.checkVector(doc); err != nil {
+		return false, err
+	}
+	return idx.store.Upsert(ctx, doc)
+}
+
+// checkExisting checks the documents of batch updates and deletes are indexed before changing any
+func (idx *InvertedIndex) checkExisting(ids []string) error {
+	idx.store.mu.RLock()
+	defer idx.store.mu.RUnlock()
+	return idx.store.checkExisting(ids)
+}
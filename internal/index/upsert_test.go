@@ -0,0 +1,56 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestIndexes_CreateOnlyAndUpsert(t *testing.T) {
+	persisted, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer persisted.Close()
+	vectors, err := NewVectorIndex("cosine", 3, 0)
+	assert.NoError(t, err)
+	indexes := map[string]Index{
+		"simple": NewSimpleIndex(), "persisted": persisted, "inverted": NewInvertedIndex(nil), "vector": vectors,
+	}
+	for name, idx := range indexes {
+		ctx := context.Background()
+		assert.NoError(t, idx.AddDocument(ctx, makeTestDoc("1", "first", "a.txt", nil, nil)), name)
+
+		// Create-only adds fail without changing the index, for an indexed ID or one repeated in the batch
+		createOnly := WithCreateOnly(ctx)
+		assert.ErrorIs(t, idx.AddDocument(createOnly, makeTestDoc("1", "second", "a.txt", nil, nil)), ErrDocumentExists, name)
+		err := idx.AddDocuments(createOnly, []models.Document{makeTestDoc("2", "two", "b.txt", nil, nil), makeTestDoc("2", "again", "b.txt", nil, nil)})
+		assert.ErrorIs(t, err, ErrDocumentExists, name)
+		assert.EqualError(t, err, "document 2 already exists in index", name)
+		count, _ := idx.Count()
+		assert.Equal(t, 1, count, name)
+		assert.NoError(t, idx.AddDocument(createOnly, makeTestDoc("2", "two", "b.txt", nil, nil)), name)
+
+		upserter := idx.(Upserter)
+		created, err := upserter.Upsert(createOnly, makeTestDoc("1", "replaced", "a.txt", nil, nil))
+		assert.NoError(t, err, name)
+		assert.False(t, created, name)
+		created, err = upserter.Upsert(ctx, makeTestDoc("3", "three", "c.txt", nil, nil))
+		assert.NoError(t, err, name)
+		assert.True(t, created, name)
+		results, err := idx.Search(ctx, "replaced")
+		assert.NoError(t, err, name)
+		assert.Len(t, results, 1, name)
+
+		// Updates and deletes of a missing document fail before changing any
+		err = idx.UpdateDocuments(ctx, []models.Document{makeTestDoc("1", "updated", "a.txt", nil, nil), makeTestDoc("4", "four", "d.txt", nil, nil)})
+		assert.ErrorIs(t, err, ErrDocumentNotFound, name)
+		results, _ = idx.Search(ctx, "updated")
+		assert.Empty(t, results, name)
+		assert.ErrorIs(t, idx.DeleteDocuments(ctx, []string{"1", "4"}), ErrDocumentNotFound, name)
+		count, _ = idx.Count()
+		assert.Equal(t, 3, count, name)
+	}
+}
@@ -0,0 +1,252 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of the problems ValidateQuery reports
+const (
+	QueryErrorSyntax              = "syntax"                // Not a condition, or a malformed vector
+	QueryErrorUnknownDimension    = "unknown_dimension"     // No document has the dimension
+	QueryErrorBadOperator         = "bad_operator"          // An operator the query language does not have
+	QueryErrorVectorSize          = "vector_size"           // A query vector of the wrong number of dimensions
+	QueryErrorDimensionNotAllowed = "dimension_not_allowed" // Not one of the dimensions of the index config
+)
+
+// QueryError is a problem found in a query. Offset and Length count characters (Unicode code points),
+// so user interfaces can underline the problem.
+type QueryError struct {
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"` // Replacement of the characters at Offset, if one is likely
+}
+
+// QueryValidator is implemented by indexes that check queries without running them
+type QueryValidator interface {
+	// Returns the problems of a query (none: the query is valid)
+	ValidateQuery(query string) []QueryError
+}
+
+// builtinDimensions are the dimensions conditions read from document fields rather than metadata
+var builtinDimensions = []string{"filename", "path", "text"}
+
+// operatorFixes are the operators of other query languages users write, and the operator they mean
+var operatorFixes = map[string]string{
+	"=<": "<=", "=>": ">=", "<>": "!=", "=!": "!=", "!": "!=",
+	"~": "contains", "~=": "contains", "=~": "contains",
+	"has": "contains", "like": "contains", "includes": "contains", "contain": "contains", "in": "contains",
+}
+
+// looseCondition matches conditions whose operator is unknown: a dimension, a word or run of symbols,
+// and a value
+var looseCondition = regexp.MustCompile(`^([\w.]+)\s*([^\w\s"']+|\w+)\s*(.*)$`)
+
+// badOperatorPrefix matches values starting with operator symbols, as after "fileSize=<10"
+var badOperatorPrefix = regexp.MustCompile(`^[=<>!~]+`)
+
+// joinKeyword matches "or" (not supported) and "and" in other cases than the separator " and "
+var joinKeyword = regexp.MustCompile(`\s(?i:or|and)\s`)
+
+// ValidateQuery checks the conditions of a query against the dimensions of the indexed documents.
+// Queries that are not conditions run as free-text searches and are valid.
+func (idx *SimpleIndex) ValidateQuery(query string) []QueryError {
+	known, allowed := idx.dimensions()
+	return runeOffsets(query, validateConditions(query, 0, known, allowed, false))
+}
+
+// dimensions returns the metadata keys of the indexed documents, from the catalog, and the dimensions of
+// the index config (nil: any)
+func (idx *SimpleIndex) dimensions() (known, allowed map[string]bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	known = make(map[string]bool, len(idx.catalog))
+	for key := range idx.catalog {
+		known[key] = true
+	}
+	return known, idx.allowed
+}
+
+// ValidateQuery checks the conditions of a query; free-text queries are valid
+func (p *PersistedSimpleIndex) ValidateQuery(query string) []QueryError {
+	return p.index.ValidateQuery(query)
+}
+
+// ValidateQuery checks the conditions of a query; free-text queries are valid
+func (idx *InvertedIndex) ValidateQuery(query string) []QueryError {
+	return idx.store.ValidateQuery(query)
+}
+
+// ValidateQuery checks the vector and where clause of vector queries, and the conditions of others
+func (idx *VectorIndex) ValidateQuery(query string) []QueryError {
+	vector, where, ok, err := ParseVectorQuery(query)
+	if !ok {
+		return idx.store.ValidateQuery(query)
+	}
+	start := strings.Index(query, "[")
+	end := strings.Index(query, "]") + 1
+	if err != nil {
+		return runeOffsets(query, []QueryError{{Offset: start, Length: end - start, Code: QueryErrorSyntax, Message: err.Error()}})
+	}
+	var errs []QueryError
+	if idx.size > 0 && len(vector) != idx.size {
+		errs = append(errs, QueryError{Offset: start, Length: end - start, Code: QueryErrorVectorSize,
+			Message: fmt.Sprintf("query vector has %d dimensions, index expects %d", len(vector), idx.size)})
+	}
+	if where != "" {
+		offset := len(strings.TrimRightFunc(query, unicode.IsSpace)) - len(where)
+		known, allowed := idx.store.dimensions()
+		errs = append(errs, validateConditions(where, offset, known, allowed, true)...)
+	}
+	return runeOffsets(query, errs)
+}
+
+// validateConditions checks the " and "-separated conditions of a query starting at byte offset of the
+// whole query; the first may follow free text. A single part that is not a condition is a free-text
+// query, unless conditions are required (where clauses). Dimensions are checked against the allowed ones
+// of the index config (nil: any), then against known ones (none known: not checked).
+func validateConditions(query string, offset int, known, allowed map[string]bool, required bool) []QueryError {
+	parts := strings.Split(query, " and ")
+	var errs []QueryError
+	start := 0
+	for i, part := range parts {
+		partOffset := offset + start + len(part) - len(strings.TrimLeftFunc(part, unicode.IsSpace))
+		start += len(part) + len(" and ")
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if m := conditionPattern.FindStringSubmatchIndex(part); m != nil {
+			errs = append(errs, checkCondition(part, partOffset, m, known, allowed)...)
+			continue
+		}
+		if _, rest, ok := splitLeadingText(part); ok && i == 0 {
+			m := conditionPattern.FindStringSubmatchIndex(rest)
+			errs = append(errs, checkCondition(rest, partOffset+len(part)-len(rest), m, known, allowed)...)
+			continue
+		}
+		if len(parts) == 1 && !required {
+			return nil
+		}
+		errs = append(errs, looseConditionError(part, partOffset))
+	}
+	return errs
+}
+
+// checkCondition checks the dimension and operator of a condition matched by conditionPattern
+func checkCondition(part string, offset int, m []int, known, allowed map[string]bool) []QueryError {
+	var errs []QueryError
+	dimension := part[m[2]:m[3]]
+	if allowed != nil && !allowed[dimension] && !isBuiltinDimension(dimension) {
+		errs = append(errs, dimensionNotAllowed(dimension, offset+m[2], allowed))
+	} else if len(known) > 0 && !known[dimension] && !isBuiltinDimension(dimension) {
+		err := QueryError{Offset: offset + m[2], Length: len(dimension), Code: QueryErrorUnknownDimension,
+			Message: fmt.Sprintf("no document has the dimension %q", dimension)}
+		if suggestion := closestDimension(dimension, known); suggestion != "" {
+			err.Suggestion = suggestion
+			err.Message += fmt.Sprintf("; did you mean %q?", suggestion)
+		}
+		errs = append(errs, err)
+	}
+	operator, value := part[m[4]:m[5]], part[m[6]:m[7]]
+	if prefix := badOperatorPrefix.FindString(value); prefix != "" {
+		written := operator + prefix
+		err := QueryError{Offset: offset + m[4], Length: m[6] + len(prefix) - m[4], Code: QueryErrorBadOperator,
+			Message: fmt.Sprintf("unknown operator %q", written)}
+		if fix, ok := operatorFixes[written]; ok {
+			err.Suggestion = fix
+			err.Message += fmt.Sprintf("; did you mean %q?", fix)
+		}
+		errs = append(errs, err)
+	}
+	if loc := joinKeyword.FindStringIndex(value); loc != nil {
+		keyword := strings.TrimSpace(value[loc[0]:loc[1]])
+		err := QueryError{Offset: offset + m[6] + loc[0] + 1, Length: len(keyword), Code: QueryErrorBadOperator}
+		if strings.EqualFold(keyword, "or") {
+			err.Message = "conditions can only be combined with \"and\""
+		} else {
+			err.Message = "conditions are combined with a lowercase \"and\""
+			err.Suggestion = "and"
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// looseConditionError explains why a part of a query is not a condition
+func looseConditionError(part string, offset int) QueryError {
+	if m := looseCondition.FindStringSubmatchIndex(part); m != nil && m[7] > m[6] {
+		operator := part[m[4]:m[5]]
+		err := QueryError{Offset: offset + m[4], Length: len(operator), Code: QueryErrorBadOperator,
+			Message: fmt.Sprintf("unknown operator %q; operators are =, !=, <, <=, >, >= and contains", operator)}
+		if fix, ok := operatorFixes[strings.ToLower(operator)]; ok {
+			err.Suggestion = fix
+			err.Message = fmt.Sprintf("unknown operator %q; did you mean %q?", operator, fix)
+		}
+		return err
+	}
+	return QueryError{Offset: offset, Length: len(part), Code: QueryErrorSyntax,
+		Message: fmt.Sprintf("%q is not a condition (dimension, operator and value, e.g. fileSize<1000)", part)}
+}
+
+func isBuiltinDimension(dimension string) bool {
+	for _, builtin := range builtinDimensions {
+		if dimension == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// closestDimension returns the known dimension nearest to a misspelt one, or "" if none is close
+func closestDimension(dimension string, known map[string]bool) string {
+	candidates := append([]string{}, builtinDimensions...)
+	for name := range known {
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	best, bestDistance := "", len(dimension)/3+1
+	for _, candidate := range candidates {
+		if distance := editDistance(strings.ToLower(dimension), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+// runeOffsets converts the byte offsets and lengths of errors in query to characters
+func runeOffsets(query string, errs []QueryError) []QueryError {
+	for i, err := range errs {
+		errs[i].Offset = utf8.RuneCountInString(query[:err.Offset])
+		errs[i].Length = utf8.RuneCountInString(query[err.Offset : err.Offset+err.Length])
+	}
+	return errs
+}
@@ -0,0 +1,79 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimpleIndex_ValidateQuery(t *testing.T) {
+	idx := NewSimpleIndex()
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "hello", "a.go", map[string]string{"fileExtension": "go", "fileSize": "10"}, nil)))
+
+	tests := []struct {
+		name, query string
+		want        []QueryError
+	}{
+		{"free text", "hello world", nil},
+		{"conditions", "fileExtension=go and fileSize<100 and filename contains a", nil},
+		{"unknown dimension", "fileExtention=go", []QueryError{
+			{Offset: 0, Length: 13, Code: QueryErrorUnknownDimension, Message: `no document has the dimension "fileExtention"; did you mean "fileExtension"?`, Suggestion: "fileExtension"},
+		}},
+		{"unknown dimension without suggestion", "author=alice", []QueryError{
+			{Offset: 0, Length: 6, Code: QueryErrorUnknownDimension, Message: `no document has the dimension "author"`},
+		}},
+		{"reversed operator", "fileExtension=go and fileSize=<10", []QueryError{
+			{Offset: 29, Length: 2, Code: QueryErrorBadOperator, Message: `unknown operator "=<"; did you mean "<="?`, Suggestion: "<="},
+		}},
+		{"word operator", "filename has README and fileSize<10", []QueryError{
+			{Offset: 9, Length: 3, Code: QueryErrorBadOperator, Message: `unknown operator "has"; did you mean "contains"?`, Suggestion: "contains"},
+		}},
+		{"text before conditions", "kafka fileExtention=go and fileSize=<10", []QueryError{
+			{Offset: 6, Length: 13, Code: QueryErrorUnknownDimension, Message: `no document has the dimension "fileExtention"; did you mean "fileExtension"?`, Suggestion: "fileExtension"},
+			{Offset: 35, Length: 2, Code: QueryErrorBadOperator, Message: `unknown operator "=<"; did you mean "<="?`, Suggestion: "<="},
+		}},
+		{"text among conditions", "README and fileSize<10", []QueryError{
+			{Offset: 0, Length: 6, Code: QueryErrorSyntax, Message: `"README" is not a condition (dimension, operator and value, e.g. fileSize<1000)`},
+		}},
+		{"or", "fileExtension=go or fileExtension=md", []QueryError{
+			{Offset: 17, Length: 2, Code: QueryErrorBadOperator, Message: `conditions can only be combined with "and"`},
+		}},
+		{"uppercase and", "fileExtension=go AND fileSize<10", []QueryError{
+			{Offset: 17, Length: 3, Code: QueryErrorBadOperator, Message: `conditions are combined with a lowercase "and"`, Suggestion: "and"},
+		}},
+		// Offsets count characters, not bytes
+		{"unicode", "text contains héllo and fileSize=<10", []QueryError{
+			{Offset: 32, Length: 2, Code: QueryErrorBadOperator, Message: `unknown operator "=<"; did you mean "<="?`, Suggestion: "<="},
+		}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, idx.ValidateQuery(tt.query), tt.name)
+	}
+
+	// Dimensions are not checked before documents are indexed
+	assert.Empty(t, NewSimpleIndex().ValidateQuery("anything=1 and more=2"))
+}
+
+func TestVectorIndex_ValidateQuery(t *testing.T) {
+	idx, err := NewVectorIndex("cosine", 2, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocument(context.Background(), models.Document{ID: "1", Vector: []float64{1, 0}, Meta: map[string]string{"fileSize": "10"}}))
+
+	assert.Empty(t, idx.ValidateQuery("[1, 0] where fileSize<100"))
+	assert.Equal(t, []QueryError{{Offset: 0, Length: 9, Code: QueryErrorVectorSize, Message: "query vector has 3 dimensions, index expects 2"}},
+		idx.ValidateQuery("[1, 0, 1]"))
+	errs := idx.ValidateQuery("[1, x]")
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, QueryErrorSyntax, errs[0].Code)
+		assert.Equal(t, 6, errs[0].Length)
+	}
+	// The conditions of where clauses are required, and positioned in the whole query
+	assert.Equal(t, []QueryError{{Offset: 13, Length: 8, Code: QueryErrorUnknownDimension, Message: `no document has the dimension "filesize"; did you mean "fileSize"?`, Suggestion: "fileSize"}},
+		idx.ValidateQuery("[1, 0] where filesize<100"))
+	errs = idx.ValidateQuery("[1, 0] where small")
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, QueryError{Offset: 13, Length: 5, Code: QueryErrorSyntax, Message: `"small" is not a condition (dimension, operator and value, e.g. fileSize<1000)`}, errs[0])
+	}
+}
@@ -0,0 +1,294 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Metric scores how similar two vectors are; higher is more similar
+type Metric func(a, b []float64) float64
+
+// VectorIndex is an in-memory index answering k-nearest-neighbour queries over Document.Vector
+// with a brute-force scan. Queries written as a vector literal ("[0.1, 0.2, 0.3]") use kNN, optionally
+// restricted to the documents matching a where clause ("[0.1, 0.2, 0.3] where extension=md"); any other
+// query falls back to SimpleIndex search.
+type VectorIndex struct {
+	store      *SimpleIndex
+	metric     Metric
+	metricName string
+	k          int // Number of neighbours returned by vector literal queries
+	size       int // Expected vector length (0 accepts any length)
+}
+
+// NewVectorIndex creates a new VectorIndex using the named metric ("cosine", "dot" or "euclidean")
+func NewVectorIndex(metric string, k, size int) (*VectorIndex, error) {
+	m, err := metricByName(metric)
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	return &VectorIndex{store: NewSimpleIndex(), metric: m, metricName: metric, k: k, size: size}, nil
+}
+
+func metricByName(name string) (Metric, error) {
+	switch name {
+	case "", "cosine":
+		return cosineSimilarity, nil
+	case "dot":
+		return dotProduct, nil
+	case "euclidean":
+		// Negated so that closer vectors score higher
+		return func(a, b []float64) float64 { return -euclideanDistance(a, b) }, nil
+	default:
+		return nil, fmt.Errorf("unknown vector metric %s", name)
+	}
+}
+
+// Configure validates and sets the index configuration, warning about the keys it does not know
+func (idx *VectorIndex) Configure(config map[string]interface{}) error {
+	return idx.store.configure(config, vectorConfigKeys)
+}
+
+// MaxResults returns the max_results of the index config (0: none)
+func (idx *VectorIndex) MaxResults() int {
+	return idx.store.MaxResults()
+}
+
+// ShowConfig returns the current index configuration
+func (idx *VectorIndex) ShowConfig() (map[string]interface{}, error) {
+	return idx.store.ShowConfig()
+}
+
+// AddDocument adds a single document to the index
+func (idx *VectorIndex) AddDocument(ctx context.Context, doc models.Document) error {
+	if err := idx.checkVector(doc); err != nil {
+		return err
+	}
+	return idx.store.AddDocument(ctx, doc)
+}
+
+// AddDocuments adds multiple documents to the index
+func (idx *VectorIndex) AddDocuments(ctx context.Context, docs []models.Document) error {
+	for _, doc := range docs {
+		if err := idx.checkVector(doc); err != nil {
+			return err
+		}
+	}
+	return idx.store.AddDocuments(ctx, docs)
+}
+
+// Search runs a kNN query when the query is a vector query, otherwise a SimpleIndex search
+func (idx *VectorIndex) Search(ctx context.Context, query string) ([]models.Document, error) {
+	prepared, err := idx.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return prepared.search(ctx)
+}
+
+// SearchVector returns the k documents whose vectors are most similar to the given vector among those
+// matching filter (nil: all). The filter applies before the neighbours are picked, so k are returned
+// whenever k documents match.
+func (idx *VectorIndex) SearchVector(ctx context.Context, vector []float64, k int, filter *Query) ([]models.Document, error) {
+	if idx.size > 0 && len(vector) != idx.size {
+		return nil, fmt.Errorf("query vector has %d dimensions, index expects %d", len(vector), idx.size)
+	}
+
+	type scored struct {
+		doc   models.Document
+		score float64
+	}
+
+	idx.store.mu.RLock()
+	// The filter's selective conditions narrow the documents compared from the stored documents' structures
+	var steps []conditionStep
+	if filter != nil {
+		steps = idx.store.planConditions(filter, idx.store.indexedLimit())
+	}
+	candidates := make([]scored, 0, len(idx.store.documents))
+	visibility := idx.store.acl.visibility(ctx)
+	scanned := 0
+	var err error
+	idx.store.eachCandidate(steps, func(id string, doc models.Document) bool {
+		if err = checkCancelled(ctx, scanned); err != nil {
+			return false
+		}
+		scanned++
+		if len(doc.Vector) != len(vector) || !visibility.allows(doc.ID) {
+			return true
+		}
+		if filter != nil {
+			var matches bool
+			if matches, err = filter.Evaluate(doc); err != nil || !matches {
+				return err == nil
+			}
+		}
+		candidates = append(candidates, scored{doc: doc, score: idx.metric(vector, doc.Vector)})
+		return true
+	})
+	idx.store.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].doc.ID < candidates[j].doc.ID
+	})
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	results := make([]models.Document, len(candidates))
+	for i, c := range candidates {
+		results[i] = c.doc
+	}
+	log.Info().Msgf("Vector search (%s, k=%d) returned %d results", idx.metricName, k, len(results))
+	return results, nil
+}
+
+// DeleteDocument removes a document from the index
+func (idx *VectorIndex) DeleteDocument(ctx context.Context, id string) error {
+	return idx.store.DeleteDocument(ctx, id)
+}
+
+// DeleteDocuments removes multiple documents from the index
+func (idx *VectorIndex) DeleteDocuments(ctx context.Context, ids []string) error {
+	return idx.store.DeleteDocuments(ctx, ids)
+}
+
+// UpdateDocument updates an existing document in the index
+func (idx *VectorIndex) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	if err := idx.checkVector(doc); err != nil {
+		return err
+	}
+	return idx.store.UpdateDocument(ctx, id, doc)
+}
+
+// UpdateDocuments updates multiple documents in the index
+func (idx *VectorIndex) UpdateDocuments(ctx context.Context, docs []models.Document) error {
+	for _, doc := range docs {
+		if err := idx.checkVector(doc); err != nil {
+			return err
+		}
+	}
+	return idx.store.UpdateDocuments(ctx, docs)
+}
+
+// Close performs cleanup operations
+func (idx *VectorIndex) Close() error {
+	return idx.store.Close()
+}
+
+// Flush writes the index to disk (no-op for the in-memory vector index)
+func (idx *VectorIndex) Flush() error {
+	return idx.store.Flush()
+}
+
+// Count returns the number of documents in the index
+func (idx *VectorIndex) Count() (int, error) {
+	return idx.store.Count()
+}
+
+// Size returns the approximate size of the index in bytes
+func (idx *VectorIndex) Size() (int, error) {
+	return idx.store.Size()
+}
+
+// checkVector rejects documents whose vector does not match the configured length.
+// Documents without a vector are accepted; they are only reachable through text search.
+func (idx *VectorIndex) checkVector(doc models.Document) error {
+	if idx.size > 0 && len(doc.Vector) > 0 && len(doc.Vector) != idx.size {
+		return fmt.Errorf("document %s has a %d-dimensional vector, index expects %d", doc.ID, len(doc.Vector), idx.size)
+	}
+	return nil
+}
+
+// ParseVectorQuery parses vector queries: a vector literal, optionally followed by a where clause of
+// conditions the neighbours must match (e.g. "[0.1, 0.2] where extension=md and fileSize<1000"). ok is
+// false when the query is not a vector query.
+func ParseVectorQuery(query string) (vector []float64, where string, ok bool, err error) {
+	query = strings.TrimSpace(query)
+	end := strings.Index(query, "]")
+	if !strings.HasPrefix(query, "[") || end < 0 {
+		return nil, "", false, nil
+	}
+	if rest := strings.TrimSpace(query[end+1:]); rest != "" {
+		keyword, clause, found := strings.Cut(rest, " ")
+		if !found || !strings.EqualFold(keyword, "where") {
+			return nil, "", false, nil
+		}
+		where = strings.TrimSpace(clause)
+	}
+	vector, ok, err = ParseVectorLiteral(query[:end+1])
+	return vector, where, ok, err
+}
+
+// FormatVectorQuery renders a vector and where clause ("": none) as a query accepted by ParseVectorQuery
+func FormatVectorQuery(vector []float64, where string) string {
+	if where == "" {
+		return FormatVectorLiteral(vector)
+	}
+	return FormatVectorLiteral(vector) + " where " + where
+}
+
+// ParseVectorLiteral parses queries like "[0.1, 0.2, 0.3]"; ok is false when the query is not a vector literal
+func ParseVectorLiteral(query string) (vector []float64, ok bool, err error) {
+	query = strings.TrimSpace(query)
+	if !strings.HasPrefix(query, "[") || !strings.HasSuffix(query, "]") {
+		return nil, false, nil
+	}
+	for _, part := range strings.Split(query[1:len(query)-1], ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid vector component %q: %w", part, err)
+		}
+		vector = append(vector, value)
+	}
+	return vector, true, nil
+}
+
+// FormatVectorLiteral renders a vector as a query accepted by ParseVectorLiteral
+func FormatVectorLiteral(vector []float64) string {
+	parts := make([]string, len(vector))
+	for i, v := range vector {
+		parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func dotProduct(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	normA, normB := math.Sqrt(dotProduct(a, a)), math.Sqrt(dotProduct(b, b))
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dotProduct(a, b) / (normA * normB)
+}
+
+func euclideanDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
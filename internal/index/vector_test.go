@@ -0,0 +1,93 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVectorIndex_SearchVectorLiteral(t *testing.T) {
+	idx, err := NewVectorIndex("cosine", 2, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("x", "", "x", nil, []float64{1, 0}),
+		makeTestDoc("y", "", "y", nil, []float64{0, 1}),
+		makeTestDoc("xy", "", "xy", nil, []float64{1, 1}),
+	}))
+
+	results, err := idx.Search(context.Background(), "[1, 0.1]")
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "x", results[0].ID)
+	assert.Equal(t, "xy", results[1].ID)
+
+	_, err = idx.Search(context.Background(), "[1, nope]")
+	assert.Error(t, err)
+}
+
+func TestVectorIndex_TextQueryFallsBack(t *testing.T) {
+	idx, _ := NewVectorIndex("euclidean", 5, 0)
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("1", "hello world", "a.txt", nil, nil)))
+
+	results, err := idx.Search(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestVectorIndex_RejectsWrongVectorSize(t *testing.T) {
+	idx, _ := NewVectorIndex("dot", 5, 3)
+	assert.Error(t, idx.AddDocument(context.Background(), makeTestDoc("1", "", "a", nil, []float64{1, 2})))
+	assert.NoError(t, idx.AddDocument(context.Background(), makeTestDoc("2", "", "b", nil, []float64{1, 2, 3})))
+
+	_, err := NewVectorIndex("manhattan", 5, 0)
+	assert.Error(t, err)
+}
+
+func TestVectorIndex_PreFilter(t *testing.T) {
+	idx, err := NewVectorIndex("cosine", 2, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.AddDocuments(context.Background(), []models.Document{
+		makeTestDoc("x", "", "x", map[string]string{"extension": "go"}, []float64{1, 0}),
+		makeTestDoc("x2", "", "x2", map[string]string{"extension": "go"}, []float64{1, 0.05}),
+		makeTestDoc("xy", "", "xy", map[string]string{"extension": "md", "fileSize": "10"}, []float64{1, 1}),
+		makeTestDoc("y", "", "y", map[string]string{"extension": "md", "fileSize": "2000"}, []float64{0, 1}),
+	}))
+
+	// The closest documents do not match, yet k matching ones are returned
+	results, err := idx.Search(context.Background(), "[1, 0] where extension=md")
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "xy", results[0].ID)
+	assert.Equal(t, "y", results[1].ID)
+
+	results, err = idx.Search(context.Background(), "[1, 0] WHERE extension=md and fileSize<1000")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "xy", results[0].ID)
+
+	_, err = idx.Search(context.Background(), "[1, 0] where nonsense")
+	assert.Error(t, err)
+}
+
+func TestParseVectorQuery(t *testing.T) {
+	vector, where, ok, err := ParseVectorQuery(" [0.5, 1] where extension=md ")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []float64{0.5, 1}, vector)
+	assert.Equal(t, "extension=md", where)
+	assert.Equal(t, "[0.5, 1] where extension=md", FormatVectorQuery(vector, where))
+	assert.Equal(t, "[0.5, 1]", FormatVectorQuery(vector, ""))
+
+	_, where, ok, err = ParseVectorQuery("[0.5, 1]")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, where)
+
+	// Anything but a where clause after the literal is not a vector query
+	_, _, ok, _ = ParseVectorQuery("[draft] notes")
+	assert.False(t, ok)
+	_, _, ok, _ = ParseVectorQuery("notes")
+	assert.False(t, ok)
+}
@@ -0,0 +1,236 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+)
+
+/**
+ * Verification: writes reach the database asynchronously, after memory, so a dropped or failed write
+ * leaves the two apart. Verify compares the documents in memory with those in the database by ID and
+ * content hash and can repair either side from the other.
+ **/
+
+// Directions of repairs of a verification
+const (
+	RepairNone   = ""       // Only report the differences
+	RepairDisk   = "disk"   // Rewrite the database's differing documents from memory
+	RepairMemory = "memory" // Reload memory's differing documents from the database
+)
+
+// ErrUnknownRepair is returned (wrapped) by Verify for a repair direction other than the Repair constants
+var ErrUnknownRepair = errors.New("unknown repair direction")
+
+// VerifyReport is the differences between the documents in memory and in the database
+type VerifyReport struct {
+	Documents       int      // Documents in memory
+	Stored          int      // Documents in the database
+	MissingOnDisk   []string // IDs of the documents in memory only
+	MissingInMemory []string // IDs of the documents in the database only
+	Mismatched      []string // IDs of the documents whose content differs
+	Repaired        int      // Documents written or removed by the repair
+}
+
+// Consistent reports whether memory and the database hold the same documents
+func (r VerifyReport) Consistent() bool {
+	return len(r.MissingOnDisk) == 0 && len(r.MissingInMemory) == 0 && len(r.Mismatched) == 0
+}
+
+// Verifier is implemented by indexes that keep their documents both in memory and on disk
+type Verifier interface {
+	// Compares memory with the disk and repairs the differences in a direction (RepairNone: none)
+	Verify(repair string) (VerifyReport, error)
+}
+
+// Verify compares the documents in memory with those in the database and, with RepairDisk or
+// RepairMemory, makes one side match the other. It runs on the async worker after the writes queued
+// before it; writes made meanwhile may show as differences until they are queued.
+func (p *PersistedSimpleIndex) Verify(repair string) (VerifyReport, error) {
+	switch repair {
+	case RepairNone, RepairDisk, RepairMemory:
+	default:
+		return VerifyReport{}, fmt.Errorf("%w %q (want %q or %q)", ErrUnknownRepair, repair, RepairDisk, RepairMemory)
+	}
+	if p.readOnly && repair != RepairNone {
+		return VerifyReport{}, ErrReadOnly
+	}
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+	if db == nil {
+		return VerifyReport{}, fmt.Errorf("database not open")
+	}
+	if p.readOnly {
+		return p.verifyDatabase(repair)
+	}
+	if !p.workerRunning.Load() {
+		return VerifyReport{}, fmt.Errorf("async database worker is not running")
+	}
+	result := make(chan verifyResult, 1)
+	p.opChan <- dbOperation{opType: "verify", data: verifyRequest{repair: repair, result: result}}
+	verified := <-result
+	return verified.report, verified.err
+}
+
+// verifyRequest is the data of a "verify" operation of the async worker
+type verifyRequest struct {
+	repair string
+	result chan verifyResult
+}
+
+type verifyResult struct {
+	report VerifyReport
+	err    error
+}
+
+// documentHash is the SHA-256 of the JSON encoding of a document, which sorts its metadata keys
+func documentHash(doc models.Document) ([sha256.Size]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+	}
+	return sha256.Sum256(data), nil
+}
+
+// verifyDatabase compares memory with the database and repairs the differences; it runs on the async
+// worker, so no write is in flight
+func (p *PersistedSimpleIndex) verifyDatabase(repair string) (VerifyReport, error) {
+	p.mu.RLock()
+	db := p.db
+	p.mu.RUnlock()
+
+	memory := make(map[string][sha256.Size]byte)
+	p.index.mu.RLock()
+	for id, doc := range p.index.documents {
+		hash, err := documentHash(doc)
+		if err != nil {
+			p.index.mu.RUnlock()
+			return VerifyReport{}, err
+		}
+		memory[id] = hash
+	}
+	p.index.mu.RUnlock()
+
+	report := VerifyReport{Documents: len(memory)}
+	stored := make(map[string]models.Document) // The database's documents missing from memory or differing
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("documents"))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			report.Stored++
+			var doc models.Document
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return fmt.Errorf("failed to unmarshal document %s: %w", k, err)
+			}
+			// Documents are hashed as they decode, so older encodings of the same content match
+			hash, err := documentHash(doc)
+			if err != nil {
+				return err
+			}
+			id := string(k)
+			memoryHash, ok := memory[id]
+			switch {
+			case !ok:
+				report.MissingInMemory = append(report.MissingInMemory, id)
+				stored[id] = doc
+			case memoryHash != hash:
+				report.Mismatched = append(report.Mismatched, id)
+				stored[id] = doc
+			}
+			delete(memory, id)
+			return nil
+		})
+	})
+	if err != nil {
+		return VerifyReport{}, fmt.Errorf("failed to read database: %w", err)
+	}
+	for id := range memory {
+		report.MissingOnDisk = append(report.MissingOnDisk, id)
+	}
+	sort.Strings(report.MissingOnDisk)
+	sort.Strings(report.MissingInMemory)
+	sort.Strings(report.Mismatched)
+	if !report.Consistent() {
+		log.Warn().Msgf("Database %s differs from memory: %d documents missing on disk, %d missing in memory, %d mismatched",
+			db.Path(), len(report.MissingOnDisk), len(report.MissingInMemory), len(report.Mismatched))
+	}
+
+	switch repair {
+	case RepairDisk:
+		err = p.repairDisk(db, &report)
+	case RepairMemory:
+		err = p.repairMemory(stored, &report)
+	}
+	return report, err
+}
+
+// repairDisk writes the documents of memory the database lacks or holds differently, and removes those
+// memory lacks, in one transaction
+func (p *PersistedSimpleIndex) repairDisk(db *bbolt.DB, report *VerifyReport) error {
+	ids := append(append([]string{}, report.MissingOnDisk...), report.Mismatched...)
+	docs, err := p.index.fetch(ids)
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("documents"))
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+			}
+			if err := bucket.Put([]byte(doc.ID), data); err != nil {
+				return fmt.Errorf("failed to store document %s: %w", doc.ID, err)
+			}
+		}
+		for _, id := range report.MissingInMemory {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return fmt.Errorf("failed to delete document %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to repair database: %w", err)
+	}
+	report.Repaired = len(docs) + len(report.MissingInMemory)
+	log.Info().Msgf("Repaired %d documents of database %s from memory", report.Repaired, db.Path())
+	return nil
+}
+
+// repairMemory adds the documents of the database memory lacks or holds differently, and removes those
+// the database lacks
+func (p *PersistedSimpleIndex) repairMemory(stored map[string]models.Document, report *VerifyReport) error {
+	idx := p.index
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, id := range append(append([]string{}, report.MissingInMemory...), report.Mismatched...) {
+		if err := idx.addDocument(stored[id]); err != nil {
+			return fmt.Errorf("failed to repair document %s: %w", id, err)
+		}
+		report.Repaired++
+	}
+	for _, id := range report.MissingOnDisk {
+		if _, ok := idx.documents[id]; !ok {
+			continue
+		}
+		if err := idx.deleteDocument(id); err != nil {
+			return fmt.Errorf("failed to repair document %s: %w", id, err)
+		}
+		report.Repaired++
+	}
+	log.Info().Msgf("Repaired %d documents in memory from the database", report.Repaired)
+	return nil
+}
@@ -0,0 +1,77 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// putStored writes a document to the database of idx without changing its memory
+func putStored(t *testing.T, idx *PersistedSimpleIndex, doc models.Document) {
+	data, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.NoError(t, idx.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte("documents")).Put([]byte(doc.ID), data)
+	}))
+}
+
+func TestPersistedSimpleIndex_Verify(t *testing.T) {
+	ctx := context.Background()
+	idx, err := NewPersistedSimpleIndexWithDatabase(filepath.Join(t.TempDir(), "index.db"))
+	assert.NoError(t, err)
+	defer idx.Close()
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		{ID: "1", Text: "one", Meta: map[string]string{"b": "2", "a": "1"}},
+		{ID: "2", Text: "two"},
+		{ID: "3", Text: "three"},
+	}))
+
+	// Verification runs after the queued writes
+	report, err := idx.Verify(RepairNone)
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, VerifyReport{Documents: 3, Stored: 3}, report)
+
+	// Memory and the database drift apart
+	assert.NoError(t, idx.index.AddDocument(ctx, models.Document{ID: "4", Text: "memory only"}))
+	putStored(t, idx, models.Document{ID: "5", Text: "disk only"})
+	putStored(t, idx, models.Document{ID: "2", Text: "changed on disk"})
+
+	report, err = idx.Verify(RepairNone)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyReport{Documents: 4, Stored: 4, MissingOnDisk: []string{"4"}, MissingInMemory: []string{"5"}, Mismatched: []string{"2"}}, report)
+
+	report, err = idx.Verify(RepairDisk)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Repaired)
+	report, err = idx.Verify(RepairNone)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyReport{Documents: 4, Stored: 4}, report)
+
+	// Memory is reloaded from the database
+	putStored(t, idx, models.Document{ID: "3", Text: "changed on disk", Meta: map[string]string{"team": "a"}})
+	assert.NoError(t, idx.index.DeleteDocument(ctx, "1"))
+	assert.NoError(t, idx.index.AddDocument(ctx, models.Document{ID: "6", Text: "memory only"}))
+	report, err = idx.Verify(RepairMemory)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Repaired)
+	report, err = idx.Verify(RepairNone)
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent())
+	results, err := idx.Search(ctx, "team=a")
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "changed on disk", results[0].Text)
+	}
+	count, _ := idx.Count()
+	assert.Equal(t, 4, count)
+
+	_, err = idx.Verify("both")
+	assert.ErrorIs(t, err, ErrUnknownRepair)
+}
@@ -0,0 +1,125 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Warmer is implemented by indexes that can rebuild the structures derived from their documents before
+// serving searches, e.g. on daemon start
+type Warmer interface {
+	// Rebuilds the ACL bitmaps, metadata catalog and postings, dropping what removals left behind
+	WarmUp(ctx context.Context) error
+}
+
+// WarmUp rebuilds the ACL bitmaps, the metadata catalog and values and the lowercase fields from the
+// documents
+func (idx *SimpleIndex) WarmUp(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	acl, catalog, values := newACLBitmaps(), make(metaCatalog), newValueIndex()
+	lowered := make(map[string]loweredDocument, len(idx.documents))
+	for id, doc := range idx.documents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		acl.add(doc)
+		catalog.add(doc)
+		values.add(doc)
+		lowered[id] = lowerDocument(doc)
+	}
+	idx.acl, idx.catalog, idx.values, idx.lowered = acl, catalog, values, lowered
+	return nil
+}
+
+// WarmUp rebuilds the structures derived from the documents held in memory
+func (p *PersistedSimpleIndex) WarmUp(ctx context.Context) error {
+	return p.index.WarmUp(ctx)
+}
+
+// WarmUp rebuilds the stored documents' structures and copies the postings, so the memory of the
+// terms and documents removed since they were built is released
+func (idx *InvertedIndex) WarmUp(ctx context.Context) error {
+	if err := idx.store.WarmUp(ctx); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	postings := make(map[string]map[string]int, len(idx.postings))
+	for term, docs := range idx.postings {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		copied := make(map[string]int, len(docs))
+		for id, tf := range docs {
+			copied[id] = tf
+		}
+		postings[term] = copied
+	}
+	idx.postings = postings
+	return nil
+}
+
+// WarmUp rebuilds the structures derived from the stored documents
+func (idx *VectorIndex) WarmUp(ctx context.Context) error {
+	return idx.store.WarmUp(ctx)
+}
+
+// progressInterval is how often documents loaded from a database log their progress
+const progressInterval = 5 * time.Second
+
+// storedDocument is a document of a database, not yet decoded
+type storedDocument struct {
+	key, value []byte
+}
+
+// decodeDocuments decodes the documents of a database with one worker per CPU, logging progress
+func decodeDocuments(stored []storedDocument) ([]models.Document, error) {
+	docs := make([]models.Document, len(stored))
+	workers := max(1, min(runtime.GOMAXPROCS(0), len(stored)))
+	errs := make([]error, workers)
+	var decoded atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				log.Info().Msgf("Decoded %d of %d documents", decoded.Load(), len(stored))
+			case <-done:
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Each worker decodes every workers-th document
+			for i := w; i < len(stored) && errs[w] == nil; i += workers {
+				if err := json.Unmarshal(stored[i].value, &docs[i]); err != nil {
+					errs[w] = fmt.Errorf("failed to unmarshal document %s: %w", stored[i].key, err)
+				}
+				decoded.Add(1)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(done)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,53 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvertedIndex_WarmUp(t *testing.T) {
+	ctx := context.Background()
+	idx := NewInvertedIndex(nil)
+	assert.NoError(t, idx.AddDocuments(ctx, []models.Document{
+		{ID: "1", Text: "quarterly report", Meta: map[string]string{"team": "finance"}, AllowedPrincipals: []string{"alice"}},
+		{ID: "2", Text: "annual report", Meta: map[string]string{"team": "legal"}, AllowedPrincipals: []string{"bob"}},
+	}))
+	assert.NoError(t, idx.DeleteDocument(ctx, "1"))
+	assert.NoError(t, idx.WarmUp(ctx))
+
+	assert.Equal(t, 0, len(idx.store.acl.free), "ordinals are compacted")
+	assert.Equal(t, []Dimension{{Name: "team", Documents: 1, Values: 1}}, idx.ListDimensions(ctx, nil))
+	results, err := idx.Search(WithPrincipals(ctx, []string{"bob"}), "report")
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	results, err = idx.Search(WithPrincipals(ctx, []string{"alice"}), "report")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, idx.WarmUp(cancelled), context.Canceled)
+}
+
+func TestDecodeDocuments(t *testing.T) {
+	stored := make([]storedDocument, 100)
+	for i := range stored {
+		stored[i] = storedDocument{key: []byte(fmt.Sprint(i)), value: []byte(fmt.Sprintf(`{"id":"%d"}`, i))}
+	}
+	docs, err := decodeDocuments(stored)
+	assert.NoError(t, err)
+	assert.Len(t, docs, 100)
+	assert.Equal(t, "42", docs[42].ID)
+
+	stored[7].value = []byte("{")
+	_, err = decodeDocuments(stored)
+	assert.ErrorContains(t, err, "document 7")
+
+	docs, err = decodeDocuments(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, docs)
+}
@@ -0,0 +1,193 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+/**
+ * Write failures: the async worker retries a failed database write with exponential backoff before giving
+ * up on it. Writes given up on are counted, passed to the failure handler and fail health checks until a
+ * later write succeeds; the documents stay in memory, and Verify can repair the database from it.
+ **/
+
+const (
+	defaultWriteRetries      = 3                     // Retries of a failed database write
+	defaultWriteRetryBackoff = 50 * time.Millisecond // Delay before the first retry, doubled for each next one
+)
+
+// WriteFailure is a database write the async worker gave up on
+type WriteFailure struct {
+	Operation string   // e.g. "add_documents"
+	IDs       []string // IDs of the documents written or deleted
+	Attempts  int
+	Err       error
+	Time      time.Time
+}
+
+// PersistenceStats counts the database writes of the async worker that failed
+type PersistenceStats struct {
+	Failed      int64     // Writes given up on
+	Retried     int64     // Retries of failed writes
+	LastError   string    // Error of the last write given up on ("": none)
+	LastFailure time.Time // Time of the last write given up on (zero: none)
+}
+
+// PersistenceReporter is implemented by indexes that persist their writes asynchronously
+type PersistenceReporter interface {
+	// Returns the counts of failed database writes
+	PersistenceStats() PersistenceStats
+}
+
+// writeFailures tracks the failed writes of the async worker
+type writeFailures struct {
+	mu      sync.Mutex
+	stats   PersistenceStats
+	failing bool // Set when the last write was given up on
+	handler func(WriteFailure)
+	retries int
+	backoff time.Duration
+}
+
+// SetFailureHandler sets a function called with each database write the async worker gives up on (nil:
+// none). It runs on the worker, which waits for it, so it should return quickly.
+func (p *PersistedSimpleIndex) SetFailureHandler(handler func(WriteFailure)) {
+	p.failures.mu.Lock()
+	defer p.failures.mu.Unlock()
+	p.failures.handler = handler
+}
+
+// SetWriteRetries sets how often a failed database write is retried and the delay before the first retry,
+// doubled for each next one
+func (p *PersistedSimpleIndex) SetWriteRetries(retries int, backoff time.Duration) {
+	p.failures.mu.Lock()
+	defer p.failures.mu.Unlock()
+	p.failures.retries = retries
+	p.failures.backoff = backoff
+}
+
+// PersistenceStats returns the counts of failed database writes
+func (p *PersistedSimpleIndex) PersistenceStats() PersistenceStats {
+	p.failures.mu.Lock()
+	defer p.failures.mu.Unlock()
+	return p.failures.stats
+}
+
+// failingWrites returns an error if the last database write was given up on
+func (p *PersistedSimpleIndex) failingWrites() error {
+	p.failures.mu.Lock()
+	defer p.failures.mu.Unlock()
+	if !p.failures.failing {
+		return nil
+	}
+	return fmt.Errorf("async database writes are failing: %d given up on, last: %s", p.failures.stats.Failed, p.failures.stats.LastError)
+}
+
+// persistWrite applies a write operation to the database unless it was applied already, retrying it with
+// backoff, and records whether it was given up on
+func (p *PersistedSimpleIndex) persistWrite(op dbOperation) error {
+	if op.id != 0 && op.id <= p.lastApplied.Load() {
+		log.Debug().Msgf("Skipped async %s %d: already applied", op.opType, op.id)
+		return nil
+	}
+	p.failures.mu.Lock()
+	retries, backoff := p.failures.retries, p.failures.backoff
+	p.failures.mu.Unlock()
+
+	err := p.applyWrite(op)
+	attempts := 1
+	for ; err != nil && attempts <= retries; attempts++ {
+		delay := backoff << (attempts - 1)
+		log.Warn().Err(err).Msgf("Async %s failed, retrying in %s (%d of %d)", op.opType, delay, attempts, retries)
+		p.failures.mu.Lock()
+		p.failures.stats.Retried++
+		p.failures.mu.Unlock()
+		time.Sleep(delay)
+		err = p.applyWrite(op)
+	}
+
+	p.failures.mu.Lock()
+	p.failures.failing = err != nil
+	if err == nil {
+		p.failures.mu.Unlock()
+		if op.id != 0 {
+			p.lastApplied.Store(op.id)
+			p.checkpointWAL()
+		}
+		return nil
+	}
+	failure := WriteFailure{Operation: op.opType, IDs: operationIDs(op), Attempts: attempts, Err: err, Time: time.Now()}
+	p.failures.stats.Failed++
+	p.failures.stats.LastError = err.Error()
+	p.failures.stats.LastFailure = failure.Time
+	handler := p.failures.handler
+	p.failures.mu.Unlock()
+
+	log.Error().Err(err).Msgf("Gave up on async %s of %d documents after %d attempts", op.opType, len(failure.IDs), attempts)
+	if handler != nil {
+		handler(failure)
+	}
+	return err
+}
+
+// applyWrite applies a write operation to the database once
+func (p *PersistedSimpleIndex) applyWrite(op dbOperation) error {
+	switch op.opType {
+	case "add_document":
+		if doc, ok := op.data.(models.Document); ok {
+			return p.asyncAddDocument(op.id, doc)
+		}
+	case "add_documents":
+		if docs, ok := op.data.([]models.Document); ok {
+			return p.asyncAddDocuments(op.id, docs)
+		}
+	case "update_document":
+		if data, ok := op.data.(map[string]interface{}); ok {
+			if id, ok := data["id"].(string); ok {
+				if doc, ok := data["document"].(models.Document); ok {
+					return p.asyncUpdateDocument(op.id, id, doc)
+				}
+			}
+		}
+	case "delete_document":
+		if id, ok := op.data.(string); ok {
+			return p.asyncDeleteDocument(op.id, id)
+		}
+	case "delete_documents":
+		if ids, ok := op.data.([]string); ok {
+			return p.asyncDeleteDocuments(op.id, ids)
+		}
+	case "update_documents":
+		if docs, ok := op.data.([]models.Document); ok {
+			return p.asyncUpdateDocuments(op.id, docs)
+		}
+	}
+	return nil
+}
+
+// operationIDs returns the IDs of the documents of a write operation
+func operationIDs(op dbOperation) []string {
+	switch data := op.data.(type) {
+	case models.Document:
+		return []string{data.ID}
+	case []models.Document:
+		ids := make([]string, len(data))
+		for i, doc := range data {
+			ids[i] = doc.ID
+		}
+		return ids
+	case string:
+		return []string{data}
+	case []string:
+		return data
+	case map[string]interface{}:
+		if id, ok := data["id"].(string); ok {
+			return []string{id}
+		}
+	}
+	return nil
+}
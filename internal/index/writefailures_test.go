@@ -0,0 +1,67 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestPersistedSimpleIndex_WriteFailures(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	idx, err := NewPersistedSimpleIndexWithDatabase(dbPath)
+	assert.NoError(t, err)
+	defer idx.Close()
+	idx.SetWriteRetries(2, time.Millisecond)
+	var failures []WriteFailure
+	idx.SetFailureHandler(func(failure WriteFailure) { failures = append(failures, failure) })
+
+	ctx := WithDurability(context.Background(), DurabilityPersisted)
+	assert.NoError(t, idx.AddDocument(ctx, models.Document{ID: "1"}))
+	assert.Equal(t, PersistenceStats{}, idx.PersistenceStats())
+	assert.NoError(t, idx.HealthCheck())
+
+	// Writes to a closed database fail on every attempt and are given up on
+	assert.NoError(t, idx.db.Close())
+	assert.Error(t, idx.AddDocuments(ctx, []models.Document{{ID: "2"}, {ID: "3"}}))
+	stats := idx.PersistenceStats()
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(2), stats.Retried)
+	assert.Contains(t, stats.LastError, "database not open")
+	assert.False(t, stats.LastFailure.IsZero())
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "add_documents", failures[0].Operation)
+	assert.Equal(t, []string{"2", "3"}, failures[0].IDs)
+	assert.Equal(t, 3, failures[0].Attempts)
+	assert.ErrorIs(t, failures[0].Err, bbolt.ErrDatabaseNotOpen)
+	assert.ErrorContains(t, idx.failingWrites(), "1 given up on")
+
+	// A later write succeeding clears the failure, not the counts
+	db, err := bbolt.Open(dbPath, 0600, nil)
+	assert.NoError(t, err)
+	idx.mu.Lock()
+	idx.db = db
+	idx.mu.Unlock()
+	assert.NoError(t, idx.DeleteDocument(ctx, "1"))
+	assert.NoError(t, idx.HealthCheck())
+	assert.Equal(t, int64(1), idx.PersistenceStats().Failed)
+	assert.Len(t, failures, 1)
+}
+
+func TestPersistedSimpleIndex_WriteRetriesConfig(t *testing.T) {
+	cfg := map[string]interface{}{"db_path": filepath.Join(t.TempDir(), "index.db"), "write_retries": 5, "write_retry_backoff": "10ms"}
+	created, err := NewIndexFactory().Create("persisted", cfg)
+	assert.NoError(t, err)
+	defer created.Close()
+	idx := created.(*PersistedSimpleIndex)
+	assert.Equal(t, 5, idx.failures.retries)
+	assert.Equal(t, 10*time.Millisecond, idx.failures.backoff)
+
+	_, err = NewIndexFactory().Create("persisted", map[string]interface{}{"db_path": filepath.Join(t.TempDir(), "other.db"), "write_retries": -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package loaders
+
+/*
+Shared parsing of RFC 5322 email messages into documents, used by the mbox and IMAP loaders.
+*/
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+var (
+	htmlDropBlocks = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreaks     = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlTags       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines     = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+	headerDecoder  = new(mime.WordDecoder)
+)
+
+// messageBody holds the text extracted from a message's parts
+type messageBody struct {
+	plain       []string
+	html        []string
+	attachments []string
+}
+
+// parseMessage converts a raw RFC 5322 message into a document.
+// Plain-text parts are preferred for Text; HTML parts are converted to text when no plain part exists.
+func parseMessage(raw []byte, source string) (models.Document, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return models.Document{}, fmt.Errorf("failed to parse message from %s: %w", source, err)
+	}
+
+	body := &messageBody{}
+	if err := collectParts(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body, body); err != nil {
+		return models.Document{}, fmt.Errorf("failed to read message body from %s: %w", source, err)
+	}
+
+	text := strings.Join(body.plain, "\n")
+	if strings.TrimSpace(text) == "" {
+		htmlText := make([]string, 0, len(body.html))
+		for _, part := range body.html {
+			htmlText = append(htmlText, htmlToText(part))
+		}
+		text = strings.Join(htmlText, "\n")
+	}
+
+	meta := map[string]string{
+		"type":            "email",
+		"source":          source,
+		"subject":         decodeHeader(msg.Header.Get("Subject")),
+		"from":            formatAddresses(msg.Header, "From"),
+		"to":              formatAddresses(msg.Header, "To"),
+		"cc":              formatAddresses(msg.Header, "Cc"),
+		"messageId":       strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		"inReplyTo":       strings.Trim(msg.Header.Get("In-Reply-To"), "<> "),
+		"hasHtml":         strconv.FormatBool(len(body.html) > 0),
+		"hasAttachments":  strconv.FormatBool(len(body.attachments) > 0),
+		"attachmentCount": strconv.Itoa(len(body.attachments)),
+		"attachments":     strings.Join(body.attachments, ", "),
+		"fileSize":        strconv.Itoa(len(raw)),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		meta["date"] = date.Format(time.RFC3339)
+		meta["lastModified"] = meta["date"]
+	}
+
+	return models.Document{
+		ID:     makeID(source),
+		Text:   text,
+		Source: source,
+		Meta:   meta,
+	}, nil
+}
+
+// collectParts walks a (possibly multipart) body and gathers its text parts and attachment names
+func collectParts(contentType, transferEncoding, disposition string, r io.Reader, body *messageBody) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Messages without a valid Content-Type are plain text per RFC 2045
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = collectParts(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"), part, body)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if dispositionType, dispositionParams, err := mime.ParseMediaType(disposition); err == nil && dispositionType == "attachment" {
+		body.attachments = append(body.attachments, decodeHeader(dispositionParams["filename"]))
+		return nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		if name := params["name"]; name != "" {
+			body.attachments = append(body.attachments, decodeHeader(name))
+		}
+		return nil
+	}
+
+	content, err := io.ReadAll(decodeTransfer(transferEncoding, r))
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/html" {
+		body.html = append(body.html, string(content))
+	} else {
+		body.plain = append(body.plain, string(content))
+	}
+	return nil
+}
+
+// decodeTransfer undoes the Content-Transfer-Encoding of a part
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// newlineStripper removes line breaks so base64 content wrapped at 76 columns decodes cleanly
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	out := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[out] = b
+			out++
+		}
+	}
+	return out, err
+}
+
+// htmlToText reduces an HTML body to readable text
+func htmlToText(s string) string {
+	s = htmlDropBlocks.ReplaceAllString(s, "")
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// decodeHeader decodes RFC 2047 encoded words, falling back to the raw value
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// formatAddresses renders an address header as a comma-separated list
+func formatAddresses(header mail.Header, key string) string {
+	addresses, err := header.AddressList(key)
+	if err != nil {
+		return decodeHeader(header.Get(key))
+	}
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address.Name != "" {
+			formatted = append(formatted, address.Name+" <"+address.Address+">")
+		} else {
+			formatted = append(formatted, address.Address)
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
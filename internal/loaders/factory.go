@@ -0,0 +1,120 @@
+package loaders
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// LoaderConstructor builds a loader from the "config" map of a loader entry in the starter config
+type LoaderConstructor func(cfg map[string]interface{}) (CorpusLoader, error)
+
+// LoaderFactory instantiates loaders by type name
+type LoaderFactory struct {
+	constructors map[string]LoaderConstructor
+}
+
+// NewLoaderFactory creates a factory with the built-in loader types registered
+func NewLoaderFactory() *LoaderFactory {
+	f := &LoaderFactory{constructors: make(map[string]LoaderConstructor)}
+	f.RegisterType("FilesystemLoader", newFilesystemLoaderFromConfig)
+	f.RegisterType("MboxLoader", newMboxLoaderFromConfig)
+	f.RegisterType("IMAPLoader", newIMAPLoaderFromConfig)
+	return f
+}
+
+// RegisterType adds (or replaces) the constructor for a loader type
+func (f *LoaderFactory) RegisterType(typeName string, constructor LoaderConstructor) {
+	f.constructors[typeName] = constructor
+}
+
+// Types returns the registered loader type names, sorted
+func (f *LoaderFactory) Types() []string {
+	types := make([]string, 0, len(f.constructors))
+	for typeName := range f.constructors {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Create instantiates a loader of the given type from its config map
+func (f *LoaderFactory) Create(typeName string, cfg map[string]interface{}) (CorpusLoader, error) {
+	constructor, ok := f.constructors[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown loader type %s (known types: %v)", typeName, f.Types())
+	}
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	loader, err := constructor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", typeName, err)
+	}
+	log.Info().Msgf("LoaderFactory: created %s", typeName)
+	return loader, nil
+}
+
+func newFilesystemLoaderFromConfig(cfg map[string]interface{}) (CorpusLoader, error) {
+	root, err := config.String(cfg, "root", ".")
+	if err != nil {
+		return nil, err
+	}
+	include, err := config.Strings(cfg, "include")
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := config.Strings(cfg, "exclude")
+	if err != nil {
+		return nil, err
+	}
+
+	loader, err := NewFilesystemLoaderWithManifest(root, "")
+	if err != nil {
+		return nil, err
+	}
+	return loader.SetPatterns(include, exclude), nil
+}
+
+func newMboxLoaderFromConfig(cfg map[string]interface{}) (CorpusLoader, error) {
+	path, err := config.String(cfg, "path", "")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("missing required config key: path")
+	}
+	return NewMboxLoader(path), nil
+}
+
+func newIMAPLoaderFromConfig(cfg map[string]interface{}) (CorpusLoader, error) {
+	imapConfig := IMAPConfig{}
+	var err error
+	if imapConfig.Address, err = config.String(cfg, "address", ""); err != nil {
+		return nil, err
+	}
+	if imapConfig.Username, err = config.String(cfg, "username", ""); err != nil {
+		return nil, err
+	}
+	if imapConfig.Password, err = config.String(cfg, "password", ""); err != nil {
+		return nil, err
+	}
+	if imapConfig.Mailbox, err = config.String(cfg, "mailbox", "INBOX"); err != nil {
+		return nil, err
+	}
+	if imapConfig.TLS, err = config.Bool(cfg, "tls", true); err != nil {
+		return nil, err
+	}
+	if imapConfig.Insecure, err = config.Bool(cfg, "insecure", false); err != nil {
+		return nil, err
+	}
+
+	for key, value := range map[string]string{"address": imapConfig.Address, "username": imapConfig.Username} {
+		if value == "" {
+			return nil, fmt.Errorf("missing required config key: %s", key)
+		}
+	}
+	return NewIMAPLoader(imapConfig), nil
+}
@@ -0,0 +1,63 @@
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoaderFactory_CreateFilesystemLoaderWithPatterns(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0755))
+	writeFile(t, filepath.Join(root, ".git", "HEAD"), "ref")
+	writeFile(t, filepath.Join(root, "src", "main.go"), "package main")
+	writeFile(t, filepath.Join(root, "README.md"), "# readme")
+	writeFile(t, filepath.Join(root, "notes.txt"), "notes")
+
+	config := map[string]interface{}{
+		"root":    root,
+		"include": []interface{}{"*.go", "*.md"},
+		"exclude": []interface{}{".git"},
+	}
+	loader, err := NewLoaderFactory().Create("FilesystemLoader", config)
+	assert.NoError(t, err)
+
+	docs, err := loader.Load()
+	assert.NoError(t, err)
+	names := []string{}
+	for _, doc := range docs {
+		names = append(names, doc.Meta["filename"])
+	}
+	assert.ElementsMatch(t, []string{"main.go", "README.md"}, names)
+}
+
+func TestLoaderFactory_Errors(t *testing.T) {
+	factory := NewLoaderFactory()
+
+	_, err := factory.Create("NopeLoader", nil)
+	assert.Error(t, err)
+
+	_, err = factory.Create("MboxLoader", map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = factory.Create("FilesystemLoader", map[string]interface{}{"root": 42})
+	assert.Error(t, err)
+
+	_, err = factory.Create("IMAPLoader", map[string]interface{}{"address": "imap.example.com:993"})
+	assert.Error(t, err)
+}
+
+func TestLoaderFactory_RegisterType(t *testing.T) {
+	factory := NewLoaderFactory()
+	factory.RegisterType("Custom", func(config map[string]interface{}) (CorpusLoader, error) {
+		return NewFilesystemLoader("."), nil
+	})
+	assert.Contains(t, factory.Types(), "Custom")
+
+	loader, err := factory.Create("Custom", nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, loader)
+}
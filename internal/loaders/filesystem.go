@@ -5,6 +5,7 @@ Implementation of corpus loader for filesystem.
 */
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -25,6 +26,15 @@ const (
 
 type FilesystemLoader struct {
 	root string
+
+	// Optional glob patterns matched against file names and root-relative paths
+	include []string
+	exclude []string
+
+	// Incremental loading state (only used when a manifest path is set)
+	manifestPath string
+	manifest     *Manifest
+	pending      *Manifest
 }
 
 func NewFilesystemLoader(root string) *FilesystemLoader {
@@ -32,16 +42,114 @@ func NewFilesystemLoader(root string) *FilesystemLoader {
 	return &FilesystemLoader{root: root}
 }
 
+// NewFilesystemLoaderWithManifest creates a filesystem loader that tracks loaded files in a manifest
+// persisted at manifestPath, enabling incremental loads via LoadChanges.
+// An empty manifestPath keeps the manifest in memory only, which suits in-memory indexes.
+func NewFilesystemLoaderWithManifest(root, manifestPath string) (*FilesystemLoader, error) {
+	manifest := NewManifest()
+	if manifestPath != "" {
+		var err error
+		if manifest, err = LoadManifest(manifestPath); err != nil {
+			return nil, err
+		}
+	}
+	log.Info().Msgf("NewFilesystemLoaderWithManifest: %s (%d tracked files)", root, len(manifest.Entries))
+	return &FilesystemLoader{root: root, manifestPath: manifestPath, manifest: manifest}, nil
+}
+
+// SetPatterns restricts the loader to files matching any include pattern (all files when empty)
+// and skips files or directories matching any exclude pattern. It returns the loader for chaining.
+func (l *FilesystemLoader) SetPatterns(include, exclude []string) *FilesystemLoader {
+	l.include = include
+	l.exclude = exclude
+	return l
+}
+
+// skip reports whether path should be ignored according to the include/exclude patterns
+func (l *FilesystemLoader) skip(path string, info os.FileInfo) bool {
+	rel, err := filepath.Rel(l.root, path)
+	if err != nil {
+		rel = path
+	}
+	if rel == "." {
+		return false
+	}
+	if matchesAny(l.exclude, info.Name(), rel) {
+		return true
+	}
+	if info.IsDir() || len(l.include) == 0 {
+		return false
+	}
+	return !matchesAny(l.include, info.Name(), rel)
+}
+
+// matchesAny reports whether the name or the relative path matches any of the glob patterns
+func matchesAny(patterns []string, name, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *FilesystemLoader) Load() ([]models.Document, error) {
 	log.Info().Msgf("FilesystemLoader.Load from %s", l.root)
 	documents := []models.Document{}
 
-	err := filepath.Walk(l.root, func(path string, info os.FileInfo, err error) error {
+	err := l.walk(func(doc models.Document) error {
+		documents = append(documents, doc)
+		return nil
+	})
+
+	return documents, err
+}
+
+// Stream walks the root directory and emits each document as soon as it has been read.
+func (l *FilesystemLoader) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	log.Info().Msgf("FilesystemLoader.Stream from %s", l.root)
+	docs := make(chan models.Document, StreamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(docs)
+
+		err := l.walk(func(doc models.Document) error {
+			select {
+			case docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return docs, errs
+}
+
+// walk visits every file under the root and hands the resulting document to emit.
+func (l *FilesystemLoader) walk(emit func(models.Document) error) error {
+	return filepath.Walk(l.root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.Error().Msgf("FilesystemLoader.Load: %s", err)
 			return err
 		}
 
+		if l.skip(path, info) {
+			if info.IsDir() {
+				log.Info().Msgf("FilesystemLoader.Load: excluding directory: %s", path)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if info.IsDir() {
 			log.Info().Msgf("FilesystemLoader.Load: skipping directory: %s", path)
 			return nil
@@ -55,18 +163,8 @@ func (l *FilesystemLoader) Load() ([]models.Document, error) {
 
 		log.Info().Msgf("FilesystemLoader.Load: adding document: %s", path)
 
-		documents = append(documents, models.Document{
-			ID:     makeID(path),
-			Text:   string(content),
-			Source: path,
-			Meta:   getMeta(info, path, content),
-			Vector: getVector(path, info, content),
-		})
-
-		return nil
+		return emit(l.makeDocument(makeID(path), path, info, content))
 	})
-
-	return documents, err
 }
 
 // UUID
@@ -0,0 +1,167 @@
+package loaders
+
+/*
+Implementation of corpus loader for IMAP mailboxes.
+*/
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/rs/zerolog/log"
+)
+
+// IMAPConfig holds connection settings for an IMAP mailbox
+type IMAPConfig struct {
+	Address  string // host:port of the IMAP server
+	Username string
+	Password string
+	Mailbox  string // Mailbox to load (default: INBOX)
+	TLS      bool   // Connect over implicit TLS (port 993); otherwise upgrade with STARTTLS when offered
+	Insecure bool   // Skip TLS certificate verification (testing only)
+}
+
+// IMAPLoader loads every message of an IMAP mailbox as a document
+type IMAPLoader struct {
+	config IMAPConfig
+}
+
+// NewIMAPLoader creates a loader for the configured IMAP mailbox
+func NewIMAPLoader(config IMAPConfig) *IMAPLoader {
+	if config.Mailbox == "" {
+		config.Mailbox = "INBOX"
+	}
+	log.Info().Msgf("NewIMAPLoader: %s/%s", config.Address, config.Mailbox)
+	return &IMAPLoader{config: config}
+}
+
+// Load fetches all messages from the mailbox
+func (l *IMAPLoader) Load() ([]models.Document, error) {
+	log.Info().Msgf("IMAPLoader.Load from %s/%s", l.config.Address, l.config.Mailbox)
+	documents := []models.Document{}
+	err := l.each(context.Background(), func(doc models.Document) error {
+		documents = append(documents, doc)
+		return nil
+	})
+	return documents, err
+}
+
+// Stream emits messages as they are fetched from the server
+func (l *IMAPLoader) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document, StreamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(docs)
+
+		err := l.each(ctx, func(doc models.Document) error {
+			select {
+			case docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return docs, errs
+}
+
+// each connects, selects the mailbox read-only, and hands each fetched message to emit
+func (l *IMAPLoader) each(ctx context.Context, emit func(models.Document) error) error {
+	c, err := l.connect()
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	status, err := c.Select(l.config.Mailbox, true)
+	if err != nil {
+		return fmt.Errorf("failed to select mailbox %s: %w", l.config.Mailbox, err)
+	}
+	if status.Messages == 0 {
+		log.Info().Msgf("IMAPLoader: mailbox %s is empty", l.config.Mailbox)
+		return nil
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddRange(1, status.Messages)
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchUid, section.FetchItem()}
+
+	messages := make(chan *imap.Message, StreamBufferSize)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Fetch(seqset, items, messages)
+	}()
+
+	var emitErr error
+	for msg := range messages {
+		// Keep draining after a failure so the fetch goroutine can finish
+		if emitErr != nil || ctx.Err() != nil {
+			continue
+		}
+		body := msg.GetBody(section)
+		if body == nil {
+			continue
+		}
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			log.Warn().Msgf("IMAPLoader: failed to read message %d: %s", msg.Uid, err)
+			continue
+		}
+
+		source := fmt.Sprintf("imap://%s@%s/%s;UID=%d", l.config.Username, l.config.Address, l.config.Mailbox, msg.Uid)
+		doc, err := parseMessage(raw, source)
+		if err != nil {
+			log.Warn().Msgf("IMAPLoader: skipping message: %s", err)
+			continue
+		}
+		doc.Meta["mailbox"] = l.config.Mailbox
+		emitErr = emit(doc)
+	}
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("failed to fetch messages: %w", err)
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	return ctx.Err()
+}
+
+// connect dials the server and authenticates
+func (l *IMAPLoader) connect() (*client.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: l.config.Insecure}
+
+	var c *client.Client
+	var err error
+	if l.config.TLS {
+		c, err = client.DialTLS(l.config.Address, tlsConfig)
+	} else {
+		c, err = client.Dial(l.config.Address)
+		if err == nil {
+			if ok, _ := c.SupportStartTLS(); ok {
+				err = c.StartTLS(tlsConfig)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", l.config.Address, err)
+	}
+
+	if err := c.Login(l.config.Username, l.config.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to log in to %s: %w", l.config.Address, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,165 @@
+package loaders
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// IncrementalCorpusLoader is implemented by loaders that can report only what changed since the last load.
+type IncrementalCorpusLoader interface {
+	CorpusLoader
+	// LoadChanges compares the source against the last committed state and returns the differences.
+	LoadChanges(ctx context.Context) (models.ChangeSet, error)
+	// Commit records the state observed by the last LoadChanges call, once its changes have been applied.
+	Commit() error
+}
+
+// LoadChanges walks the root directory and compares each file against the manifest.
+// Files whose size and modification time are unchanged are skipped without being read;
+// files that were touched but have the same checksum are not reported as modified.
+// Files tracked in the manifest that no longer exist are reported as deletions.
+func (l *FilesystemLoader) LoadChanges(ctx context.Context) (models.ChangeSet, error) {
+	if l.manifest == nil {
+		return models.ChangeSet{}, fmt.Errorf("filesystem loader for %s has no manifest", l.root)
+	}
+
+	changes := models.ChangeSet{}
+	pending := NewManifest()
+
+	err := filepath.Walk(l.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Error().Msgf("FilesystemLoader.LoadChanges: %s", err)
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if l.skip(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || l.isManifestFile(path) {
+			return nil
+		}
+
+		previous, tracked := l.manifest.Entries[path]
+		if tracked && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+			pending.Entries[path] = previous
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Error().Msgf("FilesystemLoader.LoadChanges: %s", err)
+			return err
+		}
+
+		entry := ManifestEntry{
+			Checksum: checksum(content),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+		}
+
+		if tracked {
+			entry.DocumentID = previous.DocumentID
+			pending.Entries[path] = entry
+			if previous.Checksum == entry.Checksum {
+				return nil
+			}
+			log.Info().Msgf("FilesystemLoader.LoadChanges: modified document: %s", path)
+			changes.Modified = append(changes.Modified, l.makeDocument(entry.DocumentID, path, info, content))
+			return nil
+		}
+
+		entry.DocumentID = makeID(path)
+		pending.Entries[path] = entry
+		log.Info().Msgf("FilesystemLoader.LoadChanges: new document: %s", path)
+		changes.Added = append(changes.Added, l.makeDocument(entry.DocumentID, path, info, content))
+		return nil
+	})
+	if err != nil {
+		return models.ChangeSet{}, err
+	}
+
+	for path, entry := range l.manifest.Entries {
+		if _, exists := pending.Entries[path]; !exists {
+			log.Info().Msgf("FilesystemLoader.LoadChanges: removed document: %s", path)
+			changes.Deleted = append(changes.Deleted, entry.DocumentID)
+		}
+	}
+
+	l.pending = pending
+	log.Info().Msgf("FilesystemLoader.LoadChanges: %d added, %d modified, %d deleted",
+		len(changes.Added), len(changes.Modified), len(changes.Deleted))
+	return changes, nil
+}
+
+// Commit persists the manifest observed by the last LoadChanges call
+func (l *FilesystemLoader) Commit() error {
+	if l.pending == nil {
+		return nil
+	}
+	if l.manifestPath != "" {
+		if err := l.pending.Save(l.manifestPath); err != nil {
+			return err
+		}
+	}
+	l.manifest = l.pending
+	l.pending = nil
+	return nil
+}
+
+// ResetManifest forgets all tracked files so the next LoadChanges reports every file as new.
+// Use it when the target index starts empty (e.g. an in-memory index after a restart).
+func (l *FilesystemLoader) ResetManifest() {
+	l.manifest = NewManifest()
+	l.pending = nil
+}
+
+// Invalidate keeps the tracked files and their document IDs but forgets their state, so the next
+// LoadChanges reports every tracked file as modified. Use it to rebuild the documents of an index
+// that must be reindexed in place (e.g. after the extracted features changed).
+func (l *FilesystemLoader) Invalidate() {
+	if l.manifest == nil {
+		return
+	}
+	for path, entry := range l.manifest.Entries {
+		l.manifest.Entries[path] = ManifestEntry{DocumentID: entry.DocumentID}
+	}
+	l.pending = nil
+}
+
+// makeDocument builds a document for a file with a caller-provided ID
+func (l *FilesystemLoader) makeDocument(id, path string, info os.FileInfo, content []byte) models.Document {
+	return models.Document{
+		ID:     id,
+		Text:   string(content),
+		Source: path,
+		Meta:   getMeta(info, path, content),
+		Vector: getVector(path, info, content),
+	}
+}
+
+// isManifestFile reports whether path is the loader's own manifest (or its temp file)
+func (l *FilesystemLoader) isManifestFile(path string) bool {
+	if l.manifestPath == "" {
+		return false
+	}
+	path = filepath.Clean(path)
+	manifest := filepath.Clean(l.manifestPath)
+	return path == manifest || path == manifest+".tmp"
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+package loaders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestFilesystemLoader_LoadChanges(t *testing.T) {
+	root := t.TempDir()
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	writeFile(t, filepath.Join(root, "a.txt"), "alpha")
+	writeFile(t, filepath.Join(root, "b.txt"), "beta")
+
+	loader, err := NewFilesystemLoaderWithManifest(root, manifestPath)
+	assert.NoError(t, err)
+
+	// First run reports everything as new
+	changes, err := loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, changes.Added, 2)
+	assert.Empty(t, changes.Modified)
+	assert.Empty(t, changes.Deleted)
+	assert.NoError(t, loader.Commit())
+
+	ids := map[string]string{}
+	for _, doc := range changes.Added {
+		ids[filepath.Base(doc.Source)] = doc.ID
+	}
+
+	// Reopen from the persisted manifest: nothing changed
+	loader, err = NewFilesystemLoaderWithManifest(root, manifestPath)
+	assert.NoError(t, err)
+	changes, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changes.IsEmpty())
+	assert.NoError(t, loader.Commit())
+
+	// Modify a, remove b, add c
+	writeFile(t, filepath.Join(root, "a.txt"), "alpha, revised")
+	future := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(root, "a.txt"), future, future))
+	assert.NoError(t, os.Remove(filepath.Join(root, "b.txt")))
+	writeFile(t, filepath.Join(root, "c.txt"), "gamma")
+
+	changes, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, changes.Added, 1)
+	assert.Equal(t, "c.txt", filepath.Base(changes.Added[0].Source))
+	assert.Len(t, changes.Modified, 1)
+	assert.Equal(t, ids["a.txt"], changes.Modified[0].ID)
+	assert.Equal(t, "alpha, revised", changes.Modified[0].Text)
+	assert.Equal(t, []string{ids["b.txt"]}, changes.Deleted)
+}
+
+func TestFilesystemLoader_LoadChanges_TouchedButUnchanged(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.txt")
+	writeFile(t, path, "alpha")
+
+	loader, err := NewFilesystemLoaderWithManifest(root, filepath.Join(t.TempDir(), "manifest.json"))
+	assert.NoError(t, err)
+	_, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, loader.Commit())
+
+	future := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(path, future, future))
+
+	changes, err := loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changes.IsEmpty())
+}
+
+func TestFilesystemLoader_LoadChanges_UncommittedIsReplayed(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "alpha")
+
+	loader, err := NewFilesystemLoaderWithManifest(root, filepath.Join(t.TempDir(), "manifest.json"))
+	assert.NoError(t, err)
+	changes, err := loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, changes.Added, 1)
+
+	// Without a commit the same changes are reported again
+	changes, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, changes.Added, 1)
+}
+
+func TestFilesystemLoader_Invalidate(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "alpha")
+
+	loader, err := NewFilesystemLoaderWithManifest(root, filepath.Join(t.TempDir(), "manifest.json"))
+	assert.NoError(t, err)
+	changes, err := loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, loader.Commit())
+	id := changes.Added[0].ID
+
+	// Unchanged files are reported as modified, under the same ID
+	loader.Invalidate()
+	changes, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, changes.Added)
+	assert.Len(t, changes.Modified, 1)
+	assert.Equal(t, id, changes.Modified[0].ID)
+	assert.NoError(t, loader.Commit())
+
+	changes, err = loader.LoadChanges(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, changes.IsEmpty())
+}
@@ -1,6 +1,8 @@
 package loaders
 
 import (
+	"context"
+
 	"github.com/aawadall/bit-scout/internal/models"
 )
 
@@ -8,8 +10,50 @@ import (
 Interface for corpus loader, and registry of loaders.
 */
 
+// StreamBufferSize is the channel buffer used when streaming documents out of a loader.
+const StreamBufferSize = 64
+
 // CorpusLoader defines the interface for loading documents from a source.
 type CorpusLoader interface {
 	// Load loads documents.
 	Load() ([]models.Document, error)
 }
+
+// StreamingCorpusLoader is implemented by loaders that can emit documents as they are read,
+// instead of materializing the whole corpus in memory.
+type StreamingCorpusLoader interface {
+	CorpusLoader
+	// Stream emits documents on the returned channel until the source is exhausted or ctx is cancelled.
+	// The document channel is closed first, then the error channel; at most one error is sent.
+	Stream(ctx context.Context) (<-chan models.Document, <-chan error)
+}
+
+// Stream returns a document stream for any CorpusLoader. Loaders implementing StreamingCorpusLoader
+// stream natively; others are loaded in full and then replayed onto the channel.
+func Stream(ctx context.Context, loader CorpusLoader) (<-chan models.Document, <-chan error) {
+	if streaming, ok := loader.(StreamingCorpusLoader); ok {
+		return streaming.Stream(ctx)
+	}
+
+	docs := make(chan models.Document, StreamBufferSize)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(docs)
+
+		loaded, err := loader.Load()
+		if err != nil {
+			errs <- err
+			return
+		}
+		for _, doc := range loaded {
+			select {
+			case docs <- doc:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return docs, errs
+}
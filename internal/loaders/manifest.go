@@ -0,0 +1,75 @@
+package loaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestEntry records what was loaded from a single source path.
+type ManifestEntry struct {
+	DocumentID string    `json:"document_id"`
+	Checksum   string    `json:"checksum"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+}
+
+// Manifest maps source paths to the state they were in when last loaded.
+// It is persisted between runs so loaders can skip unchanged sources.
+type Manifest struct {
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// NewManifest creates an empty manifest
+func NewManifest() *Manifest {
+	return &Manifest{Entries: make(map[string]ManifestEntry)}
+}
+
+// LoadManifest reads a manifest from disk. A missing file yields an empty manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewManifest(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	manifest := NewManifest()
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if manifest.Entries == nil {
+		manifest.Entries = make(map[string]ManifestEntry)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest to disk atomically (write to a temp file, then rename)
+func (m *Manifest) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Clone returns a deep copy of the manifest
+func (m *Manifest) Clone() *Manifest {
+	clone := NewManifest()
+	for path, entry := range m.Entries {
+		clone.Entries[path] = entry
+	}
+	return clone
+}
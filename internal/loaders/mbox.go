@@ -0,0 +1,132 @@
+package loaders
+
+/*
+Implementation of corpus loader for mbox mailbox files.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// MboxLoader loads every message of an mbox file as a document
+type MboxLoader struct {
+	path string
+}
+
+// NewMboxLoader creates a loader for the mbox file at path
+func NewMboxLoader(path string) *MboxLoader {
+	log.Info().Msgf("NewMboxLoader: %s", path)
+	return &MboxLoader{path: path}
+}
+
+// Load reads all messages from the mbox file
+func (l *MboxLoader) Load() ([]models.Document, error) {
+	log.Info().Msgf("MboxLoader.Load from %s", l.path)
+	documents := []models.Document{}
+	err := l.each(func(doc models.Document) error {
+		documents = append(documents, doc)
+		return nil
+	})
+	return documents, err
+}
+
+// Stream emits messages as they are parsed from the mbox file
+func (l *MboxLoader) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document, StreamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(docs)
+
+		err := l.each(func(doc models.Document) error {
+			select {
+			case docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return docs, errs
+}
+
+// each splits the mbox file into messages and hands each parsed message to emit.
+// Messages that fail to parse are logged and skipped.
+func (l *MboxLoader) each(emit func(models.Document) error) error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox %s: %w", l.path, err)
+	}
+	defer file.Close()
+
+	index := 0
+	return splitMbox(file, func(raw []byte) error {
+		source := fmt.Sprintf("%s#%d", l.path, index)
+		index++
+
+		doc, err := parseMessage(raw, source)
+		if err != nil {
+			log.Warn().Msgf("MboxLoader: skipping message: %s", err)
+			return nil
+		}
+		doc.Meta["mailbox"] = l.path
+		return emit(doc)
+	})
+}
+
+// splitMbox separates an mbox stream into raw messages. A message starts at a "From " line;
+// mboxrd-style ">From " quoting inside bodies is undone.
+func splitMbox(r io.Reader, emit func([]byte) error) error {
+	reader := bufio.NewReader(r)
+	var current bytes.Buffer
+	started := false
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		message := bytes.TrimRight(current.Bytes(), "\r\n")
+		current.Reset()
+		if len(message) == 0 {
+			return nil
+		}
+		return emit(append([]byte(nil), message...))
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case bytes.HasPrefix(line, []byte("From ")):
+				if err := flush(); err != nil {
+					return err
+				}
+				started = true
+			case started:
+				if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+					line = line[1:]
+				}
+				current.Write(line)
+			}
+		}
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read mbox: %w", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package loaders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMbox = `From alice@example.com Mon Jan  1 10:00:00 2024
+From: Alice <alice@example.com>
+To: bob@example.com
+Subject: =?UTF-8?Q?Caf=C3=A9_plans?=
+Date: Mon, 01 Jan 2024 10:00:00 +0000
+Message-ID: <one@example.com>
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Meet at the caf=C3=A9 at noon.
+>From the office we can walk.
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>Meet at the caf&eacute; at noon.</p>
+--inner--
+--outer
+Content-Type: application/pdf; name="menu.pdf"
+Content-Disposition: attachment; filename="menu.pdf"
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQK
+--outer--
+
+From bob@example.com Tue Jan  2 11:00:00 2024
+From: bob@example.com
+To: Alice <alice@example.com>
+Subject: Re: plans
+Date: Tue, 02 Jan 2024 11:00:00 +0000
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PGh0bWw+PGJvZHk+PHA+U291bmRzIGdvb2Q8L3A+PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pjwv
+Ym9keT48L2h0bWw+
+`
+
+func TestMboxLoader_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.mbox")
+	assert.NoError(t, os.WriteFile(path, []byte(testMbox), 0644))
+
+	docs, err := NewMboxLoader(path).Load()
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+
+	first := docs[0]
+	assert.Equal(t, "email", first.Meta["type"])
+	assert.Equal(t, "Café plans", first.Meta["subject"])
+	assert.Equal(t, "Alice <alice@example.com>", first.Meta["from"])
+	assert.Equal(t, "bob@example.com", first.Meta["to"])
+	assert.Equal(t, "one@example.com", first.Meta["messageId"])
+	assert.Equal(t, "2024-01-01T10:00:00Z", first.Meta["date"])
+	assert.Equal(t, "true", first.Meta["hasAttachments"])
+	assert.Equal(t, "menu.pdf", first.Meta["attachments"])
+	assert.Contains(t, first.Text, "Meet at the café at noon.")
+	assert.Contains(t, first.Text, "\nFrom the office")
+	assert.Equal(t, path+"#0", first.Source)
+
+	second := docs[1]
+	assert.Equal(t, "Re: plans", second.Meta["subject"])
+	assert.Equal(t, "true", second.Meta["hasHtml"])
+	assert.Equal(t, "Sounds good", strings.TrimSpace(second.Text))
+}
+
+func TestMboxLoader_Stream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inbox.mbox")
+	assert.NoError(t, os.WriteFile(path, []byte(testMbox), 0644))
+
+	docs, errs := NewMboxLoader(path).Stream(context.Background())
+	count := 0
+	for range docs {
+		count++
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, 2, count)
+}
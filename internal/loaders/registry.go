@@ -1,7 +1,10 @@
 package loaders
 
 import (
+	"context"
 	"fmt"
+	"sync"
+
 	"github.com/aawadall/bit-scout/internal/models"
 	"github.com/rs/zerolog/log"
 )
@@ -22,6 +25,12 @@ func (r *LoaderRegistry) Register(name string, loader CorpusLoader) {
 	r.loaders[name] = loader
 }
 
+// Unregister removes a CorpusLoader by name.
+func (r *LoaderRegistry) Unregister(name string) {
+	log.Info().Msgf("UnregisterLoader: %s", name)
+	delete(r.loaders, name)
+}
+
 // Get retrieves a registered CorpusLoader by name.
 func (r *LoaderRegistry) Get(name string) (CorpusLoader, bool) {
 	loader, ok := r.loaders[name]
@@ -53,3 +62,38 @@ func (r *LoaderRegistry) LoadAll() ([]models.Document, error) {
 	}
 	return allDocs, nil
 }
+
+// StreamAll streams documents from all registered loaders concurrently onto a single channel.
+// A failing loader is reported on the error channel (wrapped with its name) without stopping the others.
+func (r *LoaderRegistry) StreamAll(ctx context.Context) (<-chan models.Document, <-chan error) {
+	out := make(chan models.Document, StreamBufferSize)
+	errs := make(chan error, len(r.loaders))
+
+	var wg sync.WaitGroup
+	for name, loader := range r.loaders {
+		wg.Add(1)
+		go func(name string, loader CorpusLoader) {
+			defer wg.Done()
+			docs, loaderErrs := Stream(ctx, loader)
+			for doc := range docs {
+				select {
+				case out <- doc:
+				case <-ctx.Done():
+					// Keep draining so the loader goroutine can observe cancellation and exit
+				}
+			}
+			if err := <-loaderErrs; err != nil {
+				log.Error().Msgf("StreamAll: loader '%s' failed: %s", name, err)
+				errs <- fmt.Errorf("loader %s: %w", name, err)
+			}
+		}(name, loader)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+		close(errs)
+	}()
+
+	return out, errs
+}
@@ -0,0 +1,13 @@
+package models
+
+// ChangeSet describes how a source differs from what was previously loaded from it.
+type ChangeSet struct {
+	Added    []Document // Documents whose source is new
+	Modified []Document // Documents whose source content changed since the last load
+	Deleted  []string   // IDs of documents whose source no longer exists
+}
+
+// IsEmpty reports whether the change set contains no changes
+func (c ChangeSet) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Deleted) == 0
+}
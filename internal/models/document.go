@@ -7,11 +7,43 @@ import (
 
 // Document represents a single document loaded from a corpus source.
 type Document struct {
-	ID     string
-	Text   string
-	Source string            // Source of the document (e.g., file path, URL)
-	Vector []float64         // Vector representation of the document
-	Meta   map[string]string // Optional metadata (e.g., filename, tags)
+	ID     string            `json:"id"`
+	Text   string            `json:"text"`
+	Source string            `json:"source"`           // Source of the document (e.g., file path, URL)
+	Vector []float64         `json:"vector,omitempty"` // Vector representation of the document
+	Meta   map[string]string `json:"meta,omitempty"`   // Optional metadata (e.g., filename, tags)
+	// AllowedPrincipals restricts the callers who may find the document to those with one of these
+	// principals (e.g. a user, role or group); empty: every caller
+	AllowedPrincipals []string `json:"allowed_principals,omitempty"`
+}
+
+// VisibleTo reports whether a caller with the given principals may see the document
+func (d *Document) VisibleTo(principals []string) bool {
+	if len(d.AllowedPrincipals) == 0 {
+		return true
+	}
+	for _, allowed := range d.AllowedPrincipals {
+		for _, principal := range principals {
+			if allowed == principal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Field returns a field of the document by name: "id", "text", "source" or a metadata key. The id, text
+// and source fields win over metadata keys of the same name.
+func (d *Document) Field(name string) string {
+	switch name {
+	case "id":
+		return d.ID
+	case "text":
+		return d.Text
+	case "source":
+		return d.Source
+	}
+	return d.Meta[name]
 }
 
 // Print the document
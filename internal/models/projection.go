@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Projection selects the fields of the documents returned by searches, e.g. to leave out large texts.
+// Fields are "text", "source", "vector", "allowed_principals", "meta" (all metadata) or a metadata key.
+// The ID is always returned.
+type Projection struct {
+	Include []string // Fields to return (empty: all)
+	Exclude []string // Fields to leave out, applied after Include
+}
+
+// ParseProjection reads a list of fields to include, with the fields to exclude prefixed with "-"
+// (e.g. "source", "filename", "-text")
+func ParseProjection(fields []string) (Projection, error) {
+	var p Projection
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		name, exclude := strings.CutPrefix(field, "-")
+		switch {
+		case name == "":
+			return Projection{}, fmt.Errorf("empty field name in %q", strings.Join(fields, ","))
+		case name == "id" && exclude:
+			return Projection{}, fmt.Errorf("the id is always returned, it cannot be excluded")
+		case exclude:
+			p.Exclude = append(p.Exclude, name)
+		default:
+			p.Include = append(p.Include, name)
+		}
+	}
+	return p, nil
+}
+
+// IsZero reports whether the projection returns whole documents
+func (p Projection) IsZero() bool {
+	return len(p.Include) == 0 && len(p.Exclude) == 0
+}
+
+// Merge returns a projection with the included and excluded fields of p and other
+func (p Projection) Merge(other Projection) Projection {
+	return Projection{Include: append(append([]string{}, p.Include...), other.Include...), Exclude: append(append([]string{}, p.Exclude...), other.Exclude...)}
+}
+
+// Apply returns the projected copy of doc; doc itself (and its metadata) is left unchanged
+func (p Projection) Apply(doc Document) Document {
+	if p.IsZero() {
+		return doc
+	}
+	out := doc
+	if len(p.Include) > 0 {
+		out = Document{ID: doc.ID}
+		for _, field := range p.Include {
+			switch field {
+			case "id":
+			case "text":
+				out.Text = doc.Text
+			case "source":
+				out.Source = doc.Source
+			case "vector":
+				out.Vector = doc.Vector
+			case "allowed_principals":
+				out.AllowedPrincipals = doc.AllowedPrincipals
+			case "meta":
+				out.Meta = copyMeta(doc.Meta, out.Meta)
+			default:
+				if value, ok := doc.Meta[field]; ok {
+					out.Meta = copyMeta(map[string]string{field: value}, out.Meta)
+				}
+			}
+		}
+	} else if len(doc.Meta) > 0 {
+		out.Meta = copyMeta(doc.Meta, nil)
+	}
+	for _, field := range p.Exclude {
+		switch field {
+		case "text":
+			out.Text = ""
+		case "source":
+			out.Source = ""
+		case "vector":
+			out.Vector = nil
+		case "allowed_principals":
+			out.AllowedPrincipals = nil
+		case "meta":
+			out.Meta = nil
+		default:
+			delete(out.Meta, field)
+		}
+	}
+	return out
+}
+
+// copyMeta adds the entries of meta to into, creating it if needed
+func copyMeta(meta, into map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return into
+	}
+	if into == nil {
+		into = make(map[string]string, len(meta))
+	}
+	for key, value := range meta {
+		into[key] = value
+	}
+	return into
+}
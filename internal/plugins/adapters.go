@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/pkg/plugin"
+)
+
+// ExtractTimeout bounds a single call to a feature extractor plugin
+const ExtractTimeout = 10 * time.Second
+
+// registerLoaderType adds a plugin loader to the factory; every loader created from it
+// passes its starter config map to the plugin on each load
+func registerLoaderType(factory *loaders.LoaderFactory, typeName string, impl plugin.Loader) {
+	factory.RegisterType(typeName, func(cfg map[string]interface{}) (loaders.CorpusLoader, error) {
+		return &loaderAdapter{name: typeName, loader: impl, config: cfg}, nil
+	})
+}
+
+// loaderAdapter exposes a plugin loader as a loaders.StreamingCorpusLoader
+type loaderAdapter struct {
+	name   string
+	loader plugin.Loader
+	config map[string]interface{}
+}
+
+func (a *loaderAdapter) Load() ([]models.Document, error) {
+	var docs []models.Document
+	err := a.loader.Load(context.Background(), a.config, func(doc plugin.Document) error {
+		docs = append(docs, toModel(doc))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin loader %s: %w", a.name, err)
+	}
+	return docs, nil
+}
+
+func (a *loaderAdapter) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document, loaders.StreamBufferSize)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(docs)
+
+		err := a.loader.Load(ctx, a.config, func(doc plugin.Document) error {
+			select {
+			case docs <- toModel(doc):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- fmt.Errorf("plugin loader %s: %w", a.name, err)
+		}
+	}()
+	return docs, errs
+}
+
+// extractorAdapter exposes a plugin feature extractor as a ports.FeatureExtractorPort
+type extractorAdapter struct {
+	name      string
+	extractor plugin.Extractor
+}
+
+func (a *extractorAdapter) ExtractFeatures(doc interface{}) (map[string]interface{}, error) {
+	d, ok := doc.(models.Document)
+	if !ok {
+		return nil, fmt.Errorf("expected models.Document, got %T", doc)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ExtractTimeout)
+	defer cancel()
+	features, err := a.extractor.Extract(ctx, fromModel(d))
+	if err != nil {
+		return nil, fmt.Errorf("plugin extractor %s: %w", a.name, err)
+	}
+	return features, nil
+}
+
+func toModel(doc plugin.Document) models.Document {
+	return models.Document{ID: doc.ID, Text: doc.Text, Source: doc.Source, Vector: doc.Vector, Meta: doc.Meta}
+}
+
+func fromModel(doc models.Document) plugin.Document {
+	return plugin.Document{ID: doc.ID, Text: doc.Text, Source: doc.Source, Vector: doc.Vector, Meta: doc.Meta}
+}
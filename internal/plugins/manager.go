@@ -0,0 +1,171 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/aawadall/bit-scout/pkg/plugin"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+Host side of the plugin system: plugin executables are discovered in a directory,
+launched as child processes and exposed as loader types and feature extractors.
+*/
+
+// Executable name prefixes; the rest of the name is the loader type or extractor name
+const (
+	LoaderPrefix    = "bitscout-" + plugin.KindLoader + "-"
+	ExtractorPrefix = "bitscout-" + plugin.KindExtractor + "-"
+)
+
+// Manager owns the running plugin processes
+type Manager struct {
+	clients    []*goplugin.Client
+	loaders    map[string]plugin.Loader
+	extractors map[string]plugin.Extractor
+}
+
+// NewManager creates a manager with no plugins
+func NewManager() *Manager {
+	return &Manager{
+		loaders:    make(map[string]plugin.Loader),
+		extractors: make(map[string]plugin.Extractor),
+	}
+}
+
+// Discover launches every plugin executable in dir. A missing directory is not an error.
+// Plugins that fail to start are logged and skipped so one broken plugin does not stop bit-scout.
+func (m *Manager) Discover(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debug().Msgf("Plugins directory %s does not exist, no plugins loaded", dir)
+			return nil
+		}
+		return fmt.Errorf("failed to read plugins directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		kind, name, ok := parseExecutableName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if !isExecutable(path) {
+			log.Warn().Msgf("Skipping plugin %s: not executable", path)
+			continue
+		}
+		if err := m.launch(kind, name, path); err != nil {
+			log.Error().Msgf("Failed to load plugin %s: %s", path, err)
+			continue
+		}
+		log.Info().Msgf("Loaded %s plugin %s from %s", kind, name, path)
+	}
+	return nil
+}
+
+// launch starts a plugin process and dispenses its implementation
+func (m *Manager) launch(kind, name, path string) error {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  plugin.Handshake,
+		Plugins:          plugin.PluginMap(),
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Logger:           hclog.New(&hclog.LoggerOptions{Name: "plugin." + name, Level: hclog.Warn}),
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return err
+	}
+	raw, err := rpcClient.Dispense(kind)
+	if err != nil {
+		client.Kill()
+		return err
+	}
+
+	switch impl := raw.(type) {
+	case plugin.Loader:
+		m.loaders[name] = impl
+	case plugin.Extractor:
+		m.extractors[name] = impl
+	default:
+		client.Kill()
+		return fmt.Errorf("unexpected %s plugin implementation %T", kind, raw)
+	}
+	m.clients = append(m.clients, client)
+	return nil
+}
+
+// LoaderTypes returns the loader types provided by plugins, sorted
+func (m *Manager) LoaderTypes() []string {
+	names := make([]string, 0, len(m.loaders))
+	for name := range m.loaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExtractorNames returns the feature extractors provided by plugins, sorted
+func (m *Manager) ExtractorNames() []string {
+	names := make([]string, 0, len(m.extractors))
+	for name := range m.extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterLoaderTypes adds every plugin loader to the factory as a loader type named after the plugin
+func (m *Manager) RegisterLoaderTypes(factory *loaders.LoaderFactory) {
+	for name, impl := range m.loaders {
+		registerLoaderType(factory, name, impl)
+	}
+}
+
+// Extractors returns the plugin feature extractors, keyed by name, as engine feature extractors
+func (m *Manager) Extractors() map[string]ports.FeatureExtractorPort {
+	out := make(map[string]ports.FeatureExtractorPort, len(m.extractors))
+	for name, impl := range m.extractors {
+		out[name] = &extractorAdapter{name: name, extractor: impl}
+	}
+	return out
+}
+
+// Close stops every plugin process
+func (m *Manager) Close() {
+	for _, client := range m.clients {
+		client.Kill()
+	}
+	m.clients = nil
+}
+
+// parseExecutableName splits "bitscout-<kind>-<name>[.exe]" into its kind and name
+func parseExecutableName(fileName string) (kind, name string, ok bool) {
+	base := strings.TrimSuffix(fileName, ".exe")
+	for prefix, kind := range map[string]string{LoaderPrefix: plugin.KindLoader, ExtractorPrefix: plugin.KindExtractor} {
+		if strings.HasPrefix(base, prefix) && len(base) > len(prefix) {
+			return kind, strings.TrimPrefix(base, prefix), true
+		}
+	}
+	return "", "", false
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return strings.HasSuffix(path, ".exe") || info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,114 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aawadall/bit-scout/internal/loaders"
+	"github.com/aawadall/bit-scout/internal/models"
+	"github.com/aawadall/bit-scout/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLoader struct {
+	docs   []plugin.Document
+	config map[string]interface{}
+}
+
+func (l *fakeLoader) Load(ctx context.Context, config map[string]interface{}, emit func(plugin.Document) error) error {
+	l.config = config
+	for _, doc := range l.docs {
+		if err := emit(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeExtractor struct{ err error }
+
+func (e *fakeExtractor) Extract(ctx context.Context, doc plugin.Document) (map[string]interface{}, error) {
+	return map[string]interface{}{"id": doc.ID}, e.err
+}
+
+func TestParseExecutableName(t *testing.T) {
+	tests := []struct {
+		file, kind, name string
+		ok               bool
+	}{
+		{"bitscout-loader-s3", plugin.KindLoader, "s3", true},
+		{"bitscout-extractor-sentiment.exe", plugin.KindExtractor, "sentiment", true},
+		{"bitscout-loader-", "", "", false},
+		{"bitscout-index-foo", "", "", false},
+		{"README.md", "", "", false},
+	}
+	for _, tt := range tests {
+		kind, name, ok := parseExecutableName(tt.file)
+		assert.Equal(t, tt.ok, ok, tt.file)
+		assert.Equal(t, tt.kind, kind, tt.file)
+		assert.Equal(t, tt.name, name, tt.file)
+	}
+}
+
+func TestManager_Discover_MissingDirectory(t *testing.T) {
+	m := NewManager()
+	assert.NoError(t, m.Discover(filepath.Join(t.TempDir(), "missing")))
+	assert.Empty(t, m.LoaderTypes())
+	assert.Empty(t, m.ExtractorNames())
+}
+
+func TestManager_Discover_SkipsBrokenPlugins(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bitscout-loader-broken"), []byte("#!/bin/sh\nexit 1\n"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bitscout-extractor-noexec"), []byte("data"), 0644))
+
+	m := NewManager()
+	defer m.Close()
+	assert.NoError(t, m.Discover(dir))
+	assert.Empty(t, m.LoaderTypes())
+	assert.Empty(t, m.ExtractorNames())
+}
+
+func TestManager_RegisterLoaderTypes(t *testing.T) {
+	impl := &fakeLoader{docs: []plugin.Document{{ID: "a", Text: "alpha"}, {ID: "b", Text: "beta"}}}
+	m := NewManager()
+	m.loaders["remote"] = impl
+	factory := loaders.NewLoaderFactory()
+	m.RegisterLoaderTypes(factory)
+	assert.Contains(t, factory.Types(), "remote")
+
+	loader, err := factory.Create("remote", map[string]interface{}{"bucket": "docs"})
+	assert.NoError(t, err)
+	docs, err := loader.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Document{{ID: "a", Text: "alpha"}, {ID: "b", Text: "beta"}}, docs)
+	assert.Equal(t, map[string]interface{}{"bucket": "docs"}, impl.config)
+
+	stream, errs := loaders.Stream(context.Background(), loader)
+	var streamed []string
+	for doc := range stream {
+		streamed = append(streamed, doc.ID)
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []string{"a", "b"}, streamed)
+}
+
+func TestManager_Extractors(t *testing.T) {
+	m := NewManager()
+	m.extractors["ids"] = &fakeExtractor{}
+	m.extractors["failing"] = &fakeExtractor{err: errors.New("boom")}
+	extractors := m.Extractors()
+
+	features, err := extractors["ids"].ExtractFeatures(models.Document{ID: "doc-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "doc-1"}, features)
+
+	_, err = extractors["failing"].ExtractFeatures(models.Document{ID: "doc-1"})
+	assert.ErrorContains(t, err, "plugin extractor failing")
+
+	_, err = extractors["ids"].ExtractFeatures("not a document")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// IndexSpec describes an index to create at runtime
+type IndexSpec struct {
+	Name   string
+	Type   string // An index type, e.g. "simple" or "persisted"
+	Config map[string]interface{}
+}
+
+// Snapshot describes an index export written on the server by a snapshot
+type Snapshot struct {
+	Index string
+	Path  string
+	Time  time.Time
+	Bytes int64
+}
+
+// VerifyReport is the differences between the documents an index holds in memory and in storage
+type VerifyReport struct {
+	Documents       int      // Documents in memory
+	Stored          int      // Documents in storage
+	MissingOnDisk   []string // IDs of the documents in memory only
+	MissingInMemory []string // IDs of the documents in storage only
+	Mismatched      []string // IDs of the documents whose content differs
+	Repaired        int      // Documents written or removed by the repair
+}
+
+// IndexAdminPort is implemented by engines whose indexes and loaders can be managed at runtime,
+// without editing the config and restarting (driving port). Changes are not written back to the config.
+type IndexAdminPort interface {
+	CreateIndex(spec IndexSpec) error
+	// DropIndex unregisters and closes an index; the default index and indexes loaders write to cannot be dropped
+	DropIndex(name string) error
+	ConfigureIndex(name string, config map[string]interface{}) error
+	FlushIndex(name string) error
+	OptimizeIndex(name string) error
+	// ClearIndex removes every document of an index, from memory and storage, keeping its configuration
+	ClearIndex(name string) error
+	// VerifyIndex compares an index's documents in memory and in storage, repairing them in a direction
+	// ("disk": from memory, "memory": from storage, "": none)
+	VerifyIndex(name, repair string) (VerifyReport, error)
+	SnapshotIndex(name string) (Snapshot, error)
+	// SetAlias makes alias another name of an index; RemoveAlias removes it, keeping the index
+	SetAlias(alias, index string) error
+	RemoveAlias(alias string) error
+	// TriggerLoader runs a loader's pipeline now: a refresh for scheduled loaders, a full load otherwise
+	TriggerLoader(ctx context.Context, name string) error
+}
@@ -1,23 +1,211 @@
 package ports
 
-import "github.com/aawadall/bit-scout/internal/models"
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+var (
+	// ErrRateLimited is returned (wrapped) when a request is rejected by rate limiting
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotFound is returned (wrapped) when a request names an index or other resource that does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrNotSupported is returned (wrapped) when the target of a request does not support the operation
+	ErrNotSupported = errors.New("not supported")
+	// ErrInvalid is returned (wrapped) when a request or an item of it is malformed
+	ErrInvalid = errors.New("invalid")
+	// ErrConflict is returned (wrapped) when a request conflicts with the current state, e.g. creating an
+	// index that exists
+	ErrConflict = errors.New("conflict")
+)
 
 // SearchQuery represents a search request (placeholder, expand as needed)
 type SearchQuery struct {
-	Query string
+	Query  string
+	Caller string // Who sent the query, e.g. "REST 10.0.0.7:51234" (for logs)
+	// Filter restricts the results to the documents the caller may see (nil: all). APIs set it from
+	// the caller's roles; the engine applies it to every search.
+	Filter func(doc models.Document) bool
+	// Namespace is searched instead of the default index (empty: the default index). APIs set it from
+	// the namespace the caller is bound to.
+	Namespace string
+	// Principals are the caller's principals (its name, roles and groups): documents with AllowedPrincipals
+	// are only returned to callers holding one of them (nil: not enforced, e.g. without authentication)
+	Principals []string
+	// Sort orders the results: "score" (relevance, the default), "id", or a field ("text", "source" or a
+	// metadata key), prefixed with "-" for descending order. Ties are broken by ID.
+	Sort string
+	// Projection selects the fields of the returned documents (zero: whole documents). A select clause
+	// ending the query ("fileExtension=go select id,source,filename") adds to it.
+	Projection models.Projection
+	// Language is a hint of the language of the query, e.g. "de": indexes that analyze documents by
+	// language analyze the query as documents of that language are, and find only those (empty: all)
+	Language string
 	// Add more fields as needed (filters, pagination, etc.)
 }
 
+type principalsKey struct{}
+
+// WithPrincipals passes the principals of a search's caller to the index adapters evaluating it (nil: not enforced)
+func WithPrincipals(ctx context.Context, principals []string) context.Context {
+	if principals == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, principalsKey{}, principals)
+}
+
+// PrincipalsFrom returns the principals set by WithPrincipals, which index adapters should restrict their
+// results to
+func PrincipalsFrom(ctx context.Context) ([]string, bool) {
+	principals, ok := ctx.Value(principalsKey{}).([]string)
+	return principals, ok
+}
+
+type languageKey struct{}
+
+// WithLanguage passes the language hint of a search to the index adapters evaluating it ("": none)
+func WithLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// LanguageFrom returns the language hint set by WithLanguage, "" if none
+func LanguageFrom(ctx context.Context) string {
+	language, _ := ctx.Value(languageKey{}).(string)
+	return language
+}
+
+// Durabilities of writes: when adds and deletes return, for indexes that persist them asynchronously
+const (
+	DurabilityNone      = "none"      // Once the write is in memory (fire-and-forget)
+	DurabilityPersisted = "persisted" // Once the write is committed to storage
+	DurabilityFsync     = "fsync"     // Once the write is committed and storage is synced to disk
+)
+
+type durabilityKey struct{}
+
+// WithDurability passes the durability of a write to the index adapters applying it ("": the index's default)
+func WithDurability(ctx context.Context, durability string) context.Context {
+	if durability == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, durabilityKey{}, durability)
+}
+
+// DurabilityFrom returns the durability set by WithDurability, "" if none
+func DurabilityFrom(ctx context.Context) string {
+	durability, _ := ctx.Value(durabilityKey{}).(string)
+	return durability
+}
+
 // SearchResults represents search results (placeholder, expand as needed)
 type SearchResults struct {
-	Documents []models.Document
+	Documents   []models.Document
+	FailedNodes []string // Peer nodes a distributed search got no results from (the results are partial)
+	// Warnings explain why a query matched nothing, e.g. a dimension no document has (nil: none)
+	Warnings []QueryError
 	// Add more fields as needed (scores, pagination, etc.)
 }
 
-// Stats represents system or index statistics (placeholder, expand as needed)
+// Stats represents system or index statistics
 type Stats struct {
+	NumDocuments      int
+	Indexes           []IndexStatus            // Document counts and sizes per index
+	Loaders           []LoaderStatus           // Status of scheduled loaders
+	FeatureExtractors []FeatureExtractorStatus // Registered feature extractors
+	StartedAt         time.Time                // When the engine started (zero before Start)
+	Uptime            time.Duration            // Time since StartedAt
+	Memory            MemoryDiagnostics
+	Queries           QueryStats
+}
+
+// IndexStatus reports the size of a registered index
+type IndexStatus struct {
+	Name         string
 	NumDocuments int
-	// Add more fields as needed (uptime, memory usage, etc.)
+	SizeBytes    int               // Approximate size, -1 if the index does not report it
+	Persistence  *PersistenceStats // Failed async writes to storage, nil if the index does not report them
+}
+
+// PersistenceStats counts the writes an index persisting asynchronously failed to apply to its storage
+type PersistenceStats struct {
+	Failed      int64 // Writes given up on after their retries
+	Retried     int64 // Retries of failed writes
+	LastError   string
+	LastFailure time.Time // Zero before the first write given up on
+}
+
+// FeatureExtractorStatus reports a registered feature extractor and the pipelines that apply it
+type FeatureExtractorStatus struct {
+	Name    string
+	Loaders []string // Loaders whose documents go through the extractor, sorted
+}
+
+// QueryStats counts the searches served since the engine was created
+type QueryStats struct {
+	Total        int64
+	Failed       int64
+	TotalLatency time.Duration // Sum of the latencies of every search
+	LastQuery    time.Time     // Zero before the first search
+}
+
+// LoaderStatus reports the schedule and most recent run of a scheduled loader
+type LoaderStatus struct {
+	Name         string
+	Index        string
+	Interval     time.Duration
+	Runs         int
+	Running      bool
+	RunningSince time.Time // Start of the current run, while Running
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    string
+	NextRun      time.Time
+	Added        int // Documents added by the last run
+	Modified     int // Documents updated by the last run
+	Deleted      int // Documents deleted by the last run
+}
+
+// EnginePort defines the operations the engine core offers to API adapters (driving port)
+type EnginePort interface {
+	// Search executes a search query against the engine's default index.
+	Search(query SearchQuery) (SearchResults, error)
+	// Stats returns statistics about the engine and its indexes.
+	Stats() (Stats, error)
+	// Index adds a document to the engine's default index.
+	Index(doc models.Document) error
+}
+
+// ContextSearchPort is implemented by engines whose searches can take part in the caller's trace (driving port).
+// The search and its evaluation are recorded as child spans of the span in ctx.
+type ContextSearchPort interface {
+	SearchContext(ctx context.Context, query SearchQuery) (SearchResults, error)
+}
+
+// SearchMatch is a newly indexed document matching a search subscription
+type SearchMatch struct {
+	Index    string
+	Document models.Document
+	Time     time.Time
+}
+
+// SubscriptionPort is implemented by engines that push the documents matching a query as they are
+// indexed (driving port). The channel is closed once ctx is done.
+type SubscriptionPort interface {
+	SubscribeSearch(ctx context.Context, query SearchQuery) (<-chan SearchMatch, error)
+}
+
+// IndexTransferPort is implemented by engines that can export and import whole indexes, to move them
+// between machines and versions (driving port). An empty name selects the default index.
+type IndexTransferPort interface {
+	ExportIndex(name string, w io.Writer) error
+	ImportIndex(name string, r io.Reader) error
 }
 
 // APIPort defines the interface for API adapters (driven port)
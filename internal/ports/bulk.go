@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// BulkAction is the operation of a bulk item
+type BulkAction string
+
+const (
+	BulkIndex  BulkAction = "index"  // Add the document, replacing any with the same ID
+	BulkUpdate BulkAction = "update" // Update an existing document
+	BulkDelete BulkAction = "delete" // Delete the document with the ID
+)
+
+// BulkItem is one operation of a bulk request. Deletes only need the document's ID.
+type BulkItem struct {
+	Action   BulkAction
+	Document models.Document
+}
+
+// BulkItemResult is the outcome of a bulk item; Err is nil on success
+type BulkItemResult struct {
+	Action BulkAction
+	ID     string
+	Err    error
+}
+
+// BulkResults holds the outcome of every item of a bulk request, in request order
+type BulkResults struct {
+	Items []BulkItemResult
+}
+
+// Failed returns the number of items that failed
+func (r BulkResults) Failed() int {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// BulkPort is implemented by engines that apply many document operations in one request (driving port).
+// Items fail independently: the error of Bulk is reserved for requests that cannot run at all.
+type BulkPort interface {
+	Bulk(ctx context.Context, items []BulkItem) (BulkResults, error)
+}
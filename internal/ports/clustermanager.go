@@ -1,8 +1,61 @@
 package ports
 
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
 // ClusterManagerPort defines the interface for cluster management (driven port, optional)
 type ClusterManagerPort interface {
 	RegisterNode(nodeID string, address string) error
 	DeregisterNode(nodeID string) error
 	ListNodes() ([]string, error)
+	NodeAddress(nodeID string) (string, error)
+}
+
+// ShardQuery is a search a node evaluates against its own documents only, as part of a distributed search
+type ShardQuery struct {
+	Query      string
+	Principals []string // Principals of the caller, as in SearchQuery (nil: not enforced)
+	Language   string   // Language hint of the query, as in SearchQuery (empty: none)
+}
+
+// ShardResults are the matches of one node, with the term statistics it scored them with
+type ShardResults struct {
+	Node      string
+	Documents []models.Document
+	Stats     TermStats
+}
+
+// ShardSearchPort evaluates shard queries against the local node (driving port of cluster transports)
+type ShardSearchPort interface {
+	SearchShard(ctx context.Context, query ShardQuery) (ShardResults, error)
+}
+
+// ClusterTransportPort sends shard queries to peer nodes (driven port). Implementations return
+// ctx.Err() once ctx is done.
+type ClusterTransportPort interface {
+	SearchShard(ctx context.Context, address string, query ShardQuery) (ShardResults, error)
+	Close() error
+}
+
+// ShardWrite are changes to the documents a node holds, routed to it by a sharded index
+type ShardWrite struct {
+	Add    []models.Document
+	Update []models.Document
+	Delete []string
+}
+
+// ShardWritePort applies writes routed by peer nodes to the local shards, without routing them again, and
+// moves the documents of shards the node no longer owns to their owners (driving port of cluster transports)
+type ShardWritePort interface {
+	WriteShard(ctx context.Context, write ShardWrite) error
+	Rebalance(ctx context.Context) (moved int, err error)
+}
+
+// ShardTransportPort sends shard writes and rebalance requests to peer nodes (driven port)
+type ShardTransportPort interface {
+	WriteShard(ctx context.Context, address string, write ShardWrite) error
+	Rebalance(ctx context.Context, address string) (moved int, err error)
 }
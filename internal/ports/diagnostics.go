@@ -0,0 +1,34 @@
+package ports
+
+import "time"
+
+// Diagnostics is a snapshot of the engine's runtime state, for operators profiling a live process
+type Diagnostics struct {
+	Time       time.Time                         `json:"time"`
+	Goroutines int                               `json:"goroutines"`
+	Memory     MemoryDiagnostics                 `json:"memory"`
+	Indexes    map[string]map[string]interface{} `json:"indexes"` // Per index: memory breakdown, queues and storage
+	Loaders    []LoaderStatus                    `json:"loaders"`
+	APIs       []string                          `json:"apis"`
+}
+
+// MemoryDiagnostics summarizes the Go runtime's memory statistics
+type MemoryDiagnostics struct {
+	HeapAllocBytes uint64        `json:"heapAllocBytes"` // Bytes of live and not yet collected heap objects
+	HeapInuseBytes uint64        `json:"heapInuseBytes"`
+	SysBytes       uint64        `json:"sysBytes"` // Bytes obtained from the OS
+	NumGC          uint32        `json:"numGC"`
+	LastGCPause    time.Duration `json:"lastGCPause"`
+}
+
+// DiagnosticsIndexPort is implemented by index adapters that report their internals, e.g. a breakdown
+// of the memory they hold or the depth of a write queue.
+type DiagnosticsIndexPort interface {
+	IndexPort
+	Diagnostics() map[string]interface{}
+}
+
+// DiagnosticsPort is implemented by engines that report their runtime state (driving port)
+type DiagnosticsPort interface {
+	Diagnostics() Diagnostics
+}
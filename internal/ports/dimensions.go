@@ -0,0 +1,46 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// Dimension is a metadata key of the indexed documents and its cardinality
+type Dimension struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"` // Documents holding the key
+	Values    int    `json:"values"`    // Distinct values of the key
+}
+
+// ValueCount is a value of a dimension and the number of documents holding it
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// DimensionQuery selects the documents whose metadata a caller may see, as a SearchQuery does
+type DimensionQuery struct {
+	Namespace  string                         // Index of the namespace described (empty: the default index)
+	Principals []string                       // Principals of the caller (nil: access control not enforced)
+	Filter     func(doc models.Document) bool // Documents the caller's roles may see (nil: all)
+}
+
+// DimensionIndexPort is implemented by index adapters that describe the metadata of their documents.
+// Indexes that do not have no dimensions. Only the documents visible to the principals of ctx (see
+// WithPrincipals) and matching filter (nil: all) are counted.
+type DimensionIndexPort interface {
+	IndexPort
+	ListDimensions(ctx context.Context, filter func(doc models.Document) bool) []Dimension
+	TopValues(ctx context.Context, dimension string, n int, filter func(doc models.Document) bool) []ValueCount
+}
+
+// DimensionPort is implemented by engines that describe the metadata of the indexed documents, for
+// faceted user interfaces and query builders (driving port)
+type DimensionPort interface {
+	// Returns the metadata keys of the documents of the index of a namespace the caller may see, by name
+	ListDimensions(ctx context.Context, query DimensionQuery) ([]Dimension, error)
+	// Returns the n most frequent values of a dimension in the documents the caller may see (n <= 0: all),
+	// most frequent first
+	TopValues(ctx context.Context, query DimensionQuery, dimension string, n int) ([]ValueCount, error)
+}
@@ -0,0 +1,48 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// DocumentGetterIndexPort is implemented by index adapters that fetch documents by ID, in the order of
+// the IDs, leaving out those not in the index or not visible to the principals of ctx
+type DocumentGetterIndexPort interface {
+	IndexPort
+	GetDocuments(ctx context.Context, ids []string) ([]models.Document, error)
+}
+
+// DocumentGetterPort is implemented by engines that fetch documents by ID (driving port). The namespace,
+// filter, principals and projection of the query apply as they do to searches; its text is not used.
+type DocumentGetterPort interface {
+	GetDocuments(ctx context.Context, query SearchQuery, ids []string) ([]models.Document, error)
+}
+
+// ListOptions selects a page of the documents of an index, in ID order
+type ListOptions struct {
+	After  string // Lists the documents with IDs after this one ("": from the first)
+	Offset int    // Documents to skip after After
+	Limit  int    // Most documents to list (0: all)
+	Where  string // Conditions the documents must match, e.g. "fileExtension=go" ("": all)
+}
+
+// DocumentPage is a page of the documents of an index
+type DocumentPage struct {
+	Documents []models.Document `json:"documents"`
+	Total     int               `json:"total"`          // Documents matching the options, before Offset and Limit
+	Next      string            `json:"next,omitempty"` // After of the next page ("": this is the last page)
+}
+
+// DocumentListerIndexPort is implemented by index adapters that page through their documents. Invalid
+// options fail wrapping ErrInvalid.
+type DocumentListerIndexPort interface {
+	IndexPort
+	ListDocuments(ctx context.Context, opts ListOptions) (DocumentPage, error)
+}
+
+// DocumentListerPort is implemented by engines that page through the documents of an index, for admin
+// tools (driving port). An empty name selects the default index.
+type DocumentListerPort interface {
+	ListDocuments(ctx context.Context, name string, opts ListOptions) (DocumentPage, error)
+}
@@ -0,0 +1,45 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// EventType identifies an engine lifecycle event
+type EventType string
+
+const (
+	EventDocumentIndexed EventType = "document_indexed" // Documents were added to or updated in an index
+	EventDocumentDeleted EventType = "document_deleted" // Documents were removed from an index
+	EventSearchExecuted  EventType = "search_executed"  // A search ran against an index
+	EventLoaderCompleted EventType = "loader_completed" // A loader run finished (successfully or not)
+	EventQueryMatched    EventType = "query_matched"    // Indexed documents matched a stored query
+)
+
+// Event describes something that happened inside the engine. Fields irrelevant to the event type are left empty.
+type Event struct {
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	Index       string    `json:"index,omitempty"`
+	Loader      string    `json:"loader,omitempty"`
+	DocumentIDs []string  `json:"documentIds,omitempty"` // Documents indexed, deleted or matching a stored query
+	// Documents are the documents indexed, for in-process subscribers; they are not serialized
+	Documents []models.Document `json:"-"`
+	Query     string            `json:"query,omitempty"`
+	QueryID   string            `json:"queryId,omitempty"`  // Stored query matched
+	Results   int               `json:"results,omitempty"`  // Number of search results, or documents indexed by a loader run
+	Duration  time.Duration     `json:"duration,omitempty"` // Search or loader run duration
+	Error     string            `json:"error,omitempty"`
+}
+
+// EventHandler receives engine events. Handlers run on their own goroutine, one event at a time.
+type EventHandler func(Event)
+
+// EventSubscriberPort is implemented by adapters that react to engine events (e.g. audit logs, webhooks)
+type EventSubscriberPort interface {
+	// Events returns the event types the subscriber wants (empty means all)
+	Events() []EventType
+	// Handle processes a single event
+	Handle(event Event)
+}
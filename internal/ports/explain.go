@@ -0,0 +1,28 @@
+package ports
+
+// PlanStep is a condition (or the free text) of a query as planned, and how the index answers it
+type PlanStep struct {
+	Condition string `json:"condition"`
+	Access    string `json:"access"`   // inverted, bitmap, range or scan
+	Estimate  int    `json:"estimate"` // Documents expected to match the step alone
+}
+
+// QueryPlan explains how the index of a namespace answers a query, without running it
+type QueryPlan struct {
+	Index     string     `json:"index"`
+	Plan      string     `json:"plan"` // e.g. text, conditions, text_conditions or vector
+	Documents int        `json:"documents"`
+	Steps     []PlanStep `json:"steps"` // In the order they run
+}
+
+// QueryExplainerIndexPort is implemented by index adapters that plan queries. Invalid queries fail
+// wrapping ErrInvalid.
+type QueryExplainerIndexPort interface {
+	IndexPort
+	ExplainQuery(query string) (QueryPlan, error)
+}
+
+// QueryExplainerPort is implemented by engines that explain how queries are answered (driving port)
+type QueryExplainerPort interface {
+	ExplainQuery(query SearchQuery) (QueryPlan, error)
+}
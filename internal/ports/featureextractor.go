@@ -1,6 +1,19 @@
 package ports
 
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
 // FeatureExtractorPort defines the interface for feature extractor adapters (driven port)
 type FeatureExtractorPort interface {
 	ExtractFeatures(doc interface{}) (map[string]interface{}, error)
 }
+
+// DocumentProcessorPort enriches a batch of loaded documents in place (e.g. with extracted
+// features in Meta and Vector) before the batch is indexed (driven port). Processing should stop
+// early once ctx, the context of the load, is cancelled.
+type DocumentProcessorPort interface {
+	ProcessDocuments(ctx context.Context, docs []models.Document) error
+}
@@ -0,0 +1,34 @@
+package ports
+
+// Health statuses of components and reports
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+)
+
+// ComponentHealth is the status of one part of the engine, e.g. an index, the loaders or memory
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthReport is the outcome of a health check: HealthFail if any component failed
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// Healthy reports whether every component passed
+func (r HealthReport) Healthy() bool {
+	return r.Status == HealthOK
+}
+
+// HealthPort is implemented by engines that check the health of their components (driving port),
+// for orchestrators' liveness and readiness probes
+type HealthPort interface {
+	// Liveness checks the components whose failure calls for a restart
+	Liveness() HealthReport
+	// Readiness checks the liveness components and whether the engine is started and serving
+	Readiness() HealthReport
+}
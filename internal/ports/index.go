@@ -1,9 +1,120 @@
 package ports
 
-// IndexPort defines the interface for index adapters (driven port)
+import (
+	"context"
+	"io"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// IndexPort defines the interface for index adapters (driven port). Searches should stop early,
+// returning ctx.Err(), once ctx is cancelled or past its deadline; writes should fail without changes.
 type IndexPort interface {
-	AddDocument(doc interface{}) error
-	Search(query string) ([]interface{}, error)
+	AddDocument(ctx context.Context, doc interface{}) error
+	Search(ctx context.Context, query string) ([]interface{}, error)
 	Count() (int, error)
 	Close() error
 }
+
+// BatchIndexPort is implemented by index adapters that can ingest a batch of documents in one call.
+type BatchIndexPort interface {
+	IndexPort
+	AddDocuments(ctx context.Context, docs []models.Document) error
+}
+
+// MutableIndexPort is implemented by index adapters that support updating and deleting documents.
+type MutableIndexPort interface {
+	IndexPort
+	UpdateDocument(ctx context.Context, doc models.Document) error
+	DeleteDocument(ctx context.Context, id string) error
+}
+
+// ConfigurableIndexPort is implemented by index adapters that can be reconfigured at runtime.
+type ConfigurableIndexPort interface {
+	IndexPort
+	Configure(config map[string]interface{}) error
+}
+
+// TransferIndexPort is implemented by index adapters that can export their documents and import an export
+// (NDJSON: a header line with the index type and configuration, then one document per line).
+type TransferIndexPort interface {
+	IndexPort
+	Export(w io.Writer) error
+	Import(r io.Reader) error
+}
+
+// MaintenanceIndexPort is implemented by index adapters that can write pending changes to storage and
+// compact or otherwise optimize themselves for faster searches.
+type MaintenanceIndexPort interface {
+	IndexPort
+	Flush() error
+	Optimize() error
+}
+
+// ClearableIndexPort is implemented by index adapters that can remove every document at once, keeping
+// their configuration.
+type ClearableIndexPort interface {
+	IndexPort
+	Clear() error
+}
+
+// VerifiableIndexPort is implemented by index adapters that compare the documents they hold in memory
+// with those in storage and repair the differences in a direction ("disk" or "memory"; "": none). Index
+// types that keep their documents in one place fail with ErrNotSupported, unknown directions with
+// ErrInvalid.
+type VerifiableIndexPort interface {
+	IndexPort
+	Verify(repair string) (VerifyReport, error)
+}
+
+// SizedIndexPort is implemented by index adapters that report their approximate size in bytes.
+type SizedIndexPort interface {
+	IndexPort
+	Size() (int, error)
+}
+
+// TermStats are the statistics an index ranks a free-text query with (TF-IDF), so the results of
+// several indexes can be ranked together
+type TermStats struct {
+	Terms      []string                  // Analyzed query terms (none: the query is not ranked)
+	Documents  int                       // Documents in the index
+	DocFreq    map[string]int            // Documents containing each term
+	TermFreq   map[string]map[string]int // Frequency of each term in each requested document, by ID
+	Boosts     map[string]float64        // Function score of each requested document, added to its weighted TF-IDF (nil: none)
+	TextWeight float64                   // Weight of the TF-IDF of boosted documents
+	Script     string                    // Scoring script applied to the score of boosted documents ("": none)
+}
+
+// TermStatsIndexPort is implemented by index adapters that rank free-text queries by term statistics
+type TermStatsIndexPort interface {
+	IndexPort
+	TermStats(query string, ids []string) (TermStats, error)
+}
+
+// WarmUpIndexPort is implemented by index adapters whose indexes can rebuild the structures derived
+// from their documents before serving searches
+type WarmUpIndexPort interface {
+	IndexPort
+	WarmUp(ctx context.Context) error
+}
+
+// ResultCapIndexPort is implemented by index adapters whose indexes cap the results of each search. The
+// engine keeps the first MaxResults (0: all) once the results are filtered for the caller and sorted.
+type ResultCapIndexPort interface {
+	IndexPort
+	MaxResults() int
+}
+
+// HealthCheckIndexPort is implemented by index adapters that can check the resources they depend on
+// (e.g. that a database is open and its background writer alive).
+type HealthCheckIndexPort interface {
+	IndexPort
+	HealthCheck() error
+}
+
+// PersistenceStatsIndexPort is implemented by index adapters that can report the failed async writes of
+// their indexes; indexes that do not persist asynchronously fail with ErrNotSupported
+type PersistenceStatsIndexPort interface {
+	IndexPort
+	PersistenceStats() (PersistenceStats, error)
+}
@@ -1,6 +1,27 @@
 package ports
 
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
 // LoaderPort defines the interface for loader adapters (driven port)
 type LoaderPort interface {
 	Load(source string) ([]interface{}, error)
 }
+
+// StreamingLoaderPort defines the interface for loader adapters that emit documents incrementally (driven port).
+// The document channel is closed once the loader is done; any load failures are reported on the error channel,
+// which is closed after the document channel.
+type StreamingLoaderPort interface {
+	Load(ctx context.Context) (<-chan models.Document, <-chan error)
+}
+
+// IncrementalLoaderPort defines the interface for loader adapters that can report changes since their last run (driven port).
+type IncrementalLoaderPort interface {
+	// LoadChanges returns the documents added, modified, and deleted since the last commit.
+	LoadChanges(ctx context.Context) (models.ChangeSet, error)
+	// Commit marks the last change set as applied.
+	Commit() error
+}
@@ -0,0 +1,42 @@
+package ports
+
+// MetadataOp is the kind of a change to the cluster metadata
+type MetadataOp string
+
+const (
+	MetadataCreateIndex MetadataOp = "create_index"
+	MetadataDropIndex   MetadataOp = "drop_index"
+	MetadataSetAlias    MetadataOp = "set_alias"
+	MetadataRemoveAlias MetadataOp = "remove_alias"
+	MetadataSetShards   MetadataOp = "set_shards"
+)
+
+// ShardMap is how the documents of the default index are split over the nodes of a cluster
+type ShardMap struct {
+	Shards int      // Number of shards (0: not sharded)
+	Nodes  []string // IDs of the nodes holding the shards
+}
+
+// ClusterMetadata is the state every node of a cluster agrees on: the indexes created at runtime, the
+// aliases of indexes and the shard map
+type ClusterMetadata struct {
+	Indexes map[string]IndexSpec
+	Aliases map[string]string // Alias -> index
+	Shards  ShardMap
+}
+
+// MetadataChange is a change to the cluster metadata
+type MetadataChange struct {
+	Op     MetadataOp
+	Index  IndexSpec // Index created
+	Name   string    // Index dropped, or alias set or removed
+	Target string    // Index an alias points to
+	Shards ShardMap  // New shard map
+}
+
+// MetadataPort replicates the cluster metadata (driven port, optional). Changes are applied by every node
+// in the same order once committed; Propose returns once this node applied the change.
+type MetadataPort interface {
+	Propose(change MetadataChange) error
+	Metadata() ClusterMetadata
+}
@@ -0,0 +1,26 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// NamespacePort is implemented by engines that isolate tenants in namespaces (driving port). A namespace
+// names an index (or alias) of its own: the callers bound to it index into, search and count only that
+// index, never the default one. Unknown namespaces fail with ErrNotFound.
+type NamespacePort interface {
+	// IndexNamespace adds a document to the index of a namespace
+	IndexNamespace(namespace string, doc models.Document) error
+	// BulkNamespace applies bulk items to the index of a namespace, like BulkPort
+	BulkNamespace(ctx context.Context, namespace string, items []BulkItem) (BulkResults, error)
+	// NamespaceStats returns the statistics of a namespace's index alone, without engine-wide ones
+	NamespaceStats(namespace string) (Stats, error)
+}
+
+// ContextIndexPort is implemented by engines that add single documents with a context, which carries
+// per-write options such as WithDurability (driving port)
+type ContextIndexPort interface {
+	// IndexContext adds a document to the index of a namespace ("": the default index)
+	IndexContext(ctx context.Context, namespace string, doc models.Document) error
+}
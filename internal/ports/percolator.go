@@ -0,0 +1,31 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+// StoredQuery is a standing query that documents are matched against as they are indexed
+type StoredQuery struct {
+	ID        string    `json:"id"`
+	Query     string    `json:"query"`
+	Namespace string    `json:"namespace,omitempty"` // Only documents of the namespace's index match ("": any index)
+	Created   time.Time `json:"created"`
+	// Principals the documents must be visible to, those of the caller who registered the query (nil: any)
+	Principals []string `json:"-"`
+}
+
+// PercolatorPort is implemented by engines that match documents against stored queries (reverse search,
+// driving port). Indexed documents are reported in an EventQueryMatched event for every stored query they
+// match. The queries of a namespace are only seen by its callers; the empty namespace sees every query.
+type PercolatorPort interface {
+	// RegisterQuery stores a query, with a generated ID unless it has one; existing IDs fail with ErrConflict
+	RegisterQuery(query StoredQuery) (StoredQuery, error)
+	// DeleteQuery removes a stored query of a namespace; unknown IDs fail with ErrNotFound
+	DeleteQuery(namespace, id string) error
+	// ListQueries returns the stored queries of a namespace, oldest first
+	ListQueries(namespace string) ([]StoredQuery, error)
+	// Percolate returns the stored queries of a namespace a document matches, without indexing it
+	Percolate(namespace string, doc models.Document) ([]StoredQuery, error)
+}
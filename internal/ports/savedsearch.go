@@ -0,0 +1,41 @@
+package ports
+
+import "time"
+
+// SavedSearch is a named search callers can run again, e.g. "my-todos". Its query is a mustache-style
+// template ({{name}} placeholders and {{#name}} sections) rendered with the parameters of each run, or
+// else with the defaults in Params.
+type SavedSearch struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"` // Namespace the search belongs to ("": unbound callers)
+	Query     string            `json:"query"`
+	Sort      string            `json:"sort,omitempty"`
+	Fields    []string          `json:"fields,omitempty"` // Projection of the results (see models.ParseProjection)
+	Params    map[string]string `json:"params,omitempty"` // Default values of the placeholders
+	Updated   time.Time         `json:"updated"`
+}
+
+// SavedSearchPort is implemented by engines that keep saved searches (driving port). Saved searches
+// belong to a namespace and are only seen by its callers; unknown names fail with ErrNotFound.
+type SavedSearchPort interface {
+	// SaveSearch creates or replaces a saved search
+	SaveSearch(search SavedSearch) (SavedSearch, error)
+	GetSavedSearch(namespace, name string) (SavedSearch, error)
+	DeleteSavedSearch(namespace, name string) error
+	// ListSavedSearches returns the saved searches of a namespace by name
+	ListSavedSearches(namespace string) ([]SavedSearch, error)
+	// SavedSearchQuery returns the search of a saved search with its query rendered with params (and the
+	// defaults of the others); placeholders without a value and values holding query syntax fail with
+	// ErrInvalid
+	SavedSearchQuery(namespace, name string, params map[string]string) (SearchQuery, error)
+}
+
+// SavedSearchStorePort persists saved searches (driven port)
+type SavedSearchStorePort interface {
+	Get(namespace, name string) (SavedSearch, error)
+	Put(search SavedSearch) error
+	Delete(namespace, name string) error
+	// List returns the saved searches of a namespace by name
+	List(namespace string) ([]SavedSearch, error)
+	Close() error
+}
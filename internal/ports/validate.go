@@ -0,0 +1,29 @@
+package ports
+
+// QueryError is a problem found in a query. Offset and Length count characters (Unicode code points),
+// so user interfaces can underline the problem.
+type QueryError struct {
+	Offset     int    `json:"offset"`
+	Length     int    `json:"length"`
+	Code       string `json:"code"` // e.g. syntax, unknown_dimension, bad_operator
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"` // Replacement of the characters at Offset, if one is likely
+}
+
+// QueryValidation is the outcome of checking a query without running it
+type QueryValidation struct {
+	Valid  bool         `json:"valid"`
+	Errors []QueryError `json:"errors"`
+}
+
+// QueryValidatorIndexPort is implemented by index adapters that check queries without running them.
+// Queries of indexes that do not are assumed valid.
+type QueryValidatorIndexPort interface {
+	IndexPort
+	ValidateQuery(query string) []QueryError
+}
+
+// QueryValidatorPort is implemented by engines that check queries without running them (driving port)
+type QueryValidatorPort interface {
+	ValidateQuery(query SearchQuery) (QueryValidation, error)
+}
@@ -0,0 +1,296 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+/**
+ * Scoring scripts: arithmetic expressions computing the score of a candidate document during ranking,
+ * e.g. "_score * 1.5 + log(word_count) - age_days * 0.01". Expressions combine numbers, variables,
+ * + - * / and parentheses, and the functions listed in functions. Variables are:
+ *   _score    the score the index ranked the document by (text relevance plus boosts)
+ *   age_days  days since the document's lastModified (0 without one)
+ *   any other name: the numeric value of the document's field ("text", "source" or a metadata key,
+ *             e.g. word_count or content.word_count), 0 if missing or not numeric
+ **/
+
+// Built-in variables
+const (
+	VarScore   = "_score"
+	VarAgeDays = "age_days"
+)
+
+// Script is a compiled scoring expression, safe for concurrent use
+type Script struct {
+	source string
+	eval   node
+}
+
+// env holds the values a script is evaluated with
+type env struct {
+	doc   models.Document
+	score float64
+	now   time.Time
+}
+
+type node func(e *env) float64
+
+// functions are the functions scripts can call, by name and number of arguments. The logarithms of
+// values that are not positive are 0, so missing fields do not sink a document.
+var functions = map[string]struct {
+	args int
+	call func(args []float64) float64
+}{
+	"log":   {1, func(a []float64) float64 { return positiveLog(math.Log, a[0]) }},
+	"log10": {1, func(a []float64) float64 { return positiveLog(math.Log10, a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(math.Max(a[0], 0)) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+}
+
+func positiveLog(log func(float64) float64, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return log(x)
+}
+
+// Compile parses a scoring expression
+func Compile(source string) (*Script, error) {
+	p := &parser{tokens: tokenize(source)}
+	eval, err := p.expression()
+	if err == nil && p.peek() != "" {
+		err = fmt.Errorf("unexpected %q", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid scoring script %q: %w", source, err)
+	}
+	return &Script{source: source, eval: eval}, nil
+}
+
+// String returns the source of the script
+func (s *Script) String() string {
+	return s.source
+}
+
+// Score evaluates the script for a document the index scored score at time now. Results that are not
+// finite numbers (e.g. of a division by zero) are 0.
+func (s *Script) Score(doc models.Document, score float64, now time.Time) float64 {
+	result := s.eval(&env{doc: doc, score: score, now: now})
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0
+	}
+	return result
+}
+
+// variable reads the value of a variable for the document being scored
+func variable(name string) node {
+	switch name {
+	case VarScore:
+		return func(e *env) float64 { return e.score }
+	case VarAgeDays:
+		return func(e *env) float64 {
+			modified, err := time.Parse(time.RFC3339, e.doc.Field("lastModified"))
+			if err != nil {
+				return 0
+			}
+			return e.now.Sub(modified).Hours() / 24
+		}
+	}
+	return func(e *env) float64 {
+		value, err := strconv.ParseFloat(e.doc.Field(name), 64)
+		if err != nil {
+			return 0
+		}
+		return value
+	}
+}
+
+// tokenize splits an expression into numbers, names and single character operators
+func tokenize(source string) []string {
+	var tokens []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, string(runes[start:i]))
+	}
+	return tokens
+}
+
+// parser is a recursive descent parser of expressions:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = ("-" | "+") unary | primary
+//	primary    = number | name | name "(" expression { "," expression } ")" | "(" expression ")"
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *parser) expect(token string) error {
+	if got := p.next(); got != token {
+		if got == "" {
+			return fmt.Errorf("expected %q at end of script", token)
+		}
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(e *env) float64 { return l(e) + right(e) }
+		} else {
+			left = func(e *env) float64 { return l(e) - right(e) }
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		op := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "*" {
+			left = func(e *env) float64 { return l(e) * right(e) }
+		} else {
+			left = func(e *env) float64 { return l(e) / right(e) }
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	switch p.peek() {
+	case "-":
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *env) float64 { return -operand(e) }, nil
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of script")
+	case token == "(":
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return func(*env) float64 { return value }, nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		if p.peek() == "(" {
+			return p.call(token)
+		}
+		return variable(token), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+// call parses the arguments of a function call
+func (p *parser) call(name string) (node, error) {
+	function, ok := functions[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	p.next() // (
+	var args []node
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(args) != function.args {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, function.args, len(args))
+	}
+	return func(e *env) float64 {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			values[i] = arg(e)
+		}
+		return function.call(values)
+	}, nil
+}
@@ -0,0 +1,63 @@
+package scoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/models"
+)
+
+func TestScript_Score(t *testing.T) {
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	doc := models.Document{ID: "1", Meta: map[string]string{
+		"lastModified":       now.Add(-10 * 24 * time.Hour).Format(time.RFC3339),
+		"word_count":         "100",
+		"content.word_count": "50",
+		"title":              "report",
+	}}
+
+	for source, want := range map[string]float64{
+		"_score * 1.5 + log(word_count) - age_days*0.01": 3*1.5 + 4.60517 - 0.1,
+		"1 + 2 * 3":                        7,
+		"(1 + 2) * 3":                      9,
+		"-_score + +1":                     -2,
+		"10 / 4 - 1":                       1.5,
+		"content.word_count / 5":           10,
+		"max(word_count, 500) + min(1, 2)": 501,
+		"pow(2, 3) + sqrt(16) + abs(-1)":   13,
+		"log10(word_count) + exp(0)":       3,
+		// Missing and non-numeric fields are 0, and so are their logarithms
+		"missing + title + log(missing)": 0,
+		// Results that are not finite are 0
+		"_score / 0": 0,
+	} {
+		script, err := Compile(source)
+		if assert.NoError(t, err, source) {
+			assert.InDelta(t, want, script.Score(doc, 3, now), 1e-4, source)
+			assert.Equal(t, source, script.String())
+		}
+	}
+	// Documents without lastModified have no age
+	script, err := Compile("age_days")
+	assert.NoError(t, err)
+	assert.Zero(t, script.Score(models.Document{ID: "2"}, 1, now))
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for source, message := range map[string]string{
+		"":            "unexpected end of script",
+		"_score *":    "unexpected end of script",
+		"(_score + 1": `expected ")" at end of script`,
+		"_score 2":    `unexpected "2"`,
+		"square(2)":   "unknown function square",
+		"max(1)":      "max takes 2 arguments, got 1",
+		"log(1, 2":    `expected ")"`,
+		"1.2.3":       `invalid number "1.2.3"`,
+		"_score % 2":  `unexpected "%"`,
+	} {
+		_, err := Compile(source)
+		assert.ErrorContains(t, err, message, source)
+	}
+}
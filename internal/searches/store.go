@@ -0,0 +1,107 @@
+package searches
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.etcd.io/bbolt"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// searchesBucket holds the saved searches, keyed by namespace and name
+var searchesBucket = []byte("saved_searches")
+
+// Store keeps saved searches in a bbolt database (driven adapter of ports.SavedSearchStorePort)
+type Store struct {
+	db *bbolt.DB
+}
+
+// OpenStore opens (or creates) the saved search database at path
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create saved search directory: %w", err)
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open saved searches %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(searchesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open saved searches %s: %w", path, err)
+	}
+	log.Info().Msgf("Opened saved searches at %s", path)
+	return &Store{db: db}, nil
+}
+
+// key orders the saved searches of a namespace together, by name. Namespaces hold no NUL bytes.
+func key(namespace, name string) []byte {
+	return []byte(namespace + "\x00" + name)
+}
+
+// Get returns a saved search of a namespace
+func (s *Store) Get(namespace, name string) (ports.SavedSearch, error) {
+	var search ports.SavedSearch
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(searchesBucket).Get(key(namespace, name))
+		if data == nil {
+			return fmt.Errorf("%w: saved search %s", ports.ErrNotFound, name)
+		}
+		return json.Unmarshal(data, &search)
+	})
+	return search, err
+}
+
+// Put creates or replaces a saved search
+func (s *Store) Put(search ports.SavedSearch) error {
+	data, err := json.Marshal(search)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(searchesBucket).Put(key(search.Namespace, search.Name), data)
+	})
+}
+
+// Delete removes a saved search of a namespace
+func (s *Store) Delete(namespace, name string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(searchesBucket)
+		if bucket.Get(key(namespace, name)) == nil {
+			return fmt.Errorf("%w: saved search %s", ports.ErrNotFound, name)
+		}
+		return bucket.Delete(key(namespace, name))
+	})
+}
+
+// List returns the saved searches of a namespace by name
+func (s *Store) List(namespace string) ([]ports.SavedSearch, error) {
+	searches := []ports.SavedSearch{}
+	prefix := key(namespace, "")
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(searchesBucket).Cursor()
+		for k, data := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = cursor.Next() {
+			var search ports.SavedSearch
+			if err := json.Unmarshal(data, &search); err != nil {
+				return fmt.Errorf("saved search %q: %w", k[len(prefix):], err)
+			}
+			searches = append(searches, search)
+		}
+		return nil
+	})
+	return searches, err
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
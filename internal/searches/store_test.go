@@ -0,0 +1,50 @@
+package searches
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+func TestStore_SavedSearches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "searches.db")
+	store, err := OpenStore(path)
+	assert.NoError(t, err)
+
+	todos := ports.SavedSearch{Name: "my-todos", Query: "TODO fileExtension={{ext}}", Params: map[string]string{"ext": "go"}}
+	assert.NoError(t, store.Put(todos))
+	assert.NoError(t, store.Put(ports.SavedSearch{Name: "fixmes", Query: "FIXME"}))
+	assert.NoError(t, store.Put(ports.SavedSearch{Name: "my-todos", Namespace: "team-a", Query: "TODO"}))
+
+	got, err := store.Get("", "my-todos")
+	assert.NoError(t, err)
+	assert.Equal(t, todos.Query, got.Query)
+	assert.Equal(t, todos.Params, got.Params)
+	_, err = store.Get("team-b", "my-todos")
+	assert.ErrorIs(t, err, ports.ErrNotFound)
+
+	list, err := store.List("")
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "fixmes", list[0].Name)
+		assert.Equal(t, "my-todos", list[1].Name)
+	}
+	list, err = store.List("team-a")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	assert.NoError(t, store.Delete("", "fixmes"))
+	assert.ErrorIs(t, store.Delete("", "fixmes"), ports.ErrNotFound)
+
+	// Saved searches outlive the process
+	assert.NoError(t, store.Close())
+	store, err = OpenStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+	list, err = store.List("")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
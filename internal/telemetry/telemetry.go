@@ -0,0 +1,125 @@
+package telemetry
+
+/*
+OpenTelemetry setup. The engine and API adapters create spans through the global OTel API, which is a
+no-op until Setup installs a tracer provider exporting to an OTLP collector (or stdout).
+*/
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultServiceName is the service.name of exported spans unless configured
+const DefaultServiceName = "bitscout"
+
+// Exporters accepted by Setup
+const (
+	ExporterOTLP   = "otlp"   // OTLP over HTTP (default) or gRPC, to the endpoint or the OTEL_EXPORTER_OTLP_* environment
+	ExporterStdout = "stdout" // Pretty printed JSON on stdout, for debugging
+	ExporterNone   = "none"   // Tracing disabled
+)
+
+// Setup installs the global tracer provider and W3C trace context propagation from the "telemetry"
+// section of the starter config: "exporter" (otlp, stdout or none), "protocol" (http or grpc),
+// "endpoint" (host:port), "insecure", "service_name" and "sample_ratio" (0 to 1, default 1).
+// shutdown flushes the spans still buffered and must be called before exiting.
+func Setup(ctx context.Context, cfg map[string]interface{}) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+	exporterName, err := config.String(cfg, "exporter", ExporterOTLP)
+	if err != nil {
+		return noop, err
+	}
+	if exporterName == ExporterNone {
+		return noop, nil
+	}
+	serviceName, err := config.String(cfg, "service_name", DefaultServiceName)
+	if err != nil {
+		return noop, err
+	}
+	ratio, err := config.Float(cfg, "sample_ratio", 1)
+	if err != nil {
+		return noop, err
+	}
+	if ratio < 0 || ratio > 1 {
+		return noop, fmt.Errorf("sample_ratio must be between 0 and 1, got %g", ratio)
+	}
+
+	exporter, err := newExporter(ctx, exporterName, cfg)
+	if err != nil {
+		return noop, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.Int("process.pid", os.Getpid()),
+	))
+	if err != nil {
+		return noop, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	log.Info().Msgf("Tracing enabled: %s exporter, service %s, sampling %g", exporterName, serviceName, ratio)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, name string, cfg map[string]interface{}) (sdktrace.SpanExporter, error) {
+	switch name {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %s (known: %s, %s, %s)", name, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+
+	protocol, err := config.String(cfg, "protocol", "http")
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := config.String(cfg, "endpoint", "")
+	if err != nil {
+		return nil, err
+	}
+	insecure, err := config.Bool(cfg, "insecure", false)
+	if err != nil {
+		return nil, err
+	}
+	// Without an endpoint the exporters read OTEL_EXPORTER_OTLP_ENDPOINT and related variables
+	switch protocol {
+	case "http":
+		var options []otlptracehttp.Option
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	case "grpc":
+		var options []otlptracegrpc.Option
+		if endpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		if insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	}
+	return nil, fmt.Errorf("unknown OTLP protocol %s (known: http, grpc)", protocol)
+}
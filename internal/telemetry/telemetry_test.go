@@ -0,0 +1,39 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(context.Background(), map[string]interface{}{"exporter": ExporterNone})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSetup_InstallsProvider(t *testing.T) {
+	shutdown, err := Setup(context.Background(), map[string]interface{}{"exporter": ExporterStdout, "sample_ratio": 0.0})
+	assert.NoError(t, err)
+	defer shutdown(context.Background())
+
+	// With a ratio of 0 spans are created but not sampled
+	_, span := otel.Tracer("test").Start(context.Background(), "op")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.False(t, span.SpanContext().IsSampled())
+	span.End()
+}
+
+func TestSetup_RejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"exporter":     {"exporter": "zipkin"},
+		"protocol":     {"protocol": "udp"},
+		"sample ratio": {"sample_ratio": 2.0},
+		"type":         {"insecure": "yes"},
+	} {
+		_, err := Setup(context.Background(), cfg)
+		assert.Error(t, err, name)
+	}
+}
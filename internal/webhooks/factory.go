@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"fmt"
+
+	"github.com/aawadall/bit-scout/internal/config"
+	"github.com/aawadall/bit-scout/internal/ports"
+)
+
+// knownEvents lists the event types a webhook can filter on
+var knownEvents = map[ports.EventType]struct{}{
+	ports.EventDocumentIndexed: {},
+	ports.EventDocumentDeleted: {},
+	ports.EventSearchExecuted:  {},
+	ports.EventLoaderCompleted: {},
+	ports.EventQueryMatched:    {},
+}
+
+// NewWebhookFromConfig builds a webhook from the "config" map of a webhooks entry in the starter config:
+// "url", "events" (list), "headers" (map), "secret", "timeout" (duration), "retries" and "backoff" (duration)
+func NewWebhookFromConfig(cfg map[string]interface{}) (*Webhook, error) {
+	var c Config
+	var err error
+	if c.URL, err = config.String(cfg, "url", ""); err != nil {
+		return nil, err
+	}
+	events, err := config.Strings(cfg, "events")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range events {
+		eventType := ports.EventType(name)
+		if _, ok := knownEvents[eventType]; !ok {
+			return nil, fmt.Errorf("unknown event type %s", name)
+		}
+		c.Events = append(c.Events, eventType)
+	}
+	headers, err := config.Map(cfg, "headers")
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > 0 {
+		c.Headers = make(map[string]string, len(headers))
+		for key := range headers {
+			if c.Headers[key], err = config.String(headers, key, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.Secret, err = config.String(cfg, "secret", ""); err != nil {
+		return nil, err
+	}
+	if c.Timeout, err = config.Duration(cfg, "timeout", 0); err != nil {
+		return nil, err
+	}
+	if c.Retries, err = config.Int(cfg, "retries", 0); err != nil {
+		return nil, err
+	}
+	if c.Backoff, err = config.Duration(cfg, "backoff", 0); err != nil {
+		return nil, err
+	}
+	return NewWebhook(c)
+}
@@ -0,0 +1,133 @@
+package webhooks
+
+/*
+Webhook adapter: POSTs engine events as JSON to an external URL
+*/
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body when a secret is configured
+const SignatureHeader = "X-BitScout-Signature"
+
+// EventHeader carries the event type of the payload
+const EventHeader = "X-BitScout-Event"
+
+// Config configures a webhook
+type Config struct {
+	URL     string            // Endpoint receiving the POSTed events
+	Events  []ports.EventType // Event types to send (empty means all)
+	Headers map[string]string // Extra request headers (e.g. Authorization)
+	Secret  string            // Optional HMAC secret used to sign payloads
+	Timeout time.Duration     // Per-request timeout (default: 5s)
+	Retries int               // Retries after a failed delivery (default: 0)
+	Backoff time.Duration     // Delay before the first retry, doubled on each retry (default: 1s)
+}
+
+// Webhook delivers engine events to an HTTP endpoint. It implements ports.EventSubscriberPort;
+// deliveries happen on the event bus' subscriber goroutine, so a slow endpoint never blocks indexing.
+type Webhook struct {
+	config Config
+	client *http.Client
+}
+
+// NewWebhook validates the config and creates a webhook
+func NewWebhook(config Config) (*Webhook, error) {
+	target, err := url.Parse(config.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q", config.URL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Retries < 0 {
+		return nil, fmt.Errorf("webhook retries must not be negative, got %d", config.Retries)
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	return &Webhook{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+}
+
+// Events returns the event types this webhook is subscribed to
+func (w *Webhook) Events() []ports.EventType {
+	return w.config.Events
+}
+
+// Handle delivers an event, retrying failed deliveries; failures are logged, never returned
+func (w *Webhook) Handle(event ports.Event) {
+	if err := w.Deliver(event); err != nil {
+		log.Error().Msgf("Webhook %s: %s", w.config.URL, err)
+	}
+}
+
+// Deliver POSTs an event and retries on network errors and 5xx responses
+func (w *Webhook) Deliver(event ports.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+
+	backoff := w.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(event.Type, body)
+		if err == nil {
+			log.Debug().Msgf("Webhook %s: delivered %s event", w.config.URL, event.Type)
+			return nil
+		}
+		if !retry || attempt >= w.config.Retries {
+			return fmt.Errorf("failed to deliver %s event after %d attempts: %w", event.Type, attempt+1, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends one request; retry reports whether a failure is worth retrying
+func (w *Webhook) post(eventType ports.EventType, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(eventType))
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if w.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.config.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Sign computes the payload signature receivers use to verify a delivery
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
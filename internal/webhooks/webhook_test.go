@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aawadall/bit-scout/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_DeliverSignsPayload(t *testing.T) {
+	var received ports.Event
+	var signature, eventHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		eventHeader = r.Header.Get(EventHeader)
+		assert.Equal(t, Sign("s3cret", body), signature)
+		assert.NoError(t, json.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	hook, err := NewWebhook(Config{URL: server.URL, Secret: "s3cret"})
+	assert.NoError(t, err)
+	event := ports.Event{Type: ports.EventDocumentIndexed, Index: "simple", DocumentIDs: []string{"a", "b"}}
+	assert.NoError(t, hook.Deliver(event))
+
+	assert.Equal(t, "document_indexed", eventHeader)
+	assert.Equal(t, []string{"a", "b"}, received.DocumentIDs)
+}
+
+func TestWebhook_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	hook, _ := NewWebhook(Config{URL: server.URL, Retries: 2, Backoff: time.Millisecond})
+	assert.NoError(t, hook.Deliver(ports.Event{Type: ports.EventLoaderCompleted}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Client errors are not retried
+	atomic.StoreInt32(&calls, 0)
+	badRequest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer badRequest.Close()
+	hook, _ = NewWebhook(Config{URL: badRequest.URL, Retries: 2, Backoff: time.Millisecond})
+	assert.Error(t, hook.Deliver(ports.Event{Type: ports.EventLoaderCompleted}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestNewWebhookFromConfig(t *testing.T) {
+	hook, err := NewWebhookFromConfig(map[string]interface{}{
+		"url":     "https://example.com/hook",
+		"events":  []interface{}{"loader_completed"},
+		"headers": map[string]interface{}{"Authorization": "Bearer token"},
+		"timeout": "2s",
+		"retries": 3.0,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []ports.EventType{ports.EventLoaderCompleted}, hook.Events())
+	assert.Equal(t, 2*time.Second, hook.config.Timeout)
+	assert.Equal(t, "Bearer token", hook.config.Headers["Authorization"])
+
+	_, err = NewWebhookFromConfig(map[string]interface{}{"url": "ftp://example.com"})
+	assert.Error(t, err)
+	_, err = NewWebhookFromConfig(map[string]interface{}{"url": "https://example.com", "events": []interface{}{"nope"}})
+	assert.Error(t, err)
+}
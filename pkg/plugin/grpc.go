@@ -0,0 +1,203 @@
+package plugin
+
+/*
+gRPC transport. The services are described by hand and carry protobuf well-known types
+(Struct for config and features, BytesValue for JSON documents), so no generated code is needed:
+
+	service bitscout.plugin.Loader    { rpc Load(google.protobuf.Struct) returns (stream google.protobuf.BytesValue); }
+	service bitscout.plugin.Extractor { rpc Extract(google.protobuf.BytesValue) returns (google.protobuf.Struct); }
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	loaderLoadMethod       = "/bitscout.plugin.Loader/Load"
+	extractorExtractMethod = "/bitscout.plugin.Extractor/Extract"
+)
+
+// LoaderPlugin is the go-plugin definition of a loader plugin
+type LoaderPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl Loader // Set on the plugin side only
+}
+
+func (p *LoaderPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&loaderServiceDesc, &loaderServer{impl: p.Impl})
+	return nil
+}
+
+func (p *LoaderPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &LoaderClient{conn: conn}, nil
+}
+
+// ExtractorPlugin is the go-plugin definition of a feature extractor plugin
+type ExtractorPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl Extractor // Set on the plugin side only
+}
+
+func (p *ExtractorPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&extractorServiceDesc, &extractorServer{impl: p.Impl})
+	return nil
+}
+
+func (p *ExtractorPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &ExtractorClient{conn: conn}, nil
+}
+
+// LoaderClient is the host side of a loader plugin; it implements Loader over gRPC
+type LoaderClient struct {
+	conn *grpc.ClientConn
+}
+
+// Load streams documents from the plugin, calling emit for each one
+func (c *LoaderClient) Load(ctx context.Context, config map[string]interface{}, emit func(Document) error) error {
+	req, err := structpb.NewStruct(config)
+	if err != nil {
+		return fmt.Errorf("loader config is not JSON-compatible: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.conn.NewStream(ctx, &loaderServiceDesc.Streams[0], loaderLoadMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		msg := new(wrapperspb.BytesValue)
+		if err := stream.RecvMsg(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var doc Document
+		if err := json.Unmarshal(msg.Value, &doc); err != nil {
+			return fmt.Errorf("plugin sent an invalid document: %w", err)
+		}
+		if err := emit(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// ExtractorClient is the host side of a feature extractor plugin; it implements Extractor over gRPC
+type ExtractorClient struct {
+	conn *grpc.ClientConn
+}
+
+// Extract sends a document to the plugin and returns the extracted features
+func (c *ExtractorClient) Extract(ctx context.Context, doc Document) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, extractorExtractMethod, wrapperspb.Bytes(data), out); err != nil {
+		return nil, err
+	}
+	return out.AsMap(), nil
+}
+
+// loaderService and extractorService are the handler types gRPC checks registered servers against
+type loaderService interface {
+	Load(config *structpb.Struct, stream grpc.ServerStream) error
+}
+
+type extractorService interface {
+	Extract(ctx context.Context, doc *wrapperspb.BytesValue) (*structpb.Struct, error)
+}
+
+type loaderServer struct {
+	impl Loader
+}
+
+func (s *loaderServer) Load(config *structpb.Struct, stream grpc.ServerStream) error {
+	return s.impl.Load(stream.Context(), config.AsMap(), func(doc Document) error {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(wrapperspb.Bytes(data))
+	})
+}
+
+type extractorServer struct {
+	impl Extractor
+}
+
+func (s *extractorServer) Extract(ctx context.Context, msg *wrapperspb.BytesValue) (*structpb.Struct, error) {
+	var doc Document
+	if err := json.Unmarshal(msg.Value, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	features, err := s.impl.Extract(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so typed values (e.g. []float64, int) become structpb-compatible
+	data, err := json.Marshal(features)
+	if err != nil {
+		return nil, fmt.Errorf("features are not JSON-compatible: %w", err)
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(generic)
+}
+
+var loaderServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bitscout.plugin.Loader",
+	HandlerType: (*loaderService)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Load",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			config := new(structpb.Struct)
+			if err := stream.RecvMsg(config); err != nil {
+				return err
+			}
+			return srv.(loaderService).Load(config, stream)
+		},
+	}},
+}
+
+var extractorServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bitscout.plugin.Extractor",
+	HandlerType: (*extractorService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Extract",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(wrapperspb.BytesValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return srv.(extractorService).Extract(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: extractorExtractMethod}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(extractorService).Extract(ctx, req.(*wrapperspb.BytesValue))
+			})
+		},
+	}},
+}
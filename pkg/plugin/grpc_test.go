@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedLoader struct {
+	docs []Document
+	err  error
+}
+
+func (l *fixedLoader) Load(ctx context.Context, config map[string]interface{}, emit func(Document) error) error {
+	prefix, _ := config["prefix"].(string)
+	for _, doc := range l.docs {
+		doc.ID = prefix + doc.ID
+		if err := emit(doc); err != nil {
+			return err
+		}
+	}
+	return l.err
+}
+
+type lengthExtractor struct{}
+
+func (lengthExtractor) Extract(ctx context.Context, doc Document) (map[string]interface{}, error) {
+	if doc.Text == "" {
+		return nil, errors.New("empty document")
+	}
+	return map[string]interface{}{"length": len(doc.Text), "tags": []string{doc.Meta["tag"]}}, nil
+}
+
+func dispense(t *testing.T, kind string, impl goplugin.Plugin) interface{} {
+	client, server := goplugin.TestPluginGRPCConn(t, false, map[string]goplugin.Plugin{kind: impl})
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+	raw, err := client.Dispense(kind)
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestLoaderClient_Load(t *testing.T) {
+	impl := &fixedLoader{docs: []Document{
+		{ID: "1", Text: "hello", Meta: map[string]string{"k": "v"}},
+		{ID: "2", Text: "world", Vector: []float64{0.5}},
+	}}
+	loader := dispense(t, KindLoader, &LoaderPlugin{Impl: impl}).(Loader)
+
+	var got []Document
+	err := loader.Load(context.Background(), map[string]interface{}{"prefix": "p-"}, func(doc Document) error {
+		got = append(got, doc)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Document{
+		{ID: "p-1", Text: "hello", Meta: map[string]string{"k": "v"}},
+		{ID: "p-2", Text: "world", Vector: []float64{0.5}},
+	}, got)
+}
+
+func TestLoaderClient_Load_ReportsPluginError(t *testing.T) {
+	impl := &fixedLoader{docs: []Document{{ID: "1"}}, err: errors.New("source unavailable")}
+	loader := dispense(t, KindLoader, &LoaderPlugin{Impl: impl}).(Loader)
+
+	count := 0
+	err := loader.Load(context.Background(), nil, func(Document) error {
+		count++
+		return nil
+	})
+	assert.ErrorContains(t, err, "source unavailable")
+	assert.Equal(t, 1, count)
+}
+
+func TestLoaderClient_Load_StopsWhenEmitFails(t *testing.T) {
+	impl := &fixedLoader{docs: []Document{{ID: "1"}, {ID: "2"}, {ID: "3"}}}
+	loader := dispense(t, KindLoader, &LoaderPlugin{Impl: impl}).(Loader)
+
+	stop := errors.New("stop")
+	err := loader.Load(context.Background(), nil, func(Document) error { return stop })
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestExtractorClient_Extract(t *testing.T) {
+	extractor := dispense(t, KindExtractor, &ExtractorPlugin{Impl: lengthExtractor{}}).(Extractor)
+
+	features, err := extractor.Extract(context.Background(), Document{ID: "1", Text: "hello", Meta: map[string]string{"tag": "greeting"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"length": float64(5), "tags": []interface{}{"greeting"}}, features)
+
+	_, err = extractor.Extract(context.Background(), Document{ID: "2"})
+	assert.ErrorContains(t, err, "empty document")
+}
@@ -0,0 +1,80 @@
+// Package plugin is the SDK for writing bit-scout loader and feature extractor plugins.
+//
+// A plugin is a standalone executable that bit-scout discovers in its plugins directory and runs
+// as a child process, talking to it over gRPC (github.com/hashicorp/go-plugin). Executables are
+// named after what they provide:
+//
+//	bitscout-loader-<type>     serves a Loader; <type> is used as the loader type in the starter config
+//	bitscout-extractor-<name>  serves an Extractor registered as feature extractor <name>
+//
+// A loader plugin's main function is a single call:
+//
+//	func main() { plugin.ServeLoader(&myLoader{}) }
+package plugin
+
+import (
+	"context"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// Handshake is shared by bit-scout and its plugins; it stops plugins from being run directly
+// and rejects plugins built against an incompatible protocol version.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "BITSCOUT_PLUGIN",
+	MagicCookieValue: "6f0c1e0c-bit-scout-plugin",
+}
+
+// Plugin kinds, used as go-plugin plugin names and in executable names
+const (
+	KindLoader    = "loader"
+	KindExtractor = "extractor"
+)
+
+// Document is a document exchanged with plugins; it has the same JSON shape as bit-scout's documents.
+type Document struct {
+	ID     string            `json:"id"`
+	Text   string            `json:"text"`
+	Source string            `json:"source"`
+	Vector []float64         `json:"vector,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+// Loader is implemented by loader plugins. Load reads a corpus described by the loader's starter
+// config map and calls emit for every document; it should stop and return when ctx is cancelled.
+type Loader interface {
+	Load(ctx context.Context, config map[string]interface{}, emit func(Document) error) error
+}
+
+// Extractor is implemented by feature extractor plugins. Feature values must be JSON-compatible
+// (strings, numbers, booleans, lists or objects).
+type Extractor interface {
+	Extract(ctx context.Context, doc Document) (map[string]interface{}, error)
+}
+
+// ServeLoader runs a loader plugin; call it from the plugin's main function. It blocks until bit-scout exits.
+func ServeLoader(loader Loader) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{KindLoader: &LoaderPlugin{Impl: loader}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
+
+// ServeExtractor runs a feature extractor plugin; call it from the plugin's main function.
+func ServeExtractor(extractor Extractor) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{KindExtractor: &ExtractorPlugin{Impl: extractor}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
+
+// PluginMap lists the plugin kinds bit-scout can dispense
+func PluginMap() map[string]goplugin.Plugin {
+	return map[string]goplugin.Plugin{
+		KindLoader:    &LoaderPlugin{},
+		KindExtractor: &ExtractorPlugin{},
+	}
+}